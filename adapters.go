@@ -0,0 +1,152 @@
+package feather
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// contextMaxMemory is the maximum number of bytes of the request bodies decoded by Context.Bind.
+const contextMaxMemory = 32 << 20
+
+// HTTPRouterHandler adapts a handler taking the URL params as third argument,
+// i.e. julienschmidt/httprouter handlers, whose Params are slices of structs with the Key and Value string fields:
+//
+//	p.Get("/users/:id", feather.HTTPRouterHandler(func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+//		id := ps.ByName("id")
+//		...
+//	}))
+//
+// The params are copied from the RequestVars in matching order, the catch-all is named WildcardParam.
+func HTTPRouterHandler[P ~[]E, E ~struct {
+	Key   string
+	Value string
+}](h func(http.ResponseWriter, *http.Request, P)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var ps P
+		if rv, ok := requestVarsOf(r); ok && len(rv.params) > 0 {
+			ps = make(P, len(rv.params))
+			for i, param := range rv.params {
+				ps[i] = E{Key: param.key, Value: param.value}
+			}
+		}
+
+		h(w, r, ps)
+	}
+}
+
+// Context bundles the request and response of handlers written in the style of gin and similar frameworks,
+// easing the migration of such handlers, which only need to change the type of their argument.
+type Context struct {
+	Writer  http.ResponseWriter
+	Request *http.Request
+	query   url.Values
+}
+
+// ContextHandler adapts a handler taking a Context.
+func ContextHandler(h func(c *Context)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		h(&Context{Writer: w, Request: r})
+	}
+}
+
+// Param returns the URL param.
+func (c *Context) Param(name string) string {
+	return RequestVars(c.Request).URLParam(name)
+}
+
+// FullPath returns the pattern of the matched route, e.g. /user/:id.
+func (c *Context) FullPath() string {
+	return RequestVars(c.Request).Route()
+}
+
+// Query returns the first value of the query param, blank if none.
+func (c *Context) Query(key string) string {
+	return c.DefaultQuery(key, blank)
+}
+
+// DefaultQuery returns the first value of the query param, def if none.
+func (c *Context) DefaultQuery(key string, def string) string {
+	if c.query == nil {
+		c.query = c.Request.URL.Query()
+	}
+
+	if values, ok := c.query[key]; ok && len(values) > 0 {
+		return values[0]
+	}
+
+	return def
+}
+
+// GetHeader returns the request header.
+func (c *Context) GetHeader(key string) string {
+	return c.Request.Header.Get(key)
+}
+
+// Header sets the response header, deleting it if value is blank.
+func (c *Context) Header(key string, value string) {
+	if value == blank {
+		c.Writer.Header().Del(key)
+		return
+	}
+
+	c.Writer.Header().Set(key, value)
+}
+
+// Set stores a value for the duration of the request, see ReqVars.
+func (c *Context) Set(key string, value interface{}) {
+	RequestVars(c.Request).Set(key, value)
+}
+
+// Get returns the value stored using Set and whether it exists.
+func (c *Context) Get(key string) (value interface{}, exists bool) {
+	value = RequestVars(c.Request).Get(key)
+	return value, value != nil
+}
+
+// ClientIP returns the client IP, see ClientIP.
+func (c *Context) ClientIP() string {
+	return ClientIP(c.Request)
+}
+
+// Bind decodes the request body of up to 32MB, the URL and query params into v based on the Content-Type, see Decode.
+func (c *Context) Bind(v interface{}) error {
+	return Decode(c.Request, IncludeQueryParams, contextMaxMemory, v)
+}
+
+// Status writes the status code.
+func (c *Context) Status(code int) {
+	c.Writer.WriteHeader(code)
+}
+
+// String writes the text formatted using fmt.Sprintf with the status code.
+func (c *Context) String(code int, format string, values ...interface{}) {
+	c.Writer.Header().Set(contentTypeHeader, textPlain)
+	c.Writer.WriteHeader(code)
+	if len(values) > 0 {
+		format = fmt.Sprintf(format, values...)
+	}
+	_, _ = c.Writer.Write([]byte(format))
+}
+
+// JSON writes v as JSON with the status code, see JSON.
+func (c *Context) JSON(code int, v interface{}) {
+	_ = JSON(c.Writer, code, v)
+}
+
+// XML writes v as XML with the status code, see XML.
+func (c *Context) XML(code int, v interface{}) {
+	_ = XML(c.Writer, code, v)
+}
+
+// Data writes the data with the status code and content type.
+func (c *Context) Data(code int, contentType string, data []byte) {
+	c.Writer.Header().Set(contentTypeHeader, contentType)
+	c.Writer.WriteHeader(code)
+	_, _ = c.Writer.Write(data)
+}
+
+// Redirect redirects the request to location with the status code, see Redirect.
+func (c *Context) Redirect(code int, location string) {
+	Redirect(c.Writer, c.Request, code, location)
+}
@@ -0,0 +1,96 @@
+package feather
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	. "github.com/pchchv/feather/assert"
+)
+
+// routerParam and routerParams mirror the params of httprouter.
+type routerParam struct {
+	Key   string
+	Value string
+}
+
+type routerParams []routerParam
+
+func (ps routerParams) ByName(name string) string {
+	for _, p := range ps {
+		if p.Key == name {
+			return p.Value
+		}
+	}
+
+	return ""
+}
+
+func TestHTTPRouterHandler(t *testing.T) {
+	p := New()
+	p.Get("/users/:id/files/*", HTTPRouterHandler(func(w http.ResponseWriter, r *http.Request, ps routerParams) {
+		_, _ = w.Write([]byte(ps.ByName("id") + " " + ps.ByName(WildcardParam)))
+	}))
+	p.Get("/static", HTTPRouterHandler(func(w http.ResponseWriter, r *http.Request, ps routerParams) {
+		Equal(t, len(ps), 0)
+	}))
+
+	code, body := request(http.MethodGet, "/users/13/files/a/b.txt", p)
+	Equal(t, code, http.StatusOK)
+	Equal(t, body, "13 a/b.txt")
+
+	code, _ = request(http.MethodGet, "/static", p)
+	Equal(t, code, http.StatusOK)
+}
+
+func TestContextHandler(t *testing.T) {
+	type user struct {
+		ID   int    `form:"id"`
+		Name string `json:"name"`
+	}
+
+	p := New()
+	p.Use(func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			RequestVars(r).Set("principal", "admin")
+			next(w, r)
+		}
+	})
+	p.Get("/users/:id", ContextHandler(func(c *Context) {
+		principal, ok := c.Get("principal")
+		Equal(t, ok, true)
+		c.Header("X-Route", c.FullPath())
+		c.String(http.StatusOK, "%s %s %s %s %v", c.Param("id"), c.Query("q"), c.DefaultQuery("sort", "name"), c.GetHeader("X-Test"), principal)
+	}))
+	p.Post("/users/:id", ContextHandler(func(c *Context) {
+		var u user
+		if err := c.Bind(&u); err != nil {
+			c.Status(http.StatusBadRequest)
+			return
+		}
+		c.JSON(http.StatusCreated, u)
+	}))
+	p.Get("/old", ContextHandler(func(c *Context) {
+		c.Redirect(http.StatusFound, "/new")
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "/users/13?q=go", nil)
+	r.Header.Set("X-Test", "test")
+	w := httptest.NewRecorder()
+	p.Serve().ServeHTTP(w, r)
+	Equal(t, w.Code, http.StatusOK)
+	Equal(t, w.Header().Get("X-Route"), "/users/:id")
+	Equal(t, w.Header().Get(contentTypeHeader), textPlain)
+	Equal(t, w.Body.String(), "13 go name test admin")
+
+	r = httptest.NewRequest(http.MethodPost, "/users/13", strings.NewReader(`{"name":"joe"}`))
+	r.Header.Set(contentTypeHeader, applicationJSON)
+	w = httptest.NewRecorder()
+	p.Serve().ServeHTTP(w, r)
+	Equal(t, w.Code, http.StatusCreated)
+	Equal(t, w.Body.String(), "{\"ID\":13,\"name\":\"joe\"}")
+
+	code, _ := request(http.MethodGet, "/old", p)
+	Equal(t, code, http.StatusFound)
+}
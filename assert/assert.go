@@ -7,6 +7,7 @@ import (
 	"regexp"
 	"runtime"
 	"testing"
+	"time"
 )
 
 // IsEqual returns whether val1 is equal to val2 taking into account Pointers, Interfaces and their underlying types.
@@ -171,6 +172,133 @@ func PanicMatchesSkip(t testing.TB, skip int, fn func(), matches string) {
 	fn()
 }
 
+// Panics validates that running fn panics and returns the recovered value.
+func Panics(t testing.TB, fn func()) interface{} {
+	return PanicsSkip(t, 2, fn)
+}
+
+// PanicsSkip validates that running fn panics and returns the recovered value
+// but the skip variable tells PanicsSkip how far back on the stack to report the error.
+// This is a building block to creating your own more complex validation functions.
+func PanicsSkip(t testing.TB, skip int, fn func()) interface{} {
+	r, panicked := recoverPanic(fn)
+	if !panicked {
+		_, file, line, _ := runtime.Caller(skip)
+		fmt.Printf("%s:%d Panic Expected, none found\n", path.Base(file), line)
+		t.FailNow()
+	}
+
+	return r
+}
+
+// NotPanics validates that running fn does not panic.
+func NotPanics(t testing.TB, fn func()) {
+	NotPanicsSkip(t, 2, fn)
+}
+
+// NotPanicsSkip validates that running fn does not panic
+// but the skip variable tells NotPanicsSkip how far back on the stack to report the error.
+// This is a building block to creating your own more complex validation functions.
+func NotPanicsSkip(t testing.TB, skip int, fn func()) {
+	if r, panicked := recoverPanic(fn); panicked {
+		_, file, line, _ := runtime.Caller(skip)
+		fmt.Printf("%s:%d Unexpected panic... received [%v]\n", path.Base(file), line, r)
+		t.FailNow()
+	}
+}
+
+// PanicsWithValue validates that running fn panics with a value equal to expected,
+// unlike PanicMatches the value itself is compared, i.e. an error or a struct.
+func PanicsWithValue(t testing.TB, fn func(), expected interface{}) {
+	PanicsWithValueSkip(t, 2, fn, expected)
+}
+
+// PanicsWithValueSkip validates that running fn panics with a value equal to expected
+// but the skip variable tells PanicsWithValueSkip how far back on the stack to report the error.
+// This is a building block to creating your own more complex validation functions.
+func PanicsWithValueSkip(t testing.TB, skip int, fn func(), expected interface{}) {
+	r, panicked := recoverPanic(fn)
+	if !panicked {
+		_, file, line, _ := runtime.Caller(skip)
+		fmt.Printf("%s:%d Panic Expected, none found...  expected [%v]\n", path.Base(file), line, expected)
+		t.FailNow()
+	} else if !IsEqual(r, expected) {
+		_, file, line, _ := runtime.Caller(skip)
+		fmt.Printf("%s:%d Panic...  expected [%v] received [%v]\n", path.Base(file), line, expected, r)
+		t.FailNow()
+	}
+}
+
+// NoError validates that err is nil and throws an error with line number.
+func NoError(t testing.TB, err error) {
+	NoErrorSkip(t, 2, err)
+}
+
+// NoErrorSkip validates that err is nil but the skip variable tells NoErrorSkip
+// how far back on the stack to report the error.
+// This is a building block to creating your own more complex validation functions.
+func NoErrorSkip(t testing.TB, skip int, err error) {
+	if err != nil {
+		_, file, line, _ := runtime.Caller(skip)
+		fmt.Printf("%s:%d Unexpected error: %v\n", path.Base(file), line, err)
+		t.FailNow()
+	}
+}
+
+// Error validates that err is not nil and throws an error with line number.
+func Error(t testing.TB, err error) {
+	ErrorSkip(t, 2, err)
+}
+
+// ErrorSkip validates that err is not nil but the skip variable tells ErrorSkip
+// how far back on the stack to report the error.
+// This is a building block to creating your own more complex validation functions.
+func ErrorSkip(t testing.TB, skip int, err error) {
+	if err == nil {
+		_, file, line, _ := runtime.Caller(skip)
+		fmt.Printf("%s:%d Error expected, none found\n", path.Base(file), line)
+		t.FailNow()
+	}
+}
+
+// Eventually validates that cond returns true within timeout, checking it every tick.
+// This is intended for asserting the results of asynchronous work, i.e. of a background goroutine.
+func Eventually(t testing.TB, cond func() bool, timeout time.Duration, tick time.Duration) {
+	EventuallySkip(t, 2, cond, timeout, tick)
+}
+
+// EventuallySkip validates that cond returns true within timeout, checking it every tick,
+// but the skip variable tells EventuallySkip how far back on the stack to report the error.
+// This is a building block to creating your own more complex validation functions.
+func EventuallySkip(t testing.TB, skip int, cond func() bool, timeout time.Duration, tick time.Duration) {
+	deadline := time.Now().Add(timeout)
+	for !cond() {
+		if !time.Now().Before(deadline) {
+			_, file, line, _ := runtime.Caller(skip)
+			fmt.Printf("%s:%d Condition not met within %v\n", path.Base(file), line, timeout)
+			t.FailNow()
+			return
+		}
+
+		time.Sleep(min(tick, time.Until(deadline)))
+	}
+}
+
+// recoverPanic runs fn, returning the recovered value and whether it panicked,
+// panics with a nil value are reported as a *runtime.PanicNilError.
+func recoverPanic(fn func()) (r interface{}, panicked bool) {
+	panicked = true
+	defer func() {
+		if panicked {
+			r = recover()
+		}
+	}()
+
+	fn()
+	panicked = false
+	return
+}
+
 func regexMatches(regex interface{}, value string) (r *regexp.Regexp, ok bool, err error) {
 	// must be a string
 	if r, ok = regex.(*regexp.Regexp); !ok {
@@ -2,7 +2,9 @@ package assert
 
 import (
 	"errors"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 func TestEquals(t *testing.T) {
@@ -65,3 +67,63 @@ func CustomErrorHandler(t testing.TB, errs map[string]string, key, expected stri
 	NotEqualSkip(t, 2, val, nil)
 	EqualSkip(t, 2, val, expected)
 }
+
+// failTB records failures instead of stopping the test, so failing assertions can be tested.
+type failTB struct {
+	testing.TB
+	failed bool
+}
+
+func (f *failTB) FailNow() {
+	f.failed = true
+}
+
+func TestPanics(t *testing.T) {
+	err := errors.New("omg omg omg!")
+	Equal(t, Panics(t, func() { panic(err) }), err)
+	NotPanics(t, func() {})
+	PanicsWithValue(t, func() { panic(err) }, err)
+	PanicsWithValue(t, func() { panic(13) }, 13)
+
+	ft := new(failTB)
+	Equal(t, Panics(ft, func() {}), nil)
+	Equal(t, ft.failed, true)
+
+	ft = new(failTB)
+	NotPanics(ft, func() { panic(err) })
+	Equal(t, ft.failed, true)
+
+	ft = new(failTB)
+	PanicsWithValue(ft, func() { panic(err) }, errors.New("other"))
+	Equal(t, ft.failed, true)
+
+	ft = new(failTB)
+	PanicsWithValue(ft, func() {}, err)
+	Equal(t, ft.failed, true)
+}
+
+func TestErrors(t *testing.T) {
+	NoError(t, nil)
+	Error(t, errors.New("my error"))
+
+	ft := new(failTB)
+	NoError(ft, errors.New("my error"))
+	Equal(t, ft.failed, true)
+
+	ft = new(failTB)
+	Error(ft, nil)
+	Equal(t, ft.failed, true)
+}
+
+func TestEventually(t *testing.T) {
+	var done atomic.Bool
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		done.Store(true)
+	}()
+	Eventually(t, done.Load, time.Second, time.Millisecond)
+
+	ft := new(failTB)
+	Eventually(ft, func() bool { return false }, 10*time.Millisecond, time.Millisecond)
+	Equal(t, ft.failed, true)
+}
@@ -0,0 +1,80 @@
+// Package bench contains standardized route sets and helpers to benchmark feather,
+// and a lightweight load generator to measure a running server.
+//
+// Router benchmarks:
+//
+//	func BenchmarkGitHub(b *testing.B) {
+//		bench.Run(b, bench.Mux(bench.GitHubAPI).Serve(), bench.Requests(bench.GitHubAPI))
+//	}
+package bench
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/pchchv/feather"
+)
+
+type discardWriter struct {
+	h http.Header
+}
+
+func (w *discardWriter) Header() http.Header {
+	return w.h
+}
+
+func (w *discardWriter) Write(b []byte) (int, error) {
+	return len(b), nil
+}
+
+func (w *discardWriter) WriteHeader(int) {}
+
+// Mux returns a Mux with the routes registered using a handler writing nothing.
+func Mux(routes []Route) *feather.Mux {
+	p := feather.New()
+	h := func(http.ResponseWriter, *http.Request) {}
+	for _, r := range routes {
+		p.Handle(r.Method, r.Path, h)
+	}
+
+	return p
+}
+
+// Path returns the path of a request matching the route,
+// params are replaced by their names and the catch-all by "wildcard", i.e. /users/:user becomes /users/user.
+func Path(route string) string {
+	segs := strings.Split(route, "/")
+	for i, s := range segs {
+		switch {
+		case strings.HasPrefix(s, ":"):
+			segs[i] = s[1:]
+		case s == "*":
+			segs[i] = "wildcard"
+		}
+	}
+
+	return strings.Join(segs, "/")
+}
+
+// Requests returns a request for each of the routes, see Path.
+func Requests(routes []Route) []*http.Request {
+	requests := make([]*http.Request, len(routes))
+	for i, r := range routes {
+		requests[i], _ = http.NewRequest(r.Method, Path(r.Path), nil)
+	}
+
+	return requests
+}
+
+// Run benchmarks h serving all the requests per iteration, reporting allocations.
+func Run(b *testing.B, h http.Handler, requests []*http.Request) {
+	w := &discardWriter{h: make(http.Header)}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, r := range requests {
+			h.ServeHTTP(w, r)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package bench
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/pchchv/feather/assert"
+)
+
+func TestRouteSets(t *testing.T) {
+	for _, routes := range [][]Route{GitHubAPI, ParamHeavy} {
+		p := Mux(routes)
+		hf := p.Serve()
+		for _, r := range Requests(routes) {
+			w := httptest.NewRecorder()
+			hf.ServeHTTP(w, r)
+			Equal(t, w.Code, http.StatusOK)
+		}
+	}
+}
+
+func TestPath(t *testing.T) {
+	Equal(t, Path("/repos/:owner/:repo/git/blobs/:sha"), "/repos/owner/repo/git/blobs/sha")
+	Equal(t, Path("/files/*"), "/files/wildcard")
+}
+
+func TestLoad(t *testing.T) {
+	server := httptest.NewServer(Mux(GitHubAPI).Serve())
+	defer server.Close()
+
+	res := Load(context.Background(), LoadConfig{
+		BaseURL:     server.URL,
+		Routes:      GitHubAPI,
+		Concurrency: 4,
+		Duration:    5 * time.Second,
+		Requests:    200,
+	})
+	Equal(t, res.Requests, 200)
+	Equal(t, res.Errors, 0)
+	Equal(t, res.P50 <= res.P99, true)
+	Equal(t, res.P99 <= res.Max, true)
+	NotEqual(t, res.RequestsPerSecond, float64(0))
+}
+
+func TestAllocs(t *testing.T) {
+	hf := Mux(GitHubAPI).Serve()
+	w := &discardWriter{h: make(http.Header)}
+	for _, tt := range []struct {
+		route  Route
+		allocs float64
+	}{
+		{route: Route{http.MethodGet, "/user/repos"}, allocs: 0},
+		{route: Route{http.MethodGet, "/repos/:owner/:repo/issues/:number"}, allocs: 1},
+	} {
+		r := Requests([]Route{tt.route})[0]
+		allocs := testing.AllocsPerRun(100, func() {
+			hf.ServeHTTP(w, r)
+		})
+		Equal(t, allocs, tt.allocs)
+	}
+}
+
+func BenchmarkGitHubAll(b *testing.B) {
+	Run(b, Mux(GitHubAPI).Serve(), Requests(GitHubAPI))
+}
+
+func BenchmarkGitHubStatic(b *testing.B) {
+	routes := []Route{{http.MethodGet, "/user/repos"}}
+	Run(b, Mux(GitHubAPI).Serve(), Requests(routes))
+}
+
+func BenchmarkGitHubParam(b *testing.B) {
+	routes := []Route{{http.MethodGet, "/repos/:owner/:repo/issues/:number"}}
+	Run(b, Mux(GitHubAPI).Serve(), Requests(routes))
+}
+
+func BenchmarkParamHeavy(b *testing.B) {
+	Run(b, Mux(ParamHeavy).Serve(), Requests(ParamHeavy))
+}
+
+func BenchmarkParamHeavy20(b *testing.B) {
+	Run(b, Mux(ParamHeavy).Serve(), Requests(ParamHeavy[19:20]))
+}
@@ -0,0 +1,137 @@
+package bench
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// LoadConfig contains the settings of a load test.
+type LoadConfig struct {
+	// BaseURL of the server, i.e. http://localhost:3007.
+	BaseURL string
+	// Routes requested in turn, see Path.
+	Routes []Route
+	// Concurrency is the number of concurrent workers, default 1.
+	Concurrency int
+	// Duration of the test, default 10s, the test also ends when the context is done.
+	Duration time.Duration
+	// Requests when set limits the total number of requests.
+	Requests int
+	// Client used for the requests, default http.DefaultClient.
+	Client *http.Client
+}
+
+// LoadResult contains the results of a load test.
+type LoadResult struct {
+	Requests int
+	// Errors counts failed requests and responses with a 5xx status code.
+	Errors   int
+	Duration time.Duration
+	// RequestsPerSecond is the throughput.
+	RequestsPerSecond float64
+	P50               time.Duration
+	P90               time.Duration
+	P99               time.Duration
+	Max               time.Duration
+}
+
+// Load generates load against a running server until the Duration elapsed,
+// the number of Requests were sent or ctx is done.
+func Load(ctx context.Context, cfg LoadConfig) LoadResult {
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = 1
+	}
+
+	if cfg.Duration <= 0 {
+		cfg.Duration = 10 * time.Second
+	}
+
+	if cfg.Client == nil {
+		cfg.Client = http.DefaultClient
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, cfg.Duration)
+	defer cancel()
+
+	var (
+		m         sync.Mutex
+		wg        sync.WaitGroup
+		next      int
+		errors    int
+		latencies []time.Duration
+	)
+
+	// take returns the index of the next request to send, or -1 when done
+	take := func() int {
+		m.Lock()
+		defer m.Unlock()
+		if ctx.Err() != nil || (cfg.Requests > 0 && next >= cfg.Requests) {
+			return -1
+		}
+
+		next++
+		return next - 1
+	}
+
+	start := time.Now()
+	for i := 0; i < cfg.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := take(); n != -1; n = take() {
+				route := cfg.Routes[n%len(cfg.Routes)]
+				req, err := http.NewRequestWithContext(ctx, route.Method, cfg.BaseURL+Path(route.Path), nil)
+				if err != nil {
+					panic(err)
+				}
+
+				began := time.Now()
+				resp, err := cfg.Client.Do(req)
+				if err == nil {
+					_, _ = io.Copy(io.Discard, resp.Body)
+					_ = resp.Body.Close()
+				}
+				latency := time.Since(began)
+
+				m.Lock()
+				if err != nil || resp.StatusCode >= http.StatusInternalServerError {
+					// requests canceled at the end of the test are not counted
+					if ctx.Err() == nil {
+						errors++
+						latencies = append(latencies, latency)
+					}
+				} else {
+					latencies = append(latencies, latency)
+				}
+				m.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	res := LoadResult{
+		Requests: len(latencies),
+		Errors:   errors,
+		Duration: time.Since(start),
+	}
+
+	if len(latencies) == 0 {
+		return res
+	}
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	percentile := func(p float64) time.Duration {
+		return latencies[int(float64(len(latencies)-1)*p)]
+	}
+
+	res.RequestsPerSecond = float64(res.Requests) / res.Duration.Seconds()
+	res.P50 = percentile(0.5)
+	res.P90 = percentile(0.9)
+	res.P99 = percentile(0.99)
+	res.Max = latencies[len(latencies)-1]
+	return res
+}
@@ -0,0 +1,11 @@
+package feather
+
+// maxCachedPath is the length of the longest path cached, so long paths can't bloat the caches.
+const maxCachedPath = 1024
+
+// redirectTarget is the result of the lowercase and trailing slash fallback lookups of a path.
+type redirectTarget struct {
+	to    string // path redirected to
+	route string // route pattern matching to
+	ok    bool   // false if no route matches any of the fallbacks
+}
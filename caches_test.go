@@ -0,0 +1,74 @@
+package feather
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/pchchv/feather/assert"
+)
+
+func TestRedirectCache(t *testing.T) {
+	p := New()
+	p.SetRedirectCacheSize(10)
+	p.Get("/users/:id", defaultHandler)
+
+	for i := 0; i < 2; i++ {
+		r, _ := http.NewRequest(http.MethodGet, "/USERS/13/", nil)
+		w := httptest.NewRecorder()
+		p.Serve().ServeHTTP(w, r)
+		Equal(t, w.Code, http.StatusMovedPermanently)
+		Equal(t, w.Header().Get("Location"), "/users/13")
+	}
+
+	target, ok := p.redirectCache.Get("GET /USERS/13/")
+	Equal(t, ok, true)
+	Equal(t, target, redirectTarget{to: "/users/13", route: "/users/:id", ok: true})
+
+	for i := 0; i < 2; i++ {
+		code, _ := request(http.MethodGet, "/posts/", p)
+		Equal(t, code, http.StatusNotFound)
+	}
+
+	target, ok = p.redirectCache.Get("GET /posts/")
+	Equal(t, ok, true)
+	Equal(t, target.ok, false)
+
+	p.SetRedirectCacheSize(0)
+	Equal(t, p.redirectCache == nil, true)
+	code, _ := request(http.MethodGet, "/USERS/13/", p)
+	Equal(t, code, http.StatusMovedPermanently)
+}
+
+func TestNotFoundCache(t *testing.T) {
+	p := New()
+	p.SetNotFoundCacheSize(10)
+	p.RegisterMethodNotAllowed()
+	p.Get("/users/:id", defaultHandler)
+	p.Post("/posts", defaultHandler)
+
+	for i := 0; i < 2; i++ {
+		code, _ := request(http.MethodGet, "/missing", p)
+		Equal(t, code, http.StatusNotFound)
+
+		// cached misses are still answered with 405 when another method matches
+		code, _ = request(http.MethodGet, "/posts", p)
+		Equal(t, code, http.StatusMethodNotAllowed)
+	}
+
+	_, ok := p.notFoundCache.Get("GET /missing")
+	Equal(t, ok, true)
+	_, ok = p.notFoundCache.Get("GET /posts")
+	Equal(t, ok, true)
+
+	// paths redirected are not cached
+	code, _ := request(http.MethodGet, "/users/13/", p)
+	Equal(t, code, http.StatusMovedPermanently)
+	_, ok = p.notFoundCache.Get("GET /users/13/")
+	Equal(t, ok, false)
+
+	p.SetNotFoundCacheSize(0)
+	Equal(t, p.notFoundCache == nil, true)
+	code, _ = request(http.MethodGet, "/users/13", p)
+	Equal(t, code, http.StatusOK)
+}
@@ -0,0 +1,66 @@
+// Command feather scaffolds feather projects, generates code from OpenAPI documents
+// and inspects the routes of feather binaries.
+//
+// Usage:
+//
+//	feather new [-dir directory] <module path>
+//	feather routes [-json] [-timeout duration] <binary or manifest.json> [arguments]
+//	feather openapi [-package name] [-interface name] [-o file] <openapi.json>
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+const usage = `Usage:
+
+	feather new [-dir directory] <module path>
+		generates a project skeleton serving health routes, with graceful shutdown,
+		request id, recovery and logging middleware, configured using the environment
+
+	feather routes [-json] [-timeout duration] <binary or manifest.json> [arguments]
+		lists the routes registered by a feather binary, which is started with the arguments, -routes by default,
+		and writes its route manifest to standard output using Mux.WriteRoutes instead of serving,
+		or of a manifest written using Mux.WriteRoutes
+
+	feather openapi [-package name] [-interface name] [-o file] <openapi.json>
+		generates the route registrations, request and response types and the interface
+		implementing the operations of an OpenAPI 3 document, by default to standard output
+`
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run runs the command of args, returning the exit code.
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+
+	var err error
+	switch args[0] {
+	case "new":
+		err = runNew(args[1:], stdout, stderr)
+	case "routes":
+		err = runRoutes(args[1:], stdout, stderr)
+	case "openapi":
+		err = runOpenAPI(args[1:], stdout, stderr)
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(stdout, usage)
+		return 0
+	default:
+		fmt.Fprintf(stderr, "feather: unknown command %q\n\n%s", args[0], usage)
+		return 2
+	}
+
+	if err != nil {
+		fmt.Fprintln(stderr, "feather:", err)
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	. "github.com/pchchv/feather/assert"
+)
+
+func TestNew(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "svc")
+	var stdout, stderr strings.Builder
+	Equal(t, run([]string{"new", "-dir", dir, "example.com/hello/v2"}, &stdout, &stderr), 0)
+	Equal(t, strings.HasPrefix(stdout.String(), "Generated example.com/hello/v2 in "+dir), true)
+
+	mod, err := os.ReadFile(filepath.Join(dir, "go.mod"))
+	Equal(t, err, nil)
+	Equal(t, string(mod), "module example.com/hello/v2\n\ngo 1.24\n")
+
+	ignore, err := os.ReadFile(filepath.Join(dir, ".gitignore"))
+	Equal(t, err, nil)
+	Equal(t, strings.HasPrefix(string(ignore), "/hello\n"), true)
+
+	fset := token.NewFileSet()
+	for _, name := range []string{"main.go", "routes.go"} {
+		_, err = parser.ParseFile(fset, filepath.Join(dir, name), nil, parser.AllErrors)
+		Equal(t, err, nil)
+	}
+
+	routes, err := os.ReadFile(filepath.Join(dir, "routes.go"))
+	Equal(t, err, nil)
+	Equal(t, strings.Contains(string(routes), `"Hello from hello"`), true)
+
+	// existing projects are not overwritten
+	stderr.Reset()
+	Equal(t, run([]string{"new", "-dir", dir, "example.com/hello"}, &stdout, &stderr), 1)
+	Equal(t, stderr.String(), "feather: new: "+dir+" is not empty\n")
+
+	stderr.Reset()
+	Equal(t, run([]string{"new", "-dir", t.TempDir(), "example.com/../hello"}, &stdout, &stderr), 1)
+	Equal(t, stderr.String(), "feather: new: invalid module path \"example.com/../hello\"\n")
+}
+
+func TestRoutes(t *testing.T) {
+	manifest := filepath.Join(t.TempDir(), "routes.json")
+	Equal(t, os.WriteFile(manifest, []byte(`[
+		{"method": "GET", "path": "/healthz"},
+		{"method": "POST", "host": "api.example.com", "path": "/users", "middleware": ["requestid.New", "jwt.New"]}
+	]`), 0o600), nil)
+
+	var stdout, stderr strings.Builder
+	Equal(t, run([]string{"routes", manifest}, &stdout, &stderr), 0)
+	Equal(t, stdout.String(), "METHOD  HOST             PATH      MIDDLEWARE\n"+
+		"GET     *                /healthz  \n"+
+		"POST    api.example.com  /users    requestid.New, jwt.New\n")
+
+	stdout.Reset()
+	Equal(t, run([]string{"routes", "-json", manifest}, &stdout, &stderr), 0)
+	Equal(t, strings.Contains(stdout.String(), `"host": "api.example.com"`), true)
+
+	Equal(t, run([]string{"routes", filepath.Join(t.TempDir(), "missing")}, &stdout, &stderr), 1)
+	Equal(t, run([]string{"unknown"}, &stdout, &stderr), 2)
+}
+
+func TestRoutesOfBinary(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses shell scripts as binaries")
+	}
+
+	dir := t.TempDir()
+	api := filepath.Join(dir, "api")
+	Equal(t, os.WriteFile(api, []byte("#!/bin/sh\n[ \"$1\" = -routes ] && echo '[{\"method\": \"GET\", \"path\": \"/healthz\"}]'\n"), 0o700), nil)
+	blocking := filepath.Join(dir, "blocking")
+	Equal(t, os.WriteFile(blocking, []byte("#!/bin/sh\nexec sleep 10\n"), 0o700), nil)
+
+	var stdout, stderr strings.Builder
+	Equal(t, run([]string{"routes", "-json", api}, &stdout, &stderr), 0)
+	Equal(t, strings.Contains(stdout.String(), `"path": "/healthz"`), true)
+
+	// binaries not writing their manifest are killed
+	stderr.Reset()
+	Equal(t, run([]string{"routes", "-timeout", "100ms", blocking}, &stdout, &stderr), 1)
+	Equal(t, strings.Contains(stderr.String(), "did not write its route manifest within 100ms"), true)
+}
+
+func TestOpenAPI(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "api.go")
+	var stdout, stderr strings.Builder
+	Equal(t, run([]string{"openapi", "-package", "pets", "-o", out, "../../openapi/testdata/petstore.json"}, &stdout, &stderr), 0)
+
+	src, err := os.ReadFile(out)
+	Equal(t, err, nil)
+	Equal(t, strings.Contains(string(src), "package pets\n"), true)
+	_, err = parser.ParseFile(token.NewFileSet(), out, src, parser.AllErrors)
+	Equal(t, err, nil)
+
+	Equal(t, run([]string{"openapi", "../../openapi/testdata/petstore.json"}, &stdout, &stderr), 0)
+	Equal(t, strings.Contains(stdout.String(), "package api\n"), true)
+
+	stderr.Reset()
+	Equal(t, run([]string{"openapi"}, &stdout, &stderr), 1)
+	Equal(t, stderr.String(), "feather: openapi: expected an OpenAPI document, i.e. feather openapi -o api.go openapi.json\n")
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"flag"
+	"fmt"
+	"go/format"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+//go:embed templates/*.tmpl
+var templates embed.FS
+
+// majorVersion matches the major version suffix of module paths.
+var majorVersion = regexp.MustCompile(`^v[0-9]+$`)
+
+// files maps the generated files to their templates.
+var files = []struct {
+	name     string
+	template string
+}{
+	{name: "go.mod", template: "go.mod.tmpl"},
+	{name: "main.go", template: "main.go.tmpl"},
+	{name: "routes.go", template: "routes.go.tmpl"},
+	{name: ".gitignore", template: "gitignore.tmpl"},
+}
+
+// project is the data the templates are executed with.
+type project struct {
+	Module    string
+	Name      string
+	GoVersion string
+}
+
+func runNew(args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("new", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	dir := fs.String("dir", "", "directory the project is generated in, by default the last element of the module path")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 1 {
+		return errors.New("new: expected a module path, i.e. feather new example.com/hello")
+	}
+
+	p := project{Module: fs.Arg(0), Name: path.Base(fs.Arg(0)), GoVersion: "1.24"}
+	if !validModulePath(p.Module) {
+		return fmt.Errorf("new: invalid module path %q", p.Module)
+	}
+
+	// the name of example.com/hello/v2 is hello
+	if majorVersion.MatchString(p.Name) && strings.Contains(p.Module, "/") {
+		p.Name = path.Base(path.Dir(p.Module))
+	}
+
+	if *dir == "" {
+		*dir = p.Name
+	}
+
+	if entries, err := os.ReadDir(*dir); err == nil && len(entries) > 0 {
+		return fmt.Errorf("new: %s is not empty", *dir)
+	}
+
+	if err := generate(*dir, p); err != nil {
+		return fmt.Errorf("new: %w", err)
+	}
+
+	fmt.Fprintf(stdout, "Generated %s in %s, to run it:\n\n\tcd %s\n\tgo mod tidy\n\tgo run .\n", p.Module, *dir, *dir)
+	return nil
+}
+
+// validModulePath reports whether the module path consists of non-empty elements of
+// ASCII letters, digits and the characters '.', '-', '_' and '~', not starting or ending with a dot.
+func validModulePath(p string) bool {
+	for _, elem := range strings.Split(p, "/") {
+		if elem == "" || elem[0] == '.' || elem[len(elem)-1] == '.' {
+			return false
+		}
+
+		for _, c := range elem {
+			if !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || strings.ContainsRune(".-_~", c)) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// generate writes the project files to dir.
+func generate(dir string, p project) error {
+	tmpl, err := template.ParseFS(templates, "templates/*.tmpl")
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	for _, f := range files {
+		var b bytes.Buffer
+		if err = tmpl.ExecuteTemplate(&b, f.template, p); err != nil {
+			return err
+		}
+
+		src := b.Bytes()
+		if strings.HasSuffix(f.name, ".go") {
+			if src, err = format.Source(src); err != nil {
+				return fmt.Errorf("%s: %w", f.name, err)
+			}
+		}
+
+		if err = os.WriteFile(filepath.Join(dir, f.name), src, 0o644); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/pchchv/feather/openapi"
+)
+
+func runOpenAPI(args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("openapi", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	var cfg openapi.Config
+	fs.StringVar(&cfg.Package, "package", "api", "name of the generated package")
+	fs.StringVar(&cfg.Interface, "interface", "API", "name of the generated interface implementing the operations")
+	out := fs.String("o", "", "file the code is written to, by default standard output")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 1 {
+		return errors.New("openapi: expected an OpenAPI document, i.e. feather openapi -o api.go openapi.json")
+	}
+
+	spec, err := os.ReadFile(fs.Arg(0))
+	if err != nil {
+		return fmt.Errorf("openapi: %w", err)
+	}
+
+	src, err := openapi.Generate(spec, cfg)
+	if err != nil {
+		return err
+	}
+
+	if *out == "" {
+		_, err = stdout.Write(src)
+		return err
+	}
+
+	return os.WriteFile(*out, src, 0o644)
+}
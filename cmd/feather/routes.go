@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pchchv/feather"
+)
+
+func runRoutes(args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("routes", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	asJSON := fs.Bool("json", false, "print the route manifest as JSON")
+	timeout := fs.Duration("timeout", 10*time.Second, "maximum time the binary has to write its route manifest")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() == 0 {
+		return errors.New("routes: expected a binary or a manifest, i.e. feather routes ./bin/api")
+	}
+
+	var data []byte
+	var err error
+	if name := fs.Arg(0); strings.HasSuffix(name, ".json") {
+		data, err = os.ReadFile(name)
+	} else {
+		data, err = manifestOf(name, fs.Args()[1:], *timeout, stderr)
+	}
+	if err != nil {
+		return fmt.Errorf("routes: %w", err)
+	}
+
+	var routes []feather.RouteInfo
+	if err = json.Unmarshal(data, &routes); err != nil {
+		return fmt.Errorf("routes: invalid manifest: %w", err)
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(routes)
+	}
+
+	return printRoutes(stdout, routes)
+}
+
+// manifestOf runs the binary with the arguments, -routes by default, for it to write its route manifest
+// to standard output using Mux.WriteRoutes instead of serving, and returns the manifest.
+// The binary is killed if it doesn't exit within the timeout.
+func manifestOf(binary string, args []string, timeout time.Duration, stderr io.Writer) ([]byte, error) {
+	if len(args) == 0 {
+		args = []string{"-routes"}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var stdout bytes.Buffer
+	cmd := exec.CommandContext(ctx, binary, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = stderr
+	// children of the binary may keep its output open
+	cmd.WaitDelay = time.Second
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("%s did not write its route manifest within %s, does it call Mux.WriteRoutes when started with %s?", binary, timeout, strings.Join(args, " "))
+		}
+
+		return nil, fmt.Errorf("running %s: %w", binary, err)
+	}
+
+	return stdout.Bytes(), nil
+}
+
+// printRoutes prints the routes as a table.
+func printRoutes(w io.Writer, routes []feather.RouteInfo) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "METHOD\tHOST\tPATH\tMIDDLEWARE")
+	for _, r := range routes {
+		host := r.Host
+		if host == "" {
+			host = "*"
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", r.Method, host, r.Path, strings.Join(r.Middleware, ", "))
+	}
+
+	return tw.Flush()
+}
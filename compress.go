@@ -0,0 +1,120 @@
+package feather
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// ErrUnsupportedContentEncoding is returned when decoding a body using a Content-Encoding
+// no decompressor is registered for.
+var ErrUnsupportedContentEncoding = errors.New("feather: unsupported Content-Encoding")
+
+// DecompressorFunc returns a reader decompressing the body.
+type DecompressorFunc func(body io.Reader) (io.ReadCloser, error)
+
+var (
+	decompressorsMu sync.RWMutex
+	decompressors   = map[string]DecompressorFunc{
+		gzipVal:   decompressGzip,
+		"x-gzip":  decompressGzip,
+		"deflate": decompressDeflate,
+	}
+)
+
+// RegisterDecompressor registers a decompressor used when decoding request bodies with the Content-Encoding,
+// gzip and deflate are supported by default. Other encodings, such as br or zstd, can be added using
+// third party packages, i.e.
+//
+//	feather.RegisterDecompressor("zstd", func(body io.Reader) (io.ReadCloser, error) {
+//		d, err := zstd.NewReader(body)
+//		if err != nil {
+//			return nil, err
+//		}
+//		return d.IOReadCloser(), nil
+//	})
+//
+// A nil fn removes the decompressor of the encoding.
+func RegisterDecompressor(encoding string, fn DecompressorFunc) {
+	decompressorsMu.Lock()
+	defer decompressorsMu.Unlock()
+	if fn == nil {
+		delete(decompressors, strings.ToLower(encoding))
+	} else {
+		decompressors[strings.ToLower(encoding)] = fn
+	}
+}
+
+// decompress returns the body decoded according to the Content-Encoding of the headers,
+// encodings are listed in the order they were applied, so they are removed in reverse.
+// The returned func closes the decompressors.
+func decompress(headers http.Header, body io.Reader) (io.Reader, func(), error) {
+	encodings := headers.Values(contentEncodingHeader)
+	if len(encodings) == 0 {
+		return body, func() {}, nil
+	}
+
+	var names []string
+	for _, v := range encodings {
+		for _, name := range strings.Split(v, ",") {
+			if name = strings.ToLower(strings.TrimSpace(name)); name != blank && name != "identity" {
+				names = append(names, name)
+			}
+		}
+	}
+
+	var closers []io.Closer
+	closeAll := func() {
+		for i := len(closers) - 1; i >= 0; i-- {
+			_ = closers[i].Close()
+		}
+	}
+
+	decompressorsMu.RLock()
+	defer decompressorsMu.RUnlock()
+	for i := len(names) - 1; i >= 0; i-- {
+		fn := decompressors[names[i]]
+		if fn == nil {
+			closeAll()
+			return nil, nil, ErrUnsupportedContentEncoding
+		}
+
+		rc, err := fn(body)
+		if err != nil {
+			closeAll()
+			return nil, nil, err
+		}
+
+		closers = append(closers, rc)
+		body = rc
+	}
+
+	return body, closeAll, nil
+}
+
+func decompressGzip(body io.Reader) (io.ReadCloser, error) {
+	return gzip.NewReader(body)
+}
+
+// decompressDeflate decompresses zlib wrapped deflate streams as specified for HTTP,
+// as well as the raw deflate streams some clients send instead.
+func decompressDeflate(body io.Reader) (io.ReadCloser, error) {
+	br := bufio.NewReader(body)
+	header, err := br.Peek(2)
+	if err != nil {
+		return nil, err
+	}
+
+	// a zlib header uses the deflate method and is a multiple of 31
+	if header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+		return zlib.NewReader(br)
+	}
+
+	return flate.NewReader(br), nil
+}
@@ -0,0 +1,80 @@
+package feather
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"testing"
+
+	. "github.com/pchchv/feather/assert"
+)
+
+func TestDecompress(t *testing.T) {
+	type test struct {
+		ID string `json:"id"`
+	}
+
+	body := []byte(`{"id":"joeybloggs"}`)
+	compress := func(b []byte, fn func(io.Writer) io.WriteCloser) []byte {
+		var buf bytes.Buffer
+		w := fn(&buf)
+		_, _ = w.Write(b)
+		_ = w.Close()
+		return buf.Bytes()
+	}
+	gz := func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) }
+	zl := func(w io.Writer) io.WriteCloser { return zlib.NewWriter(w) }
+	raw := func(w io.Writer) io.WriteCloser {
+		fw, _ := flate.NewWriter(w, flate.DefaultCompression)
+		return fw
+	}
+
+	tests := []struct {
+		encoding string
+		body     []byte
+		err      error
+	}{
+		{"", body, nil},
+		{"identity", body, nil},
+		{"gzip", compress(body, gz), nil},
+		{"x-gzip", compress(body, gz), nil},
+		{"deflate", compress(body, zl), nil},
+		{"deflate", compress(body, raw), nil},
+		{"deflate, GZIP", compress(compress(body, zl), gz), nil},
+		{"br", body, ErrUnsupportedContentEncoding},
+	}
+
+	for _, tt := range tests {
+		r, _ := http.NewRequest(http.MethodPost, "/", bytes.NewReader(tt.body))
+		r.Header.Set(contentTypeHeader, applicationJSON)
+		r.Header.Set(contentEncodingHeader, tt.encoding)
+
+		var v test
+		err := Decode(r, noQueryParams, 1<<20, &v)
+		Equal(t, err, tt.err)
+		if tt.err == nil {
+			Equal(t, v.ID, "joeybloggs")
+		}
+	}
+
+	// the body is limited after decompression
+	r, _ := http.NewRequest(http.MethodPost, "/", bytes.NewReader(compress(body, gz)))
+	r.Header.Set(contentTypeHeader, applicationJSON)
+	r.Header.Set(contentEncodingHeader, gzipVal)
+	NotEqual(t, Decode(r, noQueryParams, 10, &test{}), nil)
+
+	RegisterDecompressor("BR", func(body io.Reader) (io.ReadCloser, error) {
+		return io.NopCloser(body), nil
+	})
+	defer RegisterDecompressor("br", nil)
+
+	r, _ = http.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+	r.Header.Set(contentTypeHeader, applicationJSON)
+	r.Header.Set(contentEncodingHeader, "br")
+	var v test
+	Equal(t, Decode(r, noQueryParams, 1<<20, &v), nil)
+	Equal(t, v.ID, "joeybloggs")
+}
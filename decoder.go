@@ -0,0 +1,46 @@
+package feather
+
+import (
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// DecoderFunc decodes the request body into v.
+type DecoderFunc func(body io.Reader, v interface{}) error
+
+var (
+	decodersMu sync.RWMutex
+	decoders   = make(map[string]DecoderFunc)
+	// hasDecoders avoids locking in Decode while no decoders are registered
+	hasDecoders atomic.Bool
+)
+
+// RegisterDecoder registers a decoder used by Decode for requests with the media type as Content-Type,
+// i.e. vendor types such as application/vnd.myco+json.
+// Registered decoders are consulted before the built-in ones, so they can also replace the JSON, XML
+// and form decoding of Decode, the type specific functions such as DecodeJSON are not affected.
+// Like JSON and XML the body is decompressed according to its Content-Encoding and limited to maxMemory,
+// and query params are merged afterwards according to the QueryParamsOption.
+// A nil fn removes the decoder of the media type.
+func RegisterDecoder(mediaType string, fn DecoderFunc) {
+	decodersMu.Lock()
+	defer decodersMu.Unlock()
+	if fn == nil {
+		delete(decoders, strings.ToLower(mediaType))
+	} else {
+		decoders[strings.ToLower(mediaType)] = fn
+	}
+	hasDecoders.Store(len(decoders) > 0)
+}
+
+func registeredDecoder(mediaType string) DecoderFunc {
+	if !hasDecoders.Load() {
+		return nil
+	}
+
+	decodersMu.RLock()
+	defer decodersMu.RUnlock()
+	return decoders[strings.ToLower(strings.TrimSpace(mediaType))]
+}
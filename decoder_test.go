@@ -0,0 +1,123 @@
+package feather
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	. "github.com/pchchv/feather/assert"
+)
+
+func TestRegisterDecoder(t *testing.T) {
+	type TestStruct struct {
+		ID     int `form:"id"`
+		Posted string
+	}
+
+	RegisterDecoder("application/x-test-text", func(body io.Reader, v interface{}) error {
+		b, err := io.ReadAll(body)
+		if err != nil {
+			return err
+		}
+
+		v.(*TestStruct).Posted = string(b)
+		return nil
+	})
+
+	var test TestStruct
+	p := New()
+	p.Post("/decode/:id", func(w http.ResponseWriter, r *http.Request) {
+		test = TestStruct{}
+		err := Decode(r, httpQueryParams, 64, &test)
+		Equal(t, err, nil)
+	})
+	p.Post("/decode-noquery/:id", func(w http.ResponseWriter, r *http.Request) {
+		test = TestStruct{}
+		err := Decode(r, noQueryParams, 64, &test)
+		Equal(t, err, nil)
+	})
+
+	hf := p.Serve()
+	r, _ := http.NewRequest(http.MethodPost, "/decode/13", strings.NewReader("posted value"))
+	r.Header.Set(contentTypeHeader, "application/x-test-text; charset=utf-8")
+	w := httptest.NewRecorder()
+	hf.ServeHTTP(w, r)
+	Equal(t, w.Code, http.StatusOK)
+	Equal(t, test.ID, 13)
+	Equal(t, test.Posted, "posted value")
+
+	r, _ = http.NewRequest(http.MethodPost, "/decode-noquery/13", strings.NewReader("posted"))
+	r.Header.Set(contentTypeHeader, "Application/X-Test-Text")
+	w = httptest.NewRecorder()
+	hf.ServeHTTP(w, r)
+	Equal(t, w.Code, http.StatusOK)
+	Equal(t, test.ID, 0)
+	Equal(t, test.Posted, "posted")
+
+	var buf bytes.Buffer
+	gzw := gzip.NewWriter(&buf)
+	_, _ = gzw.Write([]byte("gzipped"))
+	_ = gzw.Close()
+	r, _ = http.NewRequest(http.MethodPost, "/decode/13", &buf)
+	r.Header.Set(contentTypeHeader, "application/x-test-text")
+	r.Header.Set(contentEncodingHeader, gzipVal)
+	w = httptest.NewRecorder()
+	hf.ServeHTTP(w, r)
+	Equal(t, w.Code, http.StatusOK)
+	Equal(t, test.ID, 13)
+	Equal(t, test.Posted, "gzipped")
+}
+
+func TestRegisterDecoderPrecedence(t *testing.T) {
+	type TestStruct struct {
+		ID     int `form:"id"`
+		Posted string
+	}
+
+	RegisterDecoder("application/vnd.myco+json", func(body io.Reader, v interface{}) error {
+		return json.NewDecoder(body).Decode(v)
+	})
+	RegisterDecoder(nakedApplicationJSON, func(body io.Reader, v interface{}) error {
+		v.(*TestStruct).Posted = "overridden"
+		return nil
+	})
+	defer RegisterDecoder("application/vnd.myco+json", nil)
+
+	var test TestStruct
+	p := New()
+	p.Post("/decode/:id", func(w http.ResponseWriter, r *http.Request) {
+		test = TestStruct{}
+		err := Decode(r, httpQueryParams, 64, &test)
+		Equal(t, err, nil)
+	})
+
+	hf := p.Serve()
+	r, _ := http.NewRequest(http.MethodPost, "/decode/13", strings.NewReader(`{"Posted":"value"}`))
+	r.Header.Set(contentTypeHeader, "application/vnd.myco+json")
+	w := httptest.NewRecorder()
+	hf.ServeHTTP(w, r)
+	Equal(t, w.Code, http.StatusOK)
+	Equal(t, test.ID, 13)
+	Equal(t, test.Posted, "value")
+
+	r, _ = http.NewRequest(http.MethodPost, "/decode/13", strings.NewReader(`{"Posted":"value"}`))
+	r.Header.Set(contentTypeHeader, applicationJSON)
+	w = httptest.NewRecorder()
+	hf.ServeHTTP(w, r)
+	Equal(t, w.Code, http.StatusOK)
+	Equal(t, test.Posted, "overridden")
+
+	// removing the decoder restores the built-in one
+	RegisterDecoder(nakedApplicationJSON, nil)
+	r, _ = http.NewRequest(http.MethodPost, "/decode/13", strings.NewReader(`{"Posted":"value"}`))
+	r.Header.Set(contentTypeHeader, applicationJSON)
+	w = httptest.NewRecorder()
+	hf.ServeHTTP(w, r)
+	Equal(t, w.Code, http.StatusOK)
+	Equal(t, test.Posted, "value")
+}
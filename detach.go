@@ -0,0 +1,30 @@
+package feather
+
+import (
+	"context"
+	"net/http"
+)
+
+// detachedContext hides the pooled requestVars from the values of the request context.
+type detachedContext struct {
+	context.Context
+}
+
+func (c detachedContext) Value(key any) any {
+	if key == defaultContextIdentifier {
+		return nil
+	}
+
+	return c.Context.Value(key)
+}
+
+// Detach returns a context for goroutines outliving the request, i.e.
+//
+//	go audit(feather.Detach(r), event)
+//
+// The context carries the values of the request context, such as the request id, principal or locale set by middleware,
+// but is neither canceled nor has a deadline once the request completes.
+// The pooled request variables are not carried, since they are reused by other requests.
+func Detach(r *http.Request) context.Context {
+	return detachedContext{Context: context.WithoutCancel(r.Context())}
+}
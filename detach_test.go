@@ -0,0 +1,35 @@
+package feather
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	. "github.com/pchchv/feather/assert"
+)
+
+func TestDetach(t *testing.T) {
+	type principalKey struct{}
+
+	var ctx, reqCtx context.Context
+	p := New()
+	p.Use(func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			next(w, r.WithContext(context.WithValue(r.Context(), principalKey{}, "joeybloggs")))
+		}
+	})
+	p.WithMeta(Meta{Timeout: time.Minute}).Get("/users/:id", func(w http.ResponseWriter, r *http.Request) {
+		ctx, reqCtx = Detach(r), r.Context()
+	})
+
+	code, _ := request(http.MethodGet, "/users/13", p)
+	Equal(t, code, http.StatusOK)
+	Equal(t, reqCtx.Err(), context.Canceled)
+	Equal(t, ctx.Err(), nil)
+	Equal(t, ctx.Done() == nil, true)
+	_, ok := ctx.Deadline()
+	Equal(t, ok, false)
+	Equal(t, ctx.Value(principalKey{}), "joeybloggs")
+	Equal(t, ctx.Value(defaultContextIdentifier), nil)
+}
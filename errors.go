@@ -0,0 +1,167 @@
+package feather
+
+import (
+	"errors"
+	"html"
+	"net/http"
+	"runtime"
+	"strconv"
+)
+
+// HTTPError is an error carrying the response status code and the message sent to the client.
+// Internal, if set, is the underlying error which is logged but never sent to the client.
+type HTTPError struct {
+	Code     int
+	Message  string
+	Internal error
+}
+
+// PanicError is the error value of a panic recovered by HandleErrors,
+// so the ErrorHandler and middleware, see HandledError, can tell panics from the returned errors.
+type PanicError struct {
+	Err error
+}
+
+// Error returns the error of the panic.
+func (e *PanicError) Error() string {
+	return "panic: " + e.Err.Error()
+}
+
+// Unwrap returns the error of the panic.
+func (e *PanicError) Unwrap() error {
+	return e.Err
+}
+
+// NewHTTPError returns an HTTPError with the status code and message,
+// the status text of the code is used if message is blank.
+func NewHTTPError(code int, message string) *HTTPError {
+	if message == blank {
+		message = http.StatusText(code)
+	}
+
+	return &HTTPError{Code: code, Message: message}
+}
+
+// Error returns the status code and message followed by the internal error, if any.
+func (e *HTTPError) Error() string {
+	s := strconv.Itoa(e.Code) + " " + e.Message
+	if e.Internal != nil {
+		s += ": " + e.Internal.Error()
+	}
+
+	return s
+}
+
+// Unwrap returns the internal error.
+func (e *HTTPError) Unwrap() error {
+	return e.Internal
+}
+
+// WithInternal returns a copy of the error with the internal error set.
+func (e *HTTPError) WithInternal(err error) *HTTPError {
+	c := *e
+	c.Internal = err
+	return &c
+}
+
+// HandlerFuncE is a handler returning an error, see HandleErrors.
+type HandlerFuncE func(w http.ResponseWriter, r *http.Request) error
+
+// ErrorHandler responds to the errors returned by HandlerFuncE handlers.
+type ErrorHandler func(w http.ResponseWriter, r *http.Request, err error)
+
+// HandleErrors adapts a handler returning errors to an http.HandlerFunc, i.e.
+//
+//	p.Get("/users/:id", feather.HandleErrors(func(w http.ResponseWriter, r *http.Request) error {
+//		u, ok := users[feather.RequestVars(r).URLParam("id")]
+//		if !ok {
+//			return feather.NewHTTPError(http.StatusNotFound, "user not found")
+//		}
+//		return feather.JSON(w, http.StatusOK, u)
+//	}))
+//
+// The returned errors and panics with an error value, wrapped in a PanicError, are passed to the ErrorHandler set using
+// Mux.SetErrorHandler, DefaultErrorHandler if none. Panics with other values, runtime errors,
+// i.e. a nil map write, and http.ErrAbortHandler are re-panicked for the recovery middleware and net/http,
+// so bugs are reported with their stack.
+func HandleErrors(h HandlerFuncE) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			if v := recover(); v != nil {
+				err, ok := v.(error)
+				if _, bug := v.(runtime.Error); !ok || bug || errors.Is(err, http.ErrAbortHandler) {
+					panic(v)
+				}
+
+				handleError(w, r, &PanicError{Err: err})
+			}
+		}()
+
+		if err := h(w, r); err != nil {
+			handleError(w, r, err)
+		}
+	}
+}
+
+// SetErrorHandler sets the handler responding to the errors returned by HandlerFuncE handlers,
+// see HandleErrors.
+// Default is DefaultErrorHandler.
+func (p *Mux) SetErrorHandler(h ErrorHandler) {
+	p.errorHandler = h
+}
+
+// handleError passes err to the ErrorHandler of the Mux serving the request.
+func handleError(w http.ResponseWriter, r *http.Request, err error) {
+	rv, ok := requestVarsOf(r)
+	if ok {
+		rv.err = err
+	}
+
+	if ok && rv.mux.errorHandler != nil {
+		rv.mux.errorHandler(w, r, err)
+		return
+	}
+
+	DefaultErrorHandler(w, r, err)
+}
+
+// HandledError returns the last error of the request passed to the ErrorHandler, nil if none,
+// so middleware such as tracing and metrics can report the errors once the handler returns.
+func HandledError(r *http.Request) error {
+	if rv, ok := requestVarsOf(r); ok {
+		return rv.err
+	}
+
+	return nil
+}
+
+// DefaultErrorHandler responds with the status code and message of HTTPErrors, and with
+// 500 Internal Server Error for other errors, as JSON if preferred by the Accept header and HTML otherwise.
+// Errors other than HTTPErrors and the internal errors of 5xx HTTPErrors are logged using Logger,
+// and if the response was already started the error is only logged.
+func DefaultErrorHandler(w http.ResponseWriter, r *http.Request, err error) {
+	code, message := http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError)
+	var he *HTTPError
+	if errors.As(err, &he) {
+		code, message = he.Code, he.Message
+	}
+
+	if he == nil || (code >= http.StatusInternalServerError && he.Internal != nil) {
+		Logger(r).Error("handler error", "status", code, "error", err)
+	}
+
+	if rw, ok := ResponseWriterOf(w); ok && rw.Written() {
+		return
+	}
+
+	if NegotiateContentType(r, applicationJSONNoCharset, textHTMLNoCharset) == textHTMLNoCharset {
+		text := html.EscapeString(message)
+		w.Header().Set(contentTypeHeader, textHTML)
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.WriteHeader(code)
+		_, _ = w.Write([]byte("<!DOCTYPE html>\n<html><head><title>" + text + "</title></head><body><h1>" + text + "</h1></body></html>\n"))
+		return
+	}
+
+	_ = JSON(w, code, map[string]string{"error": message})
+}
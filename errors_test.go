@@ -0,0 +1,137 @@
+package feather
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	. "github.com/pchchv/feather/assert"
+)
+
+func TestHTTPError(t *testing.T) {
+	internal := errors.New("connection refused")
+	err := NewHTTPError(http.StatusServiceUnavailable, blank).WithInternal(internal)
+	Equal(t, err.Message, "Service Unavailable")
+	Equal(t, err.Error(), "503 Service Unavailable: connection refused")
+	Equal(t, errors.Is(err, internal), true)
+	Equal(t, NewHTTPError(http.StatusNotFound, "user not found").Error(), "404 user not found")
+}
+
+func TestHandleErrors(t *testing.T) {
+	p := New()
+	p.SetLogger(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	p.Get("/ok", HandleErrors(func(w http.ResponseWriter, r *http.Request) error {
+		return JSON(w, http.StatusOK, "ok")
+	}))
+	p.Get("/missing", HandleErrors(func(w http.ResponseWriter, r *http.Request) error {
+		return NewHTTPError(http.StatusNotFound, "user <b>not</b> found")
+	}))
+	p.Get("/wrapped", HandleErrors(func(w http.ResponseWriter, r *http.Request) error {
+		return errors.Join(errors.New("lookup"), NewHTTPError(http.StatusConflict, blank))
+	}))
+	p.Get("/plain", HandleErrors(func(w http.ResponseWriter, r *http.Request) error {
+		return errors.New("secret database details")
+	}))
+	p.Get("/panic", HandleErrors(func(w http.ResponseWriter, r *http.Request) error {
+		panic(NewHTTPError(http.StatusForbidden, blank))
+	}))
+	p.Get("/started", HandleErrors(func(w http.ResponseWriter, r *http.Request) error {
+		w.WriteHeader(http.StatusAccepted)
+		return errors.New("too late")
+	}))
+	p.Get("/panic-value", HandleErrors(func(w http.ResponseWriter, r *http.Request) error {
+		panic("not an error")
+	}))
+	p.Get("/panic-bug", HandleErrors(func(w http.ResponseWriter, r *http.Request) error {
+		var m map[string]int
+		m["bug"]++
+		return nil
+	}))
+
+	tests := []struct {
+		path   string
+		accept string
+		code   int
+		body   string
+	}{
+		{path: "/ok", code: http.StatusOK, body: "\"ok\""},
+		{path: "/missing", code: http.StatusNotFound, body: "{\"error\":\"user \\u003cb\\u003enot\\u003c/b\\u003e found\"}"},
+		{path: "/missing", accept: "text/html,*/*;q=0.8", code: http.StatusNotFound, body: "<!DOCTYPE html>\n<html><head><title>user &lt;b&gt;not&lt;/b&gt; found</title></head><body><h1>user &lt;b&gt;not&lt;/b&gt; found</h1></body></html>\n"},
+		{path: "/wrapped", accept: "application/json", code: http.StatusConflict, body: "{\"error\":\"Conflict\"}"},
+		{path: "/plain", code: http.StatusInternalServerError, body: "{\"error\":\"Internal Server Error\"}"},
+		{path: "/panic", code: http.StatusForbidden, body: "{\"error\":\"Forbidden\"}"},
+		{path: "/started", code: http.StatusAccepted, body: ""},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		if tt.accept != blank {
+			r.Header.Set("Accept", tt.accept)
+		}
+
+		w := httptest.NewRecorder()
+		p.Serve().ServeHTTP(w, r)
+		Equal(t, w.Code, tt.code)
+		Equal(t, w.Body.String(), tt.body)
+	}
+
+	PanicsWithValue(t, func() { request(http.MethodGet, "/panic-value", p) }, "not an error")
+
+	// runtime errors are bugs, left to the recovery middleware
+	PanicMatches(t, func() { request(http.MethodGet, "/panic-bug", p) }, "assignment to entry in nil map")
+}
+
+func TestSetErrorHandler(t *testing.T) {
+	p := New()
+	p.SetErrorHandler(func(w http.ResponseWriter, r *http.Request, err error) {
+		w.WriteHeader(http.StatusTeapot)
+		_, _ = w.Write([]byte(err.Error()))
+	})
+	p.Get("/", HandleErrors(func(w http.ResponseWriter, r *http.Request) error {
+		return errors.New("custom")
+	}))
+
+	code, body := request(http.MethodGet, "/", p)
+	Equal(t, code, http.StatusTeapot)
+	Equal(t, strings.TrimSpace(body), "custom")
+}
+
+func TestHandledError(t *testing.T) {
+	var handled []error
+	p := New()
+	p.SetErrorHandler(func(w http.ResponseWriter, r *http.Request, err error) {
+		w.WriteHeader(http.StatusBadGateway)
+	})
+	p.Use(func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			next(w, r)
+			handled = append(handled, HandledError(r))
+		}
+	})
+	errUpstream := errors.New("upstream")
+	p.Get("/error", HandleErrors(func(w http.ResponseWriter, r *http.Request) error {
+		return errUpstream
+	}))
+	p.Get("/ok", HandleErrors(func(w http.ResponseWriter, r *http.Request) error {
+		return nil
+	}))
+
+	p.Get("/panic", HandleErrors(func(w http.ResponseWriter, r *http.Request) error {
+		panic(errUpstream)
+	}))
+
+	request(http.MethodGet, "/error", p)
+	request(http.MethodGet, "/ok", p)
+	request(http.MethodGet, "/panic", p)
+	Equal(t, handled[:2], []error{errUpstream, nil})
+
+	// recovered panics are told apart from the returned errors
+	var pe *PanicError
+	Equal(t, errors.As(handled[2], &pe), true)
+	Equal(t, errors.Is(handled[2], errUpstream), true)
+	Equal(t, HandledError(httptest.NewRequest(http.MethodGet, "/", nil)), nil)
+}
@@ -11,7 +11,7 @@ func main() {
 	p := feather.New()
 	p.Use(lr.LoggingAndRecovery(false))
 	p.Get("/", helloWorld)
-	http.ListenAndServe(":3007", p.Serve())
+	p.ListenAndServeGracefully(":3007")
 }
 
 func helloWorld(w http.ResponseWriter, r *http.Request) {
@@ -5,11 +5,11 @@ import (
 	"log"
 	"net"
 	"net/http"
-	"runtime"
 	"sync"
 	"time"
 
 	"github.com/pchchv/feather"
+	"github.com/pchchv/feather/middlewares/recovery"
 )
 
 const (
@@ -83,13 +83,16 @@ func (lw *logWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
 
 // HandlePanic handles graceful panic by redirecting to friendly error page or rendering a friendly error page.
 // trace passed just in case you want rendered to developer when not running in production.
-func HandlePanic(w http.ResponseWriter, r *http.Request, trace []byte) {
+func HandlePanic(w http.ResponseWriter, r *http.Request, err interface{}, trace []byte) {
 	// redirect to or directly render friendly error page
+	recovery.DefaultHandler(w, r, err, trace)
 }
 
-// LoggingAndRecovery handle HTTP request logging + recovery.
+// LoggingAndRecovery handle HTTP request logging + recovery, the recovery is done by middlewares/recovery.
 func LoggingAndRecovery(color bool) feather.Middleware {
+	recoverer := recovery.New(recovery.Config{Handler: HandlePanic, Stack: true})
 	return func(next http.HandlerFunc) http.HandlerFunc {
+		next = recoverer(next)
 		if color {
 			return func(w http.ResponseWriter, r *http.Request) {
 				t1 := time.Now()
@@ -98,19 +101,6 @@ func LoggingAndRecovery(color bool) feather.Middleware {
 				lw.size = 0
 				lw.committed = false
 				lw.ResponseWriter = w
-				defer func() {
-					if err := recover(); err != nil {
-						trace := make([]byte, 1<<16)
-						n := runtime.Stack(trace, true)
-						log.Printf(" %srecovering from panic: %+v\nStack Trace:\n %s%s", red, err, trace[:n], reset)
-						HandlePanic(lw, r, trace[:n])
-						lrpool.Put(lw)
-						return
-					}
-
-					lrpool.Put(lw)
-				}()
-
 				next(lw, r)
 
 				color := status
@@ -125,6 +115,7 @@ func LoggingAndRecovery(color bool) feather.Middleware {
 				}
 
 				log.Printf("%s %d %s[%s%s%s] %q %v %d\n", color, code, reset, color, r.Method, reset, r.URL, time.Since(t1), lw.Size())
+				lrpool.Put(lw)
 			}
 		}
 
@@ -135,20 +126,10 @@ func LoggingAndRecovery(color bool) feather.Middleware {
 			lw.size = 0
 			lw.committed = false
 			lw.ResponseWriter = w
-			defer func() {
-				if err := recover(); err != nil {
-					trace := make([]byte, 1<<16)
-					n := runtime.Stack(trace, true)
-					log.Printf(" %srecovering from panic: %+v\nStack Trace:\n %s%s", red, err, trace[:n], reset)
-					HandlePanic(lw, r, trace[:n])
-				}
-
-				lrpool.Put(lw)
-			}()
-
 			next(lw, r)
 
 			log.Printf("%d [%s] %q %v %d\n", lw.Status(), r.Method, r.URL, time.Since(t1), lw.Size())
+			lrpool.Put(lw)
 		}
 
 	}
@@ -12,7 +12,7 @@ func main() {
 	p.Use(lr.LoggingAndRecovery(true))
 	p.Get("/user/:id", user)
 
-	http.ListenAndServe(":3007", p.Serve())
+	p.ListenAndServeGracefully(":3007")
 }
 
 func user(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,135 @@
+package export
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/pchchv/feather"
+)
+
+const (
+	contentDispositionHeader = "Content-Disposition"
+	contentTypeHeader        = "Content-Type"
+	textCSV                  = "text/csv; charset=utf-8"
+	defaultFlushRows         = 100
+)
+
+// Rows is a row iterator used to feed the exporters.
+// Next returns io.EOF once all rows have been produced.
+type Rows interface {
+	Next() ([]string, error)
+}
+
+// RowsFunc is an adapter allowing the use of an ordinary function as Rows.
+type RowsFunc func() ([]string, error)
+
+// Next calls f().
+func (f RowsFunc) Next() ([]string, error) {
+	return f()
+}
+
+// SliceRows returns Rows iterating over an in-memory set of rows.
+func SliceRows(rows [][]string) Rows {
+	var i int
+	return RowsFunc(func() ([]string, error) {
+		if i >= len(rows) {
+			return nil, io.EOF
+		}
+
+		i++
+		return rows[i-1], nil
+	})
+}
+
+// Options contains the optional settings shared by the exporters.
+type Options struct {
+	// Header is written as the first row when not empty.
+	Header []string
+	// FlushRows is the number of rows written between flushes of the response,
+	// so slow clients apply backpressure to the row producer instead of the
+	// response being buffered in memory. Defaults to 100.
+	FlushRows int
+}
+
+func (o *Options) flushRows() int {
+	if o == nil || o.FlushRows <= 0 {
+		return defaultFlushRows
+	}
+
+	return o.FlushRows
+}
+
+func (o *Options) header() []string {
+	if o == nil {
+		return nil
+	}
+
+	return o.Header
+}
+
+// CSV streams the provided rows to the client as a CSV attachment.
+//
+// Rows are pulled one at a time and the response is flushed every Options.FlushRows rows.
+// Streaming stops with the request context's error if the client goes away.
+func CSV(w http.ResponseWriter, r *http.Request, filename string, rows Rows, opts *Options) (err error) {
+	setAttachmentHeaders(w, filename, textCSV)
+	w.WriteHeader(http.StatusOK)
+	cw := csv.NewWriter(w)
+	if h := opts.header(); len(h) > 0 {
+		if err = cw.Write(h); err != nil {
+			return
+		}
+	}
+
+	err = stream(w, r, rows, opts.flushRows(), cw.Write, func() error {
+		cw.Flush()
+		return cw.Error()
+	})
+	if err != nil {
+		return
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// stream pulls rows, passing each to write and calling flush every n rows.
+func stream(w http.ResponseWriter, r *http.Request, rows Rows, n int, write func([]string) error, flush func() error) (err error) {
+	ctx := r.Context()
+	rc := http.NewResponseController(w)
+	for i := 1; ; i++ {
+		if err = ctx.Err(); err != nil {
+			return
+		}
+
+		var row []string
+		if row, err = rows.Next(); err != nil {
+			if err == io.EOF {
+				err = nil
+			}
+			return
+		}
+
+		if err = write(row); err != nil {
+			return
+		}
+
+		if i%n == 0 {
+			if err = flush(); err != nil {
+				return
+			}
+
+			if err = rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+				return
+			}
+			err = nil
+		}
+	}
+}
+
+func setAttachmentHeaders(w http.ResponseWriter, filename string, contentType string) {
+	w.Header().Set(contentDispositionHeader, feather.ContentDisposition("attachment", filename))
+	w.Header().Set(contentTypeHeader, contentType)
+}
@@ -0,0 +1,85 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/pchchv/feather"
+	. "github.com/pchchv/feather/assert"
+)
+
+func TestCSV(t *testing.T) {
+	p := feather.New()
+	p.Get("/report", func(w http.ResponseWriter, r *http.Request) {
+		rows := SliceRows([][]string{{"1", "Patient Zero"}, {"2", "Smith, John"}})
+		err := CSV(w, r, "report.csv", rows, &Options{Header: []string{"id", "name"}, FlushRows: 1})
+		Equal(t, err, nil)
+	})
+
+	r, _ := http.NewRequest(http.MethodGet, "/report", nil)
+	w := httptest.NewRecorder()
+	p.Serve().ServeHTTP(w, r)
+	Equal(t, w.Code, http.StatusOK)
+	Equal(t, w.Header().Get(contentTypeHeader), textCSV)
+	Equal(t, w.Header().Get(contentDispositionHeader), `attachment; filename="report.csv"`)
+	Equal(t, w.Body.String(), "id,name\n1,Patient Zero\n2,\"Smith, John\"\n")
+	Equal(t, w.Flushed, true)
+}
+
+func TestCSVCancelled(t *testing.T) {
+	var produced int
+	ctx, cancel := context.WithCancel(context.Background())
+	rows := RowsFunc(func() ([]string, error) {
+		produced++
+		if produced == 2 {
+			cancel()
+		}
+		return []string{"row"}, nil
+	})
+
+	var err error
+	p := feather.New()
+	p.Get("/report", func(w http.ResponseWriter, r *http.Request) {
+		err = CSV(w, r, "report.csv", rows, nil)
+	})
+
+	r, _ := http.NewRequestWithContext(ctx, http.MethodGet, "/report", nil)
+	p.Serve().ServeHTTP(httptest.NewRecorder(), r)
+	Equal(t, err, context.Canceled)
+	Equal(t, produced, 2)
+}
+
+func TestXLSX(t *testing.T) {
+	r, _ := http.NewRequest(http.MethodGet, "/report", nil)
+	w := httptest.NewRecorder()
+	rows := SliceRows([][]string{{"1", "<Patient & Zero>"}})
+	err := XLSX(w, r, "report.xlsx", "", rows, &Options{Header: []string{"id", "name"}})
+	Equal(t, err, nil)
+	Equal(t, w.Header().Get(contentTypeHeader), applicationXLSX)
+	Equal(t, w.Header().Get(contentDispositionHeader), `attachment; filename="report.xlsx"`)
+
+	zr, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+	Equal(t, err, nil)
+	Equal(t, len(zr.File), 5)
+
+	files := make(map[string]string)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		Equal(t, err, nil)
+		b, err := io.ReadAll(rc)
+		Equal(t, err, nil)
+		files[f.Name] = string(b)
+	}
+
+	Equal(t, strings.Contains(files["xl/workbook.xml"], `<sheet name="Sheet1"`), true)
+	sheet := files["xl/worksheets/sheet1.xml"]
+	Equal(t, strings.HasSuffix(sheet, "</sheetData></worksheet>"), true)
+	Equal(t, strings.Count(sheet, "<row>"), 2)
+	Equal(t, strings.Contains(sheet, "&lt;Patient &amp; Zero&gt;"), true)
+}
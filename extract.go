@@ -0,0 +1,47 @@
+package feather
+
+import "net/http"
+
+// ParamExtractor returns the URL param named name of a request routed by another router,
+// ok is false if the router didn't set it.
+type ParamExtractor func(r *http.Request, name string) (value string, ok bool)
+
+var paramExtractors []ParamExtractor
+
+// RegisterParamExtractor adds a fallback for the URL params of RequestVars for requests
+// not routed by feather, so handlers shared between routers, i.e. during a migration, can use a single params accessor.
+// The extractors are tried in registration order, i.e. for gorilla/mux:
+//
+//	feather.RegisterParamExtractor(func(r *http.Request, name string) (string, bool) {
+//		v, ok := mux.Vars(r)[name]
+//		return v, ok
+//	})
+//
+// or for chi:
+//
+//	feather.RegisterParamExtractor(func(r *http.Request, name string) (string, bool) {
+//		v := chi.URLParam(r, name)
+//		return v, v != ""
+//	})
+//
+// The extractors must be registered during initialization, before serving requests.
+func RegisterParamExtractor(fn ParamExtractor) {
+	paramExtractors = append(paramExtractors, fn)
+}
+
+// PathValueExtractor extracts the wildcards of the patterns of http.ServeMux, see http.Request.PathValue.
+func PathValueExtractor(r *http.Request, name string) (string, bool) {
+	v := r.PathValue(name)
+	return v, v != blank
+}
+
+// extractParam returns the URL param of the request using the registered extractors.
+func extractParam(r *http.Request, name string) string {
+	for _, fn := range paramExtractors {
+		if v, ok := fn(r, name); ok {
+			return v
+		}
+	}
+
+	return blank
+}
@@ -0,0 +1,58 @@
+package feather
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	. "github.com/pchchv/feather/assert"
+)
+
+type varsKey struct{}
+
+func TestParamExtractor(t *testing.T) {
+	defer func() { paramExtractors = nil }()
+
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		rv := RequestVars(r)
+		id, err := rv.URLParamInt("id")
+		Equal(t, err, nil)
+		_, _ = w.Write([]byte(rv.URLParam("name") + " " + strconv.Itoa(id)))
+	}
+
+	// without extractors the params are empty
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /users/{name}/{id}", func(w http.ResponseWriter, r *http.Request) {
+		Equal(t, RequestVars(r).URLParam("name"), "")
+	})
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/joe/13", nil))
+
+	RegisterParamExtractor(func(r *http.Request, name string) (string, bool) {
+		vars, _ := r.Context().Value(varsKey{}).(map[string]string)
+		v, ok := vars[name]
+		return v, ok
+	})
+	RegisterParamExtractor(PathValueExtractor)
+
+	mux = http.NewServeMux()
+	mux.HandleFunc("GET /users/{name}/{id}", handler)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/joe/13", nil))
+	Equal(t, w.Body.String(), "joe 13")
+
+	// the extractors are tried in registration order
+	r := httptest.NewRequest(http.MethodGet, "/users/joe/13", nil)
+	r = r.WithContext(context.WithValue(r.Context(), varsKey{}, map[string]string{"id": "7"}))
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, r)
+	Equal(t, w.Body.String(), "joe 7")
+
+	// requests routed by feather don't use the extractors
+	p := New()
+	p.Get("/users/:name/:id", handler)
+	code, body := request(http.MethodGet, "/users/ann/42", p)
+	Equal(t, code, http.StatusOK)
+	Equal(t, body, "ann 42")
+}
@@ -2,9 +2,15 @@ package feather
 
 import (
 	"context"
+	"log/slog"
 	"net/http"
+	"net/url"
+	"slices"
+	"sort"
 	"strings"
 	"sync"
+
+	"github.com/pchchv/feather/internal/lru"
 )
 
 const (
@@ -14,6 +20,7 @@ const (
 	acceptedLanguageHeader   = "Accept-Language"
 	contentEncodingHeader    = "Content-Encoding"
 	contentDispositionHeader = "Content-Disposition"
+	contentLengthHeader      = "Content-Length"
 	contentTypeHeader        = "Content-Type"
 	xRealIPHeader            = "X-Real-Ip"
 	xForwardedForHeader      = "X-Forwarded-For"
@@ -22,12 +29,19 @@ const (
 	paramByte                = ':'
 	basePath                 = "/"
 	wildByte                 = '*'
+	constraintStartByte      = '('
+	constraintEndByte        = ')'
 	blank                    = ""
 )
 
 var (
 	default404Handler = func(w http.ResponseWriter, r *http.Request) {
-		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		msg := http.StatusText(http.StatusNotFound)
+		if routes := RouteSuggestions(r); len(routes) > 0 {
+			msg += "\n\nDid you mean?\n  " + strings.Join(routes, "\n  ")
+		}
+
+		http.Error(w, msg, http.StatusNotFound)
 	}
 
 	methodNotAllowedHandler = func(w http.ResponseWriter, r *http.Request) {
@@ -48,6 +62,9 @@ var (
 // Middleware is feather's middleware definition.
 type Middleware func(h http.HandlerFunc) http.HandlerFunc
 
+// ConstraintFunc reports whether value is valid for a URL param.
+type ConstraintFunc func(value string) bool
+
 // Mux is the main request multiplexer.
 type Mux struct {
 	routeGroup
@@ -56,12 +73,70 @@ type Mux struct {
 	http404     http.HandlerFunc // 404 Not Found
 	http405     http.HandlerFunc // 405 Method Not Allowed
 	httpOPTIONS http.HandlerFunc
-	mostParams  uint8 // mostParams used to keep track of the most amount of params in any URL and this will set the default capacity of each Params
-	// redirectTrailingSlash enables automatic redirection if
-	// the current route can't be matched but a handler for the path with (without) the trailing slash exists.
-	// For example if /foo/ is requested but a route only exists for /foo,
-	// the client is redirected to /foo with http status code 301 for GET requests and 307 for all other request methods.
-	redirectTrailingSlash bool
+	mostParams  uint8        // mostParams used to keep track of the most amount of params in any URL and this will set the default capacity of each Params
+	logger      *slog.Logger // base of the request scoped loggers, see Logger
+	logFields   []LogField
+	propagate   []string // header names copied by OutgoingHeaders
+	// maxSuggestions is the maximum number of routes suggested to the 404 handler, 0 disables suggestions
+	maxSuggestions int
+	// strictMethods when set contains the only methods accepted, other methods are answered
+	// with 501 Not Implemented unless a route was explicitly registered for them.
+	strictMethods map[string]struct{}
+	// explicitMethods contains the methods routes were registered for, excluding those registered by Any.
+	explicitMethods map[string]struct{}
+	anyMethods      []string // methods registered by Any
+	// routeMiddleware contains the group middleware of each route keyed by method and route pattern,
+	// used by redirects when redirectGroupMiddleware is enabled.
+	routeMiddleware map[string][]Middleware
+	constraints     map[string]ConstraintFunc  // keyed by param name, see RegisterConstraint
+	routes          []RouteInfo                // registered routes, see Routes
+	poolCounters    *PoolCounters              // requestVars pool counters, nil unless enabled
+	poolMaxSize     int                        // maximum size of pooled requestVars, see SetPoolMaxSize
+	poolDebug       bool                       // poison released requestVars, see SetPoolDebug
+	groupHandlers   []*groupHandlers           // 404 and 405 handlers of groups, longest prefix first
+	redirectCache   *lru.Cache[redirectTarget] // redirect targets of missed paths, nil unless enabled
+	notFoundCache   *lru.Cache[struct{}]       // paths not found, nil unless enabled
+	maxQueryParams  int                        // maximum number of query params, 0 is unlimited, see SetQueryLimits
+	maxQueryLength  int                        // maximum length of the raw query, 0 is unlimited, see SetQueryLimits
+	// hostTrees contains the trees of the virtual hosts keyed by host and method, see Host.
+	hostTrees map[string]map[string]*node
+	// trailingSlash determines how the requests are handled which can't be matched, but the path lowercase
+	// or with (without) the trailing slash can, see SetTrailingSlashPolicy.
+	trailingSlash TrailingSlashPolicy
+	// rawParams matches the routes with the escaped path, so the params are not decoded, see SetParamsDecoding.
+	rawParams bool
+	// pathCleaning determines how the paths with multiple slashes or . and .. elements are handled, see SetPathCleaning.
+	pathCleaning PathCleaning
+	// redirectJSONBody answers the redirects of non-GET requests with a JSON body describing the canonical URL.
+	redirectJSONBody bool
+	// redirectGroupMiddleware runs the trailing slash and lowercase redirects through
+	// the middleware of the group the redirect target was registered with instead of the Mux middleware.
+	redirectGroupMiddleware bool
+	// strictAccept answers requests not accepting any of the media types the route produces with 406 Not Acceptable.
+	strictAccept bool
+	// propagateDeadline applies the deadlines of incoming requests to their contexts, see SetDeadlinePropagation.
+	propagateDeadline bool
+	// htmlETags adds weak ETags to the pages rendered using HTML and answers matching conditional requests with 304.
+	htmlETags bool
+	// errorHandler responds to the errors returned by HandlerFuncE handlers, see SetErrorHandler.
+	errorHandler ErrorHandler
+	// catchAllFallback allows routes sharing a path segment with a catch-all, which then matches
+	// only the paths no other route matches, see SetCatchAllFallback.
+	catchAllFallback bool
+	// jsonPrefix is written before top-level JSON arrays, see SetSecureJSONPrefix.
+	jsonPrefix string
+	// memoryLimit is the maximum number of bytes buffered per request, 0 is unlimited, see SetRequestMemoryLimit.
+	memoryLimit int64
+	// serverTiming sends the Server-Timing header of the routed requests, see SetServerTiming.
+	serverTiming bool
+	// idnHosts converts the internationalized host names to their ASCII form, see SetIDNHosts.
+	idnHosts bool
+	// pathNormalizer normalizes the request paths containing non-ASCII characters, see SetPathNormalizer.
+	pathNormalizer func(path string) string
+	// served is set by Serve, after which the trees are sorted and no more routes can be registered.
+	served bool
+	// automaticHEAD answers HEAD requests with the GET handler of the route if no HEAD handler is registered.
+	automaticHEAD bool
 	// If enabled, the router checks if another method is allowed for the current route,
 	// if the current request can not be routed.
 	// If this is the case, the request is answered with 'Method Not Allowed' and HTTP status code 405.
@@ -79,21 +154,28 @@ func New() *Mux {
 			middleware: make([]Middleware, 0),
 		},
 		trees:                      make(map[string]*node),
+		explicitMethods:            make(map[string]struct{}),
+		routeMiddleware:            make(map[string][]Middleware),
+		anyMethods:                 defaultAnyMethods,
+		propagate:                  append([]string(nil), defaultPropagatedHeaders...),
 		mostParams:                 0,
 		http404:                    default404Handler,
 		http405:                    methodNotAllowedHandler,
 		httpOPTIONS:                automaticOPTIONSHandler,
-		redirectTrailingSlash:      true,
+		trailingSlash:              Redirect301,
 		handleMethodNotAllowed:     false,
 		automaticallyHandleOPTIONS: false,
 	}
 	p.routeGroup.feather = p
 	p.pool.New = func() interface{} {
-		rv := &requestVars{
+		if p.poolCounters != nil {
+			p.poolCounters.New()
+		}
+
+		return &requestVars{
 			params: make(urlParams, p.mostParams),
+			mux:    p,
 		}
-		rv.ctx = context.WithValue(context.Background(), defaultContextIdentifier, rv)
-		return rv
 	}
 
 	return p
@@ -119,17 +201,200 @@ func (p urlParams) Get(key string) (param string) {
 }
 
 // Serve returns an http.Handler to be used.
+// The routing trees are sorted by priority and frozen, registering routes afterwards panics.
 func (p *Mux) Serve() http.Handler {
-	// is reserved for any logic that must occur before service begins,
-	// i.e. although this router does not use priority to determine route order,
-	// it is possible to add tree node sorting here
+	// routes can no longer be registered, so the trees are sorted once
+	if !p.served {
+		p.served = true
+		for _, tree := range p.trees {
+			tree.sort()
+			tree.markDeclinable(false)
+		}
+
+		for _, trees := range p.hostTrees {
+			for _, tree := range trees {
+				tree.sort()
+				tree.markDeclinable(false)
+			}
+		}
+	}
+
 	return http.HandlerFunc(p.serveHTTP)
 }
 
-// SetRedirectTrailingSlash tells feather whether to attempt to fix the URL by trying to find it.
-// lowercase -> with or without slash -> 404
-func (p *Mux) SetRedirectTrailingSlash(set bool) {
-	p.redirectTrailingSlash = set
+// TrailingSlashPolicy determines how the requests are handled whose path doesn't match any route,
+// but matches one lowercase or with (without) the trailing slash, i.e. /foo/ when only /foo is registered.
+type TrailingSlashPolicy uint8
+
+const (
+	// Redirect301 redirects GET requests to the matching path with 301 Moved Permanently
+	// and all other requests with 308 Permanent Redirect, keeping their method and body. This is the default.
+	Redirect301 TrailingSlashPolicy = iota
+	// Redirect308 redirects all requests to the matching path with 308 Permanent Redirect.
+	Redirect308
+	// StripSilently routes the requests to the matching route without a redirect, as if the matching path was requested,
+	// for API clients not following redirects.
+	StripSilently
+	// Strict only routes the requests whose path matches a route exactly, the others are not found.
+	Strict
+)
+
+// SetTrailingSlashPolicy sets how the requests are handled whose path matches a route
+// only lowercase or with (without) the trailing slash, default is Redirect301.
+// The path is tried lowercase first, then with (without) the trailing slash.
+func (p *Mux) SetTrailingSlashPolicy(policy TrailingSlashPolicy) {
+	p.trailingSlash = policy
+}
+
+// PathCleaning determines how the requests are handled whose path contains multiple slashes or . and .. elements,
+// i.e. /users//13/../14.
+type PathCleaning uint8
+
+const (
+	// KeepPaths matches the paths as requested, the default.
+	KeepPaths PathCleaning = iota
+	// CleanPaths matches the cleaned paths, i.e. /users/14 for /users//13/../14, without a redirect.
+	CleanPaths
+	// RedirectCleanPaths redirects to the cleaned path if it matches a route,
+	// with 301 Moved Permanently for GET requests and 308 Permanent Redirect otherwise.
+	RedirectCleanPaths
+)
+
+// SetPathCleaning sets how the requests are handled whose path contains multiple slashes or . and .. elements,
+// default is KeepPaths. The cleaned path keeps the trailing slash, if any.
+func (p *Mux) SetPathCleaning(cleaning PathCleaning) {
+	p.pathCleaning = cleaning
+}
+
+// SetParamsDecoding tells feather whether the URL params are percent-decoded, i.e. "jo hn" for /user/jo%20hn.
+// When false, the routes are matched with the escaped path, so the params are the raw encoded values
+// and can contain encoded slashes, i.e. "a%2Fb" for /files/a%2Fb matching /files/:name,
+// the static parts of the routes must then not contain characters escaped in paths.
+// By default, true.
+func (p *Mux) SetParamsDecoding(decode bool) {
+	p.rawParams = !decode
+}
+
+// SetRedirectJSONBody enables describing the canonical URL in a JSON body of the 308 redirects
+// of non-GET requests, so API clients not following redirects can act on them, i.e.
+//
+//	{"status":308,"message":"Permanent Redirect","location":"/users"}
+//
+// Default is false.
+func (p *Mux) SetRedirectJSONBody(enable bool) {
+	p.redirectJSONBody = enable
+}
+
+// redirectBody is the JSON body of redirects, see SetRedirectJSONBody.
+type redirectBody struct {
+	Status   int    `json:"status"`
+	Message  string `json:"message"`
+	Location string `json:"location"`
+}
+
+// SetRedirectCacheSize caches the results of the lowercase and trailing slash lookups
+// of up to size missed paths, avoiding the extra tree traversals for frequently requested misspelled paths.
+// The least recently used results are evicted first, 0, the default, disables the cache.
+func (p *Mux) SetRedirectCacheSize(size int) {
+	if size <= 0 {
+		p.redirectCache = nil
+		return
+	}
+
+	p.redirectCache = lru.New[redirectTarget](size)
+}
+
+// SetStrictAccept enables answering requests with 406 Not Acceptable when their Accept header
+// doesn't accept any of the media types the route produces, declared using Meta.Produces,
+// instead of responding with a media type the client didn't ask for.
+// Routes without declared media types and requests without an Accept header are not affected.
+// Default is false.
+func (p *Mux) SetStrictAccept(enable bool) {
+	p.strictAccept = enable
+}
+
+// SetHTMLETags enables weak ETags of the pages rendered using HTML, computed from the rendered output,
+// and answering requests whose If-None-Match lists the ETag with 304 Not Modified,
+// saving the bandwidth of unchanged server rendered pages. The ETags are weak,
+// so they stay valid when the gzip middleware compresses the pages.
+// Default is false.
+func (p *Mux) SetHTMLETags(enable bool) {
+	p.htmlETags = enable
+}
+
+// SetQueryLimits limits the number of query params and the length of the raw query, in bytes,
+// requests exceeding them are answered with 400 Bad Request before being routed,
+// so neither ParseForm nor the decoders parse overly large queries.
+// 0, the default, means unlimited.
+func (p *Mux) SetQueryLimits(maxParams int, maxLength int) {
+	p.maxQueryParams = maxParams
+	p.maxQueryLength = maxLength
+}
+
+// exceedsQueryLimits reports whether the raw query exceeds the limits set using SetQueryLimits.
+// The params are counted by their separators, without parsing the query.
+func (p *Mux) exceedsQueryLimits(rawQuery string) bool {
+	if rawQuery == blank {
+		return false
+	}
+
+	if p.maxQueryLength > 0 && len(rawQuery) > p.maxQueryLength {
+		return true
+	}
+
+	return p.maxQueryParams > 0 && strings.Count(rawQuery, "&")+1 > p.maxQueryParams
+}
+
+// SetNotFoundCacheSize caches up to size method and path pairs which were not found, including the redirect lookups,
+// so floods of requests for missing paths, i.e. by scanners, skip the lookups.
+// The least recently used paths are evicted first, the cache stays valid since routes can't be registered
+// once serving, see Serve.
+// 0, the default, disables the cache.
+func (p *Mux) SetNotFoundCacheSize(size int) {
+	if size <= 0 {
+		p.notFoundCache = nil
+		return
+	}
+
+	p.notFoundCache = lru.New[struct{}](size)
+}
+
+// SetRedirectGroupMiddleware tells feather whether the trailing slash and lowercase redirects
+// run through the middleware of the group the redirect target belongs to,
+// so i.e. auth and logging middleware of the group see the redirected requests.
+// By default, false and only the Mux middleware is used.
+func (p *Mux) SetRedirectGroupMiddleware(set bool) {
+	p.redirectGroupMiddleware = set
+}
+
+// SetCatchAllFallback tells feather whether routes can share a path segment with a catch-all,
+// i.e. /files/health and /files/*, the other routes take precedence and the catch-all matches
+// the paths none of them match. It must be set before registering the routes.
+// By default, false and registering such routes panics.
+func (p *Mux) SetCatchAllFallback(set bool) {
+	p.catchAllFallback = set
+}
+
+// SetAutomaticHEAD tells feather whether HEAD requests are handled by the GET handler of a route
+// without a HEAD handler; the response body is discarded by the http.Server.
+// HEAD is then also included in the Allow header of routes with a GET handler.
+// By default, false.
+func (p *Mux) SetAutomaticHEAD(set bool) {
+	p.automaticHEAD = set
+}
+
+// RegisterConstraint registers a constraint for all URL params with the given name,
+// requests with param values not satisfying it do not match the route, i.e.
+//
+//	p.RegisterConstraint("id", func(v string) bool { _, err := strconv.Atoi(v); return err == nil })
+//
+// Single routes can instead constrain a param using a regular expression, i.e. /user/:id(\d+).
+func (p *Mux) RegisterConstraint(param string, fn ConstraintFunc) {
+	if p.constraints == nil {
+		p.constraints = make(map[string]ConstraintFunc)
+	}
+
+	p.constraints[param] = fn
 }
 
 // Register404 allows to override the handler function for routes not found.
@@ -156,6 +421,19 @@ func (p *Mux) RegisterAutomaticOPTIONS(middleware ...Middleware) {
 	p.httpOPTIONS = h
 }
 
+// RegisterAutomaticOPTIONSHandler handles OPTION requests automatically, like RegisterAutomaticOPTIONS,
+// using the provided handler instead of replying 200, i.e. to add CORS headers or a body describing the resource.
+// The Allow header is set before the handler runs and the allowed methods are available using AllowedMethods.
+func (p *Mux) RegisterAutomaticOPTIONSHandler(options http.HandlerFunc, middleware ...Middleware) {
+	p.automaticallyHandleOPTIONS = true
+	h := options
+	for i := len(middleware) - 1; i >= 0; i-- {
+		h = middleware[i](h)
+	}
+
+	p.httpOPTIONS = h
+}
+
 // RegisterMethodNotAllowed indicates feather whether the http 405 Method Not Allowed status code should be processed.
 func (p *Mux) RegisterMethodNotAllowed(middleware ...Middleware) {
 	p.handleMethodNotAllowed = true
@@ -167,9 +445,194 @@ func (p *Mux) RegisterMethodNotAllowed(middleware ...Middleware) {
 	p.http405 = h
 }
 
-func (p *Mux) redirect(method string, to string) (h http.HandlerFunc) {
+// notFound returns the 404 handler of the nearest group containing path, falling back to the Mux handler.
+func (p *Mux) notFound(path string) http.HandlerFunc {
+	for _, gh := range p.groupHandlers {
+		if gh.http404 != nil && gh.contains(path) {
+			return gh.http404
+		}
+	}
+
+	return p.http404
+}
+
+// methodNotAllowed returns the 405 handler of the nearest group containing path,
+// falling back to the Mux handler, or nil if 405 responses aren't enabled for path.
+func (p *Mux) methodNotAllowed(path string) http.HandlerFunc {
+	for _, gh := range p.groupHandlers {
+		if gh.http405 != nil && gh.contains(path) {
+			return gh.http405
+		}
+	}
+
+	if p.handleMethodNotAllowed {
+		return p.http405
+	}
+
+	return nil
+}
+
+// AllowedMethods returns the methods allowed for the request path, as sent in the Allow header,
+// when the request is answered by the automatic OPTIONS handler or the 405 handler.
+func AllowedMethods(r *http.Request) []string {
+	if rv, ok := requestVarsOf(r); ok {
+		return rv.allowed
+	}
+
+	return nil
+}
+
+// requestVars returns a reset requestVars from the pool.
+func (p *Mux) requestVars() *requestVars {
+	rv := p.getRequestVars()
+	rv.params = rv.params[0:0]
+	rv.route = blank
+	rv.logger = nil
+	rv.outgoing = nil
+	rv.suggestions = nil
+	rv.allowed = nil
+	rv.meta = nil
+	rv.memory.Store(0)
+	rv.skip = 0
+	rv.next = false
+	rv.err = nil
+	return rv
+}
+
+// usesRequestVars reports whether a setting of the Mux reads the request variables,
+// so they are stored even for the plain static routes.
+func (p *Mux) usesRequestVars() bool {
+	return p.errorHandler != nil || p.logger != nil || len(p.logFields) > 0 || p.memoryLimit > 0 ||
+		p.htmlETags || p.serverTiming || p.propagateDeadline || len(p.propagate) != len(defaultPropagatedHeaders)
+}
+
+// matchedRoute returns the pattern of the route matching path.
+func (p *Mux) matchedRoute(tree *node, path string) (route string, ok bool) {
+	h, rv := tree.find(path, p)
+	if rv != nil {
+		route = rv.route
+		p.putRequestVars(rv)
+	} else {
+		// plain static route, see serveHTTP
+		route = path
+	}
+
+	return route, h != nil
+}
+
+// allowedMethods returns the sorted, deduplicated methods other than exclude with a route matching path,
+// path "*" matches every route.
+func (p *Mux) allowedMethods(trees map[string]*node, path string, exclude string) []string {
+	methods := make([]string, 0, len(trees)+1)
+	for m, tree := range trees {
+		if m == exclude || !p.accepts(m) {
+			continue
+		}
+
+		if path != "*" {
+			if _, ok := p.matchedRoute(tree, path); !ok {
+				continue
+			}
+		}
+
+		methods = append(methods, m)
+		if m == http.MethodGet && p.automaticHEAD && exclude != http.MethodHead {
+			methods = append(methods, http.MethodHead)
+		}
+	}
+
+	sort.Strings(methods)
+	return slices.Compact(methods)
+}
+
+// withPath returns a shallow copy of r with the URL path replaced by path.
+func withPath(r *http.Request, path string) *http.Request {
+	u := *r.URL
+	u.Path = path
+	u.RawPath = blank
+	return withURL(r, &u)
+}
+
+// withURL returns a shallow copy of r with the URL replaced by u.
+func withURL(r *http.Request, u *url.URL) *http.Request {
+	r2 := *r
+	r2.URL = u
+	return &r2
+}
+
+// lookupPath returns the path of r the routes are matched with, escaped unless the params are decoded, see SetParamsDecoding.
+func (p *Mux) lookupPath(r *http.Request) string {
+	if p.rawParams {
+		return r.URL.EscapedPath()
+	}
+
+	return r.URL.Path
+}
+
+// lookupURL returns a copy of u with the path replaced by path, a path the routes are matched with, see lookupPath.
+func (p *Mux) lookupURL(u *url.URL, path string) *url.URL {
+	v := *u
+	v.Path, v.RawPath = path, blank
+	if p.rawParams {
+		if unescaped, err := url.PathUnescape(path); err == nil {
+			v.Path, v.RawPath = unescaped, path
+		}
+	}
+
+	return &v
+}
+
+// knownNotFound reports whether path of the host is in the not found cache.
+func (p *Mux) knownNotFound(method string, host string, path string) bool {
+	if p.notFoundCache == nil {
+		return false
+	}
+
+	_, ok := p.notFoundCache.Get(method + " " + host + path)
+	return ok
+}
+
+// redirectTarget finds path again all lowercase, then with or without the trailing slash,
+// using the redirect cache if enabled.
+func (p *Mux) redirectTarget(method string, host string, tree *node, path string) (target redirectTarget) {
+	var key string
+	if p.redirectCache != nil && len(path) <= maxCachedPath {
+		key = method + " " + host + path
+		if target, ok := p.redirectCache.Get(key); ok {
+			return target
+		}
+	}
+
+	lc := strings.ToLower(path)
+	if lc != path {
+		target.to = lc
+		target.route, target.ok = p.matchedRoute(tree, lc)
+	}
+
+	if !target.ok {
+		if lc[len(lc)-1:] == basePath {
+			target.to = lc[:len(lc)-1]
+		} else {
+			target.to = lc + basePath
+		}
+
+		target.route, target.ok = p.matchedRoute(tree, target.to)
+	}
+
+	if !target.ok {
+		target = redirectTarget{}
+	}
+
+	if key != blank {
+		p.redirectCache.Add(key, target)
+	}
+
+	return
+}
+
+func (p *Mux) redirect(method string, host string, route string, to string) (h http.HandlerFunc) {
 	code := http.StatusMovedPermanently
-	if method != http.MethodGet {
+	if method != http.MethodGet || p.trailingSlash == Redirect308 {
 		code = http.StatusPermanentRedirect
 	}
 
@@ -177,8 +640,24 @@ func (p *Mux) redirect(method string, to string) (h http.HandlerFunc) {
 		http.Redirect(w, r, to, code)
 	}
 
-	for i := len(p.middleware) - 1; i >= 0; i-- {
-		h = p.middleware[i](h)
+	if code == http.StatusPermanentRedirect && p.redirectJSONBody {
+		h = func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Location", to)
+			_ = JSON(w, code, redirectBody{
+				Status:   code,
+				Message:  http.StatusText(code),
+				Location: to,
+			})
+		}
+	}
+
+	middleware := p.middleware
+	if p.redirectGroupMiddleware {
+		middleware = p.routeMiddleware[method+" "+host+route]
+	}
+
+	for i := len(middleware) - 1; i >= 0; i-- {
+		h = middleware[i](h)
 	}
 
 	return
@@ -186,99 +665,156 @@ func (p *Mux) redirect(method string, to string) (h http.HandlerFunc) {
 
 // serveHTTP conforms to the http.Handler interface.
 func (p *Mux) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if !p.accepts(r.Method) {
+		http.Error(w, http.StatusText(http.StatusNotImplemented), http.StatusNotImplemented)
+		return
+	}
+
+	if p.exceedsQueryLimits(r.URL.RawQuery) {
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	r = p.normalizePath(r)
+	path := p.lookupPath(r)
+	var cleaned string // the path cleaned if it should be redirected to
+	if p.pathCleaning != KeepPaths && needsCleaning(path) {
+		if p.pathCleaning == CleanPaths {
+			r = withURL(r, p.lookupURL(r.URL, cleanPath(path)))
+			path = p.lookupPath(r)
+		} else {
+			cleaned = cleanPath(path)
+		}
+	}
+
 	var rv *requestVars
 	var h http.HandlerFunc
-	tree := p.trees[r.Method]
-	if tree != nil {
-		if h, rv = tree.find(r.URL.Path, p); h == nil {
-			if p.redirectTrailingSlash && len(r.URL.Path) > 1 { // find again all lowercase
-				orig := r.URL.Path
-				lc := strings.ToLower(orig)
-				if lc != r.URL.Path {
-					if h, _ = tree.find(lc, p); h != nil {
-						r.URL.Path = lc
-						h = p.redirect(r.Method, r.URL.String())
-						r.URL.Path = orig
+	var routed bool // the handler is the one of the matched route, which can decline the request using NextRoute
+	trees, host := p.treesOf(r.Host)
+	tree := trees[r.Method]
+	if r.Method == http.MethodHead && p.automaticHEAD {
+		if tree == nil {
+			tree = trees[http.MethodGet]
+		} else if _, ok := p.matchedRoute(tree, path); !ok && trees[http.MethodGet] != nil {
+			tree = trees[http.MethodGet]
+		}
+	}
+
+	if tree != nil && cleaned != blank {
+		if route, ok := p.matchedRoute(tree, cleaned); ok {
+			h = p.redirect(r.Method, host, route, p.lookupURL(r.URL, cleaned).String())
+			goto END
+		}
+	}
+
+	if tree != nil && !p.knownNotFound(r.Method, host, path) {
+		if h, rv = tree.find(path, p); h == nil {
+			if p.trailingSlash != Strict && len(path) > 1 {
+				if target := p.redirectTarget(r.Method, host, tree, path); target.ok {
+					if p.trailingSlash == StripSilently {
+						if rv != nil {
+							p.putRequestVars(rv)
+						}
+						r = withURL(r, p.lookupURL(r.URL, target.to))
+						path = target.to
+						h, rv = tree.find(path, p)
+					} else {
+						h = p.redirect(r.Method, host, target.route, p.lookupURL(r.URL, target.to).String())
 						goto END
 					}
 				}
+			}
 
-				if lc[len(lc)-1:] == basePath {
-					lc = lc[:len(lc)-1]
-				} else {
-					lc = lc + basePath
-				}
+			if h == nil && p.notFoundCache != nil && len(path) <= maxCachedPath {
+				p.notFoundCache.Add(r.Method+" "+host+path, struct{}{})
+			}
+		}
 
-				if h, _ = tree.find(lc, p); h != nil {
-					r.URL.Path = lc
-					h = p.redirect(r.Method, r.URL.String())
-					r.URL.Path = orig
-					goto END
-				}
+		if h != nil {
+			if rv == nil {
+				// plain static route, nothing reads the request variables, so they aren't stored
+				// and the route pattern, the lookup path, is set like http.ServeMux does
+				r.Pattern = path
+			} else {
+				routed = true
 			}
-		} else {
 			goto END
 		}
 	}
 
 	if p.automaticallyHandleOPTIONS && r.Method == http.MethodOptions {
-		if r.URL.Path == "*" { // check server-wide OPTIONS
-			for m := range p.trees {
-				if m == http.MethodOptions {
-					continue
-				}
-
-				w.Header().Add(allowHeader, m)
-			}
-		} else {
-			for m, ctree := range p.trees {
-				if m == r.Method || m == http.MethodOptions {
-					continue
-				}
-
-				if h, _ = ctree.find(r.URL.Path, p); h != nil {
-					w.Header().Add(allowHeader, m)
-				}
-			}
+		// "*" checks server-wide OPTIONS
+		methods := append(p.allowedMethods(trees, path, http.MethodOptions), http.MethodOptions)
+		for _, m := range methods {
+			w.Header().Add(allowHeader, m)
 		}
 
-		w.Header().Add(allowHeader, http.MethodOptions)
+		if rv == nil {
+			rv = p.requestVars()
+		}
+		rv.allowed = methods
 		h = p.httpOPTIONS
 		goto END
 	}
 
-	if p.handleMethodNotAllowed {
-		var found bool
-		for m, ctree := range p.trees {
-			if m == r.Method {
-				continue
-			}
-
-			if h, _ = ctree.find(r.URL.Path, p); h != nil {
+	if h405 := p.methodNotAllowed(r.URL.Path); h405 != nil {
+		if methods := p.allowedMethods(trees, path, r.Method); len(methods) > 0 {
+			for _, m := range methods {
 				w.Header().Add(allowHeader, m)
-				found = true
 			}
-		}
 
-		if found {
-			h = p.http405
+			if rv == nil {
+				rv = p.requestVars()
+			}
+			rv.allowed = methods
+			h = h405
 			goto END
 		}
 	}
 
 	// not found
-	h = p.http404
+	h = p.notFound(r.URL.Path)
+	if p.maxSuggestions > 0 {
+		if rv == nil {
+			rv = p.requestVars()
+		}
+		rv.suggestions = p.suggest(trees, r.URL.Path)
+	}
 
 END:
 	if rv != nil {
 		rv.formParsed = false
-		// store on context
-		r = r.WithContext(rv.ctx)
+		// store on the request context, keeping its cancellation, deadline and values
+		rr := &routedRequest{ctx: requestContext{Context: r.Context(), rv: rv}}
+		rr.req = *r.WithContext(&rr.ctx)
+		rr.req.Pattern = rv.route
+		r = &rr.req
+	}
+
+	if p.propagateDeadline {
+		if deadline, ok := requestDeadline(r.Header); ok {
+			ctx, cancel := context.WithDeadline(r.Context(), deadline)
+			defer cancel()
+			r = r.WithContext(ctx)
+		}
+	}
+
+	rw := getResponseWriter(w)
+	rw.jsonPrefix = p.jsonPrefix
+	if p.serverTiming && rv != nil {
+		startServerTiming(rv, rw)
+	}
+
+	h(rw, r)
+	for declined := 1; routed && rv.next; declined++ {
+		h, routed = p.nextRoute(tree, path, rv, declined)
+		h(rw, r)
 	}
 
-	h(w, r)
+	rw.runAfter()
+	putResponseWriter(rw)
 
 	if rv != nil {
-		p.pool.Put(rv)
+		p.putRequestVars(rv)
 	}
 }
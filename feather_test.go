@@ -2,12 +2,14 @@ package feather
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"strconv"
+	"strings"
 	"testing"
 
 	. "github.com/pchchv/feather/assert"
@@ -506,6 +508,7 @@ func TestAllMethods(t *testing.T) {
 
 	// test any
 	p2 := New()
+	p2.SetAnyMethods(http.MethodConnect, http.MethodDelete, http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPatch, http.MethodPost, http.MethodPut, http.MethodTrace)
 	p2.Any("/test", defaultHandler)
 
 	hf = p2.Serve()
@@ -582,7 +585,7 @@ func TestRouterAPI(t *testing.T) {
 			if _, err := w.Write([]byte(r.URL.Path)); err != nil {
 				panic(err)
 			}
-		})
+		}, nil)
 	}
 
 	for _, route := range githubAPI {
@@ -688,10 +691,123 @@ func TestAutomaticallyHandleOPTIONS(t *testing.T) {
 	Equal(t, len(allow), 10)
 }
 
+func TestAutomaticHEAD(t *testing.T) {
+	p := New()
+	p.RegisterAutomaticOPTIONS()
+	p.RegisterMethodNotAllowed()
+	p.Get("/home", defaultHandler)
+	p.Post("/home", defaultHandler)
+	p.Get("/explicit", defaultHandler)
+	p.Head("/explicit", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	code, _ := request(http.MethodHead, "/home", p)
+	Equal(t, code, http.StatusMethodNotAllowed)
+
+	p.SetAutomaticHEAD(true)
+
+	code, _ = request(http.MethodHead, "/home", p)
+	Equal(t, code, http.StatusOK)
+
+	code, _ = request(http.MethodHead, "/explicit", p)
+	Equal(t, code, http.StatusNoContent)
+
+	code, _ = request(http.MethodHead, "/missing", p)
+	Equal(t, code, http.StatusNotFound)
+
+	r, _ := http.NewRequest(http.MethodOptions, "/home", nil)
+	w := httptest.NewRecorder()
+	p.serveHTTP(w, r)
+	Equal(t, w.Code, http.StatusOK)
+	Equal(t, w.Header()[allowHeader], []string{http.MethodGet, http.MethodHead, http.MethodPost, http.MethodOptions})
+
+	// explicit and derived HEAD are not duplicated
+	r, _ = http.NewRequest(http.MethodOptions, "/explicit", nil)
+	w = httptest.NewRecorder()
+	p.serveHTTP(w, r)
+	Equal(t, w.Header()[allowHeader], []string{http.MethodGet, http.MethodHead, http.MethodOptions})
+
+	r, _ = http.NewRequest(http.MethodPut, "/home", nil)
+	w = httptest.NewRecorder()
+	p.serveHTTP(w, r)
+	Equal(t, w.Code, http.StatusMethodNotAllowed)
+	Equal(t, w.Header()[allowHeader], []string{http.MethodGet, http.MethodHead, http.MethodPost})
+}
+
+func TestAutomaticOPTIONSHandler(t *testing.T) {
+	p := New()
+	p.RegisterAutomaticOPTIONSHandler(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Access-Control-Allow-Methods", strings.Join(AllowedMethods(r), ", "))
+		_ = JSON(w, http.StatusOK, map[string][]string{"methods": AllowedMethods(r)})
+	})
+	p.RegisterMethodNotAllowed(func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, strings.Join(AllowedMethods(r), ","), http.StatusMethodNotAllowed)
+		}
+	})
+	p.Get("/users/:id", defaultHandler)
+	p.Put("/users/:id", defaultHandler)
+
+	r, _ := http.NewRequest(http.MethodOptions, "/users/13", nil)
+	w := httptest.NewRecorder()
+	p.serveHTTP(w, r)
+	Equal(t, w.Code, http.StatusOK)
+	Equal(t, w.Header()[allowHeader], []string{http.MethodGet, http.MethodPut, http.MethodOptions})
+	Equal(t, w.Header().Get("Access-Control-Allow-Methods"), "GET, PUT, OPTIONS")
+	Equal(t, w.Body.String(), `{"methods":["GET","PUT","OPTIONS"]}`)
+
+	r, _ = http.NewRequest(http.MethodPost, "/users/13", nil)
+	w = httptest.NewRecorder()
+	p.serveHTTP(w, r)
+	Equal(t, w.Code, http.StatusMethodNotAllowed)
+	Equal(t, w.Body.String(), "GET,PUT\n")
+
+	p.Get("/plain", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(strings.Join(AllowedMethods(r), ",")))
+	})
+	code, body := request(http.MethodGet, "/plain", p)
+	Equal(t, code, http.StatusOK)
+	Equal(t, body, "")
+}
+
+func TestQueryLimits(t *testing.T) {
+	p := New()
+	p.Get("/search", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(strconv.Itoa(len(QueryParams(r, httpQueryParams)))))
+	})
+
+	code, body := request(http.MethodGet, "/search?a=1&b=2&c=3&d=4", p)
+	Equal(t, code, http.StatusOK)
+	Equal(t, body, "4")
+
+	p.SetQueryLimits(3, 16)
+
+	code, body = request(http.MethodGet, "/search?a=1&b=2&c=3", p)
+	Equal(t, code, http.StatusOK)
+	Equal(t, body, "3")
+
+	code, _ = request(http.MethodGet, "/search", p)
+	Equal(t, code, http.StatusOK)
+
+	code, body = request(http.MethodGet, "/search?a=1&b=2&c=3&d=4", p)
+	Equal(t, code, http.StatusBadRequest)
+	Equal(t, body, "Bad Request\n")
+
+	code, _ = request(http.MethodGet, "/search?q=01234567890123456", p)
+	Equal(t, code, http.StatusBadRequest)
+
+	p.SetQueryLimits(0, 0)
+	code, _ = request(http.MethodGet, "/search?q=01234567890123456&a=1&b=2&c=3", p)
+	Equal(t, code, http.StatusOK)
+}
+
 func TestRedirect(t *testing.T) {
 	p := New()
 	p.Get("/home/", defaultHandler)
 	p.Post("/home/", defaultHandler)
+	p.Get("/users/:id", defaultHandler)
+	p.Get("/users/:id/profile", defaultHandler)
 
 	code, _ := request(http.MethodGet, "/home/", p)
 	Equal(t, code, http.StatusOK)
@@ -708,7 +824,7 @@ func TestRedirect(t *testing.T) {
 	code, _ = request(http.MethodPost, "/home", p)
 	Equal(t, code, http.StatusPermanentRedirect)
 
-	p.SetRedirectTrailingSlash(false)
+	p.SetTrailingSlashPolicy(Strict)
 
 	code, _ = request(http.MethodGet, "/home/", p)
 	Equal(t, code, http.StatusOK)
@@ -725,10 +841,7 @@ func TestRedirect(t *testing.T) {
 	code, _ = request(http.MethodPost, "/home", p)
 	Equal(t, code, http.StatusNotFound)
 
-	p.SetRedirectTrailingSlash(true)
-
-	p.Get("/users/:id", defaultHandler)
-	p.Get("/users/:id/profile", defaultHandler)
+	p.SetTrailingSlashPolicy(Redirect301)
 
 	code, _ = request(http.MethodGet, "/users/10", p)
 	Equal(t, code, http.StatusOK)
@@ -736,7 +849,7 @@ func TestRedirect(t *testing.T) {
 	code, _ = request(http.MethodGet, "/users/10/", p)
 	Equal(t, code, http.StatusMovedPermanently)
 
-	p.SetRedirectTrailingSlash(false)
+	p.SetTrailingSlashPolicy(Strict)
 
 	code, _ = request(http.MethodGet, "/users/10", p)
 	Equal(t, code, http.StatusOK)
@@ -745,6 +858,200 @@ func TestRedirect(t *testing.T) {
 	Equal(t, code, http.StatusNotFound)
 }
 
+func TestTrailingSlashPolicy(t *testing.T) {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(RequestVars(r).Route() + " " + r.URL.Path + " " + RequestVars(r).URLParam("id")))
+	}
+
+	p := New()
+	p.Get("/home", fn)
+	p.Post("/home", fn)
+	p.Get("/users/:id/", fn)
+
+	p.SetTrailingSlashPolicy(Redirect308)
+	code, _ := request(http.MethodGet, "/home/", p)
+	Equal(t, code, http.StatusPermanentRedirect)
+
+	code, _ = request(http.MethodPost, "/Home", p)
+	Equal(t, code, http.StatusPermanentRedirect)
+
+	p.SetTrailingSlashPolicy(StripSilently)
+	tests := []struct {
+		method string
+		path   string
+		body   string
+	}{
+		{method: http.MethodGet, path: "/home", body: "/home /home "},
+		{method: http.MethodGet, path: "/home/", body: "/home /home "},
+		{method: http.MethodPost, path: "/HOME/", body: "/home /home "},
+		{method: http.MethodGet, path: "/users/13", body: "/users/:id/ /users/13/ 13"},
+	}
+
+	for _, tt := range tests {
+		code, body := request(tt.method, tt.path, p)
+		Equal(t, code, http.StatusOK)
+		Equal(t, body, tt.body)
+	}
+
+	code, _ = request(http.MethodGet, "/missing/", p)
+	Equal(t, code, http.StatusNotFound)
+}
+
+func TestPathCleaning(t *testing.T) {
+	paths := []struct {
+		path     string
+		expected string
+	}{
+		{path: "", expected: "/"},
+		{path: "/", expected: "/"},
+		{path: "//", expected: "/"},
+		{path: "/users//13", expected: "/users/13"},
+		{path: "/users/./13/", expected: "/users/13/"},
+		{path: "/users/13/../14", expected: "/users/14"},
+		{path: "/../users/..", expected: "/"},
+		{path: "/users/.", expected: "/users"},
+		{path: "/users/.hidden", expected: "/users/.hidden"},
+	}
+
+	for _, tt := range paths {
+		Equal(t, cleanPath(tt.path), tt.expected)
+	}
+
+	Equal(t, needsCleaning("/users/.hidden/a..b"), false)
+	Equal(t, needsCleaning("/users/.."), true)
+
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.URL.Path + " " + RequestVars(r).URLParam("id")))
+	}
+
+	p := New()
+	p.Get("/users/:id", fn)
+	p.Post("/users/:id", fn)
+
+	code, _ := request(http.MethodGet, "/users//13", p)
+	Equal(t, code, http.StatusNotFound)
+
+	p.SetPathCleaning(CleanPaths)
+	code, body := request(http.MethodGet, "/users//13/../14", p)
+	Equal(t, code, http.StatusOK)
+	Equal(t, body, "/users/14 14")
+
+	p.SetPathCleaning(RedirectCleanPaths)
+	r, _ := http.NewRequest(http.MethodGet, "/users/./13?page=2", nil)
+	w := httptest.NewRecorder()
+	p.Serve().ServeHTTP(w, r)
+	Equal(t, w.Code, http.StatusMovedPermanently)
+	Equal(t, w.Header().Get("Location"), "/users/13?page=2")
+
+	code, _ = request(http.MethodPost, "/users///13", p)
+	Equal(t, code, http.StatusPermanentRedirect)
+
+	// only redirected if the cleaned path matches
+	code, _ = request(http.MethodGet, "/posts//13", p)
+	Equal(t, code, http.StatusNotFound)
+}
+
+func TestParamsDecoding(t *testing.T) {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(RequestVars(r).URLParam("name") + "|" + RequestVars(r).Wildcard()))
+	}
+
+	for _, decode := range []bool{true, false} {
+		p := New()
+		p.SetParamsDecoding(decode)
+		p.SetTrailingSlashPolicy(StripSilently)
+		p.Get("/users/:name", fn)
+		p.Get("/files/:name/*", fn)
+		p.Redirect("/u/:name", "/users/:name")
+
+		tests := []struct {
+			path    string
+			decoded string
+			raw     string
+		}{
+			{path: "/users/jo%20hn", decoded: "jo hn|", raw: "jo%20hn|"},
+			{path: "/users/jo%20hn/", decoded: "jo hn|", raw: "jo%20hn|"},
+			{path: "/files/a%2Fb/c%20d", decoded: "a|b/c d", raw: "a%2Fb|c%20d"},
+		}
+
+		for _, tt := range tests {
+			code, body := request(http.MethodGet, tt.path, p)
+			Equal(t, code, http.StatusOK)
+			if decode {
+				Equal(t, body, tt.decoded)
+			} else {
+				Equal(t, body, tt.raw)
+			}
+		}
+
+		r, _ := http.NewRequest(http.MethodGet, "/u/a%2Fb", nil)
+		w := httptest.NewRecorder()
+		p.Serve().ServeHTTP(w, r)
+		if decode {
+			Equal(t, w.Code, http.StatusNotFound)
+		} else {
+			Equal(t, w.Code, http.StatusMovedPermanently)
+			Equal(t, w.Header().Get("Location"), "/users/a%2Fb")
+		}
+	}
+}
+
+func TestRedirectJSONBody(t *testing.T) {
+	p := New()
+	p.SetRedirectJSONBody(true)
+	p.Get("/users", defaultHandler)
+	p.Post("/users", defaultHandler)
+
+	r, _ := http.NewRequest(http.MethodPost, "/users/?page=2", nil)
+	w := httptest.NewRecorder()
+	p.Serve().ServeHTTP(w, r)
+	Equal(t, w.Code, http.StatusPermanentRedirect)
+	Equal(t, w.Header().Get("Location"), "/users?page=2")
+	Equal(t, w.Header().Get(contentTypeHeader), applicationJSON)
+	Equal(t, w.Body.String(), `{"status":308,"message":"Permanent Redirect","location":"/users?page=2"}`)
+
+	// GET redirects are unchanged
+	r, _ = http.NewRequest(http.MethodGet, "/users/", nil)
+	w = httptest.NewRecorder()
+	p.Serve().ServeHTTP(w, r)
+	Equal(t, w.Code, http.StatusMovedPermanently)
+	Equal(t, w.Header().Get(contentTypeHeader), "text/html; charset=utf-8")
+}
+
+func TestRedirectGroupMiddleware(t *testing.T) {
+	var calls []string
+	track := func(name string) Middleware {
+		return func(next http.HandlerFunc) http.HandlerFunc {
+			return func(w http.ResponseWriter, r *http.Request) {
+				calls = append(calls, name)
+				next(w, r)
+			}
+		}
+	}
+
+	p := New()
+	p.Use(track("mux"))
+	admin := p.GroupWithMore("/admin", track("admin"))
+	admin.Get("/users", defaultHandler)
+	admin.Get("/users/:id", defaultHandler)
+
+	code, _ := request(http.MethodGet, "/admin/users/", p)
+	Equal(t, code, http.StatusMovedPermanently)
+	Equal(t, calls, []string{"mux"})
+
+	p.SetRedirectGroupMiddleware(true)
+
+	calls = nil
+	code, _ = request(http.MethodGet, "/admin/users/", p)
+	Equal(t, code, http.StatusMovedPermanently)
+	Equal(t, calls, []string{"mux", "admin"})
+
+	calls = nil
+	code, _ = request(http.MethodGet, "/Admin/users/13", p)
+	Equal(t, code, http.StatusMovedPermanently)
+	Equal(t, calls, []string{"mux", "admin"})
+}
+
 func TestNotFound(t *testing.T) {
 	notFound := func(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
@@ -768,6 +1075,32 @@ func TestNotFound(t *testing.T) {
 	Equal(t, code, http.StatusNotFound)
 }
 
+func TestRequestContext(t *testing.T) {
+	type upstreamKey struct{}
+
+	var errs []error
+	var values []interface{}
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		errs = append(errs, r.Context().Err())
+		values = append(values, r.Context().Value(upstreamKey{}))
+		_ = RequestVars(r).Route()
+	}
+
+	p := New()
+	p.Get("/static", handler)
+	p.Get("/users/:id", handler)
+	hf := p.Serve()
+
+	for _, path := range []string{"/static", "/users/13"} {
+		ctx, cancel := context.WithCancel(context.WithValue(context.Background(), upstreamKey{}, "upstream"))
+		cancel()
+		hf.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil).WithContext(ctx))
+	}
+
+	Equal(t, errs, []error{context.Canceled, context.Canceled})
+	Equal(t, values, []interface{}{"upstream", "upstream"})
+}
+
 func TestBadAdd(t *testing.T) {
 	fn := func(w http.ResponseWriter, r *http.Request) {
 		if _, err := w.Write([]byte(r.Method)); err != nil {
@@ -846,3 +1179,77 @@ func requestMultiPart(method string, url string, p *Mux) (int, string) {
 	hf.ServeHTTP(wr, r)
 	return wr.Code, wr.Body.String()
 }
+
+func TestStrictMethods(t *testing.T) {
+	p := New()
+	p.SetStrictMethods()
+	p.RegisterMethodNotAllowed()
+	p.Any("/any", defaultHandler)
+
+	code, body := request(http.MethodGet, "/any", p)
+	Equal(t, code, http.StatusOK)
+	Equal(t, body, http.MethodGet)
+
+	code, _ = request(http.MethodTrace, "/any", p)
+	Equal(t, code, http.StatusNotImplemented)
+
+	code, _ = request(http.MethodConnect, "/any", p)
+	Equal(t, code, http.StatusNotImplemented)
+
+	code, _ = request("PROPFIND", "/any", p)
+	Equal(t, code, http.StatusNotImplemented)
+
+	// explicitly registered
+	p = New()
+	p.SetStrictMethods()
+	p.Trace("/trace", defaultHandler)
+	code, body = request(http.MethodTrace, "/trace", p)
+	Equal(t, code, http.StatusOK)
+	Equal(t, body, http.MethodTrace)
+
+	p2 := New()
+	p2.Any("/any", defaultHandler)
+	p2.SetStrictMethods(http.MethodGet, http.MethodPost)
+	p2.RegisterMethodNotAllowed()
+
+	code, _ = request(http.MethodTrace, "/any", p2)
+	Equal(t, code, http.StatusNotImplemented)
+
+	p2 = New()
+	p2.SetStrictMethods(http.MethodGet, http.MethodPost)
+	p2.RegisterMethodNotAllowed()
+	p2.Get("/get", defaultHandler)
+	p2.Trace("/get", defaultHandler)
+	r, _ := http.NewRequest(http.MethodPost, "/get", nil)
+	w := httptest.NewRecorder()
+	p2.serveHTTP(w, r)
+	Equal(t, w.Code, http.StatusMethodNotAllowed)
+	Equal(t, len(w.Header()[allowHeader]), 2)
+}
+
+func TestAnyDefaultMethods(t *testing.T) {
+	p := New()
+	p.Any("/test", defaultHandler)
+	for _, m := range []string{http.MethodDelete, http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPatch, http.MethodPost, http.MethodPut} {
+		code, body := request(m, "/test", p)
+		Equal(t, code, http.StatusOK)
+		Equal(t, body, m)
+	}
+
+	code, _ := request(http.MethodConnect, "/test", p)
+	Equal(t, code, http.StatusNotFound)
+
+	code, _ = request(http.MethodTrace, "/test", p)
+	Equal(t, code, http.StatusNotFound)
+
+	p = New()
+	p.SetAnyMethods(http.MethodGet, "PROPFIND")
+	p.Any("/custom", defaultHandler)
+
+	code, body := request("PROPFIND", "/custom", p)
+	Equal(t, code, http.StatusOK)
+	Equal(t, body, "PROPFIND")
+
+	code, _ = request(http.MethodPost, "/custom", p)
+	Equal(t, code, http.StatusNotFound)
+}
@@ -0,0 +1,45 @@
+// Package feathertest provides test servers and clients for feather routers,
+// making it easy to test TLS, HTTP/2, h2c and streaming behaviors.
+package feathertest
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/pchchv/feather"
+)
+
+// NewTLSServer starts a server serving p over TLS, negotiating HTTP/2 when http2 is set.
+// The client returned by the Client method of the server trusts its certificate
+// and uses HTTP/2 when enabled. The server must be closed once done, i.e.
+//
+//	s := feathertest.NewTLSServer(p, true)
+//	defer s.Close()
+//	resp, err := s.Client().Get(s.URL + "/users/13")
+func NewTLSServer(p *feather.Mux, http2 bool) *httptest.Server {
+	s := httptest.NewUnstartedServer(p.Serve())
+	s.EnableHTTP2 = http2
+	s.StartTLS()
+	return s
+}
+
+// NewH2CServer starts a server serving p using HTTP/1.1 and HTTP/2 without TLS (h2c),
+// use H2CClient to send HTTP/2 requests to it.
+// The server must be closed once done.
+func NewH2CServer(p *feather.Mux) *httptest.Server {
+	s := httptest.NewUnstartedServer(p.Serve())
+	s.Config.Protocols = new(http.Protocols)
+	s.Config.Protocols.SetHTTP1(true)
+	s.Config.Protocols.SetUnencryptedHTTP2(true)
+	s.Start()
+	return s
+}
+
+// H2CClient returns a client sending requests using HTTP/2 without TLS (h2c),
+// i.e. to servers started using NewH2CServer.
+func H2CClient() *http.Client {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.Protocols = new(http.Protocols)
+	t.Protocols.SetUnencryptedHTTP2(true)
+	return &http.Client{Transport: t}
+}
@@ -0,0 +1,58 @@
+package feathertest
+
+import (
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/pchchv/feather"
+	. "github.com/pchchv/feather/assert"
+)
+
+func newMux() *feather.Mux {
+	p := feather.New()
+	p.Get("/proto", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Proto))
+	})
+	return p
+}
+
+func get(t *testing.T, c *http.Client, url string) (proto string, body string) {
+	resp, err := c.Get(url)
+	Equal(t, err, nil)
+	defer resp.Body.Close()
+
+	b, err := io.ReadAll(resp.Body)
+	Equal(t, err, nil)
+	Equal(t, resp.StatusCode, http.StatusOK)
+	return resp.Proto, string(b)
+}
+
+func TestNewTLSServer(t *testing.T) {
+	s := NewTLSServer(newMux(), true)
+	defer s.Close()
+
+	proto, body := get(t, s.Client(), s.URL+"/proto")
+	Equal(t, proto, "HTTP/2.0")
+	Equal(t, body, "HTTP/2.0")
+
+	s1 := NewTLSServer(newMux(), false)
+	defer s1.Close()
+
+	proto, body = get(t, s1.Client(), s1.URL+"/proto")
+	Equal(t, proto, "HTTP/1.1")
+	Equal(t, body, "HTTP/1.1")
+}
+
+func TestNewH2CServer(t *testing.T) {
+	s := NewH2CServer(newMux())
+	defer s.Close()
+
+	proto, body := get(t, H2CClient(), s.URL+"/proto")
+	Equal(t, proto, "HTTP/2.0")
+	Equal(t, body, "HTTP/2.0")
+
+	proto, body = get(t, s.Client(), s.URL+"/proto")
+	Equal(t, proto, "HTTP/1.1")
+	Equal(t, body, "HTTP/1.1")
+}
@@ -0,0 +1,91 @@
+package feathertest
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("feathertest.update", false, "update the golden files compared by feathertest.Golden")
+
+// Golden compares the body of resp to the golden file at path, failing the test if they differ, i.e.
+//
+//	w := httptest.NewRecorder()
+//	p.Serve().ServeHTTP(w, r)
+//	feathertest.Golden(t, w.Result(), "testdata/user.json")
+//
+// JSON bodies are normalized before comparing, so the key order and indentation don't matter,
+// and are written indented so golden files diff nicely.
+// Running the tests with -feathertest.update writes the body to the golden file instead,
+// creating it if necessary.
+func Golden(t testing.TB, resp *http.Response, path string) {
+	t.Helper()
+	body, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		t.Fatalf("feathertest: reading response body: %v", err)
+	}
+
+	isJSON := isJSONResponse(resp, path)
+	if isJSON {
+		body = normalizeJSON(body)
+	}
+
+	if *update {
+		if err = os.MkdirAll(filepath.Dir(path), 0o755); err == nil {
+			err = os.WriteFile(path, body, 0o644)
+		}
+
+		if err != nil {
+			t.Fatalf("feathertest: updating golden file: %v", err)
+		}
+		return
+	}
+
+	golden, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("feathertest: reading golden file: %v, run the tests with -feathertest.update to create it", err)
+	}
+
+	if isJSON {
+		golden = normalizeJSON(golden)
+	}
+
+	if !bytes.Equal(body, golden) {
+		t.Errorf("feathertest: response body does not match golden file %s\n--- got\n%s\n--- want\n%s", path, body, golden)
+	}
+}
+
+func isJSONResponse(resp *http.Response, path string) bool {
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		return true
+	}
+
+	mt, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	return mt == "application/json" || strings.HasSuffix(mt, "+json")
+}
+
+// normalizeJSON returns the JSON indented with sorted object keys and a trailing newline,
+// invalid JSON is returned as is so it's reported as a mismatch.
+func normalizeJSON(b []byte) []byte {
+	var v interface{}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return b
+	}
+
+	nb, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return b
+	}
+
+	return append(nb, '\n')
+}
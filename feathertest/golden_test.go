@@ -0,0 +1,57 @@
+package feathertest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pchchv/feather"
+	. "github.com/pchchv/feather/assert"
+)
+
+// failTB records failures instead of stopping the test, so failing comparisons can be tested.
+type failTB struct {
+	testing.TB
+	failed bool
+}
+
+func (f *failTB) Helper() {}
+
+func (f *failTB) Errorf(format string, args ...interface{}) {
+	f.failed = true
+}
+
+func TestGolden(t *testing.T) {
+	p := feather.New()
+	p.Get("/user", func(w http.ResponseWriter, r *http.Request) {
+		_ = feather.JSON(w, http.StatusOK, map[string]interface{}{"name": "joeybloggs", "id": 13, "roles": []string{"admin"}})
+	})
+	p.Get("/text", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("hello"))
+	})
+
+	serve := func(path string) *http.Response {
+		w := httptest.NewRecorder()
+		r, _ := http.NewRequest(http.MethodGet, path, nil)
+		p.Serve().ServeHTTP(w, r)
+		return w.Result()
+	}
+
+	Golden(t, serve("/user"), "testdata/user.json")
+	Golden(t, serve("/text"), "testdata/text.txt")
+
+	ft := new(failTB)
+	Golden(ft, serve("/text"), "testdata/user.json")
+	Equal(t, ft.failed, true)
+
+	dir := t.TempDir()
+	*update = true
+	defer func() { *update = false }()
+	Golden(t, serve("/user"), filepath.Join(dir, "nested", "user.json"))
+
+	b, err := os.ReadFile(filepath.Join(dir, "nested", "user.json"))
+	Equal(t, err, nil)
+	Equal(t, string(b), "{\n  \"id\": 13,\n  \"name\": \"joeybloggs\",\n  \"roles\": [\n    \"admin\"\n  ]\n}\n")
+}
@@ -1,7 +1,10 @@
 package feather
 
 import (
+	"errors"
+	"net/http"
 	"net/url"
+	"strings"
 
 	"github.com/pchchv/form"
 )
@@ -16,10 +19,143 @@ type FormEncoder interface {
 	Encode(interface{}) (url.Values, error)
 }
 
+// FieldErrorer is implemented by validation errors carrying a message per form field,
+// allowing MapFormErrors to map them.
+type FieldErrorer interface {
+	FieldErrors() map[string]string
+}
+
 var (
 	// DefaultFormDecoder of this package, which is configurable.
 	DefaultFormDecoder FormDecoder = form.NewDecoder()
 
 	// DefaultFormEncoder of this package, which is configurable.
 	DefaultFormEncoder FormEncoder = form.NewEncoder()
+
+	// formErrorMessages are the messages of the decode errors of the default form decoder by prefix.
+	formErrorMessages = []struct {
+		prefix  string
+		message string
+	}{
+		{"Invalid Unsigned Integer Value", "must be a whole number of zero or more"},
+		{"Invalid Integer Value", "must be a whole number"},
+		{"Invalid Float Value", "must be a number"},
+		{"Invalid Boolean Value", "must be true or false"},
+	}
 )
+
+// FormErrors maps form field names to error messages.
+type FormErrors map[string]string
+
+// Form is a submitted HTML form, used to re-render it with the submitted values and errors, i.e.
+//
+//	{{ with .Form }}
+//	<input name="age" value="{{ .Value "age" }}">
+//	{{ if .HasError "age" }}<p class="error">{{ .Error "age" }}</p>{{ end }}
+//	{{ end }}
+type Form struct {
+	Values url.Values
+	Errors FormErrors
+}
+
+// Value returns the first submitted value of the field.
+func (f *Form) Value(field string) string {
+	return f.Values.Get(field)
+}
+
+// HasError returns whether the field has an error.
+func (f *Form) HasError(field string) bool {
+	_, ok := f.Errors[field]
+	return ok
+}
+
+// Error returns the error message of the field, blank if none.
+func (f *Form) Error(field string) string {
+	return f.Errors[field]
+}
+
+// AddError adds an error message to the field, i.e. after validating the decoded struct.
+func (f *Form) AddError(field string, message string) {
+	if f.Errors == nil {
+		f.Errors = make(FormErrors)
+	}
+
+	f.Errors[field] = message
+}
+
+// Valid returns whether the form has no errors.
+func (f *Form) Valid() bool {
+	return len(f.Errors) == 0
+}
+
+// MapFormErrors maps the decode errors of the default form decoder and errors implementing FieldErrorer,
+// such as validation errors, to messages per field.
+// Other errors are mapped to the blank field as errors of the whole form.
+func MapFormErrors(err error) FormErrors {
+	if err == nil {
+		return nil
+	}
+
+	var decodeErrs form.DecodeErrors
+	if errors.As(err, &decodeErrs) {
+		errs := make(FormErrors, len(decodeErrs))
+		for field, err := range decodeErrs {
+			errs[field] = formErrorMessage(err)
+		}
+
+		return errs
+	}
+
+	var fe FieldErrorer
+	if errors.As(err, &fe) {
+		errs := make(FormErrors)
+		for field, msg := range fe.FieldErrors() {
+			errs[field] = msg
+		}
+
+		return errs
+	}
+
+	return FormErrors{blank: err.Error()}
+}
+
+func formErrorMessage(err error) string {
+	msg := err.Error()
+	for _, m := range formErrorMessages {
+		if strings.HasPrefix(msg, m.prefix) {
+			return m.message
+		}
+	}
+
+	return msg
+}
+
+// DecodeHTMLForm decodes the submitted form into v using DecodeForm or DecodeMultipartForm depending on the Content-Type,
+// returning the Form for re-rendering it with the submitted values and the errors mapped by MapFormErrors.
+// Values which failed to decode leave v unchanged, so v can be used to re-render the form as well.
+// An error is only returned if the form could not be parsed.
+func DecodeHTMLForm(r *http.Request, qp QueryParamsOption, maxMemory int64, v interface{}) (*Form, error) {
+	var err error
+	if strings.HasPrefix(r.Header.Get(contentTypeHeader), multipartForm) {
+		err = DecodeMultipartForm(r, qp, maxMemory, v)
+	} else {
+		err = DecodeForm(r, qp, v)
+	}
+
+	f := &Form{Values: r.PostForm}
+	if qp == httpQueryParams {
+		f.Values = r.Form
+	}
+
+	if err != nil {
+		var decodeErrs form.DecodeErrors
+		var fe FieldErrorer
+		if !errors.As(err, &decodeErrs) && !errors.As(err, &fe) {
+			return nil, err
+		}
+
+		f.Errors = MapFormErrors(err)
+	}
+
+	return f, nil
+}
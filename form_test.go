@@ -0,0 +1,96 @@
+package feather
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	. "github.com/pchchv/feather/assert"
+)
+
+type validationErrors map[string]string
+
+func (v validationErrors) Error() string {
+	return "validation failed"
+}
+
+func (v validationErrors) FieldErrors() map[string]string {
+	return v
+}
+
+func TestMapFormErrors(t *testing.T) {
+	Equal(t, MapFormErrors(nil), nil)
+	Equal(t, MapFormErrors(errors.New("bad")), FormErrors{"": "bad"})
+	Equal(t, MapFormErrors(validationErrors{"email": "is required"}), FormErrors{"email": "is required"})
+
+	type Test struct {
+		Age    int
+		Count  uint
+		Ratio  float64
+		Active bool
+	}
+
+	var test Test
+	err := DefaultFormDecoder.Decode(&test, url.Values{
+		"Age":    {"x"},
+		"Count":  {"-1"},
+		"Ratio":  {"y"},
+		"Active": {"z"},
+	})
+	Equal(t, MapFormErrors(err), FormErrors{
+		"Age":    "must be a whole number",
+		"Count":  "must be a whole number of zero or more",
+		"Ratio":  "must be a number",
+		"Active": "must be true or false",
+	})
+}
+
+func TestDecodeHTMLForm(t *testing.T) {
+	type Signup struct {
+		Name string `form:"name"`
+		Age  int    `form:"age"`
+	}
+
+	var f *Form
+	var signup Signup
+	var err error
+	p := New()
+	p.Post("/signup", func(w http.ResponseWriter, r *http.Request) {
+		signup = Signup{}
+		f, err = DecodeHTMLForm(r, noQueryParams, 16<<10, &signup)
+	})
+
+	hf := p.Serve()
+	r, _ := http.NewRequest(http.MethodPost, "/signup?name=query", strings.NewReader("name=joeybloggs&age=old"))
+	r.Header.Set(contentTypeHeader, applicationForm)
+	hf.ServeHTTP(httptest.NewRecorder(), r)
+	Equal(t, err, nil)
+	Equal(t, signup.Name, "joeybloggs")
+	Equal(t, signup.Age, 0)
+	Equal(t, f.Valid(), false)
+	Equal(t, f.Value("name"), "joeybloggs")
+	Equal(t, f.Value("age"), "old")
+	Equal(t, f.HasError("name"), false)
+	Equal(t, f.HasError("age"), true)
+	Equal(t, f.Error("age"), "must be a whole number")
+
+	r, _ = http.NewRequest(http.MethodPost, "/signup", strings.NewReader("name=&age=42"))
+	r.Header.Set(contentTypeHeader, applicationForm)
+	hf.ServeHTTP(httptest.NewRecorder(), r)
+	Equal(t, err, nil)
+	Equal(t, signup.Age, 42)
+	Equal(t, f.Valid(), true)
+
+	f.AddError("name", "is required")
+	Equal(t, f.Valid(), false)
+	Equal(t, f.Error("name"), "is required")
+
+	r, _ = http.NewRequest(http.MethodPost, "/signup", strings.NewReader("name=%zz"))
+	r.Header.Set(contentTypeHeader, applicationForm)
+	hf.ServeHTTP(httptest.NewRecorder(), r)
+	NotEqual(t, err, nil)
+	Equal(t, f, nil)
+}
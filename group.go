@@ -1,7 +1,10 @@
 package feather
 
 import (
+	"io/fs"
 	"net/http"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
 )
@@ -9,18 +12,26 @@ import (
 var _ IRouteGroup = &routeGroup{}
 
 // IRoutes interface for routes.
+// Middleware passed when registering a route is applied to that route only, after the group middleware.
 type IRoutes interface {
 	Use(...Middleware)
-	Any(string, http.HandlerFunc)
-	Get(string, http.HandlerFunc)
-	Post(string, http.HandlerFunc)
-	Delete(string, http.HandlerFunc)
-	Patch(string, http.HandlerFunc)
-	Put(string, http.HandlerFunc)
-	Options(string, http.HandlerFunc)
-	Head(string, http.HandlerFunc)
-	Connect(string, http.HandlerFunc)
-	Trace(string, http.HandlerFunc)
+	Any(string, http.HandlerFunc, ...Middleware)
+	Get(string, http.HandlerFunc, ...Middleware)
+	Post(string, http.HandlerFunc, ...Middleware)
+	Delete(string, http.HandlerFunc, ...Middleware)
+	Patch(string, http.HandlerFunc, ...Middleware)
+	Put(string, http.HandlerFunc, ...Middleware)
+	Options(string, http.HandlerFunc, ...Middleware)
+	Head(string, http.HandlerFunc, ...Middleware)
+	Connect(string, http.HandlerFunc, ...Middleware)
+	Trace(string, http.HandlerFunc, ...Middleware)
+	Handle(method string, path string, h http.HandlerFunc, middleware ...Middleware)
+	Match(methods []string, path string, h http.HandlerFunc, middleware ...Middleware)
+	Static(prefix string, root string, opts ...StaticOptions)
+	StaticFS(prefix string, fsys fs.FS, opts ...StaticOptions)
+	Redirect(path string, target string)
+	Favicon(data []byte)
+	Robots(rules RobotsRules)
 }
 
 // IRouteGroup interface for router group.
@@ -29,43 +40,52 @@ type IRouteGroup interface {
 	GroupWithNone(prefix string) IRouteGroup
 	GroupWithMore(prefix string, middleware ...Middleware) IRouteGroup
 	Group(prefix string) IRouteGroup
+	WithMeta(meta Meta) IRouteGroup
+	Register404(notFound http.HandlerFunc, middleware ...Middleware)
+	Register405(methodNotAllowed http.HandlerFunc, middleware ...Middleware)
+	Host(host string) IRouteGroup
+	WithPolicy(policy Policy) IRouteGroup
+	WellKnown() *WellKnown
 }
 
 // routeGroup containing all fields and methods for use.
 type routeGroup struct {
 	prefix     string
+	host       string // virtual host of the routes, blank for all hosts, see Host
 	middleware []Middleware
 	feather    *Mux
+	meta       Meta     // operational settings of the group's routes
+	policies   []string // names of the policies protecting the group's routes, see WithPolicy
 }
 
 // Get adds a GET route & handler to the router.
-func (g *routeGroup) Get(path string, h http.HandlerFunc) {
-	g.handle(http.MethodGet, path, h)
+func (g *routeGroup) Get(path string, h http.HandlerFunc, middleware ...Middleware) {
+	g.handle(http.MethodGet, path, h, middleware)
 }
 
 // Delete adds a DELETE route & handler to the router.
-func (g *routeGroup) Delete(path string, h http.HandlerFunc) {
-	g.handle(http.MethodDelete, path, h)
+func (g *routeGroup) Delete(path string, h http.HandlerFunc, middleware ...Middleware) {
+	g.handle(http.MethodDelete, path, h, middleware)
 }
 
 // Post adds a POST route & handler to the router.
-func (g *routeGroup) Post(path string, h http.HandlerFunc) {
-	g.handle(http.MethodPost, path, h)
+func (g *routeGroup) Post(path string, h http.HandlerFunc, middleware ...Middleware) {
+	g.handle(http.MethodPost, path, h, middleware)
 }
 
 // Put adds a PUT route & handler to the router.
-func (g *routeGroup) Put(path string, h http.HandlerFunc) {
-	g.handle(http.MethodPut, path, h)
+func (g *routeGroup) Put(path string, h http.HandlerFunc, middleware ...Middleware) {
+	g.handle(http.MethodPut, path, h, middleware)
 }
 
 // Patch adds a PATCH route & handler to the router.
-func (g *routeGroup) Patch(path string, h http.HandlerFunc) {
-	g.handle(http.MethodPatch, path, h)
+func (g *routeGroup) Patch(path string, h http.HandlerFunc, middleware ...Middleware) {
+	g.handle(http.MethodPatch, path, h, middleware)
 }
 
 // Options adds an OPTIONS route & handler to the router.
-func (g *routeGroup) Options(path string, h http.HandlerFunc) {
-	g.handle(http.MethodOptions, path, h)
+func (g *routeGroup) Options(path string, h http.HandlerFunc, middleware ...Middleware) {
+	g.handle(http.MethodOptions, path, h, middleware)
 }
 
 // Use adds a middleware handler to the group middleware chain.
@@ -74,39 +94,47 @@ func (g *routeGroup) Use(m ...Middleware) {
 }
 
 // Trace adds a TRACE route & handler to the router.
-func (g *routeGroup) Trace(path string, h http.HandlerFunc) {
-	g.handle(http.MethodTrace, path, h)
+func (g *routeGroup) Trace(path string, h http.HandlerFunc, middleware ...Middleware) {
+	g.handle(http.MethodTrace, path, h, middleware)
 }
 
 // Handle allows for any method to be registered with the given route & handler.
 // Allows for non standard methods to be used like CalDavs PROPFIND and so forth.
-func (g *routeGroup) Handle(method string, path string, h http.HandlerFunc) {
-	g.handle(method, path, h)
+func (g *routeGroup) Handle(method string, path string, h http.HandlerFunc, middleware ...Middleware) {
+	g.handle(method, path, h, middleware)
 }
 
 // Head adds a HEAD route & handler to the router.
-func (g *routeGroup) Head(path string, h http.HandlerFunc) {
-	g.handle(http.MethodHead, path, h)
+func (g *routeGroup) Head(path string, h http.HandlerFunc, middleware ...Middleware) {
+	g.handle(http.MethodHead, path, h, middleware)
 }
 
 // Connect adds a CONNECT route & handler to the router.
-func (g *routeGroup) Connect(path string, h http.HandlerFunc) {
-	g.handle(http.MethodConnect, path, h)
+func (g *routeGroup) Connect(path string, h http.HandlerFunc, middleware ...Middleware) {
+	g.handle(http.MethodConnect, path, h, middleware)
 }
 
-// Match adds a route & handler to the router for multiple HTTP methods provided.
-func (g *routeGroup) Match(methods []string, path string, h http.HandlerFunc) {
-	for _, m := range methods {
-		g.handle(m, path, h)
+// Match adds a route & handler to the router for multiple HTTP methods provided,
+// standard or not, i.e. the WebDAV methods PROPFIND, PROPPATCH, MKCOL, COPY, MOVE, LOCK and UNLOCK.
+// Methods listed more than once are registered once.
+func (g *routeGroup) Match(methods []string, path string, h http.HandlerFunc, middleware ...Middleware) {
+	for i, m := range methods {
+		if !slices.Contains(methods[:i], m) {
+			g.handle(m, path, h, middleware)
+		}
 	}
 }
 
 // GroupWithNone creates a new sub router with specified prefix and no middleware attached.
+// The policies of the group, see WithPolicy, are dropped along with their middleware,
+// so the RouteInfo of its routes doesn't list policies that aren't enforced.
 func (g *routeGroup) GroupWithNone(prefix string) IRouteGroup {
 	return &routeGroup{
 		prefix:     g.prefix + prefix,
+		host:       g.host,
 		feather:    g.feather,
 		middleware: make([]Middleware, 0),
+		meta:       g.meta,
 	}
 }
 
@@ -114,8 +142,11 @@ func (g *routeGroup) GroupWithNone(prefix string) IRouteGroup {
 func (g *routeGroup) GroupWithMore(prefix string, middleware ...Middleware) IRouteGroup {
 	rg := &routeGroup{
 		prefix:     g.prefix + prefix,
+		host:       g.host,
 		feather:    g.feather,
 		middleware: make([]Middleware, len(g.middleware)),
+		meta:       g.meta,
+		policies:   g.policies,
 	}
 	copy(rg.middleware, g.middleware)
 	rg.Use(middleware...)
@@ -126,44 +157,168 @@ func (g *routeGroup) GroupWithMore(prefix string, middleware ...Middleware) IRou
 func (g *routeGroup) Group(prefix string) IRouteGroup {
 	rg := &routeGroup{
 		prefix:     g.prefix + prefix,
+		host:       g.host,
 		feather:    g.feather,
 		middleware: make([]Middleware, len(g.middleware)),
+		meta:       g.meta,
+		policies:   g.policies,
 	}
 	copy(rg.middleware, g.middleware)
 	return rg
 }
 
-// Any adds a route & handler to the router for all HTTP methods.
-func (g *routeGroup) Any(path string, h http.HandlerFunc) {
-	g.Connect(path, h)
-	g.Delete(path, h)
-	g.Get(path, h)
-	g.Head(path, h)
-	g.Options(path, h)
-	g.Patch(path, h)
-	g.Post(path, h)
-	g.Put(path, h)
-	g.Trace(path, h)
+// Register404 overrides the handler for routes not found below the group prefix,
+// i.e. an /api group answering with JSON errors while the rest of the site serves an HTML page.
+// The handler of the group with the longest matching prefix is used,
+// param segments of the prefix match any segment.
+// Only the middleware passed is applied, just like Mux.Register404.
+func (g *routeGroup) Register404(notFound http.HandlerFunc, middleware ...Middleware) {
+	h := notFound
+	for i := len(middleware) - 1; i >= 0; i-- {
+		h = middleware[i](h)
+	}
+
+	g.feather.groupHandlersOf(g.prefix).http404 = h
 }
 
-func (g *routeGroup) handle(method string, path string, handler http.HandlerFunc) {
+// Register405 enables 405 Method Not Allowed responses below the group prefix using the handler,
+// regardless of RegisterMethodNotAllowed, registering it on a group with the path of a single route
+// overrides the handler for that route only.
+// The handler of the group with the longest matching prefix is used and only the middleware passed is applied.
+func (g *routeGroup) Register405(methodNotAllowed http.HandlerFunc, middleware ...Middleware) {
+	h := methodNotAllowed
+	for i := len(middleware) - 1; i >= 0; i-- {
+		h = middleware[i](h)
+	}
+
+	g.feather.groupHandlersOf(g.prefix).http405 = h
+}
+
+// groupHandlers are the 404 and 405 handlers of a group prefix.
+type groupHandlers struct {
+	segments []string // segments of the route pattern of the prefix
+	http404  http.HandlerFunc
+	http405  http.HandlerFunc
+}
+
+// contains reports whether path is the prefix or below it.
+func (gh *groupHandlers) contains(path string) bool {
+	var segment string
+	for _, s := range gh.segments {
+		if path == blank {
+			return false
+		}
+
+		// path starts with '/'
+		path = path[1:]
+		if i := strings.IndexByte(path, slashByte); i != -1 {
+			segment, path = path[:i], path[i:]
+		} else {
+			segment, path = path, blank
+		}
+
+		switch {
+		case s[0] == wildByte:
+			return true
+		case strings.IndexByte(s, paramByte) != -1:
+			if !segmentMatches(s, segment) {
+				return false
+			}
+		case s != segment:
+			return false
+		}
+	}
+
+	return true
+}
+
+// groupHandlersOf returns the handlers of the group prefix, adding them if necessary.
+func (p *Mux) groupHandlersOf(prefix string) *groupHandlers {
+	var segments []string
+	for _, s := range strings.Split(routePattern(prefix), basePath) {
+		if s != blank {
+			segments = append(segments, s)
+		}
+	}
+
+	for _, gh := range p.groupHandlers {
+		if slices.Equal(gh.segments, segments) {
+			return gh
+		}
+	}
+
+	gh := &groupHandlers{segments: segments}
+	p.groupHandlers = append(p.groupHandlers, gh)
+	sort.SliceStable(p.groupHandlers, func(i, j int) bool {
+		return len(p.groupHandlers[i].segments) > len(p.groupHandlers[j].segments)
+	})
+	return gh
+}
+
+// Any adds a route & handler to the router for all HTTP methods except CONNECT and TRACE,
+// the methods can be configured using SetAnyMethods.
+// When a strict method set is configured, see SetStrictMethods, only the accepted methods are registered.
+func (g *routeGroup) Any(path string, h http.HandlerFunc, middleware ...Middleware) {
+	for _, m := range g.feather.anyMethods {
+		if g.feather.accepts(m) {
+			g.register(m, path, h, middleware)
+		}
+	}
+}
+
+// handle registers the handler wrapped in the route middleware followed by the group middleware,
+// so route middleware runs after the group chain.
+func (g *routeGroup) handle(method string, path string, handler http.HandlerFunc, middleware []Middleware) {
+	if !validMethod(method) {
+		panic("invalid method '" + method + "' for path '" + g.prefix + path + "'")
+	}
+
+	g.feather.explicitMethods[method] = struct{}{}
+	g.register(method, path, handler, middleware)
+}
+
+func (g *routeGroup) register(method string, path string, handler http.HandlerFunc, middleware []Middleware) {
+	if g.feather.served {
+		panic("routes must be registered before Serve is called, path '" + g.prefix + path + "'")
+	}
+
 	if i := strings.Index(path, "//"); i != -1 {
 		panic("Bad path '" + path + "' contains duplicate // at index:" + strconv.Itoa(i))
 	}
 
 	h := handler
+	for i := len(middleware) - 1; i >= 0; i-- {
+		h = middleware[i](h)
+	}
+
 	for i := len(g.middleware) - 1; i >= 0; i-- {
 		h = g.middleware[i](h)
 	}
 
-	tree := g.feather.trees[method]
+	h = withMeta(g.meta, h)
+
+	trees := g.feather.treesFor(g.host)
+	tree := trees[method]
 	if tree == nil {
 		tree = new(node)
-		g.feather.trees[method] = tree
+		trees[method] = tree
 	}
 
-	pCount := tree.addRoute(g.prefix+path, h) + 1
+	plain := len(middleware) == 0 && len(g.middleware) == 0 && g.meta.isZero()
+	pCount := tree.addRoute(g.prefix+path, h, plain, g.feather) + 1
 	if pCount > g.feather.mostParams {
 		g.feather.mostParams = pCount
 	}
+
+	route := routePattern(g.prefix + path)
+	g.feather.routeMiddleware[method+" "+g.host+route] = g.middleware
+	g.feather.routes = append(g.feather.routes, RouteInfo{
+		Method:     method,
+		Host:       g.host,
+		Path:       route,
+		Consumes:   g.meta.Consumes,
+		Produces:   g.meta.Produces,
+		Middleware: middlewareNames(g.middleware, middleware),
+		Policies:   g.policies,
+	})
 }
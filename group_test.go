@@ -85,25 +85,11 @@ func TestUseAndGroup(t *testing.T) {
 	p := New()
 	p.Use(logger)
 	p.Get("/", fn)
-	code, body := request(http.MethodGet, "/", p)
-	Equal(t, code, http.StatusOK)
-	Equal(t, body, http.MethodGet)
-	Equal(t, log, "/")
 
 	g := p.Group("/users")
 	g.Get("/", fn)
 	g.Get("/list/", fn)
 
-	code, body = request(http.MethodGet, "/users/", p)
-	Equal(t, code, http.StatusOK)
-	Equal(t, body, http.MethodGet)
-	Equal(t, log, "/users/")
-
-	code, body = request(http.MethodGet, "/users/list/", p)
-	Equal(t, code, http.StatusOK)
-	Equal(t, body, http.MethodGet)
-	Equal(t, log, "/users/list/")
-
 	logger2 := func(next http.HandlerFunc) http.HandlerFunc {
 		return func(w http.ResponseWriter, r *http.Request) {
 			log = r.URL.Path + "2"
@@ -114,6 +100,29 @@ func TestUseAndGroup(t *testing.T) {
 	sh.Get("/", fn)
 	sh.Get("/list/", fn)
 
+	sc := sh.Group("/children")
+	sc.Get("/", fn)
+	sc.Get("/list/", fn)
+
+	g2 := p.GroupWithNone("/admins")
+	g2.Get("/", fn)
+	g2.Get("/list/", fn)
+
+	code, body := request(http.MethodGet, "/", p)
+	Equal(t, code, http.StatusOK)
+	Equal(t, body, http.MethodGet)
+	Equal(t, log, "/")
+
+	code, body = request(http.MethodGet, "/users/", p)
+	Equal(t, code, http.StatusOK)
+	Equal(t, body, http.MethodGet)
+	Equal(t, log, "/users/")
+
+	code, body = request(http.MethodGet, "/users/list/", p)
+	Equal(t, code, http.StatusOK)
+	Equal(t, body, http.MethodGet)
+	Equal(t, log, "/users/list/")
+
 	code, body = request(http.MethodGet, "/superheros/", p)
 	Equal(t, code, http.StatusOK)
 	Equal(t, body, http.MethodGet)
@@ -124,10 +133,6 @@ func TestUseAndGroup(t *testing.T) {
 	Equal(t, body, http.MethodGet)
 	Equal(t, log, "/superheros/list/2")
 
-	sc := sh.Group("/children")
-	sc.Get("/", fn)
-	sc.Get("/list/", fn)
-
 	code, body = request(http.MethodGet, "/superheros/children/", p)
 	Equal(t, code, http.StatusOK)
 	Equal(t, body, http.MethodGet)
@@ -139,10 +144,6 @@ func TestUseAndGroup(t *testing.T) {
 	Equal(t, log, "/superheros/children/list/2")
 
 	log = ""
-	g2 := p.GroupWithNone("/admins")
-	g2.Get("/", fn)
-	g2.Get("/list/", fn)
-
 	code, body = request(http.MethodGet, "/admins/", p)
 	Equal(t, code, http.StatusOK)
 	Equal(t, body, http.MethodGet)
@@ -218,3 +219,130 @@ func TestGrouplogic(t *testing.T) {
 	Equal(t, bb, 2)
 	Equal(t, cc, 1)
 }
+
+func TestRouteMiddleware(t *testing.T) {
+	var order string
+	mw := func(name string) Middleware {
+		return func(next http.HandlerFunc) http.HandlerFunc {
+			return func(w http.ResponseWriter, r *http.Request) {
+				order += name
+				next(w, r)
+			}
+		}
+	}
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		order += "h"
+	}
+
+	p := New()
+	p.Use(mw("m"))
+	g := p.GroupWithMore("/api", mw("g"))
+	g.Get("/secure", fn, mw("a"), mw("b"))
+	g.Get("/open", fn)
+	p.Match([]string{http.MethodPost, http.MethodPut}, "/match", fn, mw("a"))
+
+	code, _ := request(http.MethodGet, "/api/secure", p)
+	Equal(t, code, http.StatusOK)
+	Equal(t, order, "mgabh")
+
+	order = ""
+	code, _ = request(http.MethodGet, "/api/open", p)
+	Equal(t, code, http.StatusOK)
+	Equal(t, order, "mgh")
+
+	order = ""
+	code, _ = request(http.MethodPut, "/match", p)
+	Equal(t, code, http.StatusOK)
+	Equal(t, order, "mah")
+}
+
+func TestMatchCustomMethods(t *testing.T) {
+	var routes IRoutes = New()
+	routes.Match([]string{"PROPFIND", "PROPPATCH", "PROPFIND"}, "/dav/*", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Method))
+	})
+	routes.Handle("MKCOL", "/dav/*", defaultHandler)
+	p := routes.(*Mux)
+	p.SetStrictMethods()
+
+	for _, method := range []string{"PROPFIND", "PROPPATCH"} {
+		code, body := request(method, "/dav/docs", p)
+		Equal(t, code, http.StatusOK)
+		Equal(t, body, method)
+	}
+
+	code, _ := request("MKCOL", "/dav/docs", p)
+	Equal(t, code, http.StatusOK)
+
+	code, _ = request("LOCK", "/dav/docs", p)
+	Equal(t, code, http.StatusNotImplemented)
+	Equal(t, len(p.Routes()), 3)
+
+	PanicMatches(t, func() { p.Match([]string{"PROP FIND"}, "/x", defaultHandler) }, "invalid method 'PROP FIND' for path '/x'")
+	PanicMatches(t, func() { p.Handle("", "/x", defaultHandler) }, "invalid method '' for path '/x'")
+}
+
+func TestGroupErrorHandlers(t *testing.T) {
+	text := func(code int, body string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(code)
+			_, _ = w.Write([]byte(body))
+		}
+	}
+	mw := func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set(contentTypeHeader, applicationJSON)
+			next(w, r)
+		}
+	}
+
+	p := New()
+	p.Register404(text(http.StatusNotFound, "html"))
+	p.Get("/", defaultHandler)
+
+	api := p.Group("/api")
+	api.Register404(text(http.StatusNotFound, `{"error":"not found"}`), mw)
+	api.Get("/users", defaultHandler)
+
+	users := api.Group("/users/:id")
+	users.Register404(text(http.StatusNotFound, `{"error":"no such user resource"}`))
+	users.Register405(text(http.StatusMethodNotAllowed, `{"error":"method not allowed"}`))
+	users.Get("", defaultHandler)
+	users.Get("/posts", defaultHandler)
+
+	code, body := request(http.MethodGet, "/nope", p)
+	Equal(t, code, http.StatusNotFound)
+	Equal(t, body, "html")
+
+	code, body = request(http.MethodGet, "/apiv2", p)
+	Equal(t, code, http.StatusNotFound)
+	Equal(t, body, "html")
+
+	r, _ := http.NewRequest(http.MethodGet, "/api/nope", nil)
+	w := httptest.NewRecorder()
+	p.Serve().ServeHTTP(w, r)
+	Equal(t, w.Code, http.StatusNotFound)
+	Equal(t, w.Body.String(), `{"error":"not found"}`)
+	Equal(t, w.Header().Get(contentTypeHeader), applicationJSON)
+
+	code, body = request(http.MethodGet, "/api/users/13/nope", p)
+	Equal(t, code, http.StatusNotFound)
+	Equal(t, body, `{"error":"no such user resource"}`)
+
+	// 405 is only enabled below the users group
+	code, body = request(http.MethodPost, "/api/users", p)
+	Equal(t, code, http.StatusNotFound)
+	Equal(t, body, `{"error":"not found"}`)
+
+	r, _ = http.NewRequest(http.MethodPost, "/api/users/13/posts", nil)
+	w = httptest.NewRecorder()
+	p.Serve().ServeHTTP(w, r)
+	Equal(t, w.Code, http.StatusMethodNotAllowed)
+	Equal(t, w.Body.String(), `{"error":"method not allowed"}`)
+	Equal(t, w.Header()[allowHeader], []string{http.MethodGet})
+
+	p.RegisterMethodNotAllowed()
+	code, body = request(http.MethodPost, "/", p)
+	Equal(t, code, http.StatusMethodNotAllowed)
+	Equal(t, body, "")
+}
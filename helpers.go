@@ -1,16 +1,18 @@
 package feather
 
 import (
-	"compress/gzip"
-	"encoding/json"
 	"encoding/xml"
 	"io"
 	"mime"
 	"net"
 	"net/http"
+	"net/textproto"
 	"net/url"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
+	"unicode"
 )
 
 // QueryParamsOption represents the options for
@@ -38,16 +40,33 @@ const (
 	utf8                     = "utf-8"
 )
 
+// The QueryParamsOptions, the QueryParams function taking the natural name.
+const (
+	// IncludeQueryParams merges the query params and the URL params of the route into the decoded value.
+	IncludeQueryParams = httpQueryParams
+	// NoQueryParams decodes the request body only.
+	NoQueryParams = noQueryParams
+)
+
 var xmlHeaderBytes = []byte(xml.Header)
 
 // RequestVars returns the request scoped variables tracked by feather.
+// For requests not routed by feather, the URL params are extracted using
+// the extractors registered with RegisterParamExtractor, if any.
+//
+// Static routes registered without middleware and metadata are served without request variables,
+// unless a setting of the Mux reads them, so they don't allocate. The returned variables then only
+// hold the route, the values set by their handlers using Set, SetOutgoingHeader or SetBaggage aren't kept.
 func RequestVars(r *http.Request) ReqVars {
-	rv := r.Context().Value(defaultContextIdentifier)
-	if rv == nil {
-		return new(requestVars)
+	rv, ok := requestVarsOf(r)
+	if !ok {
+		if len(paramExtractors) > 0 {
+			return &requestVars{foreign: r, route: r.Pattern}
+		}
+		return &requestVars{route: r.Pattern}
 	}
 
-	return rv.(*requestVars)
+	return rv
 }
 
 // ParseForm calls the underlying http.Request ParseForm but also adds the
@@ -59,13 +78,11 @@ func ParseForm(r *http.Request) error {
 		return err
 	}
 
-	if rvi := r.Context().Value(defaultContextIdentifier); rvi != nil {
-		if rv := rvi.(*requestVars); !rv.formParsed {
-			for _, p := range rv.params {
-				r.Form.Add(p.key, p.value)
-			}
-			rv.formParsed = true
+	if rv, ok := requestVarsOf(r); ok && !rv.formParsed {
+		for _, p := range rv.params {
+			r.Form.Add(p.key, p.value)
 		}
+		rv.formParsed = true
 	}
 
 	return nil
@@ -76,30 +93,46 @@ func ParseForm(r *http.Request) error {
 // i.e. ?id=13&ok=true but does not add the params to the
 // http.Request.URL.RawQuery for SEO purposes.
 func ParseMultipartForm(r *http.Request, maxMemory int64) error {
-	if err := r.ParseMultipartForm(maxMemory); err != nil {
+	if err := parseMultipartForm(r, maxMemory); err != nil {
 		return err
 	}
 
-	if rvi := r.Context().Value(defaultContextIdentifier); rvi != nil {
-		if rv := rvi.(*requestVars); !rv.formParsed {
-			for _, p := range rv.params {
-				r.Form.Add(p.key, p.value)
-			}
-			rv.formParsed = true
+	if rv, ok := requestVarsOf(r); ok && !rv.formParsed {
+		for _, p := range rv.params {
+			r.Form.Add(p.key, p.value)
 		}
+		rv.formParsed = true
 	}
 
 	return nil
 }
 
+// parseMultipartForm parses the multipart form once, accounting the memory of the parsed form
+// for the rest of the request, see SetRequestMemoryLimit.
+func parseMultipartForm(r *http.Request, maxMemory int64) error {
+	if r.MultipartForm != nil {
+		return r.ParseMultipartForm(maxMemory)
+	}
+
+	if err := allocate(r, maxMemory, http.StatusRequestEntityTooLarge); err != nil {
+		return err
+	}
+
+	err := r.ParseMultipartForm(maxMemory)
+	if r.MultipartForm == nil {
+		Free(r, maxMemory)
+	}
+
+	return err
+}
+
 // Attachment is a helper method for returning an attachement file to be downloaded,
 // if a line needs to be opened, see the Inline function.
+// The Content-Disposition header is encoded following RFC 6266, see ContentDisposition,
+// and Content-Length is set if r is an io.Seeker, i.e. an *os.File.
+// The reader is copied as is, files supporting resumed downloads are served using File or FileFS.
 func Attachment(w http.ResponseWriter, r io.Reader, filename string) (err error) {
-	w.Header().Set(contentDispositionHeader, "attachment;filename="+filename)
-	w.Header().Set(contentTypeHeader, detectContentType(filename))
-	w.WriteHeader(http.StatusOK)
-	_, err = io.Copy(w, r)
-	return
+	return serveReader(w, r, "attachment", filename)
 }
 
 // AcceptedLanguages returns an array of accepted languages denoted by
@@ -121,15 +154,121 @@ func AcceptedLanguages(r *http.Request) (languages []string) {
 	return
 }
 
+// AddVary adds the header names to the Vary header of the response unless already listed,
+// combining all values into a single header so caches see one complete list.
+// Once * is listed other names are omitted.
+func AddVary(w http.ResponseWriter, headers ...string) {
+	h := w.Header()
+	existing := h.Values(varyHeader)
+	names := make([]string, 0, len(existing)+len(headers))
+	add := func(name string) {
+		name = strings.TrimSpace(name)
+		switch {
+		case name == blank:
+		case name == "*":
+			names = append(names[:0], name)
+		case len(names) == 1 && names[0] == "*":
+		default:
+			name = textproto.CanonicalMIMEHeaderKey(name)
+			if !slices.Contains(names, name) {
+				names = append(names, name)
+			}
+		}
+	}
+
+	for _, v := range existing {
+		for _, name := range strings.Split(v, ",") {
+			add(name)
+		}
+	}
+
+	for _, name := range headers {
+		add(name)
+	}
+
+	if len(names) > 0 {
+		h[varyHeader] = []string{strings.Join(names, ", ")}
+	}
+}
+
 // EncodeToURLValues encodes a struct or field into a set of url.Values.
 func EncodeToURLValues(v interface{}) (url.Values, error) {
 	return DefaultFormEncoder.Encode(v)
 }
 
 // Inline is a helper method for returning a file inline to be rendered/opened by the browser.
+// The headers are set like by Attachment.
+// The reader is copied as is, files supporting Range and conditional requests are served using File or FileFS.
 func Inline(w http.ResponseWriter, r io.Reader, filename string) (err error) {
-	w.Header().Set(contentDispositionHeader, "inline;filename="+filename)
-	w.Header().Set(contentTypeHeader, detectContentType(filename))
+	return serveReader(w, r, "inline", filename)
+}
+
+// ContentDisposition returns the Content-Disposition header value of the disposition type, i.e. attachment,
+// and filename following RFC 6266. The filename is quoted, with non-ASCII characters replaced in the quoted
+// fallback, and names containing non-ASCII characters are added UTF-8 encoded using the filename* parameter, i.e.
+//
+//	attachment; filename="na_ve report.pdf"; filename*=UTF-8''na%C3%AFve%20report.pdf
+func ContentDisposition(dispositionType string, filename string) string {
+	var b strings.Builder
+	b.WriteString(dispositionType)
+	b.WriteString(`; filename="`)
+	ascii := true
+	for _, c := range filename {
+		switch {
+		case c == '"' || c == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(c)
+		case c < ' ' || c == 0x7f:
+			b.WriteByte('_')
+		case c > unicode.MaxASCII:
+			ascii = false
+			b.WriteByte('_')
+		default:
+			b.WriteRune(c)
+		}
+	}
+	b.WriteByte('"')
+
+	if !ascii {
+		b.WriteString("; filename*=UTF-8''")
+		for i := 0; i < len(filename); i++ {
+			if c := filename[i]; isAttrChar(c) {
+				b.WriteByte(c)
+			} else {
+				b.WriteByte('%')
+				b.WriteByte(upperhex[c>>4])
+				b.WriteByte(upperhex[c&15])
+			}
+		}
+	}
+
+	return b.String()
+}
+
+// isAttrChar reports whether c may be used unencoded in an RFC 8187 ext-value.
+func isAttrChar(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || strings.IndexByte("!#$&+-.^_`|~", c) != -1
+}
+
+const upperhex = "0123456789ABCDEF"
+
+// serveReader writes r with the Content-Disposition of the disposition type and filename.
+func serveReader(w http.ResponseWriter, r io.Reader, dispositionType string, filename string) (err error) {
+	h := w.Header()
+	h.Set(contentDispositionHeader, ContentDisposition(dispositionType, filename))
+	h.Set(contentTypeHeader, detectContentType(filename))
+	if s, ok := r.(io.Seeker); ok && h.Get(contentLengthHeader) == blank {
+		// the remaining size, the reader may not be at its start
+		if cur, err := s.Seek(0, io.SeekCurrent); err == nil {
+			if end, err := s.Seek(0, io.SeekEnd); err == nil {
+				if _, err = s.Seek(cur, io.SeekStart); err != nil {
+					return err
+				}
+				h.Set(contentLengthHeader, strconv.FormatInt(end-cur, 10))
+			}
+		}
+	}
+
 	w.WriteHeader(http.StatusOK)
 	_, err = io.Copy(w, r)
 	return
@@ -192,20 +331,22 @@ func XMLBytes(w http.ResponseWriter, status int, b []byte) (err error) {
 
 // JSON marshals provided interface + returns JSON + status code.
 func JSON(w http.ResponseWriter, status int, i interface{}) error {
-	b, err := json.Marshal(i)
+	b, err := marshalJSON(i, blank)
 	if err != nil {
 		return err
 	}
 
 	w.Header().Set(contentTypeHeader, applicationJSON)
 	w.WriteHeader(status)
-	_, err = w.Write(b)
+	if err = writeJSONPrefix(w, b); err == nil {
+		_, err = w.Write(b)
+	}
 	return err
 }
 
 // JSONP sends a JSONP response with status code and uses `callback` to construct the JSONP payload.
 func JSONP(w http.ResponseWriter, status int, i interface{}, callback string) error {
-	b, err := json.Marshal(i)
+	b, err := marshalJSON(i, blank)
 	if err != nil {
 		return err
 	}
@@ -221,11 +362,29 @@ func JSONP(w http.ResponseWriter, status int, i interface{}, callback string) er
 	return err
 }
 
+// JSONPretty marshals provided interface indented using indent, i.e. two spaces,
+// for responses read by people + returns JSON + status code.
+func JSONPretty(w http.ResponseWriter, status int, i interface{}, indent string) error {
+	b, err := marshalJSON(i, indent)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set(contentTypeHeader, applicationJSON)
+	w.WriteHeader(status)
+	if err = writeJSONPrefix(w, b); err == nil {
+		_, err = w.Write(b)
+	}
+	return err
+}
+
 // JSONBytes returns provided JSON response with status code.
 func JSONBytes(w http.ResponseWriter, status int, b []byte) (err error) {
 	w.Header().Set(contentTypeHeader, applicationJSON)
 	w.WriteHeader(status)
-	_, err = w.Write(b)
+	if err = writeJSONPrefix(w, b); err == nil {
+		_, err = w.Write(b)
+	}
 	return err
 }
 
@@ -236,7 +395,15 @@ func JSONBytes(w http.ResponseWriter, status int, b []byte) (err error) {
 func JSONStream(w http.ResponseWriter, status int, i interface{}) error {
 	w.Header().Set(contentTypeHeader, applicationJSON)
 	w.WriteHeader(status)
-	return json.NewEncoder(w).Encode(i)
+	if isJSONArray(i) {
+		if prefix := jsonPrefixOf(w); prefix != blank {
+			if _, err := io.WriteString(w, prefix); err != nil {
+				return err
+			}
+		}
+	}
+
+	return streamJSON(w, i)
 }
 
 // QueryParams returns the r.URL.Query() values and optionally have them include the
@@ -245,8 +412,7 @@ func JSONStream(w http.ResponseWriter, status int, i interface{}) error {
 func QueryParams(r *http.Request, qp QueryParamsOption) (values url.Values) {
 	values = r.URL.Query()
 	if qp == httpQueryParams {
-		if rvi := r.Context().Value(defaultContextIdentifier); rvi != nil {
-			rv := rvi.(*requestVars)
+		if rv, ok := requestVarsOf(r); ok {
 			for _, p := range rv.params {
 				values.Add(p.key, p.value)
 			}
@@ -270,7 +436,7 @@ func DecodeMultipartForm(r *http.Request, qp QueryParamsOption, maxMemory int64,
 		}
 	}
 
-	if err = r.ParseMultipartForm(maxMemory); err == nil {
+	if err = parseMultipartForm(r, maxMemory); err == nil {
 		switch qp {
 		case httpQueryParams:
 			err = DefaultFormDecoder.Decode(v, r.Form)
@@ -284,14 +450,14 @@ func DecodeMultipartForm(r *http.Request, qp QueryParamsOption, maxMemory int64,
 
 // DecodeSEOQueryParams decodes the SEO Query params only and ignores the normal URL Query params.
 func DecodeSEOQueryParams(r *http.Request, v interface{}) (err error) {
-	if rvi := r.Context().Value(defaultContextIdentifier); rvi != nil {
-		rv := rvi.(*requestVars)
-		values := make(url.Values, len(rv.params))
+	if rv, ok := requestVarsOf(r); ok {
+		values := getValues()
 		for _, p := range rv.params {
 			values.Add(p.key, p.value)
 		}
 
 		err = DefaultFormDecoder.Decode(v, values)
+		putValues(values)
 	}
 
 	return
@@ -332,11 +498,14 @@ func DecodeForm(r *http.Request, qp QueryParamsOption, v interface{}) (err error
 // e. g. route /user/:id?test=true both 'id' and 'test' are treated as query params and added to parsed XML.
 // SEO query params are treated just like normal query params.
 func DecodeXML(r *http.Request, qp QueryParamsOption, maxMemory int64, v interface{}) error {
-	var values url.Values
-	if qp == httpQueryParams {
-		values = r.URL.Query()
+	free, err := allocateBody(r, maxMemory)
+	if err != nil {
+		return err
 	}
+	defer free()
 
+	values := queryValues(r, qp)
+	defer putValues(values)
 	return decodeXML(r.Header, r.Body, qp, values, maxMemory, v)
 }
 
@@ -349,11 +518,14 @@ func DecodeXML(r *http.Request, qp QueryParamsOption, maxMemory int64, v interfa
 // e. g. route /user/:id?test=true both 'id' and 'test' are treated as query params and added to parsed JSON.
 // SEO query params are treated just like normal query params.
 func DecodeJSON(r *http.Request, qp QueryParamsOption, maxMemory int64, v interface{}) error {
-	var values url.Values
-	if qp == httpQueryParams {
-		values = r.URL.Query()
+	free, err := allocateBody(r, maxMemory)
+	if err != nil {
+		return err
 	}
+	defer free()
 
+	values := queryValues(r, qp)
+	defer putValues(values)
 	return decodeJSON(r.Header, r.Body, qp, values, maxMemory, v)
 }
 
@@ -364,12 +536,24 @@ func DecodeJSON(r *http.Request, qp QueryParamsOption, maxMemory int64, v interf
 // no contentTypeHeader is specified the only difference is that
 // it will always decode SEO Query Params.
 func DecodeQueryParams(r *http.Request, qp QueryParamsOption, v interface{}) error {
-	return DefaultFormDecoder.Decode(v, QueryParams(r, qp))
+	values := getValues()
+	parseQuery(values, r.URL.RawQuery)
+	if qp == httpQueryParams {
+		if rv, ok := requestVarsOf(r); ok {
+			for _, p := range rv.params {
+				values.Add(p.key, p.value)
+			}
+		}
+	}
+
+	err := DefaultFormDecoder.Decode(v, values)
+	putValues(values)
+	return err
 }
 
 // Decode takes the request and attempts to discover it's content type via the
 // http headers and then decode the request body into the provided struct.
-// Example if header was "application/json" would decode using json.NewDecoder(ioext.LimitReader(r.Body, maxMemory)).Decode(v).
+// Example if header was "application/json" would decode the size limited body using the JSONEngine, see SetJSONEngine.
 //
 // NOTE: when qp=QueryParams both query params and SEO query params will be parsed and included
 // e. g. route /user/:id?test=true both 'id' and 'test' are treated as query params and added to the
@@ -381,6 +565,26 @@ func Decode(r *http.Request, qp QueryParamsOption, maxMemory int64, v interface{
 		typ = typ[:idx]
 	}
 
+	// registered decoders take precedence over the built-in ones
+	if fn := registeredDecoder(typ); fn != nil {
+		if qp == httpQueryParams {
+			if err = DecodeSEOQueryParams(r, v); err != nil {
+				return
+			}
+		}
+
+		var free func()
+		if free, err = allocateBody(r, maxMemory); err != nil {
+			return
+		}
+
+		values := queryValues(r, qp)
+		err = decodeBody(r.Header, r.Body, qp, values, maxMemory, v, fn)
+		putValues(values)
+		free()
+		return
+	}
+
 	switch typ {
 	case applicationForm:
 		err = DecodeForm(r, qp, v)
@@ -412,48 +616,69 @@ func detectContentType(filename string) string {
 	}
 }
 
-func decodeQueryParams(values url.Values, v interface{}) error {
-	return DefaultFormDecoder.Decode(v, values)
+// queryValues returns the query params of the request in pooled url.Values when qp=QueryParams,
+// nil otherwise, they must be released using putValues.
+func queryValues(r *http.Request, qp QueryParamsOption) url.Values {
+	if qp != httpQueryParams {
+		return nil
+	}
+
+	values := getValues()
+	parseQuery(values, r.URL.RawQuery)
+	return values
 }
 
-func decodeXML(headers http.Header, body io.Reader, qp QueryParamsOption, values url.Values, maxMemory int64, v interface{}) (err error) {
-	if encoding := headers.Get(contentEncodingHeader); encoding == gzipVal {
-		var gzr *gzip.Reader
-		gzr, err = gzip.NewReader(body)
+// parseQuery parses the raw query into values like url.ParseQuery, without allocating a new map.
+// Just like url.URL.Query malformed pairs are skipped.
+func parseQuery(values url.Values, query string) {
+	for query != blank {
+		var key string
+		key, query, _ = strings.Cut(query, "&")
+		if key == blank || strings.Contains(key, ";") {
+			continue
+		}
+
+		key, value, _ := strings.Cut(key, "=")
+		key, err := url.QueryUnescape(key)
 		if err != nil {
-			return
+			continue
 		}
 
-		defer func() {
-			_ = gzr.Close()
-		}()
+		value, err = url.QueryUnescape(value)
+		if err != nil {
+			continue
+		}
 
-		body = gzr
+		values[key] = append(values[key], value)
 	}
+}
 
-	err = xml.NewDecoder(LimitReader(body, maxMemory)).Decode(v)
-	if qp == httpQueryParams && err == nil {
-		err = decodeQueryParams(values, v)
-	}
+func decodeQueryParams(values url.Values, v interface{}) error {
+	return DefaultFormDecoder.Decode(v, values)
+}
 
-	return
+func decodeXML(headers http.Header, body io.Reader, qp QueryParamsOption, values url.Values, maxMemory int64, v interface{}) (err error) {
+	return decodeBody(headers, body, qp, values, maxMemory, v, func(body io.Reader, v interface{}) error {
+		return xml.NewDecoder(body).Decode(v)
+	})
 }
 
 func decodeJSON(headers http.Header, body io.Reader, qp QueryParamsOption, values url.Values, maxMemory int64, v interface{}) (err error) {
-	if encoding := headers.Get(contentEncodingHeader); encoding == gzipVal {
-		var gzr *gzip.Reader
-		gzr, err = gzip.NewReader(body)
-		if err != nil {
-			return
-		}
+	return decodeBody(headers, body, qp, values, maxMemory, v, func(body io.Reader, v interface{}) error {
+		return unmarshalJSON(body, v)
+	})
+}
 
-		defer func() {
-			_ = gzr.Close()
-		}()
-		body = gzr
+// decodeBody decodes the decompressed and size limited body using fn, see RegisterDecompressor,
+// then decodes the values when query params are included.
+func decodeBody(headers http.Header, body io.Reader, qp QueryParamsOption, values url.Values, maxMemory int64, v interface{}, fn DecoderFunc) (err error) {
+	body, closeFn, err := decompress(headers, body)
+	if err != nil {
+		return
 	}
+	defer closeFn()
 
-	err = json.NewDecoder(LimitReader(body, maxMemory)).Decode(v)
+	err = fn(LimitReader(body, maxMemory), v)
 	if qp == httpQueryParams && err == nil {
 		err = decodeQueryParams(values, v)
 	}
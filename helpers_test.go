@@ -144,6 +144,24 @@ func TestAcceptedLanguages(t *testing.T) {
 	Equal(t, len(languages), 0)
 }
 
+func TestAddVary(t *testing.T) {
+	w := httptest.NewRecorder()
+	AddVary(w, acceptHeader)
+	Equal(t, w.Header().Values(varyHeader), []string{acceptHeader})
+
+	w.Header().Add(varyHeader, "origin,  Accept-Encoding")
+	AddVary(w, "accept", "Accept-Language", acceptHeader)
+	Equal(t, w.Header().Values(varyHeader), []string{"Accept, Origin, Accept-Encoding, Accept-Language"})
+
+	AddVary(w, "*")
+	AddVary(w, "Cookie")
+	Equal(t, w.Header().Values(varyHeader), []string{"*"})
+
+	w = httptest.NewRecorder()
+	AddVary(w)
+	Equal(t, len(w.Header().Values(varyHeader)), 0)
+}
+
 func TestAttachment(t *testing.T) {
 	p := New()
 	p.Get("/dl", func(w http.ResponseWriter, r *http.Request) {
@@ -166,7 +184,7 @@ func TestAttachment(t *testing.T) {
 	hf := p.Serve()
 	hf.ServeHTTP(w, r)
 	Equal(t, w.Code, http.StatusOK)
-	Equal(t, w.Header().Get(contentDispositionHeader), "attachment;filename=logo.png")
+	Equal(t, w.Header().Get(contentDispositionHeader), `attachment; filename="logo.png"`)
 	Equal(t, w.Header().Get(contentTypeHeader), "image/png")
 	Equal(t, w.Body.Len(), 20797)
 
@@ -178,11 +196,46 @@ func TestAttachment(t *testing.T) {
 	hf = p.Serve()
 	hf.ServeHTTP(w, r)
 	Equal(t, w.Code, http.StatusOK)
-	Equal(t, w.Header().Get(contentDispositionHeader), "attachment;filename=logo")
+	Equal(t, w.Header().Get(contentDispositionHeader), `attachment; filename="logo"`)
 	Equal(t, w.Header().Get(contentTypeHeader), "application/octet-stream")
 	Equal(t, w.Body.Len(), 20797)
 }
 
+func TestContentDisposition(t *testing.T) {
+	tests := []struct {
+		filename string
+		expected string
+	}{
+		{filename: "report.pdf", expected: `attachment; filename="report.pdf"`},
+		{filename: "annual report.pdf", expected: `attachment; filename="annual report.pdf"`},
+		{filename: `say "hi"\.txt`, expected: `attachment; filename="say \"hi\"\\.txt"`},
+		{filename: "line\nbreak.txt", expected: `attachment; filename="line_break.txt"`},
+		{filename: "naïve report.pdf", expected: `attachment; filename="na_ve report.pdf"; filename*=UTF-8''na%C3%AFve%20report.pdf`},
+		{filename: "отчёт.txt", expected: `attachment; filename="_____.txt"; filename*=UTF-8''%D0%BE%D1%82%D1%87%D1%91%D1%82.txt`},
+	}
+
+	for _, tt := range tests {
+		Equal(t, ContentDisposition("attachment", tt.filename), tt.expected)
+	}
+}
+
+func TestAttachmentContentLength(t *testing.T) {
+	r := strings.NewReader("skipped,name\n1,joeybloggs\n")
+	_, _ = r.Seek(8, io.SeekStart)
+	w := httptest.NewRecorder()
+	Equal(t, Attachment(w, r, "users €.csv"), nil)
+	Equal(t, w.Header().Get(contentDispositionHeader), `attachment; filename="users _.csv"; filename*=UTF-8''users%20%E2%82%AC.csv`)
+	Equal(t, w.Header().Get(contentTypeHeader), "text/csv; charset=utf-8")
+	Equal(t, w.Header().Get(contentLengthHeader), "18")
+	Equal(t, w.Body.String(), "name\n1,joeybloggs\n")
+
+	// readers which are not seekers are streamed without Content-Length
+	w = httptest.NewRecorder()
+	Equal(t, Inline(w, io.LimitReader(strings.NewReader("body"), 4), "a.txt"), nil)
+	Equal(t, w.Header().Get(contentLengthHeader), "")
+	Equal(t, w.Body.String(), "body")
+}
+
 func TestEncodeToURLValues(t *testing.T) {
 	type Test struct {
 		Domain string `form:"domain"`
@@ -218,7 +271,7 @@ func TestInline(t *testing.T) {
 	hf := p.Serve()
 	hf.ServeHTTP(w, r)
 	Equal(t, w.Code, http.StatusOK)
-	Equal(t, w.Header().Get(contentDispositionHeader), "inline;filename=logo.png")
+	Equal(t, w.Header().Get(contentDispositionHeader), `inline; filename="logo.png"`)
 	Equal(t, w.Header().Get(contentTypeHeader), "image/png")
 	Equal(t, w.Body.Len(), 20797)
 
@@ -230,7 +283,7 @@ func TestInline(t *testing.T) {
 	hf = p.Serve()
 	hf.ServeHTTP(w, r)
 	Equal(t, w.Code, http.StatusOK)
-	Equal(t, w.Header().Get(contentDispositionHeader), "inline;filename=logo")
+	Equal(t, w.Header().Get(contentDispositionHeader), `inline; filename="logo"`)
 	Equal(t, w.Header().Get(contentTypeHeader), "application/octet-stream")
 	Equal(t, w.Body.Len(), 20797)
 }
@@ -442,6 +495,67 @@ func TestDecodeQueryParams(t *testing.T) {
 	Equal(t, test.ID, 14)
 }
 
+func TestParseQuery(t *testing.T) {
+	for _, query := range []string{
+		"",
+		"a=1&b=2&a=3",
+		"a&b=&=c",
+		"a%20b=c+d&e=%zz&f;g=1&h=1;2&&i=%C3%A9",
+	} {
+		expected, _ := url.ParseQuery(query)
+		values := getValues()
+		parseQuery(values, query)
+		Equal(t, values, expected)
+		putValues(values)
+	}
+
+	// pooled values are returned empty
+	values := getValues()
+	parseQuery(values, "a=1")
+	putValues(values)
+	putValues(nil)
+	Equal(t, len(getValues()), 0)
+}
+
+func benchmarkDecode(b *testing.B, path string, decode func(r *http.Request, v interface{}) error) {
+	type Test struct {
+		ID   int    `form:"id"`
+		Name string `form:"name"`
+	}
+
+	p := New()
+	p.Get("/users/:id", func(w http.ResponseWriter, r *http.Request) {
+		var test Test
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if err := decode(r, &test); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	r, _ := http.NewRequest(http.MethodGet, path, nil)
+	p.Serve().ServeHTTP(httptest.NewRecorder(), r)
+}
+
+func BenchmarkDecodeSEOQueryParams(b *testing.B) {
+	benchmarkDecode(b, "/users/13", DecodeSEOQueryParams)
+}
+
+func BenchmarkDecodeQueryParams(b *testing.B) {
+	benchmarkDecode(b, "/users/13?name=joeybloggs", func(r *http.Request, v interface{}) error {
+		return DecodeQueryParams(r, httpQueryParams, v)
+	})
+}
+
+func BenchmarkDecodeJSONQueryParams(b *testing.B) {
+	benchmarkDecode(b, "/users/13?name=joeybloggs", func(r *http.Request, v interface{}) error {
+		r.Body = io.NopCloser(strings.NewReader("{}"))
+		return DecodeJSON(r, httpQueryParams, 1<<10, v)
+	})
+}
+
 func TestDecode(t *testing.T) {
 	type TestStruct struct {
 		ID              int `form:"id"`
@@ -0,0 +1,76 @@
+package feather
+
+import (
+	"net"
+	"strings"
+)
+
+// Host creates a new sub router for the virtual host with the same prefix and middleware,
+// its routes are only matched for requests to the host, i.e. p.Host("api.example.com").Get("/users", ...).
+// Each host has separate routing trees, requests to hosts without routes of their own
+// use the routes registered without a host.
+// The host is matched case-insensitively and without the port, see SetIDNHosts for internationalized host names.
+func (g *routeGroup) Host(host string) IRouteGroup {
+	rg := &routeGroup{
+		prefix:     g.prefix,
+		host:       g.feather.hostKey(host),
+		feather:    g.feather,
+		middleware: make([]Middleware, len(g.middleware)),
+		meta:       g.meta,
+		policies:   g.policies,
+	}
+	copy(rg.middleware, g.middleware)
+	return rg
+}
+
+// treesOf returns the routing trees of the host, falling back to those of the default host.
+func (p *Mux) treesOf(host string) (trees map[string]*node, matched string) {
+	if len(p.hostTrees) == 0 {
+		return p.trees, blank
+	}
+
+	host = p.hostKey(host)
+	if trees, ok := p.hostTrees[host]; ok {
+		return trees, host
+	}
+
+	return p.trees, blank
+}
+
+// treesFor returns the routing trees routes of the host are registered in, creating them if necessary.
+func (p *Mux) treesFor(host string) map[string]*node {
+	if host == blank {
+		return p.trees
+	}
+
+	trees, ok := p.hostTrees[host]
+	if !ok {
+		if p.hostTrees == nil {
+			p.hostTrees = make(map[string]map[string]*node)
+		}
+
+		trees = make(map[string]*node)
+		p.hostTrees[host] = trees
+	}
+
+	return trees
+}
+
+// hostKey returns the host the routes of host are registered and looked up with.
+func (p *Mux) hostKey(host string) string {
+	host = normalizeHost(host)
+	if p.idnHosts && !isASCII(host) {
+		host = toASCIIHost(host)
+	}
+
+	return host
+}
+
+// normalizeHost lowercases the host and strips the port, if any.
+func normalizeHost(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	return strings.ToLower(strings.TrimSuffix(host, "."))
+}
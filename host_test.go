@@ -0,0 +1,81 @@
+package feather
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	. "github.com/pchchv/feather/assert"
+)
+
+func hostRequest(method string, host string, path string, p *Mux) (int, string) {
+	r, _ := http.NewRequest(method, path, nil)
+	r.Host = host
+	w := httptest.NewRecorder()
+	p.Serve().ServeHTTP(w, r)
+	return w.Code, w.Body.String()
+}
+
+func TestHost(t *testing.T) {
+	p := New()
+	p.Get("/users/:id", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("default " + RequestVars(r).URLParam("id")))
+	})
+
+	api := p.Host("API.example.com")
+	api.Get("/users/:id", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("api " + RequestVars(r).URLParam("id")))
+	})
+	api.Group("/v2").Get("/status", defaultHandler)
+
+	code, body := hostRequest(http.MethodGet, "api.example.com:8080", "/users/13", p)
+	Equal(t, code, http.StatusOK)
+	Equal(t, body, "api 13")
+
+	code, body = hostRequest(http.MethodGet, "www.example.com", "/users/13", p)
+	Equal(t, code, http.StatusOK)
+	Equal(t, body, "default 13")
+
+	code, _ = hostRequest(http.MethodGet, "api.example.com", "/v2/status", p)
+	Equal(t, code, http.StatusOK)
+
+	code, _ = hostRequest(http.MethodGet, "www.example.com", "/v2/status", p)
+	Equal(t, code, http.StatusNotFound)
+
+	// the trailing slash redirects use the tree of the host
+	code, _ = hostRequest(http.MethodGet, "api.example.com", "/v2/status/", p)
+	Equal(t, code, http.StatusMovedPermanently)
+
+	routes := p.Routes()
+	Equal(t, len(routes), 3)
+	Equal(t, routes[0].Host, "")
+	Equal(t, routes[1].Host, "api.example.com")
+	Equal(t, routes[1].Path, "/users/:id")
+	Equal(t, routes[2].Path, "/v2/status")
+
+	var b strings.Builder
+	Equal(t, p.DumpTree(&b), nil)
+	Equal(t, strings.Contains(b.String(), "api.example.com GET\n"), true)
+}
+
+func TestHostMethodNotAllowed(t *testing.T) {
+	p := New()
+	p.RegisterMethodNotAllowed()
+	p.SetNotFoundCacheSize(10)
+	p.Post("/items", defaultHandler)
+	p.Host("api.example.com").Get("/items", defaultHandler)
+
+	code, _ := hostRequest(http.MethodGet, "api.example.com", "/items", p)
+	Equal(t, code, http.StatusOK)
+
+	code, _ = hostRequest(http.MethodGet, "www.example.com", "/items", p)
+	Equal(t, code, http.StatusMethodNotAllowed)
+
+	// the not found cache is keyed by host
+	code, _ = hostRequest(http.MethodGet, "api.example.com", "/items", p)
+	Equal(t, code, http.StatusOK)
+
+	code, _ = hostRequest(http.MethodPut, "api.example.com", "/items", p)
+	Equal(t, code, http.StatusMethodNotAllowed)
+}
@@ -0,0 +1,164 @@
+package feather
+
+import (
+	"net/http"
+	"strings"
+	unicodeutf8 "unicode/utf8"
+)
+
+const (
+	// punycode parameters, see RFC 3492 section 5
+	punyBase        = 36
+	punyTMin        = 1
+	punyTMax        = 26
+	punySkew        = 38
+	punyDamp        = 700
+	punyInitialBias = 72
+	punyInitialN    = 128
+	acePrefix       = "xn--"
+)
+
+// SetIDNHosts tells feather whether internationalized host names are converted to their ASCII form,
+// i.e. bücher.example to xn--bcher-kva.example, so p.Host("bücher.example") matches the requests
+// of clients sending the host either way. The labels are lowercased but not mapped otherwise.
+// It must be set before registering the routes of the hosts.
+// By default, false and the hosts are only lowercased.
+func (p *Mux) SetIDNHosts(set bool) {
+	p.idnHosts = set
+}
+
+// SetPathNormalizer sets the function normalizing the request paths containing non-ASCII characters
+// before matching, so visually identical Unicode paths, which can be encoded differently,
+// match a single route, i.e. using the NFC normalization of golang.org/x/text/unicode/norm:
+//
+//	p.SetPathNormalizer(norm.NFC.String)
+//
+// The routes must be registered normalized. By default, nil and the paths are matched as requested.
+func (p *Mux) SetPathNormalizer(fn func(path string) string) {
+	p.pathNormalizer = fn
+}
+
+// normalizePath returns r with the path normalized by the path normalizer, if any.
+func (p *Mux) normalizePath(r *http.Request) *http.Request {
+	if p.pathNormalizer == nil || isASCII(r.URL.Path) {
+		return r
+	}
+
+	if path := p.pathNormalizer(r.URL.Path); path != r.URL.Path {
+		return withPath(r, path)
+	}
+
+	return r
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= unicodeutf8.RuneSelf {
+			return false
+		}
+	}
+
+	return true
+}
+
+// toASCIIHost converts the labels of host containing non-ASCII characters to punycode prefixed by xn--.
+// The ideographic full stops are label separators like the dot.
+func toASCIIHost(host string) string {
+	host = strings.NewReplacer("。", ".", "．", ".", "｡", ".").Replace(host)
+	labels := strings.Split(host, ".")
+	for i, label := range labels {
+		if !isASCII(label) {
+			labels[i] = acePrefix + punycode(label)
+		}
+	}
+
+	return strings.Join(labels, ".")
+}
+
+// punycode encodes s, see RFC 3492 section 6.3.
+// Invalid UTF-8 is encoded as U+FFFD, the labels of host names are too short to overflow.
+func punycode(s string) string {
+	runes := []rune(s)
+	out := make([]byte, 0, len(s)+8)
+	for _, r := range runes {
+		if r < unicodeutf8.RuneSelf {
+			out = append(out, byte(r))
+		}
+	}
+
+	basic := len(out)
+	handled := basic
+	if basic > 0 {
+		out = append(out, '-')
+	}
+
+	n, delta, bias := rune(punyInitialN), 0, punyInitialBias
+	for handled < len(runes) {
+		// the next code point to insert is the smallest one not handled yet
+		m := rune(unicodeutf8.MaxRune)
+		for _, r := range runes {
+			if r >= n && r < m {
+				m = r
+			}
+		}
+
+		delta += int(m-n) * (handled + 1)
+		n = m
+		for _, r := range runes {
+			if r < n {
+				delta++
+			}
+
+			if r != n {
+				continue
+			}
+
+			q := delta
+			for k := punyBase; ; k += punyBase {
+				t := min(max(k-bias, punyTMin), punyTMax)
+				if q < t {
+					break
+				}
+
+				out = append(out, punyDigit(t+(q-t)%(punyBase-t)))
+				q = (q - t) / (punyBase - t)
+			}
+
+			out = append(out, punyDigit(q))
+			bias = punyAdapt(delta, handled+1, handled == basic)
+			delta = 0
+			handled++
+		}
+
+		delta++
+		n++
+	}
+
+	return string(out)
+}
+
+// punyAdapt is the bias adaptation function, see RFC 3492 section 6.1.
+func punyAdapt(delta int, points int, first bool) int {
+	if first {
+		delta /= punyDamp
+	} else {
+		delta /= 2
+	}
+
+	delta += delta / points
+	k := 0
+	for delta > ((punyBase-punyTMin)*punyTMax)/2 {
+		delta /= punyBase - punyTMin
+		k += punyBase
+	}
+
+	return k + (punyBase-punyTMin+1)*delta/(delta+punySkew)
+}
+
+func punyDigit(d int) byte {
+	if d < 26 {
+		return byte('a' + d)
+	}
+
+	return byte('0' + d - 26)
+}
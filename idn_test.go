@@ -0,0 +1,80 @@
+package feather
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	. "github.com/pchchv/feather/assert"
+)
+
+func TestPunycode(t *testing.T) {
+	tests := []struct {
+		host     string
+		expected string
+	}{
+		{host: "bücher.example", expected: "xn--bcher-kva.example"},
+		{host: "münchen.de", expected: "xn--mnchen-3ya.de"},
+		{host: "españa.com", expected: "xn--espaa-rta.com"},
+		{host: "例え.テスト", expected: "xn--r8jz45g.xn--zckzah"},
+		{host: "пример。рф", expected: "xn--e1afmkfd.xn--p1ai"},
+		{host: "example.com", expected: "example.com"},
+	}
+
+	for _, tt := range tests {
+		Equal(t, toASCIIHost(tt.host), tt.expected)
+	}
+}
+
+func TestIDNHosts(t *testing.T) {
+	p := New()
+	p.SetIDNHosts(true)
+	p.Get("/", defaultHandler)
+	p.Host("Bücher.example").Get("/", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("books"))
+	})
+
+	for _, host := range []string{"xn--bcher-kva.example", "bücher.example:8080", "BÜCHER.example"} {
+		code, body := hostRequest(http.MethodGet, host, "/", p)
+		Equal(t, code, http.StatusOK)
+		Equal(t, body, "books")
+	}
+
+	code, body := hostRequest(http.MethodGet, "example.com", "/", p)
+	Equal(t, code, http.StatusOK)
+	Equal(t, body, http.MethodGet)
+
+	// disabled
+	p = New()
+	p.Host("bücher.example").Get("/", defaultHandler)
+	code, _ = hostRequest(http.MethodGet, "xn--bcher-kva.example", "/", p)
+	Equal(t, code, http.StatusNotFound)
+}
+
+func TestPathNormalizer(t *testing.T) {
+	var calls int
+	p := New()
+	// composes e and the combining acute accent, standing in for norm.NFC.String
+	p.SetPathNormalizer(func(path string) string {
+		calls++
+		return strings.ReplaceAll(path, "e\u0301", "\u00e9")
+	})
+	p.Get("/caf\u00e9/:name", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.URL.Path + " " + RequestVars(r).URLParam("name")))
+	})
+
+	code, body := request(http.MethodGet, "/cafe%CC%81/cre%CC%80me", p)
+	Equal(t, code, http.StatusOK)
+	Equal(t, body, "/caf\u00e9/cre\u0300me cre\u0300me")
+	Equal(t, calls, 1)
+
+	code, body = request(http.MethodGet, "/caf\u00e9/creme", p)
+	Equal(t, code, http.StatusOK)
+	Equal(t, body, "/caf\u00e9/creme creme")
+	Equal(t, calls, 2)
+
+	// ASCII paths are not normalized
+	code, _ = request(http.MethodGet, "/cafe/creme", p)
+	Equal(t, code, http.StatusNotFound)
+	Equal(t, calls, 2)
+}
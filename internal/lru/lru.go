@@ -0,0 +1,68 @@
+// Package lru implements the access ordered LRU cache shared by feather and its middlewares.
+package lru
+
+import (
+	"container/list"
+	"sync"
+)
+
+// Cache is an access ordered LRU cache safe for concurrent use,
+// it holds up to size values, evicting the least recently used first.
+type Cache[V any] struct {
+	m     sync.Mutex
+	size  int
+	ll    *list.List // front is the most recently used
+	items map[string]*list.Element
+}
+
+type entry[V any] struct {
+	key   string
+	value V
+}
+
+// New returns a cache holding up to size values.
+func New[V any](size int) *Cache[V] {
+	return &Cache[V]{
+		size:  size,
+		ll:    list.New(),
+		items: make(map[string]*list.Element, size),
+	}
+}
+
+// Get returns the value of key and marks it as the most recently used.
+func (c *Cache[V]) Get(key string) (v V, ok bool) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	e, ok := c.items[key]
+	if !ok {
+		return
+	}
+
+	c.ll.MoveToFront(e)
+	return e.Value.(*entry[V]).value, true
+}
+
+// Add sets the value of key, evicting the least recently used value when the cache is full.
+func (c *Cache[V]) Add(key string, v V) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	if e, ok := c.items[key]; ok {
+		e.Value.(*entry[V]).value = v
+		c.ll.MoveToFront(e)
+		return
+	}
+
+	c.items[key] = c.ll.PushFront(&entry[V]{key: key, value: v})
+	if c.ll.Len() > c.size {
+		oldest := c.ll.Back()
+		c.ll.Remove(oldest)
+		delete(c.items, oldest.Value.(*entry[V]).key)
+	}
+}
+
+// Len returns the number of values cached.
+func (c *Cache[V]) Len() int {
+	c.m.Lock()
+	defer c.m.Unlock()
+	return c.ll.Len()
+}
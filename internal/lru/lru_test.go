@@ -0,0 +1,31 @@
+package lru
+
+import (
+	"testing"
+
+	. "github.com/pchchv/feather/assert"
+)
+
+func TestCache(t *testing.T) {
+	c := New[string](2)
+	c.Add("a", "/a")
+	c.Add("b", "/b")
+
+	// a is now the most recently used, so b is evicted
+	v, ok := c.Get("a")
+	Equal(t, ok, true)
+	Equal(t, v, "/a")
+	c.Add("c", "/c")
+
+	_, ok = c.Get("b")
+	Equal(t, ok, false)
+	_, ok = c.Get("a")
+	Equal(t, ok, true)
+	_, ok = c.Get("c")
+	Equal(t, ok, true)
+
+	c.Add("c", "/c2")
+	v, _ = c.Get("c")
+	Equal(t, v, "/c2")
+	Equal(t, c.Len(), 2)
+}
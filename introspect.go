@@ -0,0 +1,150 @@
+package feather
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// closureSuffix matches the suffixes the runtime adds to names of closures and method values.
+var closureSuffix = regexp.MustCompile(`(\.func\d+|\.\d+|-fm)+$`)
+
+// RouteInfo describes a registered route.
+type RouteInfo struct {
+	Method string `json:"method"`
+	// Host is the virtual host of the route, blank for routes of all hosts, see Host.
+	Host string `json:"host,omitempty"`
+	Path string `json:"path"`
+	// Consumes and Produces are the media types declared using Meta, i.e. for generating documentation.
+	Consumes []string `json:"consumes,omitempty"`
+	Produces []string `json:"produces,omitempty"`
+	// Middleware contains the names of the middleware wrapping the handler in the order they run,
+	// group middleware first. Names are derived from the function names,
+	// i.e. gzip.Gzip or cors.New for a middleware returned by cors.New.
+	Middleware []string `json:"middleware,omitempty"`
+	// Policies contains the names of the policies protecting the route, see WithPolicy.
+	Policies []string `json:"policies,omitempty"`
+}
+
+// WriteRoutes writes the route manifest, the registered routes as a JSON array sorted like Routes, to w.
+// Applications call it behind a flag of their own, i.e. -routes, for `feather routes` to inspect their binary.
+func (p *Mux) WriteRoutes(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent(blank, "  ")
+	return enc.Encode(p.Routes())
+}
+
+// Routes returns the registered routes sorted by host, path and method.
+func (p *Mux) Routes() []RouteInfo {
+	routes := make([]RouteInfo, len(p.routes))
+	copy(routes, p.routes)
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Host != routes[j].Host {
+			return routes[i].Host < routes[j].Host
+		}
+
+		if routes[i].Path != routes[j].Path {
+			return routes[i].Path < routes[j].Path
+		}
+
+		return routes[i].Method < routes[j].Method
+	})
+
+	return routes
+}
+
+// DumpTree writes the routing tree of each method to w including the middleware of each route,
+// followed by the trees of the virtual hosts, intended for debugging.
+func (p *Mux) DumpTree(w io.Writer) error {
+	middleware := make(map[string][]string, len(p.routes))
+	for _, ri := range p.routes {
+		middleware[ri.Method+" "+ri.Host+ri.Path] = ri.Middleware
+	}
+
+	hosts := make([]string, 0, len(p.hostTrees))
+	for host := range p.hostTrees {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+
+	var b strings.Builder
+	dumpTrees(&b, p.trees, blank, middleware)
+	for _, host := range hosts {
+		dumpTrees(&b, p.hostTrees[host], host, middleware)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// dumpTrees writes the trees of each method, prefixed with the host if any.
+func dumpTrees(b *strings.Builder, trees map[string]*node, host string, middleware map[string][]string) {
+	methods := make([]string, 0, len(trees))
+	for m := range trees {
+		methods = append(methods, m)
+	}
+	sort.Strings(methods)
+
+	for _, m := range methods {
+		if host != blank {
+			b.WriteString(host + " ")
+		}
+
+		b.WriteString(m + "\n")
+		trees[m].dump(b, 1, func(route string) []string {
+			return middleware[m+" "+host+route]
+		})
+	}
+}
+
+func (n *node) dump(b *strings.Builder, depth int, middleware func(route string) []string) {
+	b.WriteString(strings.Repeat("  ", depth) + n.path)
+	if n.handler != nil {
+		fmt.Fprintf(b, " -> %s [%s]", n.route, strings.Join(middleware(n.route), ", "))
+	}
+	b.WriteByte('\n')
+
+	for _, c := range n.children {
+		c.dump(b, depth+1, middleware)
+	}
+
+	if n.paramChild != nil {
+		n.paramChild.dump(b, depth+1, middleware)
+	}
+
+	if n.catchAll != nil {
+		n.catchAll.dump(b, depth+1, middleware)
+	}
+}
+
+// middlewareNames returns the names of the middleware functions.
+func middlewareNames(middleware ...[]Middleware) (names []string) {
+	for _, mw := range middleware {
+		for _, m := range mw {
+			names = append(names, funcName(m))
+		}
+	}
+
+	return
+}
+
+// funcName returns the name of fn without its package path and closure suffixes,
+// i.e. github.com/pchchv/feather/middlewares/cors.New.func1 becomes cors.New.
+func funcName(fn any) string {
+	f := runtime.FuncForPC(reflect.ValueOf(fn).Pointer())
+	if f == nil {
+		return "unknown"
+	}
+
+	name := f.Name()
+	if i := strings.LastIndexByte(name, '/'); i != -1 {
+		name = name[i+1:]
+	}
+
+	return strings.TrimSuffix(closureSuffix.ReplaceAllString(name, blank), ".glob.")
+}
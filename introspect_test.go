@@ -0,0 +1,74 @@
+package feather
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	. "github.com/pchchv/feather/assert"
+)
+
+func authMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return next
+}
+
+func limit(n int) Middleware {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			next(w, r)
+		}
+	}
+}
+
+func TestRoutes(t *testing.T) {
+	p := New()
+	p.Use(authMiddleware)
+	p.Get("/", defaultHandler)
+	admin := p.GroupWithMore("/admin", limit(10))
+	admin.Get("/users/:id", defaultHandler, authMiddleware)
+	admin.Delete("/users/:id", defaultHandler)
+
+	Equal(t, p.Routes(), []RouteInfo{
+		{Method: http.MethodGet, Path: "/", Middleware: []string{"feather.authMiddleware"}},
+		{Method: http.MethodDelete, Path: "/admin/users/:id", Middleware: []string{"feather.authMiddleware", "feather.limit"}},
+		{Method: http.MethodGet, Path: "/admin/users/:id", Middleware: []string{"feather.authMiddleware", "feather.limit", "feather.authMiddleware"}},
+	})
+
+	var b strings.Builder
+	Equal(t, p.DumpTree(&b), nil)
+	Equal(t, b.String(), `DELETE
+  /admin/users/
+    :id -> /admin/users/:id [feather.authMiddleware, feather.limit]
+GET
+  / -> / [feather.authMiddleware]
+    admin/users/
+      :id -> /admin/users/:id [feather.authMiddleware, feather.limit, feather.authMiddleware]
+`)
+}
+
+func TestWriteRoutes(t *testing.T) {
+	p := New()
+	p.Get("/users/:id", defaultHandler, authMiddleware)
+	p.Host("api.example.com").WithMeta(Meta{Produces: []string{"application/json"}}).Post("/users", defaultHandler)
+
+	var b strings.Builder
+	Equal(t, p.WriteRoutes(&b), nil)
+	Equal(t, b.String(), `[
+  {
+    "method": "GET",
+    "path": "/users/:id",
+    "middleware": [
+      "feather.authMiddleware"
+    ]
+  },
+  {
+    "method": "POST",
+    "host": "api.example.com",
+    "path": "/users",
+    "produces": [
+      "application/json"
+    ]
+  }
+]
+`)
+}
@@ -0,0 +1,170 @@
+package feather
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"reflect"
+	"sync"
+)
+
+// SecureJSONPrefix is the prefix commonly used against JSON hijacking, see SetSecureJSONPrefix.
+const SecureJSONPrefix = ")]}',\n"
+
+// JSONEngine encodes and decodes the JSON of the helpers, JSON, JSONP, JSONPretty, JSONStream,
+// Render, Negotiate and the Decode functions, see SetJSONEngine.
+// Third party packages such as jsoniter or go-json are used through a small adapter
+// returning their encoders and decoders.
+type JSONEngine interface {
+	Marshal(v interface{}) ([]byte, error)
+	NewEncoder(w io.Writer) JSONEncoder
+	NewDecoder(r io.Reader) JSONDecoder
+}
+
+// JSONEncoder writes JSON values to an output stream, see json.Encoder.
+type JSONEncoder interface {
+	Encode(v interface{}) error
+	SetEscapeHTML(on bool)
+	SetIndent(prefix, indent string)
+}
+
+// JSONDecoder reads JSON values from an input stream, see json.Decoder.
+type JSONDecoder interface {
+	Decode(v interface{}) error
+}
+
+// stdJSON is the default JSONEngine using encoding/json.
+type stdJSON struct{}
+
+func (stdJSON) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (stdJSON) NewEncoder(w io.Writer) JSONEncoder {
+	return json.NewEncoder(w)
+}
+
+func (stdJSON) NewDecoder(r io.Reader) JSONDecoder {
+	return json.NewDecoder(r)
+}
+
+var (
+	jsonMu         sync.RWMutex
+	jsonEngine     JSONEngine = stdJSON{}
+	jsonEscapeHTML            = true
+)
+
+// SetJSONEngine sets the engine encoding and decoding JSON, i.e. to use a faster third party package.
+// A nil engine restores encoding/json.
+// Default is encoding/json.
+func SetJSONEngine(engine JSONEngine) {
+	if engine == nil {
+		engine = stdJSON{}
+	}
+
+	jsonMu.Lock()
+	jsonEngine = engine
+	jsonMu.Unlock()
+}
+
+// SetEscapeHTML sets whether the JSON helpers escape the HTML characters <, > and & in strings,
+// so JSON can be embedded in HTML safely, disabling it keeps URLs and markup readable.
+// Default is true.
+func SetEscapeHTML(escape bool) {
+	jsonMu.Lock()
+	jsonEscapeHTML = escape
+	jsonMu.Unlock()
+}
+
+// SetSecureJSONPrefix sets the prefix written before JSON responses whose top-level value is an array,
+// by JSON, JSONPretty, JSONBytes, JSONStream, Render and Negotiate, usually SecureJSONPrefix.
+// The prefix makes the responses invalid JavaScript, so legacy browsers vulnerable to JSON hijacking
+// can't execute them using script tags, clients strip it before parsing the JSON.
+// Default is blank, no prefix.
+func (p *Mux) SetSecureJSONPrefix(prefix string) {
+	p.jsonPrefix = prefix
+}
+
+// jsonPrefixOf returns the prefix set using SetSecureJSONPrefix by the Mux serving w, blank if none.
+func jsonPrefixOf(w http.ResponseWriter) string {
+	if rw, ok := ResponseWriterOf(w); ok {
+		if rw, ok := rw.(*responseWriter); ok {
+			return rw.jsonPrefix
+		}
+	}
+
+	return blank
+}
+
+// writeJSONPrefix writes the prefix set using SetSecureJSONPrefix if b is a JSON array.
+func writeJSONPrefix(w http.ResponseWriter, b []byte) error {
+	if b = bytes.TrimLeft(b, " \t\r\n"); len(b) == 0 || b[0] != '[' {
+		return nil
+	}
+
+	prefix := jsonPrefixOf(w)
+	if prefix == blank {
+		return nil
+	}
+
+	_, err := io.WriteString(w, prefix)
+	return err
+}
+
+// isJSONArray reports whether v is encoded as a JSON array, a slice other than []byte or an array.
+func isJSONArray(v interface{}) bool {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if t == nil {
+		return false
+	}
+
+	return t.Kind() == reflect.Array || (t.Kind() == reflect.Slice && t.Elem().Kind() != reflect.Uint8)
+}
+
+func jsonSettings() (JSONEngine, bool) {
+	jsonMu.RLock()
+	defer jsonMu.RUnlock()
+	return jsonEngine, jsonEscapeHTML
+}
+
+// marshalJSON returns the JSON encoding of v, indented using indent if not blank,
+// without a trailing newline.
+func marshalJSON(v interface{}, indent string) ([]byte, error) {
+	engine, escapeHTML := jsonSettings()
+	if escapeHTML && indent == blank {
+		return engine.Marshal(v)
+	}
+
+	var buf bytes.Buffer
+	if err := encodeJSONTo(&buf, engine, escapeHTML, v, indent); err != nil {
+		return nil, err
+	}
+
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// streamJSON writes the JSON encoding of v followed by a newline to w.
+func streamJSON(w io.Writer, v interface{}) error {
+	engine, escapeHTML := jsonSettings()
+	return encodeJSONTo(w, engine, escapeHTML, v, blank)
+}
+
+func encodeJSONTo(w io.Writer, engine JSONEngine, escapeHTML bool, v interface{}, indent string) error {
+	enc := engine.NewEncoder(w)
+	enc.SetEscapeHTML(escapeHTML)
+	if indent != blank {
+		enc.SetIndent(blank, indent)
+	}
+
+	return enc.Encode(v)
+}
+
+func unmarshalJSON(r io.Reader, v interface{}) error {
+	engine, _ := jsonSettings()
+	return engine.NewDecoder(r).Decode(v)
+}
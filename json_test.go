@@ -0,0 +1,140 @@
+package feather
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	. "github.com/pchchv/feather/assert"
+)
+
+// countingJSON is a JSONEngine counting its uses.
+type countingJSON struct {
+	stdJSON
+	marshals, encoders, decoders int
+}
+
+func (c *countingJSON) Marshal(v interface{}) ([]byte, error) {
+	c.marshals++
+	return c.stdJSON.Marshal(v)
+}
+
+func (c *countingJSON) NewEncoder(w io.Writer) JSONEncoder {
+	c.encoders++
+	return c.stdJSON.NewEncoder(w)
+}
+
+func (c *countingJSON) NewDecoder(r io.Reader) JSONDecoder {
+	c.decoders++
+	return c.stdJSON.NewDecoder(r)
+}
+
+func TestJSONPretty(t *testing.T) {
+	w := httptest.NewRecorder()
+	Equal(t, JSONPretty(w, http.StatusCreated, map[string]interface{}{"id": 1, "tags": []string{"a"}}, "  "), nil)
+	Equal(t, w.Code, http.StatusCreated)
+	Equal(t, w.Header().Get(contentTypeHeader), applicationJSON)
+	Equal(t, w.Body.String(), "{\n  \"id\": 1,\n  \"tags\": [\n    \"a\"\n  ]\n}")
+
+	w = httptest.NewRecorder()
+	NotEqual(t, JSONPretty(w, http.StatusOK, func() {}, "  "), nil)
+}
+
+func TestSetEscapeHTML(t *testing.T) {
+	defer SetEscapeHTML(true)
+	v := map[string]string{"url": "/a?b=1&c=<d>"}
+
+	w := httptest.NewRecorder()
+	Equal(t, JSON(w, http.StatusOK, v), nil)
+	Equal(t, w.Body.String(), `{"url":"/a?b=1\u0026c=\u003cd\u003e"}`)
+
+	SetEscapeHTML(false)
+	w = httptest.NewRecorder()
+	Equal(t, JSON(w, http.StatusOK, v), nil)
+	Equal(t, w.Body.String(), `{"url":"/a?b=1&c=<d>"}`)
+
+	w = httptest.NewRecorder()
+	Equal(t, JSONStream(w, http.StatusOK, v), nil)
+	Equal(t, w.Body.String(), "{\"url\":\"/a?b=1&c=<d>\"}\n")
+
+	w = httptest.NewRecorder()
+	Equal(t, JSONP(w, http.StatusOK, v, "cb"), nil)
+	Equal(t, w.Body.String(), `cb({"url":"/a?b=1&c=<d>"});`)
+}
+
+func TestSetJSONEngine(t *testing.T) {
+	engine := &countingJSON{}
+	SetJSONEngine(engine)
+	defer SetJSONEngine(nil)
+
+	w := httptest.NewRecorder()
+	Equal(t, JSON(w, http.StatusOK, []int{1}), nil)
+	Equal(t, w.Body.String(), "[1]")
+	Equal(t, JSONStream(httptest.NewRecorder(), http.StatusOK, []int{1}), nil)
+	Equal(t, JSONPretty(httptest.NewRecorder(), http.StatusOK, []int{1}, "\t"), nil)
+
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"id": 7}`))
+	r.Header.Set(contentTypeHeader, applicationJSON)
+	var body struct {
+		ID int `json:"id"`
+	}
+	Equal(t, DecodeJSON(r, NoQueryParams, 1<<10, &body), nil)
+	Equal(t, body.ID, 7)
+
+	Equal(t, engine.marshals, 1)
+	Equal(t, engine.encoders, 2)
+	Equal(t, engine.decoders, 1)
+
+	SetJSONEngine(nil)
+	_, ok := jsonEngine.(stdJSON)
+	Equal(t, ok, true)
+}
+
+func TestSecureJSONPrefix(t *testing.T) {
+	p := New()
+	p.SetSecureJSONPrefix(SecureJSONPrefix)
+	p.Get("/json", func(w http.ResponseWriter, r *http.Request) {
+		_ = JSON(w, http.StatusOK, []int{1, 2})
+	})
+	p.Get("/object", func(w http.ResponseWriter, r *http.Request) {
+		_ = JSON(w, http.StatusOK, map[string]int{"a": 1})
+	})
+	p.Get("/pretty", func(w http.ResponseWriter, r *http.Request) {
+		_ = JSONPretty(w, http.StatusOK, []int{1}, " ")
+	})
+	p.Get("/bytes", func(w http.ResponseWriter, r *http.Request) {
+		_ = JSONBytes(w, http.StatusOK, []byte(" [1]"))
+	})
+	p.Get("/stream", func(w http.ResponseWriter, r *http.Request) {
+		_ = JSONStream(w, http.StatusOK, &[1]string{"a"})
+	})
+	p.Get("/stream-bytes", func(w http.ResponseWriter, r *http.Request) {
+		_ = JSONStream(w, http.StatusOK, []byte("a"))
+	})
+	p.Get("/render", func(w http.ResponseWriter, r *http.Request) {
+		_ = Render(w, r, http.StatusOK, []string{"a"})
+	})
+
+	tests := map[string]string{
+		"/json":         ")]}',\n[1,2]",
+		"/object":       `{"a":1}`,
+		"/pretty":       ")]}',\n[\n 1\n]",
+		"/bytes":        ")]}',\n [1]",
+		"/stream":       ")]}',\n[\"a\"]\n",
+		"/stream-bytes": "\"YQ==\"\n",
+		"/render":       ")]}',\n[\"a\"]",
+	}
+
+	for path, expected := range tests {
+		code, body := request(http.MethodGet, path, p)
+		Equal(t, code, http.StatusOK)
+		Equal(t, body, expected)
+	}
+
+	// outside of a Mux there is no prefix
+	w := httptest.NewRecorder()
+	Equal(t, JSON(w, http.StatusOK, []int{1}), nil)
+	Equal(t, w.Body.String(), "[1]")
+}
@@ -0,0 +1,63 @@
+package feather
+
+import (
+	"log/slog"
+	"net/http"
+)
+
+// LogField returns an attribute added to every request scoped logger,
+// e.g. the request id or the authenticated principal.
+type LogField func(r *http.Request) slog.Attr
+
+// StringField returns a LogField adding the value returned by fn under key,
+// the attribute is omitted when fn returns blank.
+func StringField(key string, fn func(r *http.Request) string) LogField {
+	return func(r *http.Request) slog.Attr {
+		if v := fn(r); v != blank {
+			return slog.String(key, v)
+		}
+
+		return slog.Attr{}
+	}
+}
+
+// SetLogger sets the logger from which the request scoped loggers returned by Logger are derived.
+// Every request scoped logger has the method and route fields plus any of the provided fields.
+// By default slog.Default() is used.
+func (p *Mux) SetLogger(l *slog.Logger, fields ...LogField) {
+	p.logger = l
+	p.logFields = fields
+}
+
+// Logger returns the request scoped logger, pre-populated with the method, route
+// and the fields configured using SetLogger.
+// The logger is built once per request and must not be retained after the request completes.
+func Logger(r *http.Request) *slog.Logger {
+	rv, ok := requestVarsOf(r)
+	if !ok {
+		// the pattern is set for the plain static routes served without request variables
+		if r.Pattern != blank {
+			return slog.Default().With(slog.String("method", r.Method), slog.String("route", r.Pattern))
+		}
+		return slog.Default().With(slog.String("method", r.Method))
+	}
+
+	if rv.logger == nil {
+		l := rv.mux.logger
+		if l == nil {
+			l = slog.Default()
+		}
+
+		attrs := make([]any, 0, len(rv.mux.logFields)+2)
+		attrs = append(attrs, slog.String("method", r.Method), slog.String("route", rv.route))
+		for _, f := range rv.mux.logFields {
+			if a := f(r); a.Key != blank {
+				attrs = append(attrs, a)
+			}
+		}
+
+		rv.logger = l.With(attrs...)
+	}
+
+	return rv.logger
+}
@@ -0,0 +1,32 @@
+package feather
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+
+	. "github.com/pchchv/feather/assert"
+)
+
+func TestLogger(t *testing.T) {
+	var buff bytes.Buffer
+	p := New()
+	p.SetLogger(slog.New(slog.NewTextHandler(&buff, nil)),
+		StringField("request_id", func(r *http.Request) string { return r.Header.Get("X-Request-Id") }),
+		StringField("principal", func(r *http.Request) string { return "" }),
+	)
+	p.Get("/users/:id", func(w http.ResponseWriter, r *http.Request) {
+		Equal(t, Logger(r), Logger(r))
+		Logger(r).Info("hello")
+	})
+
+	r, _ := http.NewRequest(http.MethodGet, "/users/13", nil)
+	r.Header.Set("X-Request-Id", "abc")
+	p.Serve().ServeHTTP(nil, r)
+
+	out := buff.String()
+	Equal(t, strings.Contains(out, "msg=hello method=GET route=/users/:id request_id=abc"), true)
+	Equal(t, strings.Contains(out, "principal"), false)
+}
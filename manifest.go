@@ -0,0 +1,133 @@
+package feather
+
+import (
+	"encoding/json"
+	"errors"
+	"html/template"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+const immutableCacheControl = "public, max-age=31536000, immutable"
+
+// Manifest maps the source files of a frontend build to their fingerprinted files,
+// read from the manifest.json produced by Vite or webpack-manifest-plugin.
+type Manifest struct {
+	prefix  string
+	entries map[string]manifestEntry
+	files   map[string]struct{} // fingerprinted files relative to the static root
+}
+
+type manifestEntry struct {
+	File string   `json:"file"`
+	CSS  []string `json:"css"`
+}
+
+// LoadManifest reads the manifest at name in fsys, the fingerprinted files are served under prefix
+// by Static or StaticFS, i.e.
+//
+//	m, err := feather.LoadManifest(dist, ".vite/manifest.json", "/assets")
+//	p.StaticFS("/assets", dist, feather.StaticOptions{Manifest: m})
+//
+// Both the Vite format, whose entries are objects with file and css fields,
+// and the webpack format, whose entries are the fingerprinted file names, are supported.
+func LoadManifest(fsys fs.FS, name string, prefix string) (*Manifest, error) {
+	b, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw map[string]json.RawMessage
+	if err = json.Unmarshal(b, &raw); err != nil {
+		return nil, err
+	}
+
+	m := &Manifest{
+		prefix:  strings.TrimSuffix(prefix, basePath),
+		entries: make(map[string]manifestEntry, len(raw)),
+		files:   make(map[string]struct{}),
+	}
+	for k, v := range raw {
+		var e manifestEntry
+		if err = json.Unmarshal(v, &e.File); err != nil {
+			if err = json.Unmarshal(v, &e); err != nil {
+				return nil, err
+			}
+		}
+
+		m.entries[k] = e
+		for _, f := range append([]string{e.File}, e.CSS...) {
+			if name, ok := m.relative(f); ok {
+				m.files[name] = struct{}{}
+			}
+		}
+	}
+
+	return m, nil
+}
+
+// URL returns the URL of the fingerprinted file of the source file, i.e. /assets/main-4889e940.js for src/main.ts.
+func (m *Manifest) URL(name string) (string, error) {
+	e, ok := m.entries[name]
+	if !ok {
+		return blank, errors.New("feather: '" + name + "' not found in manifest")
+	}
+
+	return m.url(e.File), nil
+}
+
+// CSS returns the URLs of the stylesheets imported by the source file, Vite manifests only.
+func (m *Manifest) CSS(name string) []string {
+	e := m.entries[name]
+	urls := make([]string, len(e.CSS))
+	for i, f := range e.CSS {
+		urls[i] = m.url(f)
+	}
+
+	return urls
+}
+
+// TemplateFuncs returns the asset and assetCSS template funcs, i.e.
+//
+//	<script type="module" src="{{ asset "src/main.ts" }}"></script>
+//	{{ range assetCSS "src/main.ts" }}<link rel="stylesheet" href="{{ . }}">{{ end }}
+//
+// Executing a template with a source file missing from the manifest fails.
+func (m *Manifest) TemplateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"asset":    m.URL,
+		"assetCSS": m.CSS,
+	}
+}
+
+// fingerprinted reports whether the file, relative to the static root, is listed in the manifest.
+func (m *Manifest) fingerprinted(name string) bool {
+	_, ok := m.files[name]
+	return ok
+}
+
+func (m *Manifest) url(file string) string {
+	if isAbsoluteURL(file) {
+		return file
+	}
+
+	return m.prefix + path.Clean(basePath+file)
+}
+
+// relative returns the file of the manifest relative to the static root, false if not served under the prefix.
+func (m *Manifest) relative(file string) (string, bool) {
+	switch {
+	case file == blank || strings.Contains(file, "://"):
+		return blank, false
+	case strings.HasPrefix(file, basePath):
+		name, ok := strings.CutPrefix(file, m.prefix+basePath)
+		return name, ok
+	}
+
+	return path.Clean(file), true
+}
+
+func isAbsoluteURL(s string) bool {
+	return strings.HasPrefix(s, basePath) || strings.Contains(s, "://")
+}
@@ -0,0 +1,76 @@
+package feather
+
+import (
+	"errors"
+	"net/http"
+)
+
+// ErrMemoryLimit is the internal error of the *HTTPError returned when a request
+// exceeds the memory limit set using SetRequestMemoryLimit.
+var ErrMemoryLimit = errors.New("feather: request memory limit exceeded")
+
+// SetRequestMemoryLimit limits the bytes buffered per request by the decoders, the parsed multipart forms,
+// Render and HTML, and by handlers accounting their buffers using Allocate,
+// so a single request can't exhaust the memory of a multi-tenant server.
+// Exceeding the limit returns an *HTTPError wrapping ErrMemoryLimit, 413 Request Entity Too Large
+// while reading the request and 507 Insufficient Storage otherwise, which HandleErrors answers.
+// Decoding accounts the Content-Length of the body, or the maxMemory passed to the decoder
+// if the length is unknown or the body is compressed.
+// 0, the default, disables the accounting.
+func (p *Mux) SetRequestMemoryLimit(limit int64) {
+	p.memoryLimit = limit
+}
+
+// Allocate accounts n bytes buffered by the handler of the request against the limit set using SetRequestMemoryLimit,
+// returning a 507 Insufficient Storage *HTTPError if it is exceeded. Buffers released before the request completes
+// are returned using Free.
+func Allocate(r *http.Request, n int64) error {
+	return allocate(r, n, http.StatusInsufficientStorage)
+}
+
+// Free returns n bytes accounted using Allocate.
+func Free(r *http.Request, n int64) {
+	if rv, ok := requestVarsOf(r); ok && rv.mux.memoryLimit > 0 {
+		rv.memory.Add(-n)
+	}
+}
+
+// MemoryUsed returns the bytes currently accounted for the request, 0 if the accounting is disabled.
+func MemoryUsed(r *http.Request) int64 {
+	if rv, ok := requestVarsOf(r); ok && rv.mux.memoryLimit > 0 {
+		return rv.memory.Load()
+	}
+
+	return 0
+}
+
+// allocate accounts n bytes, answering with status if the limit is exceeded.
+// Failed allocations are not accounted.
+func allocate(r *http.Request, n int64, status int) error {
+	rv, ok := requestVarsOf(r)
+	if !ok || rv.mux.memoryLimit <= 0 {
+		return nil
+	}
+
+	if rv.memory.Add(n) > rv.mux.memoryLimit {
+		rv.memory.Add(-n)
+		return NewHTTPError(status, blank).WithInternal(ErrMemoryLimit)
+	}
+
+	return nil
+}
+
+// allocateBody accounts the bytes decoding the body may buffer, at most maxMemory,
+// returning the function freeing them once decoded.
+func allocateBody(r *http.Request, maxMemory int64) (free func(), err error) {
+	n := maxMemory
+	if r.ContentLength >= 0 && r.ContentLength < n && r.Header.Get(contentEncodingHeader) == blank {
+		n = r.ContentLength
+	}
+
+	if err = allocate(r, n, http.StatusRequestEntityTooLarge); err != nil {
+		return nil, err
+	}
+
+	return func() { Free(r, n) }, nil
+}
@@ -0,0 +1,84 @@
+package feather
+
+import (
+	"bytes"
+	"errors"
+	"html/template"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	. "github.com/pchchv/feather/assert"
+)
+
+func TestRequestMemoryLimit(t *testing.T) {
+	p := New()
+	p.SetRequestMemoryLimit(1 << 10)
+	p.Post("/json", HandleErrors(func(w http.ResponseWriter, r *http.Request) error {
+		var v map[string]string
+		if err := DecodeJSON(r, NoQueryParams, 4<<10, &v); err != nil {
+			return err
+		}
+
+		// the decode buffers are freed once decoded
+		Equal(t, MemoryUsed(r), int64(0))
+		return JSON(w, http.StatusOK, v)
+	}))
+	p.Post("/form", HandleErrors(func(w http.ResponseWriter, r *http.Request) error {
+		if err := ParseMultipartForm(r, 512); err != nil {
+			return err
+		}
+
+		// the parsed form is kept for the rest of the request
+		Equal(t, MemoryUsed(r), int64(512))
+		return nil
+	}))
+	p.Get("/buffers", HandleErrors(func(w http.ResponseWriter, r *http.Request) error {
+		Equal(t, Allocate(r, 1000), nil)
+		err := Allocate(r, 100)
+		Equal(t, errors.Is(err, ErrMemoryLimit), true)
+		Equal(t, MemoryUsed(r), int64(1000))
+
+		Free(r, 1000)
+		return Render(w, r, http.StatusOK, strings.Repeat("a", 2<<10))
+	}))
+	p.Get("/page", HandleErrors(func(w http.ResponseWriter, r *http.Request) error {
+		return HTML(w, r, http.StatusOK, template.Must(template.New("").Parse("{{.}}")), blank, strings.Repeat("a", 2<<10))
+	}))
+
+	serve := func(r *http.Request) *httptest.ResponseRecorder {
+		r.Header.Set(acceptHeader, applicationJSONNoCharset)
+		w := httptest.NewRecorder()
+		p.Serve().ServeHTTP(w, r)
+		return w
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/json", strings.NewReader(`{"a": "b"}`))
+	Equal(t, serve(r).Code, http.StatusOK)
+
+	// the body is larger than the limit
+	r = httptest.NewRequest(http.MethodPost, "/json", strings.NewReader(`{"a": "`+strings.Repeat("b", 2<<10)+`"}`))
+	Equal(t, serve(r).Code, http.StatusRequestEntityTooLarge)
+
+	// the size of bodies of unknown length is bounded by maxMemory
+	r = httptest.NewRequest(http.MethodPost, "/json", strings.NewReader(`{"a": "b"}`))
+	r.ContentLength = -1
+	Equal(t, serve(r).Code, http.StatusRequestEntityTooLarge)
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	_ = mw.WriteField("a", "b")
+	_ = mw.Close()
+	r = httptest.NewRequest(http.MethodPost, "/form", &body)
+	r.Header.Set(contentTypeHeader, mw.FormDataContentType())
+	Equal(t, serve(r).Code, http.StatusOK)
+
+	Equal(t, serve(httptest.NewRequest(http.MethodGet, "/buffers", nil)).Code, http.StatusInsufficientStorage)
+	Equal(t, serve(httptest.NewRequest(http.MethodGet, "/page", nil)).Code, http.StatusInsufficientStorage)
+
+	// disabled
+	p.SetRequestMemoryLimit(0)
+	Equal(t, serve(httptest.NewRequest(http.MethodGet, "/page", nil)).Code, http.StatusOK)
+}
@@ -0,0 +1,129 @@
+package feather
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// Compression is the compression setting of a route, consulted by compression middleware.
+type Compression uint8
+
+// Compression settings.
+const (
+	// CompressionDefault leaves the decision to the compression middleware.
+	CompressionDefault Compression = iota
+	// CompressionEnabled requests responses to be compressed.
+	CompressionEnabled
+	// CompressionDisabled requests responses not to be compressed, i.e. for already compressed content.
+	CompressionDisabled
+)
+
+// Meta contains the operational settings of a group or route, zero values are unset.
+type Meta struct {
+	// Timeout sets the deadline of the request context.
+	Timeout time.Duration
+	// MaxBodySize limits the number of bytes read from the request body,
+	// reading beyond the limit returns an *http.MaxBytesError.
+	MaxBodySize int64
+	// Compression is consulted by compression middleware such as middlewares/gzip.
+	Compression Compression
+	// Consumes lists the media types of the request bodies the route accepts, i.e. application/json,
+	// enforced by middleware such as middlewares/mediatype.
+	Consumes []string
+	// Produces lists the media types the route responds with, i.e. application/json,
+	// requests not accepting any of them are answered with 406 Not Acceptable when enabled using SetStrictAccept
+	// or by middleware such as middlewares/mediatype.
+	Produces []string
+}
+
+// isZero reports whether no value is set.
+func (m Meta) isZero() bool {
+	return m.Timeout == 0 && m.MaxBodySize == 0 && m.Compression == CompressionDefault &&
+		len(m.Consumes) == 0 && len(m.Produces) == 0
+}
+
+// merge returns m overridden by the set values of o.
+func (m Meta) merge(o Meta) Meta {
+	if o.Timeout > 0 {
+		m.Timeout = o.Timeout
+	}
+
+	if o.MaxBodySize > 0 {
+		m.MaxBodySize = o.MaxBodySize
+	}
+
+	if o.Compression != CompressionDefault {
+		m.Compression = o.Compression
+	}
+
+	if len(o.Consumes) > 0 {
+		m.Consumes = o.Consumes
+	}
+
+	if len(o.Produces) > 0 {
+		m.Produces = o.Produces
+	}
+
+	return m
+}
+
+// WithMeta returns a group with the same prefix and middleware whose routes also carry meta,
+// values set in meta override those of the group, i.e.
+//
+//	api := p.Group("/api").WithMeta(feather.Meta{Timeout: 5 * time.Second})
+//	api.WithMeta(feather.Meta{MaxBodySize: 10 << 20}).Post("/upload", upload)
+func (g *routeGroup) WithMeta(meta Meta) IRouteGroup {
+	rg := &routeGroup{
+		prefix:     g.prefix,
+		host:       g.host,
+		feather:    g.feather,
+		middleware: make([]Middleware, len(g.middleware)),
+		meta:       g.meta.merge(meta),
+		policies:   g.policies,
+	}
+	copy(rg.middleware, g.middleware)
+	return rg
+}
+
+// RouteMeta returns the metadata of the matched route.
+// Since it is set before any middleware runs it is also available to middleware registered using Use.
+func RouteMeta(r *http.Request) Meta {
+	rv, ok := requestVarsOf(r)
+	if !ok || rv.meta == nil {
+		return Meta{}
+	}
+
+	return *rv.meta
+}
+
+// withMeta wraps the fully chained handler of a route, enforcing the Timeout, MaxBodySize
+// and, if enabled, the Produces media types.
+func withMeta(meta Meta, h http.HandlerFunc) http.HandlerFunc {
+	if meta.isZero() {
+		return h
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if rv, ok := requestVarsOf(r); ok {
+			rv.meta = &meta
+			if rv.mux.strictAccept && len(meta.Produces) > 0 && NegotiateContentType(r, meta.Produces...) == blank {
+				AddVary(w, acceptHeader)
+				http.Error(w, http.StatusText(http.StatusNotAcceptable), http.StatusNotAcceptable)
+				return
+			}
+		}
+
+		if meta.MaxBodySize > 0 && r.Body != nil {
+			r.Body = http.MaxBytesReader(w, r.Body, meta.MaxBodySize)
+		}
+
+		if meta.Timeout > 0 {
+			ctx, cancel := context.WithTimeout(r.Context(), meta.Timeout)
+			defer cancel()
+			r = r.WithContext(ctx)
+		}
+
+		h(w, r)
+	}
+}
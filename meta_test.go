@@ -0,0 +1,109 @@
+package feather
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/pchchv/feather/assert"
+)
+
+func TestMeta(t *testing.T) {
+	var meta Meta
+	var deadline bool
+	var readErr error
+	p := New()
+	p.Use(func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			// available to global middleware
+			meta = RouteMeta(r)
+			next(w, r)
+		}
+	})
+
+	h := func(w http.ResponseWriter, r *http.Request) {
+		_, deadline = r.Context().Deadline()
+		_, readErr = io.ReadAll(r.Body)
+	}
+
+	api := p.Group("/api").WithMeta(Meta{Timeout: time.Second, Compression: CompressionDisabled})
+	api.Post("/users", h)
+	api.WithMeta(Meta{MaxBodySize: 4, Compression: CompressionEnabled}).Post("/upload", h)
+	api.Group("/v2").Post("/users", h)
+	p.Post("/plain", h)
+
+	hf := p.Serve()
+	do := func(path string) {
+		r := httptest.NewRequest(http.MethodPost, path, strings.NewReader("too large"))
+		hf.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	do("/api/users")
+	Equal(t, meta, Meta{Timeout: time.Second, Compression: CompressionDisabled})
+	Equal(t, deadline, true)
+	Equal(t, readErr, nil)
+
+	do("/api/upload")
+	Equal(t, meta, Meta{Timeout: time.Second, MaxBodySize: 4, Compression: CompressionEnabled})
+	var maxErr *http.MaxBytesError
+	Equal(t, errors.As(readErr, &maxErr), true)
+
+	do("/api/v2/users")
+	Equal(t, meta.Timeout, time.Second)
+
+	do("/plain")
+	Equal(t, meta, Meta{})
+	Equal(t, deadline, false)
+	Equal(t, readErr, nil)
+}
+
+func TestStrictAccept(t *testing.T) {
+	p := New()
+	api := p.Group("/api").WithMeta(Meta{Produces: []string{applicationJSONNoCharset}})
+	api.Get("/users", func(w http.ResponseWriter, r *http.Request) {
+		_ = JSON(w, http.StatusOK, RouteMeta(r).Produces)
+	})
+	api.WithMeta(Meta{Produces: []string{"text/csv"}}).Get("/export", defaultHandler)
+	p.Get("/plain", defaultHandler)
+
+	do := func(path string, accept string) *httptest.ResponseRecorder {
+		r, _ := http.NewRequest(http.MethodGet, path, nil)
+		if accept != "" {
+			r.Header.Set(acceptHeader, accept)
+		}
+
+		w := httptest.NewRecorder()
+		p.Serve().ServeHTTP(w, r)
+		return w
+	}
+
+	// disabled by default
+	w := do("/api/users", "text/html")
+	Equal(t, w.Code, http.StatusOK)
+	Equal(t, w.Body.String(), `["application/json"]`)
+
+	p.SetStrictAccept(true)
+
+	w = do("/api/users", "text/html")
+	Equal(t, w.Code, http.StatusNotAcceptable)
+	Equal(t, w.Header().Get(varyHeader), acceptHeader)
+
+	w = do("/api/users", "text/html, application/*;q=0.5")
+	Equal(t, w.Code, http.StatusOK)
+
+	w = do("/api/users", "")
+	Equal(t, w.Code, http.StatusOK)
+
+	w = do("/api/export", "application/json")
+	Equal(t, w.Code, http.StatusNotAcceptable)
+
+	w = do("/api/export", "text/csv")
+	Equal(t, w.Code, http.StatusOK)
+
+	w = do("/plain", "image/png")
+	Equal(t, w.Code, http.StatusOK)
+}
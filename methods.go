@@ -0,0 +1,77 @@
+package feather
+
+import (
+	"net/http"
+	"strings"
+)
+
+var (
+	// defaultAnyMethods are the methods registered by Any unless configured using SetAnyMethods,
+	// i.e. all standard methods except CONNECT and TRACE.
+	defaultAnyMethods = []string{
+		http.MethodDelete,
+		http.MethodGet,
+		http.MethodHead,
+		http.MethodOptions,
+		http.MethodPatch,
+		http.MethodPost,
+		http.MethodPut,
+	}
+
+	// defaultStrictMethods are the methods accepted by SetStrictMethods when none are provided.
+	defaultStrictMethods = defaultAnyMethods
+)
+
+// SetStrictMethods restricts the methods accepted by the Mux to the provided ones,
+// or all standard methods except CONNECT and TRACE if none are provided.
+// Requests using other methods are answered with 501 Not Implemented,
+// unless a route was explicitly registered for the method, e.g. using Trace or Handle.
+// Any only registers the accepted methods and they are the only ones listed in Allow headers.
+func (p *Mux) SetStrictMethods(methods ...string) {
+	if len(methods) == 0 {
+		methods = defaultStrictMethods
+	}
+
+	p.strictMethods = make(map[string]struct{}, len(methods))
+	for _, m := range methods {
+		p.strictMethods[m] = struct{}{}
+	}
+}
+
+// SetAnyMethods sets the methods registered by Any for routes registered afterwards.
+// By default all standard methods except CONNECT and TRACE, which are rarely intended
+// and often forbidden by security policies.
+func (p *Mux) SetAnyMethods(methods ...string) {
+	p.anyMethods = methods
+}
+
+// accepts returns whether requests using the method are accepted.
+func (p *Mux) accepts(method string) bool {
+	if p.strictMethods == nil {
+		return true
+	}
+
+	if _, ok := p.strictMethods[method]; ok {
+		return true
+	}
+
+	_, ok := p.explicitMethods[method]
+	return ok
+}
+
+// validMethod reports whether the method is a token, see RFC 9110 section 9.1,
+// methods are case-sensitive and not validated otherwise so any extension method can be registered.
+func validMethod(method string) bool {
+	if method == blank {
+		return false
+	}
+
+	for i := 0; i < len(method); i++ {
+		c := method[i]
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.IndexByte("!#$%&'*+-.^_`|~", c) != -1) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,237 @@
+// Package cache provides middleware caching the responses of GET requests in memory,
+// which can be primed on startup using Warmup.
+package cache
+
+import (
+	"container/list"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pchchv/feather"
+)
+
+const (
+	defaultTTL         = time.Minute
+	defaultMaxEntries  = 1024
+	defaultMaxBodySize = 1 << 20
+	cacheControlHeader = "Cache-Control"
+	setCookieHeader    = "Set-Cookie"
+	authorization      = "Authorization"
+	ageHeader          = "Age"
+	varyHeader         = "Vary"
+)
+
+// Config contains the cache settings.
+type Config struct {
+	// Store keeps the cached responses, by default a Store of 1024 entries.
+	Store *Store
+	// TTL is the time the responses are cached, 1 minute by default.
+	TTL time.Duration
+	// MaxBodySize is the maximum size of the cached response bodies, larger responses are not cached, 1MB by default.
+	MaxBodySize int
+	// Key returns the key the response of the request is cached with,
+	// by default the host and the request URI, so the query is part of the key.
+	Key func(r *http.Request) string
+}
+
+// Store keeps the cached responses in memory, evicting the least recently used first.
+// It is safe for concurrent use and can be shared by several cache middlewares.
+type Store struct {
+	max     int
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List // most recently used first
+}
+
+type entry struct {
+	key     string
+	status  int
+	header  http.Header
+	body    []byte
+	expires time.Time
+	created time.Time
+}
+
+// NewStore returns a store of up to maxEntries responses, 1024 if maxEntries <= 0.
+func NewStore(maxEntries int) *Store {
+	if maxEntries <= 0 {
+		maxEntries = defaultMaxEntries
+	}
+
+	return &Store{
+		max:     maxEntries,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// Len returns the number of cached responses, including expired ones not evicted yet.
+func (s *Store) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.order.Len()
+}
+
+// Purge removes all cached responses.
+func (s *Store) Purge() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	clear(s.entries)
+	s.order.Init()
+}
+
+func (s *Store) get(k string, now time.Time) (*entry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	el, ok := s.entries[k]
+	if !ok {
+		return nil, false
+	}
+
+	e := el.Value.(*entry)
+	if !now.Before(e.expires) {
+		s.order.Remove(el)
+		delete(s.entries, k)
+		return nil, false
+	}
+
+	s.order.MoveToFront(el)
+	return e, true
+}
+
+func (s *Store) add(e *entry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if el, ok := s.entries[e.key]; ok {
+		el.Value = e
+		s.order.MoveToFront(el)
+		return
+	}
+
+	s.entries[e.key] = s.order.PushFront(e)
+	if s.order.Len() > s.max {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		delete(s.entries, oldest.Value.(*entry).key)
+	}
+}
+
+// New returns the middleware answering GET requests with the cached responses
+// and caching the 200 OK responses of the others.
+// Requests carrying credentials and responses setting cookies, varying by a header
+// or marked as private or no-store by Cache-Control are not cached.
+func New(cfg Config) feather.Middleware {
+	if cfg.Store == nil {
+		cfg.Store = NewStore(defaultMaxEntries)
+	}
+
+	if cfg.TTL <= 0 {
+		cfg.TTL = defaultTTL
+	}
+
+	if cfg.MaxBodySize <= 0 {
+		cfg.MaxBodySize = defaultMaxBodySize
+	}
+
+	if cfg.Key == nil {
+		cfg.Key = key
+	}
+
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet || r.Header.Get(authorization) != "" {
+				next(w, r)
+				return
+			}
+
+			k := cfg.Key(r)
+			now := time.Now()
+			if e, ok := cfg.Store.get(k, now); ok {
+				h := w.Header()
+				for name, values := range e.header {
+					h[name] = values
+				}
+				h.Set(ageHeader, strconv.Itoa(int(now.Sub(e.created)/time.Second)))
+				w.WriteHeader(e.status)
+				_, _ = w.Write(e.body)
+				return
+			}
+
+			rec := &recorder{ResponseWriter: w, max: cfg.MaxBodySize}
+			next(rec, r)
+			if rec.cacheable() {
+				now = time.Now()
+				cfg.Store.add(&entry{
+					key:     k,
+					status:  rec.status,
+					header:  w.Header().Clone(),
+					body:    rec.body,
+					expires: now.Add(cfg.TTL),
+					created: now,
+				})
+			}
+		}
+	}
+}
+
+// key returns the host and the request URI.
+func key(r *http.Request) string {
+	return r.Host + r.URL.RequestURI()
+}
+
+// recorder records the response written through it, up to max bytes of the body.
+type recorder struct {
+	http.ResponseWriter
+	status   int
+	body     []byte
+	max      int
+	overflow bool // the body exceeds max and is not recorded
+}
+
+func (w *recorder) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *recorder) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+
+	if !w.overflow {
+		if len(w.body)+len(b) > w.max {
+			w.overflow = true
+			w.body = nil
+		} else {
+			w.body = append(w.body, b...)
+		}
+	}
+
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap returns the underlying writer, it is used by http.ResponseController.
+func (w *recorder) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// cacheable reports whether the recorded response can be cached.
+func (w *recorder) cacheable() bool {
+	if w.status != http.StatusOK || w.overflow {
+		return false
+	}
+
+	h := w.Header()
+	if h.Get(setCookieHeader) != "" || h.Get(varyHeader) != "" {
+		return false
+	}
+
+	cc := strings.ToLower(h.Get(cacheControlHeader))
+	return !strings.Contains(cc, "no-store") && !strings.Contains(cc, "private")
+}
@@ -0,0 +1,147 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pchchv/feather"
+	. "github.com/pchchv/feather/assert"
+)
+
+func serve(h http.Handler, method string, target string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, nil)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestCache(t *testing.T) {
+	var calls int
+	p := feather.New()
+	p.Use(New(Config{}))
+	p.Get("/page", func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = w.Write([]byte("page " + strconv.Itoa(calls)))
+	})
+	p.Get("/private", func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Cache-Control", "private")
+		_, _ = w.Write([]byte("private " + strconv.Itoa(calls)))
+	})
+	p.Get("/missing", func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		http.NotFound(w, r)
+	})
+	p.Post("/page", func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		_, _ = w.Write([]byte("post " + strconv.Itoa(calls)))
+	})
+	h := p.Serve()
+
+	w := serve(h, http.MethodGet, "/page")
+	Equal(t, w.Body.String(), "page 1")
+	Equal(t, w.Header().Get("Age"), "")
+
+	w = serve(h, http.MethodGet, "/page")
+	Equal(t, w.Code, http.StatusOK)
+	Equal(t, w.Body.String(), "page 1")
+	Equal(t, w.Header().Get("Content-Type"), "text/plain")
+	Equal(t, w.Header().Get("Age"), "0")
+
+	w = serve(h, http.MethodGet, "/page?q=1")
+	Equal(t, w.Body.String(), "page 2")
+
+	w = serve(h, http.MethodPost, "/page")
+	Equal(t, w.Body.String(), "post 3")
+
+	serve(h, http.MethodGet, "/private")
+	w = serve(h, http.MethodGet, "/private")
+	Equal(t, w.Body.String(), "private 5")
+
+	serve(h, http.MethodGet, "/missing")
+	serve(h, http.MethodGet, "/missing")
+	Equal(t, calls, 7)
+
+	r := httptest.NewRequest(http.MethodGet, "/page", nil)
+	r.Header.Set("Authorization", "Bearer token")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	Equal(t, w.Body.String(), "page 8")
+}
+
+func TestCacheStore(t *testing.T) {
+	var calls int
+	store := NewStore(2)
+	p := feather.New()
+	p.Use(New(Config{Store: store, TTL: 50 * time.Millisecond, MaxBodySize: 4}))
+	p.Get("/:name", func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		_, _ = w.Write([]byte(feather.RequestVars(r).URLParam("name")))
+	})
+	h := p.Serve()
+
+	serve(h, http.MethodGet, "/a")
+	serve(h, http.MethodGet, "/b")
+	serve(h, http.MethodGet, "/a")
+	Equal(t, calls, 2)
+	Equal(t, store.Len(), 2)
+
+	// b is the least recently used
+	serve(h, http.MethodGet, "/c")
+	Equal(t, store.Len(), 2)
+	serve(h, http.MethodGet, "/a")
+	Equal(t, calls, 3)
+	serve(h, http.MethodGet, "/b")
+	Equal(t, calls, 4)
+
+	// too large to be cached
+	serve(h, http.MethodGet, "/large")
+	w := serve(h, http.MethodGet, "/large")
+	Equal(t, w.Body.String(), "large")
+	Equal(t, calls, 6)
+
+	time.Sleep(60 * time.Millisecond)
+	serve(h, http.MethodGet, "/b")
+	Equal(t, calls, 7)
+
+	store.Purge()
+	Equal(t, store.Len(), 0)
+}
+
+func TestWarmup(t *testing.T) {
+	var calls int
+	p := feather.New()
+	p.Use(New(Config{}))
+	p.Get("/expensive", func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		_, _ = w.Write([]byte(r.Host + " " + strconv.Itoa(calls)))
+	})
+	p.Get("/broken", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	h := p.Serve()
+
+	err := Warmup(context.Background(), h, "http://example.com/expensive", "/broken", "/missing")
+	NotEqual(t, err, nil)
+	Equal(t, strings.Contains(err.Error(), "/broken: status 500"), true)
+	Equal(t, strings.Contains(err.Error(), "/missing: status 404"), true)
+	Equal(t, strings.Contains(err.Error(), "expensive"), false)
+	Equal(t, calls, 1)
+
+	w := serve(h, http.MethodGet, "http://example.com/expensive")
+	Equal(t, w.Body.String(), "example.com 1")
+	Equal(t, calls, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = Warmup(ctx, h, "/expensive")
+	Equal(t, errors.Is(err, context.Canceled), true)
+	Equal(t, calls, 1)
+}
@@ -0,0 +1,73 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Warmup primes the cache by dispatching a GET request for each target to h, typically the handler
+// returned by Serve, so the expensive pages are cached before the first clients request them:
+//
+//	h := p.Serve()
+//	if err := cache.Warmup(ctx, h, "/", "/products", "https://shop.example.com/catalog"); err != nil {
+//		log.Println("cache warmup:", err)
+//	}
+//
+// The targets are paths or absolute URLs, the host of which is part of the default cache key,
+// so the routes served by host must be warmed up using absolute URLs.
+// The responses are discarded, the targets not answered with 200 OK are reported in the returned error.
+// It stops at the first target if ctx is done.
+func Warmup(ctx context.Context, h http.Handler, targets ...string) error {
+	var errs []error
+	for _, target := range targets {
+		if err := ctx.Err(); err != nil {
+			return errors.Join(append(errs, err)...)
+		}
+
+		r, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("cache: warmup %s: %w", target, err))
+			continue
+		}
+
+		r.RequestURI = r.URL.RequestURI()
+
+		w := &discardWriter{header: make(http.Header)}
+		h.ServeHTTP(w, r)
+		if w.status == 0 {
+			w.status = http.StatusOK
+		}
+
+		if w.status != http.StatusOK {
+			errs = append(errs, fmt.Errorf("cache: warmup %s: status %d", target, w.status))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// discardWriter records the status of the response and discards the rest.
+type discardWriter struct {
+	header http.Header
+	status int
+}
+
+func (w *discardWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *discardWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *discardWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+
+	return len(b), nil
+}
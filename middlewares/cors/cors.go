@@ -0,0 +1,238 @@
+package cors
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/pchchv/feather"
+	"github.com/pchchv/feather/internal/lru"
+)
+
+const (
+	allowHeader                         = "Allow"
+	varyHeader                          = "Vary"
+	originHeader                        = "Origin"
+	accessControlRequestMethodHeader    = "Access-Control-Request-Method"
+	accessControlRequestHeadersHeader   = "Access-Control-Request-Headers"
+	accessControlAllowOriginHeader      = "Access-Control-Allow-Origin"
+	accessControlAllowMethodsHeader     = "Access-Control-Allow-Methods"
+	accessControlAllowHeadersHeader     = "Access-Control-Allow-Headers"
+	accessControlAllowCredentialsHeader = "Access-Control-Allow-Credentials"
+	accessControlExposeHeadersHeader    = "Access-Control-Expose-Headers"
+	accessControlMaxAgeHeader           = "Access-Control-Max-Age"
+	accessControlRequestPrivateNetwork  = "Access-Control-Request-Private-Network"
+	accessControlAllowPrivateNetwork    = "Access-Control-Allow-Private-Network"
+	wildcard                            = "*"
+)
+
+var defaultMethods = []string{http.MethodGet, http.MethodHead, http.MethodPost}
+
+// Config contains the CORS settings.
+type Config struct {
+	// AllowedOrigins is the list of origins allowed to make cross-origin requests.
+	// "*" allows all origins and an origin may contain a single wildcard, e.g. "https://*.example.com".
+	AllowedOrigins []string
+	// AllowOriginFunc validates origins, it is consulted when the origin does not match AllowedOrigins.
+	AllowOriginFunc func(r *http.Request, origin string) bool
+	// AllowedMethods allowed for cross-origin requests.
+	// When empty, preflights are answered with the methods registered for the requested route
+	// if the middleware is used with feather's automatic OPTIONS handling, otherwise GET, HEAD and POST.
+	AllowedMethods []string
+	// AllowedHeaders allowed in cross-origin requests, when empty the requested headers are allowed.
+	AllowedHeaders []string
+	// ExposedHeaders are the response headers exposed to the client.
+	ExposedHeaders []string
+	// AllowCredentials indicates whether the request can include user credentials.
+	AllowCredentials bool
+	// MaxAge is the number of seconds a preflight result can be cached, 0 omits the header
+	// and a negative value sends 0, disabling caching.
+	MaxAge int
+	// AllowPrivateNetwork answers Private Network Access preflights, sent by browsers for requests
+	// from public websites to private network addresses, i.e. internal APIs, allowing the request.
+	AllowPrivateNetwork bool
+	// PreflightCacheSize is the number of preflight results cached per origin, requested method and headers,
+	// so the policy is not evaluated again for every preflight, the least recently used are evicted first.
+	// AllowOriginFunc must then only depend on the origin. By default, 0 and the results are not cached.
+	PreflightCacheSize int
+}
+
+type origin struct {
+	prefix string
+	suffix string
+	any    bool
+}
+
+func (o origin) matches(s string) bool {
+	if o.any {
+		return len(s) >= len(o.prefix)+len(o.suffix) && strings.HasPrefix(s, o.prefix) && strings.HasSuffix(s, o.suffix)
+	}
+
+	return s == o.prefix
+}
+
+// New returns a CORS middleware.
+//
+// To answer preflights for every route it should be registered both using Use and RegisterAutomaticOPTIONS:
+//
+//	c := cors.New(cfg)
+//	p.Use(c)
+//	p.RegisterAutomaticOPTIONS(c)
+func New(cfg Config) feather.Middleware {
+	var allowAll bool
+	origins := make([]origin, 0, len(cfg.AllowedOrigins))
+	for _, o := range cfg.AllowedOrigins {
+		if o == wildcard {
+			allowAll = true
+			continue
+		}
+
+		if i := strings.IndexByte(o, '*'); i != -1 {
+			origins = append(origins, origin{prefix: o[:i], suffix: o[i+1:], any: true})
+		} else {
+			origins = append(origins, origin{prefix: o})
+		}
+	}
+
+	allowed := func(r *http.Request, o string) bool {
+		if allowAll {
+			return true
+		}
+
+		for _, og := range origins {
+			if og.matches(o) {
+				return true
+			}
+		}
+
+		return cfg.AllowOriginFunc != nil && cfg.AllowOriginFunc(r, o)
+	}
+
+	allowedHeaders := strings.Join(cfg.AllowedHeaders, ", ")
+	exposedHeaders := strings.Join(cfg.ExposedHeaders, ", ")
+	maxAge := strconv.Itoa(max(cfg.MaxAge, 0))
+
+	// preflight results by preflightKey, the least recently used are evicted first
+	var cache *lru.Cache[http.Header]
+	if cfg.PreflightCacheSize > 0 {
+		cache = lru.New[http.Header](cfg.PreflightCacheSize)
+	}
+
+	// preflightResult sets the headers answering the preflight of an allowed origin
+	preflightResult := func(h http.Header, r *http.Request, o string, methods []string) {
+		requested := r.Header.Get(accessControlRequestMethodHeader)
+		var ok bool
+		for _, m := range methods {
+			if m == requested {
+				ok = true
+				break
+			}
+		}
+
+		if !ok {
+			return
+		}
+
+		if allowAll && !cfg.AllowCredentials {
+			h.Set(accessControlAllowOriginHeader, wildcard)
+		} else {
+			h.Set(accessControlAllowOriginHeader, o)
+		}
+
+		if cfg.AllowCredentials {
+			h.Set(accessControlAllowCredentialsHeader, "true")
+		}
+
+		h.Set(accessControlAllowMethodsHeader, strings.Join(methods, ", "))
+		if allowedHeaders != "" {
+			h.Set(accessControlAllowHeadersHeader, allowedHeaders)
+		} else if rh := r.Header.Get(accessControlRequestHeadersHeader); rh != "" {
+			h.Set(accessControlAllowHeadersHeader, rh)
+		}
+
+		if cfg.MaxAge != 0 {
+			h.Set(accessControlMaxAgeHeader, maxAge)
+		}
+
+		if cfg.AllowPrivateNetwork && r.Header.Get(accessControlRequestPrivateNetwork) == "true" {
+			h.Set(accessControlAllowPrivateNetwork, "true")
+		}
+	}
+
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			o := r.Header.Get(originHeader)
+			preflight := r.Method == http.MethodOptions && r.Header.Get(accessControlRequestMethodHeader) != ""
+			h := w.Header()
+			if o == "" {
+				next(w, r)
+				return
+			}
+
+			if !preflight {
+				feather.AddVary(w, originHeader)
+			} else if cfg.AllowPrivateNetwork {
+				feather.AddVary(w, originHeader, accessControlRequestMethodHeader, accessControlRequestHeadersHeader, accessControlRequestPrivateNetwork)
+			} else {
+				feather.AddVary(w, originHeader, accessControlRequestMethodHeader, accessControlRequestHeadersHeader)
+			}
+
+			if !preflight {
+				if !allowed(r, o) {
+					next(w, r)
+					return
+				}
+
+				if allowAll && !cfg.AllowCredentials {
+					h.Set(accessControlAllowOriginHeader, wildcard)
+				} else {
+					h.Set(accessControlAllowOriginHeader, o)
+				}
+
+				if cfg.AllowCredentials {
+					h.Set(accessControlAllowCredentialsHeader, "true")
+				}
+
+				if exposedHeaders != "" {
+					h.Set(accessControlExposeHeadersHeader, exposedHeaders)
+				}
+
+				next(w, r)
+				return
+			}
+
+			methods := cfg.AllowedMethods
+			if len(methods) == 0 {
+				// feather's automatic OPTIONS handling has set the methods registered for the route
+				if methods = h.Values(allowHeader); len(methods) == 0 {
+					methods = defaultMethods
+				}
+			}
+
+			var k string
+			var result http.Header
+			if cache != nil {
+				k = preflightKey(r, o, methods)
+				result, _ = cache.Get(k)
+			}
+
+			if result == nil {
+				result = make(http.Header)
+				if allowed(r, o) {
+					preflightResult(result, r, o, methods)
+				}
+
+				if cache != nil {
+					cache.Add(k, result)
+				}
+			}
+
+			// the values are copied, so handlers changing the response headers can't alter the cached result
+			for name, values := range result {
+				h[name] = append([]string(nil), values...)
+			}
+
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}
+}
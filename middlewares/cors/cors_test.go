@@ -0,0 +1,197 @@
+package cors
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/pchchv/feather"
+	. "github.com/pchchv/feather/assert"
+)
+
+func newMux(cfg Config) http.Handler {
+	c := New(cfg)
+	p := feather.New()
+	p.Use(c)
+	p.RegisterAutomaticOPTIONS(c)
+	p.Get("/users", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("users"))
+	})
+	p.Post("/users", func(w http.ResponseWriter, r *http.Request) {})
+	return p.Serve()
+}
+
+func do(hf http.Handler, method, origin, requestMethod string) *httptest.ResponseRecorder {
+	r, _ := http.NewRequest(method, "/users", nil)
+	if origin != "" {
+		r.Header.Set(originHeader, origin)
+	}
+
+	if requestMethod != "" {
+		r.Header.Set(accessControlRequestMethodHeader, requestMethod)
+		r.Header.Set(accessControlRequestHeadersHeader, "X-Custom")
+	}
+
+	w := httptest.NewRecorder()
+	hf.ServeHTTP(w, r)
+	return w
+}
+
+func TestPreflight(t *testing.T) {
+	hf := newMux(Config{
+		AllowedOrigins:   []string{"https://*.example.com"},
+		AllowOriginFunc:  func(r *http.Request, origin string) bool { return origin == "https://partner.io" },
+		AllowCredentials: true,
+		MaxAge:           600,
+	})
+
+	w := do(hf, http.MethodOptions, "https://app.example.com", http.MethodPost)
+	Equal(t, w.Code, http.StatusNoContent)
+	Equal(t, w.Header().Get(accessControlAllowOriginHeader), "https://app.example.com")
+	Equal(t, w.Header().Get(accessControlAllowCredentialsHeader), "true")
+	Equal(t, w.Header().Get(accessControlAllowHeadersHeader), "X-Custom")
+	Equal(t, w.Header().Get(accessControlMaxAgeHeader), "600")
+	methods := w.Header().Get(accessControlAllowMethodsHeader)
+	Equal(t, strings.Contains(methods, http.MethodGet), true)
+	Equal(t, strings.Contains(methods, http.MethodPost), true)
+	Equal(t, strings.Contains(methods, http.MethodPut), false)
+
+	// method not registered for the route
+	w = do(hf, http.MethodOptions, "https://app.example.com", http.MethodPut)
+	Equal(t, w.Code, http.StatusNoContent)
+	Equal(t, w.Header().Get(accessControlAllowOriginHeader), "")
+
+	w = do(hf, http.MethodOptions, "https://partner.io", http.MethodGet)
+	Equal(t, w.Header().Get(accessControlAllowOriginHeader), "https://partner.io")
+
+	w = do(hf, http.MethodOptions, "https://evil.com", http.MethodGet)
+	Equal(t, w.Code, http.StatusNoContent)
+	Equal(t, w.Header().Get(accessControlAllowOriginHeader), "")
+}
+
+func TestSimpleRequest(t *testing.T) {
+	hf := newMux(Config{
+		AllowedOrigins: []string{"*"},
+		ExposedHeaders: []string{"X-Total"},
+	})
+
+	w := do(hf, http.MethodGet, "https://any.io", "")
+	Equal(t, w.Code, http.StatusOK)
+	Equal(t, w.Body.String(), "users")
+	Equal(t, w.Header().Get(accessControlAllowOriginHeader), "*")
+	Equal(t, w.Header().Get(accessControlExposeHeadersHeader), "X-Total")
+	Equal(t, w.Header().Get(varyHeader), originHeader)
+
+	w = do(hf, http.MethodGet, "", "")
+	Equal(t, w.Code, http.StatusOK)
+	Equal(t, w.Header().Get(accessControlAllowOriginHeader), "")
+
+	// a plain OPTIONS request is still answered by feather
+	w = do(hf, http.MethodOptions, "", "")
+	Equal(t, w.Code, http.StatusOK)
+	Equal(t, len(w.Header().Values(allowHeader)), 3)
+}
+
+func TestPreflightAutomaticHEAD(t *testing.T) {
+	c := New(Config{AllowedOrigins: []string{"*"}})
+	p := feather.New()
+	p.SetAutomaticHEAD(true)
+	p.Use(c)
+	p.RegisterAutomaticOPTIONS(c)
+	p.Get("/users", func(w http.ResponseWriter, r *http.Request) {})
+	p.Head("/users", func(w http.ResponseWriter, r *http.Request) {})
+	p.Post("/users", func(w http.ResponseWriter, r *http.Request) {})
+	hf := p.Serve()
+
+	w := do(hf, http.MethodOptions, "https://any.io", http.MethodHead)
+	Equal(t, w.Code, http.StatusNoContent)
+	Equal(t, w.Header().Get(accessControlAllowOriginHeader), "*")
+	Equal(t, w.Header().Get(accessControlAllowMethodsHeader), "GET, HEAD, POST, OPTIONS")
+
+	p = feather.New()
+	p.SetAutomaticHEAD(true)
+	p.Use(c)
+	p.RegisterAutomaticOPTIONS(c)
+	p.Get("/users", func(w http.ResponseWriter, r *http.Request) {})
+	hf = p.Serve()
+
+	// HEAD derived from GET is allowed
+	w = do(hf, http.MethodOptions, "https://any.io", http.MethodHead)
+	Equal(t, w.Header().Get(accessControlAllowOriginHeader), "*")
+	Equal(t, w.Header().Get(accessControlAllowMethodsHeader), "GET, HEAD, OPTIONS")
+}
+
+func TestPreflightPrivateNetwork(t *testing.T) {
+	preflight := func(hf http.Handler, privateNetwork bool) *httptest.ResponseRecorder {
+		r, _ := http.NewRequest(http.MethodOptions, "/users", nil)
+		r.Header.Set(originHeader, "https://app.example.com")
+		r.Header.Set(accessControlRequestMethodHeader, http.MethodGet)
+		if privateNetwork {
+			r.Header.Set(accessControlRequestPrivateNetwork, "true")
+		}
+
+		w := httptest.NewRecorder()
+		hf.ServeHTTP(w, r)
+		return w
+	}
+
+	hf := newMux(Config{AllowedOrigins: []string{"*"}, AllowPrivateNetwork: true, MaxAge: -1})
+	w := preflight(hf, true)
+	Equal(t, w.Code, http.StatusNoContent)
+	Equal(t, w.Header().Get(accessControlAllowPrivateNetwork), "true")
+	Equal(t, w.Header().Get(accessControlMaxAgeHeader), "0")
+	Equal(t, strings.Contains(strings.Join(w.Header().Values(varyHeader), ","), accessControlRequestPrivateNetwork), true)
+
+	w = preflight(hf, false)
+	Equal(t, w.Header().Get(accessControlAllowPrivateNetwork), "")
+
+	hf = newMux(Config{AllowedOrigins: []string{"*"}})
+	w = preflight(hf, true)
+	Equal(t, w.Header().Get(accessControlAllowPrivateNetwork), "")
+	Equal(t, w.Header().Get(accessControlMaxAgeHeader), "")
+}
+
+func TestPreflightCache(t *testing.T) {
+	var calls int
+	hf := newMux(Config{
+		AllowOriginFunc: func(r *http.Request, origin string) bool {
+			calls++
+			return origin == "https://partner.io"
+		},
+		MaxAge:             600,
+		PreflightCacheSize: 2,
+	})
+
+	for i := 0; i < 3; i++ {
+		w := do(hf, http.MethodOptions, "https://partner.io", http.MethodPost)
+		Equal(t, w.Code, http.StatusNoContent)
+		Equal(t, w.Header().Get(accessControlAllowOriginHeader), "https://partner.io")
+		Equal(t, w.Header().Get(accessControlMaxAgeHeader), "600")
+		Equal(t, strings.Contains(strings.Join(w.Header().Values(varyHeader), ","), originHeader), true)
+
+		// changing the response headers in place doesn't alter the cached result
+		w.Header()[accessControlAllowOriginHeader][0] = "https://evil.com"
+	}
+	Equal(t, calls, 1)
+
+	for i := 0; i < 2; i++ {
+		w := do(hf, http.MethodOptions, "https://evil.com", http.MethodPost)
+		Equal(t, w.Header().Get(accessControlAllowOriginHeader), "")
+	}
+	Equal(t, calls, 2)
+
+	// a different requested method is another result
+	w := do(hf, http.MethodOptions, "https://partner.io", http.MethodPut)
+	Equal(t, w.Header().Get(accessControlAllowOriginHeader), "")
+	Equal(t, calls, 3)
+
+	// the partner.io POST preflight is the least recently used and was evicted
+	do(hf, http.MethodOptions, "https://partner.io", http.MethodPost)
+	Equal(t, calls, 4)
+
+	// simple requests are not cached
+	do(hf, http.MethodGet, "https://partner.io", "")
+	do(hf, http.MethodGet, "https://partner.io", "")
+	Equal(t, calls, 6)
+}
@@ -0,0 +1,25 @@
+package cors
+
+import (
+	"net/http"
+	"strings"
+)
+
+// preflightKey returns the key of the preflight result, which depends on the origin,
+// the requested method and headers, the Private Network Access request and the methods of the route.
+func preflightKey(r *http.Request, origin string, methods []string) string {
+	var b strings.Builder
+	b.WriteString(origin)
+	b.WriteByte('\n')
+	b.WriteString(r.Header.Get(accessControlRequestMethodHeader))
+	b.WriteByte('\n')
+	b.WriteString(r.Header.Get(accessControlRequestHeadersHeader))
+	b.WriteByte('\n')
+	b.WriteString(r.Header.Get(accessControlRequestPrivateNetwork))
+	for _, m := range methods {
+		b.WriteByte('\n')
+		b.WriteString(m)
+	}
+
+	return b.String()
+}
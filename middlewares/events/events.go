@@ -0,0 +1,77 @@
+package events
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/pchchv/feather"
+)
+
+// Event describes a completed request.
+type Event struct {
+	Method    string        `json:"method"`
+	Route     string        `json:"route"`
+	Path      string        `json:"path"`
+	Status    int           `json:"status"`
+	Latency   time.Duration `json:"latency"`
+	Principal string        `json:"principal,omitempty"`
+	Time      time.Time     `json:"time"`
+}
+
+// Publisher is the interface message bus adapters (NATS, Kafka, ...) implement.
+// Publish is called after the handler has returned so it should not block for long,
+// adapters are expected to buffer or publish asynchronously.
+type Publisher interface {
+	Publish(ctx context.Context, e Event) error
+}
+
+// PublisherFunc is an adapter allowing the use of an ordinary function as a Publisher.
+type PublisherFunc func(ctx context.Context, e Event) error
+
+// Publish calls f(ctx, e).
+func (f PublisherFunc) Publish(ctx context.Context, e Event) error {
+	return f(ctx, e)
+}
+
+// Config contains the events middleware settings.
+type Config struct {
+	Publisher Publisher
+	// Principal extracts the authenticated principal of the request, optional.
+	Principal func(r *http.Request) string
+	// OnError is called when publishing fails, optional.
+	OnError func(r *http.Request, err error)
+}
+
+// New returns a middleware publishing a request-completed Event for every request.
+func New(cfg Config) feather.Middleware {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			w, rt := feather.TrackResponse(w)
+			next(w, r)
+
+			status := rt.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+
+			e := Event{
+				Method:  r.Method,
+				Route:   feather.RequestVars(r).Route(),
+				Path:    r.URL.Path,
+				Status:  status,
+				Latency: time.Since(start),
+				Time:    start,
+			}
+
+			if cfg.Principal != nil {
+				e.Principal = cfg.Principal(r)
+			}
+
+			if err := cfg.Publisher.Publish(context.WithoutCancel(r.Context()), e); err != nil && cfg.OnError != nil {
+				cfg.OnError(r, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pchchv/feather"
+	. "github.com/pchchv/feather/assert"
+)
+
+func TestEvents(t *testing.T) {
+	var published []Event
+	var failed error
+	pub := PublisherFunc(func(ctx context.Context, e Event) error {
+		published = append(published, e)
+		if e.Status == http.StatusTeapot {
+			return errors.New("bus down")
+		}
+		return nil
+	})
+
+	p := feather.New()
+	p.Use(New(Config{
+		Publisher: pub,
+		Principal: func(r *http.Request) string { return r.Header.Get("X-User") },
+		OnError:   func(r *http.Request, err error) { failed = err },
+	}))
+	p.Get("/users/:id", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+	p.Get("/health", func(w http.ResponseWriter, r *http.Request) {
+		// the writer of the Mux is passed through, not hidden by a wrapper
+		_, flusher := w.(http.Flusher)
+		_, hijacker := w.(http.Hijacker)
+		Equal(t, flusher && hijacker, true)
+		_, _ = w.Write([]byte("ok"))
+	})
+	hf := p.Serve()
+
+	r, _ := http.NewRequest(http.MethodGet, "/users/13", nil)
+	r.Header.Set("X-User", "joeybloggs")
+	w := httptest.NewRecorder()
+	hf.ServeHTTP(w, r)
+	Equal(t, len(published), 1)
+	Equal(t, published[0].Route, "/users/:id")
+	Equal(t, published[0].Path, "/users/13")
+	Equal(t, published[0].Status, http.StatusTeapot)
+	Equal(t, published[0].Principal, "joeybloggs")
+	Equal(t, failed.Error(), "bus down")
+
+	r, _ = http.NewRequest(http.MethodGet, "/health", nil)
+	w = httptest.NewRecorder()
+	hf.ServeHTTP(w, r)
+	Equal(t, len(published), 2)
+	Equal(t, published[1].Route, "/health")
+	Equal(t, published[1].Status, http.StatusOK)
+	Equal(t, published[1].Principal, "")
+}
@@ -0,0 +1,263 @@
+// Package experiment provides middleware deterministically assigning requests to the variants of A/B experiments,
+// by hashing the user id or an assignment cookie, so a user sees the same variant on every request.
+package experiment
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"hash/fnv"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pchchv/feather"
+)
+
+// AssignmentsKey is the ReqVars key under which the assignments of the request are stored, see VariantOf and FromRequest.
+const AssignmentsKey = "feather.experiments"
+
+const (
+	defaultCookie = "feather_experiment"
+	defaultHeader = "X-Experiments"
+	cookieMaxAge  = 365 * 24 * time.Hour
+)
+
+// Variant of an experiment.
+type Variant struct {
+	Name string
+	// Weight is the share of the units assigned to the variant relative to the other variants,
+	// 1 if not positive.
+	Weight int
+}
+
+// Experiment assigns units, users or anonymous visitors, to its variants.
+type Experiment struct {
+	Name     string
+	Variants []Variant
+}
+
+// Exposure is reported when a handler reads the assigned variant, see VariantOf.
+type Exposure struct {
+	Experiment string    `json:"experiment"`
+	Variant    string    `json:"variant"`
+	Unit       string    `json:"unit"`
+	Route      string    `json:"route"`
+	Time       time.Time `json:"time"`
+}
+
+// Sink is the interface analytics adapters implement.
+// Expose is called while handling the request so it should not block for long,
+// adapters are expected to buffer or report asynchronously.
+type Sink interface {
+	Expose(ctx context.Context, e Exposure) error
+}
+
+// SinkFunc is an adapter allowing the use of an ordinary function as a Sink.
+type SinkFunc func(ctx context.Context, e Exposure) error
+
+// Expose calls f(ctx, e).
+func (f SinkFunc) Expose(ctx context.Context, e Exposure) error {
+	return f(ctx, e)
+}
+
+// Config contains the experiment middleware settings.
+type Config struct {
+	Experiments []Experiment
+	// UserID returns the id of the authenticated user, which is hashed instead of the cookie
+	// so users see the same variants on all devices, optional.
+	UserID func(r *http.Request) string
+	// Cookie storing the random unit id of anonymous visitors, feather_experiment by default.
+	Cookie string
+	// Header listing the assignments in the response, i.e. X-Experiments: checkout=b, search=a,
+	// X-Experiments by default, "-" disables it.
+	Header string
+	// Sink the exposures are reported to, optional.
+	Sink Sink
+	// OnError is called when reporting an exposure fails, optional.
+	OnError func(r *http.Request, err error)
+}
+
+// assignments are the variants assigned to a request by experiment.
+type assignments struct {
+	r        *http.Request
+	unit     string
+	variants map[string]string
+	cfg      *Config
+	mu       sync.Mutex
+	exposed  map[string]bool
+}
+
+// New returns the experiment middleware, which assigns every request to a variant of every experiment,
+// stores the assignments in the request variables, see VariantOf, and lists them in the response header.
+// It panics if an experiment has no variants.
+func New(cfg Config) feather.Middleware {
+	if cfg.Cookie == "" {
+		cfg.Cookie = defaultCookie
+	}
+
+	if cfg.Header == "" {
+		cfg.Header = defaultHeader
+	}
+
+	for _, e := range cfg.Experiments {
+		if len(e.Variants) == 0 {
+			panic("experiment: " + e.Name + " has no variants")
+		}
+	}
+
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			unit := ""
+			if cfg.UserID != nil {
+				unit = cfg.UserID(r)
+			}
+
+			if unit == "" {
+				if c, err := r.Cookie(cfg.Cookie); err == nil && valid(c.Value) {
+					unit = c.Value
+				} else {
+					unit = generate()
+					http.SetCookie(w, &http.Cookie{
+						Name:     cfg.Cookie,
+						Value:    unit,
+						Path:     "/",
+						MaxAge:   int(cookieMaxAge / time.Second),
+						HttpOnly: true,
+						SameSite: http.SameSiteLaxMode,
+					})
+				}
+			}
+
+			a := &assignments{r: r, unit: unit, variants: make(map[string]string, len(cfg.Experiments)), cfg: &cfg}
+			var header strings.Builder
+			for i, e := range cfg.Experiments {
+				v := assign(e, unit)
+				a.variants[e.Name] = v
+				if i > 0 {
+					header.WriteString(", ")
+				}
+				header.WriteString(e.Name)
+				header.WriteByte('=')
+				header.WriteString(v)
+			}
+
+			if cfg.Header != "-" && header.Len() > 0 {
+				w.Header().Set(cfg.Header, header.String())
+			}
+
+			feather.RequestVars(r).Set(AssignmentsKey, a)
+			next(w, r)
+		}
+	}
+}
+
+// VariantOf returns the variant of the experiment the request is assigned to, blank if none,
+// and reports the exposure to the Sink the first time it is called per request and experiment.
+func VariantOf(r *http.Request, experiment string) string {
+	a, _ := feather.RequestVars(r).Get(AssignmentsKey).(*assignments)
+	if a == nil {
+		return ""
+	}
+
+	v, ok := a.variants[experiment]
+	if !ok {
+		return ""
+	}
+
+	a.expose(experiment, v)
+	return v
+}
+
+// FromRequest returns the assignments of the request, nil if none.
+// Reading them doesn't report exposures.
+func FromRequest(r *http.Request) map[string]string {
+	a, _ := feather.RequestVars(r).Get(AssignmentsKey).(*assignments)
+	if a == nil {
+		return nil
+	}
+
+	variants := make(map[string]string, len(a.variants))
+	for e, v := range a.variants {
+		variants[e] = v
+	}
+
+	return variants
+}
+
+func (a *assignments) expose(experiment, variant string) {
+	if a.cfg.Sink == nil {
+		return
+	}
+
+	a.mu.Lock()
+	if a.exposed[experiment] {
+		a.mu.Unlock()
+		return
+	}
+
+	if a.exposed == nil {
+		a.exposed = make(map[string]bool)
+	}
+	a.exposed[experiment] = true
+	a.mu.Unlock()
+
+	e := Exposure{
+		Experiment: experiment,
+		Variant:    variant,
+		Unit:       a.unit,
+		Route:      feather.RequestVars(a.r).Route(),
+		Time:       time.Now(),
+	}
+
+	if err := a.cfg.Sink.Expose(a.r.Context(), e); err != nil && a.cfg.OnError != nil {
+		a.cfg.OnError(a.r, err)
+	}
+}
+
+// assign returns the variant of the experiment the unit is assigned to by hashing
+// the experiment name and the unit, so the assignments of different experiments are independent.
+func assign(e Experiment, unit string) string {
+	total := 0
+	for _, v := range e.Variants {
+		total += weight(v)
+	}
+
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(e.Name))
+	_, _ = h.Write([]byte{0})
+	_, _ = h.Write([]byte(unit))
+	bucket := int(h.Sum64() % uint64(total))
+	for _, v := range e.Variants {
+		if bucket -= weight(v); bucket < 0 {
+			return v.Name
+		}
+	}
+
+	return e.Variants[len(e.Variants)-1].Name
+}
+
+func weight(v Variant) int {
+	if v.Weight <= 0 {
+		return 1
+	}
+
+	return v.Weight
+}
+
+// valid reports whether the cookie value is a unit id generated by the middleware.
+func valid(id string) bool {
+	if len(id) != 32 {
+		return false
+	}
+
+	_, err := hex.DecodeString(id)
+	return err == nil
+}
+
+func generate() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
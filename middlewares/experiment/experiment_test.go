@@ -0,0 +1,121 @@
+package experiment
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pchchv/feather"
+	. "github.com/pchchv/feather/assert"
+)
+
+var checkout = Experiment{Name: "checkout", Variants: []Variant{{Name: "a"}, {Name: "b"}}}
+
+func TestExperiment(t *testing.T) {
+	var exposures []Exposure
+	p := feather.New()
+	p.Use(New(Config{
+		Experiments: []Experiment{checkout, {Name: "search", Variants: []Variant{{Name: "old"}}}},
+		UserID:      func(r *http.Request) string { return r.Header.Get("X-User") },
+		Sink: SinkFunc(func(_ context.Context, e Exposure) error {
+			exposures = append(exposures, e)
+			return nil
+		}),
+	}))
+	p.Get("/", func(w http.ResponseWriter, r *http.Request) {
+		// exposures are reported once per request and experiment
+		_ = VariantOf(r, "checkout")
+		_, _ = w.Write([]byte(VariantOf(r, "checkout") + VariantOf(r, "missing")))
+	})
+
+	serve := func(user string, cookie *http.Cookie) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if user != "" {
+			r.Header.Set("X-User", user)
+		}
+		if cookie != nil {
+			r.AddCookie(cookie)
+		}
+		w := httptest.NewRecorder()
+		p.Serve().ServeHTTP(w, r)
+		return w
+	}
+
+	w := serve("user-1", nil)
+	variant := assign(checkout, "user-1")
+	Equal(t, w.Body.String(), variant)
+	Equal(t, w.Header().Get("X-Experiments"), "checkout="+variant+", search=old")
+	Equal(t, w.Header().Get("Set-Cookie"), "")
+	Equal(t, len(exposures), 1)
+	Equal(t, exposures[0].Experiment, "checkout")
+	Equal(t, exposures[0].Variant, variant)
+	Equal(t, exposures[0].Unit, "user-1")
+	Equal(t, exposures[0].Route, "/")
+
+	// anonymous visitors keep their assignments using the cookie
+	w = serve("", nil)
+	cookies := w.Result().Cookies()
+	Equal(t, len(cookies), 1)
+	Equal(t, cookies[0].Name, "feather_experiment")
+	Equal(t, len(cookies[0].Value), 32)
+	variant = w.Body.String()
+	for i := 0; i < 5; i++ {
+		w = serve("", cookies[0])
+		Equal(t, w.Body.String(), variant)
+		Equal(t, w.Header().Get("Set-Cookie"), "")
+	}
+
+	// invalid cookies are replaced
+	w = serve("", &http.Cookie{Name: "feather_experiment", Value: "forged"})
+	NotEqual(t, w.Header().Get("Set-Cookie"), "")
+}
+
+func TestAssign(t *testing.T) {
+	e := Experiment{Name: "pricing", Variants: []Variant{{Name: "control", Weight: 9}, {Name: "treatment", Weight: 1}}}
+	counts := make(map[string]int)
+	for i := 0; i < 10000; i++ {
+		counts[assign(e, generate())]++
+	}
+
+	Equal(t, counts["control"] > 8500 && counts["control"] < 9500, true)
+	Equal(t, counts["control"]+counts["treatment"], 10000)
+	Equal(t, assign(e, "unit"), assign(e, "unit"))
+}
+
+func TestExperimentHelpers(t *testing.T) {
+	sinkErr := errors.New("sink down")
+	var reported error
+	p := feather.New()
+	p.Get("/none", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(VariantOf(r, "checkout")))
+	})
+	g := p.GroupWithMore("/exp", New(Config{
+		Experiments: []Experiment{checkout},
+		Header:      "-",
+		Sink:        SinkFunc(func(context.Context, Exposure) error { return sinkErr }),
+		OnError:     func(_ *http.Request, err error) { reported = err },
+	}))
+	g.Get("", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(FromRequest(r)["checkout"]))
+		Equal(t, reported, nil)
+		_ = VariantOf(r, "checkout")
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/none", nil)
+	w := httptest.NewRecorder()
+	p.Serve().ServeHTTP(w, r)
+	Equal(t, w.Body.String(), "")
+
+	r = httptest.NewRequest(http.MethodGet, "/exp", nil)
+	w = httptest.NewRecorder()
+	p.Serve().ServeHTTP(w, r)
+	NotEqual(t, w.Body.String(), "")
+	Equal(t, w.Header().Get("X-Experiments"), "")
+	Equal(t, reported, sinkErr)
+
+	PanicsWithValue(t, func() {
+		New(Config{Experiments: []Experiment{{Name: "empty"}}})
+	}, "experiment: empty has no variants")
+}
@@ -0,0 +1,179 @@
+// Package fieldmap provides middleware rewriting JSON request bodies using a declarative field mapping,
+// renaming, dropping and defaulting fields before handlers decode them,
+// so handlers keep accepting requests using an older schema.
+package fieldmap
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/pchchv/feather"
+)
+
+const defaultMaxBodySize = 1 << 20
+
+// Config contains the field mapping, fields are addressed by dot separated paths, i.e. user.name.
+// Renames are applied first, followed by drops and defaults.
+type Config struct {
+	// Rename maps old field paths to new ones, i.e. {"fullName": "user.name"}.
+	// A field already present under the new path is kept and the old one dropped.
+	Rename map[string]string
+	// Drop lists field paths removed from the body.
+	Drop []string
+	// Default sets field paths missing from the body to the values.
+	Default map[string]interface{}
+	// MaxBodySize is the size of the largest body mapped, larger bodies are answered
+	// with 413 Request Entity Too Large. By default 1MB.
+	MaxBodySize int64
+}
+
+// New returns a middleware applying the mapping to JSON object bodies,
+// or to every object of a JSON array body.
+// Other bodies, including invalid JSON, are passed on unchanged so handlers report the errors.
+func New(cfg Config) feather.Middleware {
+	if cfg.MaxBodySize <= 0 {
+		cfg.MaxBodySize = defaultMaxBodySize
+	}
+
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if r.Body == nil || r.Body == http.NoBody || !isJSON(r.Header.Get("Content-Type")) {
+				next(w, r)
+				return
+			}
+
+			b, err := io.ReadAll(io.LimitReader(r.Body, cfg.MaxBodySize+1))
+			r.Body.Close()
+			if err != nil {
+				http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+				return
+			}
+
+			if int64(len(b)) > cfg.MaxBodySize {
+				http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+				return
+			}
+
+			if mapped, ok := cfg.apply(b); ok {
+				b = mapped
+			}
+
+			r.Body = io.NopCloser(bytes.NewReader(b))
+			r.ContentLength = int64(len(b))
+			r.Header.Set("Content-Length", strconv.Itoa(len(b)))
+			next(w, r)
+		}
+	}
+}
+
+// apply maps the body, false if it isn't a JSON object or array.
+func (cfg *Config) apply(b []byte) ([]byte, bool) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+
+	var v interface{}
+	if err := dec.Decode(&v); err != nil || dec.More() {
+		return nil, false
+	}
+
+	switch v := v.(type) {
+	case map[string]interface{}:
+		cfg.mapObject(v)
+	case []interface{}:
+		for _, e := range v {
+			if obj, ok := e.(map[string]interface{}); ok {
+				cfg.mapObject(obj)
+			}
+		}
+	default:
+		return nil, false
+	}
+
+	mapped, err := json.Marshal(v)
+	return mapped, err == nil
+}
+
+func (cfg *Config) mapObject(obj map[string]interface{}) {
+	// remove all renamed fields first so renames don't chain
+	renamed := make(map[string]interface{}, len(cfg.Rename))
+	for from, to := range cfg.Rename {
+		if value, ok := remove(obj, from); ok {
+			renamed[to] = value
+		}
+	}
+
+	for to, value := range renamed {
+		if _, exists := get(obj, to); !exists {
+			set(obj, to, value)
+		}
+	}
+
+	for _, path := range cfg.Drop {
+		remove(obj, path)
+	}
+
+	for path, value := range cfg.Default {
+		if _, exists := get(obj, path); !exists {
+			set(obj, path, value)
+		}
+	}
+}
+
+// parent returns the object holding the last segment of the path,
+// creating missing objects if create is set.
+func parent(obj map[string]interface{}, path string, create bool) (map[string]interface{}, string) {
+	for {
+		name, rest, nested := strings.Cut(path, ".")
+		if !nested {
+			return obj, name
+		}
+
+		child, ok := obj[name].(map[string]interface{})
+		if !ok {
+			if _, exists := obj[name]; exists || !create {
+				return nil, ""
+			}
+
+			child = make(map[string]interface{})
+			obj[name] = child
+		}
+		obj, path = child, rest
+	}
+}
+
+func get(obj map[string]interface{}, path string) (interface{}, bool) {
+	if obj, name := parent(obj, path, false); obj != nil {
+		v, ok := obj[name]
+		return v, ok
+	}
+
+	return nil, false
+}
+
+func set(obj map[string]interface{}, path string, value interface{}) {
+	if obj, name := parent(obj, path, true); obj != nil {
+		obj[name] = value
+	}
+}
+
+func remove(obj map[string]interface{}, path string) (interface{}, bool) {
+	obj, name := parent(obj, path, false)
+	if obj == nil {
+		return nil, false
+	}
+
+	v, ok := obj[name]
+	delete(obj, name)
+	return v, ok
+}
+
+// isJSON reports whether the media type is application/json or uses the +json suffix.
+func isJSON(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && (mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"))
+}
@@ -0,0 +1,82 @@
+package fieldmap
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/pchchv/feather"
+	. "github.com/pchchv/feather/assert"
+)
+
+func TestFieldMap(t *testing.T) {
+	p := feather.New()
+	p.Use(New(Config{
+		Rename:      map[string]string{"fullName": "user.name", "mail": "email", "email": "contact.email"},
+		Drop:        []string{"legacy", "user.internal"},
+		Default:     map[string]interface{}{"role": "member", "user.active": true},
+		MaxBodySize: 256,
+	}))
+	p.Post("/users", func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		Equal(t, r.ContentLength, int64(len(b)))
+		_, _ = w.Write(b)
+	})
+	h := p.Serve()
+
+	tests := []struct {
+		contentType string
+		body        string
+		code        int
+		expected    string
+	}{
+		{
+			contentType: "application/json",
+			body:        `{"fullName":"Joey","mail":"joey@example.com","email":"old@example.com","legacy":1,"user":{"internal":true},"id":12345678901234567890}`,
+			code:        http.StatusOK,
+			expected:    `{"contact":{"email":"old@example.com"},"email":"joey@example.com","id":12345678901234567890,"role":"member","user":{"active":true,"name":"Joey"}}`,
+		},
+		{
+			// fields present under the new name are kept
+			contentType: "application/vnd.api+json; charset=utf-8",
+			body:        `{"fullName":"Joey","user":{"name":"Joe","active":false},"role":"admin"}`,
+			code:        http.StatusOK,
+			expected:    `{"role":"admin","user":{"active":false,"name":"Joe"}}`,
+		},
+		{
+			contentType: "application/json",
+			body:        `[{"mail":"a"},2,{"legacy":true}]`,
+			code:        http.StatusOK,
+			expected:    `[{"email":"a","role":"member","user":{"active":true}},2,{"role":"member","user":{"active":true}}]`,
+		},
+		{
+			contentType: "application/json",
+			body:        `{"invalid"`,
+			code:        http.StatusOK,
+			expected:    `{"invalid"`,
+		},
+		{
+			contentType: "text/plain",
+			body:        `{"legacy":1}`,
+			code:        http.StatusOK,
+			expected:    `{"legacy":1}`,
+		},
+		{
+			contentType: "application/json",
+			body:        `{"legacy":"` + strings.Repeat("a", 256) + `"}`,
+			code:        http.StatusRequestEntityTooLarge,
+			expected:    "Request Entity Too Large\n",
+		},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(tt.body))
+		r.Header.Set("Content-Type", tt.contentType)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		Equal(t, w.Code, tt.code)
+		Equal(t, w.Body.String(), tt.expected)
+	}
+}
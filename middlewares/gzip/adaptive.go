@@ -0,0 +1,235 @@
+package gzip
+
+import (
+	"compress/gzip"
+	"io"
+	"math"
+	"net/http"
+	"runtime/metrics"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pchchv/feather"
+)
+
+const (
+	contentLengthHeader = "Content-Length"
+	defaultLargeSize    = 64 << 10
+	cpuSampleInterval   = 250 * time.Millisecond
+	cpuTotalMetric      = "/cpu/classes/total:cpu-seconds"
+	cpuIdleMetric       = "/cpu/classes/idle:cpu-seconds"
+	defaultMaxLevel     = 6 // the level of gzip.DefaultCompression
+	defaultMinLevel     = gzip.BestSpeed
+	defaultThreshold    = 0.5
+)
+
+// levelPools pool the gzip writers of the adaptive middleware by level.
+var levelPools [gzip.BestCompression + 1]sync.Pool
+
+// AdaptiveConfig contains the settings of the adaptive compression, trading ratio for latency under load.
+type AdaptiveConfig struct {
+	// MinLevel is the level used under full CPU pressure, gzip.BestSpeed by default.
+	MinLevel int
+	// MaxLevel is the level of responses smaller than LargeSize without CPU pressure, 6 by default,
+	// the level of gzip.DefaultCompression.
+	MaxLevel int
+	// LargeSize is the size in bytes from which responses, whose compression costs the most CPU,
+	// start at the level halfway between MinLevel and MaxLevel, 64KB by default.
+	// The size is the Content-Length set by the handler, or else the size of the first write.
+	LargeSize int
+	// Pressure returns the CPU pressure, from 0 when idle to 1 when saturated, CPUPressure by default.
+	Pressure func() float64
+	// Threshold is the pressure from which the level is lowered, proportionally to the pressure
+	// above it until MinLevel is reached at full pressure, 0.5 by default.
+	Threshold float64
+}
+
+// level returns the compression level of a response of size bytes under the pressure.
+func (cfg *AdaptiveConfig) level(size int, pressure float64) int {
+	level := cfg.MaxLevel
+	if size >= cfg.LargeSize {
+		level = (cfg.MinLevel + cfg.MaxLevel) / 2
+	}
+
+	if pressure > cfg.Threshold {
+		excess := math.Min((pressure-cfg.Threshold)/(1-cfg.Threshold), 1)
+		level -= int(math.Round(excess * float64(level-cfg.MinLevel)))
+	}
+
+	return level
+}
+
+// GzipAdaptive returns a middleware compressing the HTTP responses using gzip at a level chosen per response,
+// based on its size and the CPU pressure, so compression trades ratio for latency automatically under load.
+// It panics if the levels are invalid.
+func GzipAdaptive(cfg AdaptiveConfig) feather.Middleware {
+	if cfg.MinLevel == 0 {
+		cfg.MinLevel = defaultMinLevel
+	}
+
+	if cfg.MaxLevel == 0 {
+		cfg.MaxLevel = defaultMaxLevel
+	}
+
+	if cfg.MinLevel < gzip.BestSpeed || cfg.MaxLevel > gzip.BestCompression || cfg.MinLevel > cfg.MaxLevel {
+		panic("gzip: invalid adaptive compression levels: " + strconv.Itoa(cfg.MinLevel) + " to " + strconv.Itoa(cfg.MaxLevel))
+	}
+
+	if cfg.LargeSize <= 0 {
+		cfg.LargeSize = defaultLargeSize
+	}
+
+	if cfg.Pressure == nil {
+		cfg.Pressure = CPUPressure
+	}
+
+	if cfg.Threshold <= 0 || cfg.Threshold >= 1 {
+		cfg.Threshold = defaultThreshold
+	}
+
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			feather.AddVary(w, acceptEncodingHeader)
+			if !compress(r) {
+				next(w, r)
+				return
+			}
+
+			aw := &adaptiveWriter{gzipWriter: gzipWriter{ResponseWriter: w}, cfg: &cfg}
+			w.Header().Set(contentEncodingHeader, gzipVal)
+			defer func() {
+				if !aw.sniffComplete {
+					// it is necessary to reset response to its
+					// pristine state where nothing is written to the body
+					w.Header().Del(contentEncodingHeader)
+					if aw.gz != nil {
+						aw.gz.Reset(io.Discard)
+					}
+				}
+
+				if aw.gz != nil {
+					aw.gz.Close()
+					counters.Put()
+					levelPools[aw.level].Put(aw.gz)
+				}
+			}()
+
+			next(aw, r)
+		}
+	}
+}
+
+// adaptiveWriter is a gzipWriter whose compressor is chosen once the size of the response is known.
+type adaptiveWriter struct {
+	gzipWriter
+	cfg   *AdaptiveConfig
+	gz    *gzip.Writer
+	level int
+}
+
+// start chooses the compressor of the response, whose size is at least size.
+func (w *adaptiveWriter) start(size int) {
+	h := w.Header()
+	if cl, err := strconv.Atoi(h.Get(contentLengthHeader)); err == nil && cl > size {
+		size = cl
+	}
+	// the length of the compressed response is unknown
+	h.Del(contentLengthHeader)
+
+	w.level = w.cfg.level(size, w.cfg.Pressure())
+	counters.Get()
+	if gz, ok := levelPools[w.level].Get().(*gzip.Writer); ok {
+		gz.Reset(w.ResponseWriter)
+		w.gz = gz
+	} else {
+		counters.New()
+		w.gz, _ = gzip.NewWriterLevel(w.ResponseWriter, w.level)
+	}
+
+	w.Writer = w.gz
+}
+
+// compressing reports whether the response is compressed, choosing the compressor on first use.
+func (w *adaptiveWriter) compressing(size int) bool {
+	if w.checkStream(); w.passthrough {
+		return false
+	}
+
+	if w.gz == nil {
+		w.start(size)
+	}
+
+	return true
+}
+
+func (w *adaptiveWriter) WriteHeader(code int) {
+	if w.checkStream(); !w.passthrough {
+		w.Header().Del(contentLengthHeader)
+	}
+
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *adaptiveWriter) Write(b []byte) (int, error) {
+	w.compressing(len(b))
+	return w.gzipWriter.Write(b)
+}
+
+func (w *adaptiveWriter) Flush() error {
+	if !w.compressing(0) {
+		return nil
+	}
+
+	return w.gz.Flush()
+}
+
+// FlushError flushes the compressed data and the underlying writer, it is used by http.ResponseController.
+func (w *adaptiveWriter) FlushError() error {
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	return http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+var cpu struct {
+	mu       sync.Mutex
+	sampled  time.Time
+	samples  [2]metrics.Sample
+	total    float64
+	idle     float64
+	pressure atomic.Uint64 // float64 bits
+}
+
+// CPUPressure returns the CPU utilization of the process relative to GOMAXPROCS, from 0 to 1,
+// estimated by the Go runtime, which updates it at every garbage collection.
+// It is sampled at most every 250ms, concurrent callers get the last sample.
+func CPUPressure() float64 {
+	if !cpu.mu.TryLock() {
+		return math.Float64frombits(cpu.pressure.Load())
+	}
+	defer cpu.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(cpu.sampled) < cpuSampleInterval {
+		return math.Float64frombits(cpu.pressure.Load())
+	}
+
+	cpu.sampled = now
+	cpu.samples[0].Name, cpu.samples[1].Name = cpuTotalMetric, cpuIdleMetric
+	metrics.Read(cpu.samples[:])
+	if cpu.samples[0].Value.Kind() != metrics.KindFloat64 || cpu.samples[1].Value.Kind() != metrics.KindFloat64 {
+		return 0
+	}
+
+	total, idle := cpu.samples[0].Value.Float64(), cpu.samples[1].Value.Float64()
+	if dt := total - cpu.total; dt > 0 {
+		pressure := math.Max(0, math.Min(1, 1-(idle-cpu.idle)/dt))
+		cpu.pressure.Store(math.Float64bits(pressure))
+	}
+
+	cpu.total, cpu.idle = total, idle
+	return math.Float64frombits(cpu.pressure.Load())
+}
@@ -16,32 +16,86 @@ const (
 	contentEncodingHeader = "Content-Encoding"
 	acceptEncodingHeader  = "Accept-Encoding"
 	contentTypeHeader     = "Content-Type"
-	varyHeader            = "Vary"
 	textPlain             = "text/plain" + "; charset=" + "utf-8"
+	textEventStream       = "text/event-stream"
 	gzipVal               = "gzip"
 )
 
-var gzipPool = sync.Pool{
-	New: func() interface{} {
-		return &gzipWriter{Writer: gzip.NewWriter(io.Discard)}
-	},
+var (
+	gzipPool = sync.Pool{
+		New: func() interface{} {
+			counters.New()
+			return &gzipWriter{Writer: gzip.NewWriter(io.Discard)}
+		},
+	}
+
+	// counters of the operations of all gzip writer pools
+	counters feather.PoolCounters
+)
+
+// PoolStats returns the counters of the gzip writer pools.
+func PoolStats() feather.PoolStats {
+	return counters.Stats()
+}
+
+// PublishPoolStats publishes the counters of the gzip writer pools as an expvar under name,
+// it panics if the name is already registered.
+func PublishPoolStats(name string) {
+	counters.Publish(name)
 }
 
 type gzipWriter struct {
 	io.Writer
 	http.ResponseWriter
 	sniffComplete bool
+	checked       bool // whether the response was checked for streaming
+	passthrough   bool // streaming responses are written uncompressed
 }
 
 func (w *gzipWriter) Flush() error {
 	return w.Writer.(*gzip.Writer).Flush()
 }
 
+// FlushError flushes the compressed data and the underlying writer, it is used by http.ResponseController.
+func (w *gzipWriter) FlushError() error {
+	w.checkStream()
+	if !w.passthrough {
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+
+	return http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// checkStream disables compression for Server-Sent Events,
+// which must reach the client without being buffered by the compressor.
+func (w *gzipWriter) checkStream() {
+	if w.checked {
+		return
+	}
+
+	w.checked = true
+	if strings.HasPrefix(w.Header().Get(contentTypeHeader), textEventStream) {
+		w.passthrough = true
+		w.Header().Del(contentEncodingHeader)
+	}
+}
+
+func (w *gzipWriter) WriteHeader(code int) {
+	w.checkStream()
+	w.ResponseWriter.WriteHeader(code)
+}
+
 func (w *gzipWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
 	return w.ResponseWriter.(http.Hijacker).Hijack()
 }
 
 func (w *gzipWriter) Write(b []byte) (int, error) {
+	if w.checkStream(); w.passthrough {
+		return w.ResponseWriter.Write(b)
+	}
+
 	if !w.sniffComplete {
 		if w.Header().Get(contentTypeHeader) == "" {
 			w.Header().Set(contentTypeHeader, http.DetectContentType(b))
@@ -53,13 +107,22 @@ func (w *gzipWriter) Write(b []byte) (int, error) {
 	return w.Writer.Write(b)
 }
 
+// compress reports whether the response should be compressed,
+// the client must accept gzip and the route must not have disabled compression using feather.Meta.
+func compress(r *http.Request) bool {
+	return strings.Contains(r.Header.Get(acceptEncodingHeader), gzipVal) &&
+		feather.RouteMeta(r).Compression != feather.CompressionDisabled
+}
+
 // Gzip returns a middleware which compresses HTTP response using gzip compression scheme.
 func Gzip(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Add(varyHeader, acceptEncodingHeader)
-		if strings.Contains(r.Header.Get(acceptEncodingHeader), gzipVal) {
+		feather.AddVary(w, acceptEncodingHeader)
+		if compress(r) {
+			counters.Get()
 			gz := gzipPool.Get().(*gzipWriter)
 			gz.sniffComplete = false
+			gz.checked, gz.passthrough = false, false
 			gzr := gz.Writer.(*gzip.Writer)
 			gzr.Reset(w)
 			gz.ResponseWriter = w
@@ -74,6 +137,7 @@ func Gzip(next http.HandlerFunc) http.HandlerFunc {
 				}
 
 				gzr.Close()
+				counters.Put()
 				gzipPool.Put(gz)
 			}()
 		}
@@ -93,6 +157,7 @@ func GzipLevel(level int) feather.Middleware {
 
 	var gzipPool = sync.Pool{
 		New: func() interface{} {
+			counters.New()
 			z, _ := gzip.NewWriterLevel(io.Discard, level)
 			return &gzipWriter{Writer: z}
 		},
@@ -100,10 +165,12 @@ func GzipLevel(level int) feather.Middleware {
 
 	return func(next http.HandlerFunc) http.HandlerFunc {
 		return func(w http.ResponseWriter, r *http.Request) {
-			w.Header().Add(varyHeader, acceptEncodingHeader)
-			if strings.Contains(r.Header.Get(acceptEncodingHeader), gzipVal) {
+			feather.AddVary(w, acceptEncodingHeader)
+			if compress(r) {
+				counters.Get()
 				gz := gzipPool.Get().(*gzipWriter)
 				gz.sniffComplete = false
+				gz.checked, gz.passthrough = false, false
 				gzr := gz.Writer.(*gzip.Writer)
 				gzr.Reset(w)
 				gz.ResponseWriter = w
@@ -118,6 +185,7 @@ func GzipLevel(level int) feather.Middleware {
 					}
 
 					gzr.Close()
+					counters.Put()
 					gzipPool.Put(gz)
 				}()
 			}
@@ -9,6 +9,7 @@ import (
 	"net"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"testing"
 
 	"github.com/pchchv/feather"
@@ -165,3 +166,151 @@ func TestGzipLevel(t *testing.T) {
 	Equal(t, err, nil)
 	Equal(t, resp.StatusCode, http.StatusOK)
 }
+
+func TestGzipDisabledByMeta(t *testing.T) {
+	p := feather.New()
+	p.Use(Gzip)
+	p.WithMeta(feather.Meta{Compression: feather.CompressionDisabled}).Get("/archive", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("archive"))
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/archive", nil)
+	r.Header.Set(acceptEncodingHeader, gzipVal)
+	w := httptest.NewRecorder()
+	p.Serve().ServeHTTP(w, r)
+	Equal(t, w.Code, http.StatusOK)
+	Equal(t, w.Header().Get(contentEncodingHeader), "")
+	Equal(t, w.Body.String(), "archive")
+}
+
+func TestGzipEventStream(t *testing.T) {
+	p := feather.New()
+	p.Use(Gzip)
+	p.Get("/events", func(w http.ResponseWriter, r *http.Request) {
+		es, err := feather.EventStream(w)
+		if err != nil {
+			panic(err)
+		}
+		defer es.Close()
+
+		_ = es.SendEvent("1", "", "data")
+	})
+
+	server := httptest.NewServer(p.Serve())
+	defer server.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/events", nil)
+	req.Header.Set(acceptEncodingHeader, gzipVal)
+	resp, err := http.DefaultTransport.RoundTrip(req)
+	Equal(t, err, nil)
+	defer resp.Body.Close()
+	Equal(t, resp.Header.Get(contentEncodingHeader), "")
+	Equal(t, resp.Header.Get(contentTypeHeader), textEventStream)
+
+	b, err := io.ReadAll(resp.Body)
+	Equal(t, err, nil)
+	Equal(t, string(b), "id: 1\ndata: data\n\n")
+}
+
+func TestGzipPoolStats(t *testing.T) {
+	before := PoolStats()
+	p := feather.New()
+	p.Use(Gzip)
+	p.Get("/test", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("test"))
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/test", nil)
+	r.Header.Set(acceptEncodingHeader, gzipVal)
+	p.Serve().ServeHTTP(httptest.NewRecorder(), r)
+
+	after := PoolStats()
+	Equal(t, after.Gets-before.Gets, uint64(1))
+	Equal(t, after.Puts-before.Puts, uint64(1))
+}
+
+func TestGzipVary(t *testing.T) {
+	p := feather.New()
+	p.Use(Gzip, Gzip)
+	p.Get("/", func(w http.ResponseWriter, r *http.Request) {
+		_ = feather.Negotiate(w, r, http.StatusOK, "test")
+	})
+
+	r, _ := http.NewRequest(http.MethodGet, "/", nil)
+	w := httptest.NewRecorder()
+	p.Serve().ServeHTTP(w, r)
+	Equal(t, w.Header().Values("Vary"), []string{"Accept-Encoding, Accept"})
+}
+
+func TestGzipAdaptiveLevel(t *testing.T) {
+	cfg := AdaptiveConfig{MinLevel: 1, MaxLevel: 9, LargeSize: 1024, Threshold: 0.5}
+	tests := []struct {
+		size     int
+		pressure float64
+		level    int
+	}{
+		{size: 10, pressure: 0, level: 9},
+		{size: 10, pressure: 0.5, level: 9},
+		{size: 10, pressure: 0.75, level: 5},
+		{size: 10, pressure: 1, level: 1},
+		{size: 1024, pressure: 0, level: 5},
+		{size: 1024, pressure: 0.75, level: 3},
+		{size: 1024, pressure: 2, level: 1},
+	}
+
+	for _, tt := range tests {
+		Equal(t, cfg.level(tt.size, tt.pressure), tt.level)
+	}
+}
+
+func TestGzipAdaptive(t *testing.T) {
+	PanicMatches(t, func() { GzipAdaptive(AdaptiveConfig{MinLevel: 9, MaxLevel: 1}) }, "gzip: invalid adaptive compression levels: 9 to 1")
+	PanicMatches(t, func() { GzipAdaptive(AdaptiveConfig{MaxLevel: 99}) }, "gzip: invalid adaptive compression levels: 1 to 99")
+
+	var pressure float64
+	body := bytes.Repeat([]byte("adaptive "), 1000)
+	p := feather.New()
+	p.Use(GzipAdaptive(AdaptiveConfig{LargeSize: 4096, Pressure: func() float64 { return pressure }}))
+	p.Get("/test", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		_, _ = w.Write(body)
+	})
+	p.Get("/empty", func(w http.ResponseWriter, r *http.Request) {})
+	hf := p.Serve()
+
+	for _, pressure = range []float64{0, 1} {
+		r := httptest.NewRequest(http.MethodGet, "/test", nil)
+		r.Header.Set(acceptEncodingHeader, gzipVal)
+		w := httptest.NewRecorder()
+		hf.ServeHTTP(w, r)
+		Equal(t, w.Code, http.StatusOK)
+		Equal(t, w.Header().Get(contentEncodingHeader), gzipVal)
+		Equal(t, w.Header().Get("Content-Length"), "")
+		Equal(t, w.Header().Get(contentTypeHeader), textPlain)
+
+		gr, err := gzip.NewReader(w.Body)
+		Equal(t, err, nil)
+		b, err := io.ReadAll(gr)
+		Equal(t, err, nil)
+		Equal(t, string(b), string(body))
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/test", nil)
+	w := httptest.NewRecorder()
+	hf.ServeHTTP(w, r)
+	Equal(t, w.Header().Get(contentEncodingHeader), "")
+	Equal(t, w.Body.String(), string(body))
+
+	r = httptest.NewRequest(http.MethodGet, "/empty", nil)
+	r.Header.Set(acceptEncodingHeader, gzipVal)
+	w = httptest.NewRecorder()
+	hf.ServeHTTP(w, r)
+	Equal(t, w.Code, http.StatusOK)
+	Equal(t, w.Header().Get(contentEncodingHeader), "")
+	Equal(t, w.Body.Len(), 0)
+}
+
+func TestCPUPressure(t *testing.T) {
+	pressure := CPUPressure()
+	Equal(t, pressure >= 0 && pressure <= 1, true)
+}
@@ -0,0 +1,215 @@
+package jwt
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	defaultJWKSRefresh = time.Hour
+	jwksTimeout        = 10 * time.Second
+	maxJWKSSize        = 1 << 20
+)
+
+// jwk is a JSON Web Key, only the public key parameters are used.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+	K   string `json:"k"`
+}
+
+// jwks fetches and caches the keys of a JSON Web Key Set by key id.
+type jwks struct {
+	url       string
+	client    *http.Client
+	refresh   time.Duration
+	m         sync.Mutex
+	keys      map[string]interface{}
+	err       error         // error of the last fetch
+	attempted time.Time     // time of the last fetch, failed ones included so retries back off
+	fetching  chan struct{} // closed once the fetch in flight completes, nil if none
+	now       func() time.Time
+}
+
+func newJWKS(url string, client *http.Client, refresh time.Duration) *jwks {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	if refresh <= 0 {
+		refresh = defaultJWKSRefresh
+	}
+
+	return &jwks{url: url, client: client, refresh: refresh, now: time.Now}
+}
+
+// keyFunc returns the key of the key id, fetching the key set when it is stale
+// or the key id is unknown, the latter at most every refresh / 60 so unknown key ids can't flood the URL.
+// The key set is fetched by a single request at a time, outside the lock, the others keep using the cached keys
+// and only wait for the fetch in flight if there are none yet.
+func (j *jwks) keyFunc(h Header) (interface{}, error) {
+	j.m.Lock()
+	now := j.now()
+	age := now.Sub(j.attempted)
+	_, ok := j.keys[h.Kid]
+	switch {
+	case j.fetching == nil && (age >= j.refresh || (!ok && age >= j.refresh/60)):
+		j.attempted = now
+		done := make(chan struct{})
+		j.fetching = done
+		j.m.Unlock()
+
+		keys, err := j.fetch()
+		j.m.Lock()
+		// keep using the cached keys if the URL is temporarily unavailable
+		if err == nil {
+			j.keys = keys
+		}
+		j.err = err
+		j.fetching = nil
+		close(done)
+	case j.fetching != nil && j.keys == nil:
+		done := j.fetching
+		j.m.Unlock()
+		<-done
+		j.m.Lock()
+	}
+	defer j.m.Unlock()
+
+	if j.keys == nil {
+		return nil, j.err
+	}
+
+	key, ok := j.keys[h.Kid]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownKey, h.Kid)
+	}
+
+	return key, nil
+}
+
+func (j *jwks) fetch() (map[string]interface{}, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), jwksTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, j.url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("jwt: fetching key set: %w", err)
+	}
+
+	resp, err := j.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("jwt: fetching key set: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("jwt: fetching key set: %s", resp.Status)
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err = json.NewDecoder(http.MaxBytesReader(nil, resp.Body, maxJWKSSize)).Decode(&set); err != nil {
+		return nil, fmt.Errorf("jwt: decoding key set: %w", err)
+	}
+
+	keys := make(map[string]interface{}, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+
+		// keys of unsupported types are skipped
+		if key, err := k.publicKey(); err == nil {
+			keys[k.Kid] = key
+		}
+	}
+
+	return keys, nil
+}
+
+var errUnsupportedKey = errors.New("jwt: unsupported key")
+
+// publicKey returns the key used to verify signatures.
+func (k jwk) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+
+		e, err := decodeInt(k.E)
+		if err != nil || !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, errUnsupportedKey
+		}
+
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, errUnsupportedKey
+		}
+
+		x, err := decodeInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+
+		y, err := decodeInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+
+		if !curve.IsOnCurve(x, y) {
+			return nil, errUnsupportedKey
+		}
+
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case "OKP":
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || k.Crv != "Ed25519" || len(x) != ed25519.PublicKeySize {
+			return nil, errUnsupportedKey
+		}
+
+		return ed25519.PublicKey(x), nil
+	case "oct":
+		return base64.RawURLEncoding.DecodeString(k.K)
+	default:
+		return nil, errUnsupportedKey
+	}
+}
+
+func decodeInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(b) == 0 {
+		return nil, errUnsupportedKey
+	}
+
+	return new(big.Int).SetBytes(b), nil
+}
@@ -0,0 +1,268 @@
+// Package jwt provides middleware verifying JSON Web Tokens, signed using HMAC, RSA, ECDSA or Ed25519 keys
+// which are configured statically, looked up using a KeyFunc or fetched from a JWKS URL,
+// and storing their claims in the request variables.
+package jwt
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/pchchv/feather"
+)
+
+// ClaimsKey is the ReqVars key under which the claims of the verified token are stored, see FromRequest.
+const ClaimsKey = "feather.jwt_claims"
+
+const (
+	authorizationHeader   = "Authorization"
+	wwwAuthenticateHeader = "WWW-Authenticate"
+	bearer                = "Bearer "
+)
+
+// Claims are the claims of a token.
+type Claims map[string]interface{}
+
+// Subject returns the sub claim.
+func (c Claims) Subject() string {
+	s, _ := c["sub"].(string)
+	return s
+}
+
+// Issuer returns the iss claim.
+func (c Claims) Issuer() string {
+	s, _ := c["iss"].(string)
+	return s
+}
+
+// Audience returns the aud claim, which is either a string or an array of strings.
+func (c Claims) Audience() []string {
+	switch aud := c["aud"].(type) {
+	case string:
+		return []string{aud}
+	case []interface{}:
+		auds := make([]string, 0, len(aud))
+		for _, a := range aud {
+			if s, ok := a.(string); ok {
+				auds = append(auds, s)
+			}
+		}
+		return auds
+	default:
+		return nil
+	}
+}
+
+// Scopes returns the space separated scope claim or the scp claim, which may also be an array of strings.
+func (c Claims) Scopes() []string {
+	switch scp := c["scope"].(type) {
+	case string:
+		return strings.Fields(scp)
+	}
+
+	switch scp := c["scp"].(type) {
+	case string:
+		return strings.Fields(scp)
+	case []interface{}:
+		scopes := make([]string, 0, len(scp))
+		for _, s := range scp {
+			if s, ok := s.(string); ok {
+				scopes = append(scopes, s)
+			}
+		}
+		return scopes
+	default:
+		return nil
+	}
+}
+
+// time returns the NumericDate claim name as a time.
+func (c Claims) time(name string) (t time.Time, ok bool) {
+	f, ok := c[name].(float64)
+	if !ok {
+		return
+	}
+
+	return time.Unix(0, int64(f*float64(time.Second))), true
+}
+
+// KeyFunc returns the key verifying tokens signed using the algorithm by the key id of the header, if any:
+// []byte for HMAC, *rsa.PublicKey, *ecdsa.PublicKey or ed25519.PublicKey.
+type KeyFunc func(h Header) (interface{}, error)
+
+// Config contains the JWT verification settings, one of Key, KeyFunc or JWKSURL is required.
+type Config struct {
+	// Key verifies all tokens, []byte for HMAC, *rsa.PublicKey, *ecdsa.PublicKey or ed25519.PublicKey.
+	Key interface{}
+	// KeyFunc looks up the key of the token, i.e. by the key id.
+	KeyFunc KeyFunc
+	// JWKSURL is the URL of a JSON Web Key Set, the keys are looked up by the key id of the token.
+	JWKSURL string
+	// JWKSRefresh is the interval the key set is fetched again, by default 1 hour.
+	// Tokens with an unknown key id also refresh the key set, at most every JWKSRefresh / 60.
+	JWKSRefresh time.Duration
+	// Client fetches the key set, by default http.DefaultClient. The fetches time out after 10 seconds.
+	Client *http.Client
+	// Algorithms allowed, by default HS256, HS384, HS512, RS256, RS384, RS512, PS256, PS384, PS512,
+	// ES256, ES384, ES512 and EdDSA. The key type must match the algorithm regardless.
+	Algorithms []string
+	// Audience, if set, must be one of the aud claim values.
+	Audience string
+	// Issuer, if set, must equal the iss claim.
+	Issuer string
+	// Leeway accounts for clock skew when validating the exp and nbf claims.
+	Leeway time.Duration
+	// Token extracts the token from the request, by default from the Authorization Bearer header.
+	Token func(r *http.Request) string
+	// ErrorHandler responds to requests without a valid token,
+	// by default 401 Unauthorized with a WWW-Authenticate header.
+	ErrorHandler func(w http.ResponseWriter, r *http.Request, err error)
+	// now returns the current time, used by the tests
+	now func() time.Time
+}
+
+var defaultAlgorithms = []string{
+	"HS256", "HS384", "HS512",
+	"RS256", "RS384", "RS512",
+	"PS256", "PS384", "PS512",
+	"ES256", "ES384", "ES512",
+	"EdDSA",
+}
+
+// New returns a middleware verifying the token of every request and storing its claims in the request variables,
+// see FromRequest. It panics if no key is configured.
+func New(cfg Config) feather.Middleware {
+	if cfg.KeyFunc == nil {
+		switch {
+		case cfg.Key != nil:
+			key := cfg.Key
+			cfg.KeyFunc = func(Header) (interface{}, error) {
+				return key, nil
+			}
+		case cfg.JWKSURL != "":
+			cfg.KeyFunc = newJWKS(cfg.JWKSURL, cfg.Client, cfg.JWKSRefresh).keyFunc
+		default:
+			panic("jwt: one of Key, KeyFunc or JWKSURL is required")
+		}
+	}
+
+	if len(cfg.Algorithms) == 0 {
+		cfg.Algorithms = defaultAlgorithms
+	}
+
+	if cfg.Token == nil {
+		cfg.Token = bearerToken
+	}
+
+	if cfg.ErrorHandler == nil {
+		cfg.ErrorHandler = unauthorized
+	}
+
+	if cfg.now == nil {
+		cfg.now = time.Now
+	}
+
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			claims, err := cfg.verify(cfg.Token(r))
+			if err != nil {
+				cfg.ErrorHandler(w, r, err)
+				return
+			}
+
+			feather.RequestVars(r).Set(ClaimsKey, claims)
+			next(w, r)
+		}
+	}
+}
+
+// verify verifies the token and validates its claims.
+func (cfg *Config) verify(token string) (Claims, error) {
+	if token == "" {
+		return nil, ErrMissingToken
+	}
+
+	h, claims, signingInput, sig, err := parse(token)
+	if err != nil {
+		return nil, err
+	}
+
+	if !slices.Contains(cfg.Algorithms, h.Alg) {
+		return nil, ErrAlgorithm
+	}
+
+	key, err := cfg.KeyFunc(h)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = verifySignature(h.Alg, key, signingInput, sig); err != nil {
+		return nil, err
+	}
+
+	now := cfg.now()
+	if exp, ok := claims.time("exp"); ok && !now.Before(exp.Add(cfg.Leeway)) {
+		return nil, ErrExpired
+	}
+
+	if nbf, ok := claims.time("nbf"); ok && now.Add(cfg.Leeway).Before(nbf) {
+		return nil, ErrNotValidYet
+	}
+
+	if cfg.Issuer != "" && claims.Issuer() != cfg.Issuer {
+		return nil, ErrIssuer
+	}
+
+	if cfg.Audience != "" && !slices.Contains(claims.Audience(), cfg.Audience) {
+		return nil, ErrAudience
+	}
+
+	return claims, nil
+}
+
+// FromRequest returns the claims of the token verified by the middleware, nil if none.
+func FromRequest(r *http.Request) Claims {
+	claims, _ := feather.RequestVars(r).Get(ClaimsKey).(Claims)
+	return claims
+}
+
+// RequireScopes returns a middleware, intended to be passed when registering routes,
+// answering requests whose token lacks any of the scopes with 403 Forbidden.
+// It must run after the middleware returned by New.
+func RequireScopes(scopes ...string) feather.Middleware {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			granted := FromRequest(r).Scopes()
+			for _, s := range scopes {
+				if !slices.Contains(granted, s) {
+					w.Header().Set(wwwAuthenticateHeader, `Bearer error="insufficient_scope", scope="`+strings.Join(scopes, " ")+`"`)
+					http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+					return
+				}
+			}
+
+			next(w, r)
+		}
+	}
+}
+
+func bearerToken(r *http.Request) string {
+	auth := r.Header.Get(authorizationHeader)
+	if len(auth) > len(bearer) && strings.EqualFold(auth[:len(bearer)], bearer) {
+		return strings.TrimSpace(auth[len(bearer):])
+	}
+
+	return ""
+}
+
+func unauthorized(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, ErrMissingToken) {
+		w.Header().Set(wwwAuthenticateHeader, "Bearer")
+	} else {
+		w.Header().Set(wwwAuthenticateHeader, `Bearer error="invalid_token"`)
+	}
+
+	http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+}
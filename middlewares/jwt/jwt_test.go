@@ -0,0 +1,323 @@
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pchchv/feather"
+	. "github.com/pchchv/feather/assert"
+)
+
+var now = time.Unix(1700000000, 0)
+
+func sign(t *testing.T, h Header, claims Claims, key interface{}) string {
+	hb, _ := json.Marshal(h)
+	cb, _ := json.Marshal(claims)
+	input := base64.RawURLEncoding.EncodeToString(hb) + "." + base64.RawURLEncoding.EncodeToString(cb)
+
+	var sig []byte
+	var err error
+	if h.Alg == "EdDSA" {
+		sig = ed25519.Sign(key.(ed25519.PrivateKey), []byte(input))
+	} else {
+		hash, _ := hashOf(h.Alg)
+		hh := hash.New()
+		hh.Write([]byte(input))
+		digest := hh.Sum(nil)
+		switch h.Alg[:2] {
+		case "HS":
+			mac := hmac.New(hash.New, key.([]byte))
+			mac.Write([]byte(input))
+			sig = mac.Sum(nil)
+		case "RS":
+			sig, err = rsa.SignPKCS1v15(rand.Reader, key.(*rsa.PrivateKey), hash, digest)
+		case "PS":
+			sig, err = rsa.SignPSS(rand.Reader, key.(*rsa.PrivateKey), hash, digest, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+		case "ES":
+			k := key.(*ecdsa.PrivateKey)
+			var r, s *big.Int
+			r, s, err = ecdsa.Sign(rand.Reader, k, digest)
+			size := (k.Curve.Params().BitSize + 7) / 8
+			sig = make([]byte, 2*size)
+			r.FillBytes(sig[:size])
+			s.FillBytes(sig[size:])
+		}
+	}
+	Equal(t, err, nil)
+
+	return input + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func serve(p *feather.Mux, path string, token string) *httptest.ResponseRecorder {
+	r, _ := http.NewRequest(http.MethodGet, path, nil)
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	w := httptest.NewRecorder()
+	p.Serve().ServeHTTP(w, r)
+	return w
+}
+
+func TestVerify(t *testing.T) {
+	secret := []byte("secret")
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	edPub, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	claims := Claims{"sub": "joeybloggs", "exp": float64(now.Add(time.Minute).Unix())}
+
+	tests := []struct {
+		alg     string
+		signKey interface{}
+		key     interface{}
+	}{
+		{"HS256", secret, secret},
+		{"HS512", secret, secret},
+		{"RS256", rsaKey, &rsaKey.PublicKey},
+		{"PS384", rsaKey, &rsaKey.PublicKey},
+		{"ES256", ecKey, &ecKey.PublicKey},
+		{"EdDSA", edKey, edPub},
+	}
+
+	for _, tt := range tests {
+		cfg := &Config{
+			KeyFunc:    func(Header) (interface{}, error) { return tt.key, nil },
+			Algorithms: defaultAlgorithms,
+			now:        func() time.Time { return now },
+		}
+		token := sign(t, Header{Alg: tt.alg}, claims, tt.signKey)
+
+		c, err := cfg.verify(token)
+		Equal(t, err, nil)
+		Equal(t, c.Subject(), "joeybloggs")
+
+		// tampered
+		_, err = cfg.verify(token[:len(token)-4] + "AAAA")
+		NotEqual(t, err, nil)
+	}
+
+	// a public key can't be used as an HMAC secret
+	cfg := &Config{
+		KeyFunc:    func(Header) (interface{}, error) { return &rsaKey.PublicKey, nil },
+		Algorithms: defaultAlgorithms,
+		now:        func() time.Time { return now },
+	}
+	_, err := cfg.verify(sign(t, Header{Alg: "HS256"}, claims, []byte("public key bytes")))
+	Equal(t, err, ErrKeyType)
+
+	_, err = cfg.verify("e30.e30")
+	Equal(t, errors.Is(err, ErrMalformed), true)
+
+	cfg.Algorithms = []string{"ES256"}
+	_, err = cfg.verify(sign(t, Header{Alg: "RS256"}, claims, rsaKey))
+	Equal(t, err, ErrAlgorithm)
+}
+
+func TestClaimsValidation(t *testing.T) {
+	secret := []byte("secret")
+	cfg := &Config{
+		KeyFunc:    func(Header) (interface{}, error) { return secret, nil },
+		Algorithms: defaultAlgorithms,
+		Audience:   "api",
+		Issuer:     "https://issuer.example.com",
+		Leeway:     time.Second,
+		now:        func() time.Time { return now },
+	}
+	valid := func() Claims {
+		return Claims{
+			"iss": "https://issuer.example.com",
+			"aud": []interface{}{"web", "api"},
+			"exp": float64(now.Unix()),
+			"nbf": float64(now.Add(time.Second).Unix()),
+		}
+	}
+
+	_, err := cfg.verify(sign(t, Header{Alg: "HS256"}, valid(), secret))
+	Equal(t, err, nil)
+
+	c := valid()
+	c["exp"] = float64(now.Add(-time.Second).Unix())
+	_, err = cfg.verify(sign(t, Header{Alg: "HS256"}, c, secret))
+	Equal(t, err, ErrExpired)
+
+	c = valid()
+	c["nbf"] = float64(now.Add(2 * time.Second).Unix())
+	_, err = cfg.verify(sign(t, Header{Alg: "HS256"}, c, secret))
+	Equal(t, err, ErrNotValidYet)
+
+	c = valid()
+	c["aud"] = "web"
+	_, err = cfg.verify(sign(t, Header{Alg: "HS256"}, c, secret))
+	Equal(t, err, ErrAudience)
+
+	c = valid()
+	c["iss"] = "https://evil.example.com"
+	_, err = cfg.verify(sign(t, Header{Alg: "HS256"}, c, secret))
+	Equal(t, err, ErrIssuer)
+
+	_, err = cfg.verify("")
+	Equal(t, err, ErrMissingToken)
+}
+
+func TestMiddleware(t *testing.T) {
+	secret := []byte("secret")
+	p := feather.New()
+	p.Use(New(Config{Key: secret, now: func() time.Time { return now }}))
+	p.Get("/me", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(FromRequest(r).Subject()))
+	})
+	p.Get("/admin", func(w http.ResponseWriter, r *http.Request) {}, RequireScopes("admin", "write"))
+
+	token := sign(t, Header{Alg: "HS256"}, Claims{"sub": "joeybloggs", "scope": "read write"}, secret)
+	w := serve(p, "/me", token)
+	Equal(t, w.Code, http.StatusOK)
+	Equal(t, w.Body.String(), "joeybloggs")
+
+	w = serve(p, "/me", "")
+	Equal(t, w.Code, http.StatusUnauthorized)
+	Equal(t, w.Header().Get("WWW-Authenticate"), "Bearer")
+
+	w = serve(p, "/me", "invalid")
+	Equal(t, w.Code, http.StatusUnauthorized)
+	Equal(t, w.Header().Get("WWW-Authenticate"), `Bearer error="invalid_token"`)
+
+	w = serve(p, "/admin", token)
+	Equal(t, w.Code, http.StatusForbidden)
+	Equal(t, w.Header().Get("WWW-Authenticate"), `Bearer error="insufficient_scope", scope="admin write"`)
+
+	token = sign(t, Header{Alg: "HS256"}, Claims{"scp": []string{"admin", "write"}}, secret)
+	w = serve(p, "/admin", token)
+	Equal(t, w.Code, http.StatusOK)
+
+	PanicsWithValue(t, func() { New(Config{}) }, "jwt: one of Key, KeyFunc or JWKSURL is required")
+}
+
+func TestJWKS(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	b64 := func(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+	keys := []map[string]string{
+		{"kty": "RSA", "kid": "rsa", "n": b64(rsaKey.N.Bytes()), "e": b64(big.NewInt(int64(rsaKey.E)).Bytes())},
+		{"kty": "EC", "kid": "ec", "crv": "P-256", "x": b64(ecKey.X.Bytes()), "y": b64(ecKey.Y.Bytes())},
+		{"kty": "RSA", "kid": "enc", "use": "enc", "n": b64(rsaKey.N.Bytes()), "e": "AQAB"},
+		{"kty": "unknown", "kid": "unknown"},
+	}
+
+	var fetches atomic.Int32
+	var down atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		if down.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"keys": keys})
+	}))
+	defer srv.Close()
+
+	clock := now
+	j := newJWKS(srv.URL, srv.Client(), time.Hour)
+	j.now = func() time.Time { return clock }
+	cfg := &Config{KeyFunc: j.keyFunc, Algorithms: defaultAlgorithms, now: func() time.Time { return clock }}
+
+	_, err := cfg.verify(sign(t, Header{Alg: "RS256", Kid: "rsa"}, Claims{"sub": "a"}, rsaKey))
+	Equal(t, err, nil)
+	_, err = cfg.verify(sign(t, Header{Alg: "ES256", Kid: "ec"}, Claims{"sub": "a"}, ecKey))
+	Equal(t, err, nil)
+	Equal(t, fetches.Load(), int32(1))
+
+	// unknown key ids refresh the key set, but not too often
+	_, err = cfg.verify(sign(t, Header{Alg: "RS256", Kid: "enc"}, Claims{"sub": "a"}, rsaKey))
+	Equal(t, errors.Is(err, ErrUnknownKey), true)
+	Equal(t, fetches.Load(), int32(1))
+
+	clock = clock.Add(time.Minute)
+	_, err = cfg.verify(sign(t, Header{Alg: "RS256", Kid: "unknown"}, Claims{"sub": "a"}, rsaKey))
+	Equal(t, errors.Is(err, ErrUnknownKey), true)
+	Equal(t, fetches.Load(), int32(2))
+
+	clock = clock.Add(time.Hour)
+	_, err = cfg.verify(sign(t, Header{Alg: "RS256", Kid: "rsa"}, Claims{"sub": "a"}, rsaKey))
+	Equal(t, err, nil)
+	Equal(t, fetches.Load(), int32(3))
+
+	// cached keys are used while the key set is unavailable, and the failed fetch isn't retried on every request
+	down.Store(true)
+	clock = clock.Add(2 * time.Hour)
+	for i := 0; i < 3; i++ {
+		_, err = cfg.verify(sign(t, Header{Alg: "RS256", Kid: "rsa"}, Claims{"sub": "a"}, rsaKey))
+		Equal(t, err, nil)
+	}
+	_, err = cfg.verify(sign(t, Header{Alg: "RS256", Kid: "enc"}, Claims{"sub": "a"}, rsaKey))
+	Equal(t, errors.Is(err, ErrUnknownKey), true)
+	Equal(t, fetches.Load(), int32(4))
+
+	clock = clock.Add(time.Minute)
+	_, err = cfg.verify(sign(t, Header{Alg: "RS256", Kid: "enc"}, Claims{"sub": "a"}, rsaKey))
+	Equal(t, errors.Is(err, ErrUnknownKey), true)
+	Equal(t, fetches.Load(), int32(5))
+
+	// without cached keys, the error of the fetch is returned until the next attempt
+	j = newJWKS(srv.URL, srv.Client(), time.Hour)
+	j.now = func() time.Time { return clock }
+	cfg.KeyFunc = j.keyFunc
+	for i := 0; i < 2; i++ {
+		_, err = cfg.verify(sign(t, Header{Alg: "RS256", Kid: "rsa"}, Claims{"sub": "a"}, rsaKey))
+		NotEqual(t, err, nil)
+	}
+	Equal(t, fetches.Load(), int32(6))
+}
+
+func TestJWKSFetchInFlight(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	b64 := func(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+	keys := []map[string]string{{"kty": "EC", "kid": "ec", "crv": "P-256", "x": b64(key.X.Bytes()), "y": b64(key.Y.Bytes())}}
+
+	var fetches atomic.Int32
+	started, release := make(chan struct{}, 1), make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fetches.Add(1) > 1 {
+			started <- struct{}{}
+			<-release
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"keys": keys})
+	}))
+	defer srv.Close()
+
+	clock := now
+	j := newJWKS(srv.URL, srv.Client(), time.Hour)
+	j.now = func() time.Time { return clock }
+	_, err := j.keyFunc(Header{Kid: "ec"})
+	Equal(t, err, nil)
+
+	// a single request refreshes the stale key set, the others keep using the cached keys meanwhile
+	clock = clock.Add(2 * time.Hour)
+	done := make(chan error)
+	go func() {
+		_, err := j.keyFunc(Header{Kid: "ec"})
+		done <- err
+	}()
+	<-started
+
+	for i := 0; i < 3; i++ {
+		_, err = j.keyFunc(Header{Kid: "ec"})
+		Equal(t, err, nil)
+	}
+	Equal(t, fetches.Load(), int32(2))
+
+	close(release)
+	Equal(t, <-done, nil)
+}
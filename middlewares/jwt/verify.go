@@ -0,0 +1,174 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rsa"
+	_ "crypto/sha256" // SHA-256 for HS256, RS256, PS256 and ES256
+	_ "crypto/sha512" // SHA-384 and SHA-512 for the other algorithms
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// Errors returned when verifying tokens, passed to Config.ErrorHandler.
+var (
+	ErrMissingToken = errors.New("jwt: missing token")
+	ErrMalformed    = errors.New("jwt: malformed token")
+	ErrAlgorithm    = errors.New("jwt: algorithm not allowed")
+	ErrKeyType      = errors.New("jwt: key type doesn't match the algorithm")
+	ErrUnknownKey   = errors.New("jwt: unknown key")
+	ErrSignature    = errors.New("jwt: invalid signature")
+	ErrExpired      = errors.New("jwt: token is expired")
+	ErrNotValidYet  = errors.New("jwt: token is not valid yet")
+	ErrAudience     = errors.New("jwt: invalid audience")
+	ErrIssuer       = errors.New("jwt: invalid issuer")
+)
+
+// Header is the JOSE header of a token.
+type Header struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid,omitempty"`
+	Typ string `json:"typ,omitempty"`
+}
+
+// parse splits and decodes the compact serialization of a signed token.
+func parse(token string) (h Header, claims Claims, signingInput string, sig []byte, err error) {
+	first := strings.IndexByte(token, '.')
+	last := strings.LastIndexByte(token, '.')
+	if first == -1 || first == last {
+		err = ErrMalformed
+		return
+	}
+
+	signingInput = token[:last]
+	var b []byte
+	if b, err = base64.RawURLEncoding.DecodeString(token[:first]); err != nil {
+		err = fmt.Errorf("%w: header: %w", ErrMalformed, err)
+		return
+	}
+
+	if err = json.Unmarshal(b, &h); err != nil {
+		err = fmt.Errorf("%w: header: %w", ErrMalformed, err)
+		return
+	}
+
+	if b, err = base64.RawURLEncoding.DecodeString(token[first+1 : last]); err != nil {
+		err = fmt.Errorf("%w: claims: %w", ErrMalformed, err)
+		return
+	}
+
+	if err = json.Unmarshal(b, &claims); err != nil || claims == nil {
+		err = fmt.Errorf("%w: claims: %v", ErrMalformed, err)
+		return
+	}
+
+	if sig, err = base64.RawURLEncoding.DecodeString(token[last+1:]); err != nil {
+		err = fmt.Errorf("%w: signature: %w", ErrMalformed, err)
+	}
+
+	return
+}
+
+// hashOf returns the hash of the algorithm, i.e. SHA-256 for RS256.
+func hashOf(alg string) (crypto.Hash, bool) {
+	if len(alg) != 5 {
+		return 0, false
+	}
+
+	switch alg[2:] {
+	case "256":
+		return crypto.SHA256, true
+	case "384":
+		return crypto.SHA384, true
+	case "512":
+		return crypto.SHA512, true
+	default:
+		return 0, false
+	}
+}
+
+// verifySignature verifies the signature of the signing input using the key,
+// the key type must match the algorithm so a public key can't be used as an HMAC secret.
+func verifySignature(alg string, key interface{}, signingInput string, sig []byte) error {
+	if alg == "EdDSA" {
+		k, ok := key.(ed25519.PublicKey)
+		if !ok {
+			return ErrKeyType
+		}
+
+		if !ed25519.Verify(k, []byte(signingInput), sig) {
+			return ErrSignature
+		}
+
+		return nil
+	}
+
+	hash, ok := hashOf(alg)
+	if !ok {
+		return ErrAlgorithm
+	}
+
+	switch alg[:2] {
+	case "HS":
+		k, ok := key.([]byte)
+		if !ok {
+			return ErrKeyType
+		}
+
+		mac := hmac.New(hash.New, k)
+		mac.Write([]byte(signingInput))
+		if !hmac.Equal(mac.Sum(nil), sig) {
+			return ErrSignature
+		}
+
+		return nil
+	}
+
+	h := hash.New()
+	h.Write([]byte(signingInput))
+	digest := h.Sum(nil)
+	switch alg[:2] {
+	case "RS", "PS":
+		k, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return ErrKeyType
+		}
+
+		var err error
+		if alg[0] == 'R' {
+			err = rsa.VerifyPKCS1v15(k, hash, digest, sig)
+		} else {
+			err = rsa.VerifyPSS(k, hash, digest, sig, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+		}
+
+		if err != nil {
+			return ErrSignature
+		}
+	case "ES":
+		k, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return ErrKeyType
+		}
+
+		size := (k.Curve.Params().BitSize + 7) / 8
+		if len(sig) != 2*size {
+			return ErrSignature
+		}
+
+		r := new(big.Int).SetBytes(sig[:size])
+		s := new(big.Int).SetBytes(sig[size:])
+		if !ecdsa.Verify(k, digest, r, s) {
+			return ErrSignature
+		}
+	default:
+		return ErrAlgorithm
+	}
+
+	return nil
+}
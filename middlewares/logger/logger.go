@@ -0,0 +1,123 @@
+// Package logger provides structured request logging middleware writing to a pluggable Sink,
+// such as log/slog using SlogSink or zerolog using a SinkFunc.
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"time"
+
+	"github.com/pchchv/feather"
+)
+
+// Entry describes a completed request.
+type Entry struct {
+	Method    string
+	Path      string
+	Route     string
+	Status    int
+	Bytes     int64
+	Latency   time.Duration
+	ClientIP  string
+	RequestID string
+}
+
+// Sink writes the log entries.
+type Sink interface {
+	Log(r *http.Request, e Entry)
+}
+
+// SinkFunc is an adapter allowing the use of an ordinary function as a Sink, i.e. for zerolog
+//
+//	logger.SinkFunc(func(r *http.Request, e logger.Entry) {
+//		log.Info().Str("method", e.Method).Int("status", e.Status).Dur("latency", e.Latency).Msg("request")
+//	})
+type SinkFunc func(r *http.Request, e Entry)
+
+// Log calls f(r, e).
+func (f SinkFunc) Log(r *http.Request, e Entry) {
+	f(r, e)
+}
+
+// SlogSink returns a Sink logging to l, 4xx responses are logged at the warn level and 5xx at the error level.
+func SlogSink(l *slog.Logger) Sink {
+	return SinkFunc(func(r *http.Request, e Entry) {
+		level := slog.LevelInfo
+		switch {
+		case e.Status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case e.Status >= http.StatusBadRequest:
+			level = slog.LevelWarn
+		}
+
+		attrs := make([]slog.Attr, 0, 8)
+		attrs = append(attrs,
+			slog.String("method", e.Method),
+			slog.String("path", e.Path),
+			slog.String("route", e.Route),
+			slog.Int("status", e.Status),
+			slog.Int64("bytes", e.Bytes),
+			slog.Duration("latency", e.Latency),
+			slog.String("client_ip", e.ClientIP),
+		)
+		if e.RequestID != "" {
+			attrs = append(attrs, slog.String("request_id", e.RequestID))
+		}
+
+		l.LogAttrs(context.WithoutCancel(r.Context()), level, "request", attrs...)
+	})
+}
+
+// Config contains the logger settings.
+type Config struct {
+	// Sink the entries are written to, by default SlogSink(slog.Default()).
+	Sink Sink
+	// SampleRate is the fraction of successful requests logged between 0 and 1,
+	// responses with a status of 400 or more are always logged. 0 logs all requests.
+	SampleRate float64
+	// Skip excludes requests from logging, i.e. health checks, optional.
+	Skip func(r *http.Request) bool
+}
+
+// New returns a middleware logging every completed request.
+// It should be registered first using Use, so the latency includes the other middleware.
+func New(cfg Config) feather.Middleware {
+	if cfg.Sink == nil {
+		cfg.Sink = SlogSink(slog.Default())
+	}
+
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if cfg.Skip != nil && cfg.Skip(r) {
+				next(w, r)
+				return
+			}
+
+			start := time.Now()
+			w, rt := feather.TrackResponse(w)
+			next(w, r)
+
+			status, bytes := rt.Status(), rt.Size()
+			if status == 0 {
+				status = http.StatusOK
+			}
+
+			if cfg.SampleRate > 0 && status < http.StatusBadRequest && rand.Float64() >= cfg.SampleRate {
+				return
+			}
+
+			cfg.Sink.Log(r, Entry{
+				Method:    r.Method,
+				Path:      r.URL.Path,
+				Route:     feather.RequestVars(r).Route(),
+				Status:    status,
+				Bytes:     bytes,
+				Latency:   time.Since(start),
+				ClientIP:  feather.ClientIP(r),
+				RequestID: feather.RequestID(r),
+			})
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package logger
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/pchchv/feather"
+	. "github.com/pchchv/feather/assert"
+	"github.com/pchchv/feather/middlewares/requestid"
+)
+
+func serve(p *feather.Mux, method string, path string) *httptest.ResponseRecorder {
+	r, _ := http.NewRequest(method, path, nil)
+	r.RemoteAddr = "10.0.0.1:1234"
+	w := httptest.NewRecorder()
+	p.Serve().ServeHTTP(w, r)
+	return w
+}
+
+func TestLogger(t *testing.T) {
+	var entries []Entry
+	p := feather.New()
+	p.Use(New(Config{
+		Sink: SinkFunc(func(r *http.Request, e Entry) {
+			entries = append(entries, e)
+		}),
+		Skip: func(r *http.Request) bool {
+			return r.URL.Path == "/health"
+		},
+	}), requestid.New(requestid.Config{Generator: func() string { return "id" }}))
+	p.Get("/users/:id", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("user"))
+	})
+	p.Get("/health", func(w http.ResponseWriter, r *http.Request) {})
+	p.Post("/users", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	serve(p, http.MethodGet, "/users/13")
+	serve(p, http.MethodGet, "/health")
+	serve(p, http.MethodPost, "/users")
+	Equal(t, len(entries), 2)
+
+	e := entries[0]
+	Equal(t, e.Method, http.MethodGet)
+	Equal(t, e.Path, "/users/13")
+	Equal(t, e.Route, "/users/:id")
+	Equal(t, e.Status, http.StatusOK)
+	Equal(t, e.Bytes, int64(4))
+	Equal(t, e.ClientIP, "10.0.0.1")
+	Equal(t, e.RequestID, "id")
+	Equal(t, e.Latency > 0, true)
+
+	Equal(t, entries[1].Status, http.StatusCreated)
+	Equal(t, entries[1].Bytes, int64(0))
+}
+
+func TestLoggerSampling(t *testing.T) {
+	var n int
+	p := feather.New()
+	p.Use(New(Config{
+		Sink:       SinkFunc(func(r *http.Request, e Entry) { n++ }),
+		SampleRate: 0.000001,
+	}))
+	p.Get("/ok", func(w http.ResponseWriter, r *http.Request) {})
+	p.Get("/fail", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	for i := 0; i < 100; i++ {
+		serve(p, http.MethodGet, "/ok")
+	}
+	Equal(t, n < 5, true)
+
+	n = 0
+	for i := 0; i < 100; i++ {
+		serve(p, http.MethodGet, "/fail")
+	}
+	Equal(t, n, 100)
+}
+
+func TestSlogSink(t *testing.T) {
+	var buf bytes.Buffer
+	l := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey || a.Key == "latency" {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+
+	p := feather.New()
+	p.Use(New(Config{Sink: SlogSink(l)}))
+	p.Get("/users/:id", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "gone", http.StatusNotFound)
+	})
+
+	serve(p, http.MethodGet, "/users/13")
+	Equal(t, strings.TrimSpace(buf.String()), `level=WARN msg=request method=GET path=/users/13 route=/users/:id status=404 bytes=5 client_ip=10.0.0.1`)
+}
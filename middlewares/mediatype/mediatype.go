@@ -0,0 +1,84 @@
+// Package mediatype provides middleware enforcing the media types routes declare using feather.Meta,
+// answering requests with unsupported bodies with 415 Unsupported Media Type
+// and requests not accepting any of the produced media types with 406 Not Acceptable.
+package mediatype
+
+import (
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/pchchv/feather"
+)
+
+// Config contains the media type enforcement settings.
+type Config struct {
+	// UnsupportedMediaType responds to requests whose body has none of the consumed media types,
+	// by default 415 Unsupported Media Type.
+	UnsupportedMediaType http.HandlerFunc
+	// NotAcceptable responds to requests accepting none of the produced media types,
+	// by default 406 Not Acceptable.
+	NotAcceptable http.HandlerFunc
+}
+
+// New returns a middleware enforcing the Consumes and Produces media types of the matched route,
+// see feather.RouteMeta. Consumed media types may use wildcards, i.e. image/*.
+// Requests without a body or without an Accept header are not checked respectively,
+// neither are routes without declared media types.
+func New(cfg Config) feather.Middleware {
+	if cfg.UnsupportedMediaType == nil {
+		cfg.UnsupportedMediaType = func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, http.StatusText(http.StatusUnsupportedMediaType), http.StatusUnsupportedMediaType)
+		}
+	}
+
+	if cfg.NotAcceptable == nil {
+		cfg.NotAcceptable = func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, http.StatusText(http.StatusNotAcceptable), http.StatusNotAcceptable)
+		}
+	}
+
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			meta := feather.RouteMeta(r)
+			if len(meta.Consumes) > 0 && hasBody(r) && !consumes(meta.Consumes, r.Header.Get("Content-Type")) {
+				w.Header().Set("Accept", strings.Join(meta.Consumes, ", "))
+				cfg.UnsupportedMediaType(w, r)
+				return
+			}
+
+			if len(meta.Produces) > 0 {
+				feather.AddVary(w, "Accept")
+				if feather.NegotiateContentType(r, meta.Produces...) == "" {
+					cfg.NotAcceptable(w, r)
+					return
+				}
+			}
+
+			next(w, r)
+		}
+	}
+}
+
+// hasBody reports whether the request has a body or declares its media type.
+func hasBody(r *http.Request) bool {
+	return r.ContentLength > 0 || len(r.TransferEncoding) > 0 || r.Header.Get("Content-Type") != ""
+}
+
+// consumes reports whether the media type of contentType matches one of the consumed media types.
+func consumes(mediaTypes []string, contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	typ, subtype, _ := strings.Cut(mediaType, "/")
+	for _, mt := range mediaTypes {
+		t, s, _ := strings.Cut(strings.ToLower(mt), "/")
+		if (t == "*" || t == typ) && (s == "*" || s == subtype) {
+			return true
+		}
+	}
+
+	return false
+}
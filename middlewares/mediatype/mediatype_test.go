@@ -0,0 +1,83 @@
+package mediatype
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/pchchv/feather"
+	. "github.com/pchchv/feather/assert"
+)
+
+func TestMediaType(t *testing.T) {
+	p := feather.New()
+	p.Use(New(Config{}))
+	api := p.Group("/api").WithMeta(feather.Meta{
+		Consumes: []string{"application/json", "image/*"},
+		Produces: []string{"application/json"},
+	})
+	api.Post("/users", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	})
+	p.Post("/plain", func(w http.ResponseWriter, r *http.Request) {})
+
+	do := func(path string, contentType string, accept string, body string) *httptest.ResponseRecorder {
+		r, _ := http.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		if contentType != "" {
+			r.Header.Set("Content-Type", contentType)
+		}
+		if accept != "" {
+			r.Header.Set("Accept", accept)
+		}
+
+		w := httptest.NewRecorder()
+		p.Serve().ServeHTTP(w, r)
+		return w
+	}
+
+	w := do("/api/users", "application/json; charset=utf-8", "application/json", "{}")
+	Equal(t, w.Code, http.StatusCreated)
+	Equal(t, w.Header().Get("Vary"), "Accept")
+
+	w = do("/api/users", "image/png", "", "png")
+	Equal(t, w.Code, http.StatusCreated)
+
+	w = do("/api/users", "", "", "")
+	Equal(t, w.Code, http.StatusCreated)
+
+	w = do("/api/users", "application/xml", "", "<user/>")
+	Equal(t, w.Code, http.StatusUnsupportedMediaType)
+	Equal(t, w.Header().Get("Accept"), "application/json, image/*")
+
+	w = do("/api/users", "", "", "no content type")
+	Equal(t, w.Code, http.StatusUnsupportedMediaType)
+
+	w = do("/api/users", "application/json", "text/html", "{}")
+	Equal(t, w.Code, http.StatusNotAcceptable)
+
+	w = do("/plain", "application/xml", "text/html", "<user/>")
+	Equal(t, w.Code, http.StatusOK)
+
+	routes := p.Routes()
+	Equal(t, routes[0].Path, "/api/users")
+	Equal(t, routes[0].Consumes, []string{"application/json", "image/*"})
+	Equal(t, routes[0].Produces, []string{"application/json"})
+}
+
+func TestMediaTypeHandlers(t *testing.T) {
+	p := feather.New()
+	p.Use(New(Config{
+		UnsupportedMediaType: func(w http.ResponseWriter, r *http.Request) {
+			_ = feather.JSON(w, http.StatusUnsupportedMediaType, map[string]string{"error": "unsupported"})
+		},
+	}))
+	p.WithMeta(feather.Meta{Consumes: []string{"application/json"}}).Post("/", func(w http.ResponseWriter, r *http.Request) {})
+
+	r, _ := http.NewRequest(http.MethodPost, "/", strings.NewReader("a=1"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	p.Serve().ServeHTTP(w, r)
+	Equal(t, w.Code, http.StatusUnsupportedMediaType)
+	Equal(t, w.Body.String(), `{"error":"unsupported"}`)
+}
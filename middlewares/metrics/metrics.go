@@ -0,0 +1,176 @@
+// Package metrics provides middleware counting the requests by error class, distinguishing client errors,
+// server errors, timeouts, cancellations by the client and panics, so dashboards tell bad clients from outages.
+package metrics
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/pchchv/feather"
+)
+
+// Class is the error class of a request.
+type Class string
+
+// Error classes.
+const (
+	// ClassNone are the requests without errors.
+	ClassNone Class = ""
+	// ClassClient are the requests answered with 4xx, the client sent a bad request.
+	ClassClient Class = "client"
+	// ClassServer are the requests answered with 5xx other than timeouts.
+	ClassServer Class = "server"
+	// ClassTimeout are the requests whose deadline was exceeded, or answered with 504 Gateway Timeout.
+	ClassTimeout Class = "timeout"
+	// ClassCanceled are the requests whose context was canceled or failing with context.Canceled,
+	// i.e. calls canceled as the client went away.
+	ClassCanceled Class = "canceled"
+	// ClassPanic are the requests whose handler panicked.
+	ClassPanic Class = "panic"
+)
+
+// Stats contains the request counters.
+type Stats struct {
+	Requests      uint64 `json:"requests"`
+	ClientErrors  uint64 `json:"client_errors"`
+	ServerErrors  uint64 `json:"server_errors"`
+	Timeouts      uint64 `json:"timeouts"`
+	Cancellations uint64 `json:"cancellations"`
+	Panics        uint64 `json:"panics"`
+}
+
+// Counters counts the requests by error class, it is safe for concurrent use.
+type Counters struct {
+	requests, client, server, timeouts, canceled, panics atomic.Uint64
+}
+
+// Add counts a request of the class.
+func (c *Counters) Add(class Class) {
+	c.requests.Add(1)
+	switch class {
+	case ClassClient:
+		c.client.Add(1)
+	case ClassServer:
+		c.server.Add(1)
+	case ClassTimeout:
+		c.timeouts.Add(1)
+	case ClassCanceled:
+		c.canceled.Add(1)
+	case ClassPanic:
+		c.panics.Add(1)
+	}
+}
+
+// Stats returns a snapshot of the counters.
+func (c *Counters) Stats() Stats {
+	return Stats{
+		Requests:      c.requests.Load(),
+		ClientErrors:  c.client.Load(),
+		ServerErrors:  c.server.Load(),
+		Timeouts:      c.timeouts.Load(),
+		Cancellations: c.canceled.Load(),
+		Panics:        c.panics.Load(),
+	}
+}
+
+// Publish publishes the counters as an expvar under name, it panics if the name is already registered.
+func (c *Counters) Publish(name string) {
+	expvar.Publish(name, expvar.Func(func() any {
+		return c.Stats()
+	}))
+}
+
+// Config contains the metrics settings.
+type Config struct {
+	// Counters counts the requests, a new Counters by default.
+	Counters *Counters
+	// Observe is called with the class and status of every completed request, i.e. to increment
+	// the counters of a metrics library labeled by route, optional.
+	// The status of panicking requests is 0.
+	Observe func(r *http.Request, class Class, status int)
+}
+
+// New returns a middleware counting the requests by error class, which is derived from the errors passed to
+// the error handler, see feather.HandledError, the request context and the response status.
+// It should be registered after the recovery middleware, so panics reach it.
+func New(cfg Config) feather.Middleware {
+	if cfg.Counters == nil {
+		cfg.Counters = new(Counters)
+	}
+
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			defer func() {
+				if v := recover(); v != nil {
+					if err, ok := v.(error); !ok || !errors.Is(err, http.ErrAbortHandler) {
+						cfg.Counters.Add(ClassPanic)
+						if cfg.Observe != nil {
+							cfg.Observe(r, ClassPanic, 0)
+						}
+					}
+					panic(v)
+				}
+			}()
+
+			w, rt := feather.TrackResponse(w)
+			next(w, r)
+
+			status := rt.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+
+			class := Classify(r, status)
+			cfg.Counters.Add(class)
+			if cfg.Observe != nil {
+				cfg.Observe(r, class, status)
+			}
+		}
+	}
+}
+
+// Classify returns the error class of the completed request answered with status.
+// The errors passed to the error handler take precedence, then the state of the request context
+// and finally the status.
+func Classify(r *http.Request, status int) Class {
+	err := feather.HandledError(r)
+	var pe *feather.PanicError
+	switch {
+	case errors.As(err, &pe):
+		// recovered by feather.HandleErrors
+		return ClassPanic
+	case errors.Is(err, context.DeadlineExceeded) || errors.Is(err, http.ErrHandlerTimeout):
+		return ClassTimeout
+	case errors.Is(err, context.Canceled):
+		return ClassCanceled
+	}
+
+	switch r.Context().Err() {
+	case context.DeadlineExceeded:
+		return ClassTimeout
+	case context.Canceled:
+		return ClassCanceled
+	}
+
+	var he *feather.HTTPError
+	if errors.As(err, &he) && he.Code > 0 {
+		status = he.Code
+	} else if err != nil && status < http.StatusBadRequest {
+		// the error was handled after the response started
+		status = http.StatusInternalServerError
+	}
+
+	switch {
+	case status == http.StatusGatewayTimeout:
+		return ClassTimeout
+	case status >= http.StatusInternalServerError:
+		return ClassServer
+	case status >= http.StatusBadRequest:
+		return ClassClient
+	}
+
+	return ClassNone
+}
@@ -0,0 +1,95 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pchchv/feather"
+	. "github.com/pchchv/feather/assert"
+)
+
+func TestMetrics(t *testing.T) {
+	counters := new(Counters)
+	var classes []Class
+	p := feather.New()
+	p.SetLogger(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	p.Use(New(Config{Counters: counters, Observe: func(r *http.Request, class Class, status int) {
+		classes = append(classes, class)
+	}}))
+	p.Get("/ok", func(w http.ResponseWriter, r *http.Request) {})
+	p.Get("/bad", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	})
+	p.Get("/conflict", feather.HandleErrors(func(w http.ResponseWriter, r *http.Request) error {
+		return feather.NewHTTPError(http.StatusConflict, "")
+	}))
+	p.Get("/db", feather.HandleErrors(func(w http.ResponseWriter, r *http.Request) error {
+		return errors.New("db down")
+	}))
+	p.Get("/slow", feather.HandleErrors(func(w http.ResponseWriter, r *http.Request) error {
+		return fmt.Errorf("query: %w", context.DeadlineExceeded)
+	}))
+	p.Get("/gateway", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusGatewayTimeout)
+	})
+	p.Get("/gone", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	p.Get("/canceled", feather.HandleErrors(func(w http.ResponseWriter, r *http.Request) error {
+		return fmt.Errorf("upstream: %w", context.Canceled)
+	}))
+	p.Group("").WithMeta(feather.Meta{Timeout: time.Nanosecond}).Get("/deadline", func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	})
+	p.Get("/panic", func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+	p.Get("/panic-error", feather.HandleErrors(func(w http.ResponseWriter, r *http.Request) error {
+		panic(errors.New("boom"))
+	}))
+	p.Get("/panic-bug", feather.HandleErrors(func(w http.ResponseWriter, r *http.Request) error {
+		var m map[string]int
+		m["boom"]++
+		return nil
+	}))
+	hf := p.Serve()
+
+	for _, path := range []string{"/ok", "/bad", "/conflict", "/db", "/slow", "/gateway", "/gone"} {
+		hf.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	hf.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/canceled", nil))
+	hf.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/deadline", nil))
+
+	// the client went away
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	hf.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/ok", nil).WithContext(ctx))
+
+	PanicsWithValue(t, func() {
+		hf.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/panic", nil))
+	}, "boom")
+
+	// panics recovered by HandleErrors and the runtime errors it re-panics
+	hf.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/panic-error", nil))
+	PanicMatches(t, func() {
+		hf.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/panic-bug", nil))
+	}, "assignment to entry in nil map")
+
+	Equal(t, classes, []Class{ClassNone, ClassClient, ClassClient, ClassServer, ClassTimeout, ClassTimeout, ClassServer, ClassCanceled, ClassTimeout, ClassCanceled, ClassPanic, ClassPanic, ClassPanic})
+	Equal(t, counters.Stats(), Stats{
+		Requests:      13,
+		ClientErrors:  2,
+		ServerErrors:  2,
+		Timeouts:      3,
+		Cancellations: 2,
+		Panics:        3,
+	})
+}
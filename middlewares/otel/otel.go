@@ -0,0 +1,216 @@
+// Package otel provides tracing middleware starting a server span per request named by the matched route,
+// following the OpenTelemetry HTTP semantic conventions. It depends on small interfaces instead of
+// the OpenTelemetry SDK, adapting an OpenTelemetry tracer takes a few lines:
+//
+//	type tracer struct{ trace.Tracer }
+//
+//	func (t tracer) Start(ctx context.Context, name string, attrs ...otel.Attribute) (context.Context, otel.Span) {
+//		ctx, span := t.Tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(convert(attrs)...))
+//		return ctx, spanAdapter{span}
+//	}
+package otel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/pchchv/feather"
+)
+
+// SpanKey is the key of the span of the request in the request variables.
+const SpanKey = "otel.span"
+
+// Attribute semantic convention keys.
+const (
+	AttrHTTPMethod       = "http.request.method"
+	AttrHTTPRoute        = "http.route"
+	AttrHTTPStatusCode   = "http.response.status_code"
+	AttrHTTPResponseSize = "http.response.body.size"
+	AttrURLPath          = "url.path"
+	AttrURLScheme        = "url.scheme"
+	AttrServerAddress    = "server.address"
+	AttrClientAddress    = "client.address"
+	AttrUserAgent        = "user_agent.original"
+)
+
+// Code is the status of a span.
+type Code uint8
+
+// Span status codes, in the order of the OpenTelemetry codes.
+const (
+	// StatusUnset is the default status.
+	StatusUnset Code = iota
+	// StatusError marks the operation as failed.
+	StatusError
+	// StatusOK marks the operation as successful, it is never set by the middleware.
+	StatusOK
+)
+
+// Attribute is a key value pair describing a span, the values are strings, ints or int64s.
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// String returns a string attribute.
+func String(key string, value string) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Int returns an int attribute.
+func Int(key string, value int) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Span is a traced operation.
+type Span interface {
+	SetAttributes(attrs ...Attribute)
+	RecordError(err error)
+	SetStatus(code Code, description string)
+	End()
+}
+
+// Tracer starts spans.
+type Tracer interface {
+	// Start starts a server span named name, a child of the span context of ctx if any,
+	// and returns the context carrying the span.
+	Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span)
+}
+
+// Propagator extracts the trace context of incoming requests.
+type Propagator interface {
+	Extract(ctx context.Context, h http.Header) context.Context
+}
+
+// PropagatorFunc adapts a function to a Propagator, i.e. an OpenTelemetry propagator:
+//
+//	otel.PropagatorFunc(func(ctx context.Context, h http.Header) context.Context {
+//		return propagator.Extract(ctx, propagation.HeaderCarrier(h))
+//	})
+type PropagatorFunc func(ctx context.Context, h http.Header) context.Context
+
+// Extract calls f(ctx, h).
+func (f PropagatorFunc) Extract(ctx context.Context, h http.Header) context.Context {
+	return f(ctx, h)
+}
+
+// Config contains the tracing settings.
+type Config struct {
+	// Tracer starts the spans, required.
+	Tracer Tracer
+	// Propagator extracts the trace context of the requests, TraceContext by default.
+	Propagator Propagator
+	// SpanName returns the name of the span, by default the method followed by the route,
+	// or the method alone for requests not matching a route.
+	SpanName func(r *http.Request, route string) string
+	// Skip returns true for requests not traced, i.e. health checks, optional.
+	Skip func(r *http.Request) bool
+}
+
+// New returns a middleware tracing every request. The span is injected into the request context,
+// and stored in the request variables, see FromRequest.
+// Responses with a 5xx status and panics set the Error status, the errors passed to the error handler
+// and panics are recorded, see feather.HandledError. It panics if Tracer is nil.
+func New(cfg Config) feather.Middleware {
+	if cfg.Tracer == nil {
+		panic("otel: Tracer is required")
+	}
+
+	if cfg.Propagator == nil {
+		cfg.Propagator = TraceContext{}
+	}
+
+	if cfg.SpanName == nil {
+		cfg.SpanName = spanName
+	}
+
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if cfg.Skip != nil && cfg.Skip(r) {
+				next(w, r)
+				return
+			}
+
+			route := feather.RequestVars(r).Route()
+			attrs := []Attribute{
+				String(AttrHTTPMethod, r.Method),
+				String(AttrURLPath, r.URL.Path),
+				String(AttrURLScheme, scheme(r)),
+				String(AttrServerAddress, r.Host),
+				String(AttrClientAddress, feather.ClientIP(r)),
+			}
+			if route != "" {
+				attrs = append(attrs, String(AttrHTTPRoute, route))
+			}
+			if ua := r.UserAgent(); ua != "" {
+				attrs = append(attrs, String(AttrUserAgent, ua))
+			}
+
+			ctx := cfg.Propagator.Extract(r.Context(), r.Header)
+			ctx, span := cfg.Tracer.Start(ctx, cfg.SpanName(r, route), attrs...)
+			r = r.WithContext(ctx)
+			feather.RequestVars(r).Set(SpanKey, span)
+
+			var status int
+			var bytes int64
+			defer func() {
+				if v := recover(); v != nil {
+					err, ok := v.(error)
+					if !ok {
+						err = fmt.Errorf("panic: %v", v)
+					}
+
+					if !errors.Is(err, http.ErrAbortHandler) {
+						span.RecordError(err)
+						span.SetStatus(StatusError, err.Error())
+					}
+					span.End()
+					panic(v)
+				}
+
+				if err := feather.HandledError(r); err != nil {
+					span.RecordError(err)
+				}
+
+				if status == 0 {
+					status = http.StatusOK
+				}
+
+				span.SetAttributes(Int(AttrHTTPStatusCode, status), Attribute{Key: AttrHTTPResponseSize, Value: bytes})
+				if status >= http.StatusInternalServerError {
+					span.SetStatus(StatusError, http.StatusText(status))
+				}
+				span.End()
+			}()
+
+			w, rt := feather.TrackResponse(w)
+			next(w, r)
+			status, bytes = rt.Status(), rt.Size()
+		}
+	}
+}
+
+// FromRequest returns the span of the request started by the middleware, nil if none.
+func FromRequest(r *http.Request) Span {
+	span, _ := feather.RequestVars(r).Get(SpanKey).(Span)
+	return span
+}
+
+// spanName returns the method followed by the route, or the method alone if none.
+func spanName(r *http.Request, route string) string {
+	if route == "" {
+		return r.Method
+	}
+
+	return r.Method + " " + route
+}
+
+func scheme(r *http.Request) string {
+	if r.TLS != nil {
+		return "https"
+	}
+
+	return "http"
+}
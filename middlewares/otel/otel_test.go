@@ -0,0 +1,154 @@
+package otel
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pchchv/feather"
+	. "github.com/pchchv/feather/assert"
+)
+
+type span struct {
+	name   string
+	parent SpanContext
+	remote bool
+	attrs  map[string]interface{}
+	errs   []error
+	code   Code
+	desc   string
+	ended  bool
+}
+
+func (s *span) SetAttributes(attrs ...Attribute) {
+	for _, a := range attrs {
+		s.attrs[a.Key] = a.Value
+	}
+}
+
+func (s *span) RecordError(err error) { s.errs = append(s.errs, err) }
+
+func (s *span) SetStatus(code Code, desc string) { s.code, s.desc = code, desc }
+
+func (s *span) End() { s.ended = true }
+
+type spanCtxKey struct{}
+
+type tracer struct {
+	spans []*span
+}
+
+func (t *tracer) Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span) {
+	s := &span{name: name, attrs: make(map[string]interface{})}
+	s.parent, s.remote = FromContext(ctx)
+	s.SetAttributes(attrs...)
+	t.spans = append(t.spans, s)
+	return context.WithValue(ctx, spanCtxKey{}, s), s
+}
+
+func TestTracing(t *testing.T) {
+	tr := new(tracer)
+	var fromCtx, fromVars Span
+	errConflict := feather.NewHTTPError(http.StatusConflict, "")
+
+	p := feather.New()
+	p.Use(New(Config{Tracer: tr, Skip: func(r *http.Request) bool { return r.URL.Path == "/health" }}))
+	p.Get("/users/:id", func(w http.ResponseWriter, r *http.Request) {
+		fromCtx, _ = r.Context().Value(spanCtxKey{}).(Span)
+		fromVars = FromRequest(r)
+		_, _ = w.Write([]byte("user"))
+	})
+	p.Get("/conflict", feather.HandleErrors(func(w http.ResponseWriter, r *http.Request) error {
+		return errConflict
+	}))
+	p.Get("/fail", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	p.Get("/panic", func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+	p.Get("/health", func(w http.ResponseWriter, r *http.Request) {})
+	hf := p.Serve()
+
+	r := httptest.NewRequest(http.MethodGet, "/users/13", nil)
+	r.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	r.Header.Set("tracestate", "congo=t61rcWkgMzE")
+	r.Header.Set("User-Agent", "test")
+	hf.ServeHTTP(httptest.NewRecorder(), r)
+	Equal(t, len(tr.spans), 1)
+	s := tr.spans[0]
+	Equal(t, s.name, "GET /users/:id")
+	Equal(t, s.remote, true)
+	Equal(t, s.parent.TraceID, "4bf92f3577b34da6a3ce929d0e0e4736")
+	Equal(t, s.parent.SpanID, "00f067aa0ba902b7")
+	Equal(t, s.parent.Sampled(), true)
+	Equal(t, s.parent.TraceState, "congo=t61rcWkgMzE")
+	Equal(t, s.attrs[AttrHTTPRoute], "/users/:id")
+	Equal(t, s.attrs[AttrHTTPMethod], http.MethodGet)
+	Equal(t, s.attrs[AttrURLPath], "/users/13")
+	Equal(t, s.attrs[AttrUserAgent], "test")
+	Equal(t, s.attrs[AttrHTTPStatusCode], http.StatusOK)
+	Equal(t, s.attrs[AttrHTTPResponseSize], int64(4))
+	Equal(t, s.code, StatusUnset)
+	Equal(t, s.ended, true)
+	Equal(t, fromCtx == Span(s), true)
+	Equal(t, fromVars == Span(s), true)
+
+	hf.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/conflict", nil))
+	s = tr.spans[1]
+	Equal(t, s.remote, false)
+	Equal(t, s.attrs[AttrHTTPStatusCode], http.StatusConflict)
+	Equal(t, len(s.errs), 1)
+	Equal(t, errors.Is(s.errs[0], errConflict), true)
+	Equal(t, s.code, StatusUnset)
+
+	hf.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/fail", nil))
+	s = tr.spans[2]
+	Equal(t, s.code, StatusError)
+	Equal(t, s.desc, "Service Unavailable")
+
+	PanicsWithValue(t, func() {
+		hf.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/panic", nil))
+	}, "boom")
+	s = tr.spans[3]
+	Equal(t, s.code, StatusError)
+	Equal(t, s.desc, "panic: boom")
+	Equal(t, s.ended, true)
+
+	hf.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/health", nil))
+	Equal(t, len(tr.spans), 4)
+
+	// i.e. the 404 handler, when registered with the middleware
+	Equal(t, spanName(httptest.NewRequest(http.MethodGet, "/missing", nil), ""), http.MethodGet)
+
+	PanicMatches(t, func() { New(Config{}) }, "otel: Tracer is required")
+}
+
+func TestTraceContext(t *testing.T) {
+	tests := []struct {
+		header string
+		ok     bool
+	}{
+		{header: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", ok: true},
+		{header: "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-future", ok: true},
+		{header: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", ok: false},
+		{header: "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", ok: false},
+		{header: "00-00000000000000000000000000000000-00f067aa0ba902b7-01", ok: false},
+		{header: "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", ok: false},
+		{header: "00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01", ok: false},
+		{header: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7", ok: false},
+		{header: "", ok: false},
+	}
+
+	for _, tt := range tests {
+		h := make(http.Header)
+		h.Set(traceparentHeader, tt.header)
+		sc, ok := FromContext(TraceContext{}.Extract(context.Background(), h))
+		Equal(t, ok, tt.ok)
+		if ok {
+			Equal(t, sc.SpanID, "00f067aa0ba902b7")
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package otel
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+const (
+	traceparentHeader = "Traceparent"
+	tracestateHeader  = "Tracestate"
+	sampledFlag       = 0x01
+)
+
+// SpanContext is the trace context of the remote parent of a request, see FromContext.
+type SpanContext struct {
+	// TraceID is the 32 lowercase hex digits trace id.
+	TraceID string
+	// SpanID is the 16 lowercase hex digits id of the parent span.
+	SpanID string
+	// Flags are the trace flags, see Sampled.
+	Flags byte
+	// TraceState is the vendor specific tracestate header.
+	TraceState string
+}
+
+// Sampled reports whether the parent sampled the trace.
+func (sc SpanContext) Sampled() bool {
+	return sc.Flags&sampledFlag != 0
+}
+
+type spanContextKey struct{}
+
+// FromContext returns the span context extracted by TraceContext and whether there is one,
+// Tracer implementations use it to start the spans as children of the remote parent.
+func FromContext(ctx context.Context) (SpanContext, bool) {
+	sc, ok := ctx.Value(spanContextKey{}).(SpanContext)
+	return sc, ok
+}
+
+// TraceContext is the Propagator of the W3C Trace Context traceparent and tracestate headers.
+type TraceContext struct{}
+
+// Extract returns ctx carrying the span context of the traceparent header, ctx if missing or invalid.
+func (TraceContext) Extract(ctx context.Context, h http.Header) context.Context {
+	sc, ok := parseTraceparent(h.Get(traceparentHeader))
+	if !ok {
+		return ctx
+	}
+
+	sc.TraceState = strings.Join(h.Values(tracestateHeader), ",")
+	return context.WithValue(ctx, spanContextKey{}, sc)
+}
+
+// parseTraceparent parses the version-traceid-parentid-flags header,
+// later versions may append fields, which are ignored.
+func parseTraceparent(v string) (sc SpanContext, ok bool) {
+	v = strings.TrimSpace(v)
+	if len(v) < 55 || v[2] != '-' || v[35] != '-' || v[52] != '-' {
+		return
+	}
+
+	version := v[:2]
+	if !isHex(version) || version == "ff" || (version == "00" && len(v) != 55) || (len(v) > 55 && v[55] != '-') {
+		return
+	}
+
+	sc.TraceID, sc.SpanID = v[3:35], v[36:52]
+	flags := v[53:55]
+	if !isHex(sc.TraceID) || !isHex(sc.SpanID) || !isHex(flags) ||
+		strings.Trim(sc.TraceID, "0") == "" || strings.Trim(sc.SpanID, "0") == "" {
+		return SpanContext{}, false
+	}
+
+	sc.Flags = unhex(flags[0])<<4 | unhex(flags[1])
+	return sc, true
+}
+
+// isHex reports whether s consists of lowercase hex digits.
+func isHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f') {
+			return false
+		}
+	}
+
+	return true
+}
+
+func unhex(c byte) byte {
+	if c <= '9' {
+		return c - '0'
+	}
+
+	return c - 'a' + 10
+}
@@ -0,0 +1,92 @@
+package ratelimit
+
+import (
+	"context"
+	"hash/maphash"
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	shardCount = 64
+	// sweepInterval is the number of takes of a shard between removing its full buckets.
+	sweepInterval = 1024
+)
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	// full is when the bucket is full again, after which it can be removed
+	full time.Time
+}
+
+type shard struct {
+	m       sync.Mutex
+	buckets map[string]*bucket
+	takes   int
+}
+
+// MemoryStore keeps the token buckets in memory, sharded to reduce lock contention.
+// Full buckets are removed periodically since they are equivalent to a missing bucket.
+type MemoryStore struct {
+	seed   maphash.Seed
+	shards [shardCount]shard
+	now    func() time.Time
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	s := &MemoryStore{
+		seed: maphash.MakeSeed(),
+		now:  time.Now,
+	}
+	for i := range s.shards {
+		s.shards[i].buckets = make(map[string]*bucket)
+	}
+
+	return s
+}
+
+// Take takes a token from the bucket of key, the rate of the limit must be positive.
+func (s *MemoryStore) Take(_ context.Context, key string, limit Limit) (res Result, err error) {
+	now := s.now()
+	sh := &s.shards[maphash.String(s.seed, key)%shardCount]
+	sh.m.Lock()
+	defer sh.m.Unlock()
+
+	if sh.takes++; sh.takes >= sweepInterval {
+		sh.takes = 0
+		for k, b := range sh.buckets {
+			if !now.Before(b.full) {
+				delete(sh.buckets, k)
+			}
+		}
+	}
+
+	burst := float64(limit.Burst)
+	b, ok := sh.buckets[key]
+	if !ok {
+		b = &bucket{tokens: burst, last: now}
+		sh.buckets[key] = b
+	} else {
+		b.tokens = math.Min(burst, b.tokens+now.Sub(b.last).Seconds()*limit.Rate)
+		b.last = now
+	}
+
+	if b.tokens >= 1 {
+		b.tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = duration((1 - b.tokens) / limit.Rate)
+	}
+
+	res.Remaining = int(b.tokens)
+	res.Reset = duration((burst - b.tokens) / limit.Rate)
+	b.full = now.Add(res.Reset)
+	return
+}
+
+func duration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
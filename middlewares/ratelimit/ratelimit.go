@@ -0,0 +1,158 @@
+// Package ratelimit provides token bucket rate limiting middleware,
+// keyed by the client IP by default, with the buckets kept in a pluggable Store.
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/pchchv/feather"
+)
+
+const (
+	headerLimit           = "RateLimit-Limit"
+	headerRemaining       = "RateLimit-Remaining"
+	headerReset           = "RateLimit-Reset"
+	headerLegacyLimit     = "X-RateLimit-Limit"
+	headerLegacyRemaining = "X-RateLimit-Remaining"
+	headerLegacyReset     = "X-RateLimit-Reset"
+	headerRetryAfter      = "Retry-After"
+)
+
+// Headers selects the names of the rate limit headers sent with the responses.
+type Headers uint8
+
+const (
+	// DraftHeaders are the RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset headers
+	// of the IETF httpapi draft, the default.
+	DraftHeaders Headers = iota
+	// LegacyHeaders are the X-RateLimit-Limit, X-RateLimit-Remaining and X-RateLimit-Reset headers.
+	LegacyHeaders
+	// BothHeaders sends both the draft and the legacy headers, i.e. while clients migrate.
+	BothHeaders
+	// NoHeaders omits the rate limit headers, Retry-After is still sent with limited requests.
+	NoHeaders
+)
+
+// Limit is the token bucket configuration of a key.
+type Limit struct {
+	// Rate is the number of tokens added to the bucket per second.
+	Rate float64
+	// Burst is the capacity of the bucket, the number of requests allowed at once.
+	Burst int
+}
+
+// Result is the outcome of taking a token.
+type Result struct {
+	// Allowed reports whether a token was taken.
+	Allowed bool
+	// Remaining is the number of tokens left in the bucket.
+	Remaining int
+	// RetryAfter is the time until the next token is available when not allowed.
+	RetryAfter time.Duration
+	// Reset is the time until the bucket is full again.
+	Reset time.Duration
+}
+
+// Store keeps the token buckets, i.e. in memory using MemoryStore,
+// or in Redis to share the limits between instances, it must be safe for concurrent use.
+type Store interface {
+	// Take takes a token from the bucket of key, creating a full bucket if it doesn't exist.
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// Config contains the rate limit settings.
+type Config struct {
+	// Limit is the rate and burst of every key, the burst defaults to the rate rounded up, at least 1.
+	Limit Limit
+	// Key returns the key of the request the limit applies to, by default feather.ClientIP.
+	Key func(r *http.Request) string
+	// Store keeps the buckets, by default a MemoryStore.
+	Store Store
+	// LimitReached responds to limited requests, by default 429 Too Many Requests.
+	// The rate limit headers, including Retry-After, are set before it is called.
+	LimitReached http.HandlerFunc
+	// Skip excludes requests from rate limiting, i.e. health checks, optional.
+	Skip func(r *http.Request) bool
+	// Headers selects the rate limit headers, by default DraftHeaders.
+	Headers Headers
+}
+
+// New returns a middleware limiting the requests of every key using a token bucket.
+// Responses carry the RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset headers,
+// or their legacy X-RateLimit names, see Config.Headers, so clients can throttle themselves,
+// and limited requests also Retry-After, all in seconds.
+// Requests are allowed when the Store fails, the error is logged using feather.Logger.
+// It panics if the rate is not positive.
+func New(cfg Config) feather.Middleware {
+	if cfg.Limit.Rate <= 0 {
+		panic("ratelimit: the rate must be positive")
+	}
+
+	if cfg.Limit.Burst <= 0 {
+		cfg.Limit.Burst = max(1, int(math.Ceil(cfg.Limit.Rate)))
+	}
+
+	if cfg.Key == nil {
+		cfg.Key = feather.ClientIP
+	}
+
+	if cfg.Store == nil {
+		cfg.Store = NewMemoryStore()
+	}
+
+	if cfg.LimitReached == nil {
+		cfg.LimitReached = func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+		}
+	}
+
+	limit := strconv.Itoa(cfg.Limit.Burst)
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if cfg.Skip != nil && cfg.Skip(r) {
+				next(w, r)
+				return
+			}
+
+			res, err := cfg.Store.Take(r.Context(), cfg.Key(r), cfg.Limit)
+			if err != nil {
+				feather.Logger(r).Error("rate limit store failed", "error", err)
+				next(w, r)
+				return
+			}
+
+			h := w.Header()
+			if cfg.Headers != NoHeaders {
+				remaining, reset := strconv.Itoa(res.Remaining), seconds(res.Reset)
+				if cfg.Headers != LegacyHeaders {
+					h.Set(headerLimit, limit)
+					h.Set(headerRemaining, remaining)
+					h.Set(headerReset, reset)
+				}
+
+				if cfg.Headers != DraftHeaders {
+					h.Set(headerLegacyLimit, limit)
+					h.Set(headerLegacyRemaining, remaining)
+					h.Set(headerLegacyReset, reset)
+				}
+			}
+
+			if !res.Allowed {
+				h.Set(headerRetryAfter, seconds(res.RetryAfter))
+				cfg.LimitReached(w, r)
+				return
+			}
+
+			next(w, r)
+		}
+	}
+}
+
+// seconds formats d in whole seconds, rounded up.
+func seconds(d time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10)
+}
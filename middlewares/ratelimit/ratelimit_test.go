@@ -0,0 +1,156 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"hash/maphash"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/pchchv/feather"
+	. "github.com/pchchv/feather/assert"
+)
+
+type failingStore struct{}
+
+func (failingStore) Take(context.Context, string, Limit) (Result, error) {
+	return Result{}, errors.New("unavailable")
+}
+
+func serve(p *feather.Mux, remoteAddr string) *httptest.ResponseRecorder {
+	r, _ := http.NewRequest(http.MethodGet, "/", nil)
+	r.RemoteAddr = remoteAddr
+	w := httptest.NewRecorder()
+	p.Serve().ServeHTTP(w, r)
+	return w
+}
+
+func TestRateLimit(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+
+	p := feather.New()
+	p.Use(New(Config{Limit: Limit{Rate: 0.5, Burst: 2}, Store: store}))
+	p.Get("/", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	})
+
+	w := serve(p, "10.0.0.1:1234")
+	Equal(t, w.Code, http.StatusOK)
+	Equal(t, w.Header().Get("RateLimit-Limit"), "2")
+	Equal(t, w.Header().Get("RateLimit-Remaining"), "1")
+	Equal(t, w.Header().Get("RateLimit-Reset"), "2")
+
+	w = serve(p, "10.0.0.1:1234")
+	Equal(t, w.Code, http.StatusOK)
+	Equal(t, w.Header().Get("RateLimit-Remaining"), "0")
+	Equal(t, w.Header().Get("RateLimit-Reset"), "4")
+
+	w = serve(p, "10.0.0.1:1234")
+	Equal(t, w.Code, http.StatusTooManyRequests)
+	Equal(t, w.Header().Get("Retry-After"), "2")
+
+	// other clients have their own bucket
+	w = serve(p, "10.0.0.2:1234")
+	Equal(t, w.Code, http.StatusOK)
+
+	now = now.Add(2 * time.Second)
+	w = serve(p, "10.0.0.1:1234")
+	Equal(t, w.Code, http.StatusOK)
+	Equal(t, w.Header().Get("RateLimit-Remaining"), "0")
+}
+
+func TestRateLimitConfig(t *testing.T) {
+	p := feather.New()
+	p.Use(New(Config{
+		Limit: Limit{Rate: 1},
+		Key: func(r *http.Request) string {
+			return r.Header.Get("X-Api-Key")
+		},
+		LimitReached: func(w http.ResponseWriter, r *http.Request) {
+			_ = feather.JSON(w, http.StatusTooManyRequests, map[string]string{"error": "slow down"})
+		},
+		Skip: func(r *http.Request) bool {
+			return r.URL.Path == "/health"
+		},
+	}))
+	p.Get("/", func(w http.ResponseWriter, r *http.Request) {})
+	p.Get("/health", func(w http.ResponseWriter, r *http.Request) {})
+
+	Equal(t, serve(p, "10.0.0.1:1").Code, http.StatusOK)
+	w := serve(p, "10.0.0.2:1")
+	Equal(t, w.Code, http.StatusTooManyRequests)
+	Equal(t, w.Body.String(), `{"error":"slow down"}`)
+
+	r, _ := http.NewRequest(http.MethodGet, "/health", nil)
+	w = httptest.NewRecorder()
+	p.Serve().ServeHTTP(w, r)
+	Equal(t, w.Code, http.StatusOK)
+	Equal(t, w.Header().Get("RateLimit-Limit"), "")
+
+	// failing stores allow the requests
+	p = feather.New()
+	p.Use(New(Config{Limit: Limit{Rate: 1}, Store: failingStore{}}))
+	p.Get("/", func(w http.ResponseWriter, r *http.Request) {})
+	Equal(t, serve(p, "10.0.0.1:1").Code, http.StatusOK)
+
+	PanicsWithValue(t, func() { New(Config{}) }, "ratelimit: the rate must be positive")
+}
+
+func TestRateLimitHeaders(t *testing.T) {
+	tests := []struct {
+		headers Headers
+		draft   string
+		legacy  string
+	}{
+		{headers: DraftHeaders, draft: "1"},
+		{headers: LegacyHeaders, legacy: "1"},
+		{headers: BothHeaders, draft: "1", legacy: "1"},
+		{headers: NoHeaders},
+	}
+
+	for _, tt := range tests {
+		p := feather.New()
+		p.Use(New(Config{Limit: Limit{Rate: 1}, Headers: tt.headers}))
+		p.Get("/", func(w http.ResponseWriter, r *http.Request) {})
+
+		w := serve(p, "10.0.0.1:1")
+		Equal(t, w.Header().Get("RateLimit-Limit"), tt.draft)
+		Equal(t, w.Header().Get("X-RateLimit-Limit"), tt.legacy)
+
+		w = serve(p, "10.0.0.1:1")
+		Equal(t, w.Code, http.StatusTooManyRequests)
+		Equal(t, w.Header().Get("RateLimit-Remaining") != "", tt.draft != "")
+		Equal(t, w.Header().Get("X-RateLimit-Reset") != "", tt.legacy != "")
+		Equal(t, w.Header().Get("Retry-After"), "1")
+	}
+}
+
+func TestMemoryStoreSweep(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+	limit := Limit{Rate: 1, Burst: 1}
+
+	_, _ = store.Take(context.Background(), "a", limit)
+	sh := &store.shards[maphash.String(store.seed, "a")%shardCount]
+	Equal(t, len(sh.buckets), 1)
+
+	// find another key of the same shard
+	other := "b"
+	for i := 0; maphash.String(store.seed, other)%shardCount != maphash.String(store.seed, "a")%shardCount; i++ {
+		other = strconv.Itoa(i)
+	}
+
+	now = now.Add(time.Second)
+	sh.takes = sweepInterval - 1
+	_, _ = store.Take(context.Background(), other, limit)
+
+	_, ok := sh.buckets["a"]
+	Equal(t, ok, false)
+	Equal(t, len(sh.buckets), 1)
+}
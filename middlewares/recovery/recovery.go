@@ -0,0 +1,124 @@
+// Package recovery provides middleware recovering from panics in handlers,
+// logging them and responding with 500 Internal Server Error.
+package recovery
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"runtime"
+
+	"github.com/pchchv/feather"
+)
+
+const (
+	applicationJSON = "application/json"
+	textHTML        = "text/html"
+	maxStackSize    = 64 << 10
+)
+
+// Handler responds to a request whose handler panicked with err.
+// The stack is nil unless Config.Stack is set.
+type Handler func(w http.ResponseWriter, r *http.Request, err interface{}, stack []byte)
+
+// Config contains the recovery settings.
+type Config struct {
+	// Handler responds to the panicking requests, by default DefaultHandler.
+	// It is not called if the response was already started, the panic is only logged then.
+	Handler Handler
+	// Stack captures the stack trace of the panicking goroutine, which is logged and passed to the Handler.
+	Stack bool
+	// AllGoroutines captures the stack traces of all goroutines instead of the panicking one only, requires Stack.
+	AllGoroutines bool
+	// DisableLog stops logging the panics using feather.Logger, i.e. when the Handler logs them.
+	DisableLog bool
+}
+
+// DefaultHandler responds with 500 Internal Server Error, as JSON if preferred by the Accept header and HTML otherwise.
+// Neither the panic nor the stack are included in the response.
+func DefaultHandler(w http.ResponseWriter, r *http.Request, _ interface{}, _ []byte) {
+	text := http.StatusText(http.StatusInternalServerError)
+	if feather.NegotiateContentType(r, applicationJSON, textHTML) == textHTML {
+		w.Header().Set("Content-Type", textHTML+"; charset=utf-8")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte("<!DOCTYPE html>\n<html><head><title>" + text + "</title></head><body><h1>" + text + "</h1></body></html>\n"))
+		return
+	}
+
+	_ = feather.JSON(w, http.StatusInternalServerError, map[string]string{"error": text})
+}
+
+type responseWriter struct {
+	http.ResponseWriter
+	started bool
+}
+
+func (w *responseWriter) WriteHeader(status int) {
+	// 1xx informational responses don't start the final response
+	if status >= http.StatusOK {
+		w.started = true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	w.started = true
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap returns the underlying http.ResponseWriter for use by http.ResponseController.
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// New returns a middleware recovering from panics in the handlers and the middleware registered after it.
+// Panics with http.ErrAbortHandler are re-panicked so net/http aborts the response as intended.
+func New(cfg Config) feather.Middleware {
+	if cfg.Handler == nil {
+		cfg.Handler = DefaultHandler
+	}
+
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			// the Mux tracks whether the response was started already, only other writers are wrapped
+			fw, ok := feather.ResponseWriterOf(w)
+			var rw *responseWriter
+			if !ok {
+				rw = &responseWriter{ResponseWriter: w}
+				w = rw
+			}
+
+			defer func() {
+				err := recover()
+				if err == nil {
+					return
+				}
+
+				if e, ok := err.(error); ok && errors.Is(e, http.ErrAbortHandler) {
+					panic(err)
+				}
+
+				var stack []byte
+				if cfg.Stack {
+					stack = make([]byte, maxStackSize)
+					stack = stack[:runtime.Stack(stack, cfg.AllGoroutines)]
+				}
+
+				if !cfg.DisableLog {
+					attrs := []any{slog.Any("panic", err)}
+					if stack != nil {
+						attrs = append(attrs, slog.String("stack", string(stack)))
+					}
+					feather.Logger(r).Error("recovered from panic", attrs...)
+				}
+
+				if started := fw != nil && fw.Written() || rw != nil && rw.started; !started {
+					cfg.Handler(w, r, err, stack)
+				}
+			}()
+
+			next(w, r)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package recovery
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/pchchv/feather"
+	. "github.com/pchchv/feather/assert"
+)
+
+func serve(p *feather.Mux, path string, accept string) *httptest.ResponseRecorder {
+	r, _ := http.NewRequest(http.MethodGet, path, nil)
+	if accept != "" {
+		r.Header.Set("Accept", accept)
+	}
+
+	w := httptest.NewRecorder()
+	p.Serve().ServeHTTP(w, r)
+	return w
+}
+
+func TestRecovery(t *testing.T) {
+	var buf bytes.Buffer
+	p := feather.New()
+	p.SetLogger(slog.New(slog.NewTextHandler(&buf, nil)))
+	p.Use(New(Config{}))
+	p.Get("/panic", func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+	p.Get("/started", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("partial"))
+		panic("boom")
+	})
+	p.Get("/ok", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	})
+
+	w := serve(p, "/panic", "")
+	Equal(t, w.Code, http.StatusInternalServerError)
+	Equal(t, w.Header().Get("Content-Type"), "application/json; charset=utf-8")
+	Equal(t, w.Body.String(), `{"error":"Internal Server Error"}`)
+	Equal(t, strings.Contains(buf.String(), "recovered from panic"), true)
+	Equal(t, strings.Contains(buf.String(), "panic=boom"), true)
+	Equal(t, strings.Contains(buf.String(), "stack="), false)
+
+	w = serve(p, "/panic", "text/html,application/xhtml+xml,*/*;q=0.8")
+	Equal(t, w.Code, http.StatusInternalServerError)
+	Equal(t, w.Header().Get("Content-Type"), "text/html; charset=utf-8")
+	Equal(t, strings.Contains(w.Body.String(), "<h1>Internal Server Error</h1>"), true)
+
+	w = serve(p, "/started", "")
+	Equal(t, w.Code, http.StatusOK)
+	Equal(t, w.Body.String(), "partial")
+
+	w = serve(p, "/ok", "")
+	Equal(t, w.Code, http.StatusOK)
+	Equal(t, w.Body.String(), "ok")
+}
+
+func TestRecoveryHandler(t *testing.T) {
+	var recovered interface{}
+	var stack []byte
+	p := feather.New()
+	p.Use(New(Config{
+		Stack:      true,
+		DisableLog: true,
+		Handler: func(w http.ResponseWriter, r *http.Request, err interface{}, s []byte) {
+			recovered, stack = err, s
+			http.Error(w, "custom", http.StatusServiceUnavailable)
+		},
+	}))
+	p.Get("/panic", func(w http.ResponseWriter, r *http.Request) {
+		panic(errors.New("boom"))
+	})
+
+	w := serve(p, "/panic", "")
+	Equal(t, w.Code, http.StatusServiceUnavailable)
+	Equal(t, w.Body.String(), "custom\n")
+	Equal(t, recovered.(error).Error(), "boom")
+	Equal(t, strings.Contains(string(stack), "goroutine"), true)
+}
+
+func TestRecoveryErrAbortHandler(t *testing.T) {
+	p := feather.New()
+	p.Use(New(Config{}))
+	p.Get("/abort", func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	})
+
+	PanicsWithValue(t, func() {
+		serve(p, "/abort", "")
+	}, http.ErrAbortHandler)
+}
@@ -0,0 +1,77 @@
+// Package requestid provides middleware assigning every request an id,
+// available to handlers and loggers using feather.RequestID.
+package requestid
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/pchchv/feather"
+)
+
+const (
+	defaultHeader = "X-Request-Id"
+	maxLength     = 128
+)
+
+// Config contains the request id settings.
+type Config struct {
+	// Header carrying the request id, X-Request-Id by default.
+	Header string
+	// Generator returns a new request id, by default 16 random bytes hex encoded.
+	Generator func() string
+	// IgnoreIncoming generates a new id even if the request carries one,
+	// i.e. when the service is not behind a trusted proxy.
+	IgnoreIncoming bool
+}
+
+// New returns the request id middleware.
+// The id of the incoming request is used if valid, otherwise a new one is generated.
+// It is stored in the request variables, returned in the response header
+// and propagated to downstream calls using feather.OutgoingHeaders.
+func New(cfg Config) feather.Middleware {
+	if cfg.Header == "" {
+		cfg.Header = defaultHeader
+	}
+
+	if cfg.Generator == nil {
+		cfg.Generator = generate
+	}
+
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(cfg.Header)
+			if cfg.IgnoreIncoming || !valid(id) {
+				id = cfg.Generator()
+			}
+
+			feather.RequestVars(r).Set(feather.RequestIDKey, id)
+			feather.SetOutgoingHeader(r, cfg.Header, id)
+			w.Header().Set(cfg.Header, id)
+			next(w, r)
+		}
+	}
+}
+
+// valid reports whether the incoming id is non blank, not overly long and printable ASCII,
+// so it can be safely logged and echoed.
+func valid(id string) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+
+	return true
+}
+
+func generate() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
@@ -0,0 +1,62 @@
+package requestid
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/pchchv/feather"
+	. "github.com/pchchv/feather/assert"
+)
+
+func serve(p *feather.Mux, header string, id string) *httptest.ResponseRecorder {
+	r, _ := http.NewRequest(http.MethodGet, "/", nil)
+	if id != "" {
+		r.Header.Set(header, id)
+	}
+
+	w := httptest.NewRecorder()
+	p.Serve().ServeHTTP(w, r)
+	return w
+}
+
+func TestRequestID(t *testing.T) {
+	p := feather.New()
+	p.Use(New(Config{}))
+	p.Get("/", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(feather.RequestID(r) + " " + feather.OutgoingHeaders(r).Get("X-Request-Id")))
+	})
+
+	w := serve(p, "X-Request-Id", "abc-123")
+	Equal(t, w.Body.String(), "abc-123 abc-123")
+	Equal(t, w.Header().Get("X-Request-Id"), "abc-123")
+
+	w = serve(p, "X-Request-Id", "")
+	id := w.Header().Get("X-Request-Id")
+	Equal(t, len(id), 32)
+	Equal(t, w.Body.String(), id+" "+id)
+
+	w = serve(p, "X-Request-Id", "bad id\n")
+	NotEqual(t, w.Header().Get("X-Request-Id"), "bad id\n")
+	Equal(t, len(w.Header().Get("X-Request-Id")), 32)
+
+	w = serve(p, "X-Request-Id", strings.Repeat("a", 129))
+	Equal(t, len(w.Header().Get("X-Request-Id")), 32)
+}
+
+func TestRequestIDConfig(t *testing.T) {
+	p := feather.New()
+	p.Use(New(Config{
+		Header:         "X-Correlation-Id",
+		Generator:      func() string { return "generated" },
+		IgnoreIncoming: true,
+	}))
+	p.Get("/", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(feather.RequestID(r)))
+	})
+
+	w := serve(p, "X-Correlation-Id", "incoming")
+	Equal(t, w.Body.String(), "generated")
+	Equal(t, w.Header().Get("X-Correlation-Id"), "generated")
+}
@@ -0,0 +1,198 @@
+package feather
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	acceptHeader = "Accept"
+	textXML      = "text/xml"
+)
+
+// MarshalFunc marshals v to the body of a response.
+type MarshalFunc func(v interface{}) ([]byte, error)
+
+type marshaler struct {
+	mediaType   string
+	contentType string
+	fn          MarshalFunc
+}
+
+var (
+	marshalersMu sync.RWMutex
+	marshalers   []marshaler // custom marshalers in registration order
+)
+
+// RegisterMarshaler registers a marshaler used by Negotiate for the media type,
+// i.e. application/msgpack, responses are sent with the given contentType.
+// Registering a marshaler for application/json, application/xml or text/plain overrides the built-in one.
+func RegisterMarshaler(mediaType string, contentType string, fn MarshalFunc) {
+	marshalersMu.Lock()
+	defer marshalersMu.Unlock()
+	mediaType = strings.ToLower(mediaType)
+	for i, m := range marshalers {
+		if m.mediaType == mediaType {
+			marshalers[i] = marshaler{mediaType: mediaType, contentType: contentType, fn: fn}
+			return
+		}
+	}
+
+	marshalers = append(marshalers, marshaler{mediaType: mediaType, contentType: contentType, fn: fn})
+}
+
+// Negotiate renders data in the format preferred by the Accept header of the request,
+// JSON, XML, plain text or any format registered using RegisterMarshaler.
+// JSON is used when the request has no Accept header.
+// If none of the formats is acceptable, 406 Not Acceptable is returned.
+func Negotiate(w http.ResponseWriter, r *http.Request, status int, data interface{}) error {
+	AddVary(w, acceptHeader)
+
+	marshalersMu.RLock()
+	offers := make([]string, 0, 4+len(marshalers))
+	offers = append(offers, applicationJSONNoCharset, applicationXMLNoCharset, textXML, textPlainNoCharset)
+	custom := make(map[string]marshaler, len(marshalers))
+	for _, m := range marshalers {
+		if _, ok := custom[m.mediaType]; !ok {
+			custom[m.mediaType] = m
+			switch m.mediaType {
+			case applicationJSONNoCharset, applicationXMLNoCharset, textXML, textPlainNoCharset:
+			default:
+				offers = append(offers, m.mediaType)
+			}
+		}
+	}
+	marshalersMu.RUnlock()
+
+	mediaType := NegotiateContentType(r, offers...)
+	if m, ok := custom[mediaType]; ok {
+		b, err := m.fn(data)
+		if err != nil {
+			return err
+		}
+
+		w.Header().Set(contentTypeHeader, m.contentType)
+		w.WriteHeader(status)
+		_, err = w.Write(b)
+		return err
+	}
+
+	switch mediaType {
+	case applicationJSONNoCharset:
+		return JSON(w, status, data)
+	case applicationXMLNoCharset, textXML:
+		return XML(w, status, data)
+	case textPlainNoCharset:
+		w.Header().Set(contentTypeHeader, textPlain)
+		w.WriteHeader(status)
+		_, err := w.Write(plainText(data))
+		return err
+	}
+
+	http.Error(w, http.StatusText(http.StatusNotAcceptable), http.StatusNotAcceptable)
+	return nil
+}
+
+// NegotiateContentType returns the offered media type best matching the Accept header of the request,
+// the first offer if the request has no Accept header or blank if none is acceptable.
+// Among equally acceptable offers the first one is chosen.
+func NegotiateContentType(r *http.Request, offers ...string) string {
+	if len(offers) == 0 {
+		return blank
+	}
+
+	ranges := parseAccept(r.Header.Values(acceptHeader))
+	if len(ranges) == 0 {
+		return offers[0]
+	}
+
+	var best string
+	var bestQ float64
+	var bestSpecificity int
+	for _, offer := range offers {
+		q, specificity := -1.0, -1
+		for _, ar := range ranges {
+			if s := ar.matches(offer); s > specificity {
+				q, specificity = ar.q, s
+			}
+		}
+
+		if q > bestQ || (q == bestQ && q > 0 && specificity > bestSpecificity) {
+			best, bestQ, bestSpecificity = offer, q, specificity
+		}
+	}
+
+	return best
+}
+
+// acceptRange is a media range of the Accept header.
+type acceptRange struct {
+	typ     string
+	subtype string
+	q       float64
+}
+
+// matches returns the specificity of the range matching the media type, -1 if it does not match.
+func (ar acceptRange) matches(mediaType string) int {
+	typ, subtype, _ := strings.Cut(strings.ToLower(mediaType), "/")
+	switch {
+	case ar.typ == typ && ar.subtype == subtype:
+		return 2
+	case ar.typ == typ && ar.subtype == "*":
+		return 1
+	case ar.typ == "*" && ar.subtype == "*":
+		return 0
+	}
+
+	return -1
+}
+
+// parseAccept parses the media ranges of the Accept header values sorted by decreasing quality.
+func parseAccept(values []string) (ranges []acceptRange) {
+	for _, v := range values {
+		for _, s := range strings.Split(v, ",") {
+			params := strings.Split(s, ";")
+			mt := strings.ToLower(strings.TrimSpace(params[0]))
+			typ, subtype, ok := strings.Cut(mt, "/")
+			if !ok || typ == blank || subtype == blank {
+				continue
+			}
+
+			ar := acceptRange{typ: typ, subtype: subtype, q: 1}
+			for _, p := range params[1:] {
+				k, v, _ := strings.Cut(strings.TrimSpace(p), "=")
+				if strings.EqualFold(k, "q") {
+					if q, err := strconv.ParseFloat(v, 64); err == nil && q >= 0 && q <= 1 {
+						ar.q = q
+					}
+				}
+			}
+
+			ranges = append(ranges, ar)
+		}
+	}
+
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].q > ranges[j].q
+	})
+	return
+}
+
+func plainText(data interface{}) []byte {
+	switch v := data.(type) {
+	case string:
+		return []byte(v)
+	case []byte:
+		return v
+	case fmt.Stringer:
+		return []byte(v.String())
+	case error:
+		return []byte(v.Error())
+	default:
+		return []byte(fmt.Sprint(v))
+	}
+}
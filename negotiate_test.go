@@ -0,0 +1,92 @@
+package feather
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/pchchv/feather/assert"
+)
+
+type negotiateTest struct {
+	Name string `json:"name" xml:"name"`
+}
+
+func (n negotiateTest) String() string {
+	return "name=" + n.Name
+}
+
+func TestNegotiateContentType(t *testing.T) {
+	offers := []string{applicationJSONNoCharset, applicationXMLNoCharset, textPlainNoCharset}
+	tests := []struct {
+		accept   string
+		expected string
+	}{
+		{"", applicationJSONNoCharset},
+		{"*/*", applicationJSONNoCharset},
+		{"application/xml", applicationXMLNoCharset},
+		{"text/html, application/xml;q=0.9, */*;q=0.8", applicationXMLNoCharset},
+		{"text/*", textPlainNoCharset},
+		{"application/json;q=0, */*", applicationXMLNoCharset},
+		{"application/json;q=0.5, text/plain;q=0.6", textPlainNoCharset},
+		{"text/plain;q=0.5, text/*;q=1, */*;q=0.1", textPlainNoCharset},
+		{"image/png", ""},
+		{"Application/XML", applicationXMLNoCharset},
+		{"invalid, application/xml;q=abc", applicationXMLNoCharset},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if tt.accept != "" {
+			r.Header.Set(acceptHeader, tt.accept)
+		}
+		Equal(t, NegotiateContentType(r, offers...), tt.expected)
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	data := negotiateTest{Name: "feather"}
+	do := func(accept string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set(acceptHeader, accept)
+		w := httptest.NewRecorder()
+		Equal(t, Negotiate(w, r, http.StatusCreated, data), nil)
+		return w
+	}
+
+	w := do("application/json")
+	Equal(t, w.Code, http.StatusCreated)
+	Equal(t, w.Header().Get(contentTypeHeader), applicationJSON)
+	Equal(t, w.Header().Get(varyHeader), acceptHeader)
+	Equal(t, w.Body.String(), `{"name":"feather"}`)
+
+	w = do("text/xml")
+	Equal(t, w.Header().Get(contentTypeHeader), applicationXML)
+	Equal(t, w.Body.String(), xml.Header+"<negotiateTest><name>feather</name></negotiateTest>")
+
+	w = do("text/plain")
+	Equal(t, w.Header().Get(contentTypeHeader), textPlain)
+	Equal(t, w.Body.String(), "name=feather")
+
+	w = do("image/png")
+	Equal(t, w.Code, http.StatusNotAcceptable)
+
+	RegisterMarshaler("application/x-test", "application/x-test", func(v interface{}) ([]byte, error) {
+		return []byte("test:" + v.(negotiateTest).Name), nil
+	})
+	RegisterMarshaler("text/plain", "text/plain", func(v interface{}) ([]byte, error) {
+		return []byte("custom"), nil
+	})
+	defer func() {
+		marshalers = nil
+	}()
+
+	w = do("application/x-test, application/json;q=0.5")
+	Equal(t, w.Code, http.StatusCreated)
+	Equal(t, w.Header().Get(contentTypeHeader), "application/x-test")
+	Equal(t, w.Body.String(), "test:feather")
+
+	w = do("text/plain")
+	Equal(t, w.Body.String(), "custom")
+}
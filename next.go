@@ -0,0 +1,42 @@
+package feather
+
+import "net/http"
+
+// NextRoute declines the request from within the handler of the matched route,
+// letting the next best matching route handle it once the handler returns, i.e.
+//
+//	p.Get("/assets/*", func(w http.ResponseWriter, r *http.Request) {
+//		if !exists(feather.RequestVars(r).Wildcard()) {
+//			feather.NextRoute(w, r) // falls through to /*, serving the index of the single page app
+//			return
+//		}
+//		...
+//	})
+//	p.Get("/*", spaIndex)
+//
+// The next route is the one matching if the declining route didn't exist, preferring static routes
+// over params and params over catch-alls, and the 404 handler if there is none.
+// The handler must not write the response before declining it.
+// NextRoute has no effect outside the handlers of matched routes.
+func NextRoute(w http.ResponseWriter, r *http.Request) {
+	if rv, ok := requestVarsOf(r); ok {
+		rv.next = true
+	}
+}
+
+// nextRoute returns the handler of the route matching path after skipping
+// the declined routes, see NextRoute, or the 404 handler if there is none.
+func (p *Mux) nextRoute(tree *node, path string, rv *requestVars, declined int) (h http.HandlerFunc, routed bool) {
+	rv.next = false
+	rv.skip = declined
+	rv.params = rv.params[:0]
+	if len(path) >= len(tree.path) && path[:len(tree.path)] == tree.path {
+		if h = tree.match(path[len(tree.path):], p, &rv); h != nil {
+			return h, true
+		}
+	}
+
+	rv.params = rv.params[:0]
+	rv.route = blank
+	return p.notFound(path), false
+}
@@ -0,0 +1,70 @@
+package feather
+
+import (
+	"net/http"
+	"testing"
+
+	. "github.com/pchchv/feather/assert"
+)
+
+func TestNextRoute(t *testing.T) {
+	var calls string
+	respond := func(w http.ResponseWriter, r *http.Request) {
+		rv := RequestVars(r)
+		_, _ = w.Write([]byte(rv.Route() + " " + rv.URLParam("id") + rv.Wildcard()))
+	}
+	// declines the paths ending with "next"
+	decline := func(name string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			calls += name
+			if len(r.URL.Path) >= 4 && r.URL.Path[len(r.URL.Path)-4:] == "next" {
+				NextRoute(w, r)
+				return
+			}
+			respond(w, r)
+		}
+	}
+
+	p := New()
+	p.SetCatchAllFallback(true)
+	p.Get("/users/next", decline("s"))
+	p.Get("/users/:id", decline("p"))
+	p.Get("/users/*", decline("c"))
+	p.Get("/assets/*", decline("a"))
+	p.Get("/*", respond)
+
+	tests := []struct {
+		path  string
+		body  string
+		calls string
+	}{
+		{path: "/users/13", body: "/users/:id 13", calls: "p"},
+		// static, param and catch-all decline in turn
+		{path: "/users/next", body: "/* users/next", calls: "spc"},
+		{path: "/users/a/next", body: "/* users/a/next", calls: "c"},
+		{path: "/assets/app.js", body: "/assets/* app.js", calls: "a"},
+		{path: "/assets/next", body: "/* assets/next", calls: "a"},
+	}
+
+	for _, tt := range tests {
+		calls = ""
+		code, body := request(http.MethodGet, tt.path, p)
+		Equal(t, code, http.StatusOK)
+		Equal(t, body, tt.body)
+		Equal(t, calls, tt.calls)
+	}
+
+	p = New()
+	p.Get("/users/:id", decline("p"))
+	p.Register404(func(w http.ResponseWriter, r *http.Request) {
+		calls += "n"
+		// has no effect
+		NextRoute(w, r)
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	calls = ""
+	code, _ := request(http.MethodGet, "/users/next", p)
+	Equal(t, code, http.StatusNotFound)
+	Equal(t, calls, "pn")
+}
@@ -2,7 +2,9 @@ package feather
 
 import (
 	"net/http"
-	"net/url"
+	"regexp"
+	"sort"
+	"strings"
 )
 
 const (
@@ -16,6 +18,10 @@ type nodeType uint8
 type existingParams map[string]struct{}
 
 func (e existingParams) check(param string, path string) {
+	if i := strings.IndexByte(param, constraintStartByte); i != -1 {
+		param = param[:i]
+	}
+
 	if _, ok := e[param]; ok {
 		panic("Duplicate param name '" + param + "' detected for route '" + path + "'")
 	}
@@ -23,342 +29,434 @@ func (e existingParams) check(param string, path string) {
 	e[param] = struct{}{}
 }
 
+// node of the routing tree, the path of static nodes is literal and matched before
+// the param child, which is matched before the catch-all, so /users/new and /users/:id can coexist.
 type node struct {
-	path      string
-	indices   string
-	children  []*node
-	handler   http.HandlerFunc
-	priority  uint32
-	nType     nodeType
-	wildChild bool
+	path       string
+	indices    string
+	children   []*node // static children, indexed by the first byte of their path
+	paramChild *node   // param matching the path segment when no static child matches
+	catchAll   *node   // catch-all leaf matching the rest of the path after this node, whose path ends with '/'
+	handler    http.HandlerFunc
+	route      string         // full route pattern of the handler, if any
+	plain      bool           // the handler has neither middleware nor metadata and can't be declined, see serveHTTP
+	param      string         // name of the param of a hasParams node
+	constraint *regexp.Regexp // constraint of the param value, i.e. :id(\d+)
+	priority   uint32
+	nType      nodeType
 }
 
-func (n *node) insertChild(numParams uint8, existing existingParams, path string, fullPath string, handler http.HandlerFunc) {
-	var offset int // already handled bytes of the path
-	// find prefix until first wildcard
-	// (beginning with paramByte' or wildByte')
-	for i, max := 0, len(path); numParams > 0; i++ {
-		c := path[i]
-		if c != paramByte && c != wildByte {
-			continue
-		}
-
-		// find wildcard end
-		// (either '/' or path end)
+// wildcardEnd returns the end of the wildcard starting at i, params end after their name and constraint
+// and catch-alls at the end of the path segment.
+func wildcardEnd(path string, i int, fullPath string) int {
+	if path[i] == wildByte {
 		end := i + 1
-		for end < max && path[end] != slashByte {
-			switch path[end] {
+		for ; end < len(path) && path[end] != slashByte; end++ {
 			// wildcard name must not contain ':' and '*'
-			case paramByte, wildByte:
+			if path[end] == paramByte || path[end] == wildByte {
 				panic("only one wildcard per path segment is allowed, has: '" + path[i:] + "' in path '" + fullPath + "'")
-			default:
-				end++
 			}
 		}
 
-		// check if this node existing children,
-		// which will be unreachable if a wildcard is inserted here
-		if len(n.children) > 0 {
-			panic("wildcard route '" + path[i:end] + "' conflicts with existing children in path '" + fullPath + "'")
+		return end
+	}
+
+	end := paramNameEnd(path, i)
+	if end < len(path) && path[end] == constraintStartByte {
+		ce := constraintEnd(path, end)
+		if ce == -1 {
+			panic("unterminated constraint in path '" + fullPath + "'")
 		}
 
-		if c == paramByte { // param
-			// check if the wildcard has a name
-			if end-i < 2 {
-				panic("wildcards must be named with a non-empty name in path '" + fullPath + "'")
-			}
+		if strings.IndexByte(path[end:ce], slashByte) != -1 {
+			panic("constraint must not contain '/' in path '" + fullPath + "'")
+		}
 
-			// split path at the beginning of the wildcard
-			if i > 0 {
-				n.path = path[offset:i]
-				offset = i
-			}
+		end = ce
+	}
 
-			child := &node{
-				nType: hasParams,
-			}
-			n.children = []*node{child}
-			n.wildChild = true
-			n = child
-			n.priority++
-			numParams--
-			// if the path doesn't end with the wildcard,
-			// then there will be another non-wildcard subpath starting with '/'
-			if end < max {
-				existing.check(path[offset:end], fullPath)
-				n.path = path[offset:end]
-				offset = end
-				child := &node{
-					priority: 1,
-				}
-				n.children = []*node{child}
-				n = child
-			}
-		} else { // catchAll
-			if end != max || numParams > 1 {
-				panic("Character after the * symbol is not permitted, path '" + fullPath + "'")
-			}
+	return end
+}
+
+// checkWildcards panics if a wildcard of the path is malformed or a param name is used twice,
+// before the route is added to the tree.
+func checkWildcards(path string) {
+	existing := make(existingParams)
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		if c != paramByte && c != wildByte {
+			continue
+		}
 
-			if len(n.path) > 0 && n.path[len(n.path)-1] == '/' {
-				panic("catch-all conflicts with existing handle for the path segment root in path '" + fullPath + "'")
+		end := wildcardEnd(path, i, path)
+		if c == paramByte {
+			// check if the wildcard has a name
+			if end-i < 2 || path[i+1] == constraintStartByte {
+				panic("wildcards must be named with a non-empty name in path '" + path + "'")
 			}
 
-			// currently fixed width 1 for '/'
-			i--
-			if path[i] != slashByte {
-				panic("no / before catch-all in path '" + fullPath + "'")
+			// params within a segment must be delimited by a static part
+			if end < len(path) {
+				switch path[end] {
+				case paramByte:
+					panic("params must be separated by a static part, has: '" + path[i:] + "' in path '" + path + "'")
+				case wildByte:
+					panic("only one wildcard per path segment is allowed, has: '" + path[i:] + "' in path '" + path + "'")
+				}
 			}
 
-			n.path = path[offset:i]
-			// first node: catchAll node with empty path
-			child := &node{
-				wildChild: true,
-				nType:     matchesAny,
+			existing.check(path[i:end], path)
+		} else {
+			if end != len(path) {
+				panic("Character after the * symbol is not permitted, path '" + path + "'")
 			}
-			n.children = []*node{child}
-			n.indices = string(path[i])
-			n = child
-			n.priority++
-			// second node: node holding the variable
-			child = &node{
-				path:     path[i:],
-				nType:    matchesAny,
-				handler:  handler,
-				priority: 1,
+
+			if i == 0 || path[i-1] != slashByte {
+				panic("no / before catch-all in path '" + path + "'")
 			}
-			n.children = []*node{child}
-			return
 		}
+
+		i = end - 1
 	}
+}
 
-	if n.nType == hasParams {
-		existing.check(path[offset:], fullPath)
+// staticEnd returns the end of the static prefix of the path, the first wildcard or the path end.
+func staticEnd(path string) int {
+	if i := strings.IndexAny(path, ":*"); i != -1 {
+		return i
 	}
 
-	// insert remaining path part and handle to the leaf
-	n.path = path[offset:]
-	n.handler = handler
+	return len(path)
 }
 
-// incrementChildPriority increments priority of the given child and reorders if necessary.
-func (n *node) incrementChildPriority(pos int) int {
-	n.children[pos].priority++
-	prio := n.children[pos].priority
-	// adjust position (move to front)
-	newPos := pos
-	for newPos > 0 && n.children[newPos-1].priority < prio {
-		// swap node positions
-		n.children[newPos-1], n.children[newPos] = n.children[newPos], n.children[newPos-1]
-		newPos--
+// setParam sets the param name and constraint of a hasParams node from its path.
+func (n *node) setParam(fullPath string) {
+	n.param = n.path[1:]
+	i := strings.IndexByte(n.param, constraintStartByte)
+	if i == -1 {
+		return
 	}
 
-	// build new index char string
-	if newPos != pos {
-		n.indices = n.indices[:newPos] + // unchanged prefix, might be empty
-			n.indices[pos:pos+1] + // the index char we move
-			n.indices[newPos:pos] + n.indices[pos+1:] // rest without char at 'pos'
+	expr := n.param[i+1 : len(n.param)-1]
+	re, err := regexp.Compile("^(?:" + expr + ")$")
+	if err != nil {
+		panic("invalid constraint '" + expr + "' in path '" + fullPath + "': " + err.Error())
 	}
 
-	return newPos
+	n.param = n.param[:i]
+	n.constraint = re
+}
+
+// sort orders the static children of the subtree by priority, the number of routes below them,
+// so the children leading to the most routes are compared first, and rebuilds their indices.
+func (n *node) sort() {
+	sort.SliceStable(n.children, func(i, j int) bool {
+		return n.children[i].priority > n.children[j].priority
+	})
+
+	indices := make([]byte, len(n.children))
+	for i, child := range n.children {
+		indices[i] = child.path[0]
+		child.sort()
+	}
+
+	n.indices = string(indices)
+	if n.paramChild != nil {
+		n.paramChild.sort()
+	}
+}
+
+// markDeclinable clears plain of the routes whose path another route may match,
+// so their handlers can decline the requests using NextRoute.
+func (n *node) markDeclinable(declinable bool) {
+	declinable = declinable || n.paramChild != nil || n.catchAll != nil
+	if declinable {
+		n.plain = false
+	}
+
+	for _, child := range n.children {
+		child.markDeclinable(declinable)
+	}
+
+	if n.paramChild != nil {
+		n.paramChild.markDeclinable(declinable)
+	}
 }
 
 // addRoute adds the node with the given handle to the path.
 // Middleware is set here because it needs to transfer all route's middlewares
 // (it is a chain of functions) with its handler to the node.
-func (n *node) addRoute(path string, handler http.HandlerFunc) (lp uint8) {
+func (n *node) addRoute(path string, handler http.HandlerFunc, plain bool, mux *Mux) (lp uint8) {
 	var err error
 	if path == blank {
 		path = basePath
 	}
 
-	existing := make(existingParams)
 	fullPath := path
-	if path, err = url.QueryUnescape(path); err != nil {
+	if path, err = unescapeRoute(path); err != nil {
 		panic("Query Unescape Error on path '" + fullPath + "': " + err.Error())
 	}
 
 	fullPath = path
+	lp = countParams(path)
+	checkWildcards(path)
 	n.priority++
-	numParams := countParams(path)
-	lp = numParams
-	// non-empty tree
-	if len(n.path) > 0 || len(n.children) > 0 {
-	walk:
-		for {
-			// find the longest common prefix.
-			// this also implies that the common prefix contains no : or *
-			// since the existing key can't contain those chars.
-			var i int
-			max := min(len(path), len(n.path))
-			for i < max && path[i] == n.path[i] {
-				i++
-			}
-
-			// split edge
-			if i < len(n.path) {
-				child := node{
-					path:      n.path[i:],
-					wildChild: n.wildChild,
-					indices:   n.indices,
-					children:  n.children,
-					handler:   n.handler,
-					priority:  n.priority - 1,
-				}
-				n.children = []*node{&child}
-				// []byte for proper unicode char conversion
-				n.indices = string([]byte{n.path[i]})
-				n.path = path[:i]
-				n.handler = nil
-				n.wildChild = false
-			}
+	// empty tree
+	if len(n.path) == 0 && len(n.children) == 0 && n.paramChild == nil {
+		n.nType = isRoot
+		n.path = path[:staticEnd(path)]
+	}
 
-			// make new node a child of this node
-			if i < len(path) {
-				path = path[i:]
-				if n.wildChild {
-					n = n.children[0]
-					n.priority++
-					numParams--
-					existing.check(n.path, fullPath)
-					// check if the wildcard matches
-					if len(path) >= len(n.path) && n.path == path[:len(n.path)] {
-						// check for longer wildcard, e.g. :name and :names
-						if len(n.path) >= len(path) || path[len(n.path)] == slashByte {
-							continue walk
-						}
-					}
+walk:
+	for {
+		// find the longest common prefix.
+		// this also implies that the common prefix contains no : or *
+		// since the existing key can't contain those chars.
+		var i int
+		max := min(len(path), len(n.path))
+		for i < max && path[i] == n.path[i] {
+			i++
+		}
 
-					panic("path segment '" + path + "' conflicts with existing wildcard '" + n.path + "' in path '" + fullPath + "'")
-				}
+		// split edge
+		if i < len(n.path) {
+			child := node{
+				path:       n.path[i:],
+				indices:    n.indices,
+				children:   n.children,
+				paramChild: n.paramChild,
+				catchAll:   n.catchAll,
+				handler:    n.handler,
+				route:      n.route,
+				priority:   n.priority - 1,
+			}
+			n.children = []*node{&child}
+			// []byte for proper unicode char conversion
+			n.indices = string([]byte{n.path[i]})
+			n.path = n.path[:i]
+			n.handler = nil
+			n.route = blank
+			n.paramChild = nil
+			n.catchAll = nil
+		}
 
-				c := path[0]
-				// slash after param
-				if n.nType == hasParams && c == slashByte && len(n.children) == 1 {
-					n = n.children[0]
-					n.priority++
-					continue walk
-				}
+		path = path[i:]
+		// the rest of the path is matched by the catch-all of this node
+		if n.catchAll != nil {
+			switch {
+			case len(path) > 0 && path[0] == wildByte:
+				panic("handlers are already registered for path '" + fullPath + "'")
+			case !mux.catchAllFallback:
+				panic("path segment '/" + path + "' conflicts with existing wildcard '/" + n.catchAll.path + "' in path '" + fullPath + "'")
+			}
+		}
 
-				// check if a child with the next path byte exists
-				for i := 0; i < len(n.indices); i++ {
-					if c == n.indices[i] {
-						i = n.incrementChildPriority(i)
-						n = n.children[i]
-						continue walk
-					}
-				}
+		if path == blank { // make node a (in-path) leaf
+			if n.handler != nil {
+				panic("handlers are already registered for path '" + fullPath + "'")
+			}
 
-				// otherwise insert it
-				if c != paramByte && c != wildByte {
+			n.handler = handler
+			n.route = fullPath
+			n.plain = plain
+			return
+		}
 
-					// []byte for proper unicode char conversion
-					n.indices += string([]byte{c})
-					child := &node{}
-					n.children = append(n.children, child)
-					n.incrementChildPriority(len(n.indices) - 1)
-					n = child
+		switch c := path[0]; c {
+		case wildByte:
+			n.insertCatchAll(path, fullPath, handler, mux)
+			return
+		case paramByte:
+			end := wildcardEnd(path, 0, fullPath)
+			if p := n.paramChild; p != nil {
+				if p.path != path[:end] {
+					panic("path segment '" + path + "' conflicts with existing wildcard '" + p.path + "' in path '" + fullPath + "'")
 				}
+			} else {
+				n.paramChild = &node{path: path[:end], nType: hasParams}
+				n.paramChild.setParam(fullPath)
+			}
 
-				n.insertChild(numParams, existing, path, fullPath, handler)
-				return
-			} else if i == len(path) { // make node a (in-path) leaf
+			n = n.paramChild
+			n.priority++
+			path = path[end:]
+			if path == blank {
 				if n.handler != nil {
 					panic("handlers are already registered for path '" + fullPath + "'")
 				}
+
 				n.handler = handler
+				n.route = fullPath
+				n.plain = plain
+				return
 			}
 
-			return
+			// the static part following the param
+			c = path[0]
+			fallthrough
+		default:
+			// check if a child with the next path byte exists
+			for i := 0; i < len(n.indices); i++ {
+				if c == n.indices[i] {
+					n = n.children[i]
+					n.priority++
+					continue walk
+				}
+			}
+
+			// otherwise insert it
+			// []byte for proper unicode char conversion
+			n.indices += string([]byte{c})
+			child := &node{path: path[:staticEnd(path)], priority: 1}
+			n.children = append(n.children, child)
+			n = child
 		}
-	} else { // empty tree
-		n.insertChild(numParams, existing, path, fullPath, handler)
-		n.nType = isRoot
 	}
+}
 
-	return
+// insertCatchAll adds the catch-all matching the rest of the path after n.
+func (n *node) insertCatchAll(path string, fullPath string, handler http.HandlerFunc, mux *Mux) {
+	if !mux.catchAllFallback {
+		// the existing routes would be unreachable,
+		// unless the catch-all only matches what they don't, see SetCatchAllFallback
+		switch {
+		case n.paramChild != nil:
+			panic("path segment '" + path + "' conflicts with existing wildcard '" + n.paramChild.path + "' in path '" + fullPath + "'")
+		case len(n.children) > 0:
+			panic("wildcard route '" + path + "' conflicts with existing children in path '" + fullPath + "'")
+		case n.handler != nil:
+			panic("catch-all conflicts with existing handle for the path segment root in path '" + fullPath + "'")
+		}
+	}
+
+	n.catchAll = &node{
+		path:     path,
+		nType:    matchesAny,
+		handler:  handler,
+		route:    fullPath,
+		priority: 1,
+	}
 }
 
 // find returns the handle registered with the given path (key).
+// Static routes are preferred over params and params over catch-alls, backtracking when
+// the preferred route doesn't match the rest of the path, i.e. /users/newest matches /users/:id
+// rather than nothing when /users/new is registered.
 func (n *node) find(path string, mux *Mux) (handler http.HandlerFunc, rv *requestVars) {
-walk: // outer loop for walking the tree
-	for {
-		if len(path) > len(n.path) {
-			if path[:len(n.path)] == n.path {
-				path = path[len(n.path):]
-				// if this node does not have a wildcard (param or catchAll) child,
-				// it is possible just look up the next child node and continue to walk down the tree
-				if !n.wildChild {
-					c := path[0]
-					for i := 0; i < len(n.indices); i++ {
-						if c == n.indices[i] {
-							n = n.children[i]
-							continue walk
-						}
-					}
+	if len(path) < len(n.path) || path[:len(n.path)] != n.path {
+		return
+	}
 
-					return
-				}
+	if handler = n.match(path[len(n.path):], mux, &rv); handler == nil && rv != nil {
+		rv.params = rv.params[:0]
+	}
 
-				// handle wildcard child
-				n = n.children[0]
-				switch n.nType {
-				case hasParams:
-					// find param end (either '/' or path end)
-					var end int
-					for end < len(path) && path[end] != slashByte {
-						end++
-					}
+	return
+}
 
-					if rv == nil {
-						rv = mux.pool.Get().(*requestVars)
-						rv.params = rv.params[0:0]
-					}
+// match returns the handler matching the rest of the path after n, capturing the params in rv.
+func (n *node) match(path string, mux *Mux, rv **requestVars) http.HandlerFunc {
+	if path == blank && n.handler != nil && !(*rv).skips() {
+		if *rv == nil && (!n.plain || mux.usesRequestVars()) {
+			*rv = mux.requestVars()
+		}
 
-					// save param value
-					i := len(rv.params)
-					rv.params = rv.params[:i+1] // expand slice within preallocated capacity
-					rv.params[i].key = n.path[1:]
-					rv.params[i].value = path[:end]
-					// is needed to go deeper
-					if end < len(path) {
-						if len(n.children) > 0 {
-							path = path[end:]
-							n = n.children[0]
-							continue walk
-						}
-						return
-					}
+		if *rv != nil {
+			(*rv).route = n.route
+		}
+		return n.handler
+	}
 
-					if n.handler != nil {
-						handler = n.handler
-						return
+	if path != blank {
+		c := path[0]
+		for i := 0; i < len(n.indices); i++ {
+			if c == n.indices[i] {
+				child := n.children[i]
+				if len(path) >= len(child.path) && path[:len(child.path)] == child.path {
+					if h := child.match(path[len(child.path):], mux, rv); h != nil {
+						return h
 					}
+				}
+				break
+			}
+		}
 
-					return
-				case matchesAny:
-					if rv == nil {
-						rv = mux.pool.Get().(*requestVars)
-						rv.params = rv.params[0:0]
-					}
+		if p := n.paramChild; p != nil {
+			seg := strings.IndexByte(path, slashByte)
+			if seg == -1 {
+				seg = len(path)
+			}
 
-					// save param value
-					i := len(rv.params)
-					rv.params = rv.params[:i+1] // expand slice within preallocated capacity
-					rv.params[i].key = WildcardParam
-					rv.params[i].value = path[1:]
-					handler = n.handler
-					return
+			// a param followed by a static part within the segment, like the '.' of :name.:ext,
+			// ends before its last occurrence first, so the param takes the longest value
+			if p.indices != blank && p.indices != basePath {
+				for end := seg - 1; end > 0; end-- {
+					if strings.IndexByte(p.indices, path[end]) != -1 {
+						if h := p.matchParam(path, end, mux, rv); h != nil {
+							return h
+						}
+					}
 				}
 			}
-		} else if path == n.path {
-			// must reached the node containing the handle
-			// check if this node has a handle registered
-			if n.handler != nil {
-				handler = n.handler
+
+			if h := p.matchParam(path, seg, mux, rv); h != nil {
+				return h
 			}
 		}
+	}
 
-		// nothing found
-		return
+	if n.catchAll == nil || (*rv).skips() {
+		return nil
 	}
+
+	if *rv == nil {
+		*rv = mux.requestVars()
+	}
+
+	// save the wildcard value
+	i := len((*rv).params)
+	(*rv).params = (*rv).params[:i+1]
+	(*rv).params[i].key = WildcardParam
+	(*rv).params[i].value = path
+	(*rv).route = n.catchAll.route
+	return n.catchAll.handler
+}
+
+// matchParam returns the handler matching the rest of the path after the param node n with the value path[:end].
+func (n *node) matchParam(path string, end int, mux *Mux, rv **requestVars) http.HandlerFunc {
+	if !n.matches(path[:end], mux) {
+		return nil
+	}
+
+	if *rv == nil {
+		*rv = mux.requestVars()
+	}
+
+	// save param value
+	params := (*rv).params
+	i := len(params)
+	params = params[:i+1] // expand slice within preallocated capacity
+	params[i].key = n.param
+	params[i].value = path[:end]
+	(*rv).params = params
+	if h := n.match(path[end:], mux, rv); h != nil {
+		return h
+	}
+
+	// backtrack
+	(*rv).params = params[:i]
+	return nil
+}
+
+// matches reports whether the param value satisfies the constraint of the node and the ConstraintFunc registered for the param.
+func (n *node) matches(value string, mux *Mux) bool {
+	if n.constraint != nil && !n.constraint.MatchString(value) {
+		return false
+	}
+
+	if fn := mux.constraints[n.param]; fn != nil && !fn(value) {
+		return false
+	}
+
+	return true
 }
@@ -2,6 +2,7 @@ package feather
 
 import (
 	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	. "github.com/pchchv/feather/assert"
@@ -68,3 +69,300 @@ func TestBadRoutes(t *testing.T) {
 	p := New()
 	PanicMatches(t, func() { p.Get("/users//:id", defaultHandler) }, "Bad path '/users//:id' contains duplicate // at index:6")
 }
+
+func TestRoutePattern(t *testing.T) {
+	p := New()
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		if _, err := w.Write([]byte(RequestVars(r).Route())); err != nil {
+			panic(err)
+		}
+	}
+	p.Get("/users/:id", fn)
+	p.Get("/users/:id/profile", fn)
+	p.Get("/files/*", fn)
+	p.Group("/admin/:aid").Get("", fn)
+	p.Get("/static", fn)
+
+	_, body := request(http.MethodGet, "/users/13", p)
+	Equal(t, body, "/users/:id")
+
+	_, body = request(http.MethodGet, "/users/13/profile", p)
+	Equal(t, body, "/users/:id/profile")
+
+	_, body = request(http.MethodGet, "/files/a/b.css", p)
+	Equal(t, body, "/files/*")
+
+	_, body = request(http.MethodGet, "/admin/1", p)
+	Equal(t, body, "/admin/:aid")
+
+	_, body = request(http.MethodGet, "/static", p)
+	Equal(t, body, "/static")
+}
+
+func TestParamConstraints(t *testing.T) {
+	p := New()
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		rv := RequestVars(r)
+		if _, err := w.Write([]byte(rv.Route() + "|" + rv.URLParam("id") + "|" + rv.URLParam("slug"))); err != nil {
+			panic(err)
+		}
+	}
+	p.Get("/users/:id(\\d+)", fn)
+	p.Get("/users/:id(\\d+)/posts/:slug([a-z-]+)", fn)
+	p.Get("/codes/:id((?:[A-Z]{2})|x)", fn)
+	p.Get("/tags/:slug", fn)
+	p.RegisterConstraint("slug", func(v string) bool { return len(v) <= 8 })
+
+	code, body := request(http.MethodGet, "/users/13", p)
+	Equal(t, code, http.StatusOK)
+	Equal(t, body, "/users/:id(\\d+)|13|")
+
+	code, _ = request(http.MethodGet, "/users/joeybloggs", p)
+	Equal(t, code, http.StatusNotFound)
+
+	code, body = request(http.MethodGet, "/users/13/posts/hello-world", p)
+	Equal(t, code, http.StatusNotFound) // longer than 8
+
+	code, body = request(http.MethodGet, "/users/13/posts/hello", p)
+	Equal(t, code, http.StatusOK)
+	Equal(t, body, "/users/:id(\\d+)/posts/:slug([a-z-]+)|13|hello")
+
+	code, _ = request(http.MethodGet, "/users/13/posts/a_b", p)
+	Equal(t, code, http.StatusNotFound)
+
+	code, _ = request(http.MethodGet, "/codes/DE", p)
+	Equal(t, code, http.StatusOK)
+
+	code, _ = request(http.MethodGet, "/codes/x", p)
+	Equal(t, code, http.StatusOK)
+
+	code, _ = request(http.MethodGet, "/codes/DEU", p)
+	Equal(t, code, http.StatusNotFound)
+
+	code, _ = request(http.MethodGet, "/tags/golang", p)
+	Equal(t, code, http.StatusOK)
+
+	code, _ = request(http.MethodGet, "/tags/golang-generics", p)
+	Equal(t, code, http.StatusNotFound)
+}
+
+func TestBadParamConstraints(t *testing.T) {
+	p := New()
+	p.Get("/users/:id(\\d+)", defaultHandler)
+	PanicMatches(t, func() { p.Get("/users/:id", defaultHandler) }, "path segment ':id' conflicts with existing wildcard ':id(\\d+)' in path '/users/:id'")
+	PanicMatches(t, func() { p.Get("/users/:id([a-z]+)", defaultHandler) }, "path segment ':id([a-z]+)' conflicts with existing wildcard ':id(\\d+)' in path '/users/:id([a-z]+)'")
+	PanicMatches(t, func() { p.Get("/store/:id([0-9]", defaultHandler) }, "unterminated constraint in path '/store/:id([0-9]'")
+	PanicMatches(t, func() { p.Get("/store/:id(a/b)", defaultHandler) }, "constraint must not contain '/' in path '/store/:id(a/b)'")
+	PanicMatches(t, func() { p.Get("/store/:id([)", defaultHandler) }, "invalid constraint '[' in path '/store/:id([)': error parsing regexp: missing closing ]: `[)$`")
+	PanicMatches(t, func() { p.Get("/dup/:id(\\d+)/:id(\\d+)", defaultHandler) }, "Duplicate param name ':id' detected for route '/dup/:id(\\d+)/:id(\\d+)'")
+}
+
+func TestCatchAllFallback(t *testing.T) {
+	p := New()
+	p.SetCatchAllFallback(true)
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		rv := RequestVars(r)
+		_, _ = w.Write([]byte(rv.Route() + " " + rv.URLParam(WildcardParam) + rv.URLParam("id")))
+	}
+	p.Get("/files/*", fn)
+	p.Get("/files/health", fn)
+	p.Get("/files/", fn)
+	p.Get("/files/users/:id(\\d+)", fn)
+	p.Get("/files/users/:id(\\d+)/*", fn)
+	p.Get("/", fn)
+	p.Get("/*", fn)
+	PanicMatches(t, func() { p.Get("/files/*", fn) }, "handlers are already registered for path '/files/*'")
+
+	tests := []struct {
+		path string
+		body string
+	}{
+		{path: "/files/health", body: "/files/health "},
+		{path: "/files/", body: "/files/ "},
+		{path: "/files/healthz", body: "/files/* healthz"},
+		{path: "/files/a/b.css", body: "/files/* a/b.css"},
+		{path: "/files/users/13", body: "/files/users/:id(\\d+) 13"},
+		// the constraint doesn't match
+		{path: "/files/users/me", body: "/files/* users/me"},
+		{path: "/files/users/13/avatar.png", body: "/files/users/:id(\\d+)/* avatar.png13"},
+		{path: "/", body: "/ "},
+		{path: "/index.html", body: "/* index.html"},
+	}
+
+	for _, tt := range tests {
+		code, body := request(http.MethodGet, tt.path, p)
+		Equal(t, code, http.StatusOK)
+		Equal(t, body, tt.body)
+	}
+
+	// disabled
+	p = New()
+	p.Get("/files/health", fn)
+	PanicMatches(t, func() { p.Get("/files/*", fn) }, "wildcard route '*' conflicts with existing children in path '/files/*'")
+	p.Get("/assets/*", fn)
+	PanicMatches(t, func() { p.Get("/assets/app.js", fn) }, "path segment '/app.js' conflicts with existing wildcard '/*' in path '/assets/app.js'")
+	PanicMatches(t, func() { p.Get("/assets/", fn) }, "path segment '/' conflicts with existing wildcard '/*' in path '/assets/'")
+}
+
+func TestParamAndStaticSiblings(t *testing.T) {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		rv := RequestVars(r)
+		_, _ = w.Write([]byte(rv.Route() + " " + rv.URLParam("id") + rv.URLParam("lang")))
+	}
+
+	tests := []struct {
+		path string
+		body string
+	}{
+		{path: "/users/new", body: "/users/new "},
+		{path: "/users/13", body: "/users/:id 13"},
+		{path: "/users/newest", body: "/users/:id newest"},
+		{path: "/users/new/settings", body: "/users/new/settings "},
+		// the static route doesn't match the rest of the path
+		{path: "/users/new/profile", body: "/users/:id/profile new"},
+		{path: "/users/13/profile", body: "/users/:id/profile 13"},
+		{path: "/items/search", body: "/items/search "},
+		{path: "/items/42", body: "/items/:id(\\d+) 42"},
+		{path: "/api/status", body: "/api/status "},
+		{path: "/api/about", body: "/:lang/about api"},
+		{path: "/en/about", body: "/:lang/about en"},
+	}
+
+	routes := []string{
+		"/users/new",
+		"/users/:id",
+		"/users/new/settings",
+		"/users/:id/profile",
+		"/items/:id(\\d+)",
+		"/items/search",
+		"/:lang/about",
+		"/api/status",
+	}
+
+	// registration order doesn't matter
+	for _, reverse := range []bool{false, true} {
+		p := New()
+		for i := range routes {
+			if reverse {
+				i = len(routes) - 1 - i
+			}
+			p.Get(routes[i], fn)
+		}
+
+		for _, tt := range tests {
+			code, body := request(http.MethodGet, tt.path, p)
+			Equal(t, code, http.StatusOK)
+			Equal(t, body, tt.body)
+		}
+
+		code, _ := request(http.MethodGet, "/items/abc", p)
+		Equal(t, code, http.StatusNotFound)
+
+		code, _ = request(http.MethodGet, "/users/new/other", p)
+		Equal(t, code, http.StatusNotFound)
+	}
+
+	p := New()
+	p.Get("/users/:id", fn)
+	PanicMatches(t, func() { p.Get("/users/:name", fn) }, "path segment ':name' conflicts with existing wildcard ':id' in path '/users/:name'")
+}
+
+func TestParamsWithinSegment(t *testing.T) {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		rv := RequestVars(r)
+		_, _ = w.Write([]byte(rv.Route() + " " + rv.URLParam("name") + "|" + rv.URLParam("ext") + "|" + rv.URLParam("major") + "|" + rv.URLParam("minor") + "|" + rv.URLParam("id")))
+	}
+
+	p := New()
+	p.Get("/files/:name.:ext", fn)
+	p.Get("/files/:name", fn)
+	p.Get("/v:major.:minor/api", fn)
+	p.Get("/items/:id(\\d+).json", fn)
+	p.Get("/items/:id(\\d+)", fn)
+	p.Redirect("/old/:name.:ext", "/files/:name.:ext")
+	PanicMatches(t, func() { p.Get("/files/:name:ext", fn) }, "params must be separated by a static part, has: ':name:ext' in path '/files/:name:ext'")
+	PanicMatches(t, func() { p.Get("/files/:name.:id", fn) }, "path segment ':id' conflicts with existing wildcard ':ext' in path '/files/:name.:id'")
+
+	tests := []struct {
+		path string
+		body string
+	}{
+		{path: "/files/report.pdf", body: "/files/:name.:ext report|pdf|||"},
+		// the first param takes the longest value
+		{path: "/files/archive.tar.gz", body: "/files/:name.:ext archive.tar|gz|||"},
+		{path: "/files/README", body: "/files/:name README||||"},
+		// params within a segment must not be empty
+		{path: "/files/.env", body: "/files/:name .env||||"},
+		{path: "/files/report.", body: "/files/:name report.||||"},
+		{path: "/v1.2/api", body: "/v:major.:minor/api ||1|2|"},
+		{path: "/v10.0/api", body: "/v:major.:minor/api ||10|0|"},
+		{path: "/items/42.json", body: "/items/:id(\\d+).json ||||42"},
+		{path: "/items/42", body: "/items/:id(\\d+) ||||42"},
+	}
+
+	for _, tt := range tests {
+		code, body := request(http.MethodGet, tt.path, p)
+		Equal(t, code, http.StatusOK)
+		Equal(t, body, tt.body)
+	}
+
+	code, _ := request(http.MethodGet, "/v1/api", p)
+	Equal(t, code, http.StatusNotFound)
+
+	// the constraint doesn't match
+	code, _ = request(http.MethodGet, "/items/abc.json", p)
+	Equal(t, code, http.StatusNotFound)
+
+	r := httptest.NewRequest(http.MethodGet, "/old/report.pdf", nil)
+	w := httptest.NewRecorder()
+	p.Serve().ServeHTTP(w, r)
+	Equal(t, w.Code, http.StatusMovedPermanently)
+	Equal(t, w.Header().Get("Location"), "/files/report.pdf")
+}
+
+func TestServeSortsAndFreezes(t *testing.T) {
+	p := New()
+	p.Get("/about", defaultHandler)
+	p.Get("/users", defaultHandler)
+	p.Get("/users/:id", defaultHandler)
+	p.Get("/users/:id/posts", defaultHandler)
+	p.Host("api.example.com").Get("/a", defaultHandler)
+	p.Host("api.example.com").Get("/b/c", defaultHandler)
+	p.Host("api.example.com").Get("/b/d", defaultHandler)
+
+	// the children are ordered at registration
+	Equal(t, p.trees[http.MethodGet].indices, "au")
+	Equal(t, p.hostTrees["api.example.com"][http.MethodGet].indices, "ab")
+
+	p.Serve()
+	// the children of the most routes are compared first
+	Equal(t, p.trees[http.MethodGet].indices, "ua")
+	Equal(t, p.hostTrees["api.example.com"][http.MethodGet].indices, "ba")
+
+	code, body := request(http.MethodGet, "/users/13/posts", p)
+	Equal(t, code, http.StatusOK)
+	Equal(t, body, http.MethodGet)
+
+	PanicMatches(t, func() { p.Get("/posts", defaultHandler) }, "routes must be registered before Serve is called, path '/posts'")
+	PanicMatches(t, func() { p.Group("/v2").Post("/posts", defaultHandler) }, "routes must be registered before Serve is called, path '/v2/posts'")
+}
+
+func TestParamNames(t *testing.T) {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		rv := RequestVars(r)
+		_, _ = w.Write([]byte(rv.URLParam("user-id") + rv.URLParam("file.name")))
+	}
+
+	// names only end at a delimiter when another param follows within the segment
+	p := New()
+	p.Get("/u/:user-id", fn)
+	p.Get("/docs/:file.name", fn)
+
+	code, body := request(http.MethodGet, "/u/bob", p)
+	Equal(t, code, http.StatusOK)
+	Equal(t, body, "bob")
+
+	code, body = request(http.MethodGet, "/docs/guide.md", p)
+	Equal(t, code, http.StatusOK)
+	Equal(t, body, "guide.md")
+}
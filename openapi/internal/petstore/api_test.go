@@ -0,0 +1,103 @@
+package petstore_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pchchv/feather"
+	. "github.com/pchchv/feather/assert"
+	"github.com/pchchv/feather/openapi/internal/petstore"
+)
+
+type store struct {
+	pets []petstore.Pet
+}
+
+func (s *store) ListPets(_ context.Context, params *petstore.ListPetsParams) ([]petstore.Pet, error) {
+	pets := s.pets
+	if params.Limit > 0 && params.Limit < len(pets) {
+		pets = pets[:params.Limit]
+	}
+	return pets, nil
+}
+
+func (s *store) CreatePet(_ context.Context, body *petstore.NewPet) (*petstore.Pet, error) {
+	pet := petstore.Pet{ID: int64(len(s.pets) + 1), Name: body.Name, Kind: body.Kind, CreatedAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)}
+	s.pets = append(s.pets, pet)
+	return &pet, nil
+}
+
+func (s *store) GetPet(_ context.Context, params *petstore.GetPetParams) (*petstore.Pet, error) {
+	for i := range s.pets {
+		if s.pets[i].ID == params.PetID {
+			return &s.pets[i], nil
+		}
+	}
+	return nil, feather.NewHTTPError(http.StatusNotFound, "")
+}
+
+func (s *store) DeletePetsPetID(_ context.Context, params *petstore.DeletePetsPetIDParams) error {
+	if _, err := s.GetPet(context.Background(), &petstore.GetPetParams{PetID: params.PetID}); err != nil {
+		return err
+	}
+	s.pets = nil
+	return nil
+}
+
+func TestRegisterRoutes(t *testing.T) {
+	p := feather.New()
+	petstore.RegisterRoutes(p, &store{})
+	h := p.Serve()
+
+	do := func(method, path, body string) (int, string) {
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		r.Header.Set("Accept", "application/json")
+		if body != "" {
+			r.Header.Set("Content-Type", "application/json")
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w.Code, strings.TrimSpace(w.Body.String())
+	}
+
+	code, body := do(http.MethodPost, "/pets", `{"name": "Rex", "kind": "dog"}`)
+	Equal(t, code, http.StatusCreated)
+	Equal(t, body, `{"created_at":"2024-01-02T03:04:05Z","id":1,"kind":"dog","name":"Rex"}`)
+
+	code, _ = do(http.MethodPost, "/pets", `{"name": "Tom", "kind": "cat"}`)
+	Equal(t, code, http.StatusCreated)
+
+	code, body = do(http.MethodGet, "/pets?limit=1", "")
+	Equal(t, code, http.StatusOK)
+	Equal(t, body, `[{"created_at":"2024-01-02T03:04:05Z","id":1,"kind":"dog","name":"Rex"}]`)
+
+	code, body = do(http.MethodGet, "/pets/2", "")
+	Equal(t, code, http.StatusOK)
+	Equal(t, body, `{"created_at":"2024-01-02T03:04:05Z","id":2,"kind":"cat","name":"Tom"}`)
+
+	code, _ = do(http.MethodGet, "/pets/3", "")
+	Equal(t, code, http.StatusNotFound)
+
+	// validation
+	code, body = do(http.MethodPost, "/pets", `{"kind": "bird"}`)
+	Equal(t, code, http.StatusUnprocessableEntity)
+	Equal(t, strings.Contains(body, "name is required"), true)
+
+	code, _ = do(http.MethodGet, "/pets?limit=1000", "")
+	Equal(t, code, http.StatusUnprocessableEntity)
+
+	// decoding
+	code, _ = do(http.MethodPost, "/pets", `{"name": `)
+	Equal(t, code, http.StatusBadRequest)
+
+	code, _ = do(http.MethodGet, "/pets/rex", "")
+	Equal(t, code, http.StatusBadRequest)
+
+	code, body = do(http.MethodDelete, "/pets/1", "")
+	Equal(t, code, http.StatusNoContent)
+	Equal(t, body, "")
+}
@@ -0,0 +1,4 @@
+// Package petstore is generated from testdata/petstore.json, testing the generated code.
+package petstore
+
+//go:generate go run ../../../cmd/feather openapi -package petstore -o api.go ../../testdata/petstore.json
@@ -0,0 +1,797 @@
+// Package openapi generates feather route registrations, typed request and response structs
+// and the interface implementing the operations from OpenAPI 3 documents, for spec-first development.
+//
+// The generated RegisterRoutes decodes and validates the params and the JSON request body of every operation,
+// using feather.DecodeAndValidate and validate struct tags derived from the schemas, calls the interface
+// and writes its result as JSON with the status of the first 2xx response, errors are handled
+// by the Mux ErrorHandler, see feather.HandleErrors.
+//
+// Only JSON documents, path and query params, and JSON request and response bodies are supported,
+// header and cookie params are left to the implementation. Schemas combining others using oneOf or anyOf
+// are generated as interface{}.
+package openapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/format"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const (
+	defaultPackage     = "api"
+	defaultInterface   = "API"
+	defaultMaxBodySize = 1 << 20
+)
+
+// Config contains the generator settings.
+type Config struct {
+	// Package is the name of the generated package, default api.
+	Package string
+	// Interface is the name of the generated interface implementing the operations, default API.
+	Interface string
+	// MaxBodySize is the maximum size of the request bodies in bytes, default 1MB.
+	MaxBodySize int64
+}
+
+// Generate returns the gofmt-ed Go source generated from the OpenAPI 3 document spec, encoded as JSON.
+func Generate(spec []byte, cfg Config) ([]byte, error) {
+	if cfg.Package == "" {
+		cfg.Package = defaultPackage
+	}
+
+	if cfg.Interface == "" {
+		cfg.Interface = defaultInterface
+	}
+
+	if cfg.MaxBodySize <= 0 {
+		cfg.MaxBodySize = defaultMaxBodySize
+	}
+
+	var doc document
+	if err := json.Unmarshal(spec, &doc); err != nil {
+		return nil, fmt.Errorf("openapi: invalid document: %w", err)
+	}
+
+	if !strings.HasPrefix(doc.OpenAPI, "3.") {
+		return nil, errors.New("openapi: only OpenAPI 3 documents are supported")
+	}
+
+	g := &generator{
+		doc:     &doc,
+		cfg:     cfg,
+		types:   make(map[string]bool),
+		structs: make(map[string]bool),
+		inline:  make(map[string]*schema),
+		imports: map[string]bool{"github.com/pchchv/feather": true},
+	}
+
+	src, err := g.generate()
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := format.Source(src)
+	if err != nil {
+		return nil, fmt.Errorf("openapi: formatting the generated code: %w", err)
+	}
+
+	return out, nil
+}
+
+// route is a generated operation.
+type route struct {
+	method   string
+	path     string // feather route pattern
+	name     string
+	comment  string
+	params   string // name of the params struct, blank if none
+	body     string // type of the request body, blank if none
+	optional bool   // the request body is optional
+	result   string // type of the response body, blank if none
+	status   int
+}
+
+type generator struct {
+	doc     *document
+	cfg     Config
+	decls   strings.Builder // type declarations
+	types   map[string]bool // names of the declared types
+	structs map[string]bool // names of the struct types, passed and returned as pointers
+	inline  map[string]*schema
+	imports map[string]bool
+	err     error
+}
+
+func (g *generator) generate() ([]byte, error) {
+	names := make([]string, 0, len(g.doc.Components.Schemas))
+	for name, s := range g.doc.Components.Schemas {
+		names = append(names, name)
+		if g.isObject(s) {
+			g.structs[goName(name)] = true
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		g.declare(goName(name), g.doc.Components.Schemas[name])
+	}
+
+	routes := g.routes()
+	if g.err != nil {
+		return nil, g.err
+	}
+
+	var b strings.Builder
+	b.WriteString("// Code generated by feather openapi. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&b, "package %s\n\n", g.cfg.Package)
+	if len(routes) > 0 {
+		g.imports["context"] = true
+		g.imports["errors"] = true
+		g.imports["net/http"] = true
+	}
+
+	// the standard library imports are grouped first
+	var std, other []string
+	for imp := range g.imports {
+		if strings.Contains(imp, ".") {
+			other = append(other, imp)
+		} else {
+			std = append(std, imp)
+		}
+	}
+	sort.Strings(std)
+	sort.Strings(other)
+
+	b.WriteString("import (\n")
+	for _, imp := range std {
+		fmt.Fprintf(&b, "%q\n", imp)
+	}
+	b.WriteString("\n")
+	for _, imp := range other {
+		fmt.Fprintf(&b, "%q\n", imp)
+	}
+	b.WriteString(")\n\n")
+
+	fmt.Fprintf(&b, "// maxBodySize is the maximum size of the request bodies in bytes.\nconst maxBodySize = %d\n\n", g.cfg.MaxBodySize)
+	g.writeInterface(&b, routes)
+	g.writeRegister(&b, routes)
+	b.WriteString(g.decls.String())
+	if len(routes) > 0 {
+		b.WriteString(requestErrorFunc)
+	}
+
+	return []byte(b.String()), nil
+}
+
+// routes declares the params and bodies of the operations and returns them sorted by path and method.
+func (g *generator) routes() []route {
+	paths := make([]string, 0, len(g.doc.Paths))
+	for path := range g.doc.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var routes []route
+	seen := make(map[string]bool)
+	for _, path := range paths {
+		item := g.doc.Paths[path]
+		if item == nil {
+			continue
+		}
+
+		for _, mo := range item.operations() {
+			op := mo.op
+			rt := route{method: mo.method, path: routePath(path), comment: op.Summary}
+			if op.OperationID != "" {
+				rt.name = goName(op.OperationID)
+			} else {
+				rt.name = goName(strings.ToLower(mo.method) + " " + path)
+			}
+
+			if seen[rt.name] {
+				g.fail(fmt.Errorf("openapi: duplicate operation %s", rt.name))
+				continue
+			}
+			seen[rt.name] = true
+
+			rt.params = g.declareParams(rt.name, item.Parameters, op.Parameters)
+			if rb := g.requestBody(op.RequestBody); rb != nil {
+				if s := jsonContent(rb.Content); s != nil {
+					rt.body = g.goType(s, rt.name+"Body")
+					rt.optional = !rb.Required
+				}
+			}
+
+			rt.status, rt.result = g.result(rt.name, op.Responses)
+			routes = append(routes, rt)
+		}
+	}
+
+	return routes
+}
+
+// declareParams declares the struct of the path and query params of an operation, the params
+// of the operation override those of the path item, and returns its name, blank if there are none.
+func (g *generator) declareParams(name string, common []*parameter, params []*parameter) string {
+	var all []*parameter
+	index := make(map[string]int)
+	for _, p := range append(append([]*parameter(nil), common...), params...) {
+		if p = g.parameter(p); p == nil || (p.In != "path" && p.In != "query") {
+			continue
+		}
+
+		key := p.In + " " + p.Name
+		if i, ok := index[key]; ok {
+			all[i] = p
+			continue
+		}
+
+		index[key] = len(all)
+		all = append(all, p)
+	}
+
+	if len(all) == 0 {
+		return ""
+	}
+
+	name += "Params"
+	var fields strings.Builder
+	for _, p := range all {
+		s := p.Schema
+		if s == nil {
+			s = &schema{Type: "string"}
+		}
+
+		required := p.Required || p.In == "path"
+		typ := g.goType(s, name+goName(p.Name))
+		writeComment(&fields, p.Description, "\t")
+		fmt.Fprintf(&fields, "\t%s %s `form:%q json:%q%s`\n", goName(p.Name), typ, p.Name, p.Name, validateTag(g.resolve(s), typ, required))
+	}
+
+	g.declareType(name, "are the path and query params of "+strings.TrimSuffix(name, "Params")+".", "struct {\n"+fields.String()+"}")
+	g.structs[name] = true
+	return name
+}
+
+// result returns the status of the first 2xx response and the type of its JSON body, blank if none.
+func (g *generator) result(name string, responses map[string]*response) (int, string) {
+	codes := make([]string, 0, len(responses))
+	for code := range responses {
+		if len(code) == 3 && code[0] == '2' {
+			codes = append(codes, code)
+		}
+	}
+
+	if len(codes) == 0 {
+		return http.StatusOK, ""
+	}
+
+	sort.Strings(codes)
+	status, err := strconv.Atoi(codes[0])
+	if err != nil {
+		// 2XX ranges
+		status = http.StatusOK
+	}
+
+	res := g.response(responses[codes[0]])
+	if res == nil {
+		return status, ""
+	}
+
+	s := jsonContent(res.Content)
+	if s == nil {
+		return status, ""
+	}
+
+	return status, g.goType(s, name+"Response")
+}
+
+// goType returns the Go type of the schema, declaring the types of inline objects using name.
+func (g *generator) goType(s *schema, name string) string {
+	if s == nil {
+		return "interface{}"
+	}
+
+	if s.Ref != "" {
+		if !strings.HasPrefix(s.Ref, "#/components/schemas/") {
+			g.fail(fmt.Errorf("openapi: unsupported reference %s", s.Ref))
+			return "interface{}"
+		}
+
+		return goName(refName(s.Ref))
+	}
+
+	if len(s.AllOf) == 1 {
+		return g.goType(s.AllOf[0], name)
+	}
+
+	switch {
+	case g.isObject(s):
+		// the inline objects of schemas merged using allOf are declared once
+		if g.inline[name] != s {
+			g.inline[name] = s
+			g.declare(name, s)
+		}
+		return name
+	case s.Type == "object" || (s.Type == "" && len(s.AdditionalProperties) > 0):
+		if a := s.additional(); a != nil {
+			return "map[string]" + g.goType(a, name+"Value")
+		}
+		return "map[string]interface{}"
+	case s.Type == "array":
+		return "[]" + g.goType(s.Items, name+"Item")
+	case s.Type == "string":
+		switch s.Format {
+		case "date-time":
+			g.imports["time"] = true
+			return "time.Time"
+		case "byte":
+			return "[]byte"
+		}
+		return "string"
+	case s.Type == "integer":
+		switch s.Format {
+		case "int32":
+			return "int32"
+		case "int64":
+			return "int64"
+		}
+		return "int"
+	case s.Type == "number":
+		if s.Format == "float" {
+			return "float32"
+		}
+		return "float64"
+	case s.Type == "boolean":
+		return "bool"
+	}
+
+	return "interface{}"
+}
+
+// isObject reports whether s is an object with properties, or combines such objects using allOf,
+// which are generated as structs.
+func (g *generator) isObject(s *schema) bool {
+	if len(s.Properties) > 0 {
+		return true
+	}
+
+	if len(s.AllOf) < 2 {
+		return false
+	}
+
+	for _, part := range s.AllOf {
+		if part = g.resolve(part); part == nil || len(part.Properties) == 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// declare declares the named type of a component or inline schema.
+func (g *generator) declare(name string, s *schema) {
+	if !g.isObject(s) {
+		g.declareType(name, "", g.goType(s, name+"Value"))
+		return
+	}
+
+	// the properties merged using allOf keep the type names of their schemas
+	type property struct {
+		s     *schema
+		owner string
+	}
+
+	g.structs[name] = true
+	properties := make(map[string]property)
+	var required []string
+	for _, part := range append([]*schema{s}, s.AllOf...) {
+		owner := name
+		if part.Ref != "" {
+			owner = goName(refName(part.Ref))
+		}
+
+		if part = g.resolve(part); part != nil {
+			for prop, ps := range part.Properties {
+				properties[prop] = property{s: ps, owner: owner}
+			}
+			required = append(required, part.Required...)
+		}
+	}
+
+	props := make([]string, 0, len(properties))
+	for prop := range properties {
+		props = append(props, prop)
+	}
+	sort.Strings(props)
+
+	var fields strings.Builder
+	for _, prop := range props {
+		ps := properties[prop].s
+		isRequired := contains(required, prop)
+		typ := g.goType(ps, properties[prop].owner+goName(prop))
+		if !isRequired && g.structs[typ] {
+			typ = "*" + typ
+		}
+
+		omitempty := ""
+		if !isRequired {
+			omitempty = ",omitempty"
+		}
+
+		writeComment(&fields, g.resolve(ps).Description, "\t")
+		fmt.Fprintf(&fields, "\t%s %s `json:\"%s%s\"%s`\n", goName(prop), typ, prop, omitempty, validateTag(g.resolve(ps), typ, isRequired))
+	}
+
+	g.declareType(name, s.Description, "struct {\n"+fields.String()+"}")
+}
+
+// declareType writes the declaration of the type, failing if the name is already declared.
+func (g *generator) declareType(name string, doc string, typ string) {
+	if g.types[name] {
+		g.fail(fmt.Errorf("openapi: type %s is declared twice", name))
+		return
+	}
+	g.types[name] = true
+
+	if doc == "" {
+		doc = "is generated from the schema of the same name."
+		if !g.isComponent(name) {
+			doc = "is generated from an inline schema."
+		}
+	}
+
+	if strings.HasPrefix(doc, "are ") || strings.HasPrefix(doc, "is ") {
+		doc = name + " " + doc
+	}
+
+	writeComment(&g.decls, doc, "")
+	fmt.Fprintf(&g.decls, "type %s %s\n\n", name, typ)
+}
+
+func (g *generator) isComponent(name string) bool {
+	for n := range g.doc.Components.Schemas {
+		if goName(n) == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// resolve returns the schema referenced by s, or s if it is not a reference.
+func (g *generator) resolve(s *schema) *schema {
+	for i := 0; s != nil && s.Ref != "" && i < 16; i++ {
+		s = g.doc.Components.Schemas[refName(s.Ref)]
+	}
+
+	return s
+}
+
+func (g *generator) parameter(p *parameter) *parameter {
+	if p != nil && p.Ref != "" {
+		return g.doc.Components.Parameters[refName(p.Ref)]
+	}
+
+	return p
+}
+
+func (g *generator) requestBody(rb *requestBody) *requestBody {
+	if rb != nil && rb.Ref != "" {
+		return g.doc.Components.RequestBodies[refName(rb.Ref)]
+	}
+
+	return rb
+}
+
+func (g *generator) response(r *response) *response {
+	if r != nil && r.Ref != "" {
+		return g.doc.Components.Responses[refName(r.Ref)]
+	}
+
+	return r
+}
+
+func (g *generator) fail(err error) {
+	if g.err == nil {
+		g.err = err
+	}
+}
+
+// writeInterface writes the interface implementing the operations.
+func (g *generator) writeInterface(b *strings.Builder, routes []route) {
+	fmt.Fprintf(b, "// %s implements the operations of the API.\ntype %s interface {\n", g.cfg.Interface, g.cfg.Interface)
+	for _, rt := range routes {
+		comment := rt.comment
+		if comment == "" {
+			comment = "handles " + rt.method + " " + rt.path + "."
+		}
+		writeComment(b, rt.name+" "+lowerFirst(comment), "\t")
+		fmt.Fprintf(b, "\t%s(%s) %s\n", rt.name, g.signature(rt), g.results(rt))
+	}
+	b.WriteString("}\n\n")
+}
+
+func (g *generator) signature(rt route) string {
+	args := []string{"ctx context.Context"}
+	if rt.params != "" {
+		args = append(args, "params *"+rt.params)
+	}
+
+	if rt.body != "" {
+		args = append(args, "body *"+rt.body)
+	}
+
+	return strings.Join(args, ", ")
+}
+
+func (g *generator) results(rt route) string {
+	switch {
+	case rt.result == "":
+		return "error"
+	case g.structs[rt.result]:
+		return "(*" + rt.result + ", error)"
+	default:
+		return "(" + rt.result + ", error)"
+	}
+}
+
+// writeRegister writes RegisterRoutes registering a handler per operation.
+func (g *generator) writeRegister(b *strings.Builder, routes []route) {
+	b.WriteString("// RegisterRoutes registers the operations implemented by impl on the routes.\n")
+	fmt.Fprintf(b, "func RegisterRoutes(routes feather.IRoutes, impl %s) {\n", g.cfg.Interface)
+	for i, rt := range routes {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+
+		args := []string{"r.Context()"}
+		fmt.Fprintf(b, "\troutes.%s(%q, feather.HandleErrors(func(w http.ResponseWriter, r *http.Request) error {\n", methodFuncs[rt.method], rt.path)
+		if rt.params != "" {
+			fmt.Fprintf(b, "\t\tvar params %s\n", rt.params)
+			b.WriteString("\t\tif err := feather.DecodeQueryParams(r, feather.IncludeQueryParams, &params); err != nil {\n\t\t\treturn requestError(err)\n\t\t}\n\n")
+			b.WriteString("\t\tif err := feather.DefaultValidator.Validate(&params); err != nil {\n\t\t\treturn requestError(err)\n\t\t}\n\n")
+			args = append(args, "&params")
+		}
+
+		if rt.body != "" {
+			fmt.Fprintf(b, "\t\tvar body %s\n", rt.body)
+			if rt.optional {
+				// an empty optional body is not decoded
+				b.WriteString("\t\tif r.ContentLength != 0 {\n\t\t\tif err := feather.DecodeAndValidate(r, feather.NoQueryParams, maxBodySize, &body); err != nil {\n\t\t\t\treturn requestError(err)\n\t\t\t}\n\t\t}\n\n")
+			} else {
+				b.WriteString("\t\tif err := feather.DecodeAndValidate(r, feather.NoQueryParams, maxBodySize, &body); err != nil {\n\t\t\treturn requestError(err)\n\t\t}\n\n")
+			}
+			args = append(args, "&body")
+		}
+
+		if rt.result == "" {
+			fmt.Fprintf(b, "\t\tif err := impl.%s(%s); err != nil {\n\t\t\treturn err\n\t\t}\n\n", rt.name, strings.Join(args, ", "))
+			fmt.Fprintf(b, "\t\tw.WriteHeader(%s)\n\t\treturn nil\n", statusConst(rt.status))
+		} else {
+			fmt.Fprintf(b, "\t\tres, err := impl.%s(%s)\n\t\tif err != nil {\n\t\t\treturn err\n\t\t}\n\n", rt.name, strings.Join(args, ", "))
+			fmt.Fprintf(b, "\t\treturn feather.JSON(w, %s, res)\n", statusConst(rt.status))
+		}
+		b.WriteString("\t}))\n")
+	}
+	b.WriteString("}\n\n")
+}
+
+// methodFuncs maps the HTTP methods to the feather.IRoutes methods registering their routes.
+var methodFuncs = map[string]string{
+	http.MethodGet: "Get", http.MethodHead: "Head", http.MethodPost: "Post", http.MethodPut: "Put",
+	http.MethodPatch: "Patch", http.MethodDelete: "Delete", http.MethodOptions: "Options", http.MethodTrace: "Trace",
+}
+
+const requestErrorFunc = `// requestError maps the errors decoding and validating requests to 400 Bad Request and 422 Unprocessable Entity.
+func requestError(err error) error {
+	var errs feather.ValidationErrors
+	if errors.As(err, &errs) {
+		return feather.NewHTTPError(http.StatusUnprocessableEntity, errs.Error())
+	}
+
+	return feather.NewHTTPError(http.StatusBadRequest, "").WithInternal(err)
+}
+`
+
+// statusConst returns the net/http constant of the status code, i.e. http.StatusCreated.
+func statusConst(status int) string {
+	if name, ok := statusNames[status]; ok {
+		return "http.Status" + name
+	}
+
+	return strconv.Itoa(status)
+}
+
+var statusNames = map[int]string{
+	http.StatusOK:                   "OK",
+	http.StatusCreated:              "Created",
+	http.StatusAccepted:             "Accepted",
+	http.StatusNonAuthoritativeInfo: "NonAuthoritativeInfo",
+	http.StatusNoContent:            "NoContent",
+	http.StatusResetContent:         "ResetContent",
+	http.StatusPartialContent:       "PartialContent",
+}
+
+// validateTag returns the validate struct tag, including the leading space, of a field of the type.
+// Numbers and booleans are never required, since their zero values are valid.
+func validateTag(s *schema, typ string, required bool) string {
+	var rules []string
+	if required && typ != "bool" && !isNumeric(typ) {
+		rules = append(rules, "required")
+	}
+
+	if s != nil {
+		switch {
+		case typ == "string":
+			if s.MinLength != nil {
+				rules = append(rules, "min="+strconv.Itoa(*s.MinLength))
+			}
+			if s.MaxLength != nil {
+				rules = append(rules, "max="+strconv.Itoa(*s.MaxLength))
+			}
+			if values := enumValues(s.Enum); values != "" {
+				rules = append(rules, "oneof="+values)
+			}
+			switch s.Format {
+			case "email":
+				rules = append(rules, "email")
+			case "uri", "url":
+				rules = append(rules, "url")
+			}
+		case isNumeric(typ):
+			if s.Minimum != nil {
+				rules = append(rules, "min="+strconv.FormatFloat(*s.Minimum, 'f', -1, 64))
+			}
+			if s.Maximum != nil {
+				rules = append(rules, "max="+strconv.FormatFloat(*s.Maximum, 'f', -1, 64))
+			}
+		case strings.HasPrefix(typ, "[]"):
+			if s.MinItems != nil {
+				rules = append(rules, "min="+strconv.Itoa(*s.MinItems))
+			}
+			if s.MaxItems != nil {
+				rules = append(rules, "max="+strconv.Itoa(*s.MaxItems))
+			}
+		}
+	}
+
+	if len(rules) == 0 {
+		return ""
+	}
+
+	return ` validate:"` + strings.Join(rules, ",") + `"`
+}
+
+func isNumeric(typ string) bool {
+	switch typ {
+	case "int", "int32", "int64", "float32", "float64":
+		return true
+	}
+
+	return false
+}
+
+// enumValues returns the space separated values of a string enum, blank if they can't be validated using oneof.
+func enumValues(enum []interface{}) string {
+	values := make([]string, 0, len(enum))
+	for _, v := range enum {
+		s, ok := v.(string)
+		if !ok || s == "" || strings.ContainsAny(s, " ,\"`") {
+			return ""
+		}
+		values = append(values, s)
+	}
+
+	return strings.Join(values, " ")
+}
+
+// routePath converts the OpenAPI path template to a feather route pattern, i.e. /users/{id} to /users/:id.
+func routePath(path string) string {
+	var b strings.Builder
+	for {
+		i := strings.IndexByte(path, '{')
+		j := strings.IndexByte(path, '}')
+		if i == -1 || j < i {
+			b.WriteString(path)
+			return b.String()
+		}
+
+		b.WriteString(path[:i])
+		b.WriteByte(':')
+		b.WriteString(path[i+1 : j])
+		path = path[j+1:]
+	}
+}
+
+// initialisms are written all uppercase in Go names.
+var initialisms = map[string]bool{
+	"API": true, "CSS": true, "DNS": true, "HTML": true, "HTTP": true, "HTTPS": true, "ID": true, "IP": true,
+	"JSON": true, "SQL": true, "TLS": true, "UI": true, "URI": true, "URL": true, "UUID": true, "XML": true,
+}
+
+// goName returns the exported Go name of s, i.e. UserID for user_id or userId.
+func goName(s string) string {
+	var words []string
+	runes := []rune(s)
+	start := -1
+	for i, c := range runes {
+		switch {
+		case !unicode.IsLetter(c) && !unicode.IsDigit(c):
+			if start != -1 {
+				words = append(words, string(runes[start:i]))
+				start = -1
+			}
+		case start == -1:
+			start = i
+		case unicode.IsUpper(c) && unicode.IsLower(runes[i-1]):
+			words = append(words, string(runes[start:i]))
+			start = i
+		}
+	}
+
+	if start != -1 {
+		words = append(words, string(runes[start:]))
+	}
+
+	var b strings.Builder
+	for _, w := range words {
+		if upper := strings.ToUpper(w); initialisms[upper] {
+			b.WriteString(upper)
+		} else {
+			r := []rune(w)
+			b.WriteString(string(unicode.ToUpper(r[0])) + string(r[1:]))
+		}
+	}
+
+	name := b.String()
+	if name == "" || unicode.IsDigit([]rune(name)[0]) {
+		name = "X" + name
+	}
+
+	return name
+}
+
+func lowerFirst(s string) string {
+	if s == "" {
+		return s
+	}
+
+	r := []rune(s)
+	if len(r) > 1 && unicode.IsUpper(r[1]) {
+		// initialisms such as API
+		return s
+	}
+
+	return string(unicode.ToLower(r[0])) + string(r[1:])
+}
+
+func writeComment(b *strings.Builder, text string, indent string) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return
+	}
+
+	for _, line := range strings.Split(text, "\n") {
+		b.WriteString(indent)
+		b.WriteString("// ")
+		b.WriteString(strings.TrimRight(line, " \t"))
+		b.WriteString("\n")
+	}
+}
+
+func contains(values []string, v string) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,72 @@
+package openapi
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	. "github.com/pchchv/feather/assert"
+)
+
+func TestGenerate(t *testing.T) {
+	spec, err := os.ReadFile("testdata/petstore.json")
+	Equal(t, err, nil)
+
+	// internal/petstore is generated from the spec, see go generate
+	expected, err := os.ReadFile("internal/petstore/api.go")
+	Equal(t, err, nil)
+
+	src, err := Generate(spec, Config{Package: "petstore"})
+	Equal(t, err, nil)
+	Equal(t, string(src), string(expected))
+
+	src, err = Generate(spec, Config{Package: "pets", Interface: "Service", MaxBodySize: 1024})
+	Equal(t, err, nil)
+	Equal(t, strings.Contains(string(src), "package pets\n"), true)
+	Equal(t, strings.Contains(string(src), "func RegisterRoutes(routes feather.IRoutes, impl Service) {"), true)
+	Equal(t, strings.Contains(string(src), "const maxBodySize = 1024\n"), true)
+}
+
+func TestGenerateErrors(t *testing.T) {
+	tests := []struct {
+		spec string
+		err  string
+	}{
+		{spec: `{`, err: "openapi: invalid document: unexpected end of JSON input"},
+		{spec: `{"swagger": "2.0"}`, err: "openapi: only OpenAPI 3 documents are supported"},
+		{
+			spec: `{"openapi": "3.1.0", "paths": {"/a": {"get": {"operationId": "op"}}, "/b": {"get": {"operationId": "op"}}}}`,
+			err:  "openapi: duplicate operation Op",
+		},
+		{
+			spec: `{"openapi": "3.1.0", "components": {"schemas": {"A": {"$ref": "other.json#/A"}}}}`,
+			err:  "openapi: unsupported reference other.json#/A",
+		},
+	}
+
+	for _, tt := range tests {
+		_, err := Generate([]byte(tt.spec), Config{})
+		NotEqual(t, err, nil)
+		Equal(t, err.Error(), tt.err)
+	}
+}
+
+func TestGoName(t *testing.T) {
+	tests := map[string]string{
+		"user_id":        "UserID",
+		"userId":         "UserID",
+		"get /pets/{id}": "GetPetsID",
+		"api-key":        "APIKey",
+		"HTMLContent":    "HTMLContent",
+		"2fa":            "X2fa",
+	}
+
+	for s, expected := range tests {
+		Equal(t, goName(s), expected)
+	}
+}
+
+func TestRoutePath(t *testing.T) {
+	Equal(t, routePath("/pets/{pet_id}/toys/{toy}"), "/pets/:pet_id/toys/:toy")
+	Equal(t, routePath("/pets"), "/pets")
+}
@@ -0,0 +1,148 @@
+package openapi
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+)
+
+// document is the subset of an OpenAPI 3 document used by Generate.
+type document struct {
+	OpenAPI    string               `json:"openapi"`
+	Paths      map[string]*pathItem `json:"paths"`
+	Components struct {
+		Schemas       map[string]*schema      `json:"schemas"`
+		Parameters    map[string]*parameter   `json:"parameters"`
+		RequestBodies map[string]*requestBody `json:"requestBodies"`
+		Responses     map[string]*response    `json:"responses"`
+	} `json:"components"`
+}
+
+type pathItem struct {
+	Parameters []*parameter `json:"parameters"`
+	Get        *operation   `json:"get"`
+	Put        *operation   `json:"put"`
+	Post       *operation   `json:"post"`
+	Delete     *operation   `json:"delete"`
+	Options    *operation   `json:"options"`
+	Head       *operation   `json:"head"`
+	Patch      *operation   `json:"patch"`
+	Trace      *operation   `json:"trace"`
+}
+
+// methodOperation is an operation and the HTTP method it is declared for.
+type methodOperation struct {
+	method string
+	op     *operation
+}
+
+// operations returns the operations of the path item in a stable order.
+func (p *pathItem) operations() []methodOperation {
+	all := []methodOperation{
+		{"GET", p.Get}, {"HEAD", p.Head}, {"POST", p.Post}, {"PUT", p.Put},
+		{"PATCH", p.Patch}, {"DELETE", p.Delete}, {"OPTIONS", p.Options}, {"TRACE", p.Trace},
+	}
+
+	ops := all[:0]
+	for _, o := range all {
+		if o.op != nil {
+			ops = append(ops, o)
+		}
+	}
+
+	return ops
+}
+
+type operation struct {
+	OperationID string               `json:"operationId"`
+	Summary     string               `json:"summary"`
+	Description string               `json:"description"`
+	Parameters  []*parameter         `json:"parameters"`
+	RequestBody *requestBody         `json:"requestBody"`
+	Responses   map[string]*response `json:"responses"`
+}
+
+type parameter struct {
+	Ref         string  `json:"$ref"`
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description"`
+	Required    bool    `json:"required"`
+	Schema      *schema `json:"schema"`
+}
+
+type requestBody struct {
+	Ref      string                `json:"$ref"`
+	Required bool                  `json:"required"`
+	Content  map[string]*mediaType `json:"content"`
+}
+
+type response struct {
+	Ref         string                `json:"$ref"`
+	Description string                `json:"description"`
+	Content     map[string]*mediaType `json:"content"`
+}
+
+type mediaType struct {
+	Schema *schema `json:"schema"`
+}
+
+type schema struct {
+	Ref                  string             `json:"$ref"`
+	Type                 string             `json:"type"`
+	Format               string             `json:"format"`
+	Description          string             `json:"description"`
+	Properties           map[string]*schema `json:"properties"`
+	Required             []string           `json:"required"`
+	Items                *schema            `json:"items"`
+	AdditionalProperties json.RawMessage    `json:"additionalProperties"`
+	AllOf                []*schema          `json:"allOf"`
+	Enum                 []interface{}      `json:"enum"`
+	Minimum              *float64           `json:"minimum"`
+	Maximum              *float64           `json:"maximum"`
+	MinLength            *int               `json:"minLength"`
+	MaxLength            *int               `json:"maxLength"`
+	MinItems             *int               `json:"minItems"`
+	MaxItems             *int               `json:"maxItems"`
+}
+
+// additional returns the schema of the additionalProperties, if they are a schema.
+func (s *schema) additional() *schema {
+	if len(s.AdditionalProperties) == 0 || s.AdditionalProperties[0] != '{' {
+		return nil
+	}
+
+	var a schema
+	if json.Unmarshal(s.AdditionalProperties, &a) != nil {
+		return nil
+	}
+
+	return &a
+}
+
+// refName returns the name of the component referenced by ref, i.e. User for #/components/schemas/User.
+func refName(ref string) string {
+	return ref[strings.LastIndexByte(ref, '/')+1:]
+}
+
+// jsonContent returns the schema of the JSON media type of content, if any,
+// preferring application/json over structured syntax suffixes such as application/problem+json.
+func jsonContent(content map[string]*mediaType) *schema {
+	if mt := content["application/json"]; mt != nil && mt.Schema != nil {
+		return mt.Schema
+	}
+
+	names := make([]string, 0, len(content))
+	for name := range content {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if mt := content[name]; strings.HasSuffix(name, "+json") && mt != nil && mt.Schema != nil {
+			return mt.Schema
+		}
+	}
+
+	return nil
+}
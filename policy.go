@@ -0,0 +1,97 @@
+package feather
+
+import (
+	"net/http"
+	"slices"
+)
+
+// Policy declares the protections of a group's endpoints in one place: the required authentication,
+// the roles allowed, the rate limit tier and the request body limit. Zero values are unset.
+type Policy struct {
+	// Name identifies the policy in the RouteInfo of the routes, so the protections of every endpoint
+	// can be audited using Routes or the route manifest.
+	Name string
+	// Authenticate is the middleware of the required authentication scheme, i.e. the one returned by jwt.New,
+	// answering requests without valid credentials itself.
+	Authenticate Middleware
+	// Roles are the roles allowed, requests of principals without any of them are answered with 403 Forbidden.
+	Roles []string
+	// RolesOf returns the roles of the authenticated principal, it is required when Roles are set.
+	RolesOf func(r *http.Request) []string
+	// RateLimit is the middleware of the rate limit tier, i.e. one returned by ratelimit.New,
+	// it runs after authentication so the limits can be keyed by principal.
+	RateLimit Middleware
+	// MaxBodySize limits the number of bytes read from the request body, see Meta.
+	MaxBodySize int64
+}
+
+// middleware returns the middleware enforcing the policy in the order it runs:
+// authentication, authorization and rate limiting.
+func (pol Policy) middleware() []Middleware {
+	if len(pol.Roles) > 0 && pol.RolesOf == nil {
+		panic("policy '" + pol.Name + "' has Roles but no RolesOf")
+	}
+
+	var middleware []Middleware
+	if pol.Authenticate != nil {
+		middleware = append(middleware, pol.Authenticate)
+	}
+
+	if len(pol.Roles) > 0 {
+		middleware = append(middleware, requireRoles(pol.Roles, pol.RolesOf))
+	}
+
+	if pol.RateLimit != nil {
+		middleware = append(middleware, pol.RateLimit)
+	}
+
+	return middleware
+}
+
+// requireRoles answers requests of principals without any of the roles with 403 Forbidden.
+func requireRoles(roles []string, rolesOf func(r *http.Request) []string) Middleware {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			for _, role := range rolesOf(r) {
+				if slices.Contains(roles, role) {
+					next(w, r)
+					return
+				}
+			}
+
+			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		}
+	}
+}
+
+// WithPolicy returns a group with the same prefix whose routes are protected by the policy,
+// its middleware runs after the group middleware and the policies of enclosing groups, i.e.
+//
+//	admin := p.Group("/admin").WithPolicy(feather.Policy{
+//		Name:         "admin",
+//		Authenticate: jwt.New(jwtConfig),
+//		Roles:        []string{"admin"},
+//		RolesOf:      func(r *http.Request) []string { return jwt.FromRequest(r).Scopes() },
+//		RateLimit:    ratelimit.New(ratelimit.Config{Limit: ratelimit.Limit{Rate: 5, Burst: 10}}),
+//		MaxBodySize:  1 << 20,
+//	})
+//
+// The names of the policies are listed in the RouteInfo of the routes.
+// It panics if Roles are set without RolesOf.
+func (g *routeGroup) WithPolicy(policy Policy) IRouteGroup {
+	rg := &routeGroup{
+		prefix:     g.prefix,
+		host:       g.host,
+		feather:    g.feather,
+		middleware: make([]Middleware, len(g.middleware)),
+		meta:       g.meta.merge(Meta{MaxBodySize: policy.MaxBodySize}),
+		policies:   slices.Clone(g.policies),
+	}
+	copy(rg.middleware, g.middleware)
+	rg.Use(policy.middleware()...)
+	if policy.Name != blank {
+		rg.policies = append(rg.policies, policy.Name)
+	}
+
+	return rg
+}
@@ -0,0 +1,104 @@
+package feather
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	. "github.com/pchchv/feather/assert"
+)
+
+func TestPolicy(t *testing.T) {
+	var order []string
+	var readErr error
+	trace := func(name string) Middleware {
+		return func(next http.HandlerFunc) http.HandlerFunc {
+			return func(w http.ResponseWriter, r *http.Request) {
+				order = append(order, name)
+				next(w, r)
+			}
+		}
+	}
+
+	authenticate := func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			order = append(order, "authenticate")
+			if r.Header.Get("X-User") == "" {
+				http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+				return
+			}
+
+			next(w, r)
+		}
+	}
+
+	p := New()
+	p.Use(trace("global"))
+	admin := p.Group("/admin").WithPolicy(Policy{
+		Name:         "admin",
+		Authenticate: authenticate,
+		Roles:        []string{"admin", "owner"},
+		RolesOf:      func(r *http.Request) []string { return strings.Split(r.Header.Get("X-Roles"), ",") },
+		RateLimit:    trace("ratelimit"),
+		MaxBodySize:  4,
+	})
+	admin.Post("/users", func(w http.ResponseWriter, r *http.Request) {
+		_, readErr = io.ReadAll(r.Body)
+	}, trace("route"))
+	admin.Group("/audit").WithPolicy(Policy{Name: "audit", RateLimit: trace("audit")}).Get("/log", func(w http.ResponseWriter, r *http.Request) {})
+	p.Get("/public", func(w http.ResponseWriter, r *http.Request) {})
+	// neither the middleware nor the policies of the group apply
+	admin.GroupWithNone("/open").Get("", func(w http.ResponseWriter, r *http.Request) {})
+
+	hf := p.Serve()
+	do := func(method, path, user, roles string) int {
+		r := httptest.NewRequest(method, path, strings.NewReader("too large"))
+		if user != "" {
+			r.Header.Set("X-User", user)
+			r.Header.Set("X-Roles", roles)
+		}
+
+		w := httptest.NewRecorder()
+		hf.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	Equal(t, do(http.MethodPost, "/admin/users", "", ""), http.StatusUnauthorized)
+	Equal(t, order, []string{"global", "authenticate"})
+
+	order = nil
+	Equal(t, do(http.MethodPost, "/admin/users", "joe", "user"), http.StatusForbidden)
+	Equal(t, order, []string{"global", "authenticate"})
+
+	order = nil
+	Equal(t, do(http.MethodPost, "/admin/users", "ann", "user,owner"), http.StatusOK)
+	Equal(t, order, []string{"global", "authenticate", "ratelimit", "route"})
+	var maxBytesErr *http.MaxBytesError
+	Equal(t, errors.As(readErr, &maxBytesErr), true)
+
+	order = nil
+	Equal(t, do(http.MethodGet, "/admin/audit/log", "ann", "admin"), http.StatusOK)
+	Equal(t, order, []string{"global", "authenticate", "ratelimit", "audit"})
+
+	order = nil
+	Equal(t, do(http.MethodGet, "/public", "", ""), http.StatusOK)
+	Equal(t, order, []string{"global"})
+
+	order = nil
+	Equal(t, do(http.MethodGet, "/admin/open", "", ""), http.StatusOK)
+	Equal(t, len(order), 0)
+
+	policies := make(map[string][]string)
+	for _, route := range p.Routes() {
+		policies[route.Path] = route.Policies
+	}
+	Equal(t, policies["/admin/users"], []string{"admin"})
+	Equal(t, policies["/admin/audit/log"], []string{"admin", "audit"})
+	Equal(t, len(policies["/public"]), 0)
+	Equal(t, len(policies["/admin/open"]), 0)
+
+	PanicMatches(t, func() { p.Group("/x").WithPolicy(Policy{Name: "bad", Roles: []string{"admin"}}) }, "policy 'bad' has Roles but no RolesOf")
+}
@@ -0,0 +1,160 @@
+package feather
+
+import (
+	"expvar"
+	"net/url"
+	"sync"
+	"sync/atomic"
+)
+
+// PoolStats contains the counters of a pool.
+type PoolStats struct {
+	Gets uint64 `json:"gets"`
+	Puts uint64 `json:"puts"`
+	// News counts the objects allocated because the pool was empty.
+	News uint64 `json:"news"`
+	// Discards counts the objects not returned to the pool because they exceeded the maximum size.
+	Discards uint64 `json:"discards"`
+}
+
+// PoolCounters counts the operations of a pool, it is safe for concurrent use.
+type PoolCounters struct {
+	gets, puts, news, discards atomic.Uint64
+}
+
+// Get counts a get.
+func (c *PoolCounters) Get() {
+	c.gets.Add(1)
+}
+
+// Put counts a put.
+func (c *PoolCounters) Put() {
+	c.puts.Add(1)
+}
+
+// New counts an allocation.
+func (c *PoolCounters) New() {
+	c.news.Add(1)
+}
+
+// Discard counts a discarded object.
+func (c *PoolCounters) Discard() {
+	c.discards.Add(1)
+}
+
+// Stats returns a snapshot of the counters.
+func (c *PoolCounters) Stats() PoolStats {
+	return PoolStats{
+		Gets:     c.gets.Load(),
+		Puts:     c.puts.Load(),
+		News:     c.news.Load(),
+		Discards: c.discards.Load(),
+	}
+}
+
+// Publish publishes the counters as an expvar under name, it panics if the name is already registered.
+func (c *PoolCounters) Publish(name string) {
+	expvar.Publish(name, expvar.Func(func() any {
+		return c.Stats()
+	}))
+}
+
+// EnablePoolStats enables counting the operations of the requestVars pool, see PoolStats.
+// It is disabled by default to keep atomic operations off the hot path.
+func (p *Mux) EnablePoolStats() {
+	if p.poolCounters == nil {
+		p.poolCounters = new(PoolCounters)
+	}
+}
+
+// PoolStats returns the counters of the requestVars pool, zero unless enabled using EnablePoolStats.
+// Puts falling behind Gets while requests are not in flight indicate requestVars not being released.
+func (p *Mux) PoolStats() PoolStats {
+	if p.poolCounters == nil {
+		return PoolStats{}
+	}
+
+	return p.poolCounters.Stats()
+}
+
+// PublishPoolStats enables the pool stats and publishes them as an expvar under name,
+// it panics if the name is already registered.
+func (p *Mux) PublishPoolStats(name string) {
+	p.EnablePoolStats()
+	p.poolCounters.Publish(name)
+}
+
+// SetPoolMaxSize sets the maximum size of pooled requestVars, measured in URL param capacity
+// plus the number of values stored using ReqVars.Set,
+// larger requestVars are discarded instead of being returned to the pool.
+// 0, the default, means no limit.
+func (p *Mux) SetPoolMaxSize(size int) {
+	p.poolMaxSize = size
+}
+
+// getRequestVars gets requestVars from the pool.
+func (p *Mux) getRequestVars() *requestVars {
+	if p.poolCounters != nil {
+		p.poolCounters.Get()
+	}
+
+	return p.pool.Get().(*requestVars)
+}
+
+// SetPoolDebug enables poisoning requestVars once the request completed instead of returning them to the pool,
+// so using them afterwards, i.e. by retaining the ReqVars or the request in a goroutine, panics
+// instead of silently reading the variables of another request.
+// Intended for development and tests only, since every request allocates new requestVars.
+func (p *Mux) SetPoolDebug(enable bool) {
+	p.poolDebug = enable
+}
+
+// putRequestVars returns rv to the pool unless it exceeds the maximum size.
+func (p *Mux) putRequestVars(rv *requestVars) {
+	if p.poolDebug {
+		rv.poison()
+		return
+	}
+
+	if p.poolMaxSize > 0 && rv.size() > p.poolMaxSize {
+		if p.poolCounters != nil {
+			p.poolCounters.Discard()
+		}
+		return
+	}
+
+	if p.poolCounters != nil {
+		p.poolCounters.Put()
+	}
+
+	// keep the map but release the values
+	clear(rv.values)
+	p.pool.Put(rv)
+}
+
+// maxPooledValues is the maximum number of keys of url.Values returned to the values pool,
+// so a single large query doesn't keep a large map alive.
+const maxPooledValues = 64
+
+// valuesPool holds the url.Values used to decode the query and SEO params, so decoding doesn't allocate new maps.
+var valuesPool = sync.Pool{
+	New: func() interface{} {
+		return make(url.Values)
+	},
+}
+
+// getValues gets empty url.Values from the values pool.
+func getValues() url.Values {
+	return valuesPool.Get().(url.Values)
+}
+
+// putValues clears values and returns them to the values pool unless nil or they exceed maxPooledValues.
+// The values must not be retained, i.e. by the decoder, after being put.
+func putValues(values url.Values) {
+	if values == nil || len(values) > maxPooledValues {
+		return
+	}
+
+	clear(values)
+	valuesPool.Put(values)
+}
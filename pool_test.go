@@ -0,0 +1,67 @@
+package feather
+
+import (
+	"expvar"
+	"net/http"
+	"testing"
+
+	. "github.com/pchchv/feather/assert"
+)
+
+func TestPoolStats(t *testing.T) {
+	p := New()
+	p.Get("/users/:id", defaultHandler)
+	p.Get("/static", defaultHandler)
+	p.Get("/users/:id/posts/:pid", defaultHandler)
+
+	code, _ := request(http.MethodGet, "/users/13", p)
+	Equal(t, code, http.StatusOK)
+	Equal(t, p.PoolStats(), PoolStats{})
+
+	p.PublishPoolStats("feather_test_pool")
+	for _, path := range []string{"/users/13", "/static", "/missing"} {
+		request(http.MethodGet, path, p)
+	}
+
+	// the plain static route is served without request variables
+	stats := p.PoolStats()
+	Equal(t, stats.Gets, uint64(1))
+	Equal(t, stats.Puts, uint64(1))
+	Equal(t, stats.Discards, uint64(0))
+	Equal(t, stats.News <= stats.Gets, true)
+	NotEqual(t, expvar.Get("feather_test_pool"), nil)
+
+	p.SetPoolMaxSize(1)
+	request(http.MethodGet, "/users/13/posts/1", p)
+	Equal(t, p.PoolStats().Discards, uint64(1))
+}
+
+func TestPoolDebug(t *testing.T) {
+	var retained ReqVars
+	var retainedReq *http.Request
+	p := New()
+	p.SetPoolDebug(true)
+	p.Get("/users/:id", func(w http.ResponseWriter, r *http.Request) {
+		retained = RequestVars(r)
+		retainedReq = r
+		_, _ = w.Write([]byte(retained.URLParam("id")))
+	})
+
+	code, body := request(http.MethodGet, "/users/13", p)
+	Equal(t, code, http.StatusOK)
+	Equal(t, body, "13")
+
+	msg := "feather: request variables used after the request completed, they must not be retained beyond the request"
+	PanicMatches(t, func() { retained.URLParam("id") }, msg)
+	PanicMatches(t, func() { retained.Route() }, msg)
+	PanicMatches(t, func() { RequestVars(retainedReq) }, msg)
+	PanicMatches(t, func() { Logger(retainedReq) }, msg)
+
+	// released requestVars are not reused
+	previous := retained
+	code, body = request(http.MethodGet, "/users/14", p)
+	Equal(t, code, http.StatusOK)
+	Equal(t, body, "14")
+	PanicMatches(t, func() { previous.URLParam("id") }, msg)
+	PanicMatches(t, func() { retained.URLParam("id") }, msg)
+}
@@ -0,0 +1,249 @@
+package feather
+
+import (
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RequestIDKey is the ReqVars key under which request id middleware, such as middlewares/requestid,
+// stores the id of the request, see RequestID.
+const RequestIDKey = "feather.request_id"
+
+const (
+	baggageHeader         = "Baggage"
+	grpcTimeoutHeader     = "Grpc-Timeout"
+	requestDeadlineHeader = "X-Request-Deadline"
+)
+
+// defaultPropagatedHeaders are the trace, request id, locale and tenant headers
+// copied from the incoming request by OutgoingHeaders.
+var defaultPropagatedHeaders = []string{
+	"Traceparent",
+	"Tracestate",
+	baggageHeader,
+	"X-Request-Id",
+	acceptedLanguageHeader,
+	"X-Tenant-Id",
+}
+
+// PropagateHeaders adds header names which OutgoingHeaders copies from the incoming request.
+func (p *Mux) PropagateHeaders(names ...string) {
+	for _, name := range names {
+		p.propagate = append(p.propagate, textproto.CanonicalMIMEHeaderKey(name))
+	}
+}
+
+// SetOutgoingHeader sets a header to be returned by OutgoingHeaders, overriding the incoming value if any.
+// This is intended for propagation middleware generating values, e.g. a new request id
+// when none was sent by the client.
+func SetOutgoingHeader(r *http.Request, key, value string) {
+	if rv, ok := requestVarsOf(r); ok {
+		if rv.outgoing == nil {
+			rv.outgoing = make(http.Header)
+		}
+
+		rv.outgoing.Set(key, value)
+	}
+}
+
+// OutgoingHeaders returns the headers that should be propagated to downstream HTTP/gRPC calls,
+// i.e. the propagated headers of the incoming request, see PropagateHeaders,
+// plus those set by middleware using SetOutgoingHeader.
+//
+// A new http.Header is returned on every call so it is safe to modify.
+func OutgoingHeaders(r *http.Request) http.Header {
+	names := defaultPropagatedHeaders
+	rv, ok := requestVarsOf(r)
+	if ok {
+		names = rv.mux.propagate
+	}
+
+	h := make(http.Header, len(names))
+	for _, name := range names {
+		if values := r.Header.Values(name); len(values) > 0 {
+			h[textproto.CanonicalMIMEHeaderKey(name)] = append([]string(nil), values...)
+		}
+	}
+
+	if ok {
+		for k, v := range rv.outgoing {
+			h[k] = append([]string(nil), v...)
+		}
+
+		if rv.mux.propagateDeadline {
+			if deadline, ok := r.Context().Deadline(); ok {
+				h.Set(grpcTimeoutHeader, grpcTimeout(time.Until(deadline)))
+				h.Set(requestDeadlineHeader, strconv.FormatInt(deadline.UnixMilli(), 10))
+			}
+		}
+	}
+
+	return h
+}
+
+// Propagate sets the OutgoingHeaders of the incoming request r on the outbound request out, i.e.
+//
+//	out, _ := http.NewRequestWithContext(r.Context(), http.MethodGet, url, nil)
+//	feather.Propagate(r, out)
+func Propagate(r *http.Request, out *http.Request) {
+	for k, v := range OutgoingHeaders(r) {
+		out.Header[k] = v
+	}
+}
+
+// SetDeadlinePropagation enables applying the deadline sent by clients, using the grpc-timeout header
+// or the X-Request-Deadline header as unix milliseconds or RFC 3339 time, to the request context,
+// so handlers and the outbound calls using it stop once the client gave up.
+// The remaining time is propagated by OutgoingHeaders using both headers.
+// Default is false.
+func (p *Mux) SetDeadlinePropagation(enable bool) {
+	p.propagateDeadline = enable
+}
+
+// requestDeadline returns the deadline of the request headers, if any.
+func requestDeadline(h http.Header) (time.Time, bool) {
+	if v := h.Get(grpcTimeoutHeader); v != blank {
+		if d, ok := parseGRPCTimeout(v); ok {
+			return time.Now().Add(d), true
+		}
+	}
+
+	if v := h.Get(requestDeadlineHeader); v != blank {
+		if ms, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return time.UnixMilli(ms), true
+		}
+
+		if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
+			return t, true
+		}
+	}
+
+	return time.Time{}, false
+}
+
+var grpcTimeoutUnits = map[byte]time.Duration{
+	'H': time.Hour,
+	'M': time.Minute,
+	'S': time.Second,
+	'm': time.Millisecond,
+	'u': time.Microsecond,
+	'n': time.Nanosecond,
+}
+
+// parseGRPCTimeout parses a grpc-timeout value, up to 8 digits followed by the unit, i.e. 100m.
+func parseGRPCTimeout(v string) (time.Duration, bool) {
+	if len(v) < 2 || len(v) > 9 {
+		return 0, false
+	}
+
+	unit, ok := grpcTimeoutUnits[v[len(v)-1]]
+	if !ok {
+		return 0, false
+	}
+
+	n, err := strconv.ParseUint(v[:len(v)-1], 10, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	return time.Duration(n) * unit, true
+}
+
+// grpcTimeout formats the timeout as a grpc-timeout value using the finest unit fitting in 8 digits.
+func grpcTimeout(d time.Duration) string {
+	if d <= 0 {
+		return "0n"
+	}
+
+	for _, u := range []struct {
+		unit byte
+		d    time.Duration
+	}{{'n', time.Nanosecond}, {'u', time.Microsecond}, {'m', time.Millisecond}, {'S', time.Second}, {'M', time.Minute}} {
+		if n := d / u.d; n < 1e8 {
+			return strconv.FormatInt(int64(n), 10) + string(u.unit)
+		}
+	}
+
+	return strconv.FormatInt(int64(min(d/time.Hour, 1e8-1)), 10) + "H"
+}
+
+// Baggage returns the members of the W3C baggage of the request, including those set using SetBaggage,
+// nil if none. Properties of the members are omitted.
+func Baggage(r *http.Request) map[string]string {
+	var values []string
+	if rv, ok := requestVarsOf(r); ok && rv.outgoing != nil && rv.outgoing.Get(baggageHeader) != blank {
+		values = rv.outgoing.Values(baggageHeader)
+	} else {
+		values = r.Header.Values(baggageHeader)
+	}
+
+	var baggage map[string]string
+	for _, v := range values {
+		for _, member := range strings.Split(v, ",") {
+			member, _, _ = strings.Cut(member, ";")
+			key, value, ok := strings.Cut(member, "=")
+			if !ok {
+				continue
+			}
+
+			key = strings.TrimSpace(key)
+			value, err := url.PathUnescape(strings.TrimSpace(value))
+			if key == blank || err != nil {
+				continue
+			}
+
+			if baggage == nil {
+				baggage = make(map[string]string)
+			}
+			baggage[key] = value
+		}
+	}
+
+	return baggage
+}
+
+// SetBaggage sets a member of the baggage propagated by OutgoingHeaders, keeping the other members of the incoming baggage.
+func SetBaggage(r *http.Request, key, value string) {
+	baggage := Baggage(r)
+	if baggage == nil {
+		baggage = make(map[string]string, 1)
+	}
+	baggage[key] = value
+
+	keys := make([]string, 0, len(baggage))
+	for k := range baggage {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+
+	var b strings.Builder
+	for i, k := range keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(k)
+		b.WriteByte('=')
+		b.WriteString(url.PathEscape(baggage[k]))
+	}
+
+	SetOutgoingHeader(r, baggageHeader, b.String())
+}
+
+// RequestID returns the id of the request stored by request id middleware under RequestIDKey, blank if none.
+// It can be added to the request scoped loggers using
+//
+//	p.SetLogger(l, feather.StringField("request_id", feather.RequestID))
+func RequestID(r *http.Request) string {
+	rv, ok := requestVarsOf(r)
+	if !ok {
+		return blank
+	}
+
+	id, _ := rv.Get(RequestIDKey).(string)
+	return id
+}
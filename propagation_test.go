@@ -0,0 +1,147 @@
+package feather
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	. "github.com/pchchv/feather/assert"
+)
+
+func TestOutgoingHeaders(t *testing.T) {
+	var h http.Header
+	p := New()
+	p.PropagateHeaders("x-custom")
+	p.Use(func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			SetOutgoingHeader(r, "X-Request-Id", "generated")
+			next(w, r)
+		}
+	})
+	p.Get("/", func(w http.ResponseWriter, r *http.Request) {
+		h = OutgoingHeaders(r)
+	})
+
+	r, _ := http.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Traceparent", "00-abc-def-01")
+	r.Header.Set("X-Request-Id", "incoming")
+	r.Header.Set("Accept-Language", "en")
+	r.Header.Set("X-Custom", "custom")
+	r.Header.Set("Cookie", "secret")
+	p.Serve().ServeHTTP(nil, r)
+
+	Equal(t, len(h), 4)
+	Equal(t, h.Get("Traceparent"), "00-abc-def-01")
+	Equal(t, h.Get("X-Request-Id"), "generated")
+	Equal(t, h.Get("Accept-Language"), "en")
+	Equal(t, h.Get("X-Custom"), "custom")
+
+	// outside of feather the defaults are used
+	h = OutgoingHeaders(r)
+	Equal(t, len(h), 3)
+	Equal(t, h.Get("X-Request-Id"), "incoming")
+}
+
+func TestDeadlinePropagation(t *testing.T) {
+	var deadline time.Time
+	var hasDeadline bool
+	var h http.Header
+	p := New()
+	p.Get("/", func(w http.ResponseWriter, r *http.Request) {
+		deadline, hasDeadline = r.Context().Deadline()
+		out, _ := http.NewRequestWithContext(r.Context(), http.MethodGet, "http://example.com", nil)
+		Propagate(r, out)
+		h = out.Header
+		Equal(t, RequestVars(r).Route(), "/")
+	})
+
+	do := func(name, value string) {
+		r, _ := http.NewRequest(http.MethodGet, "/", nil)
+		if name != blank {
+			r.Header.Set(name, value)
+		}
+		p.Serve().ServeHTTP(nil, r)
+	}
+
+	do(grpcTimeoutHeader, "1S")
+	Equal(t, hasDeadline, false)
+
+	p.SetDeadlinePropagation(true)
+	now := time.Now()
+	do(grpcTimeoutHeader, "1S")
+	Equal(t, hasDeadline, true)
+	Equal(t, deadline.Sub(now) > 900*time.Millisecond && deadline.Sub(now) <= time.Second+100*time.Millisecond, true)
+	Equal(t, h.Get(requestDeadlineHeader), strconv.FormatInt(deadline.UnixMilli(), 10))
+	d, ok := parseGRPCTimeout(h.Get(grpcTimeoutHeader))
+	Equal(t, ok, true)
+	Equal(t, d > 0 && d <= time.Second, true)
+
+	expected := time.Now().Add(time.Minute).Truncate(time.Millisecond)
+	do(requestDeadlineHeader, strconv.FormatInt(expected.UnixMilli(), 10))
+	Equal(t, deadline.Equal(expected), true)
+
+	do(requestDeadlineHeader, expected.Format(time.RFC3339Nano))
+	Equal(t, deadline.Equal(expected), true)
+
+	do(grpcTimeoutHeader, "1X")
+	Equal(t, hasDeadline, false)
+	Equal(t, h.Get(grpcTimeoutHeader), blank)
+
+	do(blank, blank)
+	Equal(t, hasDeadline, false)
+}
+
+func TestGRPCTimeout(t *testing.T) {
+	tests := []struct {
+		value string
+		d     time.Duration
+		ok    bool
+	}{
+		{"100m", 100 * time.Millisecond, true},
+		{"2H", 2 * time.Hour, true},
+		{"99999999n", 99999999, true},
+		{"123456789S", 0, false},
+		{"S", 0, false},
+		{"-1S", 0, false},
+	}
+
+	for _, tt := range tests {
+		d, ok := parseGRPCTimeout(tt.value)
+		Equal(t, ok, tt.ok)
+		Equal(t, d, tt.d)
+	}
+
+	Equal(t, grpcTimeout(-time.Second), "0n")
+	Equal(t, grpcTimeout(1500*time.Microsecond), "1500000n")
+	Equal(t, grpcTimeout(2*time.Second), "2000000u")
+	Equal(t, grpcTimeout(10*time.Minute), "600000m")
+	Equal(t, grpcTimeout(1000*time.Hour), "3600000S")
+}
+
+func TestBaggage(t *testing.T) {
+	var baggage map[string]string
+	var h http.Header
+	p := New()
+	p.Use(func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			SetBaggage(r, "tenant", "acme corp")
+			next(w, r)
+		}
+	})
+	p.Get("/", func(w http.ResponseWriter, r *http.Request) {
+		baggage = Baggage(r)
+		h = OutgoingHeaders(r)
+	})
+
+	r, _ := http.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set(baggageHeader, "userId=alice;prop=1, invalid, serverNode=DF%2028")
+	Equal(t, Baggage(r), map[string]string{"userId": "alice", "serverNode": "DF 28"})
+
+	p.Serve().ServeHTTP(nil, r)
+	Equal(t, baggage, map[string]string{"userId": "alice", "serverNode": "DF 28", "tenant": "acme corp"})
+	Equal(t, h.Get(baggageHeader), "serverNode=DF%2028,tenant=acme%20corp,userId=alice")
+
+	r.Header.Del(baggageHeader)
+	Equal(t, len(Baggage(r)), 0)
+}
@@ -0,0 +1,78 @@
+package proxy
+
+import (
+	"hash/fnv"
+	"net/http"
+	"time"
+)
+
+// Affinity configures session affinity, sending the requests of a client to the same target.
+// When the target of a session is evicted by the health checks the session moves to another target.
+type Affinity struct {
+	// Header, if set, names a request header whose value identifies the session, i.e. X-User-Id.
+	// Sessions are spread over the targets using rendezvous hashing,
+	// so only the sessions of evicted targets move.
+	Header string
+	// Cookie, if set, names a cookie recording the target of the session,
+	// it is set on the response of requests without it or whose target was evicted.
+	// It is used when the Header is not set or missing from the request.
+	Cookie string
+	// MaxAge of the cookie, by default it expires with the browser session.
+	MaxAge time.Duration
+}
+
+// affinity returns the index of the target of the session of the request, if any.
+func (p *Proxy) affinity(r *http.Request, targets []*target) (int, bool) {
+	a := p.cfg.Affinity
+	if a == nil {
+		return 0, false
+	}
+
+	if a.Header != "" {
+		if key := r.Header.Get(a.Header); key != "" {
+			return rendezvous(key, targets), true
+		}
+	}
+
+	if a.Cookie != "" {
+		if c, err := r.Cookie(a.Cookie); err == nil {
+			for i, t := range targets {
+				if t.id == c.Value {
+					return i, true
+				}
+			}
+		}
+	}
+
+	return 0, false
+}
+
+// rendezvous returns the index of the target with the highest hash of the key and target id.
+func rendezvous(key string, targets []*target) int {
+	var best int
+	var max uint64
+	for i, t := range targets {
+		h := fnv.New64a()
+		h.Write([]byte(t.id))
+		h.Write([]byte(key))
+		if score := h.Sum64(); i == 0 || score > max {
+			best, max = i, score
+		}
+	}
+
+	return best
+}
+
+// setCookie records the target which served the request in the affinity cookie of the response.
+func (p *Proxy) setCookie(resp *http.Response, s *selection) {
+	c := &http.Cookie{
+		Name:     p.cfg.Affinity.Cookie,
+		Value:    s.served.id,
+		Path:     "/",
+		MaxAge:   int(p.cfg.Affinity.MaxAge / time.Second),
+		HttpOnly: true,
+		Secure:   s.in.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	}
+	resp.Header.Add("Set-Cookie", c.String())
+}
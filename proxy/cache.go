@@ -0,0 +1,304 @@
+package proxy
+
+import (
+	"bytes"
+	"container/list"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultCacheSize      = 64 << 20
+	defaultCacheEntrySize = 1 << 20
+)
+
+// Cache configures the in-memory cache of upstream responses.
+// Responses to GET requests without an Authorization header are cached according to their
+// Cache-Control (s-maxage, max-age, no-store, private, no-cache) and Expires headers,
+// stale responses with an ETag or Last-Modified header are revalidated using a conditional request.
+// Responses setting cookies or varying on all headers are never cached.
+type Cache struct {
+	// MaxSize is the total size of the cached bodies, by default 64MB.
+	MaxSize int64
+	// MaxEntrySize is the size of the largest cached body, by default 1MB.
+	MaxEntrySize int64
+}
+
+// entry is a cached response.
+type entry struct {
+	key      string
+	status   int
+	header   http.Header
+	body     []byte
+	vary     http.Header // request values of the headers the response varies on
+	stored   time.Time
+	lifetime time.Duration
+}
+
+// cacheTransport serves cached responses, sending requests to the base transport on a miss.
+type cacheTransport struct {
+	base         http.RoundTripper
+	maxSize      int64
+	maxEntrySize int64
+	m            sync.Mutex
+	size         int64
+	lru          *list.List // of *entry, most recently used first
+	entries      map[string]*list.Element
+	now          func() time.Time
+}
+
+func newCacheTransport(base http.RoundTripper, c *Cache) *cacheTransport {
+	t := &cacheTransport{
+		base:         base,
+		maxSize:      c.MaxSize,
+		maxEntrySize: c.MaxEntrySize,
+		lru:          list.New(),
+		entries:      make(map[string]*list.Element),
+		now:          time.Now,
+	}
+
+	if t.maxSize <= 0 {
+		t.maxSize = defaultCacheSize
+	}
+
+	if t.maxEntrySize <= 0 {
+		t.maxEntrySize = defaultCacheEntrySize
+	}
+	return t
+}
+
+func (t *cacheTransport) RoundTrip(out *http.Request) (*http.Response, error) {
+	if out.Method != http.MethodGet || out.Header.Get("Authorization") != "" {
+		return t.base.RoundTrip(out)
+	}
+
+	key := out.URL.RequestURI()
+	reqCC := cacheControl(out.Header.Get("Cache-Control"))
+	_, noCache := reqCC["no-cache"]
+	e := t.get(key, out.Header)
+	now := t.now()
+	if e != nil && !noCache && now.Sub(e.stored) < e.lifetime {
+		return e.response(out, now), nil
+	}
+
+	// revalidate the stale entry
+	var etag, lastModified string
+	if e != nil && out.Header.Get("If-None-Match") == "" && out.Header.Get("If-Modified-Since") == "" {
+		if etag = e.header.Get("Etag"); etag != "" {
+			out.Header.Set("If-None-Match", etag)
+		}
+
+		if lastModified = e.header.Get("Last-Modified"); lastModified != "" {
+			out.Header.Set("If-Modified-Since", lastModified)
+		}
+	}
+
+	resp, err := t.base.RoundTrip(out)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusNotModified && (etag != "" || lastModified != "") {
+		resp.Body.Close()
+		fresh := *e
+		fresh.stored = now
+		if lifetime, ok := freshness(resp, now); ok {
+			fresh.lifetime = lifetime
+		}
+		t.add(&fresh)
+
+		out.Header.Del("If-None-Match")
+		out.Header.Del("If-Modified-Since")
+		return fresh.response(out, now), nil
+	}
+
+	if _, ok := reqCC["no-store"]; ok {
+		return resp, nil
+	}
+
+	lifetime, ok := freshness(resp, now)
+	if !ok {
+		return resp, nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, t.maxEntrySize+1))
+	if err != nil || int64(len(body)) > t.maxEntrySize {
+		// too large or failed, stream the rest
+		resp.Body = &readCloser{Reader: io.MultiReader(bytes.NewReader(body), resp.Body), Closer: resp.Body}
+		return resp, nil
+	}
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	e = &entry{
+		key:      key,
+		status:   resp.StatusCode,
+		header:   resp.Header.Clone(),
+		body:     body,
+		vary:     make(http.Header),
+		stored:   now,
+		lifetime: lifetime,
+	}
+	for _, v := range resp.Header.Values("Vary") {
+		for _, name := range strings.Split(v, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				e.vary[http.CanonicalHeaderKey(name)] = out.Header.Values(name)
+			}
+		}
+	}
+
+	t.add(e)
+	return resp, nil
+}
+
+// freshness returns the freshness lifetime of the response, false if it must not be cached.
+func freshness(resp *http.Response, now time.Time) (time.Duration, bool) {
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusNotModified, http.StatusMovedPermanently, http.StatusNotFound, http.StatusGone:
+	default:
+		return 0, false
+	}
+
+	if resp.Header.Get("Set-Cookie") != "" || resp.Header.Get("Vary") == "*" {
+		return 0, false
+	}
+
+	cc := cacheControl(resp.Header.Get("Cache-Control"))
+	if _, ok := cc["no-store"]; ok {
+		return 0, false
+	}
+
+	if _, ok := cc["private"]; ok {
+		return 0, false
+	}
+
+	var lifetime time.Duration
+	validator := resp.Header.Get("Etag") != "" || resp.Header.Get("Last-Modified") != ""
+	if _, ok := cc["no-cache"]; ok {
+		return 0, validator
+	} else if v, ok := cc["s-maxage"]; ok {
+		lifetime = seconds(v)
+	} else if v, ok := cc["max-age"]; ok {
+		lifetime = seconds(v)
+	} else if v := resp.Header.Get("Expires"); v != "" {
+		expires, err := http.ParseTime(v)
+		if err != nil {
+			return 0, validator
+		}
+
+		date, err := http.ParseTime(resp.Header.Get("Date"))
+		if err != nil {
+			date = now
+		}
+		lifetime = expires.Sub(date)
+	} else {
+		return 0, validator
+	}
+
+	if age, err := strconv.Atoi(resp.Header.Get("Age")); err == nil {
+		lifetime -= time.Duration(age) * time.Second
+	}
+
+	return max(lifetime, 0), lifetime > 0 || validator
+}
+
+// cacheControl parses the directives of a Cache-Control header.
+func cacheControl(v string) map[string]string {
+	if v == "" {
+		return nil
+	}
+
+	cc := make(map[string]string)
+	for _, d := range strings.Split(v, ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(d), "=")
+		cc[strings.ToLower(name)] = strings.Trim(value, `"`)
+	}
+	return cc
+}
+
+func seconds(v string) time.Duration {
+	s, err := strconv.Atoi(v)
+	if err != nil || s < 0 {
+		return 0
+	}
+
+	return time.Duration(s) * time.Second
+}
+
+// get returns the entry of the key if it matches the request headers it varies on.
+func (t *cacheTransport) get(key string, h http.Header) *entry {
+	t.m.Lock()
+	defer t.m.Unlock()
+
+	el, ok := t.entries[key]
+	if !ok {
+		return nil
+	}
+
+	e := el.Value.(*entry)
+	for name, values := range e.vary {
+		if strings.Join(h.Values(name), ",") != strings.Join(values, ",") {
+			return nil
+		}
+	}
+
+	t.lru.MoveToFront(el)
+	return e
+}
+
+// add stores the entry, evicting the least recently used entries exceeding the size.
+func (t *cacheTransport) add(e *entry) {
+	t.m.Lock()
+	defer t.m.Unlock()
+
+	if el, ok := t.entries[e.key]; ok {
+		t.remove(el)
+	}
+
+	t.entries[e.key] = t.lru.PushFront(e)
+	t.size += int64(len(e.body))
+	for t.size > t.maxSize {
+		t.remove(t.lru.Back())
+	}
+}
+
+func (t *cacheTransport) remove(el *list.Element) {
+	e := t.lru.Remove(el).(*entry)
+	delete(t.entries, e.key)
+	t.size -= int64(len(e.body))
+}
+
+// response returns the cached response to the request,
+// 304 Not Modified if the request is conditional and the ETag matches.
+func (e *entry) response(req *http.Request, now time.Time) *http.Response {
+	resp := &http.Response{
+		Status:        strconv.Itoa(e.status) + " " + http.StatusText(e.status),
+		StatusCode:    e.status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        e.header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(e.body)),
+		ContentLength: int64(len(e.body)),
+		Request:       req,
+	}
+	resp.Header.Set("Age", strconv.Itoa(int(now.Sub(e.stored)/time.Second)))
+
+	if etag := e.header.Get("Etag"); etag != "" && e.status == http.StatusOK && req.Header.Get("If-None-Match") == etag {
+		resp.StatusCode = http.StatusNotModified
+		resp.Status = "304 Not Modified"
+		resp.Body = http.NoBody
+		resp.ContentLength = 0
+	}
+
+	return resp
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
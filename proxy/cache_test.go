@@ -0,0 +1,143 @@
+package proxy
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/pchchv/feather/assert"
+)
+
+func TestCache(t *testing.T) {
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		switch r.URL.Path {
+		case "/max-age":
+			w.Header().Set("Cache-Control", "public, max-age=60")
+			w.Header().Set("Age", "10")
+		case "/etag":
+			w.Header().Set("Cache-Control", "no-cache")
+			w.Header().Set("ETag", `"v1"`)
+			if r.Header.Get("If-None-Match") == `"v1"` {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+		case "/private":
+			w.Header().Set("Cache-Control", "private, max-age=60")
+		case "/cookie":
+			w.Header().Set("Cache-Control", "max-age=60")
+			w.Header().Set("Set-Cookie", "a=b")
+		case "/vary":
+			w.Header().Set("Cache-Control", "max-age=60")
+			w.Header().Set("Vary", "Accept-Language")
+			_, _ = io.WriteString(w, r.Header.Get("Accept-Language"))
+			return
+		case "/large":
+			w.Header().Set("Cache-Control", "max-age=60")
+			_, _ = io.WriteString(w, strings.Repeat("a", 20))
+			return
+		}
+		_, _ = io.WriteString(w, r.URL.Path)
+	}))
+	defer srv.Close()
+
+	u, _ := url.Parse(srv.URL)
+	p := New(Config{Targets: []*url.URL{u}, Cache: &Cache{MaxEntrySize: 16}})
+	clock := time.Now()
+	p.rp.Transport.(*cacheTransport).now = func() time.Time { return clock }
+
+	get := func(path string, header ...string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		for i := 0; i < len(header); i += 2 {
+			r.Header.Set(header[i], header[i+1])
+		}
+
+		w := httptest.NewRecorder()
+		p.ServeHTTP(w, r)
+		return w
+	}
+
+	w := get("/max-age")
+	Equal(t, w.Body.String(), "/max-age")
+	clock = clock.Add(30 * time.Second)
+	w = get("/max-age")
+	Equal(t, w.Code, http.StatusOK)
+	Equal(t, w.Body.String(), "/max-age")
+	Equal(t, w.Header().Get("Age"), "30")
+	Equal(t, hits.Load(), int32(1))
+
+	// the upstream Age counts towards the lifetime
+	clock = clock.Add(20 * time.Second)
+	get("/max-age")
+	Equal(t, hits.Load(), int32(2))
+
+	// requests refusing cached responses
+	get("/max-age", "Cache-Control", "no-cache")
+	Equal(t, hits.Load(), int32(3))
+	get("/max-age", "Authorization", "Bearer token")
+	Equal(t, hits.Load(), int32(4))
+
+	// revalidation
+	hits.Store(0)
+	get("/etag")
+	w = get("/etag")
+	Equal(t, w.Code, http.StatusOK)
+	Equal(t, w.Body.String(), "/etag")
+	Equal(t, hits.Load(), int32(2))
+
+	w = get("/max-age", "If-None-Match", `"none"`)
+	Equal(t, w.Code, http.StatusOK)
+
+	hits.Store(0)
+	for _, path := range []string{"/private", "/cookie", "/large"} {
+		get(path)
+		w = get(path)
+		Equal(t, w.Code, http.StatusOK)
+	}
+	Equal(t, hits.Load(), int32(6))
+	Equal(t, w.Body.Len(), 20)
+
+	hits.Store(0)
+	Equal(t, get("/vary", "Accept-Language", "en").Body.String(), "en")
+	Equal(t, get("/vary", "Accept-Language", "de").Body.String(), "de")
+	Equal(t, get("/vary", "Accept-Language", "de").Body.String(), "de")
+	Equal(t, hits.Load(), int32(2))
+}
+
+func TestCacheConditional(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Header().Set("ETag", `"v1"`)
+		_, _ = io.WriteString(w, "body")
+	}))
+	defer srv.Close()
+
+	u, _ := url.Parse(srv.URL)
+	p := New(Config{Targets: []*url.URL{u}, Cache: &Cache{}})
+	code, _ := request(p, http.MethodGet, "/", nil)
+	Equal(t, code, http.StatusOK)
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("If-None-Match", `"v1"`)
+	w := httptest.NewRecorder()
+	p.ServeHTTP(w, r)
+	Equal(t, w.Code, http.StatusNotModified)
+	Equal(t, w.Body.Len(), 0)
+}
+
+func TestCacheEviction(t *testing.T) {
+	tr := newCacheTransport(nil, &Cache{MaxSize: 10})
+	tr.add(&entry{key: "a", body: make([]byte, 4)})
+	tr.add(&entry{key: "b", body: make([]byte, 4)})
+	NotEqual(t, tr.get("a", nil), nil)
+	tr.add(&entry{key: "c", body: make([]byte, 4)})
+	Equal(t, tr.get("b", nil) == nil, true)
+	Equal(t, tr.get("a", nil) != nil, true)
+	Equal(t, tr.size, int64(8))
+}
@@ -0,0 +1,101 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// HealthCheck configures the active health checks of the targets.
+type HealthCheck struct {
+	// Path requested from every target using GET, a 2xx status means the target is healthy.
+	Path string
+	// Interval between checks, by default 10s.
+	Interval time.Duration
+	// Timeout of a check, by default 2s.
+	Timeout time.Duration
+	// Failures is the number of consecutive failed checks evicting a target, by default 1.
+	// Evicted targets are restored once a check succeeds.
+	Failures int
+}
+
+// healthCheck checks the targets every interval until the proxy is closed.
+func (p *Proxy) healthCheck(hc *HealthCheck) {
+	interval, timeout, failures := hc.Interval, hc.Timeout, hc.Failures
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+
+	if timeout <= 0 {
+		timeout = 2 * time.Second
+	}
+
+	if failures <= 0 {
+		failures = 1
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-p.done
+		cancel()
+	}()
+
+	client := &http.Client{Transport: p.cfg.Transport, Timeout: timeout}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		for _, t := range *p.targets.Load() {
+			if check(ctx, client, t, hc.Path) {
+				t.failures = 0
+			} else {
+				t.failures++
+			}
+
+			t.evicted.Store(t.failures >= failures)
+		}
+
+		p.m.Lock()
+		p.updateHealthy()
+		p.m.Unlock()
+
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// check reports whether the target is healthy.
+func check(ctx context.Context, client *http.Client, t *target, path string) bool {
+	u := *t.url
+	u.Path = singleJoin(u.Path, path)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return false
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+
+	return resp.StatusCode >= 200 && resp.StatusCode < 300
+}
+
+func singleJoin(a, b string) string {
+	switch {
+	case a == "" || a[len(a)-1] != '/':
+		if b == "" || b[0] == '/' {
+			return a + b
+		}
+		return a + "/" + b
+	case b != "" && b[0] == '/':
+		return a + b[1:]
+	default:
+		return a + b
+	}
+}
@@ -0,0 +1,153 @@
+package proxy
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"time"
+)
+
+// hedgeTransport sends a second attempt of a request to the alternate target
+// when the first didn't succeed within the delay, using the first successful response.
+type hedgeTransport struct {
+	base    http.RoundTripper
+	delay   time.Duration
+	rewrite func(pr *httputil.ProxyRequest, t *target)
+}
+
+type attempt struct {
+	i      int // index of the attempt
+	resp   *http.Response
+	err    error
+	cancel context.CancelFunc
+}
+
+func (t *hedgeTransport) RoundTrip(out *http.Request) (*http.Response, error) {
+	s := selectionOf(out)
+	if s == nil || !hedgeable(out) {
+		return t.base.RoundTrip(out)
+	}
+
+	results := make(chan attempt, 2)
+	cancels := make([]context.CancelFunc, 0, 2)
+	send := func(req *http.Request) {
+		i := len(cancels)
+		ctx, cancel := context.WithCancel(req.Context())
+		cancels = append(cancels, cancel)
+		go func() {
+			resp, err := t.base.RoundTrip(req.WithContext(ctx))
+			results <- attempt{i: i, resp: resp, err: err, cancel: cancel}
+		}()
+	}
+
+	send(out)
+	timer := time.NewTimer(t.delay)
+	defer timer.Stop()
+
+	var failed *attempt
+	for pending := 1; ; {
+		select {
+		case <-timer.C:
+			if len(cancels) == 1 {
+				send(t.alternate(out, s))
+				pending++
+			}
+			continue
+		case a := <-results:
+			pending--
+			if a.err == nil && a.resp.StatusCode < http.StatusInternalServerError {
+				if pending > 0 {
+					cancels[1-a.i]()
+					go discard(results)
+				}
+
+				if failed != nil {
+					failed.close()
+				}
+
+				if a.i == 1 {
+					s.served = s.alternate
+				}
+				return respond(a)
+			}
+
+			// keep the failed attempt with a response, if any
+			if failed == nil || failed.resp == nil {
+				if failed != nil {
+					failed.close()
+				}
+				failed = &a
+			} else {
+				a.close()
+			}
+
+			if len(cancels) == 1 {
+				// the first attempt failed before the delay, hedge right away
+				send(t.alternate(out, s))
+				pending++
+			} else if pending == 0 {
+				if failed.i == 1 {
+					s.served = s.alternate
+				}
+				return respond(*failed)
+			}
+		}
+	}
+}
+
+// respond returns the response of the attempt, canceling its context once the body is closed.
+func respond(a attempt) (*http.Response, error) {
+	if a.err != nil {
+		a.cancel()
+		return nil, a.err
+	}
+
+	a.resp.Body = &cancelBody{ReadCloser: a.resp.Body, cancel: a.cancel}
+	return a.resp, nil
+}
+
+// alternate returns a copy of the upstream request pointed at the alternate target.
+func (t *hedgeTransport) alternate(out *http.Request, s *selection) *http.Request {
+	req := out.Clone(out.Context())
+	u := *s.in.URL
+	req.URL = &u
+	t.rewrite(&httputil.ProxyRequest{In: s.in, Out: req}, s.alternate)
+	return req
+}
+
+// hedgeable reports whether the request can safely be sent twice.
+func hedgeable(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return r.Body == nil || r.Body == http.NoBody
+	default:
+		return false
+	}
+}
+
+func (a attempt) close() {
+	if a.resp != nil {
+		a.resp.Body.Close()
+	}
+
+	a.cancel()
+}
+
+// discard closes the response of the losing attempt.
+func discard(results <-chan attempt) {
+	a := <-results
+	a.close()
+}
+
+// cancelBody cancels the context of the attempt once the response body is closed.
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
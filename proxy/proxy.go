@@ -0,0 +1,242 @@
+// Package proxy provides a reverse proxy handler balancing requests between upstream targets,
+// with optional session affinity, health checks, response caching and hedging of slow requests.
+//
+//	p.Any("/api/*", proxy.New(proxy.Config{Targets: targets, HedgeDelay: 50 * time.Millisecond}).ServeHTTP)
+package proxy
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pchchv/feather"
+)
+
+// ErrNoTargets is passed to the ErrorHandler when no targets were resolved.
+var ErrNoTargets = errors.New("proxy: no targets")
+
+// Config contains the proxy settings, Targets or Resolver is required.
+type Config struct {
+	// Targets are the base URLs of the upstreams, requests are distributed round robin.
+	Targets []*url.URL
+	// Resolver, if set, resolves the targets instead, so they can change without restarting.
+	Resolver Resolver
+	// ResolveInterval is the interval the targets are resolved again, by default 30s.
+	// It is jittered by up to 20% so instances don't resolve at once, failures are retried with exponential backoff.
+	ResolveInterval time.Duration
+	// Transport performs the upstream requests, by default http.DefaultTransport.
+	Transport http.RoundTripper
+	// HedgeDelay, if positive, sends a second attempt of GET, HEAD and OPTIONS requests without a body
+	// to the next target when the first didn't succeed within the delay.
+	// The first successful response, one without error and with a status below 500, is used and the other attempt canceled.
+	HedgeDelay time.Duration
+	// Affinity, if set, sends the requests of a session to the same target while it is healthy.
+	Affinity *Affinity
+	// HealthCheck, if set, periodically checks the targets, evicting unhealthy ones until they recover.
+	HealthCheck *HealthCheck
+	// Cache, if set, caches upstream responses in memory honoring their Cache-Control and ETag headers.
+	Cache *Cache
+	// Rewrite, if set, modifies the upstream request after its URL was set to the target, i.e. to strip a prefix.
+	Rewrite func(pr *httputil.ProxyRequest)
+	// ErrorHandler answers requests whose upstream failed, by default with 502 Bad Gateway.
+	ErrorHandler func(w http.ResponseWriter, r *http.Request, err error)
+}
+
+// Proxy is a reverse proxy handler.
+type Proxy struct {
+	cfg     Config
+	rp      *httputil.ReverseProxy
+	m       sync.Mutex                // serializes updates of the targets
+	targets atomic.Pointer[[]*target] // all targets
+	healthy atomic.Pointer[[]*target] // targets passing the health checks, all when none do
+	ready   chan struct{}             // closed once the targets were resolved the first time
+	counter atomic.Uint64
+	done    chan struct{}
+}
+
+// target is an upstream.
+type target struct {
+	url      *url.URL
+	id       string // identifies the target in affinity cookies without revealing its URL
+	evicted  atomic.Bool
+	failures int // consecutive failed health checks
+}
+
+// New returns a reverse proxy forwarding requests to the targets.
+// It panics if neither Targets nor a Resolver is configured.
+func New(cfg Config) *Proxy {
+	if len(cfg.Targets) == 0 && cfg.Resolver == nil {
+		panic("proxy: at least one target or a resolver is required")
+	}
+
+	if cfg.Transport == nil {
+		cfg.Transport = http.DefaultTransport
+	}
+
+	if cfg.ErrorHandler == nil {
+		cfg.ErrorHandler = badGateway
+	}
+
+	p := &Proxy{cfg: cfg, ready: make(chan struct{}), done: make(chan struct{})}
+	p.setTargets(nil)
+
+	transport := cfg.Transport
+	if cfg.HedgeDelay > 0 {
+		transport = &hedgeTransport{base: cfg.Transport, delay: cfg.HedgeDelay, rewrite: p.rewrite}
+	}
+
+	if cfg.Cache != nil {
+		transport = newCacheTransport(transport, cfg.Cache)
+	}
+
+	p.rp = &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			p.rewrite(pr, selectionOf(pr.In).primary)
+		},
+		Transport:      transport,
+		ModifyResponse: p.modifyResponse,
+		ErrorHandler:   cfg.ErrorHandler,
+	}
+
+	if cfg.Resolver != nil {
+		go p.resolve(cfg.Resolver, cfg.ResolveInterval)
+	} else {
+		p.setTargets(cfg.Targets)
+		close(p.ready)
+	}
+
+	if cfg.HealthCheck != nil {
+		go p.healthCheck(cfg.HealthCheck)
+	}
+	return p
+}
+
+// Close stops the health checks and the resolution of the targets.
+func (p *Proxy) Close() {
+	select {
+	case <-p.done:
+	default:
+		close(p.done)
+	}
+}
+
+// ServeHTTP forwards the request to the target of its session, if any, or the next target.
+// Until the targets were resolved the first time requests wait for them.
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	select {
+	case <-p.ready:
+	case <-r.Context().Done():
+		p.cfg.ErrorHandler(w, r, r.Context().Err())
+		return
+	}
+
+	targets := *p.healthy.Load()
+	if len(targets) == 0 {
+		p.cfg.ErrorHandler(w, r, ErrNoTargets)
+		return
+	}
+
+	i, ok := p.affinity(r, targets)
+	if !ok {
+		i = int((p.counter.Add(1) - 1) % uint64(len(targets)))
+	}
+
+	s := &selection{
+		primary:   targets[i],
+		alternate: targets[(i+1)%len(targets)],
+		setCookie: !ok && p.cfg.Affinity != nil && p.cfg.Affinity.Cookie != "",
+	}
+	s.served = s.primary
+	s.in = r.WithContext(context.WithValue(r.Context(), selectionKey{}, s))
+	p.rp.ServeHTTP(w, s.in)
+}
+
+// setTargets replaces the targets, keeping the health of those already known.
+func (p *Proxy) setTargets(urls []*url.URL) {
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	known := make(map[string]*target)
+	if old := p.targets.Load(); old != nil {
+		for _, t := range *old {
+			known[t.id] = t
+		}
+	}
+
+	targets := make([]*target, 0, len(urls))
+	for _, u := range urls {
+		h := fnv.New64a()
+		h.Write([]byte(u.String()))
+		id := strconv.FormatUint(h.Sum64(), 36)
+		if t, ok := known[id]; ok {
+			targets = append(targets, t)
+		} else {
+			targets = append(targets, &target{url: u, id: id})
+		}
+	}
+
+	p.targets.Store(&targets)
+	p.updateHealthy()
+}
+
+// updateHealthy stores the targets which weren't evicted, p.m must be held.
+func (p *Proxy) updateHealthy() {
+	targets := *p.targets.Load()
+	healthy := make([]*target, 0, len(targets))
+	for _, t := range targets {
+		if !t.evicted.Load() {
+			healthy = append(healthy, t)
+		}
+	}
+
+	// keep sending requests to all targets rather than none
+	if len(healthy) == 0 {
+		healthy = targets
+	}
+	p.healthy.Store(&healthy)
+}
+
+type selectionKey struct{}
+
+// selection are the targets chosen for a request.
+type selection struct {
+	in        *http.Request
+	primary   *target
+	alternate *target // target of the hedged attempt
+	served    *target // target of the response used
+	setCookie bool    // whether to set the affinity cookie
+}
+
+func selectionOf(r *http.Request) *selection {
+	s, _ := r.Context().Value(selectionKey{}).(*selection)
+	return s
+}
+
+// rewrite points the upstream request at the target.
+func (p *Proxy) rewrite(pr *httputil.ProxyRequest, t *target) {
+	pr.SetURL(t.url)
+	pr.SetXForwarded()
+	if p.cfg.Rewrite != nil {
+		p.cfg.Rewrite(pr)
+	}
+}
+
+func (p *Proxy) modifyResponse(resp *http.Response) error {
+	if s := selectionOf(resp.Request); s != nil && s.setCookie {
+		p.setCookie(resp, s)
+	}
+
+	return nil
+}
+
+func badGateway(w http.ResponseWriter, r *http.Request, err error) {
+	feather.Logger(r).Warn("proxy: upstream request failed", "error", err)
+	http.Error(w, http.StatusText(http.StatusBadGateway), http.StatusBadGateway)
+}
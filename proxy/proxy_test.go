@@ -0,0 +1,250 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pchchv/feather"
+	. "github.com/pchchv/feather/assert"
+)
+
+func upstream(t *testing.T, name string, delay time.Duration, status int, hits *atomic.Int32) *url.URL {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if hits != nil {
+			hits.Add(1)
+		}
+
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+			return
+		}
+
+		w.WriteHeader(status)
+		_, _ = io.WriteString(w, name+" "+r.URL.Path)
+	}))
+	t.Cleanup(srv.Close)
+
+	u, _ := url.Parse(srv.URL)
+	return u
+}
+
+func request(h http.Handler, method, path string, body io.Reader) (int, string) {
+	r := httptest.NewRequest(method, path, body)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w.Code, w.Body.String()
+}
+
+func TestProxy(t *testing.T) {
+	a := upstream(t, "a", 0, http.StatusOK, nil)
+	b := upstream(t, "b", 0, http.StatusOK, nil)
+	b.Path = "/b"
+
+	p := feather.New()
+	p.Get("/api/*", New(Config{
+		Targets: []*url.URL{a, b},
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			Equal(t, pr.Out.Header.Get("X-Forwarded-Host"), "example.com")
+		},
+	}).ServeHTTP)
+	h := p.Serve()
+
+	code, body := request(h, http.MethodGet, "/api/users", nil)
+	Equal(t, code, http.StatusOK)
+	Equal(t, body, "a /api/users")
+
+	code, body = request(h, http.MethodGet, "/api/users", nil)
+	Equal(t, code, http.StatusOK)
+	Equal(t, body, "b /b/api/users")
+
+	down, _ := url.Parse("http://127.0.0.1:1")
+	code, _ = request(New(Config{Targets: []*url.URL{down}}), http.MethodGet, "/", nil)
+	Equal(t, code, http.StatusBadGateway)
+
+	PanicsWithValue(t, func() { New(Config{}) }, "proxy: at least one target or a resolver is required")
+}
+
+func TestHedge(t *testing.T) {
+	var slowHits, fastHits atomic.Int32
+	slow := upstream(t, "slow", 300*time.Millisecond, http.StatusOK, &slowHits)
+	fast := upstream(t, "fast", 0, http.StatusOK, &fastHits)
+	failing := upstream(t, "failing", 0, http.StatusServiceUnavailable, nil)
+
+	h := New(Config{Targets: []*url.URL{slow, fast}, HedgeDelay: 20 * time.Millisecond})
+	start := time.Now()
+	code, body := request(h, http.MethodGet, "/", nil)
+	Equal(t, code, http.StatusOK)
+	Equal(t, body, "fast /")
+	Equal(t, time.Since(start) < 300*time.Millisecond, true)
+	Equal(t, slowHits.Load(), int32(1))
+	Equal(t, fastHits.Load(), int32(1))
+
+	// requests with a body aren't hedged
+	h = New(Config{Targets: []*url.URL{slow, fast}, HedgeDelay: 20 * time.Millisecond})
+	code, body = request(h, http.MethodPost, "/", strings.NewReader("body"))
+	Equal(t, code, http.StatusOK)
+	Equal(t, body, "slow /")
+	Equal(t, fastHits.Load(), int32(1))
+
+	// failures are hedged right away
+	h = New(Config{Targets: []*url.URL{failing, fast}, HedgeDelay: time.Hour})
+	code, body = request(h, http.MethodGet, "/", nil)
+	Equal(t, code, http.StatusOK)
+	Equal(t, body, "fast /")
+
+	// the response of the failed attempt is used when both fail
+	down, _ := url.Parse("http://127.0.0.1:1")
+	h = New(Config{Targets: []*url.URL{failing, down}, HedgeDelay: time.Hour})
+	code, body = request(h, http.MethodGet, "/", nil)
+	Equal(t, code, http.StatusServiceUnavailable)
+	Equal(t, body, "failing /")
+}
+
+func TestAffinity(t *testing.T) {
+	a := upstream(t, "a", 0, http.StatusOK, nil)
+	b := upstream(t, "b", 0, http.StatusOK, nil)
+	c := upstream(t, "c", 0, http.StatusOK, nil)
+	h := New(Config{
+		Targets:  []*url.URL{a, b, c},
+		Affinity: &Affinity{Header: "X-User-Id", Cookie: "upstream", MaxAge: time.Hour},
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	Equal(t, w.Body.String(), "a /")
+	cookie := w.Result().Cookies()[0]
+	Equal(t, cookie.Name, "upstream")
+	Equal(t, cookie.MaxAge, 3600)
+	Equal(t, cookie.HttpOnly, true)
+
+	for i := 0; i < 3; i++ {
+		r = httptest.NewRequest(http.MethodGet, "/", nil)
+		r.AddCookie(cookie)
+		w = httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		Equal(t, w.Body.String(), "a /")
+		Equal(t, len(w.Result().Cookies()), 0)
+	}
+
+	// unknown targets are replaced
+	r = httptest.NewRequest(http.MethodGet, "/", nil)
+	r.AddCookie(&http.Cookie{Name: "upstream", Value: "evicted"})
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	Equal(t, len(w.Result().Cookies()), 1)
+
+	var body string
+	for i := 0; i < 3; i++ {
+		r = httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("X-User-Id", "joeybloggs")
+		w = httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if i > 0 {
+			Equal(t, w.Body.String(), body)
+		}
+		body = w.Body.String()
+		Equal(t, len(w.Result().Cookies()), 0)
+	}
+}
+
+func TestHealthCheck(t *testing.T) {
+	var healthy atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/base/health" && !healthy.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		_, _ = io.WriteString(w, "a")
+	}))
+	defer srv.Close()
+
+	a, _ := url.Parse(srv.URL + "/base")
+	b := upstream(t, "b", 0, http.StatusOK, nil)
+	p := New(Config{
+		Targets:     []*url.URL{a, b},
+		HealthCheck: &HealthCheck{Path: "/health", Interval: 10 * time.Millisecond, Failures: 2},
+	})
+	defer p.Close()
+
+	time.Sleep(50 * time.Millisecond)
+	for i := 0; i < 4; i++ {
+		_, body := request(p, http.MethodGet, "/", nil)
+		Equal(t, body, "b /")
+	}
+
+	healthy.Store(true)
+	time.Sleep(50 * time.Millisecond)
+	_, body1 := request(p, http.MethodGet, "/", nil)
+	_, body2 := request(p, http.MethodGet, "/", nil)
+	NotEqual(t, body1, body2)
+
+	p.Close()
+	p.Close()
+}
+
+func TestResolver(t *testing.T) {
+	a := upstream(t, "a", 0, http.StatusOK, nil)
+	b := upstream(t, "b", 0, http.StatusOK, nil)
+
+	var calls atomic.Int32
+	var fail atomic.Bool
+	resolved := make(chan struct{}, 10)
+	p := New(Config{
+		Resolver: ResolverFunc(func(ctx context.Context) ([]*url.URL, error) {
+			defer func() {
+				select {
+				case resolved <- struct{}{}:
+				default:
+				}
+			}()
+			if fail.Load() {
+				return nil, errors.New("lookup failed")
+			}
+
+			if calls.Add(1) == 1 {
+				return []*url.URL{a}, nil
+			}
+			return []*url.URL{b}, nil
+		}),
+		ResolveInterval: 20 * time.Millisecond,
+	})
+	defer p.Close()
+
+	// the first request waits for the targets
+	_, body := request(p, http.MethodGet, "/", nil)
+	Equal(t, body, "a /")
+
+	<-resolved
+	<-resolved
+	_, body = request(p, http.MethodGet, "/", nil)
+	Equal(t, body, "b /")
+
+	// failures keep the previous targets
+	fail.Store(true)
+	<-resolved
+	<-resolved
+	_, body = request(p, http.MethodGet, "/", nil)
+	Equal(t, body, "b /")
+
+	p = New(Config{Resolver: Static(), ResolveInterval: time.Hour})
+	defer p.Close()
+	code, _ := request(p, http.MethodGet, "/", nil)
+	Equal(t, code, http.StatusBadGateway)
+
+	for i := 0; i < 100; i++ {
+		d := jitter(time.Second)
+		Equal(t, d >= 800*time.Millisecond && d <= 1200*time.Millisecond, true)
+	}
+}
@@ -0,0 +1,111 @@
+package proxy
+
+import (
+	"context"
+	"math/rand/v2"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultResolveInterval = 30 * time.Second
+	minResolveBackoff      = time.Second
+)
+
+// Resolver resolves the targets of the proxy.
+type Resolver interface {
+	Resolve(ctx context.Context) ([]*url.URL, error)
+}
+
+// ResolverFunc is a function resolving the targets.
+type ResolverFunc func(ctx context.Context) ([]*url.URL, error)
+
+// Resolve calls f(ctx).
+func (f ResolverFunc) Resolve(ctx context.Context) ([]*url.URL, error) {
+	return f(ctx)
+}
+
+// Static returns a resolver of a fixed list of targets.
+func Static(targets ...*url.URL) Resolver {
+	return ResolverFunc(func(context.Context) ([]*url.URL, error) {
+		return targets, nil
+	})
+}
+
+// SRV returns a resolver looking up the DNS SRV records of the service, i.e. _http._tcp.api.example.com
+// for SRV("http", "http", "tcp", "api.example.com"), resolving to the targets with the lowest priority
+// using the scheme.
+func SRV(scheme, service, proto, name string) Resolver {
+	return ResolverFunc(func(ctx context.Context) ([]*url.URL, error) {
+		_, records, err := net.DefaultResolver.LookupSRV(ctx, service, proto, name)
+		if err != nil {
+			return nil, err
+		}
+
+		targets := make([]*url.URL, 0, len(records))
+		for _, r := range records {
+			// records are sorted by priority
+			if r.Priority != records[0].Priority {
+				break
+			}
+
+			host := net.JoinHostPort(strings.TrimSuffix(r.Target, "."), strconv.Itoa(int(r.Port)))
+			targets = append(targets, &url.URL{Scheme: scheme, Host: host})
+		}
+		return targets, nil
+	})
+}
+
+// resolve resolves the targets every jittered interval until the proxy is closed,
+// failed or empty resolutions keep the previous targets and are retried with exponential backoff.
+func (p *Proxy) resolve(r Resolver, interval time.Duration) {
+	if interval <= 0 {
+		interval = defaultResolveInterval
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-p.done
+		cancel()
+	}()
+
+	var backoff time.Duration
+	first := true
+	for {
+		targets, err := r.Resolve(ctx)
+		if err == nil && len(targets) == 0 {
+			err = ErrNoTargets
+		}
+
+		delay := interval
+		if err != nil {
+			backoff = min(max(backoff*2, minResolveBackoff), interval)
+			delay = backoff
+		} else {
+			backoff = 0
+			p.setTargets(targets)
+		}
+
+		if first {
+			first = false
+			close(p.ready)
+		}
+
+		timer := time.NewTimer(jitter(delay))
+		select {
+		case <-p.done:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// jitter returns d randomly changed by up to 20%.
+func jitter(d time.Duration) time.Duration {
+	return d + time.Duration(rand.Int64N(int64(d)/5*2+1)) - d/5
+}
@@ -0,0 +1,114 @@
+package feather
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Redirect replies to the request with a redirect to target with the status code, keeping the query
+// of the request if target has none. Relative targets are resolved against the request path, see http.Redirect.
+func Redirect(w http.ResponseWriter, r *http.Request, status int, target string) {
+	if r.URL.RawQuery != blank {
+		path, fragment := target, blank
+		if i := strings.IndexByte(target, '#'); i != -1 {
+			path, fragment = target[:i], target[i:]
+		}
+
+		if !strings.Contains(path, "?") {
+			target = path + "?" + r.URL.RawQuery + fragment
+		}
+	}
+
+	http.Redirect(w, r, target, status)
+}
+
+// Redirect permanently redirects the requests for path to target, for all methods registered by Any,
+// keeping the query of the request, so URL migrations don't require writing handlers.
+// GET and HEAD requests are answered with 301 and other methods with 308, keeping their method and body.
+// The params and the wildcard of path can be used in target, which is not prefixed with the group prefix, i.e.
+//
+//	p.Redirect("/users/:id", "/members/:id")
+//	p.Redirect("/docs/*", "https://docs.example.com/*")
+func (g *routeGroup) Redirect(path string, target string) {
+	build := redirectTemplate(target)
+	g.Any(path, func(w http.ResponseWriter, r *http.Request) {
+		status := http.StatusPermanentRedirect
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			status = http.StatusMovedPermanently
+		}
+
+		Redirect(w, r, status, build(r))
+	})
+}
+
+// redirectTemplate returns a func building target for a request,
+// replacing the param segments of target by the URL params and '*' by the wildcard.
+func redirectTemplate(target string) func(r *http.Request) string {
+	segments := strings.Split(target, basePath)
+	var dynamic bool
+	for _, s := range segments {
+		if hasParam(s) || s == string(wildByte) {
+			dynamic = true
+			break
+		}
+	}
+
+	if !dynamic {
+		return func(*http.Request) string { return target }
+	}
+
+	return func(r *http.Request) string {
+		rv := RequestVars(r)
+		escape := url.PathEscape
+		if v, ok := requestVarsOf(r); ok && v.mux.rawParams {
+			// the params are escaped already, see SetParamsDecoding
+			escape = func(s string) string { return s }
+		}
+
+		var b strings.Builder
+		for i, s := range segments {
+			if i > 0 {
+				b.WriteString(basePath)
+			}
+
+			switch {
+			case s == blank:
+			case hasParam(s):
+				writeParams(&b, s, rv, escape)
+			case s == string(wildByte):
+				wildcard := strings.Split(rv.Wildcard(), basePath)
+				for j := range wildcard {
+					wildcard[j] = escape(wildcard[j])
+				}
+				b.WriteString(strings.Join(wildcard, basePath))
+			default:
+				b.WriteString(s)
+			}
+		}
+		return b.String()
+	}
+}
+
+// writeParams writes the segment of a redirect target replacing its params by the URL params.
+func writeParams(b *strings.Builder, segment string, rv ReqVars, escape func(string) string) {
+	for {
+		i := strings.IndexByte(segment, paramByte)
+		if i == -1 {
+			b.WriteString(segment)
+			return
+		}
+
+		b.WriteString(segment[:i])
+		name := segment[i+1 : paramNameEnd(segment, i)]
+		if name == blank {
+			// not a param, like the ':' of a scheme
+			b.WriteByte(paramByte)
+			segment = segment[i+1:]
+			continue
+		}
+
+		b.WriteString(escape(rv.URLParam(name)))
+		segment = segment[paramEnd(segment, i):]
+	}
+}
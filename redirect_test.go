@@ -0,0 +1,59 @@
+package feather
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/pchchv/feather/assert"
+)
+
+func TestRedirectHelper(t *testing.T) {
+	tests := []struct {
+		url      string
+		target   string
+		location string
+	}{
+		{url: "/old", target: "/new", location: "/new"},
+		{url: "/old?page=2", target: "/new", location: "/new?page=2"},
+		{url: "/old?page=2", target: "/new#top", location: "/new?page=2#top"},
+		{url: "/old?page=2", target: "/new?sort=name", location: "/new?sort=name"},
+		{url: "/old?page=2", target: "https://example.com/new", location: "https://example.com/new?page=2"},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, tt.url, nil)
+		w := httptest.NewRecorder()
+		Redirect(w, r, http.StatusFound, tt.target)
+		Equal(t, w.Code, http.StatusFound)
+		Equal(t, w.Header().Get("Location"), tt.location)
+	}
+}
+
+func TestGroupRedirect(t *testing.T) {
+	p := New()
+	p.Redirect("/old", "/new")
+	p.Redirect("/users/:id", "/members/:id")
+	p.Group("/v1").Redirect("/docs/*", "https://docs.example.com/*")
+
+	tests := []struct {
+		method   string
+		url      string
+		code     int
+		location string
+	}{
+		{method: http.MethodGet, url: "/old?page=2", code: http.StatusMovedPermanently, location: "/new?page=2"},
+		{method: http.MethodHead, url: "/old", code: http.StatusMovedPermanently, location: "/new"},
+		{method: http.MethodPost, url: "/old", code: http.StatusPermanentRedirect, location: "/new"},
+		{method: http.MethodGet, url: "/users/a%20b", code: http.StatusMovedPermanently, location: "/members/a%20b"},
+		{method: http.MethodGet, url: "/v1/docs/guide/intro", code: http.StatusMovedPermanently, location: "https://docs.example.com/guide/intro"},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest(tt.method, tt.url, nil)
+		w := httptest.NewRecorder()
+		p.Serve().ServeHTTP(w, r)
+		Equal(t, w.Code, tt.code)
+		Equal(t, w.Header().Get("Location"), tt.location)
+	}
+}
@@ -0,0 +1,138 @@
+package feather
+
+import (
+	"crypto/tls"
+	"errors"
+	"os"
+	"os/signal"
+	"time"
+)
+
+const defaultWatchInterval = 5 * time.Second
+
+// Reload reloads the TLS certificate, when serving using ListenAndServeTLS, and calls OnReload.
+// It is called when one of the ReloadSignals is received or one of the WatchFiles is modified,
+// concurrent reloads are serialized. If the certificate can't be loaded the previous one is kept.
+func (s *Server) Reload() error {
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+
+	var errs []error
+	if s.certFile != blank {
+		errs = append(errs, s.loadCertificate())
+	}
+
+	if s.OnReload != nil {
+		errs = append(errs, s.OnReload())
+	}
+
+	return errors.Join(errs...)
+}
+
+func (s *Server) loadCertificate() error {
+	cert, err := tls.LoadX509KeyPair(s.certFile, s.keyFile)
+	if err != nil {
+		return err
+	}
+
+	s.cert.Store(&cert)
+	return nil
+}
+
+// watchReloads reloads on the ReloadSignals and modifications of the WatchFiles until done is closed,
+// the returned func waits for the watchers to stop.
+func (s *Server) watchReloads(done <-chan struct{}) func() {
+	reloads := make(chan struct{}, 1)
+	trigger := func() {
+		select {
+		case reloads <- struct{}{}:
+		default:
+		}
+	}
+
+	stopped := make(chan struct{})
+	var stopSignals func()
+	if len(s.ReloadSignals) > 0 {
+		sigs := make(chan os.Signal, 1)
+		signal.Notify(sigs, s.ReloadSignals...)
+		stopSignals = func() { signal.Stop(sigs) }
+		go func() {
+			for {
+				select {
+				case <-sigs:
+					trigger()
+				case <-done:
+					return
+				}
+			}
+		}()
+	}
+
+	if len(s.WatchFiles) > 0 {
+		go s.watchFiles(done, trigger)
+	}
+
+	go func() {
+		defer close(stopped)
+		for {
+			select {
+			case <-reloads:
+				err := s.Reload()
+				if s.OnReloadComplete != nil {
+					s.OnReloadComplete(err)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		if stopSignals != nil {
+			stopSignals()
+		}
+		<-stopped
+	}
+}
+
+// watchFiles polls the modification times and sizes of the WatchFiles, calling trigger when they change.
+func (s *Server) watchFiles(done <-chan struct{}, trigger func()) {
+	interval := s.WatchInterval
+	if interval <= 0 {
+		interval = defaultWatchInterval
+	}
+
+	type state struct {
+		modTime time.Time
+		size    int64
+	}
+
+	stat := func() []state {
+		states := make([]state, len(s.WatchFiles))
+		for i, name := range s.WatchFiles {
+			if fi, err := os.Stat(name); err == nil {
+				states[i] = state{modTime: fi.ModTime(), size: fi.Size()}
+			}
+		}
+		return states
+	}
+
+	last := stat()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			current := stat()
+			for i := range current {
+				if current[i] != last[i] {
+					trigger()
+					break
+				}
+			}
+			last = current
+		case <-done:
+			return
+		}
+	}
+}
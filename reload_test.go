@@ -0,0 +1,130 @@
+package feather
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/pchchv/feather/assert"
+)
+
+func writeCertificate(t *testing.T, certFile, keyFile string, serial int64) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Equal(t, err, nil)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	Equal(t, err, nil)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	Equal(t, err, nil)
+
+	Equal(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600), nil)
+	Equal(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600), nil)
+}
+
+func TestServerReloadCertificate(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	writeCertificate(t, certFile, keyFile, 1)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	Equal(t, err, nil)
+	addr := l.Addr().String()
+	_ = l.Close()
+
+	reloads := 0
+	s := NewServer(addr, New().Serve())
+	s.Signals = nil
+	s.ReloadSignals = nil
+	s.OnReload = func() error {
+		reloads++
+		return nil
+	}
+
+	served := make(chan error, 1)
+	go func() {
+		served <- s.ListenAndServeTLS(certFile, keyFile)
+	}()
+
+	serial := func() int64 {
+		var conn *tls.Conn
+		var err error
+		for i := 0; i < 100; i++ {
+			if conn, err = tls.Dial("tcp", addr, &tls.Config{InsecureSkipVerify: true}); err == nil {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		Equal(t, err, nil)
+		defer conn.Close()
+		return conn.ConnectionState().PeerCertificates[0].SerialNumber.Int64()
+	}
+
+	Equal(t, serial(), int64(1))
+	writeCertificate(t, certFile, keyFile, 2)
+	Equal(t, s.Reload(), nil)
+	Equal(t, serial(), int64(2))
+	Equal(t, reloads, 1)
+
+	// the previous certificate is kept when the files are invalid
+	Equal(t, os.WriteFile(keyFile, []byte("invalid"), 0o600), nil)
+	NotEqual(t, s.Reload(), nil)
+	Equal(t, serial(), int64(2))
+
+	s.Stop()
+	Equal(t, <-served, nil)
+}
+
+func TestServerReloadWatchFiles(t *testing.T) {
+	config := filepath.Join(t.TempDir(), "config.json")
+	Equal(t, os.WriteFile(config, []byte("{}"), 0o600), nil)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	Equal(t, err, nil)
+
+	reloaded := make(chan error, 1)
+	reloadErr := errors.New("invalid config")
+	s := NewServer(l.Addr().String(), http.NotFoundHandler())
+	s.Signals = nil
+	s.WatchFiles = []string{config}
+	s.WatchInterval = 10 * time.Millisecond
+	s.OnReload = func() error {
+		return reloadErr
+	}
+	s.OnReloadComplete = func(err error) {
+		reloaded <- err
+	}
+
+	served := make(chan error, 1)
+	go func() {
+		served <- s.Serve(l)
+	}()
+
+	time.Sleep(30 * time.Millisecond)
+	Equal(t, os.WriteFile(config, []byte(`{"maintenance":true}`), 0o600), nil)
+	select {
+	case err := <-reloaded:
+		Equal(t, errors.Is(err, reloadErr), true)
+	case <-time.After(time.Second):
+		t.Fatal("not reloaded")
+	}
+
+	s.Stop()
+	Equal(t, <-served, nil)
+}
@@ -0,0 +1,145 @@
+package feather
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// EncoderFunc encodes v to the body of a response.
+type EncoderFunc func(w io.Writer, v interface{}) error
+
+type encoder struct {
+	mediaType   string
+	contentType string
+	fn          EncoderFunc
+}
+
+var (
+	encodersMu sync.RWMutex
+	// encoders in order of preference among equally acceptable media types, JSON first
+	encoders = []encoder{
+		{mediaType: applicationJSONNoCharset, contentType: applicationJSON, fn: encodeJSON},
+		{mediaType: applicationXMLNoCharset, contentType: applicationXML, fn: encodeXML},
+		{mediaType: textXML, contentType: textXML + charsetUTF8, fn: encodeXML},
+		{mediaType: textPlainNoCharset, contentType: textPlain, fn: encodeText},
+	}
+)
+
+// RegisterEncoder registers an encoder used by Render for the media type, i.e. application/vnd.myco+json.
+// The media type including its parameters, i.e. "application/vnd.myco+json; charset=utf-8",
+// is sent as the Content-Type of the responses.
+// Registering an encoder for a built-in media type, JSON, XML or plain text, replaces it.
+// The render/msgpack and render/yaml packages register MessagePack and YAML.
+// A nil fn removes the encoder of the media type.
+func RegisterEncoder(mediaType string, fn EncoderFunc) {
+	contentType := strings.TrimSpace(mediaType)
+	mediaType = contentType
+	if i := strings.IndexByte(mediaType, ';'); i != -1 {
+		mediaType = strings.TrimSpace(mediaType[:i])
+	}
+	mediaType = strings.ToLower(mediaType)
+
+	encodersMu.Lock()
+	defer encodersMu.Unlock()
+	for i, e := range encoders {
+		if e.mediaType == mediaType {
+			if fn == nil {
+				encoders = append(encoders[:i:i], encoders[i+1:]...)
+			} else {
+				encoders[i] = encoder{mediaType: mediaType, contentType: contentType, fn: fn}
+			}
+			return
+		}
+	}
+
+	if fn != nil {
+		encoders = append(encoders, encoder{mediaType: mediaType, contentType: contentType, fn: fn})
+	}
+}
+
+// Render encodes v using the encoder of the media type preferred by the Accept header of the request,
+// JSON, XML, plain text or any registered using RegisterEncoder.
+// JSON is used when the request has no Accept header.
+// If none of the media types is acceptable, 406 Not Acceptable is returned.
+//
+// v is encoded before the status is written, so encoding errors are returned
+// and can still be answered with an error response.
+func Render(w http.ResponseWriter, r *http.Request, status int, v interface{}) error {
+	AddVary(w, acceptHeader)
+
+	encodersMu.RLock()
+	offers := make([]string, len(encoders))
+	for i, e := range encoders {
+		offers[i] = e.mediaType
+	}
+
+	var enc encoder
+	if mediaType := NegotiateContentType(r, offers...); mediaType != blank {
+		for _, e := range encoders {
+			if e.mediaType == mediaType {
+				enc = e
+				break
+			}
+		}
+	}
+	encodersMu.RUnlock()
+
+	if enc.fn == nil {
+		http.Error(w, http.StatusText(http.StatusNotAcceptable), http.StatusNotAcceptable)
+		return nil
+	}
+
+	var buf bytes.Buffer
+	if err := enc.fn(&buf, v); err != nil {
+		return err
+	}
+
+	size := int64(buf.Cap())
+	if err := allocate(r, size, http.StatusInsufficientStorage); err != nil {
+		return err
+	}
+	defer Free(r, size)
+
+	w.Header().Set(contentTypeHeader, enc.contentType)
+	w.WriteHeader(status)
+	if enc.mediaType == applicationJSONNoCharset {
+		if err := writeJSONPrefix(w, buf.Bytes()); err != nil {
+			return err
+		}
+	}
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func encodeJSON(w io.Writer, v interface{}) error {
+	b, err := marshalJSON(v, blank)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(b)
+	return err
+}
+
+func encodeXML(w io.Writer, v interface{}) error {
+	b, err := xml.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	if _, err = w.Write(xmlHeaderBytes); err == nil {
+		_, err = w.Write(b)
+	}
+
+	return err
+}
+
+func encodeText(w io.Writer, v interface{}) error {
+	_, err := w.Write(plainText(v))
+	return err
+}
@@ -0,0 +1,100 @@
+// Package fields resolves the encoded fields of struct types for the codecs of the render packages.
+package fields
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// Field is an encoded struct field.
+type Field struct {
+	// Name is the field name or the name set using the tag.
+	Name string
+	// Index is the index sequence of the field for reflect.Value.FieldByIndex.
+	Index []int
+	// OmitEmpty is set by the omitempty tag option.
+	OmitEmpty bool
+}
+
+type key struct {
+	t   reflect.Type
+	tag string
+}
+
+var cache sync.Map // map[key][]Field
+
+// Of returns the encoded fields of the struct type named using the tag, i.e. msgpack or yaml,
+// which also supports omitempty and "-", just like the json tag.
+// The fields of embedded structs are promoted unless shadowed.
+func Of(t reflect.Type, tag string) []Field {
+	k := key{t: t, tag: tag}
+	if fields, ok := cache.Load(k); ok {
+		return fields.([]Field)
+	}
+
+	var fields []Field
+	var embedded []Field
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		value := sf.Tag.Get(tag)
+		if value == "-" {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(value, ",")
+		if sf.Anonymous && name == "" && sf.Type.Kind() == reflect.Struct && sf.Type != timeType {
+			for _, f := range Of(sf.Type, tag) {
+				f.Index = append([]int{i}, f.Index...)
+				embedded = append(embedded, f)
+			}
+			continue
+		}
+
+		if !sf.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = sf.Name
+		}
+
+		fields = append(fields, Field{Name: name, Index: []int{i}, OmitEmpty: opts == "omitempty"})
+	}
+
+	for _, f := range embedded {
+		shadowed := false
+		for _, o := range fields {
+			if o.Name == f.Name {
+				shadowed = true
+				break
+			}
+		}
+
+		if !shadowed {
+			fields = append(fields, f)
+		}
+	}
+
+	cache.Store(k, fields)
+	return fields
+}
+
+// ByName returns the field by name, preferring an exact match over a case insensitive one, nil if none.
+func ByName(fields []Field, name string) *Field {
+	var fold *Field
+	for i := range fields {
+		if fields[i].Name == name {
+			return &fields[i]
+		}
+
+		if fold == nil && strings.EqualFold(fields[i].Name, name) {
+			fold = &fields[i]
+		}
+	}
+
+	return fold
+}
@@ -0,0 +1,839 @@
+// Package msgpack provides the MessagePack codec, see https://github.com/msgpack/msgpack/blob/master/spec.md,
+// for the rendering and decoding functions of feather, which support it once Register is called:
+//
+//	msgpack.Register()
+//	...
+//	err := feather.Decode(r, feather.QueryParams, maxMemory, &v) // application/msgpack bodies
+//	err = feather.Render(w, r, http.StatusOK, v)                  // Accept: application/msgpack
+//
+// Structs are encoded as maps keyed by the field name or the name set using the msgpack tag,
+// which also supports omitempty and "-", just like the json tag.
+// time.Time is encoded using the timestamp extension type.
+// Extension types other than timestamps can't be decoded and return an error.
+package msgpack
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/pchchv/feather"
+	"github.com/pchchv/feather/render/internal/fields"
+)
+
+// MediaType is the media type of MessagePack.
+const MediaType = "application/msgpack"
+
+// aliases are media types also used for MessagePack bodies.
+var aliases = []string{"application/x-msgpack", "application/vnd.msgpack"}
+
+const tag = "msgpack"
+
+// Register registers the codec for MediaType with feather, used by feather.Render and feather.Negotiate
+// to encode responses and by feather.Decode to decode bodies of MediaType and its aliases.
+func Register() {
+	feather.RegisterEncoder(MediaType, encode)
+	feather.RegisterMarshaler(MediaType, MediaType, Marshal)
+	for _, mediaType := range append([]string{MediaType}, aliases...) {
+		feather.RegisterDecoder(mediaType, decode)
+	}
+}
+
+// Write marshals v and writes it as MessagePack with the status.
+func Write(w http.ResponseWriter, status int, v interface{}) error {
+	b, err := Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", MediaType)
+	w.WriteHeader(status)
+	_, err = w.Write(b)
+	return err
+}
+
+func encode(w io.Writer, v interface{}) error {
+	b, err := Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(b)
+	return err
+}
+
+func decode(body io.Reader, v interface{}) error {
+	b, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+
+	return Unmarshal(b, v)
+}
+
+const (
+	mpNil      = 0xc0
+	mpFalse    = 0xc2
+	mpTrue     = 0xc3
+	mpBin8     = 0xc4
+	mpBin16    = 0xc5
+	mpBin32    = 0xc6
+	mpExt8     = 0xc7
+	mpExt16    = 0xc8
+	mpExt32    = 0xc9
+	mpFloat32  = 0xca
+	mpFloat64  = 0xcb
+	mpUint8    = 0xcc
+	mpUint16   = 0xcd
+	mpUint32   = 0xce
+	mpUint64   = 0xcf
+	mpInt8     = 0xd0
+	mpInt16    = 0xd1
+	mpInt32    = 0xd2
+	mpInt64    = 0xd3
+	mpFixExt1  = 0xd4
+	mpFixExt2  = 0xd5
+	mpFixExt4  = 0xd6
+	mpFixExt8  = 0xd7
+	mpFixExt16 = 0xd8
+	mpStr8     = 0xd9
+	mpStr16    = 0xda
+	mpStr32    = 0xdb
+	mpArray16  = 0xdc
+	mpArray32  = 0xdd
+	mpMap16    = 0xde
+	mpMap32    = 0xdf
+	mpMaxDepth = 10000
+
+	mpTimestampExt     = -1   // ext type of timestamps
+	mpTimestampExtByte = 0xff // mpTimestampExt as encoded
+)
+
+var (
+	timeType = reflect.TypeOf(time.Time{})
+
+	errMsgPackTruncated = errors.New("msgpack: unexpected end of data")
+	errMsgPackDepth     = errors.New("msgpack: exceeded max depth")
+)
+
+// Marshal returns the MessagePack encoding of v.
+func Marshal(v interface{}) ([]byte, error) {
+	var e msgpackEncoder
+	if err := e.encode(reflect.ValueOf(v), 0); err != nil {
+		return nil, err
+	}
+
+	return e.b, nil
+}
+
+type msgpackEncoder struct {
+	b []byte
+}
+
+func (e *msgpackEncoder) encode(v reflect.Value, depth int) error {
+	if depth > mpMaxDepth {
+		return errMsgPackDepth
+	}
+
+	if !v.IsValid() {
+		e.b = append(e.b, mpNil)
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			e.b = append(e.b, mpNil)
+			return nil
+		}
+
+		return e.encode(v.Elem(), depth+1)
+	case reflect.Bool:
+		if v.Bool() {
+			e.b = append(e.b, mpTrue)
+		} else {
+			e.b = append(e.b, mpFalse)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		e.encodeInt(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		e.encodeUint(v.Uint())
+	case reflect.Float32:
+		e.b = append(e.b, mpFloat32)
+		e.b = binary.BigEndian.AppendUint32(e.b, math.Float32bits(float32(v.Float())))
+	case reflect.Float64:
+		e.b = append(e.b, mpFloat64)
+		e.b = binary.BigEndian.AppendUint64(e.b, math.Float64bits(v.Float()))
+	case reflect.String:
+		e.encodeString(v.String())
+	case reflect.Slice:
+		if v.IsNil() {
+			e.b = append(e.b, mpNil)
+			return nil
+		}
+
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			e.encodeBytes(v.Bytes())
+			return nil
+		}
+
+		return e.encodeArray(v, depth)
+	case reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			b := make([]byte, v.Len())
+			reflect.Copy(reflect.ValueOf(b), v)
+			e.encodeBytes(b)
+			return nil
+		}
+
+		return e.encodeArray(v, depth)
+	case reflect.Map:
+		if v.IsNil() {
+			e.b = append(e.b, mpNil)
+			return nil
+		}
+
+		return e.encodeMap(v, depth)
+	case reflect.Struct:
+		if v.Type() == timeType {
+			e.encodeTime(v.Interface().(time.Time))
+			return nil
+		}
+
+		return e.encodeStruct(v, depth)
+	default:
+		return fmt.Errorf("msgpack: unsupported type %s", v.Type())
+	}
+
+	return nil
+}
+
+func (e *msgpackEncoder) encodeInt(i int64) {
+	switch {
+	case i >= 0:
+		e.encodeUint(uint64(i))
+	case i >= -32:
+		e.b = append(e.b, byte(int8(i)))
+	case i >= math.MinInt8:
+		e.b = append(e.b, mpInt8, byte(int8(i)))
+	case i >= math.MinInt16:
+		e.b = append(e.b, mpInt16)
+		e.b = binary.BigEndian.AppendUint16(e.b, uint16(int16(i)))
+	case i >= math.MinInt32:
+		e.b = append(e.b, mpInt32)
+		e.b = binary.BigEndian.AppendUint32(e.b, uint32(int32(i)))
+	default:
+		e.b = append(e.b, mpInt64)
+		e.b = binary.BigEndian.AppendUint64(e.b, uint64(i))
+	}
+}
+
+func (e *msgpackEncoder) encodeUint(u uint64) {
+	switch {
+	case u < 0x80:
+		e.b = append(e.b, byte(u))
+	case u <= math.MaxUint8:
+		e.b = append(e.b, mpUint8, byte(u))
+	case u <= math.MaxUint16:
+		e.b = append(e.b, mpUint16)
+		e.b = binary.BigEndian.AppendUint16(e.b, uint16(u))
+	case u <= math.MaxUint32:
+		e.b = append(e.b, mpUint32)
+		e.b = binary.BigEndian.AppendUint32(e.b, uint32(u))
+	default:
+		e.b = append(e.b, mpUint64)
+		e.b = binary.BigEndian.AppendUint64(e.b, u)
+	}
+}
+
+// encodeLen appends the header of a value of length n using the fix, 8, 16 or 32 bit format,
+// fix and c8 are 0 if the format does not exist for the type.
+func (e *msgpackEncoder) encodeLen(n int, fix byte, fixMax int, c8, c16, c32 byte) {
+	switch {
+	case fix != 0 && n <= fixMax:
+		e.b = append(e.b, fix|byte(n))
+	case c8 != 0 && n <= math.MaxUint8:
+		e.b = append(e.b, c8, byte(n))
+	case n <= math.MaxUint16:
+		e.b = append(e.b, c16)
+		e.b = binary.BigEndian.AppendUint16(e.b, uint16(n))
+	default:
+		e.b = append(e.b, c32)
+		e.b = binary.BigEndian.AppendUint32(e.b, uint32(n))
+	}
+}
+
+func (e *msgpackEncoder) encodeString(s string) {
+	e.encodeLen(len(s), 0xa0, 31, mpStr8, mpStr16, mpStr32)
+	e.b = append(e.b, s...)
+}
+
+func (e *msgpackEncoder) encodeBytes(b []byte) {
+	e.encodeLen(len(b), 0, 0, mpBin8, mpBin16, mpBin32)
+	e.b = append(e.b, b...)
+}
+
+func (e *msgpackEncoder) encodeArray(v reflect.Value, depth int) error {
+	e.encodeLen(v.Len(), 0x90, 15, 0, mpArray16, mpArray32)
+	for i := 0; i < v.Len(); i++ {
+		if err := e.encode(v.Index(i), depth+1); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (e *msgpackEncoder) encodeMap(v reflect.Value, depth int) error {
+	e.encodeLen(v.Len(), 0x80, 15, 0, mpMap16, mpMap32)
+	keys := v.MapKeys()
+	if v.Type().Key().Kind() == reflect.String {
+		// sorted for a deterministic output
+		sort.Slice(keys, func(i, j int) bool {
+			return keys[i].String() < keys[j].String()
+		})
+	}
+
+	for _, k := range keys {
+		if err := e.encode(k, depth+1); err != nil {
+			return err
+		}
+
+		if err := e.encode(v.MapIndex(k), depth+1); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (e *msgpackEncoder) encodeStruct(v reflect.Value, depth int) error {
+	fs := fields.Of(v.Type(), tag)
+	n := 0
+	for _, f := range fs {
+		if !f.OmitEmpty || !v.FieldByIndex(f.Index).IsZero() {
+			n++
+		}
+	}
+
+	e.encodeLen(n, 0x80, 15, 0, mpMap16, mpMap32)
+	for _, f := range fs {
+		fv := v.FieldByIndex(f.Index)
+		if f.OmitEmpty && fv.IsZero() {
+			continue
+		}
+
+		e.encodeString(f.Name)
+		if err := e.encode(fv, depth+1); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// encodeTime appends t using the timestamp 96 format, which supports all times.
+func (e *msgpackEncoder) encodeTime(t time.Time) {
+	e.b = append(e.b, mpExt8, 12, mpTimestampExtByte)
+	e.b = binary.BigEndian.AppendUint32(e.b, uint32(t.Nanosecond()))
+	e.b = binary.BigEndian.AppendUint64(e.b, uint64(t.Unix()))
+}
+
+// Unmarshal decodes the MessagePack data into the value v points to.
+func Unmarshal(b []byte, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("msgpack: decode requires a non-nil pointer, got %T", v)
+	}
+
+	d := msgpackDecoder{b: b}
+	return d.decode(rv.Elem(), 0)
+}
+
+type msgpackDecoder struct {
+	b   []byte
+	off int
+}
+
+func (d *msgpackDecoder) read(n int) ([]byte, error) {
+	if n < 0 || n > len(d.b)-d.off {
+		return nil, errMsgPackTruncated
+	}
+
+	b := d.b[d.off : d.off+n]
+	d.off += n
+	return b, nil
+}
+
+func (d *msgpackDecoder) readUint(size int) (uint64, error) {
+	b, err := d.read(size)
+	if err != nil {
+		return 0, err
+	}
+
+	switch size {
+	case 1:
+		return uint64(b[0]), nil
+	case 2:
+		return uint64(binary.BigEndian.Uint16(b)), nil
+	case 4:
+		return uint64(binary.BigEndian.Uint32(b)), nil
+	default:
+		return binary.BigEndian.Uint64(b), nil
+	}
+}
+
+// readLen reads a length of the given size in bytes,
+// ensuring at least min bytes per element remain so corrupt lengths can't cause huge allocations.
+func (d *msgpackDecoder) readLen(size int, min int) (int, error) {
+	n, err := d.readUint(size)
+	if err != nil {
+		return 0, err
+	}
+
+	if n*uint64(min) > uint64(len(d.b)-d.off) {
+		return 0, errMsgPackTruncated
+	}
+
+	return int(n), nil
+}
+
+// msgpackHeader is a decoded value header.
+type msgpackHeader struct {
+	kind  reflect.Kind // Bool, Int64, Uint64, Float32, Float64, String, Slice (bin), Array, Map, Struct (ext) or Invalid (nil)
+	i     int64
+	u     uint64
+	f     float64
+	n     int  // length of str, bin, array, map and ext
+	ext   int8 // ext type
+	value bool
+}
+
+func (d *msgpackDecoder) readHeader() (h msgpackHeader, err error) {
+	b, err := d.read(1)
+	if err != nil {
+		return
+	}
+
+	c := b[0]
+	switch {
+	case c < 0x80:
+		h.kind, h.u = reflect.Uint64, uint64(c)
+		return
+	case c >= 0xe0:
+		h.kind, h.i = reflect.Int64, int64(int8(c))
+		return
+	case c&0xf0 == 0x80:
+		h.kind, h.n = reflect.Map, int(c&0x0f)
+		return
+	case c&0xf0 == 0x90:
+		h.kind, h.n = reflect.Array, int(c&0x0f)
+		return
+	case c&0xe0 == 0xa0:
+		h.kind, h.n = reflect.String, int(c&0x1f)
+		return
+	}
+
+	var u uint64
+	switch c {
+	case mpNil:
+		h.kind = reflect.Invalid
+	case mpFalse, mpTrue:
+		h.kind, h.value = reflect.Bool, c == mpTrue
+	case mpUint8, mpUint16, mpUint32, mpUint64:
+		h.kind = reflect.Uint64
+		h.u, err = d.readUint(1 << (c - mpUint8))
+	case mpInt8, mpInt16, mpInt32, mpInt64:
+		h.kind = reflect.Int64
+		size := 1 << (c - mpInt8)
+		if u, err = d.readUint(size); err == nil {
+			// sign extend
+			shift := 64 - 8*size
+			h.i = int64(u<<shift) >> shift
+		}
+	case mpFloat32:
+		h.kind = reflect.Float32
+		if u, err = d.readUint(4); err == nil {
+			h.f = float64(math.Float32frombits(uint32(u)))
+		}
+	case mpFloat64:
+		h.kind = reflect.Float64
+		if u, err = d.readUint(8); err == nil {
+			h.f = math.Float64frombits(u)
+		}
+	case mpStr8, mpStr16, mpStr32:
+		h.kind = reflect.String
+		h.n, err = d.readLen(1<<(c-mpStr8), 1)
+	case mpBin8, mpBin16, mpBin32:
+		h.kind = reflect.Slice
+		h.n, err = d.readLen(1<<(c-mpBin8), 1)
+	case mpArray16, mpArray32:
+		h.kind = reflect.Array
+		h.n, err = d.readLen(2<<(c-mpArray16), 1)
+	case mpMap16, mpMap32:
+		h.kind = reflect.Map
+		h.n, err = d.readLen(2<<(c-mpMap16), 2)
+	case mpFixExt1, mpFixExt2, mpFixExt4, mpFixExt8, mpFixExt16:
+		h.kind, h.n = reflect.Struct, 1<<(c-mpFixExt1)
+		err = d.readExtType(&h)
+	case mpExt8, mpExt16, mpExt32:
+		h.kind = reflect.Struct
+		if h.n, err = d.readLen(1<<(c-mpExt8), 1); err == nil {
+			err = d.readExtType(&h)
+		}
+	default:
+		err = fmt.Errorf("msgpack: invalid code 0x%x", c)
+	}
+
+	return
+}
+
+func (d *msgpackDecoder) readExtType(h *msgpackHeader) error {
+	b, err := d.read(1)
+	if err != nil {
+		return err
+	}
+
+	h.ext = int8(b[0])
+	return nil
+}
+
+func (d *msgpackDecoder) readTime(h msgpackHeader) (t time.Time, err error) {
+	if h.ext != mpTimestampExt {
+		return t, fmt.Errorf("msgpack: unsupported ext type %d", h.ext)
+	}
+
+	b, err := d.read(h.n)
+	if err != nil {
+		return
+	}
+
+	switch h.n {
+	case 4:
+		return time.Unix(int64(binary.BigEndian.Uint32(b)), 0), nil
+	case 8:
+		u := binary.BigEndian.Uint64(b)
+		return time.Unix(int64(u&0x3ffffffff), int64(u>>34)), nil
+	case 12:
+		return time.Unix(int64(binary.BigEndian.Uint64(b[4:])), int64(binary.BigEndian.Uint32(b))), nil
+	}
+
+	return t, fmt.Errorf("msgpack: invalid timestamp length %d", h.n)
+}
+
+func (d *msgpackDecoder) decode(v reflect.Value, depth int) error {
+	if depth > mpMaxDepth {
+		return errMsgPackDepth
+	}
+
+	h, err := d.readHeader()
+	if err != nil {
+		return err
+	}
+
+	return d.decodeValue(h, v, depth)
+}
+
+func (d *msgpackDecoder) decodeValue(h msgpackHeader, v reflect.Value, depth int) error {
+	if h.kind == reflect.Invalid {
+		switch v.Kind() {
+		case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice:
+			v.Set(reflect.Zero(v.Type()))
+		}
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+
+		return d.decodeValue(h, v.Elem(), depth+1)
+	case reflect.Interface:
+		if v.NumMethod() != 0 {
+			break
+		}
+
+		i, err := d.decodeInterface(h, depth)
+		if err != nil {
+			return err
+		}
+
+		if i == nil {
+			v.Set(reflect.Zero(v.Type()))
+		} else {
+			v.Set(reflect.ValueOf(i))
+		}
+		return nil
+	}
+
+	switch h.kind {
+	case reflect.Bool:
+		if v.Kind() == reflect.Bool {
+			v.SetBool(h.value)
+			return nil
+		}
+	case reflect.Int64, reflect.Uint64, reflect.Float32, reflect.Float64:
+		return decodeMsgPackNumber(h, v)
+	case reflect.String, reflect.Slice:
+		b, err := d.read(h.n)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case v.Kind() == reflect.String:
+			v.SetString(string(b))
+			return nil
+		case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8:
+			v.SetBytes(append([]byte(nil), b...))
+			return nil
+		case v.Kind() == reflect.Array && v.Type().Elem().Kind() == reflect.Uint8 && v.Len() == len(b):
+			reflect.Copy(v, reflect.ValueOf(b))
+			return nil
+		}
+	case reflect.Array:
+		switch v.Kind() {
+		case reflect.Slice:
+			s := reflect.MakeSlice(v.Type(), h.n, h.n)
+			for i := 0; i < h.n; i++ {
+				if err := d.decode(s.Index(i), depth+1); err != nil {
+					return err
+				}
+			}
+			v.Set(s)
+			return nil
+		case reflect.Array:
+			if v.Len() != h.n {
+				return fmt.Errorf("msgpack: cannot decode array of length %d into %s", h.n, v.Type())
+			}
+
+			for i := 0; i < h.n; i++ {
+				if err := d.decode(v.Index(i), depth+1); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	case reflect.Map:
+		switch v.Kind() {
+		case reflect.Map:
+			return d.decodeMap(h, v, depth)
+		case reflect.Struct:
+			return d.decodeStruct(h, v, depth)
+		}
+	case reflect.Struct:
+		if v.Type() == timeType {
+			t, err := d.readTime(h)
+			if err != nil {
+				return err
+			}
+
+			v.Set(reflect.ValueOf(t))
+			return nil
+		}
+	}
+
+	return fmt.Errorf("msgpack: cannot decode %s into %s", msgpackKindName(h), v.Type())
+}
+
+func decodeMsgPackNumber(h msgpackHeader, v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i := h.i
+		switch h.kind {
+		case reflect.Uint64:
+			if h.u > math.MaxInt64 {
+				return fmt.Errorf("msgpack: %d overflows %s", h.u, v.Type())
+			}
+			i = int64(h.u)
+		case reflect.Float32, reflect.Float64:
+			return fmt.Errorf("msgpack: cannot decode float into %s", v.Type())
+		}
+
+		if v.OverflowInt(i) {
+			return fmt.Errorf("msgpack: %d overflows %s", i, v.Type())
+		}
+
+		v.SetInt(i)
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		u := h.u
+		switch h.kind {
+		case reflect.Int64:
+			if h.i < 0 {
+				return fmt.Errorf("msgpack: %d overflows %s", h.i, v.Type())
+			}
+			u = uint64(h.i)
+		case reflect.Float32, reflect.Float64:
+			return fmt.Errorf("msgpack: cannot decode float into %s", v.Type())
+		}
+
+		if v.OverflowUint(u) {
+			return fmt.Errorf("msgpack: %d overflows %s", u, v.Type())
+		}
+
+		v.SetUint(u)
+		return nil
+	case reflect.Float32, reflect.Float64:
+		f := h.f
+		switch h.kind {
+		case reflect.Int64:
+			f = float64(h.i)
+		case reflect.Uint64:
+			f = float64(h.u)
+		}
+
+		v.SetFloat(f)
+		return nil
+	}
+
+	return fmt.Errorf("msgpack: cannot decode %s into %s", msgpackKindName(h), v.Type())
+}
+
+func (d *msgpackDecoder) decodeMap(h msgpackHeader, v reflect.Value, depth int) error {
+	t := v.Type()
+	if v.IsNil() {
+		v.Set(reflect.MakeMapWithSize(t, h.n))
+	}
+
+	for i := 0; i < h.n; i++ {
+		k := reflect.New(t.Key()).Elem()
+		if err := d.decode(k, depth+1); err != nil {
+			return err
+		}
+
+		e := reflect.New(t.Elem()).Elem()
+		if err := d.decode(e, depth+1); err != nil {
+			return err
+		}
+
+		v.SetMapIndex(k, e)
+	}
+
+	return nil
+}
+
+func (d *msgpackDecoder) decodeStruct(h msgpackHeader, v reflect.Value, depth int) error {
+	fs := fields.Of(v.Type(), tag)
+	for i := 0; i < h.n; i++ {
+		var name string
+		if err := d.decode(reflect.ValueOf(&name).Elem(), depth+1); err != nil {
+			return err
+		}
+
+		f := fields.ByName(fs, name)
+		if f == nil {
+			if err := d.skip(depth + 1); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := d.decode(v.FieldByIndex(f.Index), depth+1); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (d *msgpackDecoder) skip(depth int) error {
+	_, err := d.decodeInterfaceValue(depth)
+	return err
+}
+
+func (d *msgpackDecoder) decodeInterfaceValue(depth int) (interface{}, error) {
+	if depth > mpMaxDepth {
+		return nil, errMsgPackDepth
+	}
+
+	h, err := d.readHeader()
+	if err != nil {
+		return nil, err
+	}
+
+	return d.decodeInterface(h, depth)
+}
+
+// decodeInterface decodes a value of unknown type,
+// integers are decoded as int64 unless they overflow it, floats as float64 and maps as map[string]interface{}.
+func (d *msgpackDecoder) decodeInterface(h msgpackHeader, depth int) (interface{}, error) {
+	switch h.kind {
+	case reflect.Invalid:
+		return nil, nil
+	case reflect.Bool:
+		return h.value, nil
+	case reflect.Int64:
+		return h.i, nil
+	case reflect.Uint64:
+		if h.u <= math.MaxInt64 {
+			return int64(h.u), nil
+		}
+		return h.u, nil
+	case reflect.Float32, reflect.Float64:
+		return h.f, nil
+	case reflect.String:
+		b, err := d.read(h.n)
+		return string(b), err
+	case reflect.Slice:
+		b, err := d.read(h.n)
+		return append([]byte(nil), b...), err
+	case reflect.Array:
+		s := make([]interface{}, h.n)
+		for i := range s {
+			e, err := d.decodeInterfaceValue(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			s[i] = e
+		}
+		return s, nil
+	case reflect.Map:
+		m := make(map[string]interface{}, h.n)
+		for i := 0; i < h.n; i++ {
+			k, err := d.decodeInterfaceValue(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+
+			e, err := d.decodeInterfaceValue(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+
+			if s, ok := k.(string); ok {
+				m[s] = e
+			} else {
+				m[fmt.Sprint(k)] = e
+			}
+		}
+		return m, nil
+	default:
+		return d.readTime(h)
+	}
+}
+
+func msgpackKindName(h msgpackHeader) string {
+	switch h.kind {
+	case reflect.Int64, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "float"
+	case reflect.Slice:
+		return "bin"
+	case reflect.Array:
+		return "array"
+	case reflect.Struct:
+		return "ext"
+	}
+
+	return h.kind.String()
+}
@@ -0,0 +1,245 @@
+package msgpack
+
+import (
+	"bytes"
+	"compress/gzip"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pchchv/feather"
+	. "github.com/pchchv/feather/assert"
+)
+
+func TestMsgPackEncoding(t *testing.T) {
+	tests := []struct {
+		v        interface{}
+		expected []byte
+	}{
+		{nil, []byte{0xc0}},
+		{true, []byte{0xc3}},
+		{false, []byte{0xc2}},
+		{7, []byte{0x07}},
+		{-7, []byte{0xf9}},
+		{200, []byte{0xcc, 0xc8}},
+		{-100, []byte{0xd0, 0x9c}},
+		{1000, []byte{0xcd, 0x03, 0xe8}},
+		{-1000, []byte{0xd1, 0xfc, 0x18}},
+		{uint64(math.MaxUint64), []byte{0xcf, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+		{1.5, []byte{0xcb, 0x3f, 0xf8, 0, 0, 0, 0, 0, 0}},
+		{"abc", []byte{0xa3, 'a', 'b', 'c'}},
+		{[]byte{1, 2}, []byte{0xc4, 0x02, 0x01, 0x02}},
+		{[]int{1, 2}, []byte{0x92, 0x01, 0x02}},
+		{map[string]int{"b": 2, "a": 1}, []byte{0x82, 0xa1, 'a', 0x01, 0xa1, 'b', 0x02}},
+	}
+
+	for _, tt := range tests {
+		b, err := Marshal(tt.v)
+		Equal(t, err, nil)
+		Equal(t, b, tt.expected)
+	}
+
+	_, err := Marshal(make(chan int))
+	Equal(t, err.Error(), "msgpack: unsupported type chan int")
+}
+
+func TestMsgPackRoundTrip(t *testing.T) {
+	type Embedded struct {
+		Shadowed string
+		Promoted int
+	}
+
+	type TestStruct struct {
+		Embedded
+		Name     string `msgpack:"name"`
+		Shadowed string
+		Age      uint8
+		Score    float32
+		Tags     []string
+		Counts   map[string]int64
+		Raw      []byte
+		Hash     [2]byte
+		Next     *TestStruct
+		Any      interface{}
+		Created  time.Time
+		Empty    string `msgpack:",omitempty"`
+		Ignored  string `msgpack:"-"`
+		internal string
+	}
+
+	created := time.Date(2024, 2, 29, 13, 37, 0, 123, time.UTC)
+	in := TestStruct{
+		Embedded: Embedded{Shadowed: "embedded", Promoted: 13},
+		Name:     "joeybloggs",
+		Shadowed: "outer",
+		Age:      42,
+		Score:    1.25,
+		Tags:     []string{"a", "b"},
+		Counts:   map[string]int64{"x": -1, "y": math.MaxInt64},
+		Raw:      bytes.Repeat([]byte{0xff}, 300),
+		Hash:     [2]byte{1, 2},
+		Next:     &TestStruct{Name: "next"},
+		Any:      []interface{}{"s", int64(-3), true, nil, 2.5, map[string]interface{}{"k": "v"}},
+		Created:  created,
+		Ignored:  "ignored",
+		internal: "internal",
+	}
+
+	b, err := Marshal(in)
+	Equal(t, err, nil)
+
+	var out TestStruct
+	err = Unmarshal(b, &out)
+	Equal(t, err, nil)
+	Equal(t, out.Shadowed, "outer")
+	Equal(t, out.Embedded.Shadowed, "")
+	Equal(t, out.Promoted, 13)
+	Equal(t, out.Name, "joeybloggs")
+	Equal(t, out.Age, uint8(42))
+	Equal(t, out.Score, float32(1.25))
+	Equal(t, out.Tags, in.Tags)
+	Equal(t, out.Counts, in.Counts)
+	Equal(t, out.Raw, in.Raw)
+	Equal(t, out.Hash, in.Hash)
+	Equal(t, out.Next.Name, "next")
+	Equal(t, out.Any, in.Any)
+	Equal(t, out.Created.Equal(created), true)
+	Equal(t, out.Ignored, "")
+	Equal(t, out.internal, "")
+
+	var generic map[string]interface{}
+	err = Unmarshal(b, &generic)
+	Equal(t, err, nil)
+	Equal(t, generic["name"], "joeybloggs")
+	Equal(t, generic["Age"], int64(42))
+	_, ok := generic["Empty"]
+	Equal(t, ok, false)
+}
+
+func TestMsgPackDecodeErrors(t *testing.T) {
+	var i int8
+	err := Unmarshal([]byte{0xcd, 0x03, 0xe8}, &i)
+	Equal(t, err.Error(), "msgpack: 1000 overflows int8")
+
+	var u uint
+	err = Unmarshal([]byte{0xff}, &u)
+	Equal(t, err.Error(), "msgpack: -1 overflows uint")
+
+	var s string
+	err = Unmarshal([]byte{0x01}, &s)
+	Equal(t, err.Error(), "msgpack: cannot decode integer into string")
+
+	err = Unmarshal([]byte{0xa3, 'a'}, &s)
+	Equal(t, err, errMsgPackTruncated)
+
+	// an array claiming 4294967295 elements must not be allocated
+	var a []int
+	err = Unmarshal([]byte{0xdd, 0xff, 0xff, 0xff, 0xff}, &a)
+	Equal(t, err, errMsgPackTruncated)
+
+	err = Unmarshal([]byte{0xc1}, &s)
+	Equal(t, err.Error(), "msgpack: invalid code 0xc1")
+
+	err = Unmarshal([]byte{0xc0}, s)
+	Equal(t, err.Error(), "msgpack: decode requires a non-nil pointer, got string")
+}
+
+func TestWrite(t *testing.T) {
+	type TestStruct struct {
+		ID     int `form:"id"`
+		Posted string
+	}
+
+	w := httptest.NewRecorder()
+	Equal(t, Write(w, http.StatusOK, TestStruct{Posted: "value"}), nil)
+	Equal(t, w.Code, http.StatusOK)
+	Equal(t, w.Header().Get("Content-Type"), MediaType)
+
+	var test TestStruct
+	err := Unmarshal(w.Body.Bytes(), &test)
+	Equal(t, err, nil)
+	Equal(t, test.Posted, "value")
+
+	w = httptest.NewRecorder()
+	err = Write(w, http.StatusOK, func() {})
+	Equal(t, err.Error(), "msgpack: unsupported type func()")
+	Equal(t, w.Body.Len(), 0)
+}
+
+func TestRegister(t *testing.T) {
+	type TestStruct struct {
+		ID     int `form:"id"`
+		Posted string
+	}
+
+	Register()
+	defer func() {
+		feather.RegisterEncoder(MediaType, nil)
+		for _, mediaType := range append([]string{MediaType}, aliases...) {
+			feather.RegisterDecoder(mediaType, nil)
+		}
+	}()
+
+	var test TestStruct
+	p := feather.New()
+	p.Post("/decode/:id", func(w http.ResponseWriter, r *http.Request) {
+		test = TestStruct{}
+		err := feather.Decode(r, feather.IncludeQueryParams, 16<<10, &test)
+		Equal(t, err, nil)
+	})
+	p.Post("/decode-noquery/:id", func(w http.ResponseWriter, r *http.Request) {
+		test = TestStruct{}
+		err := feather.Decode(r, feather.NoQueryParams, 16<<10, &test)
+		Equal(t, err, nil)
+	})
+	p.Get("/render", func(w http.ResponseWriter, r *http.Request) {
+		_ = feather.Render(w, r, http.StatusOK, TestStruct{Posted: "value"})
+	})
+
+	body, err := Marshal(map[string]string{"Posted": "value"})
+	Equal(t, err, nil)
+
+	hf := p.Serve()
+	for _, typ := range append([]string{MediaType}, aliases...) {
+		r, _ := http.NewRequest(http.MethodPost, "/decode/13", bytes.NewReader(body))
+		r.Header.Set("Content-Type", typ)
+		w := httptest.NewRecorder()
+		hf.ServeHTTP(w, r)
+		Equal(t, w.Code, http.StatusOK)
+		Equal(t, test.ID, 13)
+		Equal(t, test.Posted, "value")
+	}
+
+	r, _ := http.NewRequest(http.MethodPost, "/decode-noquery/13", bytes.NewReader(body))
+	r.Header.Set("Content-Type", MediaType)
+	w := httptest.NewRecorder()
+	hf.ServeHTTP(w, r)
+	Equal(t, w.Code, http.StatusOK)
+	Equal(t, test.ID, 0)
+	Equal(t, test.Posted, "value")
+
+	// bodies are decompressed
+	var buf bytes.Buffer
+	gzw := gzip.NewWriter(&buf)
+	_, _ = gzw.Write(body)
+	_ = gzw.Close()
+	r, _ = http.NewRequest(http.MethodPost, "/decode/5", &buf)
+	r.Header.Set("Content-Type", MediaType)
+	r.Header.Set("Content-Encoding", "gzip")
+	w = httptest.NewRecorder()
+	hf.ServeHTTP(w, r)
+	Equal(t, w.Code, http.StatusOK)
+	Equal(t, test.ID, 5)
+	Equal(t, test.Posted, "value")
+
+	r, _ = http.NewRequest(http.MethodGet, "/render", nil)
+	r.Header.Set("Accept", MediaType)
+	w = httptest.NewRecorder()
+	hf.ServeHTTP(w, r)
+	Equal(t, w.Header().Get("Content-Type"), MediaType)
+	test = TestStruct{}
+	Equal(t, Unmarshal(w.Body.Bytes(), &test), nil)
+	Equal(t, test.Posted, "value")
+}
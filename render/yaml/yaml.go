@@ -0,0 +1,1396 @@
+// Package yaml provides a YAML codec, see https://yaml.org/spec/1.2.2/, for the rendering and decoding functions
+// of feather, which support it once Register is called:
+//
+//	yaml.Register()
+//	...
+//	err := feather.Decode(r, feather.QueryParams, maxMemory, &v) // application/yaml bodies
+//	err = feather.Render(w, r, http.StatusOK, v)                  // Accept: application/yaml
+//
+// Only the subset of YAML used by configuration style documents is decoded: block and flow mappings and sequences,
+// plain, quoted, literal and folded scalars and comments. Documents using anchors, aliases, tags or complex keys
+// are rejected with an error rather than misread, directives are ignored and only the first document
+// of a stream is decoded. Nesting is limited to 10000 levels.
+// Plain scalars are resolved using the core schema, i.e. null, true, 12 and 1.5, quoted scalars are always strings.
+//
+// Structs are encoded as mappings keyed by the field name or the name set using the yaml tag,
+// which also supports omitempty and "-", just like the json tag.
+// Types implementing encoding.TextMarshaler and encoding.TextUnmarshaler, such as time.Time, are encoded as strings
+// and []byte as base64 strings.
+package yaml
+
+import (
+	"encoding"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	unicodeutf8 "unicode/utf8"
+
+	"github.com/pchchv/feather"
+	"github.com/pchchv/feather/render/internal/fields"
+)
+
+// MediaType is the media type of YAML, see RFC 9512.
+const MediaType = "application/yaml"
+
+// aliases are media types also used for YAML bodies.
+var aliases = []string{"application/x-yaml", "text/yaml", "text/x-yaml"}
+
+const tag = "yaml"
+
+// Register registers the codec for MediaType with feather, used by feather.Render and feather.Negotiate
+// to encode responses and by feather.Decode to decode bodies of MediaType and its aliases.
+func Register() {
+	feather.RegisterEncoder(MediaType, encode)
+	feather.RegisterMarshaler(MediaType, MediaType, Marshal)
+	for _, mediaType := range append([]string{MediaType}, aliases...) {
+		feather.RegisterDecoder(mediaType, decode)
+	}
+}
+
+// Write marshals v and writes it as YAML with the status.
+func Write(w http.ResponseWriter, status int, v interface{}) error {
+	b, err := Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", MediaType)
+	w.WriteHeader(status)
+	_, err = w.Write(b)
+	return err
+}
+
+func encode(w io.Writer, v interface{}) error {
+	b, err := Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(b)
+	return err
+}
+
+func decode(body io.Reader, v interface{}) error {
+	b, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+
+	return Unmarshal(b, v)
+}
+
+const yamlMaxDepth = 10000
+
+var (
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+	yamlIntPattern   = regexp.MustCompile(`^[-+]?[0-9]+$`)
+	yamlFloatPattern = regexp.MustCompile(`^[-+]?(\.[0-9]+|[0-9]+(\.[0-9]*)?)([eE][-+]?[0-9]+)?$`)
+
+	errYAMLDepth = errors.New("yaml: exceeded max depth")
+)
+
+// Marshal returns the YAML encoding of v.
+func Marshal(v interface{}) ([]byte, error) {
+	var e yamlEncoder
+	if err := e.encode(reflect.ValueOf(v), 0, 0); err != nil {
+		return nil, err
+	}
+
+	return e.b, nil
+}
+
+type yamlEncoder struct {
+	b []byte
+}
+
+// encode appends v, which starts at the current column, indent, and ends with a newline.
+// Mappings and sequences are written in block style, one entry per line.
+func (e *yamlEncoder) encode(v reflect.Value, indent, depth int) error {
+	if depth > yamlMaxDepth {
+		return errYAMLDepth
+	}
+
+	if !v.IsValid() {
+		e.b = append(e.b, "null\n"...)
+		return nil
+	}
+
+	if yamlTextMarshaler(v) {
+		b, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return err
+		}
+
+		e.encodeString(string(b))
+		e.b = append(e.b, '\n')
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			e.b = append(e.b, "null\n"...)
+			return nil
+		}
+
+		return e.encode(v.Elem(), indent, depth+1)
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			b := make([]byte, v.Len())
+			reflect.Copy(reflect.ValueOf(b), v)
+			e.b = append(e.b, base64.StdEncoding.EncodeToString(b)...)
+			e.b = append(e.b, '\n')
+			return nil
+		}
+
+		if v.Len() == 0 {
+			e.b = append(e.b, "[]\n"...)
+			return nil
+		}
+
+		return e.encodeSequence(v, indent, depth)
+	case reflect.Map:
+		if v.Len() == 0 {
+			e.b = append(e.b, "{}\n"...)
+			return nil
+		}
+
+		return e.encodeMap(v, indent, depth)
+	case reflect.Struct:
+		return e.encodeStruct(v, indent, depth)
+	}
+
+	if err := e.encodeScalar(v); err != nil {
+		return err
+	}
+
+	e.b = append(e.b, '\n')
+	return nil
+}
+
+func (e *yamlEncoder) encodeScalar(v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.Bool:
+		e.b = strconv.AppendBool(e.b, v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		e.b = strconv.AppendInt(e.b, v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		e.b = strconv.AppendUint(e.b, v.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		e.encodeFloat(v.Float(), v.Type().Bits())
+	case reflect.String:
+		e.encodeString(v.String())
+	default:
+		return fmt.Errorf("yaml: unsupported type %s", v.Type())
+	}
+
+	return nil
+}
+
+func (e *yamlEncoder) encodeFloat(f float64, bits int) {
+	switch {
+	case math.IsNaN(f):
+		e.b = append(e.b, ".nan"...)
+	case math.IsInf(f, 1):
+		e.b = append(e.b, ".inf"...)
+	case math.IsInf(f, -1):
+		e.b = append(e.b, "-.inf"...)
+	default:
+		start := len(e.b)
+		e.b = strconv.AppendFloat(e.b, f, 'g', -1, bits)
+		// whole floats keep a fraction so they are not resolved as integers
+		if !strings.ContainsAny(string(e.b[start:]), ".e") {
+			e.b = append(e.b, ".0"...)
+		}
+	}
+}
+
+// encodeString appends s as a plain scalar if it is read back as the same string, double-quoted otherwise.
+func (e *yamlEncoder) encodeString(s string) {
+	if yamlPlain(s) {
+		e.b = append(e.b, s...)
+	} else {
+		e.b = strconv.AppendQuote(e.b, s)
+	}
+}
+
+// yamlPlain reports whether s can be written as a plain scalar.
+func yamlPlain(s string) bool {
+	if s == "" || s[0] == ' ' || s[len(s)-1] == ' ' || !unicodeutf8.ValidString(s) {
+		return false
+	}
+
+	if _, ok := yamlResolve(s).(string); !ok {
+		return false
+	}
+
+	switch s[0] {
+	case '?', ':', ',', '[', ']', '{', '}', '#', '&', '*', '!', '|', '>', '\'', '"', '%', '@', '`':
+		return false
+	case '-':
+		if len(s) == 1 || s[1] == ' ' || strings.HasPrefix(s, "---") {
+			return false
+		}
+	case '.':
+		if strings.HasPrefix(s, "...") {
+			return false
+		}
+	}
+
+	if strings.HasSuffix(s, ":") || strings.Contains(s, ": ") || strings.Contains(s, " #") {
+		return false
+	}
+
+	for i := 0; i < len(s); i++ {
+		if s[i] < ' ' || s[i] == 0x7f {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (e *yamlEncoder) indent(n int) {
+	for i := 0; i < n; i++ {
+		e.b = append(e.b, ' ')
+	}
+}
+
+// encodeEntry appends a mapping entry with the encoded key, block values start on the next line indented by two spaces.
+func (e *yamlEncoder) encodeEntry(key string, v reflect.Value, indent int, first bool, depth int) error {
+	if !first {
+		e.indent(indent)
+	}
+
+	e.b = append(e.b, key...)
+	e.b = append(e.b, ':')
+	if yamlBlock(v) {
+		e.b = append(e.b, '\n')
+		e.indent(indent + 2)
+		return e.encode(v, indent+2, depth+1)
+	}
+
+	e.b = append(e.b, ' ')
+	return e.encode(v, indent, depth+1)
+}
+
+func (e *yamlEncoder) encodeSequence(v reflect.Value, indent, depth int) error {
+	for i := 0; i < v.Len(); i++ {
+		if i > 0 {
+			e.indent(indent)
+		}
+
+		e.b = append(e.b, "- "...)
+		if err := e.encode(v.Index(i), indent+2, depth+1); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (e *yamlEncoder) encodeMap(v reflect.Value, indent, depth int) error {
+	type entry struct {
+		key   string
+		value reflect.Value
+	}
+
+	entries := make([]entry, 0, v.Len())
+	iter := v.MapRange()
+	for iter.Next() {
+		key, err := yamlKey(iter.Key())
+		if err != nil {
+			return err
+		}
+
+		entries = append(entries, entry{key: key, value: iter.Value()})
+	}
+
+	// sorted for a deterministic output
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].key < entries[j].key
+	})
+
+	for i, en := range entries {
+		if err := e.encodeEntry(en.key, en.value, indent, i == 0, depth); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// yamlKey returns a map key as encoded.
+func yamlKey(k reflect.Value) (string, error) {
+	if k.Kind() == reflect.Interface && !k.IsNil() {
+		k = k.Elem()
+	}
+
+	if yamlTextMarshaler(k) {
+		b, err := k.Interface().(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return "", err
+		}
+
+		var e yamlEncoder
+		e.encodeString(string(b))
+		return string(e.b), nil
+	}
+
+	switch k.Kind() {
+	case reflect.String:
+		var e yamlEncoder
+		e.encodeString(k.String())
+		return string(e.b), nil
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		var e yamlEncoder
+		if err := e.encodeScalar(k); err != nil {
+			return "", err
+		}
+		return string(e.b), nil
+	}
+
+	return "", fmt.Errorf("yaml: unsupported map key type %s", k.Type())
+}
+
+func (e *yamlEncoder) encodeStruct(v reflect.Value, indent, depth int) error {
+	first := true
+	for _, f := range fields.Of(v.Type(), tag) {
+		fv := v.FieldByIndex(f.Index)
+		if f.OmitEmpty && fv.IsZero() {
+			continue
+		}
+
+		var key yamlEncoder
+		key.encodeString(f.Name)
+		if err := e.encodeEntry(string(key.b), fv, indent, first, depth); err != nil {
+			return err
+		}
+		first = false
+	}
+
+	if first {
+		e.b = append(e.b, "{}\n"...)
+	}
+
+	return nil
+}
+
+// yamlTextMarshaler reports whether v is encoded using its MarshalText method.
+func yamlTextMarshaler(v reflect.Value) bool {
+	return v.Type().Implements(textMarshalerType) && (v.Kind() != reflect.Ptr || !v.IsNil()) && v.CanInterface()
+}
+
+// yamlBlock reports whether v is encoded as a block mapping or sequence rather than a scalar or an empty collection.
+func yamlBlock(v reflect.Value) bool {
+	for v.IsValid() {
+		if yamlTextMarshaler(v) {
+			return false
+		}
+
+		switch v.Kind() {
+		case reflect.Ptr, reflect.Interface:
+			if v.IsNil() {
+				return false
+			}
+			v = v.Elem()
+		case reflect.Slice, reflect.Array:
+			return v.Type().Elem().Kind() != reflect.Uint8 && v.Len() > 0
+		case reflect.Map:
+			return v.Len() > 0
+		case reflect.Struct:
+			for _, f := range fields.Of(v.Type(), tag) {
+				if !f.OmitEmpty || !v.FieldByIndex(f.Index).IsZero() {
+					return true
+				}
+			}
+			return false
+		default:
+			return false
+		}
+	}
+
+	return false
+}
+
+type yamlKind uint8
+
+const (
+	yamlScalar yamlKind = iota
+	yamlSequence
+	yamlMapping
+)
+
+// yamlNode is a parsed YAML node, the children of mappings are their keys and values in turn.
+type yamlNode struct {
+	kind     yamlKind
+	value    string
+	plain    bool
+	line     int
+	children []*yamlNode
+}
+
+// resolve returns the value of the scalar, nil, bool, int64, uint64, float64 or string.
+func (n *yamlNode) resolve() interface{} {
+	if !n.plain {
+		return n.value
+	}
+
+	return yamlResolve(n.value)
+}
+
+func (n *yamlNode) kindName() string {
+	switch n.kind {
+	case yamlSequence:
+		return "sequence"
+	case yamlMapping:
+		return "mapping"
+	}
+
+	switch n.resolve().(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "bool"
+	case int64, uint64:
+		return "integer"
+	case float64:
+		return "float"
+	default:
+		return "string"
+	}
+}
+
+// yamlResolve resolves a plain scalar using the core schema.
+func yamlResolve(s string) interface{} {
+	switch s {
+	case "", "~", "null", "Null", "NULL":
+		return nil
+	case "true", "True", "TRUE":
+		return true
+	case "false", "False", "FALSE":
+		return false
+	case ".inf", ".Inf", ".INF", "+.inf", "+.Inf", "+.INF":
+		return math.Inf(1)
+	case "-.inf", "-.Inf", "-.INF":
+		return math.Inf(-1)
+	case ".nan", ".NaN", ".NAN":
+		return math.NaN()
+	}
+
+	if c := s[0]; c != '-' && c != '+' && c != '.' && (c < '0' || c > '9') {
+		return s
+	}
+
+	base, digits := 10, s
+	switch {
+	case strings.HasPrefix(s, "0x"):
+		base, digits = 16, s[2:]
+	case strings.HasPrefix(s, "0o"):
+		base, digits = 8, s[2:]
+	case !yamlIntPattern.MatchString(s):
+		if yamlFloatPattern.MatchString(s) {
+			if f, err := strconv.ParseFloat(s, 64); err == nil {
+				return f
+			}
+		}
+		return s
+	}
+
+	if i, err := strconv.ParseInt(digits, base, 64); err == nil {
+		return i
+	}
+
+	if u, err := strconv.ParseUint(strings.TrimPrefix(digits, "+"), base, 64); err == nil {
+		return u
+	}
+
+	if base == 10 {
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return f
+		}
+	}
+
+	return s
+}
+
+// Unmarshal decodes the first YAML document of b into the value v points to.
+func Unmarshal(b []byte, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("yaml: decode requires a non-nil pointer, got %T", v)
+	}
+
+	n, err := parseYAML(b)
+	if err != nil {
+		return err
+	}
+
+	return decodeYAMLNode(n, rv.Elem(), 0)
+}
+
+// yamlParser parses the lines of a document, block nodes are parsed line by line
+// and flow nodes and quoted scalars, which may span lines, using the column.
+type yamlParser struct {
+	lines []string
+	row   int
+	col   int
+	depth int
+}
+
+func parseYAML(b []byte) (*yamlNode, error) {
+	s := strings.TrimPrefix(string(b), "\ufeff")
+	p := &yamlParser{lines: strings.Split(s, "\n")}
+	started, content := false, false
+lines:
+	for i, l := range p.lines {
+		l = strings.TrimSuffix(l, "\r")
+		p.lines[i] = l
+		switch {
+		case l == "---" || strings.HasPrefix(l, "--- ") || strings.HasPrefix(l, "---\t"):
+			if started || content {
+				// only the first document is decoded
+				p.lines = p.lines[:i]
+				break lines
+			}
+			started = true
+			p.lines[i] = "   " + l[3:]
+		case l == "..." || strings.HasPrefix(l, "... "):
+			p.lines = p.lines[:i]
+			break lines
+		case !started && !content && strings.HasPrefix(l, "%"):
+			// directives
+			p.lines[i] = ""
+		case !yamlBlank(l):
+			content = true
+		}
+	}
+
+	n, err := p.parseBlock(0)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := p.next(); ok {
+		return nil, p.errorf("unexpected content")
+	}
+
+	return n, nil
+}
+
+func (p *yamlParser) errorf(format string, args ...interface{}) error {
+	line := min(p.row, len(p.lines)-1) + 1
+	return fmt.Errorf("yaml: line %d: "+format, append([]interface{}{line}, args...)...)
+}
+
+// yamlBlank reports whether the line is empty or a comment.
+func yamlBlank(l string) bool {
+	l = strings.TrimLeft(l, " \t")
+	return l == "" || l[0] == '#'
+}
+
+func yamlIndent(l string) int {
+	return len(l) - len(strings.TrimLeft(l, " "))
+}
+
+// next skips the empty and comment lines, returning the indentation of the next line with content.
+func (p *yamlParser) next() (int, bool) {
+	for ; p.row < len(p.lines); p.row++ {
+		if !yamlBlank(p.lines[p.row]) {
+			return yamlIndent(p.lines[p.row]), true
+		}
+	}
+
+	return 0, false
+}
+
+func (p *yamlParser) null() *yamlNode {
+	return &yamlNode{kind: yamlScalar, plain: true, line: p.row + 1}
+}
+
+// parseBlock parses the node starting at the next line with content if it is indented by at least minIndent, null otherwise.
+func (p *yamlParser) parseBlock(minIndent int) (*yamlNode, error) {
+	indent, ok := p.next()
+	if !ok || indent < minIndent {
+		return p.null(), nil
+	}
+
+	if p.depth++; p.depth > yamlMaxDepth {
+		return nil, errYAMLDepth
+	}
+	defer func() { p.depth-- }()
+
+	content := p.lines[p.row][indent:]
+	switch {
+	case content[0] == '\t':
+		return nil, p.errorf("found a tab character used as indentation")
+	case content == "?" || strings.HasPrefix(content, "? "):
+		return nil, p.errorf("complex keys are not supported")
+	case yamlSequenceEntry(content):
+		return p.parseSequence(indent)
+	}
+
+	if _, _, ok := p.mappingKey(indent); ok {
+		return p.parseMapping(indent)
+	}
+
+	return p.parseInline(minIndent-1, indent)
+}
+
+func yamlSequenceEntry(content string) bool {
+	return content == "-" || strings.HasPrefix(content, "- ") || strings.HasPrefix(content, "-\t")
+}
+
+func (p *yamlParser) parseSequence(indent int) (*yamlNode, error) {
+	n := &yamlNode{kind: yamlSequence, line: p.row + 1}
+	for {
+		i, ok := p.next()
+		if !ok || i < indent {
+			break
+		}
+
+		l := p.lines[p.row]
+		if i > indent {
+			return nil, p.errorf("unexpected indentation")
+		}
+
+		if !yamlSequenceEntry(l[indent:]) {
+			break
+		}
+
+		// the entry continues as if the dash was indentation, so "- a: 1" starts a mapping at the column of a
+		p.lines[p.row] = l[:indent] + " " + l[indent+1:]
+		item, err := p.parseBlock(indent + 1)
+		if err != nil {
+			return nil, err
+		}
+
+		n.children = append(n.children, item)
+	}
+
+	return n, nil
+}
+
+func (p *yamlParser) parseMapping(indent int) (*yamlNode, error) {
+	n := &yamlNode{kind: yamlMapping, line: p.row + 1}
+	for {
+		i, ok := p.next()
+		if !ok || i < indent {
+			break
+		}
+
+		if i > indent {
+			return nil, p.errorf("unexpected indentation")
+		}
+
+		key, col, ok := p.mappingKey(indent)
+		if !ok {
+			if yamlSequenceEntry(p.lines[p.row][indent:]) {
+				break
+			}
+			return nil, p.errorf("expected a mapping key")
+		}
+
+		value, err := p.parseValue(indent, col)
+		if err != nil {
+			return nil, err
+		}
+
+		n.children = append(n.children, key, value)
+	}
+
+	return n, nil
+}
+
+// mappingKey returns the key of the line if it is a mapping entry and the column of its value.
+func (p *yamlParser) mappingKey(indent int) (key *yamlNode, col int, ok bool) {
+	l := p.lines[p.row]
+	switch l[indent] {
+	case '[', '{', '#':
+		return nil, 0, false
+	case '"', '\'':
+		// keys are quoted on a single line
+		lines, row := p.lines, p.row
+		p.lines, p.col = lines[:row+1], indent
+		s, err := p.parseQuoted()
+		p.lines, p.row, col = lines, row, p.col
+		if err != nil {
+			return nil, 0, false
+		}
+
+		for col < len(l) && l[col] == ' ' {
+			col++
+		}
+
+		if col == len(l) || l[col] != ':' || (col+1 < len(l) && l[col+1] != ' ' && l[col+1] != '\t') {
+			return nil, 0, false
+		}
+
+		return &yamlNode{kind: yamlScalar, value: s, line: row + 1}, col + 1, true
+	}
+
+	for i := indent; i < len(l); i++ {
+		switch l[i] {
+		case ':':
+			if i+1 == len(l) || l[i+1] == ' ' || l[i+1] == '\t' {
+				k := strings.TrimRight(l[indent:i], " \t")
+				return &yamlNode{kind: yamlScalar, value: k, plain: true, line: p.row + 1}, i + 1, true
+			}
+		case '#':
+			if l[i-1] == ' ' || l[i-1] == '\t' {
+				return nil, 0, false
+			}
+		}
+	}
+
+	return nil, 0, false
+}
+
+// parseValue parses the value of a mapping entry starting at the column of the line,
+// or on the next lines if the line has no value.
+func (p *yamlParser) parseValue(indent, col int) (*yamlNode, error) {
+	l := p.lines[p.row]
+	for col < len(l) && (l[col] == ' ' || l[col] == '\t') {
+		col++
+	}
+
+	if col < len(l) && l[col] != '#' {
+		return p.parseInline(indent, col)
+	}
+
+	p.row++
+	i, ok := p.next()
+	switch {
+	case ok && i > indent:
+		return p.parseBlock(indent + 1)
+	case ok && i == indent && yamlSequenceEntry(p.lines[p.row][i:]):
+		// a sequence may be indented like the key
+		return p.parseSequence(indent)
+	}
+
+	return p.null(), nil
+}
+
+// parseInline parses the scalar or flow node starting at the column of the line,
+// the node belongs to a parent indented by indent, -1 at the top level.
+func (p *yamlParser) parseInline(indent, col int) (*yamlNode, error) {
+	l := p.lines[p.row]
+	switch l[col] {
+	case '[', '{', '"', '\'':
+		p.col = col
+		n, err := p.parseFlow()
+		if err != nil {
+			return nil, err
+		}
+
+		if rest := p.lines[p.row][p.col:]; !yamlBlank(rest) {
+			return nil, p.errorf("unexpected content after the value: %q", strings.TrimSpace(rest))
+		}
+
+		p.row++
+		return n, nil
+	case '|', '>':
+		return p.parseBlockScalar(indent, col)
+	case '&', '*', '!':
+		return nil, p.errorf("anchors, aliases and tags are not supported")
+	}
+
+	return p.parsePlain(indent, col)
+}
+
+// parsePlain parses a plain scalar, which continues on the following lines indented more than its parent.
+func (p *yamlParser) parsePlain(indent, col int) (*yamlNode, error) {
+	n := &yamlNode{kind: yamlScalar, plain: true, line: p.row + 1}
+	var b strings.Builder
+	empty := 0
+	for first := true; p.row < len(p.lines); p.row++ {
+		l := p.lines[p.row]
+		if first {
+			l = l[col:]
+		} else if strings.TrimSpace(l) == "" {
+			empty++
+			continue
+		} else if yamlIndent(l) <= indent || yamlBlank(l) {
+			break
+		}
+
+		text := yamlStripComment(strings.TrimSpace(l))
+		if strings.HasSuffix(text, ":") || strings.Contains(text, ": ") || yamlSequenceEntry(text) {
+			return nil, p.errorf("mapping values and sequence entries are not allowed in this context")
+		}
+
+		switch {
+		case first:
+		case empty == 0:
+			b.WriteByte(' ')
+		default:
+			b.WriteString(strings.Repeat("\n", empty))
+		}
+
+		b.WriteString(text)
+		if first = false; text != strings.TrimSpace(l) {
+			// a comment ends the scalar
+			p.row++
+			break
+		}
+		empty = 0
+	}
+
+	n.value = b.String()
+	return n, nil
+}
+
+func yamlStripComment(s string) string {
+	if strings.HasPrefix(s, "#") {
+		return ""
+	}
+
+	for i := 1; i < len(s); i++ {
+		if s[i] == '#' && (s[i-1] == ' ' || s[i-1] == '\t') {
+			return strings.TrimRight(s[:i], " \t")
+		}
+	}
+
+	return s
+}
+
+// parseBlockScalar parses a literal, |, or folded, >, scalar, whose lines are indented more than its parent.
+func (p *yamlParser) parseBlockScalar(indent, col int) (*yamlNode, error) {
+	n := &yamlNode{kind: yamlScalar, line: p.row + 1}
+	header := yamlStripComment(strings.TrimSpace(p.lines[p.row][col:]))
+	folded, chomp, explicit := header[0] == '>', byte(0), 0
+	for _, c := range []byte(header[1:]) {
+		switch {
+		case (c == '-' || c == '+') && chomp == 0:
+			chomp = c
+		case c >= '1' && c <= '9' && explicit == 0:
+			explicit = int(c - '0')
+		default:
+			return nil, p.errorf("invalid block scalar header %q", header)
+		}
+	}
+
+	contentIndent := -1
+	if explicit > 0 {
+		contentIndent = max(indent, 0) + explicit
+	}
+
+	var lines []string
+	for p.row++; p.row < len(p.lines); p.row++ {
+		l := p.lines[p.row]
+		if strings.TrimSpace(l) == "" {
+			lines = append(lines, "")
+			continue
+		}
+
+		i := yamlIndent(l)
+		if contentIndent < 0 {
+			if i <= indent {
+				break
+			}
+			contentIndent = i
+		}
+
+		if i < contentIndent {
+			break
+		}
+
+		lines = append(lines, l[contentIndent:])
+	}
+
+	trailing := 0
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+		trailing++
+	}
+
+	var b strings.Builder
+	if folded {
+		yamlFold(&b, lines)
+	} else {
+		b.WriteString(strings.Join(lines, "\n"))
+	}
+
+	switch {
+	case chomp == '-':
+	case chomp == '+':
+		if len(lines) > 0 {
+			b.WriteByte('\n')
+		}
+		b.WriteString(strings.Repeat("\n", trailing))
+	case len(lines) > 0:
+		b.WriteByte('\n')
+	}
+
+	n.value = b.String()
+	return n, nil
+}
+
+// yamlFold joins the lines of a folded scalar, line breaks between lines become spaces
+// unless the lines are empty or more indented.
+func yamlFold(b *strings.Builder, lines []string) {
+	empty, prev := 0, -1
+	for i, l := range lines {
+		if l == "" {
+			empty++
+			continue
+		}
+
+		if prev >= 0 {
+			more := lines[prev][0] == ' ' || lines[prev][0] == '\t' || l[0] == ' ' || l[0] == '\t'
+			switch {
+			case empty == 0 && !more:
+				b.WriteByte(' ')
+			case more:
+				b.WriteString(strings.Repeat("\n", empty+1))
+			default:
+				b.WriteString(strings.Repeat("\n", empty))
+			}
+		} else {
+			b.WriteString(strings.Repeat("\n", empty))
+		}
+
+		b.WriteString(l)
+		prev, empty = i, 0
+	}
+}
+
+// char returns the byte at the column of the flow node being parsed, 0 at the end of the line.
+func (p *yamlParser) char() byte {
+	if p.row < len(p.lines) && p.col < len(p.lines[p.row]) {
+		return p.lines[p.row][p.col]
+	}
+
+	return 0
+}
+
+// skipSpace skips the spaces, line breaks and comments within flow nodes.
+func (p *yamlParser) skipSpace() {
+	for p.row < len(p.lines) {
+		l := p.lines[p.row]
+		for p.col < len(l) && (l[p.col] == ' ' || l[p.col] == '\t') {
+			p.col++
+		}
+
+		if p.col < len(l) && (l[p.col] != '#' || (p.col > 0 && l[p.col-1] != ' ' && l[p.col-1] != '\t')) {
+			return
+		}
+
+		p.row++
+		p.col = 0
+	}
+}
+
+// parseFlow parses a flow mapping or sequence, or a scalar within them.
+func (p *yamlParser) parseFlow() (*yamlNode, error) {
+	if p.depth++; p.depth > yamlMaxDepth {
+		return nil, errYAMLDepth
+	}
+	defer func() { p.depth-- }()
+
+	p.skipSpace()
+	switch c := p.char(); c {
+	case '[', '{':
+		return p.parseFlowCollection(c)
+	case '"', '\'':
+		n := &yamlNode{kind: yamlScalar, line: p.row + 1}
+		s, err := p.parseQuoted()
+		n.value = s
+		return n, err
+	case '&', '*', '!':
+		return nil, p.errorf("anchors, aliases and tags are not supported")
+	case 0:
+		return nil, p.errorf("unexpected end of flow collection")
+	}
+
+	l := p.lines[p.row]
+	start := p.col
+	for ; p.col < len(l); p.col++ {
+		c := l[p.col]
+		if c == ',' || c == '[' || c == ']' || c == '{' || c == '}' {
+			break
+		}
+
+		if c == ':' && (p.col+1 == len(l) || strings.IndexByte(" \t,[]{}", l[p.col+1]) >= 0) {
+			break
+		}
+
+		if c == '#' && (l[p.col-1] == ' ' || l[p.col-1] == '\t') {
+			break
+		}
+	}
+
+	return &yamlNode{kind: yamlScalar, value: strings.TrimSpace(l[start:p.col]), plain: true, line: p.row + 1}, nil
+}
+
+func (p *yamlParser) parseFlowCollection(open byte) (*yamlNode, error) {
+	n := &yamlNode{kind: yamlSequence, line: p.row + 1}
+	end := byte(']')
+	if open == '{' {
+		n.kind, end = yamlMapping, '}'
+	}
+
+	p.col++
+	for {
+		p.skipSpace()
+		switch p.char() {
+		case end:
+			p.col++
+			return n, nil
+		case ',':
+			return nil, p.errorf("unexpected ',' in flow collection")
+		}
+
+		item, err := p.parseFlow()
+		if err != nil {
+			return nil, err
+		}
+
+		if n.kind == yamlMapping {
+			if item.kind != yamlScalar {
+				return nil, p.errorf("complex keys are not supported")
+			}
+
+			value := p.null()
+			if p.skipSpace(); p.char() == ':' {
+				p.col++
+				if p.skipSpace(); p.char() == ',' || p.char() == end {
+					value = p.null()
+				} else if value, err = p.parseFlow(); err != nil {
+					return nil, err
+				}
+			}
+
+			n.children = append(n.children, item, value)
+		} else {
+			n.children = append(n.children, item)
+		}
+
+		p.skipSpace()
+		switch p.char() {
+		case ',':
+			p.col++
+		case end:
+		case 0:
+			return nil, p.errorf("unexpected end of flow collection")
+		default:
+			return nil, p.errorf("expected ',' or '%c' in flow collection", end)
+		}
+	}
+}
+
+// parseQuoted parses a single or double-quoted scalar,
+// line breaks within the scalar are folded into spaces unless followed by empty lines.
+func (p *yamlParser) parseQuoted() (string, error) {
+	q := p.char()
+	p.col++
+	var b []byte
+	for {
+		if p.row >= len(p.lines) {
+			return "", p.errorf("unterminated quoted scalar")
+		}
+
+		l := p.lines[p.row]
+		if p.col >= len(l) {
+			b = []byte(strings.TrimRight(string(b), " \t"))
+			if p.row++; p.row >= len(p.lines) {
+				return "", p.errorf("unterminated quoted scalar")
+			}
+
+			empty := 0
+			for p.row < len(p.lines)-1 && strings.TrimSpace(p.lines[p.row]) == "" {
+				empty++
+				p.row++
+			}
+
+			if empty == 0 {
+				b = append(b, ' ')
+			}
+			for i := 0; i < empty; i++ {
+				b = append(b, '\n')
+			}
+
+			l = p.lines[p.row]
+			p.col = len(l) - len(strings.TrimLeft(l, " \t"))
+			continue
+		}
+
+		c := l[p.col]
+		switch {
+		case c == q && q == '\'' && p.col+1 < len(l) && l[p.col+1] == '\'':
+			b = append(b, '\'')
+			p.col += 2
+		case c == q:
+			p.col++
+			return string(b), nil
+		case c == '\\' && q == '"':
+			if p.col+1 == len(l) {
+				// escaped line break
+				if p.row++; p.row >= len(p.lines) {
+					return "", p.errorf("unterminated quoted scalar")
+				}
+
+				l = p.lines[p.row]
+				p.col = len(l) - len(strings.TrimLeft(l, " \t"))
+				continue
+			}
+
+			var err error
+			if b, err = p.unescape(b, l); err != nil {
+				return "", err
+			}
+		default:
+			b = append(b, c)
+			p.col++
+		}
+	}
+}
+
+// unescape appends the escape sequence at the column of the line.
+func (p *yamlParser) unescape(b []byte, l string) ([]byte, error) {
+	c := l[p.col+1]
+	p.col += 2
+	switch c {
+	case '0':
+		return append(b, 0), nil
+	case 'a':
+		return append(b, '\a'), nil
+	case 'b':
+		return append(b, '\b'), nil
+	case 't', '\t':
+		return append(b, '\t'), nil
+	case 'n':
+		return append(b, '\n'), nil
+	case 'v':
+		return append(b, '\v'), nil
+	case 'f':
+		return append(b, '\f'), nil
+	case 'r':
+		return append(b, '\r'), nil
+	case 'e':
+		return append(b, 0x1b), nil
+	case ' ', '"', '/', '\\':
+		return append(b, c), nil
+	case 'N':
+		return unicodeutf8.AppendRune(b, '\u0085'), nil
+	case '_':
+		return unicodeutf8.AppendRune(b, ' '), nil
+	case 'L':
+		return unicodeutf8.AppendRune(b, ' '), nil
+	case 'P':
+		return unicodeutf8.AppendRune(b, ' '), nil
+	}
+
+	size := map[byte]int{'x': 2, 'u': 4, 'U': 8}[c]
+	if size == 0 || p.col+size > len(l) {
+		return nil, p.errorf("invalid escape sequence \\%c", c)
+	}
+
+	r, err := strconv.ParseUint(l[p.col:p.col+size], 16, 32)
+	if err != nil {
+		return nil, p.errorf("invalid escape sequence \\%c%s", c, l[p.col:p.col+size])
+	}
+
+	p.col += size
+	if c == 'x' {
+		return append(b, byte(r)), nil
+	}
+
+	return unicodeutf8.AppendRune(b, rune(r)), nil
+}
+
+func decodeYAMLNode(n *yamlNode, v reflect.Value, depth int) error {
+	if depth > yamlMaxDepth {
+		return errYAMLDepth
+	}
+
+	if n.kind == yamlScalar && n.plain && yamlResolve(n.value) == nil {
+		switch v.Kind() {
+		case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice:
+			v.Set(reflect.Zero(v.Type()))
+		}
+		return nil
+	}
+
+	if n.kind == yamlScalar && v.Kind() != reflect.Ptr && v.CanAddr() && v.Addr().Type().Implements(textUnmarshalerType) {
+		if err := v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(n.value)); err != nil {
+			return fmt.Errorf("yaml: line %d: %w", n.line, err)
+		}
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+
+		return decodeYAMLNode(n, v.Elem(), depth+1)
+	case reflect.Interface:
+		if v.NumMethod() != 0 {
+			break
+		}
+
+		if i := yamlInterface(n); i == nil {
+			v.Set(reflect.Zero(v.Type()))
+		} else {
+			v.Set(reflect.ValueOf(i))
+		}
+		return nil
+	}
+
+	switch n.kind {
+	case yamlScalar:
+		if decodeYAMLScalar(n, v) {
+			return nil
+		}
+	case yamlSequence:
+		switch v.Kind() {
+		case reflect.Slice:
+			s := reflect.MakeSlice(v.Type(), len(n.children), len(n.children))
+			for i, c := range n.children {
+				if err := decodeYAMLNode(c, s.Index(i), depth+1); err != nil {
+					return err
+				}
+			}
+			v.Set(s)
+			return nil
+		case reflect.Array:
+			if v.Len() != len(n.children) {
+				return fmt.Errorf("yaml: line %d: cannot decode sequence of length %d into %s", n.line, len(n.children), v.Type())
+			}
+
+			for i, c := range n.children {
+				if err := decodeYAMLNode(c, v.Index(i), depth+1); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	case yamlMapping:
+		switch v.Kind() {
+		case reflect.Map:
+			t := v.Type()
+			if v.IsNil() {
+				v.Set(reflect.MakeMapWithSize(t, len(n.children)/2))
+			}
+
+			for i := 0; i < len(n.children); i += 2 {
+				k := reflect.New(t.Key()).Elem()
+				if err := decodeYAMLNode(n.children[i], k, depth+1); err != nil {
+					return err
+				}
+
+				e := reflect.New(t.Elem()).Elem()
+				if err := decodeYAMLNode(n.children[i+1], e, depth+1); err != nil {
+					return err
+				}
+
+				v.SetMapIndex(k, e)
+			}
+			return nil
+		case reflect.Struct:
+			fs := fields.Of(v.Type(), tag)
+			for i := 0; i < len(n.children); i += 2 {
+				f := fields.ByName(fs, n.children[i].value)
+				if f == nil {
+					continue
+				}
+
+				if err := decodeYAMLNode(n.children[i+1], v.FieldByIndex(f.Index), depth+1); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+
+	return fmt.Errorf("yaml: line %d: cannot decode %s into %s", n.line, n.kindName(), v.Type())
+}
+
+// decodeYAMLScalar sets v to the scalar, reporting whether the scalar can be decoded into v.
+func decodeYAMLScalar(n *yamlNode, v reflect.Value) bool {
+	value := n.resolve()
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(n.value)
+		return true
+	case reflect.Bool:
+		if b, ok := value.(bool); ok {
+			v.SetBool(b)
+			return true
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if i, ok := value.(int64); ok && !v.OverflowInt(i) {
+			v.SetInt(i)
+			return true
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		switch u := value.(type) {
+		case int64:
+			if u >= 0 && !v.OverflowUint(uint64(u)) {
+				v.SetUint(uint64(u))
+				return true
+			}
+		case uint64:
+			if !v.OverflowUint(u) {
+				v.SetUint(u)
+				return true
+			}
+		}
+	case reflect.Float32, reflect.Float64:
+		switch f := value.(type) {
+		case int64:
+			v.SetFloat(float64(f))
+			return true
+		case uint64:
+			v.SetFloat(float64(f))
+			return true
+		case float64:
+			v.SetFloat(f)
+			return true
+		}
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			if b, err := base64.StdEncoding.DecodeString(n.value); err == nil {
+				v.SetBytes(b)
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// yamlInterface returns the value of a node of unknown type,
+// integers are decoded as int64 unless they overflow it, floats as float64 and mappings as map[string]interface{}.
+func yamlInterface(n *yamlNode) interface{} {
+	switch n.kind {
+	case yamlSequence:
+		s := make([]interface{}, len(n.children))
+		for i, c := range n.children {
+			s[i] = yamlInterface(c)
+		}
+		return s
+	case yamlMapping:
+		m := make(map[string]interface{}, len(n.children)/2)
+		for i := 0; i < len(n.children); i += 2 {
+			m[n.children[i].value] = yamlInterface(n.children[i+1])
+		}
+		return m
+	}
+
+	return n.resolve()
+}
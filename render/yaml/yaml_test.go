@@ -0,0 +1,341 @@
+package yaml
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pchchv/feather"
+	. "github.com/pchchv/feather/assert"
+)
+
+func TestYAMLEncoding(t *testing.T) {
+	type Inner struct {
+		Port int
+	}
+
+	type TestStruct struct {
+		Name    string `yaml:"name"`
+		Tags    []string
+		Inner   Inner
+		Empty   []int
+		Skipped string `yaml:"-"`
+		Omitted string `yaml:",omitempty"`
+	}
+
+	tests := []struct {
+		v        interface{}
+		expected string
+	}{
+		{nil, "null\n"},
+		{true, "true\n"},
+		{-7, "-7\n"},
+		{1.5, "1.5\n"},
+		{2.0, "2.0\n"},
+		{math.Inf(-1), "-.inf\n"},
+		{"abc", "abc\n"},
+		{"12", "\"12\"\n"},
+		{"true", "\"true\"\n"},
+		{"a: b", "\"a: b\"\n"},
+		{"- a", "\"- a\"\n"},
+		{"line\nbreak", "\"line\\nbreak\"\n"},
+		{"", "\"\"\n"},
+		{[]byte{1, 2}, "AQI=\n"},
+		{[]int{}, "[]\n"},
+		{map[string]int{"b": 2, "a": 1}, "a: 1\nb: 2\n"},
+		{map[int]string{1: "one"}, "1: one\n"},
+		{[][]int{{1, 2}, {3}}, "- - 1\n  - 2\n- - 3\n"},
+		{[]map[string]int{{"a": 1, "b": 2}}, "- a: 1\n  b: 2\n"},
+		{time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), "2024-01-02T03:04:05Z\n"},
+		{
+			TestStruct{Name: "api", Tags: []string{"x", "y"}, Inner: Inner{Port: 80}, Skipped: "s"},
+			"name: api\nTags:\n  - x\n  - y\nInner:\n  Port: 80\nEmpty: []\n",
+		},
+	}
+
+	for _, tt := range tests {
+		b, err := Marshal(tt.v)
+		Equal(t, err, nil)
+		Equal(t, string(b), tt.expected)
+	}
+
+	_, err := Marshal(make(chan int))
+	Equal(t, err.Error(), "yaml: unsupported type chan int")
+}
+
+func TestYAMLRoundTrip(t *testing.T) {
+	type Embedded struct {
+		Shadowed string
+		Promoted int
+	}
+
+	type TestStruct struct {
+		Embedded
+		Name     string `yaml:"name"`
+		Shadowed string
+		Age      uint8
+		Score    float32
+		Tags     []string
+		Counts   map[string]int64
+		Nested   []map[string][]int
+		Pointer  *string
+		Nil      *string
+		Bytes    []byte
+		Array    [2]bool
+		Created  time.Time
+		Any      interface{}
+		Text     string
+	}
+
+	s := "pointer"
+	in := TestStruct{
+		Embedded: Embedded{Shadowed: "inner", Promoted: 3},
+		Name:     "name",
+		Shadowed: "outer",
+		Age:      42,
+		Score:    1.25,
+		Tags:     []string{"a", "b: c", " d"},
+		Counts:   map[string]int64{"x": -1, "y": math.MaxInt64},
+		Nested:   []map[string][]int{{"n": {1, 2}}, {}},
+		Pointer:  &s,
+		Bytes:    []byte("bytes"),
+		Array:    [2]bool{true, false},
+		Created:  time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC),
+		Any:      map[string]interface{}{"k": []interface{}{int64(1), "v", nil, 1.5}},
+		Text:     "multi\nline \"quoted\" # text",
+	}
+
+	b, err := Marshal(in)
+	Equal(t, err, nil)
+
+	var out TestStruct
+	err = Unmarshal(b, &out)
+	Equal(t, err, nil)
+	Equal(t, out.Embedded.Shadowed, "")
+	out.Embedded.Shadowed = in.Embedded.Shadowed
+	Equal(t, out, in)
+}
+
+func TestYAMLDecoding(t *testing.T) {
+	doc := `%YAML 1.2
+--- # config
+name: "api" # quoted
+replicas: 3
+enabled: yes
+ratio: .5
+hex: 0x1F
+octal: 0o17
+decimal: 017
+nothing: ~
+ports: [80, 443]
+labels: {app: web, "tier": backend, empty: }
+env:
+- name: A
+  value: 1
+-   name: B
+    value: 'it''s'
+args:
+  - --verbose
+  -
+  - - nested
+    - list
+literal: |
+  line 1
+
+  line 2
+folded: >-
+  folded
+  text
+
+  paragraph
+    indented
+keep: |+
+  kept
+
+multi: plain text
+  on two lines
+escaped: "tab\tnull\x41\u00e9 \
+  joined"
+json: {"a": [1,
+  2], "b": {"c": null}}
+...
+ignored: true
+`
+
+	var v map[string]interface{}
+	err := Unmarshal([]byte(doc), &v)
+	Equal(t, err, nil)
+	Equal(t, v, map[string]interface{}{
+		"name":     "api",
+		"replicas": int64(3),
+		"enabled":  "yes",
+		"ratio":    0.5,
+		"hex":      int64(31),
+		"octal":    int64(15),
+		"decimal":  int64(17),
+		"nothing":  nil,
+		"ports":    []interface{}{int64(80), int64(443)},
+		"labels":   map[string]interface{}{"app": "web", "tier": "backend", "empty": nil},
+		"env": []interface{}{
+			map[string]interface{}{"name": "A", "value": int64(1)},
+			map[string]interface{}{"name": "B", "value": "it's"},
+		},
+		"args":    []interface{}{"--verbose", nil, []interface{}{"nested", "list"}},
+		"literal": "line 1\n\nline 2\n",
+		"folded":  "folded text\nparagraph\n  indented",
+		"keep":    "kept\n\n",
+		"multi":   "plain text on two lines",
+		"escaped": "tab\tnullAé joined",
+		"json": map[string]interface{}{
+			"a": []interface{}{int64(1), int64(2)},
+			"b": map[string]interface{}{"c": nil},
+		},
+	})
+
+	type Config struct {
+		Name     string
+		Replicas int
+		Ratio    float64
+		Ports    []uint16
+		Nothing  *int
+		Labels   map[string]string
+	}
+
+	var c Config
+	err = Unmarshal([]byte(doc), &c)
+	Equal(t, err, nil)
+	Equal(t, c, Config{Name: "api", Replicas: 3, Ratio: 0.5, Ports: []uint16{80, 443}, Labels: map[string]string{"app": "web", "tier": "backend", "empty": ""}})
+
+	var empty map[string]interface{}
+	err = Unmarshal([]byte("# nothing\n"), &empty)
+	Equal(t, err, nil)
+	Equal(t, empty, map[string]interface{}(nil))
+}
+
+func TestYAMLDecodeErrors(t *testing.T) {
+	var i int8
+	err := Unmarshal([]byte("1000"), &i)
+	Equal(t, err.Error(), "yaml: line 1: cannot decode integer into int8")
+
+	var n int
+	err = Unmarshal([]byte(`"12"`), &n)
+	Equal(t, err.Error(), "yaml: line 1: cannot decode string into int")
+
+	var m map[string]int
+	err = Unmarshal([]byte("a: 1\nb:\n  - 2\n"), &m)
+	Equal(t, err.Error(), "yaml: line 3: cannot decode sequence into int")
+
+	tests := []struct {
+		doc      string
+		expected string
+	}{
+		{"a: 1\n  b: 2\n", "yaml: line 2: mapping values and sequence entries are not allowed in this context"},
+		{"a:\n  b: 1\n c: 2\n", "yaml: line 3: unexpected indentation"},
+		{"- a\nb: 1\n", "yaml: line 2: unexpected content"},
+		{"a: &x 1\n", "yaml: line 1: anchors, aliases and tags are not supported"},
+		{"a: [1, 2\n", "yaml: line 2: unexpected end of flow collection"},
+		{"a: \"open\n", "yaml: line 2: unterminated quoted scalar"},
+		{"a: [1] 2\n", "yaml: line 1: unexpected content after the value: \"2\""},
+		{"a: \"\\q\"\n", "yaml: line 1: invalid escape sequence \\q"},
+		{"? a\n: b\n", "yaml: line 1: complex keys are not supported"},
+		{strings.Repeat("[", yamlMaxDepth+1), errYAMLDepth.Error()},
+	}
+
+	for _, tt := range tests {
+		var v interface{}
+		err = Unmarshal([]byte(tt.doc), &v)
+		NotEqual(t, err, nil)
+		Equal(t, err.Error(), tt.expected)
+	}
+
+	err = Unmarshal([]byte("a"), n)
+	Equal(t, err.Error(), "yaml: decode requires a non-nil pointer, got int")
+}
+
+func TestWrite(t *testing.T) {
+	type TestStruct struct {
+		ID     int `form:"id"`
+		Posted string
+	}
+
+	w := httptest.NewRecorder()
+	Equal(t, Write(w, http.StatusOK, TestStruct{Posted: "value"}), nil)
+	Equal(t, w.Code, http.StatusOK)
+	Equal(t, w.Header().Get("Content-Type"), MediaType)
+	Equal(t, w.Body.String(), "ID: 0\nPosted: value\n")
+
+	w = httptest.NewRecorder()
+	err := Write(w, http.StatusOK, func() {})
+	Equal(t, err.Error(), "yaml: unsupported type func()")
+	Equal(t, w.Body.Len(), 0)
+}
+
+func TestRegister(t *testing.T) {
+	type TestStruct struct {
+		ID     int `form:"id"`
+		Posted string
+	}
+
+	Register()
+	defer func() {
+		feather.RegisterEncoder(MediaType, nil)
+		for _, mediaType := range append([]string{MediaType}, aliases...) {
+			feather.RegisterDecoder(mediaType, nil)
+		}
+	}()
+
+	var test TestStruct
+	p := feather.New()
+	p.Post("/decode/:id", func(w http.ResponseWriter, r *http.Request) {
+		test = TestStruct{}
+		err := feather.Decode(r, feather.IncludeQueryParams, 16<<10, &test)
+		Equal(t, err, nil)
+	})
+	p.Post("/decode-noquery/:id", func(w http.ResponseWriter, r *http.Request) {
+		test = TestStruct{}
+		err := feather.Decode(r, feather.NoQueryParams, 16<<10, &test)
+		Equal(t, err, nil)
+	})
+	p.Post("/invalid", func(w http.ResponseWriter, r *http.Request) {
+		err := feather.Decode(r, feather.NoQueryParams, 16<<10, &test)
+		Equal(t, err.Error(), "yaml: line 1: anchors, aliases and tags are not supported")
+	})
+	p.Get("/render", func(w http.ResponseWriter, r *http.Request) {
+		_ = feather.Render(w, r, http.StatusOK, TestStruct{Posted: "value"})
+	})
+
+	body := "posted: value\n"
+	hf := p.Serve()
+	for _, typ := range []string{MediaType, "application/x-yaml", "text/yaml", "text/x-yaml; charset=utf-8"} {
+		r, _ := http.NewRequest(http.MethodPost, "/decode/13", strings.NewReader(body))
+		r.Header.Set("Content-Type", typ)
+		w := httptest.NewRecorder()
+		hf.ServeHTTP(w, r)
+		Equal(t, w.Code, http.StatusOK)
+		Equal(t, test.ID, 13)
+		Equal(t, test.Posted, "value")
+	}
+
+	r, _ := http.NewRequest(http.MethodPost, "/decode-noquery/13", strings.NewReader(body))
+	r.Header.Set("Content-Type", MediaType)
+	w := httptest.NewRecorder()
+	hf.ServeHTTP(w, r)
+	Equal(t, w.Code, http.StatusOK)
+	Equal(t, test.ID, 0)
+	Equal(t, test.Posted, "value")
+
+	r, _ = http.NewRequest(http.MethodPost, "/invalid", strings.NewReader("posted: &anchor value\n"))
+	r.Header.Set("Content-Type", MediaType)
+	hf.ServeHTTP(httptest.NewRecorder(), r)
+
+	r, _ = http.NewRequest(http.MethodGet, "/render", nil)
+	r.Header.Set("Accept", MediaType)
+	w = httptest.NewRecorder()
+	hf.ServeHTTP(w, r)
+	Equal(t, w.Code, http.StatusOK)
+	Equal(t, w.Header().Get("Content-Type"), MediaType)
+	Equal(t, w.Body.String(), "ID: 0\nPosted: value\n")
+}
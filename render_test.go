@@ -0,0 +1,82 @@
+package feather
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/pchchv/feather/assert"
+)
+
+func TestRender(t *testing.T) {
+	data := negotiateTest{Name: "feather"}
+	do := func(accept string, v interface{}) (*httptest.ResponseRecorder, error) {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if accept != "" {
+			r.Header.Set(acceptHeader, accept)
+		}
+
+		w := httptest.NewRecorder()
+		err := Render(w, r, http.StatusCreated, v)
+		return w, err
+	}
+
+	w, err := do("", data)
+	Equal(t, err, nil)
+	Equal(t, w.Code, http.StatusCreated)
+	Equal(t, w.Header().Get(contentTypeHeader), applicationJSON)
+	Equal(t, w.Header().Get(varyHeader), acceptHeader)
+	Equal(t, w.Body.String(), `{"name":"feather"}`)
+
+	w, _ = do("text/xml", data)
+	Equal(t, w.Header().Get(contentTypeHeader), "text/xml; charset=utf-8")
+	Equal(t, w.Body.String(), xml.Header+"<negotiateTest><name>feather</name></negotiateTest>")
+
+	w, _ = do("text/plain", data)
+	Equal(t, w.Header().Get(contentTypeHeader), textPlain)
+	Equal(t, w.Body.String(), "name=feather")
+
+	w, err = do("image/png", data)
+	Equal(t, err, nil)
+	Equal(t, w.Code, http.StatusNotAcceptable)
+
+	// encoding errors are returned before anything is written
+	w, err = do("", func() {})
+	NotEqual(t, err, nil)
+	Equal(t, w.Body.Len(), 0)
+	Equal(t, w.Header().Get(contentTypeHeader), "")
+}
+
+func TestRegisterEncoder(t *testing.T) {
+	errEncode := errors.New("encode failed")
+	RegisterEncoder("application/vnd.myco+json; charset=utf-8", func(w io.Writer, v interface{}) error {
+		_, err := w.Write([]byte("vnd:" + string(plainText(v))))
+		return err
+	})
+	RegisterEncoder("Text/Plain", func(w io.Writer, v interface{}) error {
+		return errEncode
+	})
+	defer func() {
+		RegisterEncoder("application/vnd.myco+json", nil)
+		RegisterEncoder(textPlain, encodeText)
+	}()
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set(acceptHeader, "application/vnd.myco+json")
+	w := httptest.NewRecorder()
+	Equal(t, Render(w, r, http.StatusOK, "value"), nil)
+	Equal(t, w.Header().Get(contentTypeHeader), "application/vnd.myco+json; charset=utf-8")
+	Equal(t, w.Body.String(), "vnd:value")
+
+	r.Header.Set(acceptHeader, "text/plain")
+	Equal(t, Render(httptest.NewRecorder(), r, http.StatusOK, "value"), errEncode)
+
+	RegisterEncoder("application/vnd.myco+json", nil)
+	r.Header.Set(acceptHeader, "application/vnd.myco+json")
+	w = httptest.NewRecorder()
+	Equal(t, Render(w, r, http.StatusOK, "value"), nil)
+	Equal(t, w.Code, http.StatusNotAcceptable)
+}
@@ -1,19 +1,178 @@
 package feather
 
-import "context"
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+)
 
 // ReqVars is the interface of request scoped variables tracked by feather.
 type ReqVars interface {
 	URLParam(pname string) string
+	URLParamInt(pname string) (int, error)
+	URLParamInt64(pname string) (int64, error)
+	URLParamBool(pname string) (bool, error)
+	URLParamFloat(pname string) (float64, error)
+	Wildcard() string
+	Route() string
+	Set(key string, value interface{})
+	Get(key string) interface{}
 }
 
 type requestVars struct {
-	ctx        context.Context // holds a copy of parent requestVars
-	params     urlParams
-	formParsed bool
+	params      urlParams
+	route       string
+	mux         *Mux
+	logger      *slog.Logger           // request scoped logger, built on first use
+	outgoing    http.Header            // headers set using SetOutgoingHeader
+	suggestions []string               // nearest routes when not found, see SetRouteSuggestions
+	allowed     []string               // methods allowed when answered by the OPTIONS or 405 handler, see AllowedMethods
+	meta        *Meta                  // metadata of the matched route, see WithMeta
+	values      map[string]interface{} // values set using Set, the map is pooled
+	released    bool                   // set once the request completed when debugging the pool, see SetPoolDebug
+	memory      atomic.Int64           // bytes accounted, see SetRequestMemoryLimit
+	skip        int                    // number of matching routes skipped by the lookup, they declined the request
+	next        bool                   // set when the route declined the request, see NextRoute
+	foreign     *http.Request          // request not routed by feather, its params are extracted, see RegisterParamExtractor
+	err         error                  // last error passed to the ErrorHandler, see HandledError
+	formParsed  bool
+}
+
+// routedRequest is the request passed to the handlers, allocated along with the context holding the requestVars.
+type routedRequest struct {
+	req http.Request
+	ctx requestContext
+}
+
+// requestContext wraps the context of the incoming request, adding the requestVars to its values.
+type requestContext struct {
+	context.Context
+	rv *requestVars
+}
+
+func (c *requestContext) Value(key any) any {
+	if key == defaultContextIdentifier {
+		return c.rv
+	}
+
+	return c.Context.Value(key)
 }
 
 // Params returns the current routes Params.
 func (r *requestVars) URLParam(pname string) string {
+	r.checkReleased()
+	if r.foreign != nil {
+		return extractParam(r.foreign, pname)
+	}
+
 	return r.params.Get(pname)
 }
+
+// URLParamInt returns the URL param parsed as an int.
+func (r *requestVars) URLParamInt(pname string) (int, error) {
+	i, err := strconv.Atoi(r.URLParam(pname))
+	return i, paramError(pname, err)
+}
+
+// URLParamInt64 returns the URL param parsed as an int64.
+func (r *requestVars) URLParamInt64(pname string) (int64, error) {
+	i, err := strconv.ParseInt(r.URLParam(pname), 10, 64)
+	return i, paramError(pname, err)
+}
+
+// URLParamBool returns the URL param parsed as a bool, see strconv.ParseBool for the accepted values.
+func (r *requestVars) URLParamBool(pname string) (bool, error) {
+	b, err := strconv.ParseBool(r.URLParam(pname))
+	return b, paramError(pname, err)
+}
+
+// URLParamFloat returns the URL param parsed as a float64.
+func (r *requestVars) URLParamFloat(pname string) (float64, error) {
+	f, err := strconv.ParseFloat(r.URLParam(pname), 64)
+	return f, paramError(pname, err)
+}
+
+// Wildcard returns the path matched by the catch-all of the route, i.e. css/main.css for /static/* and /static/css/main.css.
+func (r *requestVars) Wildcard() string {
+	return r.URLParam(WildcardParam)
+}
+
+func paramError(pname string, err error) error {
+	if err != nil {
+		return fmt.Errorf("feather: URL param '%s': %w", pname, err)
+	}
+
+	return nil
+}
+
+// Route returns the pattern of the matched route, e.g. /user/:id.
+func (r *requestVars) Route() string {
+	r.checkReleased()
+	return r.route
+}
+
+// Set stores a value for the duration of the request, i.e. to pass the authenticated principal
+// from middleware to handlers without allocating a new context using context.WithValue.
+// The storage is pooled, so values must not be retained after the request completes.
+func (r *requestVars) Set(key string, value interface{}) {
+	r.checkReleased()
+	if r.values == nil {
+		r.values = make(map[string]interface{})
+	}
+
+	r.values[key] = value
+}
+
+// Get returns the value stored using Set, nil if none.
+func (r *requestVars) Get(key string) interface{} {
+	r.checkReleased()
+	return r.values[key]
+}
+
+func (r *requestVars) checkReleased() {
+	if r.released {
+		panic("feather: request variables used after the request completed, they must not be retained beyond the request")
+	}
+}
+
+// poison clears rv and marks it as released, so later use panics.
+func (r *requestVars) poison() {
+	r.params = nil
+	r.route = blank
+	r.logger = nil
+	r.outgoing = nil
+	r.suggestions = nil
+	r.allowed = nil
+	r.meta = nil
+	r.values = nil
+	r.released = true
+}
+
+// requestVarsOf returns the requestVars of the request,
+// it panics if they were already released, see SetPoolDebug.
+func requestVarsOf(r *http.Request) (rv *requestVars, ok bool) {
+	if rv, ok = r.Context().Value(defaultContextIdentifier).(*requestVars); ok {
+		rv.checkReleased()
+	}
+
+	return
+}
+
+// skips reports whether the matching route found is skipped, consuming one of the routes to skip,
+// since it declined the request using NextRoute.
+func (r *requestVars) skips() bool {
+	if r == nil || r.skip == 0 {
+		return false
+	}
+
+	r.skip--
+	return true
+}
+
+// size returns the size of rv used to cap pooled objects.
+func (r *requestVars) size() int {
+	return cap(r.params) + len(r.values)
+}
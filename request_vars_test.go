@@ -0,0 +1,132 @@
+package feather
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/pchchv/feather/assert"
+)
+
+func TestRequestVarsValues(t *testing.T) {
+	p := New()
+	p.Use(func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if principal := r.URL.Query().Get("principal"); principal != "" {
+				RequestVars(r).Set("principal", principal)
+			}
+			next(w, r)
+		}
+	})
+	p.Get("/users/:id", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, RequestVars(r).Get("principal"))
+	})
+
+	code, body := request(http.MethodGet, "/users/13?principal=joeybloggs", p)
+	Equal(t, code, http.StatusOK)
+	Equal(t, body, "joeybloggs")
+
+	// values don't leak into the next request using the pooled map
+	for i := 0; i < 10; i++ {
+		code, body = request(http.MethodGet, "/users/13", p)
+		Equal(t, code, http.StatusOK)
+		Equal(t, body, "<nil>")
+	}
+
+	// requests not served by feather have their own storage
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	rv := RequestVars(r)
+	rv.Set("key", 13)
+	Equal(t, rv.Get("key"), 13)
+	Equal(t, rv.Get("missing"), nil)
+}
+
+func TestRequestVarsValuesPoolDebug(t *testing.T) {
+	var retained ReqVars
+	p := New()
+	p.SetPoolDebug(true)
+	p.Get("/users/:id", func(w http.ResponseWriter, r *http.Request) {
+		retained = RequestVars(r)
+		retained.Set("key", "value")
+	})
+
+	code, _ := request(http.MethodGet, "/users/13", p)
+	Equal(t, code, http.StatusOK)
+
+	msg := "feather: request variables used after the request completed, they must not be retained beyond the request"
+	PanicMatches(t, func() { retained.Get("key") }, msg)
+	PanicMatches(t, func() { retained.Set("key", "value") }, msg)
+}
+
+func TestRequestVarsValuesPoolMaxSize(t *testing.T) {
+	p := New()
+	p.EnablePoolStats()
+	p.SetPoolMaxSize(3)
+	p.Get("/:n", func(w http.ResponseWriter, r *http.Request) {
+		rv := RequestVars(r)
+		for i := 0; i < len(rv.URLParam("n")); i++ {
+			rv.Set(fmt.Sprint(i), i)
+		}
+	})
+
+	code, _ := request(http.MethodGet, "/a", p)
+	Equal(t, code, http.StatusOK)
+	Equal(t, p.PoolStats().Discards, uint64(0))
+
+	code, _ = request(http.MethodGet, "/abc", p)
+	Equal(t, code, http.StatusOK)
+	Equal(t, p.PoolStats().Discards, uint64(1))
+}
+
+func TestTypedURLParams(t *testing.T) {
+	p := New()
+	p.Get("/:int/:bool/:float/*", func(w http.ResponseWriter, r *http.Request) {
+		rv := RequestVars(r)
+		i, err := rv.URLParamInt("int")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		i64, err := rv.URLParamInt64("int")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		b, err := rv.URLParamBool("bool")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		f, err := rv.URLParamFloat("float")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		_, _ = fmt.Fprint(w, i, i64, b, f, rv.Wildcard())
+	})
+
+	code, body := request(http.MethodGet, "/13/true/1.5/rest/of/path", p)
+	Equal(t, code, http.StatusOK)
+	Equal(t, body, "13 13 true 1.5rest/of/path")
+
+	code, body = request(http.MethodGet, "/x/true/1.5/", p)
+	Equal(t, code, http.StatusBadRequest)
+	Equal(t, body, "feather: URL param 'int': strconv.Atoi: parsing \"x\": invalid syntax\n")
+
+	code, body = request(http.MethodGet, "/13/yes/1.5/", p)
+	Equal(t, code, http.StatusBadRequest)
+	Equal(t, body, "feather: URL param 'bool': strconv.ParseBool: parsing \"yes\": invalid syntax\n")
+
+	code, body = request(http.MethodGet, "/13/true/z/", p)
+	Equal(t, code, http.StatusBadRequest)
+	Equal(t, body, "feather: URL param 'float': strconv.ParseFloat: parsing \"z\": invalid syntax\n")
+
+	rv := RequestVars(httptest.NewRequest(http.MethodGet, "/", nil))
+	_, err := rv.URLParamInt64("missing")
+	Equal(t, err.Error(), "feather: URL param 'missing': strconv.ParseInt: parsing \"\": invalid syntax")
+}
@@ -0,0 +1,239 @@
+package feather
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"sync"
+)
+
+// ResponseWriter is the http.ResponseWriter passed to the middleware and handlers by the Mux,
+// tracking the status and size of the response so middleware doesn't need to wrap it.
+// It is safe to type assert to http.Flusher, http.Hijacker and http.Pusher,
+// which return an error or do nothing if the underlying writer doesn't support them,
+// and Unwrap makes it compatible with http.ResponseController.
+//
+// The ResponseWriter is pooled and must not be retained after the request completes.
+type ResponseWriter interface {
+	http.ResponseWriter
+	http.Flusher
+	http.Hijacker
+	http.Pusher
+	// Status returns the status code written, 0 if not written yet.
+	Status() int
+	// Size returns the number of bytes of the body written.
+	Size() int64
+	// Written reports whether the header was written.
+	Written() bool
+	// Before registers fn to run right before the header is written, i.e. to set headers.
+	// Functions run in the order they were registered.
+	Before(fn func(ResponseWriter))
+	// After registers fn to run after the handler returned, including all middleware.
+	// Functions run in the order they were registered.
+	After(fn func(ResponseWriter))
+	// Unwrap returns the underlying http.ResponseWriter.
+	Unwrap() http.ResponseWriter
+}
+
+var _ ResponseWriter = (*responseWriter)(nil)
+
+var responseWriterPool = sync.Pool{
+	New: func() interface{} {
+		return new(responseWriter)
+	},
+}
+
+type responseWriter struct {
+	http.ResponseWriter
+	status     int
+	size       int64
+	jsonPrefix string // prefix of top-level JSON arrays, see SetSecureJSONPrefix
+	before     []func(ResponseWriter)
+	after      []func(ResponseWriter)
+}
+
+// getResponseWriter gets a responseWriter wrapping w from the pool.
+func getResponseWriter(w http.ResponseWriter) *responseWriter {
+	rw := responseWriterPool.Get().(*responseWriter)
+	rw.ResponseWriter = w
+	return rw
+}
+
+// putResponseWriter resets rw and returns it to the pool.
+func putResponseWriter(rw *responseWriter) {
+	rw.ResponseWriter = nil
+	rw.status = 0
+	rw.size = 0
+	rw.jsonPrefix = blank
+	clear(rw.before)
+	rw.before = rw.before[:0]
+	clear(rw.after)
+	rw.after = rw.after[:0]
+	responseWriterPool.Put(rw)
+}
+
+// WriteHeader runs the Before functions and writes the header once, later calls are ignored.
+// Informational 1xx headers are passed through without counting as written.
+func (rw *responseWriter) WriteHeader(status int) {
+	if rw.status != 0 {
+		return
+	}
+
+	if status >= http.StatusContinue && status < http.StatusOK && status != http.StatusSwitchingProtocols {
+		rw.ResponseWriter.WriteHeader(status)
+		return
+	}
+
+	// set before running the functions, so they can't write the header again
+	rw.status = status
+	for _, fn := range rw.before {
+		fn(rw)
+	}
+
+	rw.ResponseWriter.WriteHeader(status)
+}
+
+// Write writes the header with 200 OK if not written yet and counts the bytes written.
+func (rw *responseWriter) Write(b []byte) (int, error) {
+	if rw.status == 0 {
+		rw.WriteHeader(http.StatusOK)
+	}
+
+	n, err := rw.ResponseWriter.Write(b)
+	rw.size += int64(n)
+	return n, err
+}
+
+// Status returns the status code written, 0 if not written yet.
+func (rw *responseWriter) Status() int {
+	return rw.status
+}
+
+// Size returns the number of bytes of the body written.
+func (rw *responseWriter) Size() int64 {
+	return rw.size
+}
+
+// Written reports whether the header was written.
+func (rw *responseWriter) Written() bool {
+	return rw.status != 0
+}
+
+// Before registers fn to run right before the header is written.
+func (rw *responseWriter) Before(fn func(ResponseWriter)) {
+	rw.before = append(rw.before, fn)
+}
+
+// After registers fn to run after the handler returned.
+func (rw *responseWriter) After(fn func(ResponseWriter)) {
+	rw.after = append(rw.after, fn)
+}
+
+// runAfter runs the After functions.
+func (rw *responseWriter) runAfter() {
+	for _, fn := range rw.after {
+		fn(rw)
+	}
+}
+
+// Flush writes the header if not written yet and flushes the buffered data, if supported.
+func (rw *responseWriter) Flush() {
+	if rw.status == 0 {
+		rw.WriteHeader(http.StatusOK)
+	}
+
+	_ = http.NewResponseController(rw.ResponseWriter).Flush()
+}
+
+// Hijack lets the caller take over the connection, if supported.
+func (rw *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(rw.ResponseWriter).Hijack()
+}
+
+// Push initiates an HTTP/2 server push, returning http.ErrNotSupported if not supported.
+func (rw *responseWriter) Push(target string, opts *http.PushOptions) error {
+	if p, ok := rw.ResponseWriter.(http.Pusher); ok {
+		return p.Push(target, opts)
+	}
+
+	return http.ErrNotSupported
+}
+
+// Unwrap returns the underlying http.ResponseWriter for use by http.ResponseController.
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// ResponseWriterOf returns the ResponseWriter of the Mux that w is or wraps, following the Unwrap chain
+// of writers wrapped by middleware.
+func ResponseWriterOf(w http.ResponseWriter) (ResponseWriter, bool) {
+	for {
+		switch t := w.(type) {
+		case ResponseWriter:
+			return t, true
+		case interface{ Unwrap() http.ResponseWriter }:
+			w = t.Unwrap()
+		default:
+			return nil, false
+		}
+	}
+}
+
+// ResponseTracker reports the status and size of a response, see TrackResponse.
+type ResponseTracker interface {
+	// Status returns the status code written, 0 if not written yet.
+	Status() int
+	// Size returns the number of bytes of the body written.
+	Size() int64
+}
+
+// TrackResponse returns the writer middleware passes to the next handler and the tracker of its response.
+// The ResponseWriter of the Mux tracks the response already, so if w is or wraps it, see ResponseWriterOf,
+// w is returned as is, keeping http.Flusher and http.Hijacker visible to the handlers.
+// Other writers, i.e. when the middleware is used outside of the Mux, are wrapped.
+func TrackResponse(w http.ResponseWriter) (http.ResponseWriter, ResponseTracker) {
+	if fw, ok := ResponseWriterOf(w); ok {
+		return w, fw
+	}
+
+	tw := &trackingWriter{ResponseWriter: w}
+	return tw, tw
+}
+
+// trackingWriter tracks the status and size of responses not written using the Mux.
+type trackingWriter struct {
+	http.ResponseWriter
+	status int
+	size   int64
+}
+
+func (w *trackingWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *trackingWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+
+	n, err := w.ResponseWriter.Write(b)
+	w.size += int64(n)
+	return n, err
+}
+
+func (w *trackingWriter) Status() int {
+	return w.status
+}
+
+func (w *trackingWriter) Size() int64 {
+	return w.size
+}
+
+// Unwrap returns the underlying http.ResponseWriter for use by http.ResponseController.
+func (w *trackingWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,108 @@
+package feather
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/pchchv/feather/assert"
+)
+
+type wrappedWriter struct {
+	http.ResponseWriter
+}
+
+func (w wrappedWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func TestResponseWriter(t *testing.T) {
+	var status int
+	var size int64
+	var order []string
+	p := New()
+	p.Use(func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			fw, ok := ResponseWriterOf(w)
+			Equal(t, ok, true)
+			fw.Before(func(fw ResponseWriter) {
+				order = append(order, "before")
+				fw.Header().Set("X-Before", "set")
+				// ignored, the header is being written
+				fw.WriteHeader(http.StatusTeapot)
+			})
+			fw.After(func(fw ResponseWriter) {
+				order = append(order, "after")
+				status, size = fw.Status(), fw.Size()
+			})
+
+			next(wrappedWriter{w}, r)
+			order = append(order, "middleware")
+		}
+	})
+	p.Get("/users", func(w http.ResponseWriter, r *http.Request) {
+		fw, ok := ResponseWriterOf(w)
+		Equal(t, ok, true)
+		Equal(t, fw.Written(), false)
+		Equal(t, fw.Status(), 0)
+
+		w.WriteHeader(http.StatusCreated)
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte("created"))
+		Equal(t, fw.Written(), true)
+
+		// flushing works through the wrappers
+		Equal(t, http.NewResponseController(w).Flush(), nil)
+	})
+
+	r, _ := http.NewRequest(http.MethodGet, "/users", nil)
+	w := httptest.NewRecorder()
+	p.Serve().ServeHTTP(w, r)
+	Equal(t, w.Code, http.StatusCreated)
+	Equal(t, w.Body.String(), "created")
+	Equal(t, w.Header().Get("X-Before"), "set")
+	Equal(t, w.Flushed, true)
+	Equal(t, status, http.StatusCreated)
+	Equal(t, size, int64(7))
+	Equal(t, order, []string{"before", "middleware", "after"})
+
+	_, ok := ResponseWriterOf(w)
+	Equal(t, ok, false)
+}
+
+func TestResponseWriterUnsupported(t *testing.T) {
+	rw := getResponseWriter(httptest.NewRecorder())
+	defer putResponseWriter(rw)
+
+	_, _, err := rw.Hijack()
+	NotEqual(t, err, nil)
+	Equal(t, rw.Push("/style.css", nil), http.ErrNotSupported)
+
+	rw.WriteHeader(http.StatusEarlyHints)
+	Equal(t, rw.Written(), false)
+	_, _ = rw.Write([]byte("ok"))
+	Equal(t, rw.Status(), http.StatusOK)
+}
+
+func TestTrackResponse(t *testing.T) {
+	// the ResponseWriter of the Mux is not wrapped
+	rw := getResponseWriter(httptest.NewRecorder())
+	defer putResponseWriter(rw)
+
+	w, rt := TrackResponse(wrappedWriter{rw})
+	Equal(t, w, http.ResponseWriter(wrappedWriter{rw}))
+	_, _ = w.Write([]byte("ok"))
+	Equal(t, rt.Status(), http.StatusOK)
+	Equal(t, rt.Size(), int64(2))
+
+	// other writers are
+	rec := httptest.NewRecorder()
+	w, rt = TrackResponse(rec)
+	Equal(t, rt.Status(), 0)
+	w.WriteHeader(http.StatusCreated)
+	_, _ = w.Write([]byte("created"))
+	Equal(t, rt.Status(), http.StatusCreated)
+	Equal(t, rt.Size(), int64(7))
+	Equal(t, rec.Body.String(), "created")
+	Equal(t, w.(interface{ Unwrap() http.ResponseWriter }).Unwrap(), http.ResponseWriter(rec))
+}
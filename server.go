@@ -0,0 +1,190 @@
+package feather
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+const defaultDrainTimeout = 30 * time.Second
+
+// Server wraps http.Server, shutting it down gracefully when
+// one of the Signals is received or Stop is called,
+// allowing in-flight requests to complete.
+type Server struct {
+	*http.Server
+	// DrainTimeout is the maximum time to wait for in-flight requests to complete, default 30s.
+	DrainTimeout time.Duration
+	// Signals triggering the shutdown, default SIGINT and SIGTERM.
+	Signals []os.Signal
+	// OnShutdownStart is called when the shutdown begins, before draining.
+	OnShutdownStart func()
+	// OnShutdownComplete is called once draining finished with the shutdown error, if any.
+	OnShutdownComplete func(err error)
+	// ReloadSignals trigger a reload, see Reload, default SIGHUP.
+	ReloadSignals []os.Signal
+	// WatchFiles trigger a reload when one of them is modified, checked every WatchInterval, default 5s.
+	WatchFiles    []string
+	WatchInterval time.Duration
+	// OnReload is called on reload to reload the configuration, i.e. redirect rules, rate limits
+	// or maintenance flags, connections are not affected.
+	OnReload func() error
+	// OnReloadComplete is called once a reload triggered by a signal or a modified file finished with its error, if any.
+	OnReloadComplete func(err error)
+	// UpgradeSignals trigger a zero-downtime binary upgrade, see Upgrade, none by default, i.e. SIGUSR2.
+	UpgradeSignals []os.Signal
+	// UpgradeTimeout is the maximum time to wait for the upgraded process to serve, default 30s.
+	UpgradeTimeout time.Duration
+	// OnUpgradeComplete is called once an upgrade triggered by a signal finished with its error, if any.
+	OnUpgradeComplete func(err error)
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	reloadMu sync.Mutex
+	cert     atomic.Pointer[tls.Certificate] // certificate reloaded from the files, see ListenAndServeTLS
+	certFile string
+	keyFile  string
+	// upgradeMu guards listener, the listener served and passed to the upgraded process.
+	upgradeMu sync.Mutex
+	listener  net.Listener
+}
+
+// NewServer returns a new Server for the handler listening on addr.
+func NewServer(addr string, h http.Handler) *Server {
+	return &Server{
+		Server: &http.Server{
+			Addr:    addr,
+			Handler: h,
+		},
+		DrainTimeout:  defaultDrainTimeout,
+		Signals:       []os.Signal{os.Interrupt, syscall.SIGTERM},
+		ReloadSignals: []os.Signal{syscall.SIGHUP},
+		stop:          make(chan struct{}),
+	}
+}
+
+// ListenAndServeGracefully listens on addr and serves the Mux until SIGINT or SIGTERM is received,
+// then gracefully shuts down.
+func (p *Mux) ListenAndServeGracefully(addr string) error {
+	return NewServer(addr, p.Serve()).ListenAndServe()
+}
+
+// ListenAndServe listens on the TCP network address and serves requests until shut down.
+// Unlike http.Server it returns nil or the shutdown error after a graceful shutdown.
+// The process started by Upgrade serves the listener inherited from its parent instead.
+func (s *Server) ListenAndServe() error {
+	l, err := s.listen(":http")
+	if err != nil {
+		return err
+	}
+
+	return s.Serve(l)
+}
+
+// ListenAndServeTLS is the TLS counterpart of ListenAndServe.
+// The certificate is loaded from the files again on every reload, without dropping connections.
+func (s *Server) ListenAndServeTLS(certFile, keyFile string) error {
+	s.certFile, s.keyFile = certFile, keyFile
+	if err := s.loadCertificate(); err != nil {
+		return err
+	}
+
+	if s.TLSConfig == nil {
+		s.TLSConfig = &tls.Config{}
+	} else {
+		s.TLSConfig = s.TLSConfig.Clone()
+	}
+
+	s.TLSConfig.GetCertificate = func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+		return s.cert.Load(), nil
+	}
+
+	l, err := s.listen(":https")
+	if err != nil {
+		return err
+	}
+
+	return s.serve(l, func() error {
+		return s.Server.ServeTLS(l, blank, blank)
+	})
+}
+
+// Serve serves requests on the listener until shut down.
+func (s *Server) Serve(l net.Listener) error {
+	return s.serve(l, func() error {
+		return s.Server.Serve(l)
+	})
+}
+
+// Stop triggers the graceful shutdown as if a signal had been received.
+func (s *Server) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.stop)
+	})
+}
+
+func (s *Server) serve(l net.Listener, fn func() error) error {
+	s.upgradeMu.Lock()
+	s.listener = l
+	s.upgradeMu.Unlock()
+	notifyReady()
+
+	sigs := make(chan os.Signal, 1)
+	if len(s.Signals) > 0 {
+		signal.Notify(sigs, s.Signals...)
+		defer signal.Stop(sigs)
+	}
+
+	served := make(chan struct{})
+	defer s.watchReloads(served)()
+	defer s.watchUpgrades(served)()
+
+	shutdown := make(chan error, 1)
+	go func() {
+		select {
+		case <-sigs:
+		case <-s.stop:
+		case <-served:
+			// stopped by other means, e.g. calling Shutdown directly
+			shutdown <- nil
+			return
+		}
+
+		shutdown <- s.shutdown()
+	}()
+
+	err := fn()
+	close(served)
+	if err != http.ErrServerClosed {
+		return err
+	}
+
+	return <-shutdown
+}
+
+func (s *Server) shutdown() error {
+	if s.OnShutdownStart != nil {
+		s.OnShutdownStart()
+	}
+
+	timeout := s.DrainTimeout
+	if timeout <= 0 {
+		timeout = defaultDrainTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	err := s.Server.Shutdown(ctx)
+	if s.OnShutdownComplete != nil {
+		s.OnShutdownComplete(err)
+	}
+
+	return err
+}
@@ -0,0 +1,60 @@
+package feather
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	. "github.com/pchchv/feather/assert"
+)
+
+func TestServerGracefulShutdown(t *testing.T) {
+	started := make(chan struct{})
+	p := New()
+	p.Get("/slow", func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(50 * time.Millisecond)
+		_, _ = w.Write([]byte("done"))
+	})
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	Equal(t, err, nil)
+
+	var events []string
+	s := NewServer(l.Addr().String(), p.Serve())
+	s.Signals = nil
+	s.OnShutdownStart = func() { events = append(events, "start") }
+	s.OnShutdownComplete = func(err error) {
+		Equal(t, err, nil)
+		events = append(events, "complete")
+	}
+
+	served := make(chan error, 1)
+	go func() {
+		served <- s.Serve(l)
+	}()
+
+	body := make(chan string, 1)
+	go func() {
+		resp, err := http.Get("http://" + l.Addr().String() + "/slow")
+		Equal(t, err, nil)
+		b, _ := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		body <- string(b)
+	}()
+
+	<-started
+	s.Stop()
+	s.Stop()
+	Equal(t, <-served, nil)
+	Equal(t, <-body, "done")
+	Equal(t, events, []string{"start", "complete"})
+}
+
+func TestServerListenError(t *testing.T) {
+	s := NewServer("bad-address", New().Serve())
+	s.Signals = nil
+	NotEqual(t, s.ListenAndServe(), nil)
+}
@@ -0,0 +1,143 @@
+package feather
+
+import (
+	"bytes"
+	"encoding/hex"
+	"hash/fnv"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	faviconPath          = "/favicon.ico"
+	robotsPath           = "/robots.txt"
+	faviconCacheControl  = "public, max-age=604800"
+	robotsCacheControl   = "public, max-age=86400"
+	imageXIcon           = "image/x-icon"
+	imagePNG             = "image/png"
+	imageSVG             = "image/svg+xml"
+	defaultRobotsAgent   = "*"
+	robotsUserAgentField = "User-agent: "
+)
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// RobotsGroup is a group of robots.txt rules applying to the crawlers of the user agents.
+type RobotsGroup struct {
+	// UserAgents the rules apply to, all crawlers if empty.
+	UserAgents []string
+	// Allow lists the path prefixes crawlers may access, overriding Disallow for longer matches.
+	Allow []string
+	// Disallow lists the path prefixes crawlers must not access, "/" disallows the whole site.
+	Disallow []string
+}
+
+// RobotsRules are the rules of robots.txt, see RFC 9309.
+type RobotsRules struct {
+	Groups []RobotsGroup
+	// Sitemaps lists the absolute URLs of the sitemaps.
+	Sitemaps []string
+}
+
+// String returns the rules in the robots.txt format.
+func (rules RobotsRules) String() string {
+	var b strings.Builder
+	for i, g := range rules.Groups {
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+
+		agents := g.UserAgents
+		if len(agents) == 0 {
+			agents = []string{defaultRobotsAgent}
+		}
+
+		for _, agent := range agents {
+			b.WriteString(robotsUserAgentField + agent + "\n")
+		}
+
+		for _, path := range g.Allow {
+			b.WriteString("Allow: " + path + "\n")
+		}
+
+		for _, path := range g.Disallow {
+			b.WriteString("Disallow: " + path + "\n")
+		}
+
+		// a group without rules allows everything, but needs a rule to be valid
+		if len(g.Allow) == 0 && len(g.Disallow) == 0 {
+			b.WriteString("Disallow:\n")
+		}
+	}
+
+	if len(rules.Sitemaps) > 0 && len(rules.Groups) > 0 {
+		b.WriteByte('\n')
+	}
+
+	for _, sitemap := range rules.Sitemaps {
+		b.WriteString("Sitemap: " + sitemap + "\n")
+	}
+
+	return b.String()
+}
+
+// Favicon registers a GET route serving the icon at favicon.ico below the group prefix,
+// cached for a week and revalidated using an ETag. The content type is image/png for PNG data,
+// image/svg+xml for SVG data and image/x-icon otherwise.
+func (g *routeGroup) Favicon(data []byte) {
+	g.Get(faviconPath, staticContent(faviconType(data), faviconCacheControl, data))
+}
+
+// Robots registers a GET route serving the rules at robots.txt below the group prefix,
+// cached for a day and revalidated using an ETag, i.e. disallowing the crawling of a staging host:
+//
+//	p.Host("staging.example.com").Robots(feather.RobotsRules{Groups: []feather.RobotsGroup{{Disallow: []string{"/"}}}})
+func (g *routeGroup) Robots(rules RobotsRules) {
+	g.Get(robotsPath, staticContent(textPlain, robotsCacheControl, []byte(rules.String())))
+}
+
+// staticContent returns a handler serving data, which must not be modified afterwards,
+// with the headers computed once so serving doesn't allocate,
+// answering requests whose If-None-Match lists the ETag of data with 304 Not Modified.
+func staticContent(contentType string, cacheControl string, data []byte) http.HandlerFunc {
+	hash := fnv.New64a()
+	hash.Write(data)
+	etag := `"` + hex.EncodeToString(hash.Sum(nil)) + `"`
+	contentTypeValue := []string{contentType}
+	cacheControlValue := []string{cacheControl}
+	etagValue := []string{etag}
+	contentLengthValue := []string{strconv.Itoa(len(data))}
+	etagKey := http.CanonicalHeaderKey(etagHeader) // Etag, as the headers are set directly
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		h := w.Header()
+		h[cacheControlHeader] = cacheControlValue
+		h[etagKey] = etagValue
+		if inm := r.Header.Get(ifNoneMatchHeader); inm != blank && etagMatch(inm, etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		h[contentTypeHeader] = contentTypeValue
+		h[contentLengthHeader] = contentLengthValue
+		w.WriteHeader(http.StatusOK)
+		if r.Method != http.MethodHead {
+			_, _ = w.Write(data)
+		}
+	}
+}
+
+// faviconType returns the content type of the icon.
+func faviconType(data []byte) string {
+	if bytes.HasPrefix(data, pngSignature) {
+		return imagePNG
+	}
+
+	trimmed := bytes.TrimSpace(data)
+	if bytes.HasPrefix(trimmed, []byte("<svg")) || bytes.HasPrefix(trimmed, []byte("<?xml")) {
+		return imageSVG
+	}
+
+	return imageXIcon
+}
@@ -0,0 +1,89 @@
+package feather
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/pchchv/feather/assert"
+)
+
+type discardWriter struct {
+	header http.Header
+	status int
+}
+
+func (w *discardWriter) Header() http.Header         { return w.header }
+func (w *discardWriter) WriteHeader(status int)      { w.status = status }
+func (w *discardWriter) Write(b []byte) (int, error) { return len(b), nil }
+
+func TestFavicon(t *testing.T) {
+	icon := []byte("\x89PNG\r\n\x1a\nicon")
+	p := New()
+	p.Favicon(icon)
+	p.Host("svg.example.com").Favicon([]byte(`  <svg xmlns="http://www.w3.org/2000/svg"></svg>`))
+	hf := p.Serve()
+
+	w := httptest.NewRecorder()
+	hf.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/favicon.ico", nil))
+	Equal(t, w.Code, http.StatusOK)
+	Equal(t, w.Body.Bytes(), icon)
+	Equal(t, w.Header().Get(contentTypeHeader), "image/png")
+	Equal(t, w.Header().Get(cacheControlHeader), "public, max-age=604800")
+	Equal(t, w.Header().Get(contentLengthHeader), "12")
+	etag := w.Header().Get(etagHeader)
+	NotEqual(t, etag, "")
+
+	r := httptest.NewRequest(http.MethodGet, "/favicon.ico", nil)
+	r.Header.Set(ifNoneMatchHeader, etag)
+	w = httptest.NewRecorder()
+	hf.ServeHTTP(w, r)
+	Equal(t, w.Code, http.StatusNotModified)
+	Equal(t, w.Body.Len(), 0)
+
+	w = httptest.NewRecorder()
+	hf.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "http://svg.example.com/favicon.ico", nil))
+	Equal(t, w.Header().Get(contentTypeHeader), "image/svg+xml")
+
+	Equal(t, faviconType([]byte("\x00\x00\x01\x00")), "image/x-icon")
+
+	h := staticContent(imagePNG, faviconCacheControl, icon)
+	dw := &discardWriter{header: make(http.Header)}
+	r = httptest.NewRequest(http.MethodGet, "/favicon.ico", nil)
+	allocs := testing.AllocsPerRun(100, func() {
+		h(dw, r)
+	})
+	Equal(t, allocs, float64(0))
+}
+
+func TestRobots(t *testing.T) {
+	rules := RobotsRules{
+		Groups: []RobotsGroup{
+			{UserAgents: []string{"GPTBot", "CCBot"}, Disallow: []string{"/"}},
+			{Allow: []string{"/admin/help"}, Disallow: []string{"/admin", "/tmp"}},
+			{UserAgents: []string{"Googlebot"}},
+		},
+		Sitemaps: []string{"https://example.com/sitemap.xml"},
+	}
+	Equal(t, rules.String(), "User-agent: GPTBot\nUser-agent: CCBot\nDisallow: /\n\n"+
+		"User-agent: *\nAllow: /admin/help\nDisallow: /admin\nDisallow: /tmp\n\n"+
+		"User-agent: Googlebot\nDisallow:\n\n"+
+		"Sitemap: https://example.com/sitemap.xml\n")
+
+	p := New()
+	p.Robots(rules)
+	p.SetAutomaticHEAD(true)
+	hf := p.Serve()
+
+	w := httptest.NewRecorder()
+	hf.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/robots.txt", nil))
+	Equal(t, w.Code, http.StatusOK)
+	Equal(t, w.Body.String(), rules.String())
+	Equal(t, w.Header().Get(contentTypeHeader), textPlain)
+	Equal(t, w.Header().Get(cacheControlHeader), "public, max-age=86400")
+
+	w = httptest.NewRecorder()
+	hf.ServeHTTP(w, httptest.NewRequest(http.MethodHead, "/robots.txt", nil))
+	Equal(t, w.Code, http.StatusOK)
+	Equal(t, w.Body.Len(), 0)
+}
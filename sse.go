@@ -0,0 +1,139 @@
+package feather
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	cacheControlHeader    = "Cache-Control"
+	xAccelBufferingHeader = "X-Accel-Buffering"
+	textEventStream       = "text/event-stream"
+	defaultKeepAlive      = 15 * time.Second
+)
+
+// EventStreamWriter writes Server-Sent Events, it is safe for concurrent use.
+type EventStreamWriter struct {
+	w      http.ResponseWriter
+	rc     *http.ResponseController
+	m      sync.Mutex
+	ticker *time.Ticker
+	done   chan struct{}
+	once   sync.Once
+	err    error // first write error, the stream is broken once set
+}
+
+// EventStream starts a Server-Sent Events response, sending keepalive pings every 15 seconds,
+// see SetKeepAlive. Close must be called once done, i.e.
+//
+//	es, err := feather.EventStream(w)
+//	if err != nil {
+//		return
+//	}
+//	defer es.Close()
+//
+// The Content-Type is set to text/event-stream, which compression middleware such as middlewares/gzip
+// detect to write the response uncompressed, and proxy buffering is disabled using X-Accel-Buffering.
+// An error is returned if the ResponseWriter does not support flushing.
+func EventStream(w http.ResponseWriter) (*EventStreamWriter, error) {
+	h := w.Header()
+	h.Set(contentTypeHeader, textEventStream)
+	h.Set(cacheControlHeader, "no-cache")
+	h.Set(xAccelBufferingHeader, "no")
+	w.WriteHeader(http.StatusOK)
+
+	es := &EventStreamWriter{
+		w:      w,
+		rc:     http.NewResponseController(w),
+		ticker: time.NewTicker(defaultKeepAlive),
+		done:   make(chan struct{}),
+	}
+
+	if err := es.rc.Flush(); err != nil {
+		es.ticker.Stop()
+		return nil, err
+	}
+
+	go es.keepAlive()
+	return es, nil
+}
+
+// SetKeepAlive sets the interval of the keepalive pings, 0 disables them.
+func (es *EventStreamWriter) SetKeepAlive(d time.Duration) {
+	if d <= 0 {
+		es.ticker.Stop()
+		return
+	}
+
+	es.ticker.Reset(d)
+}
+
+// SendEvent sends an event, id and event are omitted when blank and multiline data is sent as multiple data fields.
+func (es *EventStreamWriter) SendEvent(id string, event string, data string) error {
+	var b strings.Builder
+	if id != blank {
+		b.WriteString("id: " + singleLine(id) + "\n")
+	}
+
+	if event != blank {
+		b.WriteString("event: " + singleLine(event) + "\n")
+	}
+
+	for _, line := range strings.Split(strings.ReplaceAll(data, "\r\n", "\n"), "\n") {
+		b.WriteString("data: " + line + "\n")
+	}
+	b.WriteString("\n")
+
+	return es.write(b.String())
+}
+
+// Close stops the keepalive pings, the stream must not be written to after Close returns.
+func (es *EventStreamWriter) Close() {
+	es.once.Do(func() {
+		es.m.Lock()
+		defer es.m.Unlock()
+		es.ticker.Stop()
+		close(es.done)
+	})
+}
+
+func (es *EventStreamWriter) keepAlive() {
+	for {
+		select {
+		case <-es.done:
+			return
+		case <-es.ticker.C:
+			if err := es.write(": ping\n\n"); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (es *EventStreamWriter) write(s string) error {
+	es.m.Lock()
+	defer es.m.Unlock()
+	if es.err != nil {
+		return es.err
+	}
+
+	select {
+	case <-es.done:
+		// the response may be finished, it must not be written to anymore
+		return io.ErrClosedPipe
+	default:
+	}
+
+	if _, es.err = io.WriteString(es.w, s); es.err == nil {
+		es.err = es.rc.Flush()
+	}
+
+	return es.err
+}
+
+func singleLine(s string) string {
+	return strings.NewReplacer("\r", blank, "\n", blank).Replace(s)
+}
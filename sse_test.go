@@ -0,0 +1,65 @@
+package feather
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/pchchv/feather/assert"
+)
+
+func TestEventStream(t *testing.T) {
+	p := New()
+	p.Get("/events", func(w http.ResponseWriter, r *http.Request) {
+		es, err := EventStream(w)
+		if err != nil {
+			panic(err)
+		}
+		defer es.Close()
+
+		es.SetKeepAlive(5 * time.Millisecond)
+		time.Sleep(20 * time.Millisecond)
+		es.SetKeepAlive(0)
+		Equal(t, es.SendEvent("1", "update", "line1\nline2"), nil)
+		Equal(t, es.SendEvent("", "", "plain"), nil)
+		Equal(t, es.SendEvent("2\n", "bad\r\nevent", "x"), nil)
+	})
+
+	server := httptest.NewServer(p.Serve())
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/events")
+	Equal(t, err, nil)
+	defer resp.Body.Close()
+	Equal(t, resp.Header.Get(contentTypeHeader), textEventStream)
+	Equal(t, resp.Header.Get(cacheControlHeader), "no-cache")
+
+	var b strings.Builder
+	buf := make([]byte, 512)
+	for {
+		n, err := resp.Body.Read(buf)
+		b.Write(buf[:n])
+		if err != nil {
+			break
+		}
+	}
+
+	body := b.String()
+	Equal(t, strings.HasPrefix(body, ": ping\n\n"), true)
+	Equal(t, strings.HasSuffix(body, "id: 1\nevent: update\ndata: line1\ndata: line2\n\n"+
+		"data: plain\n\n"+
+		"id: 2\nevent: badevent\ndata: x\n\n"), true)
+}
+
+type noFlushWriter struct {
+	http.ResponseWriter
+}
+
+func TestEventStreamNotSupported(t *testing.T) {
+	es, err := EventStream(noFlushWriter{httptest.NewRecorder()})
+	Equal(t, es == nil, true)
+	Equal(t, errors.Is(err, http.ErrNotSupported), true)
+}
@@ -0,0 +1,216 @@
+package feather
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"hash/fnv"
+	"html"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+)
+
+const defaultIndexFile = "index.html"
+
+// StaticOptions contains the optional settings of Static and StaticFS.
+type StaticOptions struct {
+	// IndexFile is served for directory requests, defaults to index.html.
+	IndexFile string
+	// Browse enables directory listings for directories without an index file.
+	Browse bool
+	// NotFound handles requests for missing files, defaults to the Mux's 404 handler.
+	NotFound http.HandlerFunc
+	// Manifest of the fingerprinted files, which are served with a long lived immutable Cache-Control, see LoadManifest.
+	Manifest *Manifest
+}
+
+// Static serves the files of the root directory under prefix, i.e. p.Static("/assets", "./public").
+func (g *routeGroup) Static(prefix string, root string, opts ...StaticOptions) {
+	g.StaticFS(prefix, os.DirFS(root), opts...)
+}
+
+// StaticFS serves the files of fsys under prefix using a catch-all route for GET and HEAD requests.
+// Range and conditional requests are supported.
+func (g *routeGroup) StaticFS(prefix string, fsys fs.FS, opts ...StaticOptions) {
+	var o StaticOptions
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+
+	if o.IndexFile == blank {
+		o.IndexFile = defaultIndexFile
+	}
+
+	mux := g.feather
+	notFound := func(w http.ResponseWriter, r *http.Request) {
+		if o.NotFound != nil {
+			o.NotFound(w, r)
+			return
+		}
+
+		mux.notFound(r.URL.Path)(w, r)
+	}
+
+	h := func(w http.ResponseWriter, r *http.Request) {
+		name := path.Clean(basePath + RequestVars(r).Wildcard())[1:]
+		if name == blank {
+			name = "."
+		}
+
+		if !fs.ValidPath(name) {
+			notFound(w, r)
+			return
+		}
+
+		fi, err := fs.Stat(fsys, name)
+		if err != nil {
+			notFound(w, r)
+			return
+		}
+
+		if fi.IsDir() {
+			if !strings.HasSuffix(r.URL.Path, basePath) {
+				http.Redirect(w, r, r.URL.Path+basePath, http.StatusMovedPermanently)
+				return
+			}
+
+			index := path.Join(name, o.IndexFile)
+			if ifi, err := fs.Stat(fsys, index); err == nil && !ifi.IsDir() {
+				serveFile(w, r, fsys, index)
+				return
+			}
+
+			if !o.Browse {
+				notFound(w, r)
+				return
+			}
+
+			listDir(w, fsys, name)
+			return
+		}
+
+		if o.Manifest != nil && o.Manifest.fingerprinted(name) {
+			w.Header().Set(cacheControlHeader, immutableCacheControl)
+		}
+
+		serveFile(w, r, fsys, name)
+	}
+
+	prefix = strings.TrimSuffix(prefix, basePath) + "/*"
+	g.Get(prefix, h)
+	g.Head(prefix, h)
+}
+
+func serveFile(w http.ResponseWriter, r *http.Request, fsys fs.FS, name string) {
+	if err := FileFS(w, r, fsys, name); err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	}
+}
+
+// File serves the file name, delegating to http.ServeContent for Range, If-Modified-Since, If-None-Match
+// and If-Range support, so downloads can be resumed. The content type is detected from the extension
+// and then the content, and an ETag derived from the modification time and size, or from the content
+// of files without a modification time, is set unless already set.
+// Errors opening the file, i.e. fs.ErrNotExist, are returned before anything is written.
+func File(w http.ResponseWriter, r *http.Request, name string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return serveContent(w, r, f)
+}
+
+// FileFS is the fs.FS counterpart of File, name must be a valid fs path, see fs.ValidPath.
+func FileFS(w http.ResponseWriter, r *http.Request, fsys fs.FS, name string) error {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return serveContent(w, r, f)
+}
+
+// serveContent serves the regular file f using http.ServeContent.
+func serveContent(w http.ResponseWriter, r *http.Request, f fs.File) error {
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	if fi.IsDir() {
+		return &fs.PathError{Op: "serve", Path: fi.Name(), Err: errors.New("is a directory")}
+	}
+
+	rs, ok := f.(io.ReadSeeker)
+	if !ok {
+		b, err := io.ReadAll(f)
+		if err != nil {
+			return err
+		}
+		rs = bytes.NewReader(b)
+	}
+
+	if w.Header().Get(etagHeader) == blank {
+		etag, err := fileETag(fi, rs)
+		if err != nil {
+			return err
+		}
+		w.Header().Set(etagHeader, etag)
+	}
+
+	http.ServeContent(w, r, fi.Name(), fi.ModTime(), rs)
+	return nil
+}
+
+// fileETag returns the ETag of the file derived from its modification time and size.
+// Files without a modification time, i.e. those of an embed.FS, get one derived from their content instead,
+// so a changed file of the same size isn't answered with 304 Not Modified.
+func fileETag(fi fs.FileInfo, rs io.ReadSeeker) (string, error) {
+	if !fi.ModTime().IsZero() {
+		return `"` + strconv.FormatInt(fi.ModTime().UnixNano(), 36) + "-" + strconv.FormatInt(fi.Size(), 36) + `"`, nil
+	}
+
+	hash := fnv.New64a()
+	if _, err := io.Copy(hash, rs); err != nil {
+		return blank, err
+	}
+
+	if _, err := rs.Seek(0, io.SeekStart); err != nil {
+		return blank, err
+	}
+
+	return `"` + hex.EncodeToString(hash.Sum(nil)) + `"`, nil
+}
+
+func listDir(w http.ResponseWriter, fsys fs.FS, name string) {
+	entries, err := fs.ReadDir(fsys, name)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	var b strings.Builder
+	b.WriteString("<!doctype html>\n<pre>\n")
+	for _, e := range entries {
+		n := e.Name()
+		if e.IsDir() {
+			n += basePath
+		}
+
+		u := url.URL{Path: n}
+		b.WriteString(`<a href="` + html.EscapeString(u.String()) + `">` + html.EscapeString(n) + "</a>\n")
+	}
+	b.WriteString("</pre>\n")
+
+	w.Header().Set(contentTypeHeader, "text/html; charset=utf-8")
+	_, _ = io.WriteString(w, b.String())
+}
@@ -0,0 +1,249 @@
+package feather
+
+import (
+	"errors"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	. "github.com/pchchv/feather/assert"
+)
+
+func TestStaticFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"app.css":         {Data: []byte("body{}")},
+		"docs/index.html": {Data: []byte("<h1>docs</h1>")},
+		"img/logo.svg":    {Data: []byte("<svg/>")},
+	}
+
+	p := New()
+	p.StaticFS("/assets", fsys)
+	p.Group("/browse").StaticFS("/", fsys, StaticOptions{Browse: true})
+	p.StaticFS("/custom", fsys, StaticOptions{NotFound: func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}})
+
+	public := p.Group("/public")
+	public.Register404(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte("no such asset"))
+	})
+	public.StaticFS("/", fsys)
+
+	code, body := request(http.MethodGet, "/assets/app.css", p)
+	Equal(t, code, http.StatusOK)
+	Equal(t, body, "body{}")
+
+	r, _ := http.NewRequest(http.MethodGet, "/assets/app.css", nil)
+	w := httptest.NewRecorder()
+	p.Serve().ServeHTTP(w, r)
+	Equal(t, w.Header().Get(contentTypeHeader), "text/css; charset=utf-8")
+
+	code, body = request(http.MethodGet, "/assets/docs/", p)
+	Equal(t, code, http.StatusOK)
+	Equal(t, body, "<h1>docs</h1>")
+
+	code, _ = request(http.MethodGet, "/assets/docs", p)
+	Equal(t, code, http.StatusMovedPermanently)
+
+	code, _ = request(http.MethodHead, "/assets/app.css", p)
+	Equal(t, code, http.StatusOK)
+
+	code, _ = request(http.MethodGet, "/assets/missing.js", p)
+	Equal(t, code, http.StatusNotFound)
+
+	code, _ = request(http.MethodGet, "/assets/../static.go", p)
+	Equal(t, code, http.StatusNotFound)
+
+	// no listing by default
+	code, _ = request(http.MethodGet, "/assets/img/", p)
+	Equal(t, code, http.StatusNotFound)
+
+	code, body = request(http.MethodGet, "/browse/img/", p)
+	Equal(t, code, http.StatusOK)
+	Equal(t, body, "<!doctype html>\n<pre>\n<a href=\"logo.svg\">logo.svg</a>\n</pre>\n")
+
+	code, _ = request(http.MethodGet, "/custom/missing.js", p)
+	Equal(t, code, http.StatusTeapot)
+
+	// the 404 handler of the group applies to missing files
+	code, body = request(http.MethodGet, "/public/missing.js", p)
+	Equal(t, code, http.StatusNotFound)
+	Equal(t, body, "no such asset")
+
+	code, body = request(http.MethodGet, "/public/app.css", p)
+	Equal(t, code, http.StatusOK)
+	Equal(t, body, "body{}")
+}
+
+func TestStaticManifest(t *testing.T) {
+	fsys := fstest.MapFS{
+		".vite/manifest.json": {Data: []byte(`{
+			"src/main.ts": {"file": "assets/main-4889e940.js", "css": ["assets/main-b82dbe22.css"], "isEntry": true},
+			"src/logo.svg": {"file": "assets/logo-1a2b3c.svg"}
+		}`)},
+		"assets/main-4889e940.js":  {Data: []byte("main()")},
+		"assets/main-b82dbe22.css": {Data: []byte("body{}")},
+		"favicon.ico":              {Data: []byte("ico")},
+	}
+
+	m, err := LoadManifest(fsys, ".vite/manifest.json", "/static/")
+	Equal(t, err, nil)
+
+	u, err := m.URL("src/main.ts")
+	Equal(t, err, nil)
+	Equal(t, u, "/static/assets/main-4889e940.js")
+	Equal(t, m.CSS("src/main.ts"), []string{"/static/assets/main-b82dbe22.css"})
+	Equal(t, m.CSS("src/logo.svg"), []string{})
+
+	_, err = m.URL("src/missing.ts")
+	Equal(t, err.Error(), "feather: 'src/missing.ts' not found in manifest")
+
+	p := New()
+	p.StaticFS("/static", fsys, StaticOptions{Manifest: m})
+
+	r, _ := http.NewRequest(http.MethodGet, "/static/assets/main-4889e940.js", nil)
+	w := httptest.NewRecorder()
+	p.Serve().ServeHTTP(w, r)
+	Equal(t, w.Code, http.StatusOK)
+	Equal(t, w.Header().Get(cacheControlHeader), immutableCacheControl)
+
+	r, _ = http.NewRequest(http.MethodGet, "/static/favicon.ico", nil)
+	w = httptest.NewRecorder()
+	p.Serve().ServeHTTP(w, r)
+	Equal(t, w.Code, http.StatusOK)
+	Equal(t, w.Header().Get(cacheControlHeader), "")
+
+	var b strings.Builder
+	tmpl := template.Must(template.New("").Funcs(m.TemplateFuncs()).Parse(`<script src="{{ asset "src/main.ts" }}"></script>{{ range assetCSS "src/main.ts" }}<link href="{{ . }}">{{ end }}`))
+	err = tmpl.Execute(&b, nil)
+	Equal(t, err, nil)
+	Equal(t, b.String(), `<script src="/static/assets/main-4889e940.js"></script><link href="/static/assets/main-b82dbe22.css">`)
+
+	err = template.Must(template.New("").Funcs(m.TemplateFuncs()).Parse(`{{ asset "missing" }}`)).Execute(&b, nil)
+	NotEqual(t, err, nil)
+}
+
+func TestStaticWebpackManifest(t *testing.T) {
+	fsys := fstest.MapFS{
+		"manifest.json":    {Data: []byte(`{"main.js": "/assets/main.abc123.js", "vendor.js": "vendor.def456.js", "cdn.js": "https://cdn.example.com/cdn.js"}`)},
+		"main.abc123.js":   {Data: []byte("main()")},
+		"vendor.def456.js": {Data: []byte("vendor()")},
+	}
+
+	m, err := LoadManifest(fsys, "manifest.json", "/assets")
+	Equal(t, err, nil)
+
+	u, _ := m.URL("main.js")
+	Equal(t, u, "/assets/main.abc123.js")
+	u, _ = m.URL("vendor.js")
+	Equal(t, u, "/assets/vendor.def456.js")
+	u, _ = m.URL("cdn.js")
+	Equal(t, u, "https://cdn.example.com/cdn.js")
+	Equal(t, m.fingerprinted("main.abc123.js"), true)
+	Equal(t, m.fingerprinted("vendor.def456.js"), true)
+
+	_, err = LoadManifest(fsys, "missing.json", "/assets")
+	NotEqual(t, err, nil)
+}
+
+func TestFile(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "report.txt")
+	Equal(t, os.WriteFile(name, []byte("0123456789"), 0o600), nil)
+	modTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	Equal(t, os.Chtimes(name, modTime, modTime), nil)
+	fsys := fstest.MapFS{
+		"report.txt": {Data: []byte("0123456789"), ModTime: modTime},
+		"docs":       {Mode: fs.ModeDir},
+	}
+
+	p := New()
+	p.Get("/file", func(w http.ResponseWriter, r *http.Request) {
+		Equal(t, File(w, r, name), nil)
+	})
+	p.Get("/fs/*", func(w http.ResponseWriter, r *http.Request) {
+		if err := FileFS(w, r, fsys, RequestVars(r).Wildcard()); errors.Is(err, fs.ErrNotExist) {
+			w.WriteHeader(http.StatusNotFound)
+		} else if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	})
+
+	for _, path := range []string{"/file", "/fs/report.txt"} {
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		w := httptest.NewRecorder()
+		p.Serve().ServeHTTP(w, r)
+		Equal(t, w.Code, http.StatusOK)
+		Equal(t, w.Body.String(), "0123456789")
+		Equal(t, w.Header().Get(contentTypeHeader), "text/plain; charset=utf-8")
+		Equal(t, w.Header().Get("Accept-Ranges"), "bytes")
+		etag := w.Header().Get(etagHeader)
+		Equal(t, etag, `"`+strconv.FormatInt(modTime.UnixNano(), 36)+`-a"`)
+
+		// resumed downloads
+		r = httptest.NewRequest(http.MethodGet, path, nil)
+		r.Header.Set("Range", "bytes=4-")
+		r.Header.Set("If-Range", etag)
+		w = httptest.NewRecorder()
+		p.Serve().ServeHTTP(w, r)
+		Equal(t, w.Code, http.StatusPartialContent)
+		Equal(t, w.Body.String(), "456789")
+		Equal(t, w.Header().Get("Content-Range"), "bytes 4-9/10")
+
+		r = httptest.NewRequest(http.MethodGet, path, nil)
+		r.Header.Set(ifNoneMatchHeader, etag)
+		w = httptest.NewRecorder()
+		p.Serve().ServeHTTP(w, r)
+		Equal(t, w.Code, http.StatusNotModified)
+
+		r = httptest.NewRequest(http.MethodGet, path, nil)
+		r.Header.Set("If-Modified-Since", modTime.Format(http.TimeFormat))
+		w = httptest.NewRecorder()
+		p.Serve().ServeHTTP(w, r)
+		Equal(t, w.Code, http.StatusNotModified)
+	}
+
+	code, _ := request(http.MethodGet, "/fs/missing.txt", p)
+	Equal(t, code, http.StatusNotFound)
+
+	code, _ = request(http.MethodGet, "/fs/docs", p)
+	Equal(t, code, http.StatusInternalServerError)
+}
+
+func TestFileETagWithoutModTime(t *testing.T) {
+	serve := func(fsys fs.FS, ifNoneMatch string) *httptest.ResponseRecorder {
+		r, _ := http.NewRequest(http.MethodGet, "/app.js", nil)
+		if ifNoneMatch != blank {
+			r.Header.Set(ifNoneMatchHeader, ifNoneMatch)
+		}
+		w := httptest.NewRecorder()
+		Equal(t, FileFS(w, r, fsys, "app.js"), nil)
+		return w
+	}
+
+	// like an embed.FS, the files have no modification time
+	v1 := fstest.MapFS{"app.js": {Data: []byte("let v = 1")}}
+	v2 := fstest.MapFS{"app.js": {Data: []byte("let v = 2")}}
+
+	w := serve(v1, blank)
+	Equal(t, w.Code, http.StatusOK)
+	etag := w.Header().Get(etagHeader)
+	NotEqual(t, etag, blank)
+
+	w = serve(v1, etag)
+	Equal(t, w.Code, http.StatusNotModified)
+
+	// the redeployed file of the same size isn't answered with 304 Not Modified
+	w = serve(v2, etag)
+	Equal(t, w.Code, http.StatusOK)
+	Equal(t, w.Body.String(), "let v = 2")
+	NotEqual(t, w.Header().Get(etagHeader), etag)
+}
@@ -0,0 +1,137 @@
+package feather
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// SetRouteSuggestions enables the "did you mean" mode when max > 0.
+// When a route is not found, up to max of the nearest registered routes,
+// by edit distance to the request path, are made available to the 404 handler via RouteSuggestions.
+// This walks every tree of the request's host on each 404 and is intended for debugging and development.
+func (p *Mux) SetRouteSuggestions(max int) {
+	p.maxSuggestions = max
+}
+
+// RouteSuggestions returns the registered route patterns nearest to the request path,
+// nearest first, when the route was not found and suggestions are enabled using SetRouteSuggestions.
+func RouteSuggestions(r *http.Request) []string {
+	if rv, ok := requestVarsOf(r); ok {
+		return rv.suggestions
+	}
+
+	return nil
+}
+
+type suggestion struct {
+	route    string
+	distance int
+}
+
+// suggest returns the route patterns of trees nearest to path,
+// trees being those of the request's host, see treesOf.
+// Distant routes, further than a quarter of the compared length, are not considered.
+func (p *Mux) suggest(trees map[string]*node, path string) []string {
+	seen := make(map[string]struct{})
+	var candidates []suggestion
+	for _, tree := range trees {
+		tree.walk(func(n *node) {
+			if _, ok := seen[n.route]; ok {
+				return
+			}
+
+			seen[n.route] = struct{}{}
+			a, b := distanceOperands(n.route, path)
+			if d := levenshtein(a, b); d <= max(2, len(b)/4) {
+				candidates = append(candidates, suggestion{route: n.route, distance: d})
+			}
+		})
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].distance == candidates[j].distance {
+			return candidates[i].route < candidates[j].route
+		}
+		return candidates[i].distance < candidates[j].distance
+	})
+
+	if len(candidates) > p.maxSuggestions {
+		candidates = candidates[:p.maxSuggestions]
+	}
+
+	routes := make([]string, len(candidates))
+	for i := range candidates {
+		routes[i] = candidates[i].route
+	}
+
+	return routes
+}
+
+// walk calls fn for every node with a handler.
+func (n *node) walk(fn func(n *node)) {
+	if n.handler != nil {
+		fn(n)
+	}
+
+	for _, c := range n.children {
+		c.walk(fn)
+	}
+
+	if n.paramChild != nil {
+		n.paramChild.walk(fn)
+	}
+
+	if n.catchAll != nil {
+		n.catchAll.walk(fn)
+	}
+}
+
+// distanceOperands returns the route and path to compute the edit distance of.
+// The route's param segments are replaced with the corresponding path segments
+// so they do not count towards the distance and for catch-all routes only
+// the segments before the catch-all are compared.
+func distanceOperands(route string, path string) (string, string) {
+	if strings.IndexByte(route, paramByte) == -1 && strings.IndexByte(route, wildByte) == -1 {
+		return route, path
+	}
+
+	rs := strings.Split(route, basePath)
+	ps := strings.Split(path, basePath)
+	for i := range rs {
+		if i >= len(ps) || len(rs[i]) == 0 {
+			continue
+		}
+
+		switch {
+		case rs[i][0] == wildByte:
+			return strings.Join(rs[:i], basePath), strings.Join(ps[:i], basePath)
+		case strings.IndexByte(rs[i], paramByte) != -1:
+			rs[i] = ps[i]
+		}
+	}
+
+	return strings.Join(rs, basePath), path
+}
+
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+
+	return prev[len(b)]
+}
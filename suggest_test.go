@@ -0,0 +1,69 @@
+package feather
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/pchchv/feather/assert"
+)
+
+func TestRouteSuggestions(t *testing.T) {
+	p := New()
+	p.Get("/users/:id/profile", defaultHandler)
+	p.Get("/users", defaultHandler)
+	p.Post("/user/new", defaultHandler)
+	p.Get("/assets/*", defaultHandler)
+	p.Get("/completely/different/route", defaultHandler)
+
+	code, body := request(http.MethodGet, "/users/13/profil", p)
+	Equal(t, code, http.StatusNotFound)
+	Equal(t, body, "Not Found\n")
+
+	p.SetRouteSuggestions(2)
+	code, body = request(http.MethodGet, "/users/13/profil", p)
+	Equal(t, code, http.StatusNotFound)
+	Equal(t, body, "Not Found\n\nDid you mean?\n  /users/:id/profile\n")
+
+	code, body = request(http.MethodGet, "/usr", p)
+	Equal(t, code, http.StatusNotFound)
+	Equal(t, body, "Not Found\n\nDid you mean?\n  /users\n")
+
+	var suggestions []string
+	p.Register404(func(w http.ResponseWriter, r *http.Request) {
+		suggestions = RouteSuggestions(r)
+	})
+	request(http.MethodGet, "/asset/css/main.css", p)
+	Equal(t, suggestions, []string{"/assets/*"})
+
+	request(http.MethodGet, "/nothing/like/anything/registered", p)
+	Equal(t, len(suggestions), 0)
+}
+
+func TestRouteSuggestionsHost(t *testing.T) {
+	p := New()
+	p.SetRouteSuggestions(2)
+	p.Get("/users", defaultHandler)
+	p.Host("api.example.com").Get("/orders/:id", defaultHandler)
+
+	// the routes of the host are suggested for requests to it
+	r, _ := http.NewRequest(http.MethodGet, "http://api.example.com/order/13", nil)
+	w := httptest.NewRecorder()
+	p.Serve().ServeHTTP(w, r)
+	Equal(t, w.Code, http.StatusNotFound)
+	Equal(t, w.Body.String(), "Not Found\n\nDid you mean?\n  /orders/:id\n")
+
+	r, _ = http.NewRequest(http.MethodGet, "http://api.example.com/user", nil)
+	w = httptest.NewRecorder()
+	p.Serve().ServeHTTP(w, r)
+	Equal(t, w.Body.String(), "Not Found\n")
+
+	// and the routes registered without a host for other hosts
+	code, body := request(http.MethodGet, "/user", p)
+	Equal(t, code, http.StatusNotFound)
+	Equal(t, body, "Not Found\n\nDid you mean?\n  /users\n")
+
+	code, body = request(http.MethodGet, "/order/13", p)
+	Equal(t, code, http.StatusNotFound)
+	Equal(t, body, "Not Found\n")
+}
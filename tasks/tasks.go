@@ -0,0 +1,258 @@
+package tasks
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pchchv/feather"
+)
+
+const (
+	contentDispositionHeader = "Content-Disposition"
+	contentTypeHeader        = "Content-Type"
+	locationHeader           = "Location"
+	retryAfterHeader         = "Retry-After"
+	idParam                  = "id"
+)
+
+// Status is the state of a job.
+type Status string
+
+const (
+	// Pending jobs are still being generated.
+	Pending Status = "pending"
+	// Done jobs completed successfully and their result can be fetched.
+	Done Status = "done"
+	// Failed jobs completed with an error.
+	Failed Status = "failed"
+)
+
+// Result is the payload produced by a job.
+type Result struct {
+	ContentType string
+	// Filename, when set, is sent as an attachment Content-Disposition.
+	Filename string
+	Body     []byte
+}
+
+// Func generates a job's result.
+// The context is not tied to the request that submitted the job.
+type Func func(ctx context.Context) (Result, error)
+
+// Job is a snapshot of a job's state.
+type Job struct {
+	ID       string    `json:"id"`
+	Status   Status    `json:"status"`
+	Error    string    `json:"error,omitempty"` // message of the HTTPError failing the job, a generic one for other errors
+	Finished time.Time `json:"-"`
+	result   Result
+	done     chan struct{}
+}
+
+// Store keeps track of submitted jobs in memory.
+type Store struct {
+	prefix     string
+	ttl        time.Duration
+	retryAfter int
+	logger     *slog.Logger
+	m          sync.RWMutex
+	jobs       map[string]*Job
+}
+
+// New creates a new job Store.
+// prefix is the path the status handler is mounted at, i.e. the Location
+// returned for job 'abc' will be prefix + "/abc".
+// Finished jobs are discarded after ttl.
+func New(prefix string, ttl time.Duration) *Store {
+	return &Store{
+		prefix:     prefix,
+		ttl:        ttl,
+		retryAfter: 1,
+		logger:     slog.Default(),
+		jobs:       make(map[string]*Job),
+	}
+}
+
+// SetRetryAfter sets the number of seconds clients are told to wait between status polls. Default 1.
+func (s *Store) SetRetryAfter(seconds int) {
+	s.retryAfter = seconds
+}
+
+// SetLogger sets the logger of the errors and panics of the jobs, by default slog.Default() is used.
+func (s *Store) SetLogger(l *slog.Logger) {
+	s.logger = l
+}
+
+// Submit starts fn in the background and returns the new job's id.
+func (s *Store) Submit(fn Func) string {
+	return s.submit(fn).ID
+}
+
+// submit starts fn in the background and returns the new job,
+// its fields other than ID and done are guarded by the lock of the Store.
+func (s *Store) submit(fn Func) *Job {
+	j := &Job{
+		ID:     newID(),
+		Status: Pending,
+		done:   make(chan struct{}),
+	}
+
+	s.m.Lock()
+	s.evict()
+	s.jobs[j.ID] = j
+	s.m.Unlock()
+
+	go func() {
+		res, err := s.run(fn)
+		s.m.Lock()
+		if err != nil {
+			j.Status = Failed
+			j.Error = s.publicError(j.ID, err)
+		} else {
+			j.Status = Done
+			j.result = res
+		}
+		j.Finished = time.Now()
+		s.m.Unlock()
+		close(j.done)
+	}()
+
+	return j
+}
+
+// run calls fn, a panic is logged and fails the job, since no recovery middleware runs the jobs.
+func (s *Store) run(fn Func) (res Result, err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			s.logger.Error("tasks: job panicked", slog.Any("panic", v), slog.String("stack", string(debug.Stack())))
+			err = fmt.Errorf("tasks: job panicked: %v", v)
+		}
+	}()
+
+	return fn(context.Background())
+}
+
+// publicError logs err and returns the message sent to clients,
+// the message of HTTPErrors or a generic one, so internal details aren't leaked.
+func (s *Store) publicError(id string, err error) string {
+	s.logger.Error("tasks: job failed", slog.String("id", id), slog.Any("error", err))
+	var he *feather.HTTPError
+	if errors.As(err, &he) {
+		return he.Message
+	}
+
+	return http.StatusText(http.StatusInternalServerError)
+}
+
+// Get returns a snapshot of the job with the given id.
+func (s *Store) Get(id string) (job Job, found bool) {
+	s.m.RLock()
+	defer s.m.RUnlock()
+	j, found := s.jobs[id]
+	if found {
+		job = *j
+	}
+
+	return
+}
+
+// Handler returns the status polling handler,
+// which is expected to be registered at prefix + "/:id".
+//
+// Pending jobs answer 202 with a Retry-After header, failed jobs 500 and
+// both with a JSON description of the job. Finished jobs are answered with their result.
+func (s *Store) Handler(w http.ResponseWriter, r *http.Request) {
+	job, found := s.Get(feather.RequestVars(r).URLParam(idParam))
+	if !found {
+		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
+	}
+
+	switch job.Status {
+	case Pending:
+		w.Header().Set(retryAfterHeader, strconv.Itoa(s.retryAfter))
+		_ = feather.JSON(w, http.StatusAccepted, job)
+	case Failed:
+		_ = feather.JSON(w, http.StatusInternalServerError, job)
+	default:
+		writeResult(w, job.result)
+	}
+}
+
+// AsyncResult answers the request with 202 Accepted and a Location header
+// pointing to the status handler for the job.
+func (s *Store) AsyncResult(w http.ResponseWriter, r *http.Request, jobID string) {
+	w.Header().Set(locationHeader, s.prefix+"/"+jobID)
+	w.Header().Set(retryAfterHeader, strconv.Itoa(s.retryAfter))
+	_ = feather.JSON(w, http.StatusAccepted, Job{ID: jobID, Status: Pending})
+}
+
+// Do submits fn and waits up to wait for it to complete.
+// If it completes in time the result is written directly,
+// otherwise the response falls back to AsyncResult.
+func (s *Store) Do(w http.ResponseWriter, r *http.Request, wait time.Duration, fn Func) {
+	// the job is used rather than looked up by id, since jobs submitted concurrently can evict it
+	j := s.submit(fn)
+	t := time.NewTimer(wait)
+	defer t.Stop()
+	select {
+	case <-j.done:
+	case <-t.C:
+		s.AsyncResult(w, r, j.ID)
+		return
+	case <-r.Context().Done():
+		return
+	}
+
+	s.m.RLock()
+	job := *j
+	s.m.RUnlock()
+	if job.Status == Failed {
+		_ = feather.JSON(w, http.StatusInternalServerError, job)
+		return
+	}
+
+	writeResult(w, job.result)
+}
+
+// evict removes expired jobs, the lock must be held by the caller.
+func (s *Store) evict() {
+	if s.ttl <= 0 {
+		return
+	}
+
+	now := time.Now()
+	for id, j := range s.jobs {
+		if !j.Finished.IsZero() && now.Sub(j.Finished) > s.ttl {
+			delete(s.jobs, id)
+		}
+	}
+}
+
+func writeResult(w http.ResponseWriter, res Result) {
+	if res.Filename != "" {
+		w.Header().Set(contentDispositionHeader, feather.ContentDisposition("attachment", res.Filename))
+	}
+
+	if res.ContentType != "" {
+		w.Header().Set(contentTypeHeader, res.ContentType)
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(res.Body)
+}
+
+func newID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
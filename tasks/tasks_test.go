@@ -0,0 +1,129 @@
+package tasks
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pchchv/feather"
+	. "github.com/pchchv/feather/assert"
+)
+
+func TestAsyncResult(t *testing.T) {
+	release := make(chan struct{})
+	s := New("/jobs", time.Minute)
+	p := feather.New()
+	p.Get("/jobs/:id", s.Handler)
+	p.Post("/report", func(w http.ResponseWriter, r *http.Request) {
+		s.Do(w, r, 10*time.Millisecond, func(ctx context.Context) (Result, error) {
+			<-release
+			return Result{ContentType: "application/pdf", Filename: "report.pdf", Body: []byte("%PDF")}, nil
+		})
+	})
+	hf := p.Serve()
+
+	r, _ := http.NewRequest(http.MethodPost, "/report", nil)
+	w := httptest.NewRecorder()
+	hf.ServeHTTP(w, r)
+	Equal(t, w.Code, http.StatusAccepted)
+	Equal(t, w.Header().Get(retryAfterHeader), "1")
+	location := w.Header().Get(locationHeader)
+	NotEqual(t, location, "")
+
+	r, _ = http.NewRequest(http.MethodGet, location, nil)
+	w = httptest.NewRecorder()
+	hf.ServeHTTP(w, r)
+	Equal(t, w.Code, http.StatusAccepted)
+
+	close(release)
+	for i := 0; i < 100; i++ {
+		if job, _ := s.Get(location[len("/jobs/"):]); job.Status == Done {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	r, _ = http.NewRequest(http.MethodGet, location, nil)
+	w = httptest.NewRecorder()
+	hf.ServeHTTP(w, r)
+	Equal(t, w.Code, http.StatusOK)
+	Equal(t, w.Header().Get(contentTypeHeader), "application/pdf")
+	Equal(t, w.Header().Get(contentDispositionHeader), `attachment; filename="report.pdf"`)
+	Equal(t, w.Body.String(), "%PDF")
+
+	r, _ = http.NewRequest(http.MethodGet, "/jobs/unknown", nil)
+	w = httptest.NewRecorder()
+	hf.ServeHTTP(w, r)
+	Equal(t, w.Code, http.StatusNotFound)
+}
+
+func TestDoInline(t *testing.T) {
+	s := New("/jobs", 0)
+	r, _ := http.NewRequest(http.MethodPost, "/report", nil)
+	w := httptest.NewRecorder()
+	s.Do(w, r, time.Second, func(ctx context.Context) (Result, error) {
+		return Result{ContentType: "text/plain", Body: []byte("fast")}, nil
+	})
+	Equal(t, w.Code, http.StatusOK)
+	Equal(t, w.Body.String(), "fast")
+
+	// the filename is quoted, so it can't inject parameters or headers
+	w = httptest.NewRecorder()
+	s.Do(w, r, time.Second, func(ctx context.Context) (Result, error) {
+		return Result{Filename: "naïve\";\r\nx.pdf", Body: []byte("%PDF")}, nil
+	})
+	Equal(t, w.Header().Get(contentDispositionHeader), `attachment; filename="na_ve\";__x.pdf"; filename*=UTF-8''na%C3%AFve%22%3B%0D%0Ax.pdf`)
+
+	w = httptest.NewRecorder()
+	s.Do(w, r, time.Second, func(ctx context.Context) (Result, error) {
+		return Result{}, errors.New("boom")
+	})
+	Equal(t, w.Code, http.StatusInternalServerError)
+}
+
+func TestJobError(t *testing.T) {
+	var buf bytes.Buffer
+	s := New("/jobs", 0)
+	s.SetLogger(slog.New(slog.NewTextHandler(&buf, nil)))
+	r, _ := http.NewRequest(http.MethodPost, "/report", nil)
+
+	// internal errors are logged, but not sent to the client
+	w := httptest.NewRecorder()
+	s.Do(w, r, time.Second, func(ctx context.Context) (Result, error) {
+		return Result{}, errors.New("pq: relation \"reports\" does not exist")
+	})
+	Equal(t, w.Code, http.StatusInternalServerError)
+	Equal(t, strings.Contains(w.Body.String(), "reports"), false)
+	Equal(t, strings.Contains(w.Body.String(), `"error":"Internal Server Error"`), true)
+	Equal(t, strings.Contains(buf.String(), "reports"), true)
+
+	// the message of HTTPErrors is public
+	w = httptest.NewRecorder()
+	s.Do(w, r, time.Second, func(ctx context.Context) (Result, error) {
+		return Result{}, feather.NewHTTPError(http.StatusBadRequest, "unknown report").WithInternal(errors.New("no row 13"))
+	})
+	Equal(t, strings.Contains(w.Body.String(), `"error":"unknown report"`), true)
+	Equal(t, strings.Contains(w.Body.String(), "no row"), false)
+}
+
+func TestJobPanic(t *testing.T) {
+	var buf bytes.Buffer
+	s := New("/jobs", 0)
+	s.SetLogger(slog.New(slog.NewTextHandler(&buf, nil)))
+	r, _ := http.NewRequest(http.MethodPost, "/report", nil)
+	w := httptest.NewRecorder()
+	s.Do(w, r, time.Second, func(ctx context.Context) (Result, error) {
+		var m map[string]int
+		m["boom"]++
+		return Result{}, nil
+	})
+	Equal(t, w.Code, http.StatusInternalServerError)
+	Equal(t, strings.Contains(buf.String(), "tasks: job panicked"), true)
+	Equal(t, strings.Contains(buf.String(), "assignment to entry in nil map"), true)
+}
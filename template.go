@@ -0,0 +1,134 @@
+package feather
+
+import (
+	"bytes"
+	"encoding/hex"
+	"hash/fnv"
+	"html/template"
+	"net/http"
+	"strings"
+)
+
+const (
+	// CSRFTokenKey is the ReqVars key under which CSRF middleware stores the token of the request,
+	// read by the csrfToken and csrfField template funcs.
+	CSRFTokenKey = "feather.csrf_token"
+	// CSRFFieldName is the name of the hidden input rendered by the csrfField template func.
+	CSRFFieldName = "csrf_token"
+
+	textHTML          = textHTMLNoCharset + charsetUTF8
+	textHTMLNoCharset = "text/html"
+	etagHeader        = "ETag"
+	ifNoneMatchHeader = "If-None-Match"
+)
+
+// TemplateFuncs returns the template funcs for rendering HTML forms in the request, i.e.
+//
+//	t, _ := layout.Clone()
+//	err := t.Funcs(feather.TemplateFuncs(r)).Execute(w, data)
+//
+// The funcs are:
+//
+//	csrfToken                          the CSRF token stored under CSRFTokenKey, blank if none
+//	csrfField                          a hidden input named CSRFFieldName containing the CSRF token
+//	fieldValue .Form "email"           the submitted value of the field, see Form
+//	fieldError .Form "email"           the error message of the field
+//	errorClass .Form "email" "invalid" the class if the field has an error, blank otherwise
+//
+// The form funcs accept a nil *Form, so the same template renders the initial empty form.
+// r may be nil when the funcs are only needed to parse the templates.
+func TemplateFuncs(r *http.Request) template.FuncMap {
+	csrfToken := func() string {
+		if r == nil {
+			return blank
+		}
+
+		token, _ := RequestVars(r).Get(CSRFTokenKey).(string)
+		return token
+	}
+
+	return template.FuncMap{
+		"csrfToken": csrfToken,
+		"csrfField": func() template.HTML {
+			return template.HTML(`<input type="hidden" name="` + CSRFFieldName + `" value="` + template.HTMLEscapeString(csrfToken()) + `">`)
+		},
+		"fieldValue": func(f *Form, field string) string {
+			if f == nil {
+				return blank
+			}
+
+			return f.Value(field)
+		},
+		"fieldError": func(f *Form, field string) string {
+			if f == nil {
+				return blank
+			}
+
+			return f.Error(field)
+		},
+		"errorClass": func(f *Form, field string, class string) string {
+			if f == nil || !f.HasError(field) {
+				return blank
+			}
+
+			return class
+		},
+	}
+}
+
+// HTML executes the template, or its template with the name if not blank, and writes the output using the status.
+// The template is executed before the status is written, so execution errors are returned
+// and can still be answered with an error response.
+//
+// When enabled using SetHTMLETags, successful GET and HEAD responses get a weak ETag of the output
+// and requests whose If-None-Match lists it are answered with 304 Not Modified.
+func HTML(w http.ResponseWriter, r *http.Request, status int, t *template.Template, name string, data interface{}) (err error) {
+	var buf bytes.Buffer
+	if name == blank {
+		err = t.Execute(&buf, data)
+	} else {
+		err = t.ExecuteTemplate(&buf, name, data)
+	}
+
+	if err != nil {
+		return
+	}
+
+	size := int64(buf.Cap())
+	if err = allocate(r, size, http.StatusInsufficientStorage); err != nil {
+		return
+	}
+	defer Free(r, size)
+
+	h := w.Header()
+	h.Set(contentTypeHeader, textHTML)
+	if rv, ok := requestVarsOf(r); ok && rv.mux.htmlETags && status == http.StatusOK &&
+		(r.Method == http.MethodGet || r.Method == http.MethodHead) {
+		hash := fnv.New64a()
+		hash.Write(buf.Bytes())
+		etag := `W/"` + hex.EncodeToString(hash.Sum(nil)) + `"`
+		h.Set(etagHeader, etag)
+		if etagMatch(r.Header.Get(ifNoneMatchHeader), etag) {
+			h.Del(contentTypeHeader)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+
+	w.WriteHeader(status)
+	_, err = w.Write(buf.Bytes())
+	return
+}
+
+// etagMatch reports whether the If-None-Match header lists the ETag using the weak comparison.
+func etagMatch(ifNoneMatch string, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, tag := range strings.Split(ifNoneMatch, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == etag {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,98 @@
+package feather
+
+import (
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	. "github.com/pchchv/feather/assert"
+)
+
+func TestTemplateFuncs(t *testing.T) {
+	tmpl := template.Must(template.New("form").Funcs(TemplateFuncs(nil)).Parse(
+		`<form>{{ csrfField }}<input name="age" class="{{ errorClass .Form "age" "invalid" }}" value="{{ fieldValue .Form "age" }}">{{ fieldError .Form "age" }}</form>`,
+	))
+
+	p := New()
+	p.Use(func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			RequestVars(r).Set(CSRFTokenKey, `to"ken`)
+			next(w, r)
+		}
+	})
+	p.Get("/", func(w http.ResponseWriter, r *http.Request) {
+		var f *Form
+		if r.URL.Query().Has("age") {
+			f = &Form{Values: url.Values{"age": {r.URL.Query().Get("age")}}}
+			f.AddError("age", "must be a whole number")
+		}
+
+		t, _ := tmpl.Clone()
+		if err := t.Funcs(TemplateFuncs(r)).Execute(w, map[string]interface{}{"Form": f}); err != nil {
+			panic(err)
+		}
+	})
+
+	code, body := request(http.MethodGet, "/", p)
+	Equal(t, code, http.StatusOK)
+	Equal(t, body, `<form><input type="hidden" name="csrf_token" value="to&#34;ken"><input name="age" class="" value=""></form>`)
+
+	code, body = request(http.MethodGet, "/?age="+url.QueryEscape("<old>"), p)
+	Equal(t, code, http.StatusOK)
+	Equal(t, strings.Contains(body, `<input name="age" class="invalid" value="&lt;old&gt;">must be a whole number</form>`), true)
+}
+
+func TestHTML(t *testing.T) {
+	tmpl := template.Must(template.New("page").Parse(`<h1>{{ . }}</h1>{{ define "title" }}<title>{{ . }}</title>{{ end }}`))
+	page := "joeybloggs"
+	p := New()
+	p.Get("/", func(w http.ResponseWriter, r *http.Request) {
+		_ = HTML(w, r, http.StatusOK, tmpl, blank, page)
+	})
+	p.Get("/title", func(w http.ResponseWriter, r *http.Request) {
+		_ = HTML(w, r, http.StatusOK, tmpl, "title", page)
+	})
+	p.Get("/missing", func(w http.ResponseWriter, r *http.Request) {
+		Equal(t, HTML(w, r, http.StatusOK, tmpl, "missing", page) != nil, true)
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	h := p.Serve()
+
+	do := func(path, ifNoneMatch string) *httptest.ResponseRecorder {
+		r, _ := http.NewRequest(http.MethodGet, path, nil)
+		if ifNoneMatch != blank {
+			r.Header.Set(ifNoneMatchHeader, ifNoneMatch)
+		}
+
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	w := do("/", blank)
+	Equal(t, w.Code, http.StatusOK)
+	Equal(t, w.Header().Get(contentTypeHeader), textHTML)
+	Equal(t, w.Body.String(), "<h1>joeybloggs</h1>")
+	Equal(t, w.Header().Get(etagHeader), blank)
+
+	Equal(t, do("/title", blank).Body.String(), "<title>joeybloggs</title>")
+	Equal(t, do("/missing", blank).Code, http.StatusInternalServerError)
+
+	p.SetHTMLETags(true)
+	w = do("/", blank)
+	etag := w.Header().Get(etagHeader)
+	Equal(t, strings.HasPrefix(etag, `W/"`), true)
+
+	w = do("/", `"other", `+strings.TrimPrefix(etag, "W/"))
+	Equal(t, w.Code, http.StatusNotModified)
+	Equal(t, w.Body.Len(), 0)
+	Equal(t, w.Header().Get(etagHeader), etag)
+
+	page = "changed"
+	w = do("/", etag)
+	Equal(t, w.Code, http.StatusOK)
+	NotEqual(t, w.Header().Get(etagHeader), etag)
+}
@@ -0,0 +1,128 @@
+package feather
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	serverTimingHeader = "Server-Timing"
+	serverTimingKey    = "feather.server_timing"
+	totalMetric        = "total"
+)
+
+// ServerTiming records the phases of a request, sent in the Server-Timing header
+// so browsers and APMs show the server side breakdown of the request, see SetServerTiming.
+// The methods of a nil ServerTiming do nothing, so handlers time their phases unconditionally.
+type ServerTiming struct {
+	mu      sync.Mutex
+	start   time.Time
+	metrics []*TimingMetric
+}
+
+// TimingMetric is a phase of a request, see ServerTiming.Start.
+type TimingMetric struct {
+	t     *ServerTiming
+	name  string
+	start time.Time
+	dur   time.Duration
+	done  bool
+}
+
+// SetServerTiming enables the Server-Timing header of the routed requests, listing the phases recorded
+// using Timing(r) and the total time until the header was written, i.e. Server-Timing: db;dur=12.4, total;dur=15.1.
+// Exposing the timings can reveal information about the backend, enable it for trusted clients only or in development.
+// Default is false.
+func (p *Mux) SetServerTiming(enable bool) {
+	p.serverTiming = enable
+}
+
+// Timing returns the ServerTiming of the request, nil if not enabled using SetServerTiming, i.e.
+//
+//	defer feather.Timing(r).Start("db").Stop()
+func Timing(r *http.Request) *ServerTiming {
+	rv, ok := requestVarsOf(r)
+	if !ok {
+		return nil
+	}
+
+	t, _ := rv.Get(serverTimingKey).(*ServerTiming)
+	return t
+}
+
+// startServerTiming stores a ServerTiming in the request variables, written before the header.
+func startServerTiming(rv *requestVars, rw ResponseWriter) {
+	t := &ServerTiming{start: time.Now()}
+	rv.Set(serverTimingKey, t)
+	rw.Before(func(rw ResponseWriter) {
+		rw.Header().Add(serverTimingHeader, t.header())
+	})
+}
+
+// Start starts timing the phase with the name, which must be an HTTP token, i.e. db or cache-lookup.
+// Phases not stopped when the header is written are not sent.
+func (t *ServerTiming) Start(name string) *TimingMetric {
+	if t == nil {
+		return nil
+	}
+
+	m := &TimingMetric{t: t, name: name, start: time.Now()}
+	t.mu.Lock()
+	t.metrics = append(t.metrics, m)
+	t.mu.Unlock()
+	return m
+}
+
+// Add records a phase with the name timed by the caller.
+func (t *ServerTiming) Add(name string, d time.Duration) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	t.metrics = append(t.metrics, &TimingMetric{t: t, name: name, dur: d, done: true})
+	t.mu.Unlock()
+}
+
+// Stop stops timing the phase, later calls are ignored.
+func (m *TimingMetric) Stop() {
+	if m == nil {
+		return
+	}
+
+	m.t.mu.Lock()
+	if !m.done {
+		m.dur = time.Since(m.start)
+		m.done = true
+	}
+	m.t.mu.Unlock()
+}
+
+// header returns the Server-Timing header value of the stopped phases and the total time.
+func (t *ServerTiming) header() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var b strings.Builder
+	for _, m := range t.metrics {
+		if m.done {
+			writeTimingMetric(&b, m.name, m.dur)
+		}
+	}
+	writeTimingMetric(&b, totalMetric, time.Since(t.start))
+	return b.String()
+}
+
+// writeTimingMetric writes the metric with its duration in milliseconds.
+func writeTimingMetric(b *strings.Builder, name string, d time.Duration) {
+	if b.Len() > 0 {
+		b.WriteString(", ")
+	}
+
+	b.WriteString(name)
+	b.WriteString(";dur=")
+	b.WriteString(strconv.FormatFloat(float64(d.Microseconds())/1000, 'f', -1, 64))
+}
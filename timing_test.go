@@ -0,0 +1,46 @@
+package feather
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	. "github.com/pchchv/feather/assert"
+)
+
+func TestServerTiming(t *testing.T) {
+	p := New()
+	p.Get("/users/:id", func(w http.ResponseWriter, r *http.Request) {
+		m := Timing(r).Start("db")
+		time.Sleep(2 * time.Millisecond)
+		m.Stop()
+		m.Stop()
+		Timing(r).Add("cache", 1500*time.Microsecond)
+		// not stopped before the header is written
+		_ = Timing(r).Start("render")
+		w.WriteHeader(http.StatusOK)
+	})
+
+	serve := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		p.Serve().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/1", nil))
+		return w
+	}
+
+	// disabled, Timing returns nil which is safe to use
+	w := serve()
+	Equal(t, w.Code, http.StatusOK)
+	Equal(t, w.Header().Get("Server-Timing"), "")
+
+	p.SetServerTiming(true)
+	w = serve()
+	header := w.Header().Get("Server-Timing")
+	Equal(t, regexp.MustCompile(`^db;dur=[0-9.]+, cache;dur=1.5, total;dur=[0-9.]+$`).MatchString(header), true)
+
+	var nilTiming *ServerTiming
+	nilTiming.Add("x", time.Second)
+	Equal(t, nilTiming.Start("x"), (*TimingMetric)(nil))
+	Equal(t, Timing(httptest.NewRequest(http.MethodGet, "/", nil)), (*ServerTiming)(nil))
+}
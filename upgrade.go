@@ -0,0 +1,180 @@
+package feather
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"time"
+)
+
+const (
+	// listenerFDEnv and readyFDEnv pass the file descriptors of the inherited listener
+	// and of the pipe signaling readiness to the upgraded process.
+	listenerFDEnv         = "FEATHER_LISTENER_FD"
+	readyFDEnv            = "FEATHER_READY_FD"
+	defaultUpgradeTimeout = 30 * time.Second
+)
+
+// upgradeArgs returns the arguments the upgraded process is started with.
+var upgradeArgs = func() []string {
+	return os.Args[1:]
+}
+
+// Upgrade performs a zero-downtime binary upgrade: it starts the executable again, usually replaced by the
+// new version beforehand, with the same arguments and environment, passing it the listener of the server.
+// Once the new process serves, the server shuts down gracefully, so deploys on bare VMs neither refuse
+// new connections nor drop in-flight requests.
+// If the new process exits or doesn't serve within UpgradeTimeout it is killed and the server keeps serving.
+//
+// The new process serves the inherited listener when it calls ListenAndServe or ListenAndServeTLS.
+// Upgrade is called when one of the UpgradeSignals is received and is not supported on Windows.
+func (s *Server) Upgrade() error {
+	s.upgradeMu.Lock()
+	defer s.upgradeMu.Unlock()
+
+	if s.listener == nil {
+		return errors.New("feather: upgrade requires a serving server")
+	}
+
+	fl, ok := s.listener.(interface{ File() (*os.File, error) })
+	if !ok {
+		return fmt.Errorf("feather: upgrade can't pass a %T listener", s.listener)
+	}
+
+	f, err := fl.File()
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	// ExtraFiles start at file descriptor 3
+	cmd := exec.Command(exe, upgradeArgs()...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	cmd.Env = append(os.Environ(), listenerFDEnv+"=3", readyFDEnv+"=4")
+	cmd.ExtraFiles = []*os.File{f, w}
+	err = cmd.Start()
+	_ = w.Close()
+	if err != nil {
+		return err
+	}
+
+	exited := make(chan error, 1)
+	go func() {
+		exited <- cmd.Wait()
+	}()
+
+	ready := make(chan bool, 1)
+	go func() {
+		var b [1]byte
+		n, _ := r.Read(b[:])
+		ready <- n == 1
+	}()
+
+	timeout := s.UpgradeTimeout
+	if timeout <= 0 {
+		timeout = defaultUpgradeTimeout
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case ok := <-ready:
+		if ok {
+			s.Stop()
+			return nil
+		}
+		err = errors.New("feather: upgraded process exited before serving")
+	case err = <-exited:
+		err = fmt.Errorf("feather: upgraded process exited before serving: %w", err)
+	case <-timer.C:
+		err = errors.New("feather: upgraded process not serving after " + timeout.String())
+	}
+
+	_ = cmd.Process.Kill()
+	return err
+}
+
+// listen returns the listener inherited from the parent process during an upgrade,
+// otherwise it listens on Addr, defaultAddr if blank.
+func (s *Server) listen(defaultAddr string) (net.Listener, error) {
+	if v := os.Getenv(listenerFDEnv); v != blank {
+		_ = os.Unsetenv(listenerFDEnv)
+		fd, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("feather: invalid %s: %w", listenerFDEnv, err)
+		}
+
+		// FileListener duplicates the file descriptor
+		f := os.NewFile(uintptr(fd), "listener")
+		defer f.Close()
+		return net.FileListener(f)
+	}
+
+	addr := s.Addr
+	if addr == blank {
+		addr = defaultAddr
+	}
+
+	return net.Listen("tcp", addr)
+}
+
+// notifyReady tells the parent process the upgrade succeeded, if the process was started by Upgrade.
+func notifyReady() {
+	v := os.Getenv(readyFDEnv)
+	if v == blank {
+		return
+	}
+
+	_ = os.Unsetenv(readyFDEnv)
+	if fd, err := strconv.Atoi(v); err == nil {
+		f := os.NewFile(uintptr(fd), "ready")
+		_, _ = f.Write([]byte{1})
+		_ = f.Close()
+	}
+}
+
+// watchUpgrades upgrades on the UpgradeSignals until done is closed,
+// the returned func waits for the watcher to stop.
+func (s *Server) watchUpgrades(done <-chan struct{}) func() {
+	if len(s.UpgradeSignals) == 0 {
+		return func() {}
+	}
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, s.UpgradeSignals...)
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		for {
+			select {
+			case <-sigs:
+				err := s.Upgrade()
+				if s.OnUpgradeComplete != nil {
+					s.OnUpgradeComplete(err)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		signal.Stop(sigs)
+		<-stopped
+	}
+}
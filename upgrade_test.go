@@ -0,0 +1,78 @@
+package feather
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"runtime"
+	"testing"
+	"time"
+
+	. "github.com/pchchv/feather/assert"
+)
+
+func TestServerUpgrade(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("upgrades are not supported on Windows")
+	}
+
+	if os.Getenv(listenerFDEnv) != blank {
+		// the upgraded process serves a single request using the inherited listener
+		var s *Server
+		s = NewServer(blank, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Connection", "close")
+			_, _ = w.Write([]byte("new"))
+			go s.Stop()
+		}))
+		s.Signals = nil
+		s.ReloadSignals = nil
+		time.AfterFunc(10*time.Second, s.Stop)
+		Equal(t, s.ListenAndServe(), nil)
+		return
+	}
+
+	defer func(fn func() []string) { upgradeArgs = fn }(upgradeArgs)
+	upgradeArgs = func() []string {
+		return []string{"-test.run=^TestServerUpgrade$"}
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	Equal(t, err, nil)
+	addr := l.Addr().String()
+	_ = l.Close()
+
+	s := NewServer(addr, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Connection", "close")
+		_, _ = w.Write([]byte("old"))
+	}))
+	s.Signals = nil
+	s.ReloadSignals = nil
+	NotEqual(t, s.Upgrade(), nil)
+
+	served := make(chan error, 1)
+	go func() {
+		served <- s.ListenAndServe()
+	}()
+
+	get := func() string {
+		var res *http.Response
+		var err error
+		for i := 0; i < 100; i++ {
+			if res, err = http.Get("http://" + addr); err == nil {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		Equal(t, err, nil)
+		defer res.Body.Close()
+		b, err := io.ReadAll(res.Body)
+		Equal(t, err, nil)
+		return string(b)
+	}
+
+	Equal(t, get(), "old")
+	Equal(t, s.Upgrade(), nil)
+	Equal(t, <-served, nil)
+	Equal(t, get(), "new")
+}
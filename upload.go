@@ -0,0 +1,167 @@
+package feather
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+)
+
+// sniffLen is the number of bytes http.DetectContentType considers.
+const sniffLen = 512
+
+var (
+	// ErrFileTooLarge is returned when reading a part exceeding UploadLimits.MaxFileSize.
+	ErrFileTooLarge = errors.New("feather: uploaded file too large")
+	// ErrUploadTooLarge is returned when the request body exceeds UploadLimits.MaxTotalSize.
+	ErrUploadTooLarge = errors.New("feather: upload too large")
+)
+
+// UploadLimits limits the size of streamed multipart uploads, 0 is unlimited.
+type UploadLimits struct {
+	// MaxFileSize is the size of the largest part.
+	MaxFileSize int64
+	// MaxTotalSize is the size of the whole request body.
+	MaxTotalSize int64
+}
+
+// FilePart is a part of a multipart upload, its reads are limited to UploadLimits.MaxFileSize.
+type FilePart struct {
+	*multipart.Part
+	r           *bufio.Reader
+	remaining   int64 // bytes left before exceeding the limit, -1 is unlimited
+	contentType string
+}
+
+// Read reads the part, returning ErrFileTooLarge once it exceeds MaxFileSize.
+func (p *FilePart) Read(b []byte) (int, error) {
+	if p.remaining < 0 {
+		return p.r.Read(b)
+	}
+
+	if p.remaining == 0 {
+		// check whether the part ends exactly at the limit
+		if _, err := p.r.Peek(1); err != nil {
+			return 0, err
+		}
+		return 0, ErrFileTooLarge
+	}
+
+	if int64(len(b)) > p.remaining {
+		b = b[:p.remaining]
+	}
+
+	n, err := p.r.Read(b)
+	p.remaining -= int64(n)
+	return n, err
+}
+
+// ContentType returns the content type sniffed from the first 512 bytes of the part,
+// see http.DetectContentType, rather than the Content-Type declared by the client.
+func (p *FilePart) ContentType() string {
+	if p.contentType == blank {
+		b, _ := p.r.Peek(sniffLen)
+		p.contentType = http.DetectContentType(b)
+	}
+
+	return p.contentType
+}
+
+// Files streams the parts of a multipart/form-data request to fn, without buffering them in memory
+// or temporary files like ParseMultipartForm, i.e. for large upload endpoints.
+// fn is called for every part in order, FileName is blank for form fields.
+// Iteration stops at the first error returned by fn, which is returned,
+// parts not completely read by fn are skipped.
+func Files(r *http.Request, limits UploadLimits, fn func(part *FilePart) error) error {
+	var body *uploadReader
+	if limits.MaxTotalSize > 0 {
+		body = &uploadReader{ReadCloser: r.Body, remaining: limits.MaxTotalSize}
+		r.Body = body
+	}
+
+	// multipart doesn't necessarily return the errors of the body as is
+	exceeded := func(err error) error {
+		if body != nil && body.exceeded {
+			return ErrUploadTooLarge
+		}
+		return err
+	}
+
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return err
+	}
+
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			return nil
+		}
+
+		if err != nil {
+			return exceeded(err)
+		}
+
+		fp := &FilePart{Part: part, r: bufio.NewReaderSize(part, sniffLen), remaining: -1}
+		if limits.MaxFileSize > 0 {
+			fp.remaining = limits.MaxFileSize
+		}
+
+		err = fn(fp)
+		_ = part.Close()
+		if err != nil {
+			return exceeded(err)
+		}
+	}
+}
+
+// SaveUploadedFile writes the part to the file dst, which is created or truncated,
+// and returns the number of bytes written. The file is removed if the part can't be read completely,
+// i.e. because it exceeds the MaxFileSize.
+// dst should not be derived from the file name sent by the client without sanitizing it.
+func SaveUploadedFile(part *FilePart, dst string) (n int64, err error) {
+	f, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err = io.Copy(f, part)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+
+	if err != nil {
+		_ = os.Remove(dst)
+	}
+
+	return
+}
+
+// uploadReader limits the request body to MaxTotalSize.
+type uploadReader struct {
+	io.ReadCloser
+	remaining int64
+	exceeded  bool
+}
+
+func (u *uploadReader) Read(b []byte) (int, error) {
+	if u.remaining <= 0 {
+		var probe [1]byte
+		n, err := u.ReadCloser.Read(probe[:])
+		if n > 0 {
+			u.exceeded = true
+			return 0, ErrUploadTooLarge
+		}
+		return 0, err
+	}
+
+	if int64(len(b)) > u.remaining {
+		b = b[:u.remaining]
+	}
+
+	n, err := u.ReadCloser.Read(b)
+	u.remaining -= int64(n)
+	return n, err
+}
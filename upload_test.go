@@ -0,0 +1,108 @@
+package feather
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/pchchv/feather/assert"
+)
+
+func uploadRequest(t *testing.T, files map[string]string) *http.Request {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	Equal(t, mw.WriteField("name", "joeybloggs"), nil)
+	for _, name := range []string{"a.png", "b.txt"} {
+		if content, ok := files[name]; ok {
+			w, err := mw.CreateFormFile("file", name)
+			Equal(t, err, nil)
+			_, _ = io.WriteString(w, content)
+		}
+	}
+	Equal(t, mw.Close(), nil)
+
+	r, _ := http.NewRequest(http.MethodPost, "/upload", &body)
+	r.Header.Set(contentTypeHeader, mw.FormDataContentType())
+	return r
+}
+
+func TestFiles(t *testing.T) {
+	png := "\x89PNG\x0D\x0A\x1A\x0A" + strings.Repeat("x", 600)
+	dir := t.TempDir()
+
+	var fields, types []string
+	r := uploadRequest(t, map[string]string{"a.png": png, "b.txt": "hello"})
+	err := Files(r, UploadLimits{MaxFileSize: 1024, MaxTotalSize: 4096}, func(part *FilePart) error {
+		if part.FileName() == blank {
+			b, _ := io.ReadAll(part)
+			fields = append(fields, part.FormName()+"="+string(b))
+			return nil
+		}
+
+		types = append(types, part.ContentType())
+		n, err := SaveUploadedFile(part, filepath.Join(dir, part.FileName()))
+		Equal(t, err, nil)
+		NotEqual(t, n, int64(0))
+		return nil
+	})
+	Equal(t, err, nil)
+	Equal(t, fields, []string{"name=joeybloggs"})
+	Equal(t, types, []string{"image/png", "text/plain; charset=utf-8"})
+
+	b, _ := os.ReadFile(filepath.Join(dir, "a.png"))
+	Equal(t, string(b), png)
+	b, _ = os.ReadFile(filepath.Join(dir, "b.txt"))
+	Equal(t, string(b), "hello")
+
+	// parts ending exactly at the limit
+	var lengths []int
+	r = uploadRequest(t, map[string]string{"b.txt": "hello"})
+	err = Files(r, UploadLimits{MaxFileSize: 10}, func(part *FilePart) error {
+		b, err := io.ReadAll(part)
+		lengths = append(lengths, len(b))
+		return err
+	})
+	Equal(t, err, nil)
+	Equal(t, lengths, []int{10, 5})
+
+	// too large files aren't kept
+	r = uploadRequest(t, map[string]string{"b.txt": "hello"})
+	dst := filepath.Join(dir, "large.txt")
+	err = Files(r, UploadLimits{MaxFileSize: 4}, func(part *FilePart) error {
+		if part.FileName() == blank {
+			return nil
+		}
+
+		_, err := SaveUploadedFile(part, dst)
+		return err
+	})
+	Equal(t, err, ErrFileTooLarge)
+	_, err = os.Stat(dst)
+	Equal(t, os.IsNotExist(err), true)
+
+	r = uploadRequest(t, map[string]string{"a.png": png})
+	err = Files(r, UploadLimits{MaxTotalSize: 256}, func(part *FilePart) error {
+		_, err := io.Copy(io.Discard, part)
+		return err
+	})
+	Equal(t, err, ErrUploadTooLarge)
+
+	// errors of fn stop the iteration
+	calls := 0
+	r = uploadRequest(t, map[string]string{"a.png": png, "b.txt": "hello"})
+	err = Files(r, UploadLimits{}, func(part *FilePart) error {
+		calls++
+		return io.ErrUnexpectedEOF
+	})
+	Equal(t, err, io.ErrUnexpectedEOF)
+	Equal(t, calls, 1)
+
+	r, _ = http.NewRequest(http.MethodPost, "/upload", strings.NewReader("{}"))
+	r.Header.Set(contentTypeHeader, applicationJSON)
+	NotEqual(t, Files(r, UploadLimits{}, func(*FilePart) error { return nil }), nil)
+}
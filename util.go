@@ -1,10 +1,19 @@
 package feather
 
+import (
+	"net/url"
+	"path"
+	"strings"
+)
+
 func countParams(path string) uint8 {
 	var n uint // add one just as a buffer
 	for i := 0; i < len(path); i++ {
 		if path[i] == paramByte || path[i] == wildByte {
 			n++
+			if end := constraintAt(path, i); end != -1 {
+				i = end - 1
+			}
 		}
 	}
 	if n >= 255 {
@@ -12,3 +21,175 @@ func countParams(path string) uint8 {
 	}
 	return uint8(n)
 }
+
+// constraintAt returns the end of the constraint of the param starting at path[i],
+// i.e. the index after ')' in ':id(\d+)', or -1 if the param has no or an unterminated constraint.
+func constraintAt(path string, i int) int {
+	if path[i] != paramByte {
+		return -1
+	}
+
+	if i = paramNameEnd(path, i); i < len(path) && path[i] == constraintStartByte {
+		return constraintEnd(path, i)
+	}
+
+	return -1
+}
+
+// paramNameEnd returns the end of the name of the param starting at path[i].
+// Names end at the path segment end or a constraint, when another param follows within the segment
+// they consist of letters, digits and '_' so other bytes delimit the params, i.e. :name.:ext.
+func paramNameEnd(path string, i int) int {
+	end := i + 1
+	for ; end < len(path); end++ {
+		if c := path[end]; c == slashByte || c == constraintStartByte || c == paramByte || c == wildByte {
+			break
+		}
+	}
+
+	if end == len(path) || path[end] != paramByte {
+		return end
+	}
+
+	for i++; i < end; i++ {
+		if c := path[i]; !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_') {
+			break
+		}
+	}
+
+	return i
+}
+
+// hasParam reports whether the segment contains a named param, a ':' followed by a name.
+func hasParam(segment string) bool {
+	for i := 0; i < len(segment); i++ {
+		if segment[i] == paramByte && paramNameEnd(segment, i) > i+1 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// paramEnd returns the end of the param starting at path[i], after its name and constraint if any.
+func paramEnd(path string, i int) int {
+	if end := constraintAt(path, i); end != -1 {
+		return end
+	}
+
+	return paramNameEnd(path, i)
+}
+
+// segmentMatches reports whether the path segment matches the route segment, ignoring constraints.
+// Params match non-empty values delimited by the static part following them.
+func segmentMatches(pattern string, segment string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] != paramByte {
+			if segment == blank || segment[0] != pattern[0] {
+				return false
+			}
+
+			pattern, segment = pattern[1:], segment[1:]
+			continue
+		}
+
+		rest := pattern[paramEnd(pattern, 0):]
+		if rest == blank {
+			return segment != blank
+		}
+
+		for i := len(segment) - 1; i > 0; i-- {
+			if segment[i] == rest[0] && segmentMatches(rest, segment[i:]) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	return segment == blank
+}
+
+// constraintEnd returns the index after the parenthesis closing the one at path[i], or -1 if unterminated.
+func constraintEnd(path string, i int) int {
+	var depth int
+	for ; i < len(path); i++ {
+		switch path[i] {
+		case '\\':
+			i++
+		case constraintStartByte:
+			depth++
+		case constraintEndByte:
+			if depth--; depth == 0 {
+				return i + 1
+			}
+		}
+	}
+
+	return -1
+}
+
+// unescapeRoute query unescapes the route path except param constraints, so i.e. '+' in ':id(\d+)' is preserved.
+func unescapeRoute(path string) (string, error) {
+	var b strings.Builder
+	var last int
+	for i := 0; i < len(path); i++ {
+		end := constraintAt(path, i)
+		if end == -1 {
+			continue
+		}
+
+		start := i + strings.IndexByte(path[i:], constraintStartByte)
+		s, err := url.QueryUnescape(path[last:start])
+		if err != nil {
+			return blank, err
+		}
+
+		b.WriteString(s)
+		b.WriteString(path[start:end])
+		last = end
+		i = end - 1
+	}
+
+	s, err := url.QueryUnescape(path[last:])
+	if err != nil {
+		return blank, err
+	}
+
+	b.WriteString(s)
+	return b.String(), nil
+}
+
+// routePattern returns the route pattern of path as stored in the tree.
+func routePattern(path string) string {
+	if path == blank {
+		return basePath
+	}
+
+	if s, err := unescapeRoute(path); err == nil {
+		return s
+	}
+
+	return path
+}
+
+// cleanPath returns the canonical form of p, replacing multiple slashes by a single one
+// and resolving the . and .. elements like path.Clean, but keeping the trailing slash.
+func cleanPath(p string) string {
+	if p == blank {
+		return basePath
+	}
+
+	cleaned := path.Clean("/" + p)
+	if p[len(p)-1] == slashByte && cleaned != basePath {
+		cleaned += basePath
+	}
+
+	return cleaned
+}
+
+// needsCleaning reports whether p contains multiple slashes or . and .. elements, see cleanPath.
+func needsCleaning(p string) bool {
+	return strings.Contains(p, "//") || strings.Contains(p, "/./") || strings.Contains(p, "/../") ||
+		strings.HasSuffix(p, "/.") || strings.HasSuffix(p, "/..")
+}
@@ -0,0 +1,270 @@
+package feather
+
+import (
+	"net/http"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Validator validates decoded values, see DecodeAndValidate.
+type Validator interface {
+	Validate(v interface{}) error
+}
+
+// ValidatorFunc is a function validating decoded values.
+type ValidatorFunc func(v interface{}) error
+
+// Validate calls f(v).
+func (f ValidatorFunc) Validate(v interface{}) error {
+	return f(v)
+}
+
+// DefaultValidator of this package used by DecodeAndValidate, which is configurable,
+// i.e. to use a third party validation package.
+// By default the validate struct tags are validated, see DecodeAndValidate.
+var DefaultValidator Validator = tagValidator{}
+
+// FieldError is the validation error of a field.
+type FieldError struct {
+	// Field is the path of the field using the names of the json tags, i.e. address.city or items[0].name.
+	Field string `json:"field"`
+	// Rule is the validation rule that failed, i.e. required or max.
+	Rule string `json:"rule"`
+	// Param is the parameter of the rule, i.e. 10 for max=10.
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+}
+
+// ValidationErrors are the field errors of a failed validation,
+// rendered by JSON as an array of the field errors.
+type ValidationErrors []FieldError
+
+func (e ValidationErrors) Error() string {
+	var b strings.Builder
+	b.WriteString("feather: validation failed: ")
+	for i, fe := range e {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(fe.Field)
+		b.WriteByte(' ')
+		b.WriteString(fe.Message)
+	}
+	return b.String()
+}
+
+// FieldErrors returns the messages by field, so MapFormErrors maps ValidationErrors.
+func (e ValidationErrors) FieldErrors() map[string]string {
+	m := make(map[string]string, len(e))
+	for _, fe := range e {
+		m[fe.Field] = fe.Message
+	}
+	return m
+}
+
+// DecodeAndValidate decodes the request like Decode and validates v using the DefaultValidator,
+// making the common decode, validate and respond pipeline one call, i.e.
+//
+//	if err := feather.DecodeAndValidate(r, qp, maxBytes, &user); err != nil {
+//		var verrs feather.ValidationErrors
+//		if errors.As(err, &verrs) {
+//			_ = feather.JSON(w, http.StatusUnprocessableEntity, verrs)
+//			return
+//		}
+//		...
+//	}
+//
+// The default validator validates the validate struct tags of v, comma separated rules:
+//
+//	required     the value must not be the zero value
+//	min=n, max=n numbers must be at least, at most n, strings, slices and maps must have at least, at most n elements
+//	len=n        strings, slices and maps must have exactly n elements
+//	oneof=a b    the value must be one of the space separated values
+//	email        strings must be an email address
+//	url          strings must be an absolute URL
+//
+// Empty values are only validated by required. Nested structs, pointers to structs and slices of structs are validated as well.
+func DecodeAndValidate(r *http.Request, qp QueryParamsOption, maxMemory int64, v interface{}) error {
+	if err := Decode(r, qp, maxMemory, v); err != nil {
+		return err
+	}
+
+	return DefaultValidator.Validate(v)
+}
+
+// tagValidator validates the validate struct tags.
+type tagValidator struct{}
+
+func (tagValidator) Validate(v interface{}) error {
+	var errs ValidationErrors
+	validateValue(reflect.ValueOf(v), blank, &errs)
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+type fieldRules struct {
+	index int
+	name  string
+	rules []rule
+}
+
+type rule struct {
+	name  string
+	param string
+}
+
+// structRules caches the fieldRules of struct types.
+var structRules sync.Map
+
+func rulesOf(t reflect.Type) []fieldRules {
+	if cached, ok := structRules.Load(t); ok {
+		return cached.([]fieldRules)
+	}
+
+	var fields []fieldRules
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+
+		name := f.Name
+		if tag, _, _ := strings.Cut(f.Tag.Get("json"), ","); tag == "-" {
+			continue
+		} else if tag != blank {
+			name = tag
+		}
+
+		fr := fieldRules{index: i, name: name}
+		if tag := f.Tag.Get("validate"); tag != blank && tag != "-" {
+			for _, r := range strings.Split(tag, ",") {
+				name, param, _ := strings.Cut(strings.TrimSpace(r), "=")
+				fr.rules = append(fr.rules, rule{name: name, param: param})
+			}
+		}
+		fields = append(fields, fr)
+	}
+
+	structRules.Store(t, fields)
+	return fields
+}
+
+func validateValue(v reflect.Value, path string, errs *ValidationErrors) {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		for _, fr := range rulesOf(v.Type()) {
+			field := v.Field(fr.index)
+			fieldPath := fr.name
+			if path != blank {
+				fieldPath = path + "." + fr.name
+			}
+
+			for _, r := range fr.rules {
+				if msg, ok := validateRule(field, r); !ok {
+					*errs = append(*errs, FieldError{Field: fieldPath, Rule: r.name, Param: r.param, Message: msg})
+					break
+				}
+			}
+			validateValue(field, fieldPath, errs)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			validateValue(v.Index(i), path+"["+strconv.Itoa(i)+"]", errs)
+		}
+	}
+}
+
+// validateRule validates the value using the rule, returning the message if invalid.
+func validateRule(v reflect.Value, r rule) (string, bool) {
+	if r.name == "required" {
+		return "is required", !v.IsZero()
+	}
+
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return blank, true
+		}
+		v = v.Elem()
+	}
+
+	if v.IsZero() {
+		return blank, true
+	}
+
+	switch r.name {
+	case "min", "max", "len":
+		limit, err := strconv.ParseFloat(r.param, 64)
+		if err != nil {
+			panic("feather: invalid validate rule " + r.name + "=" + r.param)
+		}
+
+		var n float64
+		var measured string
+		switch v.Kind() {
+		case reflect.String:
+			n, measured = float64(len([]rune(v.String()))), " characters"
+		case reflect.Slice, reflect.Array, reflect.Map:
+			n, measured = float64(v.Len()), " elements"
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			n = float64(v.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			n = float64(v.Uint())
+		case reflect.Float32, reflect.Float64:
+			n = v.Float()
+		default:
+			return blank, true
+		}
+
+		switch r.name {
+		case "min":
+			return "must be at least " + r.param + measured, n >= limit
+		case "max":
+			return "must be at most " + r.param + measured, n <= limit
+		default:
+			return "must be exactly " + r.param + measured, n == limit
+		}
+	case "oneof":
+		s := fmtValue(v)
+		for _, option := range strings.Fields(r.param) {
+			if s == option {
+				return blank, true
+			}
+		}
+		return "must be one of " + strings.Join(strings.Fields(r.param), ", "), false
+	case "email":
+		addr, err := mail.ParseAddress(v.String())
+		return "must be an email address", err == nil && addr.Address == v.String()
+	case "url":
+		u, err := url.Parse(v.String())
+		return "must be a URL", err == nil && u.Scheme != blank && u.Host != blank
+	default:
+		panic("feather: unknown validate rule " + r.name)
+	}
+}
+
+func fmtValue(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(v.Uint(), 10)
+	default:
+		return blank
+	}
+}
@@ -0,0 +1,81 @@
+package feather
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	. "github.com/pchchv/feather/assert"
+)
+
+type validateAddress struct {
+	City string `json:"city" validate:"required"`
+}
+
+type validateUser struct {
+	Name      string            `json:"name" validate:"required,min=2,max=5"`
+	Email     string            `json:"email" validate:"email"`
+	Website   string            `json:"website" validate:"url"`
+	Age       int               `json:"age" validate:"min=18"`
+	Role      string            `json:"role" validate:"oneof=admin member"`
+	Tags      []string          `json:"tags" validate:"max=2"`
+	Code      string            `json:"code" validate:"len=3"`
+	Address   *validateAddress  `json:"address" validate:"required"`
+	Addresses []validateAddress `json:"addresses"`
+	Ignored   string            `json:"-" validate:"required"`
+	internal  string
+}
+
+func TestDecodeAndValidate(t *testing.T) {
+	do := func(body string) (validateUser, error) {
+		var u validateUser
+		r, _ := http.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		r.Header.Set(contentTypeHeader, applicationJSON)
+		return u, DecodeAndValidate(r, noQueryParams, 1<<20, &u)
+	}
+
+	u, err := do(`{"name":"joey","email":"joey@example.com","website":"https://example.com","age":30,"role":"admin","tags":["a"],"code":"abc","address":{"city":"Berlin"}}`)
+	Equal(t, err, nil)
+	Equal(t, u.Name, "joey")
+
+	// empty values are only validated by required
+	_, err = do(`{"name":"joey","address":{"city":"Berlin"}}`)
+	Equal(t, err, nil)
+
+	_, err = do(`{"name":"joeybloggs","email":"joey","website":"/relative","age":17,"role":"guest","tags":["a","b","c"],"code":"ab","addresses":[{"city":"Berlin"},{}]}`)
+	var verrs ValidationErrors
+	Equal(t, errors.As(err, &verrs), true)
+	Equal(t, verrs, ValidationErrors{
+		{Field: "name", Rule: "max", Param: "5", Message: "must be at most 5 characters"},
+		{Field: "email", Rule: "email", Message: "must be an email address"},
+		{Field: "website", Rule: "url", Message: "must be a URL"},
+		{Field: "age", Rule: "min", Param: "18", Message: "must be at least 18"},
+		{Field: "role", Rule: "oneof", Param: "admin member", Message: "must be one of admin, member"},
+		{Field: "tags", Rule: "max", Param: "2", Message: "must be at most 2 elements"},
+		{Field: "code", Rule: "len", Param: "3", Message: "must be exactly 3 characters"},
+		{Field: "address", Rule: "required", Message: "is required"},
+		{Field: "addresses[1].city", Rule: "required", Message: "is required"},
+	})
+	Equal(t, MapFormErrors(err)["addresses[1].city"], "is required")
+	Equal(t, strings.HasPrefix(err.Error(), "feather: validation failed: name must be at most 5 characters, email"), true)
+
+	w := httptest.NewRecorder()
+	Equal(t, JSON(w, http.StatusUnprocessableEntity, verrs), nil)
+	var rendered []map[string]string
+	Equal(t, json.Unmarshal(bytes.TrimSpace(w.Body.Bytes()), &rendered), nil)
+	Equal(t, rendered[0], map[string]string{"field": "name", "rule": "max", "param": "5", "message": "must be at most 5 characters"})
+
+	// decode errors are returned as is
+	_, err = do(`{"name":`)
+	Equal(t, errors.As(err, &verrs), false)
+
+	custom := errors.New("custom")
+	defer func(v Validator) { DefaultValidator = v }(DefaultValidator)
+	DefaultValidator = ValidatorFunc(func(v interface{}) error { return custom })
+	_, err = do(`{}`)
+	Equal(t, err, custom)
+}
@@ -0,0 +1,309 @@
+package webhookout
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	contentTypeHeader = "Content-Type"
+	applicationJSON   = "application/json; charset=utf-8"
+	// SignatureHeader holds the hex encoded HMAC-SHA256 of "<timestamp>.<body>" prefixed with "sha256=".
+	SignatureHeader = "X-Webhook-Signature"
+	// TimestampHeader holds the unix timestamp used when computing the signature.
+	TimestampHeader = "X-Webhook-Timestamp"
+	// EventHeader holds the Message Event.
+	EventHeader = "X-Webhook-Event"
+)
+
+// ErrClosed is returned by Dispatch once the Dispatcher has been closed.
+var ErrClosed = errors.New("webhookout: dispatcher closed")
+
+// StatusError is returned when a destination answers with a non 2xx status code.
+type StatusError struct {
+	Code int
+}
+
+func (e *StatusError) Error() string {
+	return "webhookout: unexpected status code " + strconv.Itoa(e.Code)
+}
+
+// Message is a single webhook delivery.
+type Message struct {
+	URL   string
+	Event string
+	Body  []byte
+	// Header contains additional headers to send, may be nil.
+	Header http.Header
+}
+
+// Config contains the Dispatcher settings, zero values are replaced by defaults.
+type Config struct {
+	// Secret used to sign the body, no signature is sent when empty.
+	Secret []byte
+	// Client used for deliveries. Defaults to a client with a 10s timeout.
+	Client *http.Client
+	// MaxAttempts is the total number of delivery attempts. Default 5.
+	MaxAttempts int
+	// BaseDelay is the delay before the first retry, doubled on every following attempt. Default 500ms.
+	BaseDelay time.Duration
+	// MaxDelay caps the backoff delay. Default 1m.
+	MaxDelay time.Duration
+	// Rate is the number of requests per second allowed per destination host, 0 means unlimited.
+	Rate float64
+	// Burst is the number of requests a destination may receive at once. Default 1.
+	Burst int
+	// Workers is the number of goroutines delivering dispatched messages. Default 4.
+	Workers int
+	// DeadLetter is called with messages whose delivery ultimately failed.
+	DeadLetter func(Message, error)
+}
+
+// Dispatcher delivers webhooks with signing, retries and per-destination rate limiting.
+type Dispatcher struct {
+	cfg      Config
+	queue    chan Message
+	wg       sync.WaitGroup
+	m        sync.Mutex // guards closed and sending on queue
+	closed   bool
+	lm       sync.Mutex
+	limiters map[string]*limiter
+}
+
+// New creates a new Dispatcher and starts its workers.
+func New(cfg Config) *Dispatcher {
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 5
+	}
+
+	if cfg.BaseDelay <= 0 {
+		cfg.BaseDelay = 500 * time.Millisecond
+	}
+
+	if cfg.MaxDelay <= 0 {
+		cfg.MaxDelay = time.Minute
+	}
+
+	if cfg.Burst <= 0 {
+		cfg.Burst = 1
+	}
+
+	if cfg.Workers <= 0 {
+		cfg.Workers = 4
+	}
+
+	d := &Dispatcher{
+		cfg:      cfg,
+		queue:    make(chan Message, cfg.Workers*16),
+		limiters: make(map[string]*limiter),
+	}
+
+	d.wg.Add(cfg.Workers)
+	for i := 0; i < cfg.Workers; i++ {
+		go func() {
+			defer d.wg.Done()
+			for msg := range d.queue {
+				_ = d.Send(context.Background(), msg)
+			}
+		}()
+	}
+
+	return d
+}
+
+// Dispatch queues the message for asynchronous delivery,
+// blocking if the queue is full.
+func (d *Dispatcher) Dispatch(msg Message) error {
+	d.m.Lock()
+	defer d.m.Unlock()
+	if d.closed {
+		return ErrClosed
+	}
+
+	d.queue <- msg
+	return nil
+}
+
+// Close stops accepting messages and waits for the queued ones to be delivered.
+func (d *Dispatcher) Close() {
+	d.m.Lock()
+	if !d.closed {
+		d.closed = true
+		close(d.queue)
+	}
+	d.m.Unlock()
+	d.wg.Wait()
+}
+
+// Send delivers the message synchronously, retrying with exponential backoff.
+// The DeadLetter hook is called before returning a final delivery error.
+func (d *Dispatcher) Send(ctx context.Context, msg Message) (err error) {
+	defer func() {
+		if err != nil && d.cfg.DeadLetter != nil {
+			d.cfg.DeadLetter(msg, err)
+		}
+	}()
+
+	var u *url.URL
+	if u, err = url.Parse(msg.URL); err != nil {
+		return
+	}
+
+	lim := d.limiter(u.Host)
+	for attempt := 0; attempt < d.cfg.MaxAttempts; attempt++ {
+		if attempt > 0 {
+			if err = sleep(ctx, d.backoff(attempt)); err != nil {
+				return
+			}
+		}
+
+		if lim != nil {
+			if err = lim.wait(ctx); err != nil {
+				return
+			}
+		}
+
+		var retry bool
+		if retry, err = d.deliver(ctx, msg); err == nil || !retry {
+			return
+		}
+	}
+
+	return
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, msg Message) (retry bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, msg.URL, bytes.NewReader(msg.Body))
+	if err != nil {
+		return false, err
+	}
+
+	for k, v := range msg.Header {
+		req.Header[k] = v
+	}
+
+	if req.Header.Get(contentTypeHeader) == "" {
+		req.Header.Set(contentTypeHeader, applicationJSON)
+	}
+
+	if msg.Event != "" {
+		req.Header.Set(EventHeader, msg.Event)
+	}
+
+	if len(d.cfg.Secret) > 0 {
+		ts := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set(TimestampHeader, ts)
+		req.Header.Set(SignatureHeader, Sign(d.cfg.Secret, ts, msg.Body))
+	}
+
+	resp, err := d.cfg.Client.Do(req)
+	if err != nil {
+		return ctx.Err() == nil, err
+	}
+
+	// drain the body so the connection can be reused
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	_ = resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+
+	retry = resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests
+	return retry, &StatusError{Code: resp.StatusCode}
+}
+
+// backoff returns the delay before the given attempt, with jitter.
+func (d *Dispatcher) backoff(attempt int) time.Duration {
+	delay := d.cfg.BaseDelay << (attempt - 1)
+	if delay <= 0 || delay > d.cfg.MaxDelay {
+		delay = d.cfg.MaxDelay
+	}
+
+	return delay/2 + rand.N(delay/2+1)
+}
+
+func (d *Dispatcher) limiter(host string) *limiter {
+	if d.cfg.Rate <= 0 {
+		return nil
+	}
+
+	d.lm.Lock()
+	defer d.lm.Unlock()
+	l, ok := d.limiters[host]
+	if !ok {
+		l = &limiter{
+			rate:   d.cfg.Rate,
+			burst:  float64(d.cfg.Burst),
+			tokens: float64(d.cfg.Burst),
+			last:   time.Now(),
+		}
+		d.limiters[host] = l
+	}
+
+	return l
+}
+
+// Sign returns the signature sent in SignatureHeader for the given timestamp and body,
+// receivers can use it to verify deliveries.
+func Sign(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// limiter is a token bucket.
+type limiter struct {
+	m      sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func (l *limiter) wait(ctx context.Context) error {
+	for {
+		l.m.Lock()
+		now := time.Now()
+		l.tokens = min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+		l.last = now
+		if l.tokens >= 1 {
+			l.tokens--
+			l.m.Unlock()
+			return nil
+		}
+
+		delay := time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+		l.m.Unlock()
+		if err := sleep(ctx, delay); err != nil {
+			return err
+		}
+	}
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
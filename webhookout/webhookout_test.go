@@ -0,0 +1,87 @@
+package webhookout
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/pchchv/feather/assert"
+)
+
+func TestSendSigned(t *testing.T) {
+	secret := []byte("secret")
+	var sig, ts, event, body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		body = string(b)
+		sig = r.Header.Get(SignatureHeader)
+		ts = r.Header.Get(TimestampHeader)
+		event = r.Header.Get(EventHeader)
+	}))
+	defer server.Close()
+
+	d := New(Config{Secret: secret})
+	defer d.Close()
+
+	err := d.Send(context.Background(), Message{URL: server.URL, Event: "user.created", Body: []byte(`{"id":1}`)})
+	Equal(t, err, nil)
+	Equal(t, body, `{"id":1}`)
+	Equal(t, event, "user.created")
+	Equal(t, sig, Sign(secret, ts, []byte(`{"id":1}`)))
+}
+
+func TestSendRetryAndDeadLetter(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	var dead int
+	d := New(Config{
+		MaxAttempts: 3,
+		BaseDelay:   time.Millisecond,
+		DeadLetter:  func(Message, error) { dead++ },
+	})
+	defer d.Close()
+
+	err := d.Send(context.Background(), Message{URL: server.URL})
+	Equal(t, err, nil)
+	Equal(t, atomic.LoadInt32(&calls), int32(3))
+	Equal(t, dead, 0)
+
+	bad := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer bad.Close()
+
+	err = d.Send(context.Background(), Message{URL: bad.URL})
+	NotEqual(t, err, nil)
+	Equal(t, err.(*StatusError).Code, http.StatusBadRequest)
+	Equal(t, dead, 1)
+}
+
+func TestDispatchRateLimited(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+	}))
+	defer server.Close()
+
+	d := New(Config{Rate: 100, Burst: 1})
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		Equal(t, d.Dispatch(Message{URL: server.URL}), nil)
+	}
+
+	d.Close()
+	Equal(t, atomic.LoadInt32(&calls), int32(5))
+	Equal(t, time.Since(start) >= 30*time.Millisecond, true)
+	Equal(t, d.Dispatch(Message{URL: server.URL}), ErrClosed)
+}
@@ -0,0 +1,380 @@
+// Package websocket upgrades feather requests to WebSocket connections (RFC 6455).
+//
+// The handler runs synchronously within the request, so the request scoped variables of feather,
+// which are returned to a pool once the request completes, stay valid while the connection is open.
+// The connection is hijacked through any wrapping response writers implementing http.Hijacker
+// or Unwrap, such as the writers of the gzip and events middleware,
+// and the handshake response is written directly to the connection.
+package websocket
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	connectionHeader    = "Connection"
+	upgradeHeader       = "Upgrade"
+	originHeader        = "Origin"
+	secKeyHeader        = "Sec-Websocket-Key"
+	secVersionHeader    = "Sec-Websocket-Version"
+	secAcceptHeader     = "Sec-Websocket-Accept"
+	secProtocolHeader   = "Sec-Websocket-Protocol"
+	acceptGUID          = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+	maxControlFrameSize = 125
+)
+
+// Message types defined in RFC 6455.
+const (
+	continuationFrame = 0
+	TextMessage       = 1
+	BinaryMessage     = 2
+	CloseMessage      = 8
+	PingMessage       = 9
+	PongMessage       = 10
+)
+
+// Close codes defined in RFC 6455.
+const (
+	CloseNormalClosure    = 1000
+	CloseProtocolError    = 1002
+	CloseMessageTooBig    = 1009
+	closeNoStatusReceived = 1005
+)
+
+var (
+	// ErrBadHandshake is returned by Upgrade when the request is not a valid WebSocket handshake.
+	ErrBadHandshake = errors.New("websocket: bad handshake")
+	// ErrOrigin is returned by Upgrade when the origin is not allowed.
+	ErrOrigin = errors.New("websocket: origin not allowed")
+	// ErrMessageTooBig is returned by ReadMessage when a message exceeds the MaxMessageSize.
+	ErrMessageTooBig = errors.New("websocket: message too big")
+	errProtocol      = errors.New("websocket: protocol error")
+)
+
+// CloseError is returned by ReadMessage when the peer closed the connection.
+type CloseError struct {
+	Code int
+	Text string
+}
+
+func (e *CloseError) Error() string {
+	return "websocket: close " + strconv.Itoa(e.Code) + " " + e.Text
+}
+
+// Handler handles an upgraded connection, the connection is closed once it returns.
+type Handler func(c *Conn)
+
+// Upgrader contains the settings of the upgrade.
+type Upgrader struct {
+	// CheckOrigin validates the Origin header,
+	// by default requests without an Origin or with an Origin matching the Host are allowed.
+	CheckOrigin func(r *http.Request) bool
+	// Subprotocols supported by the server in order of preference.
+	Subprotocols []string
+	// MaxMessageSize is the maximum size of a message read, 0 means no limit.
+	MaxMessageSize int64
+}
+
+// Upgrade upgrades the request to a WebSocket connection using the default Upgrader
+// and handles it using h.
+func Upgrade(w http.ResponseWriter, r *http.Request, h Handler) error {
+	return new(Upgrader).Upgrade(w, r, h)
+}
+
+// Upgrade upgrades the request to a WebSocket connection and handles it using h,
+// blocking until h returns.
+// If the request is not a valid handshake, an error response is written and the error returned.
+func (u *Upgrader) Upgrade(w http.ResponseWriter, r *http.Request, h Handler) error {
+	if r.Method != http.MethodGet ||
+		!headerContains(r.Header, connectionHeader, "upgrade") ||
+		!headerContains(r.Header, upgradeHeader, "websocket") ||
+		r.Header.Get(secVersionHeader) != "13" {
+		w.Header().Set(secVersionHeader, "13")
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return ErrBadHandshake
+	}
+
+	key := r.Header.Get(secKeyHeader)
+	if b, err := base64.StdEncoding.DecodeString(key); err != nil || len(b) != 16 {
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return ErrBadHandshake
+	}
+
+	checkOrigin := u.CheckOrigin
+	if checkOrigin == nil {
+		checkOrigin = sameOrigin
+	}
+
+	if !checkOrigin(r) {
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		return ErrOrigin
+	}
+
+	netConn, brw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return err
+	}
+
+	// the server's read and write timeouts no longer apply
+	_ = netConn.SetDeadline(time.Time{})
+	c := &Conn{
+		conn: netConn,
+		br:   brw.Reader,
+		bw:   brw.Writer,
+		max:  u.MaxMessageSize,
+	}
+	defer c.Close()
+
+	resp := "HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		secAcceptHeader + ": " + acceptKey(key) + "\r\n"
+	if c.subprotocol = u.subprotocol(r); c.subprotocol != "" {
+		resp += secProtocolHeader + ": " + c.subprotocol + "\r\n"
+	}
+
+	if _, err = c.bw.WriteString(resp + "\r\n"); err == nil {
+		err = c.bw.Flush()
+	}
+
+	if err != nil {
+		return err
+	}
+
+	h(c)
+	return nil
+}
+
+func (u *Upgrader) subprotocol(r *http.Request) string {
+	requested := strings.Split(r.Header.Get(secProtocolHeader), ",")
+	for _, s := range u.Subprotocols {
+		for _, rs := range requested {
+			if strings.TrimSpace(rs) == s {
+				return s
+			}
+		}
+	}
+
+	return ""
+}
+
+// Conn is a server side WebSocket connection.
+// ReadMessage must not be called concurrently, WriteMessage is safe for concurrent use.
+type Conn struct {
+	conn        net.Conn
+	br          *bufio.Reader
+	bw          *bufio.Writer
+	wm          sync.Mutex
+	max         int64
+	subprotocol string
+	closeOnce   sync.Once
+	closeSent   atomic.Bool
+}
+
+// Subprotocol returns the negotiated subprotocol, if any.
+func (c *Conn) Subprotocol() string {
+	return c.subprotocol
+}
+
+// NetConn returns the underlying connection.
+func (c *Conn) NetConn() net.Conn {
+	return c.conn
+}
+
+// ReadMessage reads the next text or binary message, answering pings and discarding pongs.
+// When the peer closes the connection a *CloseError is returned.
+func (c *Conn) ReadMessage() (typ int, data []byte, err error) {
+	for {
+		fin, op, payload, err := c.readFrame(int64(len(data)))
+		if err != nil {
+			switch err {
+			case ErrMessageTooBig:
+				_ = c.writeClose(CloseMessageTooBig)
+			case errProtocol:
+				_ = c.writeClose(CloseProtocolError)
+			}
+			return 0, nil, err
+		}
+
+		switch op {
+		case PingMessage:
+			if err = c.WriteMessage(PongMessage, payload); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case PongMessage:
+			continue
+		case CloseMessage:
+			ce := &CloseError{Code: closeNoStatusReceived}
+			if len(payload) >= 2 {
+				ce.Code = int(binary.BigEndian.Uint16(payload))
+				ce.Text = string(payload[2:])
+			}
+			_ = c.writeClose(CloseNormalClosure)
+			return 0, nil, ce
+		case continuationFrame:
+			if typ == 0 {
+				_ = c.writeClose(CloseProtocolError)
+				return 0, nil, errProtocol
+			}
+			data = append(data, payload...)
+		case TextMessage, BinaryMessage:
+			if typ != 0 {
+				_ = c.writeClose(CloseProtocolError)
+				return 0, nil, errProtocol
+			}
+			typ, data = op, payload
+		default:
+			_ = c.writeClose(CloseProtocolError)
+			return 0, nil, errProtocol
+		}
+
+		if fin {
+			return typ, data, nil
+		}
+	}
+}
+
+// WriteMessage writes a message of the given type as a single frame.
+func (c *Conn) WriteMessage(typ int, data []byte) error {
+	c.wm.Lock()
+	defer c.wm.Unlock()
+	hdr := []byte{0x80 | byte(typ)}
+	switch n := len(data); {
+	case n <= maxControlFrameSize:
+		hdr = append(hdr, byte(n))
+	case n <= 0xffff:
+		hdr = append(hdr, 126)
+		hdr = binary.BigEndian.AppendUint16(hdr, uint16(n))
+	default:
+		hdr = append(hdr, 127)
+		hdr = binary.BigEndian.AppendUint64(hdr, uint64(n))
+	}
+
+	if _, err := c.bw.Write(hdr); err != nil {
+		return err
+	}
+
+	if _, err := c.bw.Write(data); err != nil {
+		return err
+	}
+
+	return c.bw.Flush()
+}
+
+// Close sends a normal closure close frame and closes the connection.
+func (c *Conn) Close() (err error) {
+	c.closeOnce.Do(func() {
+		_ = c.writeClose(CloseNormalClosure)
+		err = c.conn.Close()
+	})
+	return
+}
+
+func (c *Conn) writeClose(code int) error {
+	if c.closeSent.Swap(true) {
+		return nil
+	}
+
+	return c.WriteMessage(CloseMessage, binary.BigEndian.AppendUint16(nil, uint16(code)))
+}
+
+func (c *Conn) readFrame(read int64) (fin bool, op int, payload []byte, err error) {
+	var h [2]byte
+	if _, err = io.ReadFull(c.br, h[:]); err != nil {
+		return
+	}
+
+	fin = h[0]&0x80 != 0
+	op = int(h[0] & 0x0f)
+	// reserved bits must be unset and clients must mask their frames
+	if h[0]&0x70 != 0 || h[1]&0x80 == 0 {
+		err = errProtocol
+		return
+	}
+
+	n := int64(h[1] & 0x7f)
+	switch n {
+	case 126:
+		var b [2]byte
+		if _, err = io.ReadFull(c.br, b[:]); err != nil {
+			return
+		}
+		n = int64(binary.BigEndian.Uint16(b[:]))
+	case 127:
+		var b [8]byte
+		if _, err = io.ReadFull(c.br, b[:]); err != nil {
+			return
+		}
+		n = int64(binary.BigEndian.Uint64(b[:]))
+	}
+
+	if op >= CloseMessage {
+		if !fin || n > maxControlFrameSize {
+			err = errProtocol
+			return
+		}
+	} else if n < 0 || (c.max > 0 && read+n > c.max) {
+		err = ErrMessageTooBig
+		return
+	}
+
+	var mask [4]byte
+	if _, err = io.ReadFull(c.br, mask[:]); err != nil {
+		return
+	}
+
+	payload = make([]byte, n)
+	if _, err = io.ReadFull(c.br, payload); err != nil {
+		return
+	}
+
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+
+	return
+}
+
+func acceptKey(key string) string {
+	h := sha1.Sum([]byte(key + acceptGUID))
+	return base64.StdEncoding.EncodeToString(h[:])
+}
+
+func headerContains(h http.Header, name string, token string) bool {
+	for _, v := range h.Values(name) {
+		for _, s := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(s), token) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get(originHeader)
+	if origin == "" {
+		return true
+	}
+
+	if i := strings.Index(origin, "://"); i != -1 {
+		origin = origin[i+3:]
+	}
+
+	return strings.EqualFold(origin, r.Host)
+}
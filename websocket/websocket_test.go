@@ -0,0 +1,163 @@
+package websocket
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/pchchv/feather"
+	. "github.com/pchchv/feather/assert"
+	"github.com/pchchv/feather/middlewares/gzip"
+)
+
+const testKey = "dGhlIHNhbXBsZSBub25jZQ=="
+
+func dial(t *testing.T, addr string, path string) (net.Conn, *bufio.Reader, *http.Response) {
+	conn, err := net.Dial("tcp", addr)
+	Equal(t, err, nil)
+
+	_, err = conn.Write([]byte("GET " + path + " HTTP/1.1\r\n" +
+		"Host: " + addr + "\r\n" +
+		"Accept-Encoding: gzip\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Sec-WebSocket-Version: 13\r\n" +
+		"Sec-WebSocket-Key: " + testKey + "\r\n" +
+		"Sec-WebSocket-Protocol: chat, superchat\r\n\r\n"))
+	Equal(t, err, nil)
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	Equal(t, err, nil)
+	return conn, br, resp
+}
+
+func writeFrame(conn net.Conn, fin bool, op byte, payload []byte) error {
+	b := []byte{op, 0x80 | byte(len(payload))}
+	if fin {
+		b[0] |= 0x80
+	}
+
+	mask := []byte{1, 2, 3, 4}
+	b = append(b, mask...)
+	for i, c := range payload {
+		b = append(b, c^mask[i%4])
+	}
+
+	_, err := conn.Write(b)
+	return err
+}
+
+func readFrame(br *bufio.Reader) (op byte, payload []byte) {
+	h := make([]byte, 2)
+	_, _ = br.Read(h)
+	payload = make([]byte, h[1]&0x7f)
+	_, _ = br.Read(payload)
+	return h[0] & 0x0f, payload
+}
+
+func TestUpgrade(t *testing.T) {
+	closed := make(chan error, 1)
+	u := &Upgrader{Subprotocols: []string{"superchat"}}
+	p := feather.New()
+	p.Use(gzip.Gzip)
+	p.Get("/rooms/:room", func(w http.ResponseWriter, r *http.Request) {
+		_ = u.Upgrade(w, r, func(c *Conn) {
+			room := feather.RequestVars(r).URLParam("room")
+			for {
+				typ, msg, err := c.ReadMessage()
+				if err != nil {
+					closed <- err
+					return
+				}
+
+				_ = c.WriteMessage(typ, append([]byte(room+":"), msg...))
+			}
+		})
+	})
+
+	server := httptest.NewServer(p.Serve())
+	defer server.Close()
+
+	addr := strings.TrimPrefix(server.URL, "http://")
+	conn, br, resp := dial(t, addr, "/rooms/go")
+	defer conn.Close()
+	Equal(t, resp.StatusCode, http.StatusSwitchingProtocols)
+	Equal(t, resp.Header.Get(secAcceptHeader), "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=")
+	Equal(t, resp.Header.Get(secProtocolHeader), "superchat")
+	Equal(t, resp.Header.Get("Content-Encoding"), "")
+
+	Equal(t, writeFrame(conn, true, TextMessage, []byte("hello")), nil)
+	op, payload := readFrame(br)
+	Equal(t, int(op), TextMessage)
+	Equal(t, string(payload), "go:hello")
+
+	// fragmented message with an interleaved ping
+	Equal(t, writeFrame(conn, false, BinaryMessage, []byte("ab")), nil)
+	Equal(t, writeFrame(conn, true, PingMessage, []byte("p")), nil)
+	Equal(t, writeFrame(conn, true, continuationFrame, []byte("cd")), nil)
+	op, payload = readFrame(br)
+	Equal(t, int(op), PongMessage)
+	Equal(t, string(payload), "p")
+	op, payload = readFrame(br)
+	Equal(t, int(op), BinaryMessage)
+	Equal(t, string(payload), "go:abcd")
+
+	Equal(t, writeFrame(conn, true, CloseMessage, binary.BigEndian.AppendUint16(nil, CloseNormalClosure)), nil)
+	op, payload = readFrame(br)
+	Equal(t, int(op), CloseMessage)
+	Equal(t, int(binary.BigEndian.Uint16(payload)), CloseNormalClosure)
+
+	var ce *CloseError
+	Equal(t, errors.As(<-closed, &ce), true)
+	Equal(t, ce.Code, CloseNormalClosure)
+}
+
+func TestMessageTooBig(t *testing.T) {
+	closed := make(chan error, 1)
+	u := &Upgrader{MaxMessageSize: 4}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = u.Upgrade(w, r, func(c *Conn) {
+			_, _, err := c.ReadMessage()
+			closed <- err
+		})
+	}))
+	defer server.Close()
+
+	conn, br, resp := dial(t, strings.TrimPrefix(server.URL, "http://"), "/")
+	defer conn.Close()
+	Equal(t, resp.StatusCode, http.StatusSwitchingProtocols)
+
+	Equal(t, writeFrame(conn, true, TextMessage, []byte("too big")), nil)
+	Equal(t, <-closed, ErrMessageTooBig)
+	op, payload := readFrame(br)
+	Equal(t, int(op), CloseMessage)
+	Equal(t, int(binary.BigEndian.Uint16(payload)), CloseMessageTooBig)
+}
+
+func TestBadHandshake(t *testing.T) {
+	h := func(c *Conn) {}
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	w := httptest.NewRecorder()
+	Equal(t, Upgrade(w, r, h), ErrBadHandshake)
+	Equal(t, w.Code, http.StatusBadRequest)
+
+	r.Header.Set(connectionHeader, "keep-alive, Upgrade")
+	r.Header.Set(upgradeHeader, "websocket")
+	r.Header.Set(secVersionHeader, "13")
+	r.Header.Set(secKeyHeader, "short")
+	w = httptest.NewRecorder()
+	Equal(t, Upgrade(w, r, h), ErrBadHandshake)
+
+	r.Header.Set(secKeyHeader, testKey)
+	r.Header.Set(originHeader, "https://evil.com")
+	w = httptest.NewRecorder()
+	Equal(t, Upgrade(w, r, h), ErrOrigin)
+	Equal(t, w.Code, http.StatusForbidden)
+}
@@ -0,0 +1,186 @@
+package feather
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	wellKnownPrefix            = "/.well-known"
+	wellKnownCacheControl      = "public, max-age=86400"
+	openIDConfigurationTTL     = time.Hour
+	openIDRetryInterval        = time.Minute
+	openIDCacheControl         = "public, max-age=3600"
+	maxOpenIDConfigurationSize = 1 << 20
+	openIDConfigurationPath    = "/openid-configuration"
+)
+
+// WellKnown registers the well-known URIs, see RFC 8615, below the /.well-known path of a group.
+type WellKnown struct {
+	g *routeGroup
+}
+
+// WellKnown returns the group of the /.well-known URIs below the group prefix, i.e.
+//
+//	wk := p.WellKnown()
+//	wk.SecurityTxt(feather.SecurityTxt{Contact: []string{"mailto:security@example.com"}, Expires: expires})
+//	wk.ChangePassword("/account/password")
+func (g *routeGroup) WellKnown() *WellKnown {
+	return &WellKnown{g: g.GroupWithMore(wellKnownPrefix).(*routeGroup)}
+}
+
+// File registers a GET route serving data at the name below /.well-known with the content type,
+// cached for a day and revalidated using an ETag, i.e. apple-app-site-association.
+func (wk *WellKnown) File(name string, contentType string, data []byte) {
+	wk.g.Get(basePath+strings.TrimPrefix(name, basePath), staticContent(contentType, wellKnownCacheControl, data))
+}
+
+// JSON registers a GET route serving v encoded as JSON at the name below /.well-known, see File.
+// It panics if v cannot be encoded.
+func (wk *WellKnown) JSON(name string, v interface{}) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		panic("well-known '" + name + "': " + err.Error())
+	}
+
+	wk.File(name, applicationJSON, b)
+}
+
+// SecurityTxt contains the fields of security.txt, see RFC 9116.
+type SecurityTxt struct {
+	// Contact lists the URIs for reporting vulnerabilities, i.e. mailto:security@example.com, required.
+	Contact []string
+	// Expires is the date after which the data should be considered stale, required.
+	Expires time.Time
+	// Encryption lists the URIs of the keys for encrypted communication.
+	Encryption []string
+	// Acknowledgments lists the URIs of the pages recognizing the reporters.
+	Acknowledgments []string
+	// PreferredLanguages lists the language tags of the preferred languages of the reports.
+	PreferredLanguages []string
+	// Canonical lists the URIs the file is served at.
+	Canonical []string
+	// Policy lists the URIs of the vulnerability disclosure policies.
+	Policy []string
+	// Hiring lists the URIs of the security related job positions.
+	Hiring []string
+}
+
+// String returns the fields in the security.txt format.
+func (s SecurityTxt) String() string {
+	var b strings.Builder
+	field := func(name string, values []string) {
+		for _, v := range values {
+			b.WriteString(name + ": " + v + "\n")
+		}
+	}
+
+	field("Contact", s.Contact)
+	field("Expires", []string{s.Expires.UTC().Format(time.RFC3339)})
+	field("Encryption", s.Encryption)
+	field("Acknowledgments", s.Acknowledgments)
+	if len(s.PreferredLanguages) > 0 {
+		field("Preferred-Languages", []string{strings.Join(s.PreferredLanguages, ", ")})
+	}
+	field("Canonical", s.Canonical)
+	field("Policy", s.Policy)
+	field("Hiring", s.Hiring)
+	return b.String()
+}
+
+// SecurityTxt registers security.txt, it panics if Contact or Expires are missing.
+func (wk *WellKnown) SecurityTxt(s SecurityTxt) {
+	if len(s.Contact) == 0 || s.Expires.IsZero() {
+		panic("security.txt requires Contact and Expires")
+	}
+
+	wk.File("/security.txt", textPlain, []byte(s.String()))
+}
+
+// ChangePassword registers change-password, redirecting password managers to the page changing passwords,
+// see https://w3c.github.io/webappsec-change-password-url/.
+func (wk *WellKnown) ChangePassword(target string) {
+	wk.g.Get("/change-password", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, target, http.StatusFound)
+	})
+}
+
+// AssetLinks registers assetlinks.json, the Digital Asset Links statements associating the site with apps,
+// i.e. Android apps opening its links, encoded as JSON.
+func (wk *WellKnown) AssetLinks(statements interface{}) {
+	wk.JSON("/assetlinks.json", statements)
+}
+
+// OpenIDConfiguration registers openid-configuration passing through the OpenID Provider metadata of the issuer,
+// so clients can discover the provider from the site, i.e. when the site proxies the provider.
+// The metadata is fetched using client, or http.DefaultClient if nil, on the first request and cached for an hour.
+// When refreshing fails the metadata fetched before is served and the fetch retried a minute later,
+// otherwise the request is answered with 502 Bad Gateway and the fetch retried on the next request.
+func (wk *WellKnown) OpenIDConfiguration(issuer string, client *http.Client) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	source := strings.TrimSuffix(issuer, basePath) + wellKnownPrefix + openIDConfigurationPath
+	var mu sync.Mutex
+	var h http.HandlerFunc
+	var fetched time.Time
+	wk.g.Get(openIDConfigurationPath, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		if h == nil || time.Since(fetched) >= openIDConfigurationTTL {
+			data, err := fetchOpenIDConfiguration(r, client, source)
+			switch {
+			case err == nil:
+				h = staticContent(applicationJSON, openIDCacheControl, data)
+				fetched = time.Now()
+			case h == nil:
+				mu.Unlock()
+				Logger(r).Error("fetching the openid configuration", "source", source, "error", err)
+				http.Error(w, http.StatusText(http.StatusBadGateway), http.StatusBadGateway)
+				return
+			default:
+				// serve the stale metadata and retry later
+				fetched = time.Now().Add(openIDRetryInterval - openIDConfigurationTTL)
+				Logger(r).Warn("refreshing the openid configuration", "source", source, "error", err)
+			}
+		}
+		serve := h
+		mu.Unlock()
+
+		serve(w, r)
+	})
+}
+
+// fetchOpenIDConfiguration returns the OpenID Provider metadata at source.
+func fetchOpenIDConfiguration(r *http.Request, client *http.Client, source string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(r.Context(), http.MethodGet, source, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New("feather: openid configuration status " + resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxOpenIDConfigurationSize))
+	if err != nil {
+		return nil, err
+	}
+
+	if !json.Valid(data) {
+		return nil, errors.New("feather: openid configuration is not valid JSON")
+	}
+
+	return data, nil
+}
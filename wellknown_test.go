@@ -0,0 +1,90 @@
+package feather
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/pchchv/feather/assert"
+)
+
+func TestWellKnown(t *testing.T) {
+	expires := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	p := New()
+	wk := p.WellKnown()
+	wk.SecurityTxt(SecurityTxt{
+		Contact:            []string{"mailto:security@example.com", "https://example.com/security"},
+		Expires:            expires,
+		PreferredLanguages: []string{"en", "de"},
+		Policy:             []string{"https://example.com/disclosure"},
+	})
+	wk.ChangePassword("/account/password")
+	wk.AssetLinks([]map[string]interface{}{{"relation": []string{"delegate_permission/common.handle_all_urls"}}})
+	wk.File("apple-app-site-association", applicationJSONNoCharset, []byte(`{"applinks":{}}`))
+	hf := p.Serve()
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		hf.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	w := get("/.well-known/security.txt")
+	Equal(t, w.Code, http.StatusOK)
+	Equal(t, w.Header().Get(contentTypeHeader), textPlain)
+	Equal(t, w.Header().Get(cacheControlHeader), "public, max-age=86400")
+	Equal(t, w.Body.String(), "Contact: mailto:security@example.com\nContact: https://example.com/security\n"+
+		"Expires: 2030-01-02T03:04:05Z\nPreferred-Languages: en, de\nPolicy: https://example.com/disclosure\n")
+
+	w = get("/.well-known/change-password")
+	Equal(t, w.Code, http.StatusFound)
+	Equal(t, w.Header().Get("Location"), "/account/password")
+
+	w = get("/.well-known/assetlinks.json")
+	Equal(t, w.Header().Get(contentTypeHeader), applicationJSON)
+	Equal(t, w.Body.String(), `[{"relation":["delegate_permission/common.handle_all_urls"]}]`)
+
+	w = get("/.well-known/apple-app-site-association")
+	Equal(t, w.Header().Get(contentTypeHeader), applicationJSONNoCharset)
+
+	PanicMatches(t, func() { New().WellKnown().SecurityTxt(SecurityTxt{Expires: expires}) }, "security.txt requires Contact and Expires")
+	PanicMatches(t, func() { New().WellKnown().JSON("bad", make(chan int)) }, "well-known 'bad': json: unsupported type: chan int")
+}
+
+func TestWellKnownOpenIDConfiguration(t *testing.T) {
+	var calls int
+	status := http.StatusOK
+	issuer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		Equal(t, r.URL.Path, "/realms/main/.well-known/openid-configuration")
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(`{"issuer":"https://auth.example.com/realms/main"}`))
+	}))
+	defer issuer.Close()
+
+	p := New()
+	p.Group("/auth").WellKnown().OpenIDConfiguration(issuer.URL+"/realms/main/", issuer.Client())
+	hf := p.Serve()
+
+	get := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		hf.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/auth/.well-known/openid-configuration", nil))
+		return w
+	}
+
+	status = http.StatusInternalServerError
+	w := get()
+	Equal(t, w.Code, http.StatusBadGateway)
+
+	status = http.StatusOK
+	w = get()
+	Equal(t, w.Code, http.StatusOK)
+	Equal(t, w.Header().Get(contentTypeHeader), applicationJSON)
+	Equal(t, w.Header().Get(cacheControlHeader), "public, max-age=3600")
+	Equal(t, strings.Contains(w.Body.String(), "realms/main"), true)
+
+	get()
+	Equal(t, calls, 2)
+}